@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Outcomes recorded on an audit entry.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Entry is a single append-only audit log record.
+type Entry struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+	Service    string    `json:"service" gorm:"type:text;not null"`
+	ActorID    uint      `json:"actor_id" gorm:"index"`
+	ActorName  string    `json:"actor_name" gorm:"type:text"`
+	Action     string    `json:"action" gorm:"type:text;index;not null"` // e.g. post.create, image.delete, auth.login
+	TargetType string    `json:"target_type" gorm:"type:text;index"`
+	TargetID   string    `json:"target_id" gorm:"type:text;index"`
+	RequestID  string    `json:"request_id" gorm:"type:text"`
+	IP         string    `json:"ip" gorm:"type:text"`
+	Before     string    `json:"before,omitempty" gorm:"type:text"` // JSON summary of the state before the action
+	After      string    `json:"after,omitempty" gorm:"type:text"`  // JSON summary of the state after the action
+	Outcome    string    `json:"outcome" gorm:"type:text;index;not null"`
+	Error      string    `json:"error,omitempty" gorm:"type:text"`
+}
+
+// TableName pins the table name shared by every service.
+func (Entry) TableName() string { return "audit_logs" }
+
+// Recorder appends audit entries. Implementations must not fail the caller's action,
+// so Record reports problems through the log only.
+type Recorder interface {
+	Record(ctx context.Context, e *Entry)
+}
+
+// Meta is the request metadata attached to every entry recorded for a request.
+type Meta struct {
+	ActorID   uint
+	ActorName string
+	RequestID string
+	IP        string
+}
+
+type metaKey struct{}
+
+// WithMeta returns a copy of ctx carrying the request metadata.
+func WithMeta(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+
+// MetaFrom returns the request metadata stored in ctx, if any.
+func MetaFrom(ctx context.Context) Meta {
+	if ctx == nil {
+		return Meta{}
+	}
+	meta, _ := ctx.Value(metaKey{}).(Meta)
+	return meta
+}
+
+// Middleware stores the caller identity injected by the api-gateway, the request ID
+// and the client IP in the request context so services can record them.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		meta := Meta{
+			ActorName: c.GetHeader("X-Username"),
+			RequestID: c.GetHeader("X-Request-Id"),
+			IP:        c.ClientIP(),
+		}
+		if id, err := strconv.ParseUint(c.GetHeader("X-User-Id"), 10, 64); err == nil {
+			meta.ActorID = uint(id)
+		}
+		c.Request = c.Request.WithContext(WithMeta(c.Request.Context(), meta))
+		c.Next()
+	}
+}
+
+// Summary marshals v into a compact JSON summary; it returns "" for nil values.
+func Summary(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	bb, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(bb)
+}
+
+// Record fills e from the request metadata and the error, then appends it with r.
+// A nil recorder is a no-op so auditing stays optional.
+func Record(ctx context.Context, r Recorder, e *Entry, err error) {
+	if r == nil || e == nil {
+		return
+	}
+	meta := MetaFrom(ctx)
+	if e.ActorID == 0 {
+		e.ActorID = meta.ActorID
+	}
+	if e.ActorName == "" {
+		e.ActorName = meta.ActorName
+	}
+	if e.RequestID == "" {
+		e.RequestID = meta.RequestID
+	}
+	if e.IP == "" {
+		e.IP = meta.IP
+	}
+	if err != nil {
+		e.Outcome = OutcomeFailure
+		e.Error = err.Error()
+	} else if e.Outcome == "" {
+		e.Outcome = OutcomeSuccess
+	}
+	r.Record(ctx, e)
+}
+
+// logRecorder is used when no database is available; it writes entries to the process log.
+type logRecorder struct{}
+
+// NewLogRecorder returns a Recorder that writes entries to the standard logger.
+func NewLogRecorder() Recorder { return logRecorder{} }
+
+func (logRecorder) Record(_ context.Context, e *Entry) {
+	log.Printf("[AUDIT] %s %s %s/%s actor=%d outcome=%s request=%s", e.Service, e.Action, e.TargetType, e.TargetID, e.ActorID, e.Outcome, e.RequestID)
+}
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Filter narrows an audit log query. Zero values are ignored.
+type Filter struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   string
+	Outcome    string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+// DefaultLimit and MaxLimit bound a page of audit entries.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// Store is a Postgres-backed Recorder that can also be queried.
+type Store struct {
+	db      *gorm.DB
+	service string
+}
+
+// NewStore creates a Store that tags each entry with the given service name.
+func NewStore(db *gorm.DB, service string) *Store {
+	return &Store{db: db, service: service}
+}
+
+// Record appends the entry. Failures are logged and never returned to the caller.
+func (s *Store) Record(ctx context.Context, e *Entry) {
+	if e.Service == "" {
+		e.Service = s.service
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	if err := s.db.WithContext(ctx).Create(e).Error; err != nil {
+		log.Printf("[ERROR] failed to record audit entry %s: %v", e.Action, err)
+	}
+}
+
+// List returns a page of entries matching filter, newest first, and the total match count.
+func (s *Store) List(filter Filter) ([]*Entry, int64, error) {
+	query := s.db.Model(&Entry{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	var entries []*Entry
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(filter.Offset).Find(&entries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	return entries, total, nil
+}
@@ -2,12 +2,16 @@ package config
 
 import (
 	"os"
+	"strings"
 )
 
 type GlobalConfig struct {
 	AccessTokenTTL  int // in minutes
 	RefreshTokenTTL int // in minutes
 	ServerPort      string
+	// TrustedProxies are the IPs or CIDRs whose X-Forwarded-For is believed when
+	// gin resolves the client IP; empty trusts none and uses the connection's address.
+	TrustedProxies []string
 }
 
 func LoadGlobalConfig() *GlobalConfig {
@@ -15,6 +19,7 @@ func LoadGlobalConfig() *GlobalConfig {
 		AccessTokenTTL:  30,   // in minutes
 		RefreshTokenTTL: 1440, // in minutes (1 day)
 		ServerPort:      getEnv("SERVER_PORT"),
+		TrustedProxies:  getEnvList("TRUSTED_PROXIES"),
 	}
 }
 
@@ -26,3 +31,15 @@ func getEnv(key string) string {
 		panic("critical config missing: " + key)
 	}
 }
+
+// getEnvList returns the comma-separated environment variable named by key, trimmed
+// and without empty entries; nil when it is unset.
+func getEnvList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,228 @@
+// Package dpop verifies DPoP proofs (RFC 9449) that bind access tokens to a key
+// held by the client.
+package dpop
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// ProofType is the typ header every DPoP proof must carry.
+const ProofType = "dpop+jwt"
+
+// ErrInvalidProof is wrapped by every error Verify returns for a bad proof.
+var ErrInvalidProof = errors.New("invalid DPoP proof")
+
+// ErrReplayedProof is returned when a proof's jti was already used.
+var ErrReplayedProof = fmt.Errorf("%w: proof was already used", ErrInvalidProof)
+
+// Proof is a verified DPoP proof.
+type Proof struct {
+	JKT      string // base64url SHA-256 JWK thumbprint of the proof key (RFC 7638)
+	JTI      string
+	IssuedAt time.Time
+}
+
+// proofClaims are the claims of a DPoP proof JWT.
+type proofClaims struct {
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	ATH string `json:"ath,omitempty"`
+	jwtlib.RegisteredClaims
+}
+
+// jwk is the public key in a proof header.
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	D   string `json:"d"` // private; must be absent
+}
+
+// Verify checks a DPoP proof for an HTTP request with the given method and URI. When
+// accessToken is not empty the proof must carry its hash in ath. Proofs issued more
+// than maxAge before or after now are refused; replay is left to a ReplayCache.
+func Verify(proof, method, uri, accessToken string, now time.Time, maxAge time.Duration) (*Proof, error) {
+	var thumbprint string
+	parser := jwtlib.NewParser(
+		jwtlib.WithValidMethods([]string{"ES256", "RS256", "PS256", "EdDSA"}),
+		jwtlib.WithoutClaimsValidation(),
+	)
+	claims := &proofClaims{}
+	_, err := parser.ParseWithClaims(proof, claims, func(token *jwtlib.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != ProofType {
+			return nil, errors.New("typ must be " + ProofType)
+		}
+		raw, ok := token.Header["jwk"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("missing jwk header")
+		}
+		key, jkt, err := parseJWK(raw)
+		if err != nil {
+			return nil, err
+		}
+		thumbprint = jkt
+		return key, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+	if claims.HTM != method {
+		return nil, fmt.Errorf("%w: htm does not match the request method", ErrInvalidProof)
+	}
+	if !sameURI(claims.HTU, uri) {
+		return nil, fmt.Errorf("%w: htu does not match the request URI", ErrInvalidProof)
+	}
+	if claims.ID == "" || len(claims.ID) > 256 {
+		return nil, fmt.Errorf("%w: missing jti", ErrInvalidProof)
+	}
+	if claims.IssuedAt == nil {
+		return nil, fmt.Errorf("%w: missing iat", ErrInvalidProof)
+	}
+	if age := now.Sub(claims.IssuedAt.Time); age > maxAge || age < -maxAge {
+		return nil, fmt.Errorf("%w: iat is outside the accepted window", ErrInvalidProof)
+	}
+	if accessToken != "" && claims.ATH != AccessTokenHash(accessToken) {
+		return nil, fmt.Errorf("%w: ath does not match the access token", ErrInvalidProof)
+	}
+	return &Proof{JKT: thumbprint, JTI: claims.ID, IssuedAt: claims.IssuedAt.Time}, nil
+}
+
+// AccessTokenHash returns the ath value for an access token.
+func AccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// sameURI compares htu to the request URI without query and fragment (RFC 9449 section 4.3).
+func sameURI(htu, uri string) bool {
+	a, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Host, b.Host) && a.EscapedPath() == b.EscapedPath()
+}
+
+// parseJWK returns the public key in a proof header and its RFC 7638 thumbprint.
+func parseJWK(raw map[string]interface{}) (interface{}, string, error) {
+	var k jwk
+	for field, dst := range map[string]*string{"kty": &k.Kty, "crv": &k.Crv, "x": &k.X, "y": &k.Y, "n": &k.N, "e": &k.E, "d": &k.D} {
+		if v, ok := raw[field]; ok {
+			s, ok := v.(string)
+			if !ok {
+				return nil, "", fmt.Errorf("jwk member %s must be a string", field)
+			}
+			*dst = s
+		}
+	}
+	if k.D != "" {
+		return nil, "", errors.New("jwk must not contain a private key")
+	}
+	switch {
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, errX := decodeFixed(k.X, 32)
+		y, errY := decodeFixed(k.Y, 32)
+		if errX != nil || errY != nil {
+			return nil, "", errors.New("invalid EC key")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, "", errors.New("invalid EC key")
+		}
+		return key, thumbprint(fmt.Sprintf(`{"crv":"P-256","kty":"EC","x":"%s","y":"%s"}`, k.X, k.Y)), nil
+	case k.Kty == "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return nil, "", errors.New("invalid RSA key")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < 2048 {
+			return nil, "", errors.New("RSA keys must be at least 2048 bits")
+		}
+		return key, thumbprint(fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, k.E, k.N)), nil
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := decodeFixed(k.X, ed25519.PublicKeySize)
+		if err != nil {
+			return nil, "", errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), thumbprint(fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, k.X)), nil
+	}
+	return nil, "", errors.New("unsupported jwk; use EC P-256, RSA or Ed25519")
+}
+
+// decodeFixed decodes a base64url member that must be exactly size bytes long.
+func decodeFixed(s string, size int) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) != size {
+		return nil, errors.New("invalid key member")
+	}
+	return b, nil
+}
+
+// thumbprint hashes the canonical JSON of a JWK's required members.
+func thumbprint(canonical string) string {
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ReplayCache remembers proof jtis so each proof is accepted once.
+type ReplayCache interface {
+	// Remember records key for ttl and reports false if it was already recorded.
+	Remember(key string, ttl time.Duration) (bool, error)
+}
+
+// RedisReplayCache is a ReplayCache shared by every gateway instance.
+type RedisReplayCache struct {
+	redis *redis.Client
+}
+
+// NewRedisReplayCache creates a ReplayCache backed by Redis.
+func NewRedisReplayCache(client *redis.Client) *RedisReplayCache {
+	return &RedisReplayCache{redis: client}
+}
+
+// Remember stores the hashed key with SET NX so concurrent replays cannot both succeed.
+func (r *RedisReplayCache) Remember(key string, ttl time.Duration) (bool, error) {
+	sum := sha256.Sum256([]byte(key))
+	return r.redis.SetNX(context.Background(), "dpop:jti:"+base64.RawURLEncoding.EncodeToString(sum[:]), "1", ttl).Result()
+}
+
+// Check verifies a proof like Verify and then records its jti in cache, failing with
+// ErrReplayedProof when the same key already used it.
+func Check(cache ReplayCache, proof, method, uri, accessToken string, now time.Time, maxAge time.Duration) (*Proof, error) {
+	p, err := Verify(proof, method, uri, accessToken, now, maxAge)
+	if err != nil {
+		return nil, err
+	}
+	// a proof stays acceptable for maxAge on either side of its iat
+	fresh, err := cache.Remember(p.JKT+":"+p.JTI, 2*maxAge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check DPoP replay: %w", err)
+	}
+	if !fresh {
+		return nil, ErrReplayedProof
+	}
+	return p, nil
+}
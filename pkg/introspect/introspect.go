@@ -0,0 +1,149 @@
+// Package introspect checks tokens against auth-service's RFC 7662 /introspect
+// endpoint, so services can validate tokens without holding the JWT signing key.
+package introspect
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL bounds how long an active result is reused, and so how long a
+// revoked token may still be reported as active.
+const DefaultCacheTTL = 10 * time.Second
+
+// maxCacheEntries bounds the cache; it is emptied when full.
+const maxCacheEntries = 1024
+
+// Result is auth-service's introspection response.
+type Result struct {
+	Active     bool   `json:"active"`
+	Scope      string `json:"scope,omitempty"`
+	Username   string `json:"username,omitempty"`
+	Subject    string `json:"sub,omitempty"`
+	ExpiresAt  int64  `json:"exp,omitempty"`
+	IssuedAt   int64  `json:"iat,omitempty"`
+	SessionID  string `json:"sid,omitempty"`
+	TokenID    uint   `json:"token_id,omitempty"`
+	AuthMethod string `json:"auth_method,omitempty"`
+	Role       string `json:"role,omitempty"`
+	MFA        bool   `json:"mfa,omitempty"`
+	// Confirmation names the DPoP key a sender-constrained token is bound to;
+	// callers must then check a DPoP proof from that key themselves
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+}
+
+// Confirmation is the cnf member of a Result.
+type Confirmation struct {
+	JKT string `json:"jkt"`
+}
+
+type cacheEntry struct {
+	result  Result
+	expires time.Time
+}
+
+// Client calls /introspect with internal client credentials and caches active results
+// briefly. It is safe for concurrent use.
+type Client struct {
+	url          string
+	clientID     string
+	clientSecret string
+	ttl          time.Duration
+	http         *http.Client
+	now          func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// NewClient creates a Client for the auth-service at authServiceURL. A ttl of zero
+// disables the cache.
+func NewClient(authServiceURL, clientID, clientSecret string, ttl time.Duration) *Client {
+	return &Client{
+		url:          strings.TrimRight(authServiceURL, "/") + "/introspect",
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		ttl:          ttl,
+		http:         &http.Client{Timeout: 3 * time.Second},
+		now:          time.Now,
+		cache:        map[string]cacheEntry{},
+	}
+}
+
+// Introspect reports what auth-service knows about token. An inactive token is not
+// an error; errors mean auth-service could not be asked. Neither is cached, so a token
+// is never refused for longer than auth-service refuses it.
+func (c *Client) Introspect(token string) (*Result, error) {
+	key := cacheKey(token)
+	now := c.now()
+	if r, ok := c.cached(key, now); ok {
+		return r, nil
+	}
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequest(http.MethodPost, c.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.clientID, c.clientSecret)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspection request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection failed with status %d", resp.StatusCode)
+	}
+	var result Result
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid introspection response: %w", err)
+	}
+	c.store(key, result, now)
+	return &result, nil
+}
+
+// cached returns a fresh cached result for key.
+func (c *Client) cached(key string, now time.Time) (*Result, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.cache[key]
+	if !ok || !now.Before(entry.expires) {
+		return nil, false
+	}
+	r := entry.result
+	return &r, true
+}
+
+// store caches an active result until the ttl passes or the token expires, whichever
+// is first.
+func (c *Client) store(key string, result Result, now time.Time) {
+	if c.ttl <= 0 || !result.Active {
+		return
+	}
+	expires := now.Add(c.ttl)
+	if result.ExpiresAt > 0 && time.Unix(result.ExpiresAt, 0).Before(expires) {
+		expires = time.Unix(result.ExpiresAt, 0)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.cache) >= maxCacheEntries {
+		c.cache = map[string]cacheEntry{}
+	}
+	c.cache[key] = cacheEntry{result: result, expires: expires}
+}
+
+// cacheKey hashes the token so the cache does not hold bearer credentials.
+func cacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
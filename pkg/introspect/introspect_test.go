@@ -0,0 +1,112 @@
+package introspect
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeAuthService answers /introspect with the result for each token and counts calls.
+type fakeAuthService struct {
+	results map[string]Result
+	status  int
+	calls   int
+}
+
+func (f *fakeAuthService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.calls++
+	if id, secret, ok := r.BasicAuth(); !ok || id != "api-gateway" || secret != "s3cret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(f.results[r.PostFormValue("token")])
+}
+
+func newTestClient(t *testing.T, auth *fakeAuthService, ttl time.Duration, now *time.Time) *Client {
+	t.Helper()
+	srv := httptest.NewServer(auth)
+	t.Cleanup(srv.Close)
+	c := NewClient(srv.URL, "api-gateway", "s3cret", ttl)
+	c.now = func() time.Time { return *now }
+	return c
+}
+
+func TestIntrospect_CachesActiveResultsForTTL(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	auth := &fakeAuthService{results: map[string]Result{"tok": {Active: true, Subject: "7", ExpiresAt: now.Add(time.Hour).Unix()}}}
+	c := newTestClient(t, auth, 10*time.Second, &now)
+
+	for i := 0; i < 3; i++ {
+		r, err := c.Introspect("tok")
+		if err != nil || !r.Active || r.Subject != "7" {
+			t.Fatalf("expected an active result, got %+v err=%v", r, err)
+		}
+	}
+	if auth.calls != 1 {
+		t.Fatalf("expected one call within the ttl, got %d", auth.calls)
+	}
+	now = now.Add(10 * time.Second)
+	if _, err := c.Introspect("tok"); err != nil || auth.calls != 2 {
+		t.Fatalf("expected the result to be fetched again after the ttl, got %d calls err=%v", auth.calls, err)
+	}
+}
+
+func TestIntrospect_CacheEndsAtExpiry(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	auth := &fakeAuthService{results: map[string]Result{"tok": {Active: true, ExpiresAt: now.Add(2 * time.Second).Unix()}}}
+	c := newTestClient(t, auth, time.Minute, &now)
+
+	_, _ = c.Introspect("tok")
+	now = now.Add(time.Second)
+	_, _ = c.Introspect("tok")
+	if auth.calls != 1 {
+		t.Fatalf("expected a cached result before expiry, got %d calls", auth.calls)
+	}
+	now = now.Add(time.Second)
+	_, _ = c.Introspect("tok")
+	if auth.calls != 2 {
+		t.Fatalf("expected the cache to end when the token expires, got %d calls", auth.calls)
+	}
+}
+
+func TestIntrospect_DoesNotCacheInactiveResultsOrErrors(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	auth := &fakeAuthService{results: map[string]Result{}}
+	c := newTestClient(t, auth, time.Minute, &now)
+
+	for i := 0; i < 2; i++ {
+		if r, err := c.Introspect("revoked"); err != nil || r.Active {
+			t.Fatalf("expected an inactive result, got %+v err=%v", r, err)
+		}
+	}
+	if auth.calls != 2 {
+		t.Fatalf("expected inactive results to be asked again, got %d calls", auth.calls)
+	}
+
+	auth.status = http.StatusServiceUnavailable
+	if _, err := c.Introspect("tok"); err == nil {
+		t.Fatalf("expected an error while auth-service is unavailable")
+	}
+	auth.status = 0
+	auth.results["tok"] = Result{Active: true}
+	if r, err := c.Introspect("tok"); err != nil || !r.Active || auth.calls != 4 {
+		t.Fatalf("expected the error not to be cached, got %+v err=%v calls=%d", r, err, auth.calls)
+	}
+}
+
+func TestIntrospect_ZeroTTLDisablesCache(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	auth := &fakeAuthService{results: map[string]Result{"tok": {Active: true}}}
+	c := newTestClient(t, auth, 0, &now)
+	_, _ = c.Introspect("tok")
+	_, _ = c.Introspect("tok")
+	if auth.calls != 2 {
+		t.Fatalf("expected no cache, got %d calls", auth.calls)
+	}
+}
@@ -2,6 +2,8 @@ package jwt
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -17,18 +19,53 @@ var ErrTokenExpired = errors.New("token is expired")
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
+	Role     string `json:"role,omitempty"`
+	MFA      bool   `json:"mfa,omitempty"` // the session passed two-factor authentication
+	// SessionID is shared by every access and refresh token of one login, across refreshes.
+	SessionID string `json:"sid,omitempty"`
+	// Confirmation binds the token to a DPoP key (RFC 9449); nil for bearer tokens.
+	Confirmation *Confirmation `json:"cnf,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Confirmation is the cnf claim of a sender-constrained token.
+type Confirmation struct {
+	JKT string `json:"jkt"` // base64url SHA-256 JWK thumbprint of the DPoP key
+}
+
+// BoundKey returns the thumbprint of the DPoP key the token is bound to, or "".
+func (c *Claims) BoundKey() string {
+	if c.Confirmation == nil {
+		return ""
+	}
+	return c.Confirmation.JKT
+}
+
+// Session describes the login a token pair is issued for.
+type Session struct {
+	ID       string // empty starts a new session
+	UserID   uint
+	Username string
+	Role     string
+	MFA      bool   // the login passed two-factor authentication
+	JKT      string // thumbprint of the DPoP key to bind the tokens to; empty for bearer tokens
+}
+
 // TokenManager provides methods for generating, validating, and revoking JWT tokens.
 type TokenManager interface {
 	// accessToken, refreshToken, error
-	GenerateToken(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error)
+	GenerateToken(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error)
+	// GenerateSessionToken issues a token pair for a login, or for an existing session
+	// when rotating refresh tokens; both tokens carry the session's mfa and cnf claims.
+	GenerateSessionToken(session Session, accessTokenExp, refreshTokenExp time.Duration) (string, string, error)
 	RefreshToken(refreshToken string, accessTokenExp time.Duration) (string, error)
 	ValidateAccessToken(tokenString string) (*Claims, error)
 	ValidateRefreshToken(tokenString string) (*Claims, error)
 	RevokeToken(tokenString string, expiresIn time.Duration) error
 	IsTokenRevoked(tokenString string) (bool, error)
+	// RevokeSession marks every token of a session as revoked for expiresIn.
+	RevokeSession(sessionID string, expiresIn time.Duration) error
+	IsSessionRevoked(sessionID string) (bool, error)
 }
 
 // NewTokenManager creates a new TokenManager with the given secret key and Redis client.
@@ -47,12 +84,31 @@ type tokenManager struct {
 	redis     *redis.Client
 }
 
-// GenerateToken creates a new access and refresh JWT token for a user.
-func (j *tokenManager) GenerateToken(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+// GenerateToken creates a new access and refresh JWT token for a user with the given role.
+func (j *tokenManager) GenerateToken(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+	return j.GenerateSessionToken(Session{UserID: userID, Username: username, Role: role}, accessTokenExp, refreshTokenExp)
+}
+
+// GenerateSessionToken creates a new access and refresh JWT token for the given session.
+func (j *tokenManager) GenerateSessionToken(session Session, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+	if session.ID == "" {
+		var err error
+		if session.ID, err = newSessionID(); err != nil {
+			return "", "", err
+		}
+	}
+	var cnf *Confirmation
+	if session.JKT != "" {
+		cnf = &Confirmation{JKT: session.JKT}
+	}
 	// Access Token
 	accessClaims := Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:       session.UserID,
+		Username:     session.Username,
+		Role:         session.Role,
+		MFA:          session.MFA,
+		SessionID:    session.ID,
+		Confirmation: cnf,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenExp)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -66,8 +122,12 @@ func (j *tokenManager) GenerateToken(userID uint, username string, accessTokenEx
 
 	// Refresh Token
 	refreshClaims := Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:       session.UserID,
+		Username:     session.Username,
+		Role:         session.Role,
+		MFA:          session.MFA,
+		SessionID:    session.ID,
+		Confirmation: cnf,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(refreshTokenExp)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -90,8 +150,12 @@ func (j *tokenManager) RefreshToken(refreshToken string, accessTokenExp time.Dur
 	}
 	// make new access token
 	accessClaims := Claims{
-		UserID:   claims.UserID,
-		Username: claims.Username,
+		UserID:       claims.UserID,
+		Username:     claims.Username,
+		Role:         claims.Role,
+		MFA:          claims.MFA,
+		SessionID:    claims.SessionID,
+		Confirmation: claims.Confirmation,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwtlib.NewNumericDate(time.Now().Add(accessTokenExp)),
 			IssuedAt:  jwtlib.NewNumericDate(time.Now()),
@@ -190,6 +254,43 @@ func (j *tokenManager) IsTokenRevoked(tokenString string) (bool, error) {
 	return res == 1, nil
 }
 
+// RevokeSession stores the session ID in Redis until expiresIn has passed.
+func (j *tokenManager) RevokeSession(sessionID string, expiresIn time.Duration) error {
+	if j.redis == nil {
+		return errors.New("redis client not configured")
+	}
+	if sessionID == "" || expiresIn <= 0 {
+		return nil
+	}
+	return j.redis.Set(context.Background(), j.sessionKey(sessionID), "revoked", expiresIn).Err()
+}
+
+// IsSessionRevoked checks if the session was revoked in Redis.
+func (j *tokenManager) IsSessionRevoked(sessionID string) (bool, error) {
+	if j.redis == nil || sessionID == "" {
+		return false, nil
+	}
+	res, err := j.redis.Exists(context.Background(), j.sessionKey(sessionID)).Result()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// newSessionID returns a random hex-encoded session ID.
+func newSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// sessionKey generates a Redis key for a revoked session.
+func (j *tokenManager) sessionKey(sessionID string) string {
+	return "jwt:session:revoked:" + sessionID
+}
+
 // redisKey generates a Redis key for a JWT token.
 func (j *tokenManager) redisKey(tokenString string) string {
 	return "jwt:blacklist:" + tokenString
@@ -0,0 +1,136 @@
+// Package middleware holds HTTP middleware shared by the gin front ends.
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// minCompressSize skips compression of responses that declare a smaller body.
+const minCompressSize = 1024
+
+// compressibleTypes are the media type prefixes worth compressing.
+var compressibleTypes = []string{
+	"text/html", "text/plain", "text/css", "text/xml",
+	"application/json", "application/javascript", "application/xml", "image/svg+xml",
+}
+
+// Compress negotiates brotli or gzip from Accept-Encoding and compresses text-like
+// responses. Responses that already carry a Content-Encoding (e.g. passed through
+// from an upstream service), partial content and event streams are left untouched.
+func Compress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+		cw := &compressWriter{ResponseWriter: c.Writer, encoding: encoding}
+		c.Writer = cw
+		defer cw.close()
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks br or gzip, preferring br when both are acceptable.
+func negotiateEncoding(header string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[name] = q > 0
+	}
+	for _, enc := range []string{"br", "gzip"} {
+		if ok, listed := accepted[enc]; ok || (!listed && accepted["*"]) {
+			return enc
+		}
+	}
+	return ""
+}
+
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	decided  bool
+	encoder  io.WriteCloser
+}
+
+// decide inspects the response headers on the first body write.
+func (w *compressWriter) decide() {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" || w.Status() != http.StatusOK {
+		return
+	}
+	if !isCompressible(h.Get("Content-Type")) {
+		return
+	}
+	if n, err := strconv.Atoi(h.Get("Content-Length")); err == nil && n < minCompressSize {
+		return
+	}
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", w.encoding)
+	h.Add("Vary", "Accept-Encoding")
+	if w.encoding == "br" {
+		w.encoder = brotli.NewWriterLevel(w.ResponseWriter, brotli.DefaultCompression)
+	} else {
+		w.encoder, _ = gzip.NewWriterLevel(w.ResponseWriter, gzip.DefaultCompression)
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.decide()
+	}
+	if w.encoder == nil {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.encoder.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush pushes buffered compressed bytes to the client.
+func (w *compressWriter) Flush() {
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) close() {
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+	}
+}
+
+func isCompressible(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, t := range compressibleTypes {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag buffers successful GET and HEAD responses, tags them with a strong ETag
+// derived from the body and answers a matching If-None-Match with 304 Not Modified.
+// An ETag already set by the handler or upstream is kept. Event streams, static
+// files carrying Last-Modified and responses that flush early pass through unbuffered.
+func ETag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+		ew := &etagWriter{ResponseWriter: c.Writer}
+		c.Writer = ew
+		c.Next()
+		ew.finish(c.GetHeader("If-None-Match"))
+	}
+}
+
+type etagWriter struct {
+	gin.ResponseWriter
+	buf         bytes.Buffer
+	passthrough bool
+	decided     bool
+}
+
+// buffering reports whether the body is being held back for tagging.
+func (w *etagWriter) buffering() bool {
+	if !w.decided {
+		w.decided = true
+		h := w.Header()
+		// files served with Last-Modified answer conditional requests themselves
+		if w.ResponseWriter.Status() != http.StatusOK || h.Get("Last-Modified") != "" || strings.HasPrefix(h.Get("Content-Type"), "text/event-stream") {
+			w.passthrough = true
+		}
+	}
+	return !w.passthrough
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	if w.buffering() {
+		return w.buf.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow is deferred while buffering so the status can still become 304.
+func (w *etagWriter) WriteHeaderNow() {
+	if w.decided && !w.passthrough {
+		return
+	}
+	if !w.decided && w.ResponseWriter.Status() == http.StatusOK {
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush means the handler is streaming; give up on tagging and send what we have.
+func (w *etagWriter) Flush() {
+	w.decided = true
+	if !w.passthrough {
+		w.passthrough = true
+		if w.buf.Len() > 0 {
+			_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+			w.buf.Reset()
+		}
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *etagWriter) Size() int {
+	if w.passthrough {
+		return w.ResponseWriter.Size()
+	}
+	return w.buf.Len()
+}
+
+func (w *etagWriter) Written() bool {
+	return w.ResponseWriter.Written() || w.buf.Len() > 0
+}
+
+func (w *etagWriter) finish(ifNoneMatch string) {
+	if w.passthrough || w.ResponseWriter.Status() != http.StatusOK {
+		if !w.passthrough && w.buf.Len() > 0 {
+			_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		}
+		return
+	}
+	tag := w.Header().Get("ETag")
+	if tag == "" {
+		sum := sha256.Sum256(w.buf.Bytes())
+		tag = `"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", tag)
+	}
+	if etagMatches(ifNoneMatch, tag) {
+		h := w.Header()
+		h.Del("Content-Length")
+		h.Del("Content-Type")
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	if w.buf.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+	} else {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// etagMatches implements the weak comparison If-None-Match requires.
+func etagMatches(header, tag string) bool {
+	if header == "" {
+		return false
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
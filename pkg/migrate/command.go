@@ -0,0 +1,88 @@
+package migrate
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Startup modes, chosen per service with DB_MIGRATE.
+const (
+	ModeUp     = "up"     // apply pending migrations before serving
+	ModeVerify = "verify" // refuse to start unless the schema is current
+)
+
+// OnStartup migrates or verifies the schema as the service starts, according to mode.
+func OnStartup(ctx context.Context, m *Migrator, mode string) error {
+	switch mode {
+	case ModeUp, "":
+		_, err := m.Up(ctx)
+		return err
+	case ModeVerify:
+		return m.Verify(ctx)
+	}
+	return fmt.Errorf("unknown DB_MIGRATE mode %q; use %s or %s", mode, ModeUp, ModeVerify)
+}
+
+// Command implements `<service> migrate status|up|down [-steps n]|to <version>` and
+// reports to out.
+func Command(ctx context.Context, m *Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate status|up|down [-steps n]|to <version>")
+	}
+	var (
+		count int
+		err   error
+	)
+	switch args[0] {
+	case "status":
+		return printStatus(ctx, m, out)
+	case "up":
+		count, err = m.Up(ctx)
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := fs.Int("steps", 1, "number of migrations to roll back")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		count, err = m.Down(ctx, *steps)
+	case "to":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: migrate to <version>")
+		}
+		version, perr := strconv.ParseInt(args[1], 10, 64)
+		if perr != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		count, err = m.To(ctx, version)
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d migration(s) run\n", count)
+	return printStatus(ctx, m, out)
+}
+
+func printStatus(ctx context.Context, m *Migrator, out io.Writer) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		state := "pending"
+		switch {
+		case s.Unknown:
+			state = "unknown to this build"
+		case s.Modified:
+			state = "modified after " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		case s.Applied:
+			state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(out, "%6d  %-40s %s\n", s.Version, s.Name, state)
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+// Package migrate applies versioned, checksummed SQL migrations to Postgres. Every
+// service keeps its migrations as <version>_<name>.up.sql / .down.sql pairs and
+// records what it applied in the shared schema_migrations table. Changes run under
+// a Postgres advisory lock, so replicas starting together apply each migration once.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// lockKey is the advisory lock held while migrating. It is shared by every service,
+// as they migrate the same database.
+const lockKey int64 = 0x6d6967726174 // "migrat"
+
+// ErrSchemaOutdated is returned by Verify when migrations are pending, were changed
+// after being applied, or the database is newer than this build.
+var ErrSchemaOutdated = errors.New("database schema is not current")
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // hex SHA-256 of Up
+}
+
+// Status describes a migration known to this build or recorded in the database.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool // applied with a different checksum than the current file
+	Unknown   bool // recorded in the database but missing from this build
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads the migrations in the root of fsys, ordered by version. Every version
+// needs both an up and a down file.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>.up.sql or .down.sql", e.Name())
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version", e.Name())
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+			sum := sha256.Sum256(body)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(body)
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", mig.Version, mig.Name)
+		}
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Migrator applies one service's migrations.
+type Migrator struct {
+	db         *sql.DB
+	service    string
+	migrations []Migration
+}
+
+// New creates a Migrator for service's migrations, as returned by Load.
+func New(db *sql.DB, service string, migrations []Migration) *Migrator {
+	return &Migrator{db: db, service: service, migrations: migrations}
+}
+
+// applied is a row of schema_migrations.
+type applied struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// querier is what reads need from *sql.DB and *sql.Conn.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Status lists every migration of the service with whether it was applied, newest last.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	done, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	return m.status(done), nil
+}
+
+func (m *Migrator) status(done map[int64]applied) []Status {
+	out := make([]Status, 0, len(m.migrations))
+	known := map[int64]bool{}
+	for _, mig := range m.migrations {
+		known[mig.Version] = true
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if a, ok := done[mig.Version]; ok {
+			s.Applied, s.AppliedAt, s.Modified = true, a.appliedAt, a.checksum != mig.Checksum
+		}
+		out = append(out, s)
+	}
+	for version, a := range done {
+		if !known[version] {
+			out = append(out, Status{Version: version, Name: a.name, Applied: true, AppliedAt: a.appliedAt, Unknown: true})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
+
+// Verify returns ErrSchemaOutdated, with the reason, unless every migration of this
+// build is applied unchanged and the database has none this build does not know.
+// It changes nothing, so it is safe for every replica to run at startup.
+func (m *Migrator) Verify(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	return checkCurrent(statuses)
+}
+
+func checkCurrent(statuses []Status) error {
+	if err := checkIntegrity(statuses); err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		if !s.Applied {
+			return fmt.Errorf("%w: migration %d_%s is pending", ErrSchemaOutdated, s.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+// checkIntegrity refuses to migrate a database whose history does not match this build.
+func checkIntegrity(statuses []Status) error {
+	for _, s := range statuses {
+		switch {
+		case s.Modified:
+			return fmt.Errorf("%w: migration %d_%s was changed after it was applied", ErrSchemaOutdated, s.Version, s.Name)
+		case s.Unknown:
+			return fmt.Errorf("%w: database has migration %d_%s, which this build does not know", ErrSchemaOutdated, s.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+// Up applies every pending migration and returns how many ran.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.To(ctx, m.latest())
+}
+
+// Down rolls back the last steps applied migrations and returns how many ran.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, nil
+	}
+	var count int
+	err := m.locked(ctx, func(conn *sql.Conn, statuses []Status) error {
+		for i := len(statuses) - 1; i >= 0 && count < steps; i-- {
+			if !statuses[i].Applied {
+				continue
+			}
+			if err := m.down(ctx, conn, m.find(statuses[i].Version)); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// To migrates up or down until version is the newest applied migration; 0 rolls
+// back everything. It returns how many migrations ran.
+func (m *Migrator) To(ctx context.Context, version int64) (int, error) {
+	if version != 0 && m.find(version) == nil {
+		return 0, fmt.Errorf("unknown migration version %d", version)
+	}
+	var count int
+	err := m.locked(ctx, func(conn *sql.Conn, statuses []Status) error {
+		for i := len(statuses) - 1; i >= 0; i-- {
+			if s := statuses[i]; s.Applied && s.Version > version {
+				if err := m.down(ctx, conn, m.find(s.Version)); err != nil {
+					return err
+				}
+				count++
+			}
+		}
+		for _, s := range statuses {
+			if !s.Applied && s.Version <= version {
+				if err := m.up(ctx, conn, m.find(s.Version)); err != nil {
+					return err
+				}
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// locked runs fn on a single connection that holds the advisory lock, with the
+// migration status read after the lock was taken.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, statuses []Status) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		service text NOT NULL,
+		version bigint NOT NULL,
+		name text NOT NULL,
+		checksum text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now(),
+		PRIMARY KEY (service, version)
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	done, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	statuses := m.status(done)
+	if err := checkIntegrity(statuses); err != nil {
+		return err
+	}
+	return fn(conn, statuses)
+}
+
+// applied reads the service's rows of schema_migrations; a missing table means none.
+func (m *Migrator) applied(ctx context.Context, q querier) (map[int64]applied, error) {
+	rows, err := q.QueryContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	exists := false
+	if rows.Next() {
+		err = rows.Scan(&exists)
+	}
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	done := map[int64]applied{}
+	if !exists {
+		return done, nil
+	}
+	rows, err = q.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations WHERE service = $1`, m.service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int64
+		var a applied
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		done[version] = a
+	}
+	return done, rows.Err()
+}
+
+// up applies mig and records it in one transaction.
+func (m *Migrator) up(ctx context.Context, conn *sql.Conn, mig *Migration) error {
+	return m.inTx(ctx, conn, mig, "up", mig.Up,
+		`INSERT INTO schema_migrations (service, version, name, checksum) VALUES ($1, $2, $3, $4)`,
+		m.service, mig.Version, mig.Name, mig.Checksum)
+}
+
+// down rolls mig back and forgets it in one transaction.
+func (m *Migrator) down(ctx context.Context, conn *sql.Conn, mig *Migration) error {
+	return m.inTx(ctx, conn, mig, "down", mig.Down,
+		`DELETE FROM schema_migrations WHERE service = $1 AND version = $2`,
+		m.service, mig.Version)
+}
+
+func (m *Migrator) inTx(ctx context.Context, conn *sql.Conn, mig *Migration, direction, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d_%s %s failed: %w", mig.Version, mig.Name, direction, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to record migration %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
@@ -0,0 +1,51 @@
+package pat
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Prefix marks a bearer token as a personal access token rather than a JWT.
+const Prefix = "pws_"
+
+// Scopes that can be granted to a personal access token.
+const (
+	ScopePostsWrite  = "posts:write"
+	ScopeImagesWrite = "images:write"
+)
+
+// KnownScopes lists every scope a token may be issued with.
+var KnownScopes = []string{ScopePostsWrite, ScopeImagesWrite}
+
+// Generate returns a new random personal access token.
+func Generate() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return Prefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// IsPersonalAccessToken reports whether the bearer token looks like a personal access token.
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, Prefix)
+}
+
+// Hash returns the hex-encoded SHA-256 of the token; only hashes are stored.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HasScope reports whether the space-separated scope string contains scope.
+func HasScope(scopes, scope string) bool {
+	for _, s := range strings.Fields(scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package returnto
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		raw, want string
+	}{
+		{"/", "/"},
+		{"/blog-post/42?lang=ko", "/blog-post/42?lang=ko"},
+		{"/blog-post/42#comments", "/blog-post/42"},
+		{"/series/3", "/series/3"},
+		{"/series", "/series"},
+		{"/seriesx", ""},
+		{"/unknown", ""},
+		{"", ""},
+		{"blog", ""},
+		{"//evil.example", ""},
+		{"https://evil.example/blog", ""},
+		{"/\\evil.example", ""},
+		{"/blog/../admin", ""},
+		{"/blog\n", ""},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.raw); got != tt.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+// Package role defines the user roles shared by auth-service and the api-gateway.
+package role
+
+// Roles a user can be assigned, from most to least privileged.
+const (
+	Owner  = "owner"  // manages users and roles, reads the audit log, writes posts
+	Editor = "editor" // writes posts
+	Reader = "reader" // signs in but cannot write
+)
+
+// Known lists every assignable role.
+var Known = []string{Owner, Editor, Reader}
+
+// Valid reports whether r is a known role.
+func Valid(r string) bool {
+	for _, k := range Known {
+		if k == r {
+			return true
+		}
+	}
+	return false
+}
+
+// CanWrite reports whether the role may create, edit and delete posts.
+func CanWrite(r string) bool {
+	return r == Owner || r == Editor
+}
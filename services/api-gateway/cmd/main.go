@@ -4,10 +4,17 @@ import (
 	"io"
 	"log"
 	"net/http"
+	neturl "net/url"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"seungpyo.lee/PersonalWebSite/pkg/dpop"
+	"seungpyo.lee/PersonalWebSite/pkg/introspect"
 	"seungpyo.lee/PersonalWebSite/pkg/jwt"
+	"seungpyo.lee/PersonalWebSite/pkg/middleware"
+	"seungpyo.lee/PersonalWebSite/pkg/pat"
+	"seungpyo.lee/PersonalWebSite/pkg/role"
 	"seungpyo.lee/PersonalWebSite/services/api-gateway/internal/config"
 	internalmw "seungpyo.lee/PersonalWebSite/services/api-gateway/internal/middleware"
 )
@@ -18,22 +25,114 @@ func main() {
 
 	TokenManager := jwt.NewTokenManagerWithoutRedis(conf.JWTSecretKey)
 	r := gin.Default()
+	// X-Forwarded-For is only believed from the ingress; services get the resolved IP
+	if err := r.SetTrustedProxies(conf.TrustedProxies); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(internalmw.RequestID())
+	// ETag wraps compression so tags and 304s match the encoded representation
+	r.Use(middleware.ETag(), middleware.Compress())
+	// DPoP (RFC 9449) is enabled by DPOP_PUBLIC_URL; proof jtis are shared through Redis
+	var dpopVerifier *internalmw.DPoPVerifier
+	if conf.DPoPPublicURL != "" {
+		if conf.RedisDBURL == "" {
+			log.Fatal("DPOP_PUBLIC_URL requires REDIS_DB_URL for proof replay protection")
+		}
+		rdb := redis.NewClient(&redis.Options{Addr: conf.RedisDBURL + ":" + conf.RedisDBPort, Password: conf.RedisDBPassword})
+		dpopVerifier = internalmw.NewDPoPVerifier(conf.DPoPPublicURL, dpop.NewRedisReplayCache(rdb))
+	}
+	// Token endpoints bind the tokens they issue to the key of a DPoP proof, if any
+	bindDPoP := internalmw.BindDPoP(dpopVerifier)
+	// Access tokens are checked against auth-service so logout and revoked sessions
+	// take effect within the introspection cache TTL
+	var sessions internalmw.SessionChecker
+	if conf.IntrospectionClientSecret != "" {
+		sessions = introspect.NewClient(conf.AuthServiceURL, conf.IntrospectionClientID, conf.IntrospectionClientSecret, introspect.DefaultCacheTTL)
+	} else {
+		log.Println("INTROSPECTION_CLIENT_SECRET is not set; revoked sessions are accepted until their access tokens expire")
+	}
 	// Auth Service proxy
-	authMw := internalmw.AuthOrRefreshMiddleware(TokenManager, conf.AuthServiceURL, conf.AccessTokenTTL)
-	r.POST("/v1/auth/refresh", proxyTo(conf.AuthServiceURL+"/refresh"))
-	r.GET("/v1/auth/oauth/google/login", proxyTo(conf.AuthServiceURL+"/oauth/google/login"))
-	r.GET("/v1/auth/oauth/google/callback", proxyTo(conf.AuthServiceURL+"/oauth/google/callback"))
+	authMw := internalmw.AuthOrRefreshMiddleware(TokenManager, conf.AuthServiceURL, conf.AccessTokenTTL, dpopVerifier, sessions)
+	r.POST("/v1/auth/refresh", bindDPoP, proxyTo(conf.AuthServiceURL+"/refresh"))
+	r.POST("/v1/auth/logout", proxyTo(conf.AuthServiceURL+"/logout"))
+	r.POST("/v1/auth/password/login", bindDPoP, proxyTo(conf.AuthServiceURL+"/password/login"))
+	r.POST("/v1/auth/password/register", proxyTo(conf.AuthServiceURL+"/password/register"))
+	r.POST("/v1/auth/password/forgot", proxyTo(conf.AuthServiceURL+"/password/forgot"))
+	r.POST("/v1/auth/password/reset", proxyTo(conf.AuthServiceURL+"/password/reset"))
+	r.POST("/v1/auth/mfa/verify", bindDPoP, proxyTo(conf.AuthServiceURL+"/mfa/verify"))
+	r.GET("/v1/auth/mfa", authMw, proxyTo(conf.AuthServiceURL+"/mfa"))
+	r.POST("/v1/auth/mfa/totp", authMw, proxyTo(conf.AuthServiceURL+"/mfa/totp"))
+	r.POST("/v1/auth/mfa/totp/confirm", authMw, proxyTo(conf.AuthServiceURL+"/mfa/totp/confirm"))
+	r.DELETE("/v1/auth/mfa/totp", authMw, proxyTo(conf.AuthServiceURL+"/mfa/totp"))
+	r.POST("/v1/auth/mfa/recovery-codes", authMw, proxyTo(conf.AuthServiceURL+"/mfa/recovery-codes"))
+	r.GET("/v1/auth/oauth/providers", proxyTo(conf.AuthServiceURL+"/oauth/providers"))
+	r.GET("/v1/auth/oauth/:provider/login", proxyTo(conf.AuthServiceURL+"/oauth/:provider/login"))
+	r.GET("/v1/auth/oauth/:provider/callback", proxyTo(conf.AuthServiceURL+"/oauth/:provider/callback"))
 	r.GET("/v1/auth/users/:id", authMw, proxyTo(conf.AuthServiceURL+"/users/:id"))
 	r.PUT("/v1/auth/users/:id", authMw, proxyTo(conf.AuthServiceURL+"/users/:id"))
+	r.GET("/v1/auth/users/:id/profile", proxyTo(conf.AuthServiceURL+"/users/:id/profile"))
+	r.POST("/v1/auth/tokens", authMw, proxyTo(conf.AuthServiceURL+"/tokens"))
+	r.GET("/v1/auth/tokens", authMw, proxyTo(conf.AuthServiceURL+"/tokens"))
+	r.DELETE("/v1/auth/tokens/:id", authMw, proxyTo(conf.AuthServiceURL+"/tokens/:id"))
+	// With REQUIRE_MFA, destructive routes need a session that passed two-factor authentication
+	mfa := func(c *gin.Context) { c.Next() }
+	if conf.RequireMFA {
+		mfa = internalmw.RequireMFA()
+	}
+	// Admin API: owners only, from a signed-in session; auth-service re-checks the stored role
+	owners := internalmw.RequireRole(role.Owner)
+	sessionOnly := internalmw.RejectPAT()
+	r.GET("/v1/admin/audit-logs", authMw, sessionOnly, owners, proxyTo(conf.AuthServiceURL+"/admin/audit-logs"))
+	r.GET("/v1/admin/users", authMw, sessionOnly, owners, proxyTo(conf.AuthServiceURL+"/admin/users"))
+	r.POST("/v1/admin/users", authMw, sessionOnly, owners, proxyTo(conf.AuthServiceURL+"/admin/users"))
+	r.PUT("/v1/admin/users/:id/role", authMw, sessionOnly, owners, mfa, proxyTo(conf.AuthServiceURL+"/admin/users/:id/role"))
+	r.DELETE("/v1/admin/users/:id", authMw, sessionOnly, owners, mfa, proxyTo(conf.AuthServiceURL+"/admin/users/:id"))
+	r.GET("/v1/admin/tags", authMw, sessionOnly, owners, proxyTo(conf.PostServiceURL+"/admin/tags"))
+	r.PUT("/v1/admin/tags/:id", authMw, sessionOnly, owners, mfa, proxyTo(conf.PostServiceURL+"/admin/tags/:id"))
+	r.POST("/v1/admin/tags/:id/merge", authMw, sessionOnly, owners, mfa, proxyTo(conf.PostServiceURL+"/admin/tags/:id/merge"))
+	r.POST("/v1/admin/tags/:id/aliases", authMw, sessionOnly, owners, mfa, proxyTo(conf.PostServiceURL+"/admin/tags/:id/aliases"))
+	r.DELETE("/v1/admin/tags/:id/aliases/:alias", authMw, sessionOnly, owners, mfa, proxyTo(conf.PostServiceURL+"/admin/tags/:id/aliases/:alias"))
 
 	// Post Service proxy
 	r.GET("/v1/posts", proxyTo(conf.PostServiceURL+"/posts"))
 	r.GET("/v1/posts/:id", proxyTo(conf.PostServiceURL+"/posts/:id"))
+	r.GET("/v1/posts/:id/related", proxyTo(conf.PostServiceURL+"/posts/:id/related"))
+	r.GET("/v1/posts/by-slug/:slug", proxyTo(conf.PostServiceURL+"/posts/by-slug/:slug"))
+	r.GET("/v1/posts/search", proxyTo(conf.PostServiceURL+"/posts/search"))
 	r.GET("/v1/tags", proxyTo(conf.PostServiceURL+"/tags"))
+	r.GET("/v1/tags/:name", proxyTo(conf.PostServiceURL+"/tags/:name"))
 	// Use API-gateway specific middleware that will attempt refresh on expired tokens
-	r.POST("/v1/posts", authMw, proxyTo(conf.PostServiceURL+"/posts"))
-	r.PUT("/v1/posts/:id", authMw, proxyTo(conf.PostServiceURL+"/posts/:id"))
-	r.DELETE("/v1/posts/:id", authMw, proxyTo(conf.PostServiceURL+"/posts/:id"))
+	// Personal access tokens need the posts:write scope; browser sessions are unscoped
+	postsWrite := internalmw.RequireScope(pat.ScopePostsWrite)
+	// Readers may sign in but only owners and editors write posts
+	writers := internalmw.RequireRole(role.Owner, role.Editor)
+	r.POST("/v1/posts", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/posts"))
+	r.PUT("/v1/posts/:id", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/posts/:id"))
+	r.DELETE("/v1/posts/:id", authMw, writers, postsWrite, mfa, proxyTo(conf.PostServiceURL+"/posts/:id"))
+	// Drafts and scheduled posts are only listed to their author
+	r.GET("/v1/drafts", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/drafts"))
+	r.GET("/v1/drafts/:id", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/drafts/:id"))
+	// Revision history of a post, for its author
+	r.GET("/v1/posts/:id/revisions", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/posts/:id/revisions"))
+	r.GET("/v1/posts/:id/revisions/:rev", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/posts/:id/revisions/:rev"))
+	r.GET("/v1/posts/:id/revisions/:rev/diff", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/posts/:id/revisions/:rev/diff"))
+	r.POST("/v1/posts/:id/revisions/:rev/restore", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/posts/:id/revisions/:rev/restore"))
+	// Anyone may comment; signed-in readers comment under their username
+	r.GET("/v1/posts/:id/comments", proxyTo(conf.PostServiceURL+"/posts/:id/comments"))
+	r.POST("/v1/posts/:id/comments", internalmw.OptionalAuth(authMw), proxyTo(conf.PostServiceURL+"/posts/:id/comments"))
+	// Authors moderate the comments on their posts
+	r.GET("/v1/comments", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/comments"))
+	r.PUT("/v1/comments/:id/status", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/comments/:id/status"))
+	r.DELETE("/v1/comments/:id", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/comments/:id"))
+	// Series are public to read; authors group their own posts
+	r.GET("/v1/series", proxyTo(conf.PostServiceURL+"/series"))
+	r.GET("/v1/series/:id", proxyTo(conf.PostServiceURL+"/series/:id"))
+	r.POST("/v1/series", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/series"))
+	r.PUT("/v1/series/:id", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/series/:id"))
+	r.DELETE("/v1/series/:id", authMw, writers, postsWrite, proxyTo(conf.PostServiceURL+"/series/:id"))
+	// Live editor notifications (Server-Sent Events), streamed without buffering;
+	// EventSource cannot set headers, so browsers authenticate with their cookie
+	r.GET("/v1/events", internalmw.CookieAuth(authMw), proxyTo(conf.PostServiceURL+"/events"))
 
 	// Img Service proxy
 	// r.POST("/v1/images", authMw, proxyTo(conf.ImgServiceURL+"/blog-image"))
@@ -48,10 +147,11 @@ func main() {
 // proxyTo creates a Gin handler that proxies requests to the specified target URL.
 func proxyTo(target string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Build the target URL with path parameters
+		// Build the target URL with path parameters, escaped again since gin decodes
+		// them: tag names may hold spaces, "#" or "?"
 		url := target
 		for _, param := range c.Params {
-			url = strings.ReplaceAll(url, ":"+param.Key, param.Value)
+			url = strings.ReplaceAll(url, ":"+param.Key, neturl.PathEscape(param.Value))
 		}
 
 		var body io.Reader
@@ -71,12 +171,15 @@ func proxyTo(target string) gin.HandlerFunc {
 			}
 		}
 
-		req, err := http.NewRequest(c.Request.Method, targetURL, body)
+		// Bind to the client request so long-lived streams end when the client leaves
+		req, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, targetURL, body)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "proxy request error"})
 			return
 		}
 		req.Header = c.Request.Header.Clone()
+		// services record the caller IP in the audit log
+		req.Header.Set("X-Forwarded-For", c.ClientIP())
 
 		client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
@@ -94,6 +197,28 @@ func proxyTo(target string) gin.HandlerFunc {
 			}
 		}
 		c.Writer.WriteHeader(resp.StatusCode)
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			streamBody(c.Writer, resp.Body)
+			return
+		}
 		_, _ = io.Copy(c.Writer, resp.Body)
 	}
 }
+
+// streamBody copies a Server-Sent Events body, flushing after every read so events
+// reach the client immediately instead of sitting in the response buffer.
+func streamBody(w gin.ResponseWriter, body io.Reader) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			w.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
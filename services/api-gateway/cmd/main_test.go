@@ -1,17 +1,21 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"seungpyo.lee/PersonalWebSite/pkg/jwt"
+	"seungpyo.lee/PersonalWebSite/pkg/middleware"
 	internalmw "seungpyo.lee/PersonalWebSite/services/api-gateway/internal/middleware"
 )
 
@@ -19,7 +23,10 @@ type stubTokenManager struct {
 	validateAccessTokenFn func(token string) (*jwt.Claims, error)
 }
 
-func (s *stubTokenManager) GenerateToken(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+func (s *stubTokenManager) GenerateToken(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+	return "", "", errors.New("not implemented")
+}
+func (s *stubTokenManager) GenerateSessionToken(session jwt.Session, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
 	return "", "", errors.New("not implemented")
 }
 func (s *stubTokenManager) RefreshToken(refreshToken string, accessTokenExp time.Duration) (string, error) {
@@ -37,6 +44,12 @@ func (s *stubTokenManager) RevokeToken(tokenString string, expiresIn time.Durati
 func (s *stubTokenManager) IsTokenRevoked(tokenString string) (bool, error) {
 	return false, errors.New("not implemented")
 }
+func (s *stubTokenManager) RevokeSession(sessionID string, expiresIn time.Duration) error {
+	return errors.New("not implemented")
+}
+func (s *stubTokenManager) IsSessionRevoked(sessionID string) (bool, error) {
+	return false, errors.New("not implemented")
+}
 
 func TestProxyTo_ForwardsPathQueryBodyHeaders(t *testing.T) {
 	t.Helper()
@@ -94,6 +107,24 @@ func TestProxyTo_ForwardsPathQueryBodyHeaders(t *testing.T) {
 	}
 }
 
+func TestProxyTo_EscapesPathParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var gotPath string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+	}))
+	defer downstream.Close()
+
+	r := gin.New()
+	r.GET("/v1/tags/:name", proxyTo(downstream.URL+"/tags/:name"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/tags/c%23%20web%3F", nil))
+	if w.Code != http.StatusOK || gotPath != "/tags/c# web?" {
+		t.Fatalf("expected the tag to reach the service whole, got %d %q", w.Code, gotPath)
+	}
+}
+
 func TestProxyTo_ReturnsBadGatewayWhenServiceUnavailable(t *testing.T) {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
@@ -124,6 +155,202 @@ func TestProxyTo_InvalidTargetURLReturnsInternalServerError(t *testing.T) {
 	}
 }
 
+func TestProxyTo_StreamsEventStreamWithoutBuffering(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	release := make(chan struct{})
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, "event: translation.completed\ndata: {}\n\n")
+		w.(http.Flusher).Flush()
+		// keep the stream open until the first event has been observed by the client
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer downstream.Close()
+	defer close(release)
+
+	r := gin.New()
+	r.Use(middleware.ETag(), middleware.Compress())
+	r.GET("/v1/events", proxyTo(downstream.URL+"/events"))
+	gateway := httptest.NewServer(r)
+	defer gateway.Close()
+
+	resp, err := http.Get(gateway.URL + "/v1/events")
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	got := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		got <- line
+	}()
+	select {
+	case line := <-got:
+		if line != "event: translation.completed\n" {
+			t.Fatalf("unexpected first line %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("event was buffered by the gateway")
+	}
+}
+
+func TestRoutePolicy_EventsStreamAcceptsAccessTokenCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokenManager := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			if token == "cookie-token" {
+				return &jwt.Claims{UserID: 7, Username: "writer", Role: "editor"}, nil
+			}
+			return nil, errors.New("invalid")
+		},
+	}
+	postSvc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-User-Id") != "7" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "event: comment.pending\ndata: {}\n\n")
+	}))
+	defer postSvc.Close()
+
+	r := gin.New()
+	authMw := internalmw.AuthOrRefreshMiddleware(tokenManager, "http://127.0.0.1:65534", 15, nil, nil)
+	r.GET("/v1/events", internalmw.CookieAuth(authMw), proxyTo(postSvc.URL+"/events"))
+
+	// EventSource sends the cookie and nothing else
+	req := httptest.NewRequest(http.MethodGet, "/v1/events", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: "cookie-token"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "event: comment.pending") {
+		t.Fatalf("expected the stream for the cookie's user, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/events", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a cookie, got %d", w.Code)
+	}
+}
+
+func newCachingProxy(t *testing.T, handler http.HandlerFunc) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	downstream := httptest.NewServer(handler)
+	t.Cleanup(downstream.Close)
+	r := gin.New()
+	r.Use(middleware.ETag(), middleware.Compress())
+	r.GET("/v1/posts", proxyTo(downstream.URL+"/posts"))
+	return r
+}
+
+func largeJSON(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = io.WriteString(w, `{"content":"`+strings.Repeat("post body ", 500)+`"}`)
+}
+
+func TestProxyTo_CompressesNegotiatedEncoding(t *testing.T) {
+	r := newCachingProxy(t, largeJSON)
+
+	cases := []struct {
+		accept string
+		want   string
+	}{
+		{"gzip, deflate", "gzip"},
+		{"gzip;q=0.5, br", "br"},
+		{"br;q=0, gzip", "gzip"},
+		{"identity", ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/v1/posts", nil)
+		req.Header.Set("Accept-Encoding", tc.accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Content-Encoding"); got != tc.want {
+			t.Fatalf("Accept-Encoding %q: expected encoding %q, got %q", tc.accept, tc.want, got)
+		}
+		if tc.want != "gzip" {
+			continue
+		}
+		zr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("gzip reader: %v", err)
+		}
+		body, _ := io.ReadAll(zr)
+		if !strings.HasPrefix(string(body), `{"content":"post body`) {
+			t.Fatalf("unexpected decompressed body: %.40s", body)
+		}
+		if w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Fatalf("expected Vary: Accept-Encoding, got %q", w.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestProxyTo_PassesThroughUpstreamContentEncoding(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, _ = zw.Write([]byte(`{"ok":true}`))
+	_ = zw.Close()
+	r := newCachingProxy(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(compressed.Bytes())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/posts", nil)
+	req.Header.Set("Accept-Encoding", "gzip, br")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected upstream gzip encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+	if !bytes.Equal(w.Body.Bytes(), compressed.Bytes()) {
+		t.Fatalf("upstream body was re-encoded")
+	}
+}
+
+func TestProxyTo_ETagAndNotModified(t *testing.T) {
+	r := newCachingProxy(t, largeJSON)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/posts", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.HasPrefix(etag, `"`) {
+		t.Fatalf("expected strong ETag on 200, got %d %q", w.Code, etag)
+	}
+
+	req2 := httptest.NewRequest(http.MethodGet, "/v1/posts", nil)
+	req2.Header.Set("Accept-Encoding", "gzip")
+	req2.Header.Set("If-None-Match", `"other", `+etag)
+	w2 := httptest.NewRecorder()
+	r.ServeHTTP(w2, req2)
+	if w2.Code != http.StatusNotModified || w2.Body.Len() != 0 {
+		t.Fatalf("expected empty 304, got %d with %d bytes", w2.Code, w2.Body.Len())
+	}
+	if w2.Header().Get("ETag") != etag {
+		t.Fatalf("expected ETag on 304")
+	}
+
+	req3 := httptest.NewRequest(http.MethodGet, "/v1/posts", nil)
+	req3.Header.Set("If-None-Match", `"stale"`)
+	w3 := httptest.NewRecorder()
+	r.ServeHTTP(w3, req3)
+	if w3.Code != http.StatusOK || w3.Body.Len() == 0 {
+		t.Fatalf("expected full response for stale tag, got %d", w3.Code)
+	}
+}
+
 func TestIntegration_ExpiredAccessTokenGetsRefreshedAndProxied(t *testing.T) {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
@@ -164,7 +391,7 @@ func TestIntegration_ExpiredAccessTokenGetsRefreshedAndProxied(t *testing.T) {
 	defer postSvc.Close()
 
 	r := gin.New()
-	authMw := internalmw.AuthOrRefreshMiddleware(tokenManager, authSvc.URL, 15)
+	authMw := internalmw.AuthOrRefreshMiddleware(tokenManager, authSvc.URL, 15, nil, nil)
 	r.POST("/v1/posts", authMw, proxyTo(postSvc.URL+"/posts"))
 
 	req := httptest.NewRequest(http.MethodPost, "/v1/posts", bytes.NewBufferString(`{"title":"test"}`))
@@ -208,7 +435,7 @@ func TestRoutePolicy_PostsGetUnprotectedAndWriteMethodsProtected(t *testing.T) {
 	defer postSvc.Close()
 
 	r := gin.New()
-	authMw := internalmw.AuthOrRefreshMiddleware(tokenManager, "http://127.0.0.1:65534", 15)
+	authMw := internalmw.AuthOrRefreshMiddleware(tokenManager, "http://127.0.0.1:65534", 15, nil, nil)
 	r.GET("/v1/posts", proxyTo(postSvc.URL+"/posts"))
 	r.POST("/v1/posts", authMw, proxyTo(postSvc.URL+"/posts"))
 	r.PUT("/v1/posts/:id", authMw, proxyTo(postSvc.URL+"/posts/:id"))
@@ -257,7 +484,7 @@ func TestRoutePolicy_AuthUsersRouteProtected(t *testing.T) {
 	defer authSvc.Close()
 
 	r := gin.New()
-	authMw := internalmw.AuthOrRefreshMiddleware(tokenManager, "http://127.0.0.1:65534", 15)
+	authMw := internalmw.AuthOrRefreshMiddleware(tokenManager, "http://127.0.0.1:65534", 15, nil, nil)
 	r.GET("/v1/auth/users/:id", authMw, proxyTo(authSvc.URL+"/users/:id"))
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/auth/users/1", nil)
@@ -268,6 +495,34 @@ func TestRoutePolicy_AuthUsersRouteProtected(t *testing.T) {
 	}
 }
 
+func TestRoutePolicy_PublicProfileRouteUnprotected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokenManager := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) { return nil, errors.New("invalid") },
+	}
+
+	authSvc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/1/profile" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"username": "writer"})
+	}))
+	defer authSvc.Close()
+
+	r := gin.New()
+	authMw := internalmw.AuthOrRefreshMiddleware(tokenManager, "http://127.0.0.1:65534", 15, nil, nil)
+	r.GET("/v1/auth/users/:id", authMw, proxyTo(authSvc.URL+"/users/:id"))
+	r.GET("/v1/auth/users/:id/profile", proxyTo(authSvc.URL+"/users/:id/profile"))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/users/1/profile", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "writer") {
+		t.Fatalf("expected public profile route to be proxied without auth, got %d %s", w.Code, w.Body.String())
+	}
+}
+
 func TestRoutePolicy_AuthRefreshRouteUnprotected(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	authSvc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -291,14 +546,107 @@ func TestRoutePolicy_AuthRefreshRouteUnprotected(t *testing.T) {
 	}
 }
 
+func TestRoutePolicy_AuthLogoutRouteUnprotected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authSvc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/logout" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if ck, err := r.Cookie("refresh_token"); err != nil || ck.Value != "a" {
+			http.Error(w, "refresh cookie not forwarded", http.StatusBadRequest)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "refresh_token", Path: "/", MaxAge: -1})
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer authSvc.Close()
+
+	r := gin.New()
+	r.POST("/v1/auth/logout", proxyTo(authSvc.URL+"/logout"))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/logout", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "a"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected unprotected logout route to return 204, got %d", w.Code)
+	}
+	if !strings.Contains(w.Header().Get("Set-Cookie"), "refresh_token=") {
+		t.Fatalf("expected cleared refresh cookie to be passed through, got %q", w.Header().Get("Set-Cookie"))
+	}
+}
+
+func TestRoutePolicy_PasswordLoginForwardsClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authSvc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/password/login" || r.Header.Get("X-Forwarded-For") == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "access_token", Value: "a", Path: "/"})
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer authSvc.Close()
+
+	r := gin.New()
+	r.POST("/v1/auth/password/login", proxyTo(authSvc.URL+"/password/login"))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/password/login", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("Set-Cookie"), "access_token=") {
+		t.Fatalf("expected unprotected password login with client IP and cookies, got %d %q", w.Code, w.Header().Get("Set-Cookie"))
+	}
+}
+
+func TestProxyTo_ForwardsClientIPResolvedThroughTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var forwarded string
+	authSvc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get("X-Forwarded-For")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer authSvc.Close()
+
+	cases := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		xff        string
+		want       string
+	}{
+		{"spoofed by a client", nil, "192.0.2.1:1234", "203.0.113.9", "192.0.2.1"},
+		{"spoof appended to by the ingress", []string{"192.0.2.10"}, "192.0.2.10:1234", "203.0.113.9, 198.51.100.7", "198.51.100.7"},
+		{"spoofed past the ingress", []string{"192.0.2.10"}, "192.0.2.1:1234", "203.0.113.9", "192.0.2.1"},
+	}
+	for _, tc := range cases {
+		r := gin.New()
+		if err := r.SetTrustedProxies(tc.trusted); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		r.POST("/v1/auth/password/login", proxyTo(authSvc.URL+"/password/login"))
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/auth/password/login", strings.NewReader(`{}`))
+		req.RemoteAddr = tc.remoteAddr
+		req.Header.Set("X-Forwarded-For", tc.xff)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		if forwarded != tc.want {
+			t.Fatalf("%s: expected X-Forwarded-For %q, got %q", tc.name, tc.want, forwarded)
+		}
+	}
+}
+
 func TestRoutePolicy_OAuthRoutesDirectProxy(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	authSvc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
-		case "/oauth/google/login":
+		case "/oauth/providers":
+			_ = json.NewEncoder(w).Encode(map[string][]string{"providers": {"github", "google"}})
+		case "/oauth/github/login":
 			w.WriteHeader(http.StatusFound)
-			w.Header().Set("Location", "https://accounts.google.com/o/oauth2/v2/auth")
-		case "/oauth/google/callback":
+			w.Header().Set("Location", "https://github.com/login/oauth/authorize")
+		case "/oauth/github/callback":
 			_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 		default:
 			http.Error(w, "not found", http.StatusNotFound)
@@ -307,17 +655,25 @@ func TestRoutePolicy_OAuthRoutesDirectProxy(t *testing.T) {
 	defer authSvc.Close()
 
 	r := gin.New()
-	r.GET("/v1/auth/oauth/google/login", proxyTo(authSvc.URL+"/oauth/google/login"))
-	r.GET("/v1/auth/oauth/google/callback", proxyTo(authSvc.URL+"/oauth/google/callback"))
+	r.GET("/v1/auth/oauth/providers", proxyTo(authSvc.URL+"/oauth/providers"))
+	r.GET("/v1/auth/oauth/:provider/login", proxyTo(authSvc.URL+"/oauth/:provider/login"))
+	r.GET("/v1/auth/oauth/:provider/callback", proxyTo(authSvc.URL+"/oauth/:provider/callback"))
+
+	providersReq := httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/providers", nil)
+	providersW := httptest.NewRecorder()
+	r.ServeHTTP(providersW, providersReq)
+	if providersW.Code != http.StatusOK || !strings.Contains(providersW.Body.String(), "github") {
+		t.Fatalf("expected providers route to proxy, got %d %s", providersW.Code, providersW.Body.String())
+	}
 
-	loginReq := httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/google/login", nil)
+	loginReq := httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/github/login", nil)
 	loginW := httptest.NewRecorder()
 	r.ServeHTTP(loginW, loginReq)
 	if loginW.Code != http.StatusFound {
 		t.Fatalf("expected oauth login route to proxy (302), got %d", loginW.Code)
 	}
 
-	callbackReq := httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/google/callback?code=abc&state=s", nil)
+	callbackReq := httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/github/callback?code=abc&state=s", nil)
 	callbackW := httptest.NewRecorder()
 	r.ServeHTTP(callbackW, callbackReq)
 	if callbackW.Code != http.StatusOK {
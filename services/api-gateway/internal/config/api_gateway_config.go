@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"seungpyo.lee/PersonalWebSite/pkg/config"
@@ -15,6 +16,17 @@ type GatewayConfig struct {
 	PostServiceURL string
 	ImgServiceURL  string
 	JWTSecretKey   string
+	RequireMFA     bool // destructive routes need a session that passed two-factor authentication
+	// DPoPPublicURL is the URL clients reach the gateway at, such as https://example.com/api;
+	// DPoP proofs name it in htu. Empty disables DPoP.
+	DPoPPublicURL   string
+	RedisDBURL      string // DPoP proof replay cache; required when DPoP is enabled
+	RedisDBPort     string
+	RedisDBPassword string
+	// Introspection client credentials, one of auth-service's INTROSPECTION_CLIENTS.
+	// With a secret, access tokens of revoked sessions are refused before they expire.
+	IntrospectionClientID     string
+	IntrospectionClientSecret string
 }
 
 func LoadGatewayConfig() *GatewayConfig {
@@ -23,11 +35,19 @@ func LoadGatewayConfig() *GatewayConfig {
 		log.Println("No .env file found, reading from environment variables")
 	}
 	return &GatewayConfig{
-		GlobalConfig:   *config.LoadGlobalConfig(),
-		AuthServiceURL: getEnv("AUTH_SERVICE_URL"),
-		PostServiceURL: getEnv("POST_SERVICE_URL"),
-		ImgServiceURL:  getEnv("IMG_SERVICE_URL"),
-		JWTSecretKey:   getEnv("JWT_SECRET_KEY"),
+		GlobalConfig:    *config.LoadGlobalConfig(),
+		AuthServiceURL:  getEnv("AUTH_SERVICE_URL"),
+		PostServiceURL:  getEnv("POST_SERVICE_URL"),
+		ImgServiceURL:   getEnv("IMG_SERVICE_URL"),
+		JWTSecretKey:    getEnv("JWT_SECRET_KEY"),
+		RequireMFA:      getEnvDefault("REQUIRE_MFA", "false") == "true",
+		DPoPPublicURL:   strings.TrimRight(getEnvDefault("DPOP_PUBLIC_URL", ""), "/"),
+		RedisDBURL:      getEnvDefault("REDIS_DB_URL", ""),
+		RedisDBPort:     getEnvDefault("REDIS_DB_PORT", "6379"),
+		RedisDBPassword: getEnvDefault("REDIS_DB_PASSWORD", ""),
+
+		IntrospectionClientID:     getEnvDefault("INTROSPECTION_CLIENT_ID", "api-gateway"),
+		IntrospectionClientSecret: getEnvDefault("INTROSPECTION_CLIENT_SECRET", ""),
 	}
 }
 
@@ -39,3 +59,11 @@ func getEnv(key string) string {
 		panic("critical config missing: " + key)
 	}
 }
+
+// getEnvDefault returns the environment variable named by key, or fallback if unset.
+func getEnvDefault(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
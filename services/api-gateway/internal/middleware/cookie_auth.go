@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// CookieAuth runs auth with the access_token cookie as a bearer token when the request
+// has no Authorization header. It serves the Server-Sent Events stream, which browsers
+// open with EventSource and cannot add headers to. Only use it on GET routes: cookies
+// are sent with cross-site requests too.
+func CookieAuth(auth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token, err := c.Cookie("access_token"); err == nil && token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		auth(c)
+	}
+}
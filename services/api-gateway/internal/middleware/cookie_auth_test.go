@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/jwt"
+)
+
+func TestCookieAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokenManager := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			switch token {
+			case "cookie-token":
+				return &jwt.Claims{UserID: 9, Username: "writer", Role: "editor"}, nil
+			case "header-token":
+				return &jwt.Claims{UserID: 4, Username: "cli", Role: "editor"}, nil
+			}
+			return nil, errors.New("invalid")
+		},
+	}
+	r := gin.New()
+	r.GET("/events", CookieAuth(AuthOrRefreshMiddleware(tokenManager, "http://127.0.0.1:65534", 15, nil, nil)), func(c *gin.Context) {
+		c.String(http.StatusOK, c.Request.Header.Get("X-User-Id"))
+	})
+
+	cases := []struct {
+		name, authorization, cookie string
+		wantCode                    int
+		wantBody                    string
+	}{
+		{"cookie only", "", "cookie-token", http.StatusOK, "9"},
+		{"header wins", "Bearer header-token", "cookie-token", http.StatusOK, "4"},
+		{"bad cookie", "", "bad", http.StatusUnauthorized, ""},
+		{"neither", "", "", http.StatusUnauthorized, ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		if tc.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "access_token", Value: tc.cookie})
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.wantCode {
+			t.Fatalf("%s: expected %d, got %d; body=%s", tc.name, tc.wantCode, w.Code, w.Body.String())
+		}
+		if tc.wantBody != "" && w.Body.String() != tc.wantBody {
+			t.Fatalf("%s: expected user %q, got %q", tc.name, tc.wantBody, w.Body.String())
+		}
+	}
+}
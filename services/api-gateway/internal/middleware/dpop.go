@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/dpop"
+)
+
+// dpopProofMaxAge is how far a proof's iat may be from the gateway's clock.
+const dpopProofMaxAge = 2 * time.Minute
+
+// dpopKeyHeader passes the thumbprint of a verified DPoP key to auth-service, which
+// binds the tokens it issues to that key. Clients cannot set it.
+const dpopKeyHeader = "X-DPoP-JKT"
+
+// errDPoPDisabled is returned for tokens bound to a key when DPoP is not configured.
+var errDPoPDisabled = errors.New("DPoP is not enabled")
+
+// DPoPVerifier checks the DPoP proofs (RFC 9449) clients send in the DPoP header.
+type DPoPVerifier struct {
+	publicURL string
+	replay    dpop.ReplayCache
+	now       func() time.Time
+}
+
+// NewDPoPVerifier creates a verifier for proofs naming URLs under publicURL, the address
+// clients reach the gateway at. replay remembers proof jtis across gateway instances.
+func NewDPoPVerifier(publicURL string, replay dpop.ReplayCache) *DPoPVerifier {
+	return &DPoPVerifier{publicURL: publicURL, replay: replay, now: time.Now}
+}
+
+// proofKey verifies the request's DPoP proof and returns the thumbprint of its key, or
+// "" when the request carries no proof. accessToken is the token the proof must hash
+// in ath; empty on token endpoints. A nil verifier ignores proofs, as a server without
+// DPoP support would.
+func (v *DPoPVerifier) proofKey(c *gin.Context, accessToken string) (string, error) {
+	proofs := c.Request.Header.Values("DPoP")
+	if v == nil || len(proofs) == 0 {
+		return "", nil
+	}
+	if len(proofs) > 1 {
+		return "", fmt.Errorf("%w: more than one DPoP header", dpop.ErrInvalidProof)
+	}
+	p, err := dpop.Check(v.replay, proofs[0], c.Request.Method, v.publicURL+c.Request.URL.Path, accessToken, v.now(), dpopProofMaxAge)
+	if err != nil {
+		return "", err
+	}
+	return p.JKT, nil
+}
+
+// checkBinding requires a proof from the key a token is bound to, and refuses the DPoP
+// authorization scheme for tokens that are not bound.
+func (v *DPoPVerifier) checkBinding(scheme, bound, jkt string) error {
+	if bound == "" {
+		if scheme == "DPoP" {
+			return fmt.Errorf("%w: token is not DPoP-bound", dpop.ErrInvalidProof)
+		}
+		return nil
+	}
+	if v == nil {
+		return errDPoPDisabled
+	}
+	if jkt == "" {
+		return fmt.Errorf("%w: token is DPoP-bound and needs a DPoP proof", dpop.ErrInvalidProof)
+	}
+	if jkt != bound {
+		return fmt.Errorf("%w: proof key does not match the token", dpop.ErrInvalidProof)
+	}
+	return nil
+}
+
+// abortDPoP rejects a request whose proof or binding failed. Errors other than a bad
+// proof mean the replay cache could not be reached.
+func abortDPoP(c *gin.Context, err error) {
+	if !errors.Is(err, dpop.ErrInvalidProof) && !errors.Is(err, errDPoPDisabled) {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "failed to verify DPoP proof"})
+		return
+	}
+	c.Header("WWW-Authenticate", `DPoP error="invalid_dpop_proof", algs="ES256 RS256 PS256 EdDSA"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+}
+
+// BindDPoP verifies the optional DPoP proof on token endpoints (login, two-factor
+// verification and refresh) and passes its key to auth-service, so the issued tokens
+// are bound to it.
+func BindDPoP(v *DPoPVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Header.Del(dpopKeyHeader)
+		jkt, err := v.proofKey(c, "")
+		if err != nil {
+			abortDPoP(c, err)
+			return
+		}
+		if jkt != "" {
+			c.Request.Header.Set(dpopKeyHeader, jkt)
+		}
+		c.Next()
+	}
+}
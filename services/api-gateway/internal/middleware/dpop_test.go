@@ -0,0 +1,278 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"seungpyo.lee/PersonalWebSite/pkg/dpop"
+	"seungpyo.lee/PersonalWebSite/pkg/jwt"
+)
+
+const testPublicURL = "https://blog.example.com/api"
+
+// memoryReplayCache is an in-memory dpop.ReplayCache.
+type memoryReplayCache struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (m *memoryReplayCache) Remember(key string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.seen == nil {
+		m.seen = map[string]bool{}
+	}
+	if m.seen[key] {
+		return false, nil
+	}
+	m.seen[key] = true
+	return true, nil
+}
+
+// dpopKey is a client key that signs DPoP proofs.
+type dpopKey struct {
+	private *ecdsa.PrivateKey
+	jwk     map[string]interface{}
+	jkt     string
+	counter int
+}
+
+func newDPoPKey(t *testing.T) *dpopKey {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	x := base64.RawURLEncoding.EncodeToString(private.X.FillBytes(make([]byte, 32)))
+	y := base64.RawURLEncoding.EncodeToString(private.Y.FillBytes(make([]byte, 32)))
+	k := &dpopKey{private: private, jwk: map[string]interface{}{"kty": "EC", "crv": "P-256", "x": x, "y": y}}
+	proof := k.proof(t, http.MethodGet, testPublicURL+"/", "")
+	p, err := dpop.Verify(proof, http.MethodGet, testPublicURL+"/", "", time.Now(), time.Minute)
+	if err != nil {
+		t.Fatalf("verify own proof: %v", err)
+	}
+	k.jkt = p.JKT
+	return k
+}
+
+// proof signs a fresh proof for method and htu, hashing accessToken into ath when set.
+func (k *dpopKey) proof(t *testing.T, method, htu, accessToken string) string {
+	t.Helper()
+	k.counter++
+	claims := jwtlib.MapClaims{"htm": method, "htu": htu, "jti": "jti-" + strconv.Itoa(k.counter), "iat": time.Now().Unix()}
+	if accessToken != "" {
+		claims["ath"] = dpop.AccessTokenHash(accessToken)
+	}
+	token := jwtlib.NewWithClaims(jwtlib.SigningMethodES256, claims)
+	token.Header["typ"] = dpop.ProofType
+	token.Header["jwk"] = k.jwk
+	signed, err := token.SignedString(k.private)
+	if err != nil {
+		t.Fatalf("sign proof: %v", err)
+	}
+	return signed
+}
+
+func newDPoPRouter(tokenManager jwt.TokenManager, authServiceURL string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	verifier := NewDPoPVerifier(testPublicURL, &memoryReplayCache{})
+	r := gin.New()
+	r.Use(AuthOrRefreshMiddleware(tokenManager, authServiceURL, 15, verifier, nil))
+	r.GET("/posts", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.Request.Header.Get("X-User-Id"), "jkt": c.Request.Header.Get(dpopKeyHeader)})
+	})
+	return r
+}
+
+func boundTokenManager(jkt string) *stubTokenManager {
+	return &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			claims := &jwt.Claims{UserID: 4, Username: "script"}
+			if token == "bound-token" {
+				claims.Confirmation = &jwt.Confirmation{JKT: jkt}
+			}
+			return claims, nil
+		},
+	}
+}
+
+func TestDPoP_BoundTokenWithValidProof(t *testing.T) {
+	key := newDPoPKey(t)
+	r := newDPoPRouter(boundTokenManager(key.jkt), "http://example.com")
+
+	req := httptest.NewRequest(http.MethodGet, "/posts?page=2", nil)
+	req.Header.Set("Authorization", "DPoP bound-token")
+	req.Header.Set("DPoP", key.proof(t, http.MethodGet, testPublicURL+"/posts", "bound-token"))
+	req.Header.Set(dpopKeyHeader, "forged")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d; body=%s", w.Code, w.Body.String())
+	}
+	var body map[string]string
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if body["user_id"] != "4" || body["jkt"] != "" {
+		t.Fatalf("unexpected forwarded headers: %v", body)
+	}
+}
+
+func TestDPoP_RejectsBadProofs(t *testing.T) {
+	key := newDPoPKey(t)
+	other := newDPoPKey(t)
+	r := newDPoPRouter(boundTokenManager(key.jkt), "http://example.com")
+	replayed := key.proof(t, http.MethodGet, testPublicURL+"/posts", "bound-token")
+
+	cases := []struct {
+		name   string
+		scheme string
+		token  string
+		proof  string
+	}{
+		{"missing proof", "DPoP", "bound-token", ""},
+		{"bound token sent as bearer without proof", "Bearer", "bound-token", ""},
+		{"wrong htu", "DPoP", "bound-token", key.proof(t, http.MethodGet, testPublicURL+"/images", "bound-token")},
+		{"wrong htm", "DPoP", "bound-token", key.proof(t, http.MethodPost, testPublicURL+"/posts", "bound-token")},
+		{"wrong ath", "DPoP", "bound-token", key.proof(t, http.MethodGet, testPublicURL+"/posts", "other-token")},
+		{"other key", "DPoP", "bound-token", other.proof(t, http.MethodGet, testPublicURL+"/posts", "bound-token")},
+		{"unbound token with DPoP scheme", "DPoP", "plain-token", key.proof(t, http.MethodGet, testPublicURL+"/posts", "plain-token")},
+		{"first use", "DPoP", "bound-token", replayed},
+		{"replayed proof", "DPoP", "bound-token", replayed},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		req.Header.Set("Authorization", tc.scheme+" "+tc.token)
+		if tc.proof != "" {
+			req.Header.Set("DPoP", tc.proof)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		want := http.StatusUnauthorized
+		if tc.name == "first use" {
+			want = http.StatusOK
+		}
+		if w.Code != want {
+			t.Fatalf("%s: expected %d, got %d; body=%s", tc.name, want, w.Code, w.Body.String())
+		}
+		if want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Fatalf("%s: expected a DPoP challenge", tc.name)
+		}
+	}
+}
+
+func TestDPoP_BoundTokenRejectedWhenDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(AuthOrRefreshMiddleware(boundTokenManager("some-jkt"), "http://example.com", 15, nil, nil))
+	r.GET("/posts", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("Authorization", "Bearer bound-token")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestDPoP_BoundPersonalAccessToken(t *testing.T) {
+	key := newDPoPKey(t)
+	authService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"user_id": 3, "username": "ci", "scopes": "posts:write", "dpop_jkt": key.jkt})
+	}))
+	defer authService.Close()
+	r := newDPoPRouter(boundTokenManager(""), authService.URL)
+
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("Authorization", "Bearer pws_bound")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without proof, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("Authorization", "DPoP pws_bound")
+	req.Header.Set("DPoP", key.proof(t, http.MethodGet, testPublicURL+"/posts", "pws_bound"))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with proof, got %d; body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestDPoP_RefreshForwardsProofKey(t *testing.T) {
+	key := newDPoPKey(t)
+	var gotJKT string
+	authService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotJKT = r.Header.Get(dpopKeyHeader)
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "bound-token"})
+	}))
+	defer authService.Close()
+	tokenManager := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			if token == "expired-token" {
+				return nil, jwt.ErrTokenExpired
+			}
+			return &jwt.Claims{UserID: 4, Confirmation: &jwt.Confirmation{JKT: key.jkt}}, nil
+		},
+	}
+	r := newDPoPRouter(tokenManager, authService.URL)
+
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("Authorization", "DPoP expired-token")
+	req.Header.Set("DPoP", key.proof(t, http.MethodGet, testPublicURL+"/posts", "expired-token"))
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "refresh"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d; body=%s", w.Code, w.Body.String())
+	}
+	if gotJKT != key.jkt {
+		t.Fatalf("expected refresh to carry the proof key, got %q", gotJKT)
+	}
+}
+
+func TestBindDPoP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key := newDPoPKey(t)
+	verifier := NewDPoPVerifier(testPublicURL, &memoryReplayCache{})
+	r := gin.New()
+	r.POST("/auth/password/login", BindDPoP(verifier), func(c *gin.Context) {
+		c.String(http.StatusOK, c.Request.Header.Get(dpopKeyHeader))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/login", nil)
+	req.Header.Set("DPoP", key.proof(t, http.MethodPost, testPublicURL+"/auth/password/login", ""))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != key.jkt {
+		t.Fatalf("expected proof key to be forwarded, got %d %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/password/login", nil)
+	req.Header.Set(dpopKeyHeader, "forged")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "" {
+		t.Fatalf("expected client-supplied key header to be dropped, got %d %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/password/login", nil)
+	req.Header.Set("DPoP", "not-a-proof")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a malformed proof, got %d", w.Code)
+	}
+}
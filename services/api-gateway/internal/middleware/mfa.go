@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireMFA rejects requests whose session did not pass two-factor authentication,
+// i.e. whose access token lacks the mfa claim. Personal access tokens never carry it.
+// It must run after AuthOrRefreshMiddleware.
+func RequireMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("mfa") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "two-factor authentication required", "mfa_required": true})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/jwt"
+)
+
+func TestRequireMFA(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokenManager := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			return &jwt.Claims{UserID: 1, Username: "u", Role: "owner", MFA: token == "mfa"}, nil
+		},
+	}
+	r := gin.New()
+	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15, nil, nil))
+	r.DELETE("/posts/1", RequireMFA(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for token, want := range map[string]int{"mfa": http.StatusNoContent, "plain": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodDelete, "/posts/1", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("token %q: expected %d, got %d", token, want, w.Code)
+		}
+	}
+}
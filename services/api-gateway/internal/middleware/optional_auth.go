@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// identityHeaders are set by auth for the services behind the gateway; a client must
+// never supply them.
+var identityHeaders = []string{"X-User-Id", "X-Username", "X-User-Role", "X-Auth-Method", "X-Token-Scopes"}
+
+// OptionalAuth runs auth for requests carrying an Authorization header and lets the
+// others through anonymously, without any identity headers the client sent. It serves
+// routes open to everyone that treat signed-in users differently.
+func OptionalAuth(auth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			auth(c)
+			return
+		}
+		for _, h := range identityHeaders {
+			c.Request.Header.Del(h)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/jwt"
+)
+
+func TestOptionalAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokenManager := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			if token == "ok" {
+				return &jwt.Claims{UserID: 9, Username: "reader", Role: "reader"}, nil
+			}
+			return nil, errors.New("invalid")
+		},
+	}
+	r := gin.New()
+	r.POST("/comments", OptionalAuth(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15, nil, nil)), func(c *gin.Context) {
+		c.String(http.StatusOK, c.Request.Header.Get("X-User-Id")+"|"+c.Request.Header.Get("X-Username")+"|"+c.Request.Header.Get("X-User-Role"))
+	})
+
+	cases := []struct {
+		name, authorization string
+		wantCode            int
+		wantBody            string
+	}{
+		{"anonymous", "", http.StatusOK, "||"},
+		{"signed in", "Bearer ok", http.StatusOK, "9|reader|reader"},
+		{"bad token", "Bearer bad", http.StatusUnauthorized, ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/comments", nil)
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		// identity headers supplied by the client must not reach the service
+		req.Header.Set("X-User-Id", "1")
+		req.Header.Set("X-Username", "owner")
+		req.Header.Set("X-User-Role", "owner")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.wantCode {
+			t.Fatalf("%s: expected %d, got %d", tc.name, tc.wantCode, w.Code)
+		}
+		if tc.wantBody != "" && w.Body.String() != tc.wantBody {
+			t.Fatalf("%s: expected forwarded identity %q, got %q", tc.name, tc.wantBody, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/pat"
+)
+
+// patIdentity is the identity auth-service resolves a personal access token to.
+type patIdentity struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	Scopes   string `json:"scopes"`
+	TokenID  uint   `json:"token_id"`
+	JKT      string `json:"dpop_jkt"`
+}
+
+// patValidator checks personal access tokens against auth-service /tokens/validate.
+// Results are not cached so revocation and usage statistics take effect immediately.
+type patValidator struct {
+	authServiceURL string
+	client         *http.Client
+}
+
+func newPATValidator(authServiceURL string) *patValidator {
+	return &patValidator{
+		authServiceURL: strings.TrimRight(authServiceURL, "/"),
+		client:         &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// Validate resolves a personal access token, returning a refreshError describing the failure.
+func (v *patValidator) Validate(token string) (*patIdentity, error) {
+	bb, _ := json.Marshal(map[string]string{"token": token})
+	req, err := http.NewRequest("POST", v.authServiceURL+"/tokens/validate", bytes.NewReader(bb))
+	if err != nil {
+		return nil, &refreshError{status: http.StatusBadGateway, message: "failed to validate token"}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := v.client.Do(req)
+	if err != nil || resp == nil {
+		return nil, &refreshError{status: http.StatusBadGateway, message: "failed to validate token"}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &refreshError{status: http.StatusUnauthorized, message: "invalid personal access token"}
+	}
+	var identity patIdentity
+	if err := json.NewDecoder(resp.Body).Decode(&identity); err != nil || identity.UserID == 0 {
+		return nil, &refreshError{status: http.StatusBadGateway, message: "invalid token validation response"}
+	}
+	return &identity, nil
+}
+
+// setPATIdentity injects the identity of a personal access token into the request.
+func setPATIdentity(c *gin.Context, identity *patIdentity) {
+	c.Set("user_id", identity.UserID)
+	c.Set("username", identity.Username)
+	c.Set("role", identity.Role)
+	c.Set("auth_method", "pat")
+	c.Set("scopes", identity.Scopes)
+	c.Request.Header.Set("X-User-Id", strconv.FormatUint(uint64(identity.UserID), 10))
+	c.Request.Header.Set("X-Username", identity.Username)
+	c.Request.Header.Set("X-User-Role", identity.Role)
+	c.Request.Header.Set("X-Auth-Method", "pat")
+	c.Request.Header.Set("X-Token-Scopes", identity.Scopes)
+}
+
+// RequireScope rejects requests authenticated with a personal access token that lacks scope.
+// Browser sessions authenticated with a JWT are not scoped and always pass.
+// It must run after AuthOrRefreshMiddleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") != "pat" {
+			c.Next()
+			return
+		}
+		if !pat.HasScope(c.GetString("scopes"), scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "token lacks scope " + scope})
+			return
+		}
+		c.Next()
+	}
+}
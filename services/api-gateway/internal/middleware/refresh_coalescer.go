@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// refreshResultTTL is how long a successful refresh is reused for requests that
+// arrive with the same (already rotated) refresh token.
+const refreshResultTTL = 10 * time.Second
+
+// refreshResult is the outcome of a single call to auth-service /refresh.
+type refreshResult struct {
+	AccessToken   string
+	RefreshToken  string // rotated refresh token, empty if auth-service did not rotate
+	RefreshMaxAge int
+}
+
+// refreshError carries the HTTP status and message the middleware should abort with.
+type refreshError struct {
+	status  int
+	message string
+}
+
+func (e *refreshError) Error() string { return e.message }
+
+type cachedRefresh struct {
+	result    *refreshResult
+	expiresAt time.Time
+}
+
+// refreshCoalescer deduplicates concurrent refreshes for the same refresh token.
+// Only one request per token reaches auth-service; the others wait for and share
+// its result. Successful results are kept for a short time so requests that were
+// in flight when the token was rotated still get the new access token.
+type refreshCoalescer struct {
+	authServiceURL string
+	client         *http.Client
+	group          singleflight.Group
+
+	mu    sync.Mutex
+	cache map[string]cachedRefresh
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+func newRefreshCoalescer(authServiceURL string) *refreshCoalescer {
+	return &refreshCoalescer{
+		authServiceURL: strings.TrimRight(authServiceURL, "/"),
+		client:         &http.Client{Timeout: 3 * time.Second},
+		cache:          make(map[string]cachedRefresh),
+		ttl:            refreshResultTTL,
+		now:            time.Now,
+	}
+}
+
+// hashRefreshToken returns the cache key for a refresh token so raw tokens are never kept in memory maps.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Refresh returns a new access token for refreshToken, calling auth-service at most once
+// for concurrent callers that share the same token. jkt is the thumbprint of the DPoP key
+// the caller proved possession of, empty for browser sessions.
+func (rc *refreshCoalescer) Refresh(refreshToken, jkt string) (*refreshResult, error) {
+	key := hashRefreshToken(refreshToken) + ":" + jkt
+	if res := rc.cached(key); res != nil {
+		log.Debug("refresh served from cache")
+		return res, nil
+	}
+	v, err, shared := rc.group.Do(key, func() (interface{}, error) {
+		// Another caller may have completed the refresh between the cache check and Do.
+		if res := rc.cached(key); res != nil {
+			return res, nil
+		}
+		res, err := rc.callAuthService(refreshToken, jkt)
+		if err != nil {
+			return nil, err
+		}
+		rc.store(key, res)
+		return res, nil
+	})
+	if shared {
+		log.Debug("refresh coalesced with an in-flight request")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v.(*refreshResult), nil
+}
+
+func (rc *refreshCoalescer) cached(key string) *refreshResult {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.cache[key]
+	if !ok {
+		return nil
+	}
+	if rc.now().After(entry.expiresAt) {
+		delete(rc.cache, key)
+		return nil
+	}
+	return entry.result
+}
+
+func (rc *refreshCoalescer) store(key string, res *refreshResult) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	now := rc.now()
+	// drop expired entries so the map does not grow without bound
+	for k, entry := range rc.cache {
+		if now.After(entry.expiresAt) {
+			delete(rc.cache, k)
+		}
+	}
+	rc.cache[key] = cachedRefresh{result: res, expiresAt: now.Add(rc.ttl)}
+}
+
+// callAuthService performs POST /refresh against auth-service.
+func (rc *refreshCoalescer) callAuthService(refreshToken, jkt string) (*refreshResult, error) {
+	bb, _ := json.Marshal(map[string]string{"refresh_token": refreshToken})
+	req, err := http.NewRequest("POST", rc.authServiceURL+"/refresh", bytes.NewReader(bb))
+	if err != nil {
+		return nil, &refreshError{status: http.StatusBadGateway, message: "failed to refresh token"}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if jkt != "" {
+		req.Header.Set(dpopKeyHeader, jkt)
+	}
+	resp, err := rc.client.Do(req)
+	if err != nil || resp == nil {
+		return nil, &refreshError{status: http.StatusBadGateway, message: "failed to refresh token"}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &refreshError{status: http.StatusUnauthorized, message: "refresh failed"}
+	}
+	var respBody map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, &refreshError{status: http.StatusBadGateway, message: "invalid refresh response"}
+	}
+	tokenVal, ok := respBody["token"].(string)
+	if !ok || tokenVal == "" {
+		return nil, &refreshError{status: http.StatusBadGateway, message: "no token in refresh response"}
+	}
+	res := &refreshResult{AccessToken: tokenVal}
+	// auth-service rotates the refresh token and returns it as a cookie
+	for _, ck := range resp.Cookies() {
+		if ck.Name == "refresh_token" && ck.Value != "" {
+			res.RefreshToken = ck.Value
+			res.RefreshMaxAge = ck.MaxAge
+		}
+	}
+	return res, nil
+}
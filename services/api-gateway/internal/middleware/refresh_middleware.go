@@ -1,25 +1,37 @@
 package middleware
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/introspect"
 	"seungpyo.lee/PersonalWebSite/pkg/jwt"
 	"seungpyo.lee/PersonalWebSite/pkg/logger"
+	"seungpyo.lee/PersonalWebSite/pkg/pat"
 )
 
 var log = logger.New("debug")
 
+// SessionChecker asks auth-service whether a token is still active, such as an
+// *introspect.Client.
+type SessionChecker interface {
+	Introspect(token string) (*introspect.Result, error)
+}
+
 // AuthOrRefreshMiddleware validates access token; if expired, it calls auth-service /refresh
 // to obtain a new access token, sets it as a cookie, updates the request Authorization header,
-// and injects X-User-Id/X-Username into the request headers.
-func AuthOrRefreshMiddleware(tokenManager jwt.TokenManager, authServiceURL string, accessTokenTTLMinutes int) gin.HandlerFunc {
+// and injects X-User-Id/X-Username/X-User-Role into the request headers. Bearer tokens carrying the
+// personal access token prefix are validated against auth-service instead. Tokens bound to a
+// DPoP key are only accepted with a fresh proof from that key, checked by dpopVerifier; a nil
+// verifier disables DPoP and rejects bound tokens. Valid access tokens are also checked
+// with sessions, so a session revoked by logout or by auth-service is refused before
+// its tokens expire; a nil checker trusts the signature and expiry alone.
+func AuthOrRefreshMiddleware(tokenManager jwt.TokenManager, authServiceURL string, accessTokenTTLMinutes int, dpopVerifier *DPoPVerifier, sessions SessionChecker) gin.HandlerFunc {
+	refresher := newRefreshCoalescer(authServiceURL)
+	patChecker := newPATValidator(authServiceURL)
 	return func(c *gin.Context) {
 		// prevent multiple refresh attempts for the same request
 		log.Debug("AuthOrRefreshMiddleware invoked")
@@ -27,21 +39,72 @@ func AuthOrRefreshMiddleware(tokenManager jwt.TokenManager, authServiceURL strin
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token refresh failed previously"})
 			return
 		}
-		header := c.GetHeader("Authorization")
-		if header == "" || !strings.HasPrefix(header, "Bearer ") {
+		scheme, tokenString, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !ok || (scheme != "Bearer" && scheme != "DPoP") {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid Authorization header"})
 			return
 		}
-		tokenString := strings.TrimPrefix(header, "Bearer ")
 		tokenString = strings.TrimSpace(tokenString)
+		// never trust scope, role or key headers supplied by the client
+		c.Request.Header.Del("X-Token-Scopes")
+		c.Request.Header.Del("X-User-Role")
+		c.Request.Header.Del(dpopKeyHeader)
+		jkt, err := dpopVerifier.proofKey(c, tokenString)
+		if err != nil {
+			abortDPoP(c, err)
+			return
+		}
+		if pat.IsPersonalAccessToken(tokenString) {
+			identity, err := patChecker.Validate(tokenString)
+			if err != nil {
+				var rerr *refreshError
+				if errors.As(err, &rerr) {
+					c.AbortWithStatusJSON(rerr.status, gin.H{"error": rerr.message})
+					return
+				}
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid personal access token"})
+				return
+			}
+			if err := dpopVerifier.checkBinding(scheme, identity.JKT, jkt); err != nil {
+				abortDPoP(c, err)
+				return
+			}
+			setPATIdentity(c, identity)
+			c.Next()
+			return
+		}
 		claims, err := tokenManager.ValidateAccessToken(tokenString)
 		if err == nil {
 			// valid
 			log.Debug("access token valid")
+			if err := dpopVerifier.checkBinding(scheme, claims.BoundKey(), jkt); err != nil {
+				abortDPoP(c, err)
+				return
+			}
+			// the role claim can be stale until the next refresh; introspection reports
+			// the user's current role
+			userRole := claims.Role
+			if sessions != nil {
+				result, err := sessions.Introspect(tokenString)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "failed to check session"})
+					return
+				}
+				if !result.Active {
+					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+					return
+				}
+				userRole = result.Role
+			}
 			c.Set("user_id", claims.UserID)
 			c.Set("username", claims.Username)
+			c.Set("role", userRole)
+			c.Set("mfa", claims.MFA)
+			c.Set("auth_method", "jwt")
 			c.Request.Header.Set("X-User-Id", strconv.FormatUint(uint64(claims.UserID), 10))
 			c.Request.Header.Set("X-Username", claims.Username)
+			c.Request.Header.Set("X-User-Role", userRole)
+			c.Request.Header.Set("X-Auth-Method", "jwt")
 			c.Next()
 			return
 		}
@@ -58,36 +121,27 @@ func AuthOrRefreshMiddleware(tokenManager jwt.TokenManager, authServiceURL strin
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "refresh token required"})
 			return
 		}
-		// call auth-service /refresh
-		body := map[string]string{"refresh_token": refreshToken}
-		bb, _ := json.Marshal(body)
-		client := &http.Client{Timeout: 3 * time.Second}
-		req, _ := http.NewRequest("POST", strings.TrimRight(authServiceURL, "/")+"/refresh", bytes.NewReader(bb))
-		req.Header.Set("Content-Type", "application/json")
-		resp, err := client.Do(req)
-		if err != nil || resp == nil {
+		// call auth-service /refresh; concurrent requests carrying the same
+		// refresh token share a single upstream call. A refresh token bound to a
+		// DPoP key is only honoured with a proof from that key.
+		res, err := refresher.Refresh(refreshToken, jkt)
+		if err != nil {
+			var rerr *refreshError
+			if errors.As(err, &rerr) {
+				c.AbortWithStatusJSON(rerr.status, gin.H{"error": rerr.message})
+				return
+			}
 			c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "failed to refresh token"})
 			return
 		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "refresh failed"})
-			return
-		}
-		var respBody map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
-			c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "invalid refresh response"})
-			return
-		}
-		tokenVal, ok := respBody["token"].(string)
-		if !ok || tokenVal == "" {
-			c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "no token in refresh response"})
-			return
+		tokenVal := res.AccessToken
+		if res.RefreshToken != "" {
+			c.SetCookie("refresh_token", res.RefreshToken, res.RefreshMaxAge, "/", "", false, true)
 		}
 		// set cookie with new access token
 		c.SetCookie("access_token", tokenVal, accessTokenTTLMinutes*60, "/", "", false, true)
 		// update request header and validate to extract claims
-		c.Request.Header.Set("Authorization", "Bearer "+tokenVal)
+		c.Request.Header.Set("Authorization", scheme+" "+tokenVal)
 		// mark request as refreshed to avoid loops
 		c.Request.Header.Set("X-Refreshed", "1")
 		c.Writer.Header().Set("X-Refreshed", "1")
@@ -96,12 +150,21 @@ func AuthOrRefreshMiddleware(tokenManager jwt.TokenManager, authServiceURL strin
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "refreshed token invalid"})
 			return
 		}
+		if err := dpopVerifier.checkBinding(scheme, newClaims.BoundKey(), jkt); err != nil {
+			abortDPoP(c, err)
+			return
+		}
 
 		// inject claims
 		c.Set("user_id", newClaims.UserID)
 		c.Set("username", newClaims.Username)
+		c.Set("role", newClaims.Role)
+		c.Set("mfa", newClaims.MFA)
+		c.Set("auth_method", "jwt")
 		c.Request.Header.Set("X-User-Id", strconv.FormatUint(uint64(newClaims.UserID), 10))
 		c.Request.Header.Set("X-Username", newClaims.Username)
+		c.Request.Header.Set("X-User-Role", newClaims.Role)
+		c.Request.Header.Set("X-Auth-Method", "jwt")
 		c.Next()
 	}
 }
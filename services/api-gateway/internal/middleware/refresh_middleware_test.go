@@ -7,10 +7,13 @@ import (
 	"net/http/httptest"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/introspect"
 	"seungpyo.lee/PersonalWebSite/pkg/jwt"
 )
 
@@ -18,7 +21,10 @@ type stubTokenManager struct {
 	validateAccessTokenFn func(token string) (*jwt.Claims, error)
 }
 
-func (s *stubTokenManager) GenerateToken(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+func (s *stubTokenManager) GenerateToken(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+	return "", "", errors.New("not implemented")
+}
+func (s *stubTokenManager) GenerateSessionToken(session jwt.Session, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
 	return "", "", errors.New("not implemented")
 }
 func (s *stubTokenManager) RefreshToken(refreshToken string, accessTokenExp time.Duration) (string, error) {
@@ -36,6 +42,12 @@ func (s *stubTokenManager) RevokeToken(tokenString string, expiresIn time.Durati
 func (s *stubTokenManager) IsTokenRevoked(tokenString string) (bool, error) {
 	return false, errors.New("not implemented")
 }
+func (s *stubTokenManager) RevokeSession(sessionID string, expiresIn time.Duration) error {
+	return errors.New("not implemented")
+}
+func (s *stubTokenManager) IsSessionRevoked(sessionID string) (bool, error) {
+	return false, errors.New("not implemented")
+}
 
 func TestAuthOrRefreshMiddleware_ValidToken(t *testing.T) {
 	t.Helper()
@@ -51,7 +63,7 @@ func TestAuthOrRefreshMiddleware_ValidToken(t *testing.T) {
 	}
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://127.0.0.1:65534", 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://127.0.0.1:65534", 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"user_id":  c.Request.Header.Get("X-User-Id"),
@@ -80,6 +92,95 @@ func TestAuthOrRefreshMiddleware_ValidToken(t *testing.T) {
 	}
 }
 
+type stubSessionChecker struct {
+	result *introspect.Result
+	err    error
+	tokens []string
+}
+
+func (s *stubSessionChecker) Introspect(token string) (*introspect.Result, error) {
+	s.tokens = append(s.tokens, token)
+	return s.result, s.err
+}
+
+func TestAuthOrRefreshMiddleware_ChecksSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokenManager := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			return &jwt.Claims{UserID: 7, Username: "alice"}, nil
+		},
+	}
+
+	tests := []struct {
+		name     string
+		sessions *stubSessionChecker
+		want     int
+	}{
+		{name: "active", sessions: &stubSessionChecker{result: &introspect.Result{Active: true, Subject: "7"}}, want: http.StatusOK},
+		{name: "revoked", sessions: &stubSessionChecker{result: &introspect.Result{Active: false}}, want: http.StatusUnauthorized},
+		{name: "auth-service down", sessions: &stubSessionChecker{err: errors.New("connection refused")}, want: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(AuthOrRefreshMiddleware(tokenManager, "http://127.0.0.1:65534", 15, nil, tt.sessions))
+			r.GET("/protected", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			req.Header.Set("Authorization", "Bearer valid-token")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d; body=%s", tt.want, w.Code, w.Body.String())
+			}
+			if len(tt.sessions.tokens) != 1 || tt.sessions.tokens[0] != "valid-token" {
+				t.Fatalf("expected the access token to be introspected once, got %v", tt.sessions.tokens)
+			}
+		})
+	}
+}
+
+func TestAuthOrRefreshMiddleware_UsesIntrospectedRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// the token was issued while alice was an owner; she has since been demoted
+	tokenManager := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			return &jwt.Claims{UserID: 7, Username: "alice", Role: "owner"}, nil
+		},
+	}
+	sessions := &stubSessionChecker{result: &introspect.Result{Active: true, Subject: "7", Role: "reader"}}
+
+	r := gin.New()
+	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://127.0.0.1:65534", 15, nil, sessions))
+	r.GET("/profile", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("role")+" "+c.Request.Header.Get("X-User-Role"))
+	})
+	r.GET("/admin/users", RequireRole("owner"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+	req.Header.Set("Authorization", "Bearer stale-token")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "reader reader" {
+		t.Fatalf("expected the introspected role to be used, got %d %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+	req.Header.Set("Authorization", "Bearer stale-token")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected a demoted owner to be refused, got %d", w.Code)
+	}
+}
+
 func TestAuthOrRefreshMiddleware_ExpiredTokenRefreshes(t *testing.T) {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
@@ -117,7 +218,7 @@ func TestAuthOrRefreshMiddleware_ExpiredTokenRefreshes(t *testing.T) {
 	defer authService.Close()
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"user_id":       c.Request.Header.Get("X-User-Id"),
@@ -168,7 +269,7 @@ func TestAuthOrRefreshMiddleware_MissingAuthorizationHeader(t *testing.T) {
 	}
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -187,7 +288,7 @@ func TestAuthOrRefreshMiddleware_InvalidAuthorizationPrefix(t *testing.T) {
 	}
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -212,7 +313,7 @@ func TestAuthOrRefreshMiddleware_EmptyBearerToken(t *testing.T) {
 	}
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -234,7 +335,7 @@ func TestAuthOrRefreshMiddleware_InvalidAccessToken_NonExpired(t *testing.T) {
 	}
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -254,7 +355,7 @@ func TestAuthOrRefreshMiddleware_ExpiredTokenWithoutRefreshCookie(t *testing.T)
 	}
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -274,7 +375,7 @@ func TestAuthOrRefreshMiddleware_StopsWhenAlreadyRefreshed(t *testing.T) {
 	}
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -295,7 +396,7 @@ func TestAuthOrRefreshMiddleware_RefreshNetworkFailure(t *testing.T) {
 	}
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://127.0.0.1:1", 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://127.0.0.1:1", 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -320,7 +421,7 @@ func TestAuthOrRefreshMiddleware_RefreshNon200(t *testing.T) {
 	defer authService.Close()
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -346,7 +447,7 @@ func TestAuthOrRefreshMiddleware_InvalidRefreshResponseJSON(t *testing.T) {
 	defer authService.Close()
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -371,7 +472,7 @@ func TestAuthOrRefreshMiddleware_RefreshResponseWithoutToken(t *testing.T) {
 	defer authService.Close()
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -401,7 +502,7 @@ func TestAuthOrRefreshMiddleware_RefreshedTokenInvalid(t *testing.T) {
 	defer authService.Close()
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -436,7 +537,7 @@ func TestAuthOrRefreshMiddleware_RefreshRequestIncludesTokenBody(t *testing.T) {
 	defer authService.Close()
 
 	r := gin.New()
-	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15))
+	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15, nil, nil))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -449,3 +550,201 @@ func TestAuthOrRefreshMiddleware_RefreshRequestIncludesTokenBody(t *testing.T) {
 		t.Fatalf("expected refresh token in body, got %q", got)
 	}
 }
+
+func TestAuthOrRefreshMiddleware_ConcurrentRefreshesAreCoalesced(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokenManager := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			if token == "expired-token" {
+				return nil, jwt.ErrTokenExpired
+			}
+			return &jwt.Claims{UserID: 5, Username: "carol"}, nil
+		},
+	}
+
+	var calls int32
+	release := make(chan struct{})
+	authService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		http.SetCookie(w, &http.Cookie{Name: "refresh_token", Value: "rotated-refresh", MaxAge: 3600})
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "new-token"})
+	}))
+	defer authService.Close()
+
+	r := gin.New()
+	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15, nil, nil))
+	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	const n = 5
+	var wg sync.WaitGroup
+	codes := make([]int, n)
+	cookies := make([]string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			req.Header.Set("Authorization", "Bearer expired-token")
+			req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "shared-refresh"})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			codes[i] = w.Code
+			cookies[i] = strings.Join(w.Header().Values("Set-Cookie"), ";")
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected a single upstream refresh, got %d", got)
+	}
+	for i := 0; i < n; i++ {
+		if codes[i] != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, codes[i])
+		}
+		if !strings.Contains(cookies[i], "refresh_token=rotated-refresh") {
+			t.Fatalf("request %d: expected rotated refresh cookie, got %q", i, cookies[i])
+		}
+	}
+}
+
+func TestAuthOrRefreshMiddleware_RecentRefreshIsReused(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokenManager := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			if token == "expired-token" {
+				return nil, jwt.ErrTokenExpired
+			}
+			return &jwt.Claims{UserID: 5, Username: "carol"}, nil
+		},
+	}
+	var calls int32
+	authService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a second call with the same (rotated) token would be rejected upstream
+		if atomic.AddInt32(&calls, 1) > 1 {
+			http.Error(w, "revoked", http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "new-token"})
+	}))
+	defer authService.Close()
+
+	r := gin.New()
+	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15, nil, nil))
+	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		req.Header.Set("Authorization", "Bearer expired-token")
+		req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "same-refresh"})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("attempt %d: expected 200, got %d", i, w.Code)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected cached refresh result to be reused, got %d upstream calls", got)
+	}
+}
+
+func TestRefreshCoalescer_ExpiredCacheEntryIsDropped(t *testing.T) {
+	rc := newRefreshCoalescer("http://example.com")
+	now := time.Now()
+	rc.now = func() time.Time { return now }
+	rc.store(hashRefreshToken("tok"), &refreshResult{AccessToken: "a"})
+	if rc.cached(hashRefreshToken("tok")) == nil {
+		t.Fatalf("expected cached result")
+	}
+	now = now.Add(refreshResultTTL + time.Second)
+	if rc.cached(hashRefreshToken("tok")) != nil {
+		t.Fatalf("expected expired entry to be dropped")
+	}
+}
+
+func TestAuthOrRefreshMiddleware_PersonalAccessToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokenManager := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			t.Fatalf("personal access tokens must not be parsed as JWTs")
+			return nil, nil
+		},
+	}
+	authService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tokens/validate" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req["token"] != "pws_good" {
+			http.Error(w, "invalid", http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"user_id": 3, "username": "ci", "scopes": "posts:write"})
+	}))
+	defer authService.Close()
+
+	r := gin.New()
+	r.Use(AuthOrRefreshMiddleware(tokenManager, authService.URL, 15, nil, nil))
+	r.POST("/posts", RequireScope("posts:write"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"user_id": c.Request.Header.Get("X-User-Id"),
+			"method":  c.Request.Header.Get("X-Auth-Method"),
+			"scopes":  c.Request.Header.Get("X-Token-Scopes"),
+		})
+	})
+	r.POST("/images", RequireScope("images:write"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodPost, "/posts", nil)
+	req.Header.Set("Authorization", "Bearer pws_good")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d; body=%s", w.Code, w.Body.String())
+	}
+	var body map[string]string
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if body["user_id"] != "3" || body["method"] != "pat" || body["scopes"] != "posts:write" {
+		t.Fatalf("unexpected injected identity: %v", body)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/images", nil)
+	req.Header.Set("Authorization", "Bearer pws_good")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for missing scope, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/posts", nil)
+	req.Header.Set("Authorization", "Bearer pws_bad")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for rejected token, got %d", w.Code)
+	}
+}
+
+func TestRequireScope_JWTSessionIsUnscoped(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokenManager := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			return &jwt.Claims{UserID: 1, Username: "owner"}, nil
+		},
+	}
+	r := gin.New()
+	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15, nil, nil))
+	r.POST("/images", RequireScope("images:write"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodPost, "/images", nil)
+	req.Header.Set("Authorization", "Bearer session-jwt")
+	req.Header.Set("X-Token-Scopes", "forged")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected JWT session to pass scope check, got %d", w.Code)
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request correlation ID to services and back to the client.
+const RequestIDHeader = "X-Request-Id"
+
+// RequestID ensures every request has a correlation ID. A well-formed ID supplied by
+// the client or an upstream proxy is kept; otherwise a random one is generated.
+// The ID is forwarded to services and echoed in the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Request.Header.Set(RequestIDHeader, id)
+		c.Writer.Header().Set(RequestIDHeader, id)
+		c.Set("request_id", id)
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// validRequestID accepts short IDs made of URL-safe characters so client input
+// cannot inject arbitrary content into logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, c.Request.Header.Get(RequestIDHeader))
+	})
+
+	cases := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"missing", "", false},
+		{"well formed", "abc-123_x.y", true},
+		{"unsafe characters", "bad id\nInjected: 1", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.incoming != "" {
+				req.Header.Set(RequestIDHeader, tc.incoming)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			forwarded := w.Body.String()
+			if forwarded == "" || w.Header().Get(RequestIDHeader) != forwarded {
+				t.Fatalf("expected forwarded id %q to be echoed, got %q", forwarded, w.Header().Get(RequestIDHeader))
+			}
+			if tc.keep && forwarded != tc.incoming {
+				t.Fatalf("expected incoming id to be kept, got %q", forwarded)
+			}
+			if !tc.keep && forwarded == tc.incoming {
+				t.Fatalf("expected a generated id, got %q", forwarded)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole rejects requests whose authenticated user holds none of roles.
+// Tokens issued before roles existed carry no role and are rejected until refreshed.
+// It must run after AuthOrRefreshMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		have := c.GetString("role")
+		for _, r := range roles {
+			if have == r {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient role"})
+	}
+}
+
+// RejectPAT rejects requests authenticated with a personal access token, whatever its
+// scopes, for routes that only a signed-in session may use. It must run after
+// AuthOrRefreshMiddleware.
+func RejectPAT() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") == "pat" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "personal access tokens cannot use the admin API"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/jwt"
+)
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokenManager := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			return &jwt.Claims{UserID: 1, Username: "u", Role: token}, nil
+		},
+	}
+	r := gin.New()
+	r.Use(AuthOrRefreshMiddleware(tokenManager, "http://example.com", 15, nil, nil))
+	r.POST("/posts", RequireRole("owner", "editor"), func(c *gin.Context) {
+		c.String(http.StatusOK, c.Request.Header.Get("X-User-Role"))
+	})
+
+	cases := []struct {
+		role string
+		want int
+	}{
+		{"owner", http.StatusOK},
+		{"editor", http.StatusOK},
+		{"reader", http.StatusForbidden},
+		{"", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/posts", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.role)
+		// a client-supplied role header must not be trusted
+		req.Header.Set("X-User-Role", "owner")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("role %q: expected %d, got %d", tc.role, tc.want, w.Code)
+		}
+		if w.Code == http.StatusOK && w.Body.String() != tc.role {
+			t.Fatalf("role %q: expected forwarded X-User-Role, got %q", tc.role, w.Body.String())
+		}
+	}
+}
+
+func TestRejectPAT(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		method string
+		want   int
+	}{
+		{"jwt", http.StatusOK},
+		{"pat", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		r := gin.New()
+		r.GET("/admin/users", func(c *gin.Context) { c.Set("auth_method", tc.method) }, RejectPAT(), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+		if w.Code != tc.want {
+			t.Fatalf("auth method %q: expected %d, got %d", tc.method, tc.want, w.Code)
+		}
+	}
+}
@@ -1,20 +1,33 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
 	"seungpyo.lee/PersonalWebSite/pkg/jwt"
 	"seungpyo.lee/PersonalWebSite/pkg/logger"
+	"seungpyo.lee/PersonalWebSite/pkg/migrate"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/adapter"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/config"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/handler"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/oauth"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/repository"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/service"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/migrations"
 )
 
 func main() {
@@ -30,10 +43,20 @@ func main() {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
 
-	// Auto-migrate User model
-	if err := db.AutoMigrate(&domain.User{}); err != nil {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		log.Fatalf("failed to load migrations: %v", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Command(context.Background(), migrator, os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	if err := migrate.OnStartup(context.Background(), migrator, conf.MigrationMode); err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
+	auditStore := audit.NewStore(db, "auth-service")
 
 	repo := repository.NewUserRepository(db)
 	redisUrl := fmt.Sprintf("%s:%s", conf.RedisDBURL, conf.RedisDBPort)
@@ -44,10 +67,68 @@ func main() {
 	})
 
 	tokenManager := jwt.NewTokenManager(conf.JWTSecretKey, redisClient)
-	svc := service.NewAuthService(repo, tokenManager)
+	var mailer domain.MailSender
+	if conf.SMTP.Host != "" {
+		mailer = adapter.NewSMTPMailSender(conf.SMTP)
+	}
+	attempts := repository.NewLoginAttemptStore(redisClient)
+	mfaRepo := repository.NewMFARepository(db)
+	mfaSvc := service.NewMFAService(mfaRepo, repo, repository.NewMFAChallengeStore(redisClient), attempts, tokenManager, *conf)
+	passwordSvc := service.NewPasswordService(repo, tokenManager, attempts, mailer, mfaSvc, *conf)
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "bootstrap-owner":
+			err = bootstrapOwner(os.Args[2:], passwordSvc, os.Stdin)
+		case "reset-mfa":
+			err = resetMFA(os.Args[2:], repo, mfaRepo)
+		default:
+			err = fmt.Errorf("unknown command %q", os.Args[1])
+		}
+		if err != nil {
+			log.Fatalf("%s: %v", os.Args[1], err)
+		}
+		return
+	}
+	rotations := repository.NewRefreshRotationStore(redisClient)
+	providers, errs := oauth.BuildRegistry(context.Background(), conf, &http.Client{Timeout: 10 * time.Second})
+	for _, err := range errs {
+		log.Printf("oauth provider disabled: %v", err)
+	}
+	log.Printf("oauth providers: %v", providers.Names())
+	states := repository.NewOAuthStateStore(redisClient)
+	svc := service.NewAuthService(repo, tokenManager, rotations, states, providers, mfaSvc)
 	h := handler.NewAuthHandler(svc, conf, tokenManager)
+	h.Audit = auditStore
+	h.MFA = mfaSvc
+	if conf.PasswordLoginEnabled {
+		h.Passwords = passwordSvc
+	}
+	tokenSvc := service.NewTokenService(repository.NewTokenRepository(db), repo)
+	th := handler.NewTokenHandler(tokenSvc)
+	th.Audit = auditStore
+	ih := handler.NewIntrospectionHandler(service.NewIntrospectionService(tokenManager, repo, tokenSvc), conf.IntrospectionClients)
+	adminSvc := service.NewUserAdminService(repo)
+	ah := handler.NewAuditHandler(auditStore, adminSvc)
+	uh := handler.NewUserAdminHandler(adminSvc)
+	uh.Audit = auditStore
+	var avatars domain.AvatarStore
+	if conf.ImageServiceURL != "" {
+		avatars = adapter.NewAvatarAdapter(conf.ImageServiceURL, &http.Client{Timeout: 10 * time.Second})
+	}
+	profileSvc := service.NewProfileService(repo, avatars)
+	h.Profiles = profileSvc
+	ph := handler.NewProfileHandler(profileSvc)
+	ph.Audit = auditStore
+	mh := handler.NewMFAHandler(mfaSvc)
+	mh.Audit = auditStore
 
 	r := gin.Default()
+	// login lockouts and audit entries key on the client IP the gateway forwards
+	if err := r.SetTrustedProxies(conf.TrustedProxies); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(audit.Middleware())
 	logger := logger.New("main")
 	r.GET("/health", func(c *gin.Context) {
 		logger.Info("health check OK")
@@ -55,12 +136,101 @@ func main() {
 			"status": "ok",
 		})
 	})
-	r.GET("/oauth/google/login", h.OAuthGoogleLogin)
-	r.GET("/oauth/google/callback", h.OAuthGoogleCallback)
+	r.GET("/oauth/providers", h.ListProviders)
+	r.GET("/oauth/:provider/login", h.OAuthLogin)
+	r.GET("/oauth/:provider/callback", h.OAuthCallback)
 	r.GET("/users/:id", h.GetUser)
+	r.PUT("/users/:id", ph.UpdateProfile)
+	r.GET("/users/:id/profile", ph.GetProfile)
 	r.POST("/refresh", h.Refresh)
+	r.POST("/logout", h.Logout)
+	r.POST("/password/login", h.PasswordLogin)
+	r.POST("/password/register", h.Register)
+	r.POST("/password/forgot", h.ForgotPassword)
+	r.POST("/password/reset", h.ResetPassword)
+	r.POST("/mfa/verify", h.VerifyMFA)
+	r.GET("/mfa", mh.GetStatus)
+	r.POST("/mfa/totp", mh.BeginEnrollment)
+	r.POST("/mfa/totp/confirm", mh.ConfirmEnrollment)
+	r.DELETE("/mfa/totp", mh.Disable)
+	r.POST("/mfa/recovery-codes", mh.RegenerateRecoveryCodes)
+	r.POST("/tokens", th.CreateToken)
+	r.GET("/tokens", th.ListTokens)
+	r.DELETE("/tokens/:id", th.RevokeToken)
+	r.POST("/tokens/validate", th.ValidateToken)
+	r.POST("/introspect", ih.Introspect)
+	r.GET("/admin/audit-logs", ah.ListAuditLogs)
+	r.GET("/admin/users", uh.ListUsers)
+	r.POST("/admin/users", uh.CreateUser)
+	r.PUT("/admin/users/:id/role", uh.UpdateRole)
+	r.DELETE("/admin/users/:id", uh.DeleteUser)
 
 	if err := r.Run(":" + conf.ServerPort); err != nil {
 		log.Fatalf("failed to start server: %v", err)
 	}
 }
+
+// newMigrator prepares auth-service's versioned migrations for db.
+func newMigrator(db *gorm.DB) (*migrate.Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	ms, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sqlDB, "auth-service", ms), nil
+}
+
+// bootstrapOwner implements `auth-service bootstrap-owner -email <email> [-username <name>]`.
+// It creates or promotes the owner account and sets its password, read from
+// OWNER_PASSWORD or else from the first line of stdin.
+func bootstrapOwner(args []string, passwords domain.PasswordService, stdin io.Reader) error {
+	fs := flag.NewFlagSet("bootstrap-owner", flag.ContinueOnError)
+	email := fs.String("email", "", "email of the owner account")
+	username := fs.String("username", "owner", "username when the account is created")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("-email is required")
+	}
+	password, ok := os.LookupEnv("OWNER_PASSWORD")
+	if !ok {
+		fmt.Fprint(os.Stderr, "password: ")
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	user, err := passwords.BootstrapOwner(*email, *username, password)
+	if err != nil {
+		return err
+	}
+	log.Printf("owner %s (user %d) can now sign in with a password", user.Email, user.ID)
+	return nil
+}
+
+// resetMFA implements `auth-service reset-mfa -email <email>`. It turns two-factor
+// authentication off for an account that lost its authenticator and recovery codes.
+func resetMFA(args []string, users domain.UserRepository, mfa domain.MFARepository) error {
+	fs := flag.NewFlagSet("reset-mfa", flag.ContinueOnError)
+	email := fs.String("email", "", "email of the account")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("-email is required")
+	}
+	user, err := users.GetByEmail(strings.ToLower(strings.TrimSpace(*email)))
+	if err != nil {
+		return err
+	}
+	if err := mfa.DeleteTOTP(user.ID); err != nil {
+		return err
+	}
+	log.Printf("two-factor authentication is off for %s (user %d)", user.Email, user.ID)
+	return nil
+}
@@ -3,14 +3,17 @@
 package integration
 
 import (
+	"context"
 	"os"
 	"strings"
 	"testing"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"seungpyo.lee/PersonalWebSite/pkg/migrate"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/repository"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/migrations"
 )
 
 func TestUserRepository_PostgresIntegration(t *testing.T) {
@@ -23,7 +26,15 @@ func TestUserRepository_PostgresIntegration(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to connect postgres: %v", err)
 	}
-	if err := db.AutoMigrate(&domain.User{}); err != nil {
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql.DB: %v", err)
+	}
+	ms, err := migrate.Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrate.New(sqlDB, "auth-service", ms).Up(context.Background()); err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
 
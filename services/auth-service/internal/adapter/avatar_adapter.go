@@ -0,0 +1,104 @@
+// Package adapter talks to the other services auth-service depends on.
+package adapter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+)
+
+// maxPictureBytes caps the size of a provider picture we are willing to import.
+const maxPictureBytes = 2 << 20
+
+// avatarExtensions maps accepted image types to the file extension img-service
+// derives the blob content type from.
+var avatarExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+type avatarAdapter struct {
+	imageServiceURL string
+	client          *http.Client
+}
+
+// NewAvatarAdapter creates an AvatarStore that stores avatars through img-service.
+func NewAvatarAdapter(imageServiceURL string, client *http.Client) domain.AvatarStore {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &avatarAdapter{imageServiceURL: strings.TrimRight(imageServiceURL, "/"), client: client}
+}
+
+type uploadAvatarRequest struct {
+	Filename string `json:"filename"`
+	UserID   string `json:"userId"`
+	Data     string `json:"data"`
+}
+
+type uploadAvatarResponse struct {
+	URL string
+}
+
+// UploadAvatar sends a data URL image to img-service and returns its path.
+func (a *avatarAdapter) UploadAvatar(userID uint, dataURL string) (string, error) {
+	contentType := strings.TrimPrefix(strings.SplitN(dataURL, ";", 2)[0], "data:")
+	ext, ok := avatarExtensions[contentType]
+	if !ok {
+		return "", domain.ErrInvalidAvatar
+	}
+	body, err := json.Marshal(uploadAvatarRequest{Filename: "avatar" + ext, UserID: fmt.Sprintf("%d", userID), Data: dataURL})
+	if err != nil {
+		return "", err
+	}
+	resp, err := a.client.Post(a.imageServiceURL+"/avatar-image", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("img-service returned status %d", resp.StatusCode)
+	}
+	var out uploadAvatarResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	return out.URL, nil
+}
+
+// ImportAvatar downloads a provider picture and stores it through img-service, so
+// pages do not hotlink the provider and the picture survives changes there.
+func (a *avatarAdapter) ImportAvatar(userID uint, pictureURL string) (string, error) {
+	if !strings.HasPrefix(pictureURL, "https://") && !strings.HasPrefix(pictureURL, "http://") {
+		return "", fmt.Errorf("unsupported picture url %q", pictureURL)
+	}
+	resp, err := a.client.Get(pictureURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("picture download returned status %d", resp.StatusCode)
+	}
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if _, ok := avatarExtensions[contentType]; !ok {
+		return "", fmt.Errorf("%w: got %q", domain.ErrInvalidAvatar, contentType)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPictureBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxPictureBytes {
+		return "", fmt.Errorf("picture is larger than %d bytes", maxPictureBytes)
+	}
+	return a.UploadAvatar(userID, "data:"+contentType+";base64,"+base64.StdEncoding.EncodeToString(data))
+}
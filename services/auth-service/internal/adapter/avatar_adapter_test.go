@@ -0,0 +1,71 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+)
+
+// newFakeServices serves img-service's /avatar-image and provider pictures under /pic/.
+func newFakeServices(t *testing.T, uploads *[]uploadAvatarRequest) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/avatar-image", func(w http.ResponseWriter, r *http.Request) {
+		var req uploadAvatarRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		*uploads = append(*uploads, req)
+		json.NewEncoder(w).Encode(map[string]string{"URL": req.UserID + "/avatar/x" + req.Filename[strings.LastIndex(req.Filename, "."):]})
+	})
+	mux.HandleFunc("/pic/ok.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("jpeg-bytes"))
+	})
+	mux.HandleFunc("/pic/page.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestImportAvatar(t *testing.T) {
+	var uploads []uploadAvatarRequest
+	srv := newFakeServices(t, &uploads)
+	a := NewAvatarAdapter(srv.URL, srv.Client())
+
+	path, err := a.ImportAvatar(7, srv.URL+"/pic/ok.jpg")
+	if err != nil || path != "7/avatar/x.jpg" {
+		t.Fatalf("unexpected result %q err=%v", path, err)
+	}
+	if len(uploads) != 1 || uploads[0].UserID != "7" || uploads[0].Data != "data:image/jpeg;base64,anBlZy1ieXRlcw==" {
+		t.Fatalf("unexpected upload %+v", uploads)
+	}
+
+	if _, err := a.ImportAvatar(7, srv.URL+"/pic/page.html"); !errors.Is(err, domain.ErrInvalidAvatar) {
+		t.Fatalf("expected non-image to be rejected, got %v", err)
+	}
+	if _, err := a.ImportAvatar(7, "file:///etc/passwd"); err == nil {
+		t.Fatalf("expected non-http picture url to be rejected")
+	}
+	if len(uploads) != 1 {
+		t.Fatalf("expected rejected pictures not to be uploaded, got %d uploads", len(uploads))
+	}
+}
+
+func TestUploadAvatar_RejectsUnknownType(t *testing.T) {
+	var uploads []uploadAvatarRequest
+	srv := newFakeServices(t, &uploads)
+	a := NewAvatarAdapter(srv.URL, srv.Client())
+	if _, err := a.UploadAvatar(1, "data:image/svg+xml;base64,PHN2Zz4="); !errors.Is(err, domain.ErrInvalidAvatar) {
+		t.Fatalf("expected ErrInvalidAvatar, got %v", err)
+	}
+}
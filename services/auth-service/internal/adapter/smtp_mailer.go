@@ -0,0 +1,53 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/config"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+)
+
+// sendMail is smtp.SendMail; tests replace it. It upgrades to TLS with STARTTLS when
+// the server offers it.
+var sendMail = smtp.SendMail
+
+type smtpMailer struct {
+	cfg config.SMTPConfig
+}
+
+// NewSMTPMailSender creates a MailSender that delivers through an SMTP relay,
+// authenticating with PLAIN when a username is configured.
+func NewSMTPMailSender(cfg config.SMTPConfig) domain.MailSender {
+	return &smtpMailer{cfg: cfg}
+}
+
+// SendMail sends a plain-text UTF-8 mail to a single recipient.
+func (m *smtpMailer) SendMail(to, subject, body string) error {
+	// Header values must not smuggle in extra headers or recipients
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return errors.New("invalid mail header")
+	}
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	addr := net.JoinHostPort(m.cfg.Host, m.cfg.Port)
+	if err := sendMail(addr, auth, m.cfg.From, []string{to}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package adapter
+
+import (
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/config"
+)
+
+func TestSMTPMailSender(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg string
+	var gotAuth smtp.Auth
+	orig := sendMail
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotAuth, gotFrom, gotTo, gotMsg = addr, a, from, to, string(msg)
+		return nil
+	}
+	t.Cleanup(func() { sendMail = orig })
+
+	m := NewSMTPMailSender(config.SMTPConfig{Host: "smtp.example.com", Port: "587", Username: "u", Password: "p", From: "blog@example.com"})
+	if err := m.SendMail("me@example.com", "Reset your password", "line one\nline two"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAddr != "smtp.example.com:587" || gotAuth == nil || gotFrom != "blog@example.com" || len(gotTo) != 1 || gotTo[0] != "me@example.com" {
+		t.Fatalf("unexpected envelope addr=%q from=%q to=%v", gotAddr, gotFrom, gotTo)
+	}
+	if !strings.Contains(gotMsg, "Subject: Reset your password\r\n") || !strings.HasSuffix(gotMsg, "\r\n\r\nline one\r\nline two") {
+		t.Fatalf("unexpected message %q", gotMsg)
+	}
+
+	if err := m.SendMail("me@example.com\r\nBcc: x@example.com", "hi", "body"); err == nil {
+		t.Fatalf("expected header injection to be rejected")
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"seungpyo.lee/PersonalWebSite/pkg/config"
@@ -13,16 +14,58 @@ type AuthConfig struct {
 	config.GlobalConfig
 	JWTSecretKey            string
 	PostgreConnectionString string
+	MigrationMode           string // DB_MIGRATE: "up" applies migrations at startup, "verify" only checks them
 	RedisDBURL              string
 	RedisDBPort             string
 	RedisDBPassword         string
 	RedisMaxRetries         int
 	RedisPoolSize           int
+	RefreshReuseGraceSecs   int // how long a just-rotated refresh token may be replayed
 	GoogleClientID          string
 	GoogleClientSecret      string
+	OAuthProviders          []OAuthProviderConfig // extra login providers next to Google
+	OwnerEmails             []string              // always signed in as owner
+	AllowedEmails           []string              // may sign up with DefaultRole
+	AllowedHostedDomains    []string              // Google Workspace domains that may sign up with DefaultRole
+	DefaultRole             string                // role of accounts created from the allowlists
+	ImageServiceURL         string                // img-service for avatars; empty disables them
+	PasswordLoginEnabled    bool                  // email+password login next to the providers
+	RegistrationEnabled     bool                  // self-service sign-up for password login
+	LoginMaxFailures        int                   // failed password logins before an account is locked
+	LoginIPMaxFailures      int                   // failed password logins before a client IP is locked
+	LoginLockoutSecs        int                   // how long failures are counted and a lockout lasts
+	PasswordResetTTLMins    int                   // lifetime of password reset links
+	MFAIssuer               string                // name authenticator apps show next to the account
+	IntrospectionClients    map[string]string     // internal client ID -> secret allowed to call /introspect
+	SMTP                    SMTPConfig
 	MYDOMAIN                string
 }
 
+// SMTPConfig configures outgoing mail. An empty Host disables mail.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// OAuthProviderConfig configures one OAuth/OIDC login provider.
+// Claim* fields override the provider type's default claim names.
+type OAuthProviderConfig struct {
+	Name               string
+	Type               string // google, github or oidc
+	ClientID           string
+	ClientSecret       string
+	IssuerURL          string // required for oidc
+	Scopes             []string
+	ClaimSubject       string
+	ClaimEmail         string
+	ClaimEmailVerified string
+	ClaimName          string
+	ClaimAvatar        string
+}
+
 func LoadAuthConfig() *AuthConfig {
 	// Load .env file for local development
 	if err := godotenv.Load(); err != nil {
@@ -31,18 +74,76 @@ func LoadAuthConfig() *AuthConfig {
 	return &AuthConfig{
 		GlobalConfig:            *config.LoadGlobalConfig(),
 		PostgreConnectionString: getEnv("POSTGRE_CONNECTION_STRING"),
+		MigrationMode:           getEnvDefault("DB_MIGRATE", "up"),
 		RedisDBURL:              getEnv("REDIS_DB_URL"),
 		RedisDBPort:             getEnv("REDIS_DB_PORT"),
 		RedisDBPassword:         getEnv("REDIS_DB_PASSWORD"),
 		RedisMaxRetries:         3,
 		RedisPoolSize:           10,
+		RefreshReuseGraceSecs:   30,
 		JWTSecretKey:            getEnv("JWT_SECRET_KEY"),
 		GoogleClientID:          getEnv("GOOGLE_CLIENT_ID"),
 		GoogleClientSecret:      getEnv("GOOGLE_CLIENT_SECRET"),
-		MYDOMAIN:                getEnv("MYDOMAIN"),
+		OAuthProviders:          loadOAuthProviders(),
+		OwnerEmails:             getEnvList("OWNER_EMAILS"),
+		AllowedEmails:           getEnvList("ALLOWED_EMAILS"),
+		AllowedHostedDomains:    getEnvList("ALLOWED_HOSTED_DOMAINS"),
+		DefaultRole:             getEnvDefault("DEFAULT_ROLE", "reader"),
+		ImageServiceURL:         getEnvDefault("IMAGE_SERVICE_URL", ""),
+		PasswordLoginEnabled:    getEnvDefault("PASSWORD_LOGIN_ENABLED", "false") == "true",
+		RegistrationEnabled:     getEnvDefault("REGISTRATION_ENABLED", "false") == "true",
+		LoginMaxFailures:        5,
+		LoginIPMaxFailures:      20,
+		LoginLockoutSecs:        900,
+		PasswordResetTTLMins:    30,
+		MFAIssuer:               getEnvDefault("MFA_ISSUER", "PersonalWebSite"),
+		IntrospectionClients:    getEnvCredentials("INTROSPECTION_CLIENTS"),
+		SMTP: SMTPConfig{
+			Host:     getEnvDefault("SMTP_HOST", ""),
+			Port:     getEnvDefault("SMTP_PORT", "587"),
+			Username: getEnvDefault("SMTP_USERNAME", ""),
+			Password: getEnvDefault("SMTP_PASSWORD", ""),
+			From:     getEnvDefault("MAIL_FROM", ""),
+		},
+		MYDOMAIN: getEnv("MYDOMAIN"),
 	}
 }
 
+// loadOAuthProviders reads OAUTH_PROVIDERS (comma-separated names) and, for each
+// name, OAUTH_<NAME>_TYPE, _CLIENT_ID, _CLIENT_SECRET, _ISSUER_URL, _SCOPES and
+// _CLAIM_{SUBJECT,EMAIL,EMAIL_VERIFIED,NAME,AVATAR}.
+func loadOAuthProviders() []OAuthProviderConfig {
+	var providers []OAuthProviderConfig
+	for _, name := range strings.Split(getEnvDefault("OAUTH_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OAUTH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		defaultType := "oidc"
+		if name == "google" || name == "github" {
+			defaultType = name
+		}
+		p := OAuthProviderConfig{
+			Name:               name,
+			Type:               getEnvDefault(prefix+"TYPE", defaultType),
+			ClientID:           getEnv(prefix + "CLIENT_ID"),
+			ClientSecret:       getEnv(prefix + "CLIENT_SECRET"),
+			Scopes:             strings.Fields(getEnvDefault(prefix+"SCOPES", "")),
+			ClaimSubject:       getEnvDefault(prefix+"CLAIM_SUBJECT", ""),
+			ClaimEmail:         getEnvDefault(prefix+"CLAIM_EMAIL", ""),
+			ClaimEmailVerified: getEnvDefault(prefix+"CLAIM_EMAIL_VERIFIED", ""),
+			ClaimName:          getEnvDefault(prefix+"CLAIM_NAME", ""),
+			ClaimAvatar:        getEnvDefault(prefix+"CLAIM_AVATAR", ""),
+		}
+		if p.Type == "oidc" {
+			p.IssuerURL = getEnv(prefix + "ISSUER_URL")
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
+
 // getEnv retrieves the value of the environment variable named by the key.
 func getEnv(key string) string {
 	if value, ok := os.LookupEnv(key); ok {
@@ -51,3 +152,36 @@ func getEnv(key string) string {
 		panic("critical config missing: " + key)
 	}
 }
+
+// getEnvDefault returns the environment variable named by key, or fallback if unset.
+func getEnvDefault(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// getEnvList returns the comma-separated environment variable named by key,
+// trimmed and lower-cased, without empty entries.
+func getEnvList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(getEnvDefault(key, ""), ",") {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// getEnvCredentials returns the comma-separated id:secret pairs in the environment
+// variable named by key. Entries without a secret are skipped.
+func getEnvCredentials(key string) map[string]string {
+	out := map[string]string{}
+	for _, pair := range strings.Split(getEnvDefault(key, ""), ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && id != "" && secret != "" {
+			out[id] = secret
+		}
+	}
+	return out
+}
@@ -0,0 +1,8 @@
+package domain
+
+import "seungpyo.lee/PersonalWebSite/pkg/audit"
+
+// AuditLog is the queryable side of the shared audit log.
+type AuditLog interface {
+	List(filter audit.Filter) ([]*audit.Entry, int64, error)
+}
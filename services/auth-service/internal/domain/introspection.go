@@ -0,0 +1,10 @@
+package domain
+
+import "seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+
+// IntrospectionService answers RFC 7662 token introspection for internal clients.
+type IntrospectionService interface {
+	// Introspect reports whether token is an active access token or personal access
+	// token. Unknown, expired and revoked tokens are inactive rather than an error.
+	Introspect(token string) (*model.IntrospectionResponse, error)
+}
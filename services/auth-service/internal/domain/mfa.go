@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+type TOTPCredential = model.TOTPCredential
+
+type RecoveryCode = model.RecoveryCode
+
+// Errors returned by MFAService.
+var (
+	ErrMFANotEnrolled      = errors.New("two-factor authentication is not set up")
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrInvalidMFACode      = errors.New("invalid authentication code")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired two-factor challenge")
+)
+
+// MFAService manages TOTP two-factor authentication (RFC 6238) and the second step
+// of logins for users who turned it on.
+type MFAService interface {
+	Status(userID uint) (*model.MFAStatus, error)
+	// BeginEnrollment creates a new, unconfirmed authenticator secret, replacing any
+	// earlier unconfirmed one.
+	BeginEnrollment(userID uint) (*model.TOTPEnrollment, error)
+	// ConfirmEnrollment turns two-factor authentication on with a first code from the
+	// authenticator and returns the recovery codes.
+	ConfirmEnrollment(userID uint, code string) ([]string, error)
+	// Disable turns two-factor authentication off; code may be a recovery code.
+	Disable(userID uint, code string) error
+	// RegenerateRecoveryCodes replaces all recovery codes; code may be a recovery code.
+	RegenerateRecoveryCodes(userID uint, code string) ([]string, error)
+	// Challenge starts the second step of a login. It returns "" when the user does
+	// not have two-factor authentication on. jkt is the DPoP key the tokens will be
+	// bound to, if any.
+	Challenge(user *User, newUser bool, jkt string) (string, error)
+	// Verify completes a challenged login with an authenticator or recovery code and
+	// issues tokens carrying the mfa claim. jkt must match the key given to Challenge.
+	Verify(challenge, code, jkt string) (*model.LoginResponse, error)
+}
+
+// MFARepository stores authenticator secrets and recovery codes.
+type MFARepository interface {
+	// GetTOTP returns ErrMFANotEnrolled when the user has no authenticator.
+	GetTOTP(userID uint) (*TOTPCredential, error)
+	SaveTOTP(cred *TOTPCredential) error
+	EnableTOTP(userID uint) error
+	// DeleteTOTP removes the authenticator and the recovery codes.
+	DeleteTOTP(userID uint) error
+	// AdvanceStep records step as used and reports false when it is not newer than
+	// the last accepted one, so every code is accepted at most once.
+	AdvanceStep(userID uint, step int64) (bool, error)
+	ReplaceRecoveryCodes(userID uint, hashes []string) error
+	// UseRecoveryCode marks an unused code as used and reports whether there was one.
+	UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error)
+	CountRecoveryCodes(userID uint) (int64, error)
+}
+
+// MFAChallengeStore keeps logins waiting for their second factor. GetChallenge and
+// ConsumeChallenge return nil without error for unknown or expired challenges;
+// ConsumeChallenge deletes the challenge as it reads it.
+type MFAChallengeStore interface {
+	SaveChallenge(token string, ch *model.MFAChallenge, ttl time.Duration) error
+	GetChallenge(token string) (*model.MFAChallenge, error)
+	ConsumeChallenge(token string) (*model.MFAChallenge, error)
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// Errors returned by PasswordService.
+var (
+	ErrPasswordLoginDisabled = errors.New("password login is disabled")
+	ErrRegistrationDisabled  = errors.New("registration is disabled")
+	ErrInvalidCredentials    = errors.New("invalid email or password")
+	ErrLoginLocked           = errors.New("too many failed logins, try again later")
+	ErrWeakPassword          = errors.New("password must be 10 to 72 characters")
+	ErrInvalidResetToken     = errors.New("invalid or expired reset token")
+	ErrMailNotConfigured     = errors.New("mail is not configured")
+)
+
+// PasswordService implements email+password login next to the OAuth providers.
+type PasswordService interface {
+	// Login checks the password and issues tokens, or a two-factor challenge when the
+	// user has it on. clientIP is used for lockout.
+	Login(req model.PasswordLoginRequest, clientIP string) (*model.LoginResponse, error)
+	// Register creates a reader account without a password and mails a link to set
+	// one. It succeeds silently for an email that already has an account.
+	Register(req model.RegisterRequest) error
+	// RequestPasswordReset mails a reset link. It succeeds silently for unknown emails.
+	RequestPasswordReset(email string) error
+	// ResetPassword sets a new password with a token from a reset link; the token
+	// stops working once the password changes.
+	ResetPassword(token, password string) (*User, error)
+	// BootstrapOwner creates or promotes the account with email to owner and sets its password.
+	BootstrapOwner(email, username, password string) (*User, error)
+}
+
+// LoginAttemptStore counts failed logins per key within a window.
+type LoginAttemptStore interface {
+	Failures(key string) (int, error)
+	// RecordFailure increments the counter, starting a new window if none is open.
+	RecordFailure(key string, window time.Duration) (int, error)
+	ClearFailures(key string) error
+}
+
+// MailSender delivers plain-text mail.
+type MailSender interface {
+	SendMail(to, subject, body string) error
+}
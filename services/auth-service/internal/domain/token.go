@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+type PersonalAccessToken = model.PersonalAccessToken
+
+// ErrTokenNotFound is returned when a personal access token does not exist for the user.
+var ErrTokenNotFound = errors.New("token not found")
+
+type TokenRepository interface {
+	Create(token *PersonalAccessToken) error
+	GetByHash(hash string) (*PersonalAccessToken, error)
+	ListByUser(userID uint) ([]*PersonalAccessToken, error)
+	Revoke(id, userID uint, at time.Time) error
+	RecordUsage(id uint, at time.Time) error
+}
+
+type TokenService interface {
+	CreateToken(userID uint, req model.CreateTokenRequest) (*model.CreateTokenResponse, error)
+	ListTokens(userID uint) ([]*PersonalAccessToken, error)
+	RevokeToken(userID, tokenID uint) error
+	ValidateToken(token string) (*model.ValidateTokenResponse, error)
+}
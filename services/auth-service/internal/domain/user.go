@@ -1,12 +1,15 @@
 package domain
 
 import (
+	"errors"
+	"time"
+
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
 )
 
 type User = model.User
 
-type GoogleUserInfo = model.GoogleUserInfo
+type OAuthUserInfo = model.OAuthUserInfo
 
 type UserRepository interface {
 	Create(user *User) error
@@ -16,11 +19,102 @@ type UserRepository interface {
 	GetByID(id uint) (*User, error)
 	Update(user *User) error
 	Delete(id uint) error
+	List() ([]*User, error)
+	CountByRole(role string) (int64, error)
+}
+
+// Errors returned by UserAdminService.
+var (
+	ErrInvalidRole  = errors.New("invalid role")
+	ErrLastOwner    = errors.New("cannot remove the last owner")
+	ErrUserExists   = errors.New("user already exists")
+	ErrUserNotFound = errors.New("user not found")
+	ErrNotOwner     = errors.New("owner role required")
+)
+
+// UserAdminService manages accounts and their roles on behalf of an owner.
+type UserAdminService interface {
+	// RequireOwner returns ErrNotOwner unless the user exists and is an owner.
+	RequireOwner(userID uint) error
+	ListUsers() ([]*User, error)
+	CreateUser(req model.CreateUserRequest) (*User, error)
+	UpdateRole(userID uint, role string) (before, after *User, err error)
+	DeleteUser(userID uint) (*User, error)
+}
+
+// Errors returned by ProfileService.
+var (
+	ErrInvalidUsername = errors.New("username may only contain letters, digits, '.', '_' and '-'")
+	ErrUsernameTaken   = errors.New("username already taken")
+	ErrInvalidLink     = errors.New("links must be http or https URLs")
+	ErrInvalidAvatar   = errors.New("avatar must be a PNG, JPEG, GIF or WebP image")
+)
+
+// ProfileService reads public profiles and lets users edit their own.
+type ProfileService interface {
+	GetProfile(userID uint) (*model.PublicProfile, error)
+	UpdateProfile(userID uint, req model.UpdateProfileRequest) (*User, error)
+	// ImportAvatar copies the provider's picture into img-service for a user who has
+	// no avatar yet. It does nothing when pictureURL is empty.
+	ImportAvatar(userID uint, pictureURL string) error
+}
+
+// AvatarStore keeps avatar images in img-service and returns their paths.
+type AvatarStore interface {
+	// UploadAvatar stores an image given as a data URL.
+	UploadAvatar(userID uint, dataURL string) (string, error)
+	// ImportAvatar downloads the image at pictureURL and stores it.
+	ImportAvatar(userID uint, pictureURL string) (string, error)
+}
+
+// ErrUnknownProvider is returned for a login provider that is not configured.
+var ErrUnknownProvider = errors.New("unsupported provider")
+
+// ErrInvalidState is returned for a login callback whose state is unknown, expired,
+// already used or issued for another provider.
+var ErrInvalidState = errors.New("invalid oauth state")
+
+// ErrEmailNotVerified is returned when the provider does not vouch for the user's email.
+var ErrEmailNotVerified = errors.New("email not verified")
+
+// ErrAccountExists is returned when a new provider identity's email belongs to an
+// account that already signs in another way. Matching emails never link identities.
+var ErrAccountExists = errors.New("an account with this email already exists; sign in with the method you used before")
+
+// ErrDPoPKeyMismatch is returned when a DPoP-bound refresh token is used without a
+// proof from the key it is bound to.
+var ErrDPoPKeyMismatch = errors.New("DPoP proof does not match the token's key")
+
+// OAuthStateStore keeps pending logins until their callback arrives. ConsumeState
+// deletes the state as it reads it and returns nil without error when it is unknown.
+type OAuthStateStore interface {
+	SaveState(state string, s *model.OAuthState, ttl time.Duration) error
+	ConsumeState(state string) (*model.OAuthState, error)
+}
+
+// RefreshRotationStore remembers recently rotated refresh tokens. GetRotation
+// returns nil without error when the token was not rotated recently.
+type RefreshRotationStore interface {
+	SaveRotation(oldRefreshToken string, rotation *model.RefreshRotation, ttl time.Duration) error
+	GetRotation(oldRefreshToken string) (*model.RefreshRotation, error)
 }
 
 type AuthService interface {
-	OAuthLogin(provider, code string) (*model.LoginResponse, *GoogleUserInfo, error)
+	// Providers lists the names of the configured login providers.
+	Providers() []string
+	// LoginURL starts a login: it stores the PKCE verifier, nonce and the page to
+	// return to under state and returns the provider's authorization URL.
+	LoginURL(provider, state, returnTo string) (string, error)
+	// OAuthLogin consumes state and exchanges the code of the login it started. Users
+	// with two-factor authentication get a challenge instead of tokens. The response
+	// carries the return path the login was started with.
+	OAuthLogin(provider, state, code string) (*model.LoginResponse, *OAuthUserInfo, error)
 	GetUserByEmail(email string) (*User, error)
 	GetUserByID(id uint) (*User, error)
-	RefreshToken(refreshToken string) (string, string, error)
+	// RefreshToken rotates refreshToken. jkt is the thumbprint of the caller's DPoP
+	// key, if it sent a proof; tokens bound to a key are only refreshed with that key.
+	RefreshToken(refreshToken, jkt string) (string, string, error)
+	// Logout revokes the refresh token and any token it was rotated into.
+	// Tokens that are already invalid are treated as logged out.
+	Logout(refreshToken string) error
 }
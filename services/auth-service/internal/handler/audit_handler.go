@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+)
+
+// AuditHandler serves the admin audit log API to owners.
+type AuditHandler struct {
+	Log   domain.AuditLog
+	Users domain.UserAdminService
+}
+
+// NewAuditHandler creates a new AuditHandler.
+func NewAuditHandler(log domain.AuditLog, users domain.UserAdminService) *AuditHandler {
+	return &AuditHandler{Log: log, Users: users}
+}
+
+// ListAuditLogs handles GET /admin/audit-logs. Supported filters: actor_id, action,
+// target_type, target_id, outcome, request_id, from and to (RFC 3339), limit and offset.
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	if !requireOwner(c, h.Users) {
+		return
+	}
+	filter := audit.Filter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		Outcome:    c.Query("outcome"),
+		RequestID:  c.Query("request_id"),
+	}
+	if v := c.Query("actor_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid actor_id"})
+			return
+		}
+		filter.ActorID = uint(id)
+	}
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if v := c.Query(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + p.name + ", expected RFC 3339"})
+				return
+			}
+			*p.dst = &t
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		filter.Limit = limit
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+			return
+		}
+		filter.Offset = offset
+	}
+	entries, total, err := h.Log.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items":  entries,
+		"total":  total,
+		"offset": filter.Offset,
+	})
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
+)
+
+type stubAuditLog struct {
+	listFn func(filter audit.Filter) ([]*audit.Entry, int64, error)
+}
+
+func (s *stubAuditLog) List(filter audit.Filter) ([]*audit.Entry, int64, error) {
+	return s.listFn(filter)
+}
+
+type stubRecorder struct {
+	entries []*audit.Entry
+}
+
+func (r *stubRecorder) Record(_ context.Context, e *audit.Entry) { r.entries = append(r.entries, e) }
+
+func newAuditRouter(log *stubAuditLog) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/admin/audit-logs", NewAuditHandler(log, &stubUserAdmin{ownerID: 1}).ListAuditLogs)
+	return r
+}
+
+func TestListAuditLogs_PassesFilters(t *testing.T) {
+	var got audit.Filter
+	r := newAuditRouter(&stubAuditLog{listFn: func(filter audit.Filter) ([]*audit.Entry, int64, error) {
+		got = filter
+		return []*audit.Entry{{ID: 1, Action: "post.delete"}}, 7, nil
+	}})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/audit-logs?actor_id=3&action=post.delete&outcome=failure&from=2026-01-01T00:00:00Z&limit=5&offset=10", nil)
+	req.Header.Set("X-User-Id", "1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got.ActorID != 3 || got.Action != "post.delete" || got.Outcome != "failure" || got.Limit != 5 || got.Offset != 10 {
+		t.Fatalf("unexpected filter: %+v", got)
+	}
+	if got.From == nil || got.From.Year() != 2026 || got.To != nil {
+		t.Fatalf("unexpected time range: from=%v to=%v", got.From, got.To)
+	}
+	var body struct {
+		Items []audit.Entry `json:"items"`
+		Total int64         `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Total != 7 || len(body.Items) != 1 {
+		t.Fatalf("unexpected body: %+v", body)
+	}
+}
+
+func TestListAuditLogs_RejectsBadInput(t *testing.T) {
+	r := newAuditRouter(&stubAuditLog{listFn: func(filter audit.Filter) ([]*audit.Entry, int64, error) {
+		t.Fatalf("List must not be called")
+		return nil, 0, nil
+	}})
+	cases := []struct {
+		path   string
+		userID string
+		want   int
+	}{
+		{"/admin/audit-logs", "", http.StatusUnauthorized},
+		{"/admin/audit-logs", "2", http.StatusForbidden},
+		{"/admin/audit-logs?actor_id=x", "1", http.StatusBadRequest},
+		{"/admin/audit-logs?from=yesterday", "1", http.StatusBadRequest},
+		{"/admin/audit-logs?limit=-1", "1", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.userID != "" {
+			req.Header.Set("X-User-Id", tc.userID)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d", tc.path, tc.want, w.Code)
+		}
+	}
+}
+
+func TestListAuditLogs_StoreError(t *testing.T) {
+	r := newAuditRouter(&stubAuditLog{listFn: func(filter audit.Filter) ([]*audit.Entry, int64, error) {
+		return nil, 0, errors.New("db down")
+	}})
+	req := httptest.NewRequest(http.MethodGet, "/admin/audit-logs", nil)
+	req.Header.Set("X-User-Id", "1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+}
+
+func TestRefresh_RecordsFailedRefresh(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := newTestHandler(&stubAuthService{
+		refreshTokenFn: func(refreshToken string) (string, string, error) {
+			return "", "", errors.New("revoked")
+		},
+	})
+	rec := &stubRecorder{}
+	h.Audit = rec
+	r := gin.New()
+	r.Use(audit.Middleware())
+	r.POST("/refresh", h.Refresh)
+
+	req := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "old"})
+	req.Header.Set("X-Request-Id", "req-9")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("expected one audit entry, got %d", len(rec.entries))
+	}
+	e := rec.entries[0]
+	if e.Action != "auth.refresh" || e.Outcome != audit.OutcomeFailure || e.RequestID != "req-9" || e.Error != "revoked" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+}
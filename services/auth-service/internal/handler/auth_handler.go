@@ -4,12 +4,16 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
+	"log"
 	"net/http"
 
+	"errors"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
 	"seungpyo.lee/PersonalWebSite/pkg/jwt"
+	"seungpyo.lee/PersonalWebSite/pkg/returnto"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/config"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
@@ -20,6 +24,10 @@ type AuthHandler struct {
 	Service      domain.AuthService
 	Config       *config.AuthConfig
 	TokenManager jwt.TokenManager
+	Audit        audit.Recorder         // optional; logins and refreshes are audited when set
+	Profiles     domain.ProfileService  // optional; imports the provider picture as avatar
+	Passwords    domain.PasswordService // optional; password login routes answer 404 without it
+	MFA          domain.MFAService      // optional; completes logins that were challenged for a second factor
 }
 
 var readRandom = rand.Read
@@ -38,6 +46,15 @@ func generateState() (string, error) {
 	return base64.RawURLEncoding.EncodeToString(buf), nil
 }
 
+// recordAuth appends an audit entry for an authentication event on the given user.
+func recordAuth(c *gin.Context, r audit.Recorder, action string, userID uint, err error) {
+	e := &audit.Entry{Action: action, TargetType: "user", ActorID: userID}
+	if userID != 0 {
+		e.TargetID = strconv.FormatUint(uint64(userID), 10)
+	}
+	audit.Record(c.Request.Context(), r, e, err)
+}
+
 // GetUser handles GET /users/:id to retrieve user info.
 func (h *AuthHandler) GetUser(c *gin.Context) {
 	idParam := c.Param("id")
@@ -71,11 +88,19 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		refreshToken = body.RefreshToken
 	}
 
-	newAccess, newRefresh, err := h.Service.RefreshToken(refreshToken)
+	newAccess, newRefresh, err := h.Service.RefreshToken(refreshToken, c.GetHeader(dpopKeyHeader))
 	if err != nil {
+		recordAuth(c, h.Audit, "auth.refresh", 0, err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
+	var userID uint
+	if h.TokenManager != nil {
+		if claims, err := h.TokenManager.ValidateAccessToken(newAccess); err == nil {
+			userID = claims.UserID
+		}
+	}
+	recordAuth(c, h.Audit, "auth.refresh", userID, nil)
 	// Set new refresh token as cookie (rotation)
 	if newRefresh != "" {
 		cookieSecure := c.Request.TLS != nil
@@ -94,22 +119,69 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"token": newAccess})
 }
 
-// OAuthGoogleLogin initiates Google OAuth login.
-func (h *AuthHandler) OAuthGoogleLogin(c *gin.Context) {
-	oauthConfig := &oauth2.Config{
-		ClientID:     h.Config.GoogleClientID,
-		ClientSecret: h.Config.GoogleClientSecret,
-		RedirectURL:  h.Config.MYDOMAIN + "/api/v1/auth/oauth/google/callback",
-		Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
-		Endpoint:     google.Endpoint,
+// Logout handles POST /logout. It revokes the refresh token from the cookie or the
+// JSON body {"refresh_token":"..."} and clears every auth cookie. Logging out without
+// a token, or with one that is already invalid, still succeeds.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	refreshToken, err := c.Cookie("refresh_token")
+	if err != nil || refreshToken == "" {
+		var body struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		_ = c.ShouldBindJSON(&body)
+		refreshToken = body.RefreshToken
 	}
+	var userID uint
+	if refreshToken != "" {
+		if h.TokenManager != nil {
+			if claims, err := h.TokenManager.ValidateRefreshToken(refreshToken); err == nil {
+				userID = claims.UserID
+			}
+		}
+		if err := h.Service.Logout(refreshToken); err != nil {
+			recordAuth(c, h.Audit, "auth.logout", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if userID != 0 {
+		recordAuth(c, h.Audit, "auth.logout", userID, nil)
+	}
+	h.clearAuthCookies(c)
+	c.Status(http.StatusNoContent)
+}
+
+// ListProviders handles GET /oauth/providers and lists the configured login providers
+// and whether password login and registration are available.
+func (h *AuthHandler) ListProviders(c *gin.Context) {
+	names := h.Service.Providers()
+	if names == nil {
+		names = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"providers":    names,
+		"password":     h.Passwords != nil,
+		"registration": h.Passwords != nil && h.Config.RegistrationEnabled,
+	})
+}
 
+// OAuthLogin handles GET /oauth/:provider/login and redirects to the provider.
+func (h *AuthHandler) OAuthLogin(c *gin.Context) {
 	// Generate random state
 	state, err := generateState()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate state"})
 		return
 	}
+	url, err := h.Service.LoginURL(c.Param("provider"), state, returnto.Sanitize(c.Query(returnto.Param)))
+	if err != nil {
+		if errors.Is(err, domain.ErrUnknownProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown provider"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	http.SetCookie(c.Writer, &http.Cookie{
 		Name:     "oauth_state",
 		Value:    state,
@@ -120,12 +192,16 @@ func (h *AuthHandler) OAuthGoogleLogin(c *gin.Context) {
 		SameSite: http.SameSiteLaxMode,
 	})
 
-	url := oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline)
 	c.Redirect(http.StatusFound, url)
 }
 
-// OAuthGoogleCallback handles the Google OAuth callback.
-func (h *AuthHandler) OAuthGoogleCallback(c *gin.Context) {
+// OAuthCallback handles GET /oauth/:provider/callback.
+func (h *AuthHandler) OAuthCallback(c *gin.Context) {
+	provider := c.Param("provider")
+	if !h.knownProvider(provider) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown provider"})
+		return
+	}
 	state := c.Query("state")
 	if state == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "state not provided"})
@@ -137,6 +213,7 @@ func (h *AuthHandler) OAuthGoogleCallback(c *gin.Context) {
 		return
 	}
 	if stateCookie != state {
+		recordAuth(c, h.Audit, "auth.login", 0, errors.New("invalid oauth state"))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid oauth state"})
 		return
 	}
@@ -157,15 +234,53 @@ func (h *AuthHandler) OAuthGoogleCallback(c *gin.Context) {
 		return
 	}
 
-	resp, _, err := h.Service.OAuthLogin("google", code)
+	resp, info, err := h.Service.OAuthLogin(provider, state, code)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		recordAuth(c, h.Audit, "auth.login", 0, err)
+		switch {
+		case errors.Is(err, domain.ErrInvalidState):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrEmailNotVerified):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrAccountExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	if h.Profiles != nil && info != nil && resp.User.AvatarURL == "" {
+		// Best effort: a missing avatar must not fail the login.
+		if err := h.Profiles.ImportAvatar(resp.User.ID, info.AvatarURL); err != nil {
+			log.Printf("failed to import avatar for user %d: %v", resp.User.ID, err)
+		}
+	}
+	if resp.MFARequired {
+		// the login is audited once the second factor is verified
+		h.setMFAChallengeCookie(c, resp.MFAToken, 300)
+		c.Redirect(http.StatusFound, h.Config.MYDOMAIN+returnto.AppendTo("/login/2fa", resp.ReturnTo))
 		return
 	}
 
-	// Existing user, set cookies and redirect to home
+	recordAuth(c, h.Audit, "auth.login", resp.User.ID, nil)
 	h.setAuthCookies(c, resp)
-	c.Redirect(http.StatusFound, h.Config.MYDOMAIN)
+	if resp.NewUser {
+		// New users pick a username before anything else.
+		c.Redirect(http.StatusFound, h.Config.MYDOMAIN+returnto.AppendTo("/set-username", resp.ReturnTo))
+		return
+	}
+	// the return path was validated when the login started; check again before redirecting
+	c.Redirect(http.StatusFound, h.Config.MYDOMAIN+returnto.Sanitize(resp.ReturnTo))
+}
+
+// knownProvider reports whether name is a configured login provider.
+func (h *AuthHandler) knownProvider(name string) bool {
+	for _, p := range h.Service.Providers() {
+		if p == name {
+			return true
+		}
+	}
+	return false
 }
 
 // setAuthCookies sets authentication cookies for the user.
@@ -204,3 +319,17 @@ func (h *AuthHandler) setAuthCookies(c *gin.Context, resp *model.LoginResponse)
 		SameSite: 0, // not set for local development
 	})
 }
+
+// clearAuthCookies expires every cookie set by setAuthCookies.
+func (h *AuthHandler) clearAuthCookies(c *gin.Context) {
+	for _, name := range []string{"refresh_token", "access_token", "userId"} {
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			Secure:   true,
+			HttpOnly: true,
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -19,12 +20,31 @@ import (
 type stubAuthService struct {
 	getUserByIDFn  func(id uint) (*domain.User, error)
 	refreshTokenFn func(refreshToken string) (string, string, error)
-	oAuthLoginFn   func(provider, code string) (*model.LoginResponse, *domain.GoogleUserInfo, error)
+	oAuthLoginFn   func(provider, state, code string) (*model.LoginResponse, *domain.OAuthUserInfo, error)
 	getUserByEmail func(email string) (*domain.User, error)
+	logoutFn       func(refreshToken string) error
+	providers      []string
+	returnTo       string
 }
 
-func (s *stubAuthService) OAuthLogin(provider, code string) (*model.LoginResponse, *domain.GoogleUserInfo, error) {
-	return s.oAuthLoginFn(provider, code)
+func (s *stubAuthService) Providers() []string {
+	if s.providers == nil {
+		return []string{"google"}
+	}
+	return s.providers
+}
+func (s *stubAuthService) LoginURL(provider, state, returnTo string) (string, error) {
+	s.returnTo = returnTo
+	for _, p := range s.Providers() {
+		if p == provider {
+			return "https://provider.example/" + provider + "?state=" + state, nil
+		}
+	}
+	return "", domain.ErrUnknownProvider
+}
+
+func (s *stubAuthService) OAuthLogin(provider, state, code string) (*model.LoginResponse, *domain.OAuthUserInfo, error) {
+	return s.oAuthLoginFn(provider, state, code)
 }
 func (s *stubAuthService) GetUserByEmail(email string) (*domain.User, error) {
 	return s.getUserByEmail(email)
@@ -32,10 +52,14 @@ func (s *stubAuthService) GetUserByEmail(email string) (*domain.User, error) {
 func (s *stubAuthService) GetUserByID(id uint) (*domain.User, error) {
 	return s.getUserByIDFn(id)
 }
-func (s *stubAuthService) RefreshToken(refreshToken string) (string, string, error) {
+func (s *stubAuthService) RefreshToken(refreshToken, jkt string) (string, string, error) {
 	return s.refreshTokenFn(refreshToken)
 }
 
+func (s *stubAuthService) Logout(refreshToken string) error {
+	return s.logoutFn(refreshToken)
+}
+
 func newTestHandler(svc domain.AuthService) *AuthHandler {
 	cfg := &config.AuthConfig{
 		GlobalConfig:       pkgconfig.GlobalConfig{RefreshTokenTTL: 60, AccessTokenTTL: 15, ServerPort: "8081"},
@@ -84,7 +108,7 @@ func TestGetUser_NotFound(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	h := newTestHandler(&stubAuthService{
 		getUserByIDFn: func(id uint) (*domain.User, error) {
-			return nil, errors.New("user not found")
+			return nil, domain.ErrUserNotFound
 		},
 	})
 	r := gin.New()
@@ -184,7 +208,7 @@ func TestRefresh_ServiceError(t *testing.T) {
 	}
 }
 
-func TestOAuthGoogleLogin_Success(t *testing.T) {
+func TestOAuthLogin_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	origRead := readRandom
 	readRandom = func(p []byte) (int, error) {
@@ -197,7 +221,7 @@ func TestOAuthGoogleLogin_Success(t *testing.T) {
 
 	h := newTestHandler(&stubAuthService{})
 	r := gin.New()
-	r.GET("/oauth/google/login", h.OAuthGoogleLogin)
+	r.GET("/oauth/:provider/login", h.OAuthLogin)
 
 	req := httptest.NewRequest(http.MethodGet, "/oauth/google/login", nil)
 	w := httptest.NewRecorder()
@@ -220,7 +244,24 @@ func TestOAuthGoogleLogin_Success(t *testing.T) {
 	}
 }
 
-func TestOAuthGoogleLogin_StateGenerationFailure(t *testing.T) {
+func TestOAuthLogin_PassesValidReturnTo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := &stubAuthService{}
+	h := newTestHandler(svc)
+	r := gin.New()
+	r.GET("/oauth/:provider/login", h.OAuthLogin)
+
+	for returnTo, want := range map[string]string{"/blog-edit/42": "/blog-edit/42", "https://evil.example": ""} {
+		req := httptest.NewRequest(http.MethodGet, "/oauth/google/login?return_to="+url.QueryEscape(returnTo), nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusFound || svc.returnTo != want {
+			t.Fatalf("return_to %q: expected %q to be stored, got %d %q", returnTo, want, w.Code, svc.returnTo)
+		}
+	}
+}
+
+func TestOAuthLogin_StateGenerationFailure(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	origRead := readRandom
 	readRandom = func(p []byte) (int, error) { return 0, errors.New("rand fail") }
@@ -228,7 +269,7 @@ func TestOAuthGoogleLogin_StateGenerationFailure(t *testing.T) {
 
 	h := newTestHandler(&stubAuthService{})
 	r := gin.New()
-	r.GET("/oauth/google/login", h.OAuthGoogleLogin)
+	r.GET("/oauth/:provider/login", h.OAuthLogin)
 
 	req := httptest.NewRequest(http.MethodGet, "/oauth/google/login", nil)
 	w := httptest.NewRecorder()
@@ -239,11 +280,11 @@ func TestOAuthGoogleLogin_StateGenerationFailure(t *testing.T) {
 	}
 }
 
-func TestOAuthGoogleCallback_MissingState(t *testing.T) {
+func TestOAuthCallback_MissingState(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	h := newTestHandler(&stubAuthService{})
 	r := gin.New()
-	r.GET("/oauth/google/callback", h.OAuthGoogleCallback)
+	r.GET("/oauth/:provider/callback", h.OAuthCallback)
 
 	req := httptest.NewRequest(http.MethodGet, "/oauth/google/callback?code=abc", nil)
 	w := httptest.NewRecorder()
@@ -254,11 +295,11 @@ func TestOAuthGoogleCallback_MissingState(t *testing.T) {
 	}
 }
 
-func TestOAuthGoogleCallback_MissingStateCookie(t *testing.T) {
+func TestOAuthCallback_MissingStateCookie(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	h := newTestHandler(&stubAuthService{})
 	r := gin.New()
-	r.GET("/oauth/google/callback", h.OAuthGoogleCallback)
+	r.GET("/oauth/:provider/callback", h.OAuthCallback)
 
 	req := httptest.NewRequest(http.MethodGet, "/oauth/google/callback?state=s&code=abc", nil)
 	w := httptest.NewRecorder()
@@ -269,11 +310,11 @@ func TestOAuthGoogleCallback_MissingStateCookie(t *testing.T) {
 	}
 }
 
-func TestOAuthGoogleCallback_StateMismatch(t *testing.T) {
+func TestOAuthCallback_StateMismatch(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	h := newTestHandler(&stubAuthService{})
 	r := gin.New()
-	r.GET("/oauth/google/callback", h.OAuthGoogleCallback)
+	r.GET("/oauth/:provider/callback", h.OAuthCallback)
 
 	req := httptest.NewRequest(http.MethodGet, "/oauth/google/callback?state=s&code=abc", nil)
 	req.AddCookie(&http.Cookie{Name: "oauth_state", Value: "x"})
@@ -285,11 +326,11 @@ func TestOAuthGoogleCallback_StateMismatch(t *testing.T) {
 	}
 }
 
-func TestOAuthGoogleCallback_MissingCode(t *testing.T) {
+func TestOAuthCallback_MissingCode(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	h := newTestHandler(&stubAuthService{})
 	r := gin.New()
-	r.GET("/oauth/google/callback", h.OAuthGoogleCallback)
+	r.GET("/oauth/:provider/callback", h.OAuthCallback)
 
 	req := httptest.NewRequest(http.MethodGet, "/oauth/google/callback?state=s", nil)
 	req.AddCookie(&http.Cookie{Name: "oauth_state", Value: "s"})
@@ -301,15 +342,15 @@ func TestOAuthGoogleCallback_MissingCode(t *testing.T) {
 	}
 }
 
-func TestOAuthGoogleCallback_OAuthLoginError(t *testing.T) {
+func TestOAuthCallback_OAuthLoginError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	h := newTestHandler(&stubAuthService{
-		oAuthLoginFn: func(provider, code string) (*model.LoginResponse, *domain.GoogleUserInfo, error) {
+		oAuthLoginFn: func(provider, state, code string) (*model.LoginResponse, *domain.OAuthUserInfo, error) {
 			return nil, nil, errors.New("oauth failed")
 		},
 	})
 	r := gin.New()
-	r.GET("/oauth/google/callback", h.OAuthGoogleCallback)
+	r.GET("/oauth/:provider/callback", h.OAuthCallback)
 
 	req := httptest.NewRequest(http.MethodGet, "/oauth/google/callback?state=s&code=abc", nil)
 	req.AddCookie(&http.Cookie{Name: "oauth_state", Value: "s"})
@@ -321,10 +362,36 @@ func TestOAuthGoogleCallback_OAuthLoginError(t *testing.T) {
 	}
 }
 
-func TestOAuthGoogleCallback_Success(t *testing.T) {
+func TestOAuthCallback_LoginErrorStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for err, want := range map[error]int{
+		domain.ErrInvalidState:     http.StatusBadRequest,
+		domain.ErrEmailNotVerified: http.StatusForbidden,
+		domain.ErrAccountExists:    http.StatusConflict,
+	} {
+		h := newTestHandler(&stubAuthService{
+			oAuthLoginFn: func(provider, state, code string) (*model.LoginResponse, *domain.OAuthUserInfo, error) {
+				return nil, nil, err
+			},
+		})
+		r := gin.New()
+		r.GET("/oauth/:provider/callback", h.OAuthCallback)
+
+		req := httptest.NewRequest(http.MethodGet, "/oauth/google/callback?state=s&code=abc", nil)
+		req.AddCookie(&http.Cookie{Name: "oauth_state", Value: "s"})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != want {
+			t.Fatalf("expected %d for %v, got %d", want, err, w.Code)
+		}
+	}
+}
+
+func TestOAuthCallback_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	h := newTestHandler(&stubAuthService{
-		oAuthLoginFn: func(provider, code string) (*model.LoginResponse, *domain.GoogleUserInfo, error) {
+		oAuthLoginFn: func(provider, state, code string) (*model.LoginResponse, *domain.OAuthUserInfo, error) {
 			return &model.LoginResponse{
 				Token:        "access",
 				RefreshToken: "refresh",
@@ -334,7 +401,7 @@ func TestOAuthGoogleCallback_Success(t *testing.T) {
 		},
 	})
 	r := gin.New()
-	r.GET("/oauth/google/callback", h.OAuthGoogleCallback)
+	r.GET("/oauth/:provider/callback", h.OAuthCallback)
 
 	req := httptest.NewRequest(http.MethodGet, "/oauth/google/callback?state=s&code=abc", nil)
 	req.AddCookie(&http.Cookie{Name: "oauth_state", Value: "s"})
@@ -369,6 +436,136 @@ func TestOAuthGoogleCallback_Success(t *testing.T) {
 	}
 }
 
+func TestOAuthCallback_RedirectsToReturnTo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resp := &model.LoginResponse{Token: "access", RefreshToken: "refresh", User: model.User{ID: 55}}
+	h := newTestHandler(&stubAuthService{
+		oAuthLoginFn: func(provider, state, code string) (*model.LoginResponse, *domain.OAuthUserInfo, error) {
+			r := *resp
+			return &r, nil, nil
+		},
+	})
+	r := gin.New()
+	r.GET("/oauth/:provider/callback", h.OAuthCallback)
+	callback := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/oauth/google/callback?state=s&code=abc", nil)
+		req.AddCookie(&http.Cookie{Name: "oauth_state", Value: "s"})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Header().Get("Location")
+	}
+
+	resp.ReturnTo = "/blog-edit/42"
+	if loc := callback(); loc != "http://localhost:3000/blog-edit/42" {
+		t.Fatalf("unexpected redirect location: %q", loc)
+	}
+	resp.ReturnTo = "//evil.example"
+	if loc := callback(); loc != "http://localhost:3000" {
+		t.Fatalf("expected invalid return path to be ignored, got %q", loc)
+	}
+	resp.ReturnTo, resp.NewUser = "/blog-edit/42", true
+	if loc := callback(); loc != "http://localhost:3000/set-username?return_to=%2Fblog-edit%2F42" {
+		t.Fatalf("unexpected redirect location for new user: %q", loc)
+	}
+	resp.NewUser, resp.MFARequired, resp.MFAToken = false, true, "challenge"
+	if loc := callback(); loc != "http://localhost:3000/login/2fa?return_to=%2Fblog-edit%2F42" {
+		t.Fatalf("unexpected redirect location for two-factor login: %q", loc)
+	}
+}
+
+func TestOAuthCallback_NewUserImportsAvatarAndPicksUsername(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := newTestHandler(&stubAuthService{
+		oAuthLoginFn: func(provider, state, code string) (*model.LoginResponse, *domain.OAuthUserInfo, error) {
+			return &model.LoginResponse{User: model.User{ID: 7}, NewUser: true},
+				&domain.OAuthUserInfo{AvatarURL: "https://provider.example/pic.png"}, nil
+		},
+	})
+	profiles := &stubProfileService{}
+	h.Profiles = profiles
+	r := gin.New()
+	r.GET("/oauth/:provider/callback", h.OAuthCallback)
+
+	req := httptest.NewRequest(http.MethodGet, "/oauth/google/callback?state=s&code=abc", nil)
+	req.AddCookie(&http.Cookie{Name: "oauth_state", Value: "s"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Header().Get("Location") != "http://localhost:3000/set-username" {
+		t.Fatalf("unexpected redirect location: %q", w.Header().Get("Location"))
+	}
+	if profiles.imported != "https://provider.example/pic.png" {
+		t.Fatalf("expected provider picture import, got %q", profiles.imported)
+	}
+}
+
+func TestOAuthCallback_PassesProviderToService(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var gotProvider string
+	h := newTestHandler(&stubAuthService{
+		providers: []string{"github", "google"},
+		oAuthLoginFn: func(provider, state, code string) (*model.LoginResponse, *domain.OAuthUserInfo, error) {
+			gotProvider = provider
+			return &model.LoginResponse{User: model.User{ID: 1}}, nil, nil
+		},
+	})
+	r := gin.New()
+	r.GET("/oauth/:provider/callback", h.OAuthCallback)
+
+	req := httptest.NewRequest(http.MethodGet, "/oauth/github/callback?state=s&code=abc", nil)
+	req.AddCookie(&http.Cookie{Name: "oauth_state", Value: "s"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusFound || gotProvider != "github" {
+		t.Fatalf("expected github login, got code=%d provider=%q", w.Code, gotProvider)
+	}
+}
+
+func TestOAuthRoutes_UnknownProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := newTestHandler(&stubAuthService{})
+	r := gin.New()
+	r.GET("/oauth/:provider/login", h.OAuthLogin)
+	r.GET("/oauth/:provider/callback", h.OAuthCallback)
+
+	for _, path := range []string{"/oauth/gitlab/login", "/oauth/gitlab/callback?state=s&code=abc"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(&http.Cookie{Name: "oauth_state", Value: "s"})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", path, w.Code)
+		}
+		for _, c := range w.Result().Cookies() {
+			if c.Name == "oauth_state" && c.MaxAge > 0 {
+				t.Fatalf("%s: did not expect a state cookie", path)
+			}
+		}
+	}
+}
+
+func TestListProviders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := newTestHandler(&stubAuthService{providers: []string{"github", "google"}})
+	r := gin.New()
+	r.GET("/oauth/providers", h.ListProviders)
+
+	req := httptest.NewRequest(http.MethodGet, "/oauth/providers", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body struct {
+		Providers []string `json:"providers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse body: %v", err)
+	}
+	if w.Code != http.StatusOK || len(body.Providers) != 2 || body.Providers[0] != "github" {
+		t.Fatalf("unexpected providers response %d %s", w.Code, w.Body.String())
+	}
+}
+
 func TestRefresh_ResponseContainsTokenField(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	h := newTestHandler(&stubAuthService{
@@ -392,3 +589,88 @@ func TestRefresh_ResponseContainsTokenField(t *testing.T) {
 		t.Fatalf("expected token in response")
 	}
 }
+
+func TestLogout_RevokesCookieTokenAndClearsCookies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var revoked string
+	h := newTestHandler(&stubAuthService{
+		logoutFn: func(refreshToken string) error {
+			revoked = refreshToken
+			return nil
+		},
+	})
+	r := gin.New()
+	r.POST("/logout", h.Logout)
+
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "cookie-refresh"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if revoked != "cookie-refresh" {
+		t.Fatalf("expected cookie token to be revoked, got %q", revoked)
+	}
+	cleared := map[string]bool{}
+	for _, ck := range w.Result().Cookies() {
+		if ck.MaxAge < 0 {
+			cleared[ck.Name] = true
+		}
+	}
+	for _, name := range []string{"refresh_token", "access_token", "userId"} {
+		if !cleared[name] {
+			t.Fatalf("expected %s cookie to be cleared, got %v", name, w.Result().Cookies())
+		}
+	}
+}
+
+func TestLogout_UsesBodyAndSucceedsWithoutToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var calls []string
+	h := newTestHandler(&stubAuthService{
+		logoutFn: func(refreshToken string) error {
+			calls = append(calls, refreshToken)
+			return nil
+		},
+	})
+	r := gin.New()
+	r.POST("/logout", h.Logout)
+
+	req := httptest.NewRequest(http.MethodPost, "/logout", strings.NewReader(`{"refresh_token":"body-refresh"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/logout", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 without a token, got %d", w.Code)
+	}
+	if len(calls) != 1 || calls[0] != "body-refresh" {
+		t.Fatalf("expected only the body token to be revoked, got %v", calls)
+	}
+}
+
+func TestLogout_ServiceError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := newTestHandler(&stubAuthService{
+		logoutFn: func(refreshToken string) error { return errors.New("failed to revoke refresh token") },
+	})
+	r := gin.New()
+	r.POST("/logout", h.Logout)
+
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "x"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+)
+
+// IntrospectionHandler serves RFC 7662 token introspection to internal services.
+type IntrospectionHandler struct {
+	Service domain.IntrospectionService
+	Clients map[string]string // client ID -> secret; empty refuses every caller
+}
+
+// NewIntrospectionHandler creates a new IntrospectionHandler.
+func NewIntrospectionHandler(service domain.IntrospectionService, clients map[string]string) *IntrospectionHandler {
+	return &IntrospectionHandler{Service: service, Clients: clients}
+}
+
+// authenticate checks the caller's HTTP Basic client credentials.
+func (h *IntrospectionHandler) authenticate(c *gin.Context) bool {
+	id, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		return false
+	}
+	want, known := h.Clients[id]
+	if !known || want == "" {
+		return false
+	}
+	// compare digests so the comparison does not depend on the secret's length
+	got, expected := sha256.Sum256([]byte(secret)), sha256.Sum256([]byte(want))
+	return subtle.ConstantTimeCompare(got[:], expected[:]) == 1
+}
+
+// Introspect handles POST /introspect with a form-encoded token. It is not routed by
+// the api-gateway.
+func (h *IntrospectionHandler) Introspect(c *gin.Context) {
+	if !h.authenticate(c) {
+		c.Header("WWW-Authenticate", `Basic realm="introspect"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		return
+	}
+	token := c.PostForm("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+	resp, err := h.Service.Introspect(token)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "temporarily_unavailable"})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
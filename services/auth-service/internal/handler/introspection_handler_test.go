@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+type stubIntrospectionService struct {
+	err error
+}
+
+func (s *stubIntrospectionService) Introspect(token string) (*model.IntrospectionResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if token != "good" {
+		return &model.IntrospectionResponse{}, nil
+	}
+	return &model.IntrospectionResponse{Active: true, Subject: "4"}, nil
+}
+
+func TestIntrospect_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		user, pass string
+		token      string
+		svcErr     error
+		want       int
+		wantBody   string
+	}{
+		{"no credentials", "", "", "good", nil, http.StatusUnauthorized, "invalid_client"},
+		{"wrong secret", "post-service", "nope", "good", nil, http.StatusUnauthorized, "invalid_client"},
+		{"unknown client", "other", "s3cret", "good", nil, http.StatusUnauthorized, "invalid_client"},
+		{"missing token", "post-service", "s3cret", "", nil, http.StatusBadRequest, "invalid_request"},
+		{"active", "post-service", "s3cret", "good", nil, http.StatusOK, `"active":true`},
+		{"inactive", "post-service", "s3cret", "bad", nil, http.StatusOK, `{"active":false}`},
+		{"store down", "post-service", "s3cret", "good", errors.New("redis down"), http.StatusServiceUnavailable, "temporarily_unavailable"},
+	}
+	for _, tc := range tests {
+		h := NewIntrospectionHandler(&stubIntrospectionService{err: tc.svcErr}, map[string]string{"post-service": "s3cret"})
+		r := gin.New()
+		r.POST("/introspect", h.Introspect)
+		req := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(url.Values{"token": {tc.token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if tc.user != "" {
+			req.SetBasicAuth(tc.user, tc.pass)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want || !strings.Contains(w.Body.String(), tc.wantBody) {
+			t.Fatalf("%s: expected %d with %s, got %d %s", tc.name, tc.want, tc.wantBody, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// mfaChallengeCookie carries the challenge of a login waiting for its second factor.
+const mfaChallengeCookie = "mfa_challenge"
+
+// dpopKeyHeader carries the thumbprint of the DPoP key whose proof the api-gateway
+// verified; the gateway strips it from client requests.
+const dpopKeyHeader = "X-DPoP-JKT"
+
+// MFAHandler lets signed-in users set up and manage TOTP two-factor authentication.
+type MFAHandler struct {
+	Service domain.MFAService
+	Audit   audit.Recorder // optional; enabling, disabling and new recovery codes are audited when set
+}
+
+// NewMFAHandler creates a new MFAHandler.
+func NewMFAHandler(service domain.MFAService) *MFAHandler {
+	return &MFAHandler{Service: service}
+}
+
+// mfaStatus maps MFAService errors to HTTP status codes.
+func mfaStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidMFACode), errors.Is(err, domain.ErrInvalidMFAChallenge):
+		return http.StatusUnauthorized
+	case errors.Is(err, domain.ErrLoginLocked):
+		return http.StatusTooManyRequests
+	case errors.Is(err, domain.ErrMFANotEnrolled), errors.Is(err, domain.ErrMFAAlreadyEnabled):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrUserNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// caller returns the signed-in user. Personal access tokens may not manage the
+// second factor that protects them.
+func (h *MFAHandler) caller(c *gin.Context) (uint, bool) {
+	userID, ok := userIDFromHeader(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, false
+	}
+	if rejectPAT(c, "manage two-factor authentication") {
+		return 0, false
+	}
+	return userID, true
+}
+
+// GetStatus handles GET /mfa.
+func (h *MFAHandler) GetStatus(c *gin.Context) {
+	userID, ok := h.caller(c)
+	if !ok {
+		return
+	}
+	status, err := h.Service.Status(userID)
+	if err != nil {
+		c.JSON(mfaStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// BeginEnrollment handles POST /mfa/totp and returns a new secret and its
+// provisioning URI for the authenticator app.
+func (h *MFAHandler) BeginEnrollment(c *gin.Context) {
+	userID, ok := h.caller(c)
+	if !ok {
+		return
+	}
+	enrollment, err := h.Service.BeginEnrollment(userID)
+	if err != nil {
+		c.JSON(mfaStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, enrollment)
+}
+
+// ConfirmEnrollment handles POST /mfa/totp/confirm with {"code"} and returns the
+// recovery codes, which are not shown again.
+func (h *MFAHandler) ConfirmEnrollment(c *gin.Context) {
+	userID, ok := h.caller(c)
+	if !ok {
+		return
+	}
+	var req model.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.Service.ConfirmEnrollment(userID, req.Code)
+	recordAuth(c, h.Audit, "mfa.enable", userID, err)
+	if err != nil {
+		c.JSON(mfaStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, model.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable handles DELETE /mfa/totp with {"code"}; a recovery code works too.
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID, ok := h.caller(c)
+	if !ok {
+		return
+	}
+	var req model.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err := h.Service.Disable(userID, req.Code)
+	recordAuth(c, h.Audit, "mfa.disable", userID, err)
+	if err != nil {
+		c.JSON(mfaStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes handles POST /mfa/recovery-codes with {"code"}.
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := h.caller(c)
+	if !ok {
+		return
+	}
+	var req model.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.Service.RegenerateRecoveryCodes(userID, req.Code)
+	recordAuth(c, h.Audit, "mfa.recovery_codes", userID, err)
+	if err != nil {
+		c.JSON(mfaStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, model.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// VerifyMFA handles POST /mfa/verify, the second step of a login. The challenge comes
+// from the body or the mfa_challenge cookie; on success the auth cookies are set.
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	if h.MFA == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": domain.ErrMFANotEnrolled.Error()})
+		return
+	}
+	var req model.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Challenge == "" {
+		req.Challenge, _ = c.Cookie(mfaChallengeCookie)
+	}
+	if req.Challenge == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": domain.ErrInvalidMFAChallenge.Error()})
+		return
+	}
+	resp, err := h.MFA.Verify(req.Challenge, req.Code, c.GetHeader(dpopKeyHeader))
+	if err != nil {
+		recordAuth(c, h.Audit, "auth.login", 0, err)
+		c.JSON(mfaStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	recordAuth(c, h.Audit, "auth.login", resp.User.ID, nil)
+	h.setMFAChallengeCookie(c, "", -1)
+	h.setAuthCookies(c, resp)
+	c.JSON(http.StatusOK, resp)
+}
+
+// setMFAChallengeCookie stores the challenge of a login waiting for its second factor;
+// a negative maxAge clears it.
+func (h *AuthHandler) setMFAChallengeCookie(c *gin.Context, challenge string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     mfaChallengeCookie,
+		Value:    challenge,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+type stubMFAService struct {
+	challenge string // the only valid challenge
+	code      string // the only valid code
+	began     int
+}
+
+func (s *stubMFAService) Status(userID uint) (*model.MFAStatus, error) {
+	return &model.MFAStatus{}, nil
+}
+func (s *stubMFAService) BeginEnrollment(userID uint) (*model.TOTPEnrollment, error) {
+	s.began++
+	return &model.TOTPEnrollment{Secret: "S", ProvisioningURI: "otpauth://totp/x"}, nil
+}
+func (s *stubMFAService) ConfirmEnrollment(userID uint, code string) ([]string, error) {
+	return []string{"aaaa-bbbb"}, nil
+}
+func (s *stubMFAService) Disable(userID uint, code string) error { return nil }
+func (s *stubMFAService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	return nil, nil
+}
+func (s *stubMFAService) Challenge(user *domain.User, newUser bool, jkt string) (string, error) {
+	return s.challenge, nil
+}
+func (s *stubMFAService) Verify(challenge, code, jkt string) (*model.LoginResponse, error) {
+	if challenge != s.challenge {
+		return nil, domain.ErrInvalidMFAChallenge
+	}
+	if code != s.code {
+		return nil, domain.ErrInvalidMFACode
+	}
+	return &model.LoginResponse{Token: "access", RefreshToken: "refresh", User: model.User{ID: 4}}, nil
+}
+
+func cookieValues(w *httptest.ResponseRecorder) map[string]string {
+	values := map[string]string{}
+	for _, c := range w.Result().Cookies() {
+		values[c.Name] = c.Value
+	}
+	return values
+}
+
+func TestVerifyMFA(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := &stubRecorder{}
+	h := newTestHandler(&stubAuthService{})
+	h.MFA = &stubMFAService{challenge: "ch1", code: "123456"}
+	h.Audit = rec
+	r := gin.New()
+	r.POST("/mfa/verify", h.VerifyMFA)
+
+	verify := func(body, cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mfa/verify", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: mfaChallengeCookie, Value: cookie})
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := verify(`{"code":"123456"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a challenge, got %d", w.Code)
+	}
+	if w := verify(`{"code":"654321"}`, "ch1"); w.Code != http.StatusUnauthorized || cookieValues(w)["access_token"] != "" {
+		t.Fatalf("expected 401 without cookies for a wrong code, got %d", w.Code)
+	}
+	w := verify(`{"code":"123456"}`, "ch1")
+	cookies := cookieValues(w)
+	if w.Code != http.StatusOK || cookies["access_token"] != "access" || cookies["refresh_token"] != "refresh" {
+		t.Fatalf("expected auth cookies, got %d %v", w.Code, cookies)
+	}
+	if v, ok := cookies[mfaChallengeCookie]; !ok || v != "" {
+		t.Fatalf("expected the challenge cookie to be cleared, got %v", cookies)
+	}
+	if n := len(rec.entries); n != 2 || rec.entries[1].Action != "auth.login" || rec.entries[1].ActorID != 4 {
+		t.Fatalf("unexpected audit entries %+v", rec.entries)
+	}
+	// the challenge may also come in the body
+	if w := verify(`{"challenge":"ch1","code":"123456"}`, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected body challenge to work, got %d", w.Code)
+	}
+}
+
+func TestPasswordLogin_MFARequired(t *testing.T) {
+	rec := &stubRecorder{}
+	w := postJSON(newPasswordRouter(&stubPasswordService{mfaToken: "ch1"}, rec), "/password/login", `{"email":"me@example.com","password":"correct horse battery"}`)
+	var body struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+		Token       string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK || !body.MFARequired || body.MFAToken != "ch1" || body.Token != "" {
+		t.Fatalf("expected a challenge, got %d %s", w.Code, w.Body.String())
+	}
+	cookies := cookieValues(w)
+	if cookies[mfaChallengeCookie] != "ch1" || cookies["access_token"] != "" {
+		t.Fatalf("expected only the challenge cookie, got %v", cookies)
+	}
+	if len(rec.entries) != 0 {
+		t.Fatalf("expected the login to be audited only after the second factor, got %+v", rec.entries)
+	}
+}
+
+func TestMFAHandler_RequiresBrowserSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := &stubMFAService{}
+	r := gin.New()
+	r.POST("/mfa/totp", NewMFAHandler(svc).BeginEnrollment)
+	for _, tc := range []struct {
+		userID, method string
+		want           int
+	}{
+		{"", "jwt", http.StatusUnauthorized},
+		{"4", "pat", http.StatusForbidden},
+		{"4", "jwt", http.StatusCreated},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/mfa/totp", nil)
+		req.Header.Set("X-User-Id", tc.userID)
+		req.Header.Set("X-Auth-Method", tc.method)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("user %q via %s: expected %d, got %d", tc.userID, tc.method, tc.want, w.Code)
+		}
+	}
+	if svc.began != 1 {
+		t.Fatalf("expected one enrollment, got %d", svc.began)
+	}
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// passwordStatus maps PasswordService errors to HTTP status codes.
+func passwordStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrPasswordLoginDisabled):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrRegistrationDisabled):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		return http.StatusUnauthorized
+	case errors.Is(err, domain.ErrLoginLocked):
+		return http.StatusTooManyRequests
+	case errors.Is(err, domain.ErrWeakPassword), errors.Is(err, domain.ErrInvalidResetToken), errors.Is(err, domain.ErrInvalidUsername):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrUsernameTaken):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrMailNotConfigured):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// passwords returns the password service, answering 404 when password login is off.
+func (h *AuthHandler) passwords(c *gin.Context) (domain.PasswordService, bool) {
+	if h.Passwords == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": domain.ErrPasswordLoginDisabled.Error()})
+		return nil, false
+	}
+	return h.Passwords, true
+}
+
+// PasswordLogin handles POST /password/login. It sets the same cookies as an OAuth login
+// and also returns the tokens. Users with two-factor authentication get
+// {"mfa_required":true,"mfa_token"} and the mfa_challenge cookie instead.
+func (h *AuthHandler) PasswordLogin(c *gin.Context) {
+	svc, ok := h.passwords(c)
+	if !ok {
+		return
+	}
+	var req model.PasswordLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.JKT = c.GetHeader(dpopKeyHeader)
+	resp, err := svc.Login(req, c.ClientIP())
+	if err != nil {
+		recordAuth(c, h.Audit, "auth.login", 0, err)
+		c.JSON(passwordStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if resp.MFARequired {
+		h.setMFAChallengeCookie(c, resp.MFAToken, 300)
+		c.JSON(http.StatusOK, gin.H{"mfa_required": true, "mfa_token": resp.MFAToken})
+		return
+	}
+	recordAuth(c, h.Audit, "auth.login", resp.User.ID, nil)
+	h.setAuthCookies(c, resp)
+	c.JSON(http.StatusOK, resp)
+}
+
+// Register handles POST /password/register. The account's password is set through the
+// mailed link.
+func (h *AuthHandler) Register(c *gin.Context) {
+	svc, ok := h.passwords(c)
+	if !ok {
+		return
+	}
+	var req model.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := svc.Register(req); err != nil {
+		c.JSON(passwordStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"status": "check your email to set a password"})
+}
+
+// ForgotPassword handles POST /password/forgot. It answers the same whether or not the
+// email has an account.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	svc, ok := h.passwords(c)
+	if !ok {
+		return
+	}
+	var req model.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := svc.RequestPasswordReset(req.Email); err != nil {
+		c.JSON(passwordStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"status": "if the email has an account, a reset link is on its way"})
+}
+
+// ResetPassword handles POST /password/reset.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	svc, ok := h.passwords(c)
+	if !ok {
+		return
+	}
+	var req model.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, err := svc.ResetPassword(req.Token, req.Password)
+	userID := uint(0)
+	if user != nil {
+		userID = user.ID
+	}
+	recordAuth(c, h.Audit, "auth.password_reset", userID, err)
+	if err != nil {
+		c.JSON(passwordStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
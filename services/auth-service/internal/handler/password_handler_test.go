@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+type stubPasswordService struct {
+	loginErr error
+	clientIP string
+	resetErr error
+	mfaToken string // when set, logins are challenged for a second factor
+}
+
+func (s *stubPasswordService) Login(req model.PasswordLoginRequest, clientIP string) (*model.LoginResponse, error) {
+	s.clientIP = clientIP
+	if s.loginErr != nil {
+		return nil, s.loginErr
+	}
+	if s.mfaToken != "" {
+		return &model.LoginResponse{MFARequired: true, MFAToken: s.mfaToken, User: model.User{ID: 4}}, nil
+	}
+	return &model.LoginResponse{Token: "access", RefreshToken: "refresh", User: model.User{ID: 4}}, nil
+}
+func (s *stubPasswordService) Register(req model.RegisterRequest) error { return nil }
+func (s *stubPasswordService) RequestPasswordReset(email string) error  { return nil }
+func (s *stubPasswordService) ResetPassword(token, password string) (*domain.User, error) {
+	if s.resetErr != nil {
+		return nil, s.resetErr
+	}
+	return &domain.User{ID: 4}, nil
+}
+func (s *stubPasswordService) BootstrapOwner(email, username, password string) (*domain.User, error) {
+	return nil, nil
+}
+
+func newPasswordRouter(svc domain.PasswordService, rec *stubRecorder) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := newTestHandler(&stubAuthService{})
+	if svc != nil {
+		h.Passwords = svc
+	}
+	if rec != nil {
+		h.Audit = rec
+	}
+	r := gin.New()
+	r.GET("/oauth/providers", h.ListProviders)
+	r.POST("/password/login", h.PasswordLogin)
+	r.POST("/password/forgot", h.ForgotPassword)
+	r.POST("/password/reset", h.ResetPassword)
+	return r
+}
+
+func postJSON(r *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPasswordRoutes_DisabledWithoutService(t *testing.T) {
+	r := newPasswordRouter(nil, nil)
+	if w := postJSON(r, "/password/login", `{"email":"me@example.com","password":"x"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when password login is off, got %d", w.Code)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oauth/providers", nil))
+	var body struct {
+		Password bool `json:"password"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Password {
+		t.Fatalf("expected password=false, got %s", w.Body.String())
+	}
+}
+
+func TestPasswordLogin_SetsCookies(t *testing.T) {
+	svc := &stubPasswordService{}
+	rec := &stubRecorder{}
+	w := postJSON(newPasswordRouter(svc, rec), "/password/login", `{"email":"me@example.com","password":"correct horse battery"}`)
+
+	if w.Code != http.StatusOK || svc.clientIP != "203.0.113.7" {
+		t.Fatalf("expected login from forwarded IP, got %d ip=%q", w.Code, svc.clientIP)
+	}
+	names := map[string]bool{}
+	for _, c := range w.Result().Cookies() {
+		names[c.Name] = true
+	}
+	if !names["access_token"] || !names["refresh_token"] || !names["userId"] {
+		t.Fatalf("expected auth cookies, got %v", names)
+	}
+	if len(rec.entries) != 1 || rec.entries[0].Action != "auth.login" || rec.entries[0].ActorID != 4 {
+		t.Fatalf("unexpected audit entries %+v", rec.entries)
+	}
+}
+
+func TestPasswordLogin_ErrorStatus(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{domain.ErrInvalidCredentials, http.StatusUnauthorized},
+		{domain.ErrLoginLocked, http.StatusTooManyRequests},
+	} {
+		w := postJSON(newPasswordRouter(&stubPasswordService{loginErr: tc.err}, nil), "/password/login", `{"email":"me@example.com","password":"x"}`)
+		if w.Code != tc.want {
+			t.Fatalf("%v: expected %d, got %d", tc.err, tc.want, w.Code)
+		}
+	}
+	if w := postJSON(newPasswordRouter(&stubPasswordService{}, nil), "/password/login", `{"email":"not-an-email","password":"x"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid email, got %d", w.Code)
+	}
+}
+
+func TestForgotAndResetPassword(t *testing.T) {
+	rec := &stubRecorder{}
+	r := newPasswordRouter(&stubPasswordService{}, rec)
+	if w := postJSON(r, "/password/forgot", `{"email":"me@example.com"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", w.Code)
+	}
+	if w := postJSON(r, "/password/reset", `{"token":"t","password":"a much better password"}`); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if len(rec.entries) != 1 || rec.entries[0].Action != "auth.password_reset" {
+		t.Fatalf("unexpected audit entries %+v", rec.entries)
+	}
+
+	r = newPasswordRouter(&stubPasswordService{resetErr: domain.ErrInvalidResetToken}, nil)
+	if w := postJSON(r, "/password/reset", `{"token":"t","password":"a much better password"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid token, got %d", w.Code)
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// ProfileHandler serves public profiles and lets signed-in users edit their own.
+type ProfileHandler struct {
+	Service domain.ProfileService
+	Audit   audit.Recorder // optional; profile changes are audited when set
+}
+
+// NewProfileHandler creates a new ProfileHandler.
+func NewProfileHandler(service domain.ProfileService) *ProfileHandler {
+	return &ProfileHandler{Service: service}
+}
+
+// profileStatus maps ProfileService errors to HTTP status codes.
+func profileStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidUsername), errors.Is(err, domain.ErrInvalidLink), errors.Is(err, domain.ErrInvalidAvatar):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrUsernameTaken):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// profileSummary is the audit representation of a profile.
+func profileSummary(u *domain.User) string {
+	if u == nil {
+		return ""
+	}
+	return audit.Summary(map[string]interface{}{
+		"username":     u.Username,
+		"display_name": u.DisplayName,
+		"avatar_url":   u.AvatarURL,
+		"links":        len(u.Links),
+	})
+}
+
+// GetProfile handles GET /users/:id/profile. It is public and leaves out the email,
+// role and provider identity.
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	profile, err := h.Service.GetProfile(uint(id))
+	if err != nil {
+		c.JSON(profileStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// UpdateProfile handles PUT /users/:id. Users may only edit their own profile.
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	userID, ok := userIDFromHeader(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if rejectPAT(c, "edit profiles") {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	if uint(id) != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "cannot edit another user's profile"})
+		return
+	}
+	var req model.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, err := h.Service.UpdateProfile(userID, req)
+	entry := &audit.Entry{Action: "user.profile_update", TargetType: "user", TargetID: c.Param("id")}
+	if err == nil {
+		entry.After = profileSummary(user)
+	}
+	audit.Record(c.Request.Context(), h.Audit, entry, err)
+	if err != nil {
+		c.JSON(profileStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+type stubProfileService struct {
+	updateErr error
+	updated   model.UpdateProfileRequest
+	imported  string
+}
+
+func (s *stubProfileService) GetProfile(userID uint) (*model.PublicProfile, error) {
+	if userID != 3 {
+		return nil, domain.ErrUserNotFound
+	}
+	return &model.PublicProfile{ID: 3, Username: "writer", DisplayName: "Writer"}, nil
+}
+func (s *stubProfileService) UpdateProfile(userID uint, req model.UpdateProfileRequest) (*domain.User, error) {
+	if s.updateErr != nil {
+		return nil, s.updateErr
+	}
+	s.updated = req
+	return &domain.User{ID: userID, Username: *req.Username}, nil
+}
+func (s *stubProfileService) ImportAvatar(userID uint, pictureURL string) error {
+	s.imported = pictureURL
+	return nil
+}
+
+func newProfileRouter(svc *stubProfileService, rec audit.Recorder) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewProfileHandler(svc)
+	h.Audit = rec
+	r := gin.New()
+	r.GET("/users/:id/profile", h.GetProfile)
+	r.PUT("/users/:id", h.UpdateProfile)
+	return r
+}
+
+func TestGetProfile(t *testing.T) {
+	r := newProfileRouter(&stubProfileService{}, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/3/profile", nil))
+
+	var p model.PublicProfile
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if w.Code != http.StatusOK || p.Username != "writer" || strings.Contains(w.Body.String(), "email") {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/4/profile", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing user, got %d", w.Code)
+	}
+}
+
+func TestUpdateProfile_OnlyOwnProfile(t *testing.T) {
+	r := newProfileRouter(&stubProfileService{}, nil)
+	cases := []struct {
+		userID string
+		method string
+		want   int
+	}{{"", "", http.StatusUnauthorized}, {"2", "jwt", http.StatusForbidden}, {"3", "pat", http.StatusForbidden}}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPut, "/users/3", strings.NewReader(`{"username":"writer"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.userID != "" {
+			req.Header.Set("X-User-Id", tc.userID)
+		}
+		if tc.method != "" {
+			req.Header.Set("X-Auth-Method", tc.method)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("as %q by %q: expected %d, got %d", tc.userID, tc.method, tc.want, w.Code)
+		}
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	svc := &stubProfileService{}
+	rec := &stubRecorder{}
+	r := newProfileRouter(svc, rec)
+	req := httptest.NewRequest(http.MethodPut, "/users/3", strings.NewReader(`{"username":"writer","bio":"hi"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Id", "3")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || svc.updated.Bio == nil || *svc.updated.Bio != "hi" {
+		t.Fatalf("expected update, got %d %s", w.Code, w.Body.String())
+	}
+	if len(rec.entries) != 1 || rec.entries[0].Action != "user.profile_update" || !strings.Contains(rec.entries[0].After, "writer") {
+		t.Fatalf("unexpected audit entries %+v", rec.entries)
+	}
+
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{domain.ErrUsernameTaken, http.StatusConflict},
+		{domain.ErrInvalidUsername, http.StatusBadRequest},
+		{domain.ErrInvalidLink, http.StatusBadRequest},
+		{domain.ErrInvalidAvatar, http.StatusBadRequest},
+	} {
+		r := newProfileRouter(&stubProfileService{updateErr: tc.err}, nil)
+		req := httptest.NewRequest(http.MethodPut, "/users/3", strings.NewReader(`{"username":"writer"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-Id", "3")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%v: expected %d, got %d", tc.err, tc.want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/service"
+)
+
+// TokenHandler handles personal access token HTTP requests.
+type TokenHandler struct {
+	Service domain.TokenService
+	Audit   audit.Recorder // optional; token creation and revocation are audited when set
+}
+
+// NewTokenHandler creates a new TokenHandler.
+func NewTokenHandler(service domain.TokenService) *TokenHandler {
+	return &TokenHandler{Service: service}
+}
+
+// userIDFromHeader reads the authenticated user id injected by the api-gateway.
+func userIDFromHeader(c *gin.Context) (uint, bool) {
+	userIDStr := c.GetHeader("X-User-Id")
+	if userIDStr == "" {
+		return 0, false
+	}
+	parsed, err := strconv.ParseUint(userIDStr, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(parsed), true
+}
+
+// rejectPAT answers 403 and returns true when the request was authenticated with a
+// personal access token. Whatever its scopes, a token may not manage the account
+// that issued it: its tokens, profile or second factor.
+func rejectPAT(c *gin.Context, what string) bool {
+	if c.GetHeader("X-Auth-Method") != "pat" {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "personal access tokens cannot " + what})
+	return true
+}
+
+// CreateToken handles POST /tokens. The plaintext token is only returned in this response.
+func (h *TokenHandler) CreateToken(c *gin.Context) {
+	userID, ok := userIDFromHeader(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	// a personal access token must not be able to mint further tokens
+	if rejectPAT(c, "create tokens") {
+		return
+	}
+	var req model.CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.Service.CreateToken(userID, req)
+	entry := &audit.Entry{Action: "token.create", TargetType: "personal_access_token"}
+	if err == nil {
+		entry.TargetID = strconv.FormatUint(uint64(resp.Info.ID), 10)
+		entry.After = audit.Summary(map[string]interface{}{"name": resp.Info.Name, "scopes": resp.Info.Scopes, "expires_at": resp.Info.ExpiresAt, "dpop_bound": resp.Info.DPoPJKT != ""})
+	}
+	audit.Record(c.Request.Context(), h.Audit, entry, err)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// ListTokens handles GET /tokens and lists the caller's tokens.
+func (h *TokenHandler) ListTokens(c *gin.Context) {
+	userID, ok := userIDFromHeader(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if rejectPAT(c, "list tokens") {
+		return
+	}
+	tokens, err := h.Service.ListTokens(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RevokeToken handles DELETE /tokens/:id.
+func (h *TokenHandler) RevokeToken(c *gin.Context) {
+	userID, ok := userIDFromHeader(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if rejectPAT(c, "revoke tokens") {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	err = h.Service.RevokeToken(userID, uint(id))
+	audit.Record(c.Request.Context(), h.Audit, &audit.Entry{Action: "token.revoke", TargetType: "personal_access_token", TargetID: c.Param("id")}, err)
+	if err != nil {
+		if errors.Is(err, domain.ErrTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ValidateToken handles POST /tokens/validate. It is called by the api-gateway only.
+func (h *TokenHandler) ValidateToken(c *gin.Context) {
+	var req model.ValidateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token required"})
+		return
+	}
+	resp, err := h.Service.ValidateToken(req.Token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/service"
+)
+
+type stubTokenService struct {
+	createFn   func(userID uint, req model.CreateTokenRequest) (*model.CreateTokenResponse, error)
+	revokeFn   func(userID, tokenID uint) error
+	validateFn func(token string) (*model.ValidateTokenResponse, error)
+}
+
+func (s *stubTokenService) CreateToken(userID uint, req model.CreateTokenRequest) (*model.CreateTokenResponse, error) {
+	return s.createFn(userID, req)
+}
+func (s *stubTokenService) ListTokens(userID uint) ([]*domain.PersonalAccessToken, error) {
+	return []*domain.PersonalAccessToken{{ID: 1, UserID: userID}}, nil
+}
+func (s *stubTokenService) RevokeToken(userID, tokenID uint) error {
+	return s.revokeFn(userID, tokenID)
+}
+func (s *stubTokenService) ValidateToken(token string) (*model.ValidateTokenResponse, error) {
+	return s.validateFn(token)
+}
+
+func newTokenRouter(svc domain.TokenService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewTokenHandler(svc)
+	r := gin.New()
+	r.POST("/tokens", h.CreateToken)
+	r.GET("/tokens", h.ListTokens)
+	r.DELETE("/tokens/:id", h.RevokeToken)
+	r.POST("/tokens/validate", h.ValidateToken)
+	return r
+}
+
+func TestCreateToken_Handler(t *testing.T) {
+	r := newTokenRouter(&stubTokenService{
+		createFn: func(userID uint, req model.CreateTokenRequest) (*model.CreateTokenResponse, error) {
+			return &model.CreateTokenResponse{Token: "pws_x"}, nil
+		},
+	})
+	tests := []struct {
+		name   string
+		userID string
+		method string
+		body   string
+		want   int
+	}{
+		{"missing user", "", "", `{"name":"ci","scopes":["posts:write"]}`, http.StatusUnauthorized},
+		{"pat cannot mint", "1", "pat", `{"name":"ci","scopes":["posts:write"]}`, http.StatusForbidden},
+		{"bad body", "1", "jwt", `{"name":""}`, http.StatusBadRequest},
+		{"success", "1", "jwt", `{"name":"ci","scopes":["posts:write"]}`, http.StatusCreated},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/tokens", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.userID != "" {
+			req.Header.Set("X-User-Id", tc.userID)
+		}
+		if tc.method != "" {
+			req.Header.Set("X-Auth-Method", tc.method)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d", tc.name, tc.want, w.Code)
+		}
+	}
+}
+
+func TestRevokeToken_Handler(t *testing.T) {
+	r := newTokenRouter(&stubTokenService{
+		revokeFn: func(userID, tokenID uint) error {
+			if tokenID == 404 {
+				return domain.ErrTokenNotFound
+			}
+			return nil
+		},
+	})
+	cases := map[string]int{"/tokens/1": http.StatusNoContent, "/tokens/404": http.StatusNotFound, "/tokens/x": http.StatusBadRequest}
+	for path, want := range cases {
+		req := httptest.NewRequest(http.MethodDelete, path, nil)
+		req.Header.Set("X-User-Id", "1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("%s: expected %d, got %d", path, want, w.Code)
+		}
+	}
+}
+
+func TestTokenManagement_RejectsPersonalAccessTokens(t *testing.T) {
+	r := newTokenRouter(&stubTokenService{
+		revokeFn: func(userID, tokenID uint) error {
+			t.Fatalf("did not expect a personal access token to revoke token %d", tokenID)
+			return nil
+		},
+	})
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodGet, "/tokens"},
+		{http.MethodDelete, "/tokens/1"},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("X-User-Id", "1")
+		req.Header.Set("X-Auth-Method", "pat")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("%s %s: expected 403, got %d", tc.method, tc.path, w.Code)
+		}
+	}
+}
+
+func TestValidateToken_Handler(t *testing.T) {
+	r := newTokenRouter(&stubTokenService{
+		validateFn: func(token string) (*model.ValidateTokenResponse, error) {
+			if token == "pws_ok" {
+				return &model.ValidateTokenResponse{UserID: 1, Username: "u", Scopes: "posts:write"}, nil
+			}
+			return nil, service.ErrInvalidToken
+		},
+	})
+	cases := map[string]int{`{"token":"pws_ok"}`: http.StatusOK, `{"token":"pws_bad"}`: http.StatusUnauthorized, `{}`: http.StatusBadRequest}
+	for body, want := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/tokens/validate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("%s: expected %d, got %d", body, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// UserAdminHandler serves the owner-only user and role management API.
+type UserAdminHandler struct {
+	Service domain.UserAdminService
+	Audit   audit.Recorder // optional; user changes are audited when set
+}
+
+// NewUserAdminHandler creates a new UserAdminHandler.
+func NewUserAdminHandler(service domain.UserAdminService) *UserAdminHandler {
+	return &UserAdminHandler{Service: service}
+}
+
+// requireOwner aborts unless the caller injected by the api-gateway is an owner signed in
+// with a session; personal access tokens never reach the admin API.
+func requireOwner(c *gin.Context, users domain.UserAdminService) bool {
+	userID, ok := userIDFromHeader(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return false
+	}
+	if rejectPAT(c, "use the admin API") {
+		return false
+	}
+	if err := users.RequireOwner(userID); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// userSummary is the audit representation of a user.
+func userSummary(u *domain.User) string {
+	if u == nil {
+		return ""
+	}
+	return audit.Summary(map[string]interface{}{"email": u.Email, "username": u.Username, "role": u.Role})
+}
+
+// adminStatus maps UserAdminService errors to HTTP status codes.
+func adminStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidRole):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrUserExists), errors.Is(err, domain.ErrLastOwner):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ListUsers handles GET /admin/users.
+func (h *UserAdminHandler) ListUsers(c *gin.Context) {
+	if !requireOwner(c, h.Service) {
+		return
+	}
+	users, err := h.Service.ListUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+// CreateUser handles POST /admin/users and pre-registers an account by email.
+func (h *UserAdminHandler) CreateUser(c *gin.Context) {
+	if !requireOwner(c, h.Service) {
+		return
+	}
+	var req model.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, err := h.Service.CreateUser(req)
+	entry := &audit.Entry{Action: "user.create", TargetType: "user"}
+	if err == nil {
+		entry.TargetID = strconv.FormatUint(uint64(user.ID), 10)
+		entry.After = userSummary(user)
+	}
+	audit.Record(c.Request.Context(), h.Audit, entry, err)
+	if err != nil {
+		c.JSON(adminStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateRole handles PUT /admin/users/:id/role.
+func (h *UserAdminHandler) UpdateRole(c *gin.Context) {
+	if !requireOwner(c, h.Service) {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	var req model.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	before, after, err := h.Service.UpdateRole(uint(id), req.Role)
+	audit.Record(c.Request.Context(), h.Audit, &audit.Entry{
+		Action:     "user.role_update",
+		TargetType: "user",
+		TargetID:   c.Param("id"),
+		Before:     userSummary(before),
+		After:      userSummary(after),
+	}, err)
+	if err != nil {
+		c.JSON(adminStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, after)
+}
+
+// DeleteUser handles DELETE /admin/users/:id.
+func (h *UserAdminHandler) DeleteUser(c *gin.Context) {
+	if !requireOwner(c, h.Service) {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	user, err := h.Service.DeleteUser(uint(id))
+	audit.Record(c.Request.Context(), h.Audit, &audit.Entry{
+		Action:     "user.delete",
+		TargetType: "user",
+		TargetID:   c.Param("id"),
+		Before:     userSummary(user),
+	}, err)
+	if err != nil {
+		c.JSON(adminStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
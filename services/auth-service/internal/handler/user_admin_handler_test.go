@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// stubUserAdmin treats ownerID as the only owner.
+type stubUserAdmin struct {
+	ownerID     uint
+	users       []*domain.User
+	createErr   error
+	updateRole  string
+	updateErr   error
+	deletedUser uint
+}
+
+func (s *stubUserAdmin) RequireOwner(userID uint) error {
+	if userID != s.ownerID {
+		return domain.ErrNotOwner
+	}
+	return nil
+}
+func (s *stubUserAdmin) ListUsers() ([]*domain.User, error) { return s.users, nil }
+func (s *stubUserAdmin) CreateUser(req model.CreateUserRequest) (*domain.User, error) {
+	if s.createErr != nil {
+		return nil, s.createErr
+	}
+	return &domain.User{ID: 9, Email: req.Email, Role: req.Role}, nil
+}
+func (s *stubUserAdmin) UpdateRole(userID uint, role string) (*domain.User, *domain.User, error) {
+	if s.updateErr != nil {
+		return nil, nil, s.updateErr
+	}
+	s.updateRole = role
+	return &domain.User{ID: userID, Role: "reader"}, &domain.User{ID: userID, Role: role}, nil
+}
+func (s *stubUserAdmin) DeleteUser(userID uint) (*domain.User, error) {
+	s.deletedUser = userID
+	return &domain.User{ID: userID}, nil
+}
+
+func newUserAdminRouter(svc *stubUserAdmin, rec audit.Recorder) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewUserAdminHandler(svc)
+	h.Audit = rec
+	r := gin.New()
+	r.GET("/admin/users", h.ListUsers)
+	r.POST("/admin/users", h.CreateUser)
+	r.PUT("/admin/users/:id/role", h.UpdateRole)
+	r.DELETE("/admin/users/:id", h.DeleteUser)
+	return r
+}
+
+func TestUserAdmin_RequiresOwner(t *testing.T) {
+	r := newUserAdminRouter(&stubUserAdmin{ownerID: 1}, nil)
+	cases := []struct {
+		method, path, userID string
+		want                 int
+	}{
+		{http.MethodGet, "/admin/users", "", http.StatusUnauthorized},
+		{http.MethodGet, "/admin/users", "2", http.StatusForbidden},
+		{http.MethodPost, "/admin/users", "2", http.StatusForbidden},
+		{http.MethodPut, "/admin/users/3/role", "2", http.StatusForbidden},
+		{http.MethodDelete, "/admin/users/3", "2", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{}`))
+		if tc.userID != "" {
+			req.Header.Set("X-User-Id", tc.userID)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s %s as %q: expected %d, got %d", tc.method, tc.path, tc.userID, tc.want, w.Code)
+		}
+	}
+}
+
+func TestUserAdmin_RejectsPersonalAccessTokens(t *testing.T) {
+	r := newUserAdminRouter(&stubUserAdmin{ownerID: 1}, nil)
+	for _, path := range []string{"/admin/users", "/admin/users/3/role"} {
+		method := http.MethodGet
+		if path != "/admin/users" {
+			method = http.MethodPut
+		}
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"role":"owner"}`))
+		req.Header.Set("X-User-Id", "1")
+		req.Header.Set("X-Auth-Method", "pat")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("%s %s with an owner PAT: expected 403, got %d", method, path, w.Code)
+		}
+	}
+}
+
+func TestUserAdmin_ListUsers(t *testing.T) {
+	r := newUserAdminRouter(&stubUserAdmin{ownerID: 1, users: []*domain.User{{ID: 1, Role: "owner"}, {ID: 2, Role: "editor"}}}, nil)
+	req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+	req.Header.Set("X-User-Id", "1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body struct {
+		Users []domain.User `json:"users"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if w.Code != http.StatusOK || len(body.Users) != 2 || body.Users[1].Role != "editor" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUserAdmin_CreateUser(t *testing.T) {
+	rec := &stubRecorder{}
+	r := newUserAdminRouter(&stubUserAdmin{ownerID: 1}, rec)
+	req := httptest.NewRequest(http.MethodPost, "/admin/users", strings.NewReader(`{"email":"co@example.com","role":"editor"}`))
+	req.Header.Set("X-User-Id", "1")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(rec.entries) != 1 || rec.entries[0].Action != "user.create" || rec.entries[0].TargetID != "9" {
+		t.Fatalf("unexpected audit entries %+v", rec.entries)
+	}
+
+	r = newUserAdminRouter(&stubUserAdmin{ownerID: 1, createErr: domain.ErrUserExists}, nil)
+	req = httptest.NewRequest(http.MethodPost, "/admin/users", strings.NewReader(`{"email":"co@example.com","role":"editor"}`))
+	req.Header.Set("X-User-Id", "1")
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for existing user, got %d", w.Code)
+	}
+}
+
+func TestUserAdmin_UpdateRole(t *testing.T) {
+	svc := &stubUserAdmin{ownerID: 1}
+	rec := &stubRecorder{}
+	r := newUserAdminRouter(svc, rec)
+	req := httptest.NewRequest(http.MethodPut, "/admin/users/3/role", strings.NewReader(`{"role":"editor"}`))
+	req.Header.Set("X-User-Id", "1")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || svc.updateRole != "editor" {
+		t.Fatalf("expected role update, got %d role=%q", w.Code, svc.updateRole)
+	}
+	e := rec.entries[0]
+	if e.Action != "user.role_update" || !strings.Contains(e.Before, "reader") || !strings.Contains(e.After, "editor") {
+		t.Fatalf("unexpected audit entry %+v", e)
+	}
+
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{{domain.ErrLastOwner, http.StatusConflict}, {domain.ErrInvalidRole, http.StatusBadRequest}, {domain.ErrUserNotFound, http.StatusNotFound}} {
+		r := newUserAdminRouter(&stubUserAdmin{ownerID: 1, updateErr: tc.err}, nil)
+		req := httptest.NewRequest(http.MethodPut, "/admin/users/3/role", strings.NewReader(`{"role":"reader"}`))
+		req.Header.Set("X-User-Id", "1")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%v: expected %d, got %d", tc.err, tc.want, w.Code)
+		}
+	}
+}
+
+func TestUserAdmin_DeleteUser(t *testing.T) {
+	svc := &stubUserAdmin{ownerID: 1}
+	r := newUserAdminRouter(svc, nil)
+	req := httptest.NewRequest(http.MethodDelete, "/admin/users/4", nil)
+	req.Header.Set("X-User-Id", "1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent || svc.deletedUser != 4 {
+		t.Fatalf("expected delete, got %d user=%d", w.Code, svc.deletedUser)
+	}
+}
@@ -4,26 +4,124 @@ import "time"
 
 // User represents a user entity
 type User struct {
-	ID         uint      `json:"id" db:"id"`
-	Username   string    `json:"username" db:"username"`
-	Email      string    `json:"email" db:"email"`
-	Provider   string    `json:"provider,omitempty" db:"provider"`
-	ProviderID string    `json:"provider_id,omitempty" db:"provider_id"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	ID           uint         `json:"id" db:"id"`
+	Username     string       `json:"username" db:"username"`
+	Email        string       `json:"email" db:"email"`
+	Provider     string       `json:"provider,omitempty" db:"provider"`
+	ProviderID   string       `json:"provider_id,omitempty" db:"provider_id"`
+	Role         string       `json:"role" db:"role" gorm:"type:text;not null;default:'reader'"`
+	DisplayName  string       `json:"display_name" db:"display_name" gorm:"type:text"`
+	Bio          string       `json:"bio" db:"bio" gorm:"type:text"`
+	AvatarURL    string       `json:"avatar_url" db:"avatar_url" gorm:"type:text"` // img-service path
+	Links        []SocialLink `json:"links" db:"links" gorm:"type:text;serializer:json"`
+	PasswordHash string       `json:"-" db:"password_hash" gorm:"type:text"` // bcrypt; empty for provider-only accounts
+	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// SocialLink is a labelled link shown on a public profile.
+type SocialLink struct {
+	Label string `json:"label" binding:"required,max=30"`
+	URL   string `json:"url" binding:"required,max=300"`
+}
+
+// PublicProfile is the part of a user that anyone may see, e.g. next to their articles.
+type PublicProfile struct {
+	ID          uint         `json:"id"`
+	Username    string       `json:"username"`
+	DisplayName string       `json:"display_name"`
+	Bio         string       `json:"bio"`
+	AvatarURL   string       `json:"avatar_url"`
+	Links       []SocialLink `json:"links"`
+}
+
+// UpdateProfileRequest changes the caller's profile. Only the fields that are sent change.
+type UpdateProfileRequest struct {
+	Username    *string       `json:"username" binding:"omitempty,min=3,max=30"`
+	DisplayName *string       `json:"display_name" binding:"omitempty,max=100"`
+	Bio         *string       `json:"bio" binding:"omitempty,max=1000"`
+	Links       *[]SocialLink `json:"links" binding:"omitempty,max=10,dive"`
+	Avatar      string        `json:"avatar,omitempty"` // data URL of a new avatar image
+}
+
+// PasswordLoginRequest logs in with email and password.
+type PasswordLoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+	JKT      string `json:"-"` // DPoP key thumbprint verified by the api-gateway
+}
+
+// RegisterRequest signs up for password login. The password is set through the
+// link mailed to the address, which proves it belongs to the caller.
+type RegisterRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Username string `json:"username" binding:"required,min=3,max=30"`
+}
+
+// ForgotPasswordRequest asks for a password reset link.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password with a token from a reset link.
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
 }
 
 // LoginResponse represents the login response payload
 type LoginResponse struct {
 	Token        string `json:"token"`
+	TokenType    string `json:"token_type,omitempty"` // DPoP for tokens bound to a key
 	ExpiresAt    int64  `json:"expires_at"`
 	RefreshToken string `json:"refresh_token"`
 	User         User   `json:"user"`
+	NewUser      bool   `json:"new_user"` // the account was created by this login
+	// MFARequired means the password or provider login succeeded but the user has
+	// two-factor authentication on: no tokens are issued until MFAToken is verified.
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+	// ReturnTo is the page a provider login was started from; it is only used to
+	// redirect the browser and never serialized.
+	ReturnTo string `json:"-"`
+}
+
+// OAuthUserInfo is the identity an OAuth/OIDC provider returned, mapped to common fields.
+type OAuthUserInfo struct {
+	Provider      string `json:"provider"`
+	Subject       string `json:"subject"` // stable user id at the provider
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	AvatarURL     string `json:"avatar_url,omitempty"`
+	HostedDomain  string `json:"hosted_domain,omitempty"` // Google Workspace domain (hd claim)
+}
+
+// CreateUserRequest pre-registers an account that is linked on its first login
+// with a verified matching email.
+type CreateUserRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Username string `json:"username" binding:"omitempty,max=100"`
+	Role     string `json:"role" binding:"required"`
+}
+
+// UpdateRoleRequest changes a user's role.
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// RefreshRotation records the token pair issued when a refresh token was rotated,
+// so the same pair can be handed out again within the reuse grace window.
+type RefreshRotation struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
-// GoogleUserInfo represents the user info from Google.
-type GoogleUserInfo struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-	Name  string `json:"name"`
+// OAuthState is the server-side half of a login that is waiting for the provider's
+// callback. It is stored under the state parameter and consumed exactly once.
+type OAuthState struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"code_verifier"`       // PKCE verifier sent with the code exchange
+	Nonce        string `json:"nonce"`               // expected nonce claim of the ID token
+	ReturnTo     string `json:"return_to,omitempty"` // validated page to send the user back to
 }
@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// TOTPCredential is a user's RFC 6238 authenticator secret. It is created unconfirmed
+// by enrollment and only enforced once Enabled.
+type TOTPCredential struct {
+	UserID    uint   `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Secret    string `json:"-" gorm:"type:text;not null"` // base32, as shown to the authenticator app
+	Enabled   bool   `json:"enabled" gorm:"not null;default:false"`
+	LastStep  int64  `json:"-" gorm:"not null;default:0"` // last accepted time step, so a code works once
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RecoveryCode is a single-use code that replaces the authenticator app once.
+// Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"type:text;not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// MFAChallenge is a login that passed its first factor and waits for the second.
+// It is stored under the challenge token and consumed once verified.
+type MFAChallenge struct {
+	UserID  uint   `json:"user_id"`
+	NewUser bool   `json:"new_user"`
+	JKT     string `json:"jkt,omitempty"` // DPoP key thumbprint of the first step
+}
+
+// MFAStatus describes the caller's two-factor authentication setup.
+type MFAStatus struct {
+	Enabled           bool  `json:"enabled"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+// TOTPEnrollment is the secret of a new authenticator, shown once while enrolling.
+// ProvisioningURI is the otpauth:// URI authenticator apps read from a QR code.
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFACodeRequest carries an authenticator code or a recovery code.
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+// MFAVerifyRequest completes a login with a second factor. The challenge may also
+// come from the mfa_challenge cookie.
+type MFAVerifyRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code" binding:"required,max=32"`
+}
+
+// RecoveryCodesResponse returns freshly generated recovery codes, shown only once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,75 @@
+package model
+
+import "time"
+
+// PersonalAccessToken is a named, scoped, revocable token used by scripts and CI.
+// Only the SHA-256 hash of the token is stored.
+type PersonalAccessToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"index;not null"`
+	Name       string     `json:"name" gorm:"type:text;not null"`
+	TokenHash  string     `json:"-" gorm:"type:text;uniqueIndex;not null"`
+	Prefix     string     `json:"prefix" gorm:"type:text"`             // first characters of the token, for display
+	Scopes     string     `json:"scopes" gorm:"type:text"`             // space-separated
+	DPoPJKT    string     `json:"dpop_jkt,omitempty" gorm:"type:text"` // DPoP key the token is bound to
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	UsageCount int64      `json:"usage_count" gorm:"not null;default:0"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateTokenRequest represents the request payload for issuing a personal access token
+type CreateTokenRequest struct {
+	Name          string   `json:"name" binding:"required,min=1,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty" binding:"omitempty,min=1,max=3650"` // 0 means no expiry
+	// DPoPJKT binds the token to the DPoP key with this RFC 7638 thumbprint
+	DPoPJKT string `json:"dpop_jkt,omitempty" binding:"omitempty,len=43,base64rawurl"`
+}
+
+// CreateTokenResponse returns the plaintext token once, together with its metadata
+type CreateTokenResponse struct {
+	Token string              `json:"token"`
+	Info  PersonalAccessToken `json:"info"`
+}
+
+// ValidateTokenRequest is sent by the gateway to check a personal access token
+type ValidateTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ValidateTokenResponse describes the identity behind a valid personal access token
+type ValidateTokenResponse struct {
+	UserID    uint       `json:"user_id"`
+	Username  string     `json:"username"`
+	Role      string     `json:"role"`
+	Scopes    string     `json:"scopes"`
+	TokenID   uint       `json:"token_id"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	DPoPJKT   string     `json:"dpop_jkt,omitempty"` // requests must carry a DPoP proof from this key
+}
+
+// IntrospectionResponse is an RFC 7662 token introspection response. Only Active is
+// set for tokens that are unknown, expired or revoked.
+type IntrospectionResponse struct {
+	Active     bool   `json:"active"`
+	Scope      string `json:"scope,omitempty"` // space-separated
+	Username   string `json:"username,omitempty"`
+	Subject    string `json:"sub,omitempty"` // user ID
+	ExpiresAt  int64  `json:"exp,omitempty"`
+	IssuedAt   int64  `json:"iat,omitempty"`
+	SessionID  string `json:"sid,omitempty"`         // login session of a JWT
+	TokenID    uint   `json:"token_id,omitempty"`    // ID of a personal access token
+	AuthMethod string `json:"auth_method,omitempty"` // jwt or pat
+	Role       string `json:"role,omitempty"`
+	MFA        bool   `json:"mfa,omitempty"`
+	// Confirmation is the DPoP key a sender-constrained token is bound to
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+}
+
+// Confirmation is the cnf member of an introspection response (RFC 9449).
+type Confirmation struct {
+	JKT string `json:"jkt"`
+}
@@ -0,0 +1,78 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+const githubAPIURL = "https://api.github.com"
+
+var githubClaims = ClaimMapping{
+	Subject: "id",
+	Email:   "email",
+	Name:    "name,login",
+	Avatar:  "avatar_url",
+}
+
+// githubProvider adds GitHub's separate email endpoint, which is the only place
+// that reports whether the address is verified.
+type githubProvider struct {
+	userInfoProvider
+	apiURL string
+}
+
+// NewGitHub creates the GitHub provider.
+func NewGitHub(name, clientID, clientSecret, redirectURL string, scopes []string, claims ClaimMapping, client *http.Client) Provider {
+	return newGitHub(name, clientID, clientSecret, redirectURL, scopes, claims, client, github.Endpoint, githubAPIURL)
+}
+
+func newGitHub(name, clientID, clientSecret, redirectURL string, scopes []string, claims ClaimMapping, client *http.Client, endpoint oauth2.Endpoint, apiURL string) *githubProvider {
+	if len(scopes) == 0 {
+		scopes = []string{"read:user", "user:email"}
+	}
+	apiURL = strings.TrimRight(apiURL, "/")
+	return &githubProvider{
+		userInfoProvider: userInfoProvider{
+			name: name,
+			config: &oauth2.Config{
+				ClientID:     clientID,
+				ClientSecret: clientSecret,
+				RedirectURL:  redirectURL,
+				Scopes:       scopes,
+				Endpoint:     endpoint,
+			},
+			userInfoURL: apiURL + "/user",
+			claims:      claims.merge(githubClaims),
+			client:      client,
+		},
+		apiURL: apiURL,
+	}
+}
+
+// UserInfo ignores nonce: GitHub is a plain OAuth 2.0 provider without ID tokens.
+func (p *githubProvider) UserInfo(ctx context.Context, token *oauth2.Token, nonce string) (*model.OAuthUserInfo, error) {
+	info, err := p.userInfoProvider.UserInfo(ctx, token, nonce)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := p.fetchJSON(ctx, token, p.apiURL+"/user/emails")
+	if err != nil {
+		return nil, err
+	}
+	emails, _ := raw.([]interface{})
+	for _, e := range emails {
+		entry, ok := e.(map[string]interface{})
+		if !ok || claimString(entry, "primary") != "true" {
+			continue
+		}
+		info.Email = claimString(entry, "email")
+		info.EmailVerified = claimString(entry, "verified") == "true"
+		break
+	}
+	return info, nil
+}
@@ -0,0 +1,32 @@
+package oauth
+
+import (
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	googleUserInfoURL = "https://openidconnect.googleapis.com/v1/userinfo"
+	googleJWKSURL     = "https://www.googleapis.com/oauth2/v3/certs"
+)
+
+// googleIssuers are the iss values Google puts in its ID tokens.
+var googleIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
+// NewGoogle creates the Google provider. Google is an OpenID Connect provider with
+// well-known endpoints, so no discovery request is made.
+func NewGoogle(name, clientID, clientSecret, redirectURL string, scopes []string, claims ClaimMapping, client *http.Client) Provider {
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	config := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+		Endpoint:     google.Endpoint,
+	}
+	return newOIDCProvider(name, config, googleUserInfoURL, claims, client, newKeySet(googleJWKSURL, client), googleIssuers...)
+}
@@ -0,0 +1,158 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// jwksMinRefresh limits how often an unknown key ID triggers a JWKS refetch.
+const jwksMinRefresh = time.Minute
+
+// keySet caches an issuer's JSON Web Key Set. Keys are fetched on first use and
+// refetched when a token names a key we do not know, since issuers rotate keys.
+type keySet struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]interface{}
+	fetched time.Time
+}
+
+func newKeySet(url string, client *http.Client) *keySet {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &keySet{url: url, client: client}
+}
+
+// jsonWebKey is the subset of RFC 7517 fields used for RSA and EC signing keys.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// key returns the public key with the given ID. An empty kid matches when the set
+// holds a single key.
+func (s *keySet) key(ctx context.Context, kid string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if k, ok := s.lookup(kid); ok {
+		return k, nil
+	}
+	if s.keys == nil || time.Since(s.fetched) >= jwksMinRefresh {
+		if err := s.refresh(ctx); err != nil {
+			return nil, err
+		}
+		if k, ok := s.lookup(kid); ok {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (s *keySet) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			return k, true
+		}
+	}
+	k, ok := s.keys[kid]
+	return k, ok
+}
+
+func (s *keySet) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build jwks request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch jwks: status %d", resp.StatusCode)
+	}
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to decode jwks: %w", err)
+	}
+	keys := make(map[string]interface{}, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped rather than failing the whole set
+		if k, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = k
+		}
+	}
+	s.keys = keys
+	s.fetched = time.Now()
+	return nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("invalid EC key %q", k.Kid)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	bb, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(bb) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(bb), nil
+}
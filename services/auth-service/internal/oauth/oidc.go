@@ -0,0 +1,180 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+var oidcClaims = ClaimMapping{
+	Subject:       "sub",
+	Email:         "email",
+	EmailVerified: "email_verified",
+	Name:          "name,preferred_username",
+	Avatar:        "picture",
+	HostedDomain:  "hd",
+}
+
+// idTokenLeeway tolerates clock skew between us and the issuer.
+const idTokenLeeway = time.Minute
+
+// discoveryDocument is the subset of the OpenID Provider Metadata we rely on.
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcProvider reads the identity from the ID token returned with the access token,
+// after checking its signature against the issuer's JWKS, its issuer, audience,
+// expiry and nonce. Claims the ID token lacks are taken from the user info endpoint.
+type oidcProvider struct {
+	userInfoProvider
+	issuers []string
+	keys    *keySet
+}
+
+// NewOIDC discovers the issuer's endpoints from issuerURL/.well-known/openid-configuration
+// and creates a provider for it.
+func NewOIDC(ctx context.Context, name, issuerURL, clientID, clientSecret, redirectURL string, scopes []string, claims ClaimMapping, client *http.Client) (Provider, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	doc, err := discover(ctx, client, issuerURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	config := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  doc.AuthorizationEndpoint,
+			TokenURL: doc.TokenEndpoint,
+		},
+	}
+	return newOIDCProvider(name, config, doc.UserInfoEndpoint, claims, client, newKeySet(doc.JWKSURI, client), doc.Issuer), nil
+}
+
+func newOIDCProvider(name string, config *oauth2.Config, userInfoURL string, claims ClaimMapping, client *http.Client, keys *keySet, issuers ...string) *oidcProvider {
+	return &oidcProvider{
+		userInfoProvider: userInfoProvider{
+			name:        name,
+			config:      config,
+			userInfoURL: userInfoURL,
+			claims:      claims.merge(oidcClaims),
+			client:      client,
+		},
+		issuers: issuers,
+		keys:    keys,
+	}
+}
+
+func (p *oidcProvider) UserInfo(ctx context.Context, token *oauth2.Token, nonce string) (*model.OAuthUserInfo, error) {
+	raw, _ := token.Extra("id_token").(string)
+	if raw == "" {
+		return nil, fmt.Errorf("provider %s did not return an id_token", p.name)
+	}
+	claims, err := p.verifyIDToken(ctx, raw, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if p.userInfoURL != "" {
+		extra, err := p.fetchClaims(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		// User info must describe the ID token's subject (OIDC Core 5.3.2)
+		if claimString(extra, "sub") != claimString(claims, "sub") {
+			return nil, fmt.Errorf("user info subject does not match id_token")
+		}
+		for k, v := range extra {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+	return mapClaims(p.name, claims, p.claims)
+}
+
+// verifyIDToken checks raw and returns its claims.
+func (p *oidcProvider) verifyIDToken(ctx context.Context, raw, nonce string) (map[string]interface{}, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384"}),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(idTokenLeeway),
+		jwt.WithJSONNumber(),
+	)
+	_, err := parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.keys.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	iss, _ := claims["iss"].(string)
+	if !containsIssuer(p.issuers, iss) {
+		return nil, fmt.Errorf("invalid id_token: unexpected issuer %q", iss)
+	}
+	// With several audiences the authorized party must be us (OIDC Core 3.1.3.7)
+	if azp, ok := claims["azp"].(string); ok && azp != p.config.ClientID {
+		return nil, fmt.Errorf("invalid id_token: unexpected authorized party %q", azp)
+	}
+	if got, _ := claims["nonce"].(string); nonce == "" || got != nonce {
+		return nil, fmt.Errorf("invalid id_token: nonce mismatch")
+	}
+	return claims, nil
+}
+
+func containsIssuer(issuers []string, iss string) bool {
+	for _, want := range issuers {
+		if strings.TrimRight(want, "/") == strings.TrimRight(iss, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+func discover(ctx context.Context, client *http.Client, issuerURL string) (*discoveryDocument, error) {
+	issuerURL = strings.TrimRight(issuerURL, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuerURL+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build discovery request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch discovery document: status %d", resp.StatusCode)
+	}
+	var doc discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode discovery document: %w", err)
+	}
+	// The issuer in the document must be the one we asked for (OIDC Discovery 4.3)
+	if strings.TrimRight(doc.Issuer, "/") != issuerURL {
+		return nil, fmt.Errorf("discovery issuer mismatch: got %q, want %q", doc.Issuer, issuerURL)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document for %s is missing required endpoints", issuerURL)
+	}
+	return &doc, nil
+}
@@ -0,0 +1,208 @@
+// Package oauth holds the OAuth 2.0 / OpenID Connect login providers of auth-service.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// Provider is a login provider that authenticates users with the authorization code flow.
+type Provider interface {
+	Name() string
+	AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string
+	Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error)
+	// UserInfo returns the identity behind token. OpenID Connect providers read it from
+	// the verified ID token, which must carry nonce; plain OAuth providers ignore nonce.
+	UserInfo(ctx context.Context, token *oauth2.Token, nonce string) (*model.OAuthUserInfo, error)
+}
+
+// ClaimMapping names the user info claims that hold each identity field. A value may
+// list fallbacks separated by commas, e.g. "name,login".
+type ClaimMapping struct {
+	Subject       string
+	Email         string
+	EmailVerified string
+	Name          string
+	Avatar        string
+	HostedDomain  string
+}
+
+// merge returns m with empty fields taken from defaults.
+func (m ClaimMapping) merge(defaults ClaimMapping) ClaimMapping {
+	pick := func(v, d string) string {
+		if v != "" {
+			return v
+		}
+		return d
+	}
+	return ClaimMapping{
+		Subject:       pick(m.Subject, defaults.Subject),
+		Email:         pick(m.Email, defaults.Email),
+		EmailVerified: pick(m.EmailVerified, defaults.EmailVerified),
+		Name:          pick(m.Name, defaults.Name),
+		Avatar:        pick(m.Avatar, defaults.Avatar),
+		HostedDomain:  pick(m.HostedDomain, defaults.HostedDomain),
+	}
+}
+
+// Registry holds the configured providers by name.
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry creates a Registry with the given providers.
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register adds p, replacing any provider with the same name.
+func (r *Registry) Register(p Provider) {
+	r.providers[p.Name()] = p
+}
+
+// Get returns the provider registered under name.
+func (r *Registry) Get(name string) (Provider, bool) {
+	if r == nil {
+		return nil, false
+	}
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// Names returns the registered provider names in alphabetical order.
+func (r *Registry) Names() []string {
+	if r == nil {
+		return nil
+	}
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// userInfoProvider is a Provider that reads the identity from a JSON user info endpoint.
+type userInfoProvider struct {
+	name        string
+	config      *oauth2.Config
+	userInfoURL string
+	claims      ClaimMapping
+	client      *http.Client
+}
+
+func (p *userInfoProvider) Name() string { return p.name }
+
+func (p *userInfoProvider) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
+	return p.config.AuthCodeURL(state, opts...)
+}
+
+func (p *userInfoProvider) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	return p.config.Exchange(p.context(ctx), code, opts...)
+}
+
+func (p *userInfoProvider) UserInfo(ctx context.Context, token *oauth2.Token, nonce string) (*model.OAuthUserInfo, error) {
+	claims, err := p.fetchClaims(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return mapClaims(p.name, claims, p.claims)
+}
+
+// fetchClaims reads the claims from the user info endpoint.
+func (p *userInfoProvider) fetchClaims(ctx context.Context, token *oauth2.Token) (map[string]interface{}, error) {
+	v, err := p.fetchJSON(ctx, token, p.userInfoURL)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to decode user info: unexpected response")
+	}
+	return obj, nil
+}
+
+// context makes the oauth2 library use the provider's HTTP client.
+func (p *userInfoProvider) context(ctx context.Context) context.Context {
+	if p.client == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, p.client)
+}
+
+// fetchJSON performs an authenticated GET and decodes the JSON body.
+func (p *userInfoProvider) fetchJSON(ctx context.Context, token *oauth2.Token, url string) (interface{}, error) {
+	client := p.config.Client(p.context(ctx), token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get user info: status %d", resp.StatusCode)
+	}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode user info: %w", err)
+	}
+	return v, nil
+}
+
+// mapClaims converts raw user info claims to an OAuthUserInfo using m.
+func mapClaims(provider string, claims map[string]interface{}, m ClaimMapping) (*model.OAuthUserInfo, error) {
+	info := &model.OAuthUserInfo{
+		Provider:     provider,
+		Subject:      claimString(claims, m.Subject),
+		Email:        claimString(claims, m.Email),
+		Name:         claimString(claims, m.Name),
+		AvatarURL:    claimString(claims, m.Avatar),
+		HostedDomain: claimString(claims, m.HostedDomain),
+	}
+	if info.Subject == "" {
+		return nil, fmt.Errorf("failed to decode user info: missing %q claim", m.Subject)
+	}
+	verified := strings.ToLower(claimString(claims, m.EmailVerified))
+	info.EmailVerified = verified == "true"
+	return info, nil
+}
+
+// claimString returns the first non-empty claim named in the comma-separated keys.
+func claimString(claims map[string]interface{}, keys string) string {
+	if keys == "" {
+		return ""
+	}
+	for _, key := range strings.Split(keys, ",") {
+		switch v := claims[strings.TrimSpace(key)].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case json.Number:
+			return v.String()
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,294 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/config"
+)
+
+// fakeIssuer serves discovery, JWKS, token and userinfo endpoints of an OIDC issuer.
+// The token endpoint returns an ID token signed with key carrying the default claims
+// overridden by idClaims; a nil override removes the claim.
+type fakeIssuer struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	idClaims map[string]interface{}
+	verifier string // code_verifier received by the token endpoint
+}
+
+func newFakeIssuer(t *testing.T, idClaims, userInfo map[string]interface{}) *fakeIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeIssuer{key: key, idClaims: idClaims}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.URL,
+			"authorization_endpoint": f.URL + "/authorize",
+			"token_endpoint":         f.URL + "/token",
+			"userinfo_endpoint":      f.URL + "/userinfo",
+			"jwks_uri":               f.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		f.verifier = r.FormValue("code_verifier")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "at",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     f.idToken(t, f.key),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer at" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(userInfo)
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// idToken signs the issuer's ID token claims with key.
+func (f *fakeIssuer) idToken(t *testing.T, key *rsa.PrivateKey) string {
+	claims := jwt.MapClaims{
+		"iss":   f.URL,
+		"aud":   "cid",
+		"sub":   "u-1",
+		"nonce": "n-1",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range f.idClaims {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newTestOIDC(t *testing.T, f *fakeIssuer, claims ClaimMapping) Provider {
+	t.Helper()
+	p, err := NewOIDC(context.Background(), "corp", f.URL+"/", "cid", "secret", "http://site/cb", nil, claims, f.Client())
+	if err != nil {
+		t.Fatalf("discovery failed: %v", err)
+	}
+	return p
+}
+
+func TestOIDC_LoginFlow(t *testing.T) {
+	f := newFakeIssuer(t, map[string]interface{}{
+		"email":          "me@example.com",
+		"email_verified": true,
+		"hd":             "example.com",
+	}, map[string]interface{}{
+		"sub":                "u-1",
+		"email":              "other@example.com",
+		"preferred_username": "me",
+	})
+	p := newTestOIDC(t, f, ClaimMapping{})
+
+	verifier := oauth2.GenerateVerifier()
+	url := p.AuthCodeURL("st", oauth2.S256ChallengeOption(verifier), oauth2.SetAuthURLParam("nonce", "n-1"))
+	if !strings.HasPrefix(url, f.URL+"/authorize?") || !strings.Contains(url, "state=st") || !strings.Contains(url, "code_challenge_method=S256") || !strings.Contains(url, "nonce=n-1") {
+		t.Fatalf("unexpected auth url %q", url)
+	}
+	if _, err := p.Exchange(context.Background(), "bad-code"); err == nil {
+		t.Fatalf("expected exchange error for bad code")
+	}
+	token, err := p.Exchange(context.Background(), "good-code", oauth2.VerifierOption(verifier))
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	if f.verifier != verifier {
+		t.Fatalf("expected code_verifier to be sent, got %q", f.verifier)
+	}
+	info, err := p.UserInfo(context.Background(), token, "n-1")
+	if err != nil {
+		t.Fatalf("user info failed: %v", err)
+	}
+	// ID token claims win over user info; missing ones are filled in from it
+	if info.Provider != "corp" || info.Subject != "u-1" || info.Email != "me@example.com" || !info.EmailVerified || info.Name != "me" || info.HostedDomain != "example.com" {
+		t.Fatalf("unexpected user info %+v", info)
+	}
+}
+
+func TestOIDC_RejectsInvalidIDToken(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name     string
+		idClaims map[string]interface{}
+		nonce    string
+		forge    bool
+		userInfo map[string]interface{}
+		want     string
+	}{
+		{name: "nonce mismatch", nonce: "other", want: "nonce mismatch"},
+		{name: "missing nonce", idClaims: map[string]interface{}{"nonce": nil}, nonce: "n-1", want: "nonce mismatch"},
+		{name: "wrong audience", idClaims: map[string]interface{}{"aud": "someone-else"}, nonce: "n-1", want: "audience"},
+		{name: "wrong issuer", idClaims: map[string]interface{}{"iss": "https://evil.example"}, nonce: "n-1", want: "unexpected issuer"},
+		{name: "expired", idClaims: map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}, nonce: "n-1", want: "expired"},
+		{name: "other authorized party", idClaims: map[string]interface{}{"azp": "someone-else"}, nonce: "n-1", want: "authorized party"},
+		{name: "forged signature", forge: true, nonce: "n-1", want: "signature"},
+		{name: "user info for another subject", nonce: "n-1", userInfo: map[string]interface{}{"sub": "u-2"}, want: "subject does not match"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			userInfo := tc.userInfo
+			if userInfo == nil {
+				userInfo = map[string]interface{}{"sub": "u-1"}
+			}
+			f := newFakeIssuer(t, tc.idClaims, userInfo)
+			p := newTestOIDC(t, f, ClaimMapping{})
+			idToken := f.idToken(t, f.key)
+			if tc.forge {
+				idToken = f.idToken(t, otherKey)
+			}
+			token := (&oauth2.Token{AccessToken: "at"}).WithExtra(map[string]interface{}{"id_token": idToken})
+			if _, err := p.UserInfo(context.Background(), token, tc.nonce); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestOIDC_MissingIDToken(t *testing.T) {
+	f := newFakeIssuer(t, nil, nil)
+	p := newTestOIDC(t, f, ClaimMapping{})
+	if _, err := p.UserInfo(context.Background(), &oauth2.Token{AccessToken: "at"}, "n-1"); err == nil || !strings.Contains(err.Error(), "did not return an id_token") {
+		t.Fatalf("expected missing id_token error, got %v", err)
+	}
+}
+
+func TestOIDC_CustomClaimMapping(t *testing.T) {
+	f := newFakeIssuer(t, nil, map[string]interface{}{
+		"sub":  "u-1",
+		"oid":  "object-id",
+		"upn":  "me@corp.example",
+		"name": "Me",
+	})
+	p := newTestOIDC(t, f, ClaimMapping{Subject: "oid", Email: "upn"})
+	token := (&oauth2.Token{AccessToken: "at"}).WithExtra(map[string]interface{}{"id_token": f.idToken(t, f.key)})
+	info, err := p.UserInfo(context.Background(), token, "n-1")
+	if err != nil {
+		t.Fatalf("user info failed: %v", err)
+	}
+	if info.Subject != "object-id" || info.Email != "me@corp.example" || info.Name != "Me" || info.EmailVerified {
+		t.Fatalf("unexpected user info %+v", info)
+	}
+}
+
+func TestOIDC_MissingSubject(t *testing.T) {
+	f := newFakeIssuer(t, map[string]interface{}{"sub": nil}, map[string]interface{}{"email": "me@example.com"})
+	p := newTestOIDC(t, f, ClaimMapping{})
+	token := (&oauth2.Token{AccessToken: "at"}).WithExtra(map[string]interface{}{"id_token": f.idToken(t, f.key)})
+	if _, err := p.UserInfo(context.Background(), token, "n-1"); err == nil || !strings.Contains(err.Error(), `missing "sub"`) {
+		t.Fatalf("expected missing subject error, got %v", err)
+	}
+}
+
+func TestOIDC_IssuerMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 "https://evil.example",
+			"authorization_endpoint": "https://evil.example/authorize",
+			"token_endpoint":         "https://evil.example/token",
+			"userinfo_endpoint":      "https://evil.example/userinfo",
+			"jwks_uri":               "https://evil.example/jwks",
+		})
+	}))
+	defer srv.Close()
+
+	if _, err := NewOIDC(context.Background(), "corp", srv.URL, "cid", "secret", "http://site/cb", nil, ClaimMapping{}, srv.Client()); err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Fatalf("expected issuer mismatch, got %v", err)
+	}
+}
+
+func TestGitHub_UsesPrimaryEmail(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":12345678901,"login":"octo","name":"","email":null,"avatar_url":"https://avatars/1"}`))
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"email":"old@example.com","primary":false,"verified":true},{"email":"octo@example.com","primary":true,"verified":true}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	p := newGitHub("github", "cid", "secret", "http://site/cb", nil, ClaimMapping{}, srv.Client(), oauth2.Endpoint{}, srv.URL)
+	info, err := p.UserInfo(context.Background(), &oauth2.Token{AccessToken: "at"}, "")
+	if err != nil {
+		t.Fatalf("user info failed: %v", err)
+	}
+	if info.Subject != "12345678901" || info.Name != "octo" || info.Email != "octo@example.com" || !info.EmailVerified || info.AvatarURL != "https://avatars/1" {
+		t.Fatalf("unexpected user info %+v", info)
+	}
+}
+
+func TestBuildRegistry(t *testing.T) {
+	srv := newFakeIssuer(t, nil, nil)
+	cfg := &config.AuthConfig{
+		MYDOMAIN:       "https://blog.example",
+		GoogleClientID: "gid",
+		OAuthProviders: []config.OAuthProviderConfig{
+			{Name: "github", Type: "github", ClientID: "x"},
+			{Name: "corp", Type: "oidc", IssuerURL: srv.URL},
+			{Name: "down", Type: "oidc", IssuerURL: "http://127.0.0.1:1"},
+			{Name: "odd", Type: "saml"},
+		},
+	}
+	registry, errs := BuildRegistry(context.Background(), cfg, srv.Client())
+
+	if got := strings.Join(registry.Names(), ","); got != "corp,github,google" {
+		t.Fatalf("unexpected providers %q", got)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 provider errors, got %v", errs)
+	}
+	p, _ := registry.Get("github")
+	if url := p.AuthCodeURL("s"); !strings.Contains(url, "redirect_uri=https%3A%2F%2Fblog.example%2Fapi%2Fv1%2Fauth%2Foauth%2Fgithub%2Fcallback") {
+		t.Fatalf("unexpected github redirect in %q", url)
+	}
+}
@@ -0,0 +1,52 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/config"
+)
+
+// CallbackURL is where a provider redirects back to after the user consents.
+func CallbackURL(domain, provider string) string {
+	return domain + "/api/v1/auth/oauth/" + provider + "/callback"
+}
+
+// BuildRegistry creates the registry for the configured providers. Google is always
+// registered from GOOGLE_CLIENT_ID/SECRET. Providers that cannot be set up (for
+// example an unreachable OIDC issuer) are left out and reported in the returned errors.
+func BuildRegistry(ctx context.Context, cfg *config.AuthConfig, client *http.Client) (*Registry, []error) {
+	registry := NewRegistry(NewGoogle("google", cfg.GoogleClientID, cfg.GoogleClientSecret, CallbackURL(cfg.MYDOMAIN, "google"), nil, ClaimMapping{}, client))
+	var errs []error
+	for _, pc := range cfg.OAuthProviders {
+		p, err := newProvider(ctx, cfg.MYDOMAIN, pc, client)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("oauth provider %s: %w", pc.Name, err))
+			continue
+		}
+		registry.Register(p)
+	}
+	return registry, errs
+}
+
+func newProvider(ctx context.Context, domain string, pc config.OAuthProviderConfig, client *http.Client) (Provider, error) {
+	claims := ClaimMapping{
+		Subject:       pc.ClaimSubject,
+		Email:         pc.ClaimEmail,
+		EmailVerified: pc.ClaimEmailVerified,
+		Name:          pc.ClaimName,
+		Avatar:        pc.ClaimAvatar,
+	}
+	redirectURL := CallbackURL(domain, pc.Name)
+	switch pc.Type {
+	case "google":
+		return NewGoogle(pc.Name, pc.ClientID, pc.ClientSecret, redirectURL, pc.Scopes, claims, client), nil
+	case "github":
+		return NewGitHub(pc.Name, pc.ClientID, pc.ClientSecret, redirectURL, pc.Scopes, claims, client), nil
+	case "oidc":
+		return NewOIDC(ctx, pc.Name, pc.IssuerURL, pc.ClientID, pc.ClientSecret, redirectURL, pc.Scopes, claims, client)
+	default:
+		return nil, fmt.Errorf("unknown provider type %q", pc.Type)
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+)
+
+// attemptStore implements domain.LoginAttemptStore using Redis counters.
+type attemptStore struct {
+	redis *redis.Client
+}
+
+// NewLoginAttemptStore creates a LoginAttemptStore backed by the given Redis client.
+func NewLoginAttemptStore(client *redis.Client) domain.LoginAttemptStore {
+	return &attemptStore{redis: client}
+}
+
+// Failures returns the failures counted in the open window, or 0 when there is none.
+func (s *attemptStore) Failures(key string) (int, error) {
+	n, err := s.redis.Get(context.Background(), attemptKey(key)).Int()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get login failures: %w", err)
+	}
+	return n, nil
+}
+
+// RecordFailure increments the counter; the first failure starts a window of the given
+// length, after which the counter disappears.
+func (s *attemptStore) RecordFailure(key string, window time.Duration) (int, error) {
+	ctx := context.Background()
+	k := attemptKey(key)
+	pipe := s.redis.TxPipeline()
+	incr := pipe.Incr(ctx, k)
+	pipe.ExpireNX(ctx, k, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return int(incr.Val()), nil
+}
+
+// ClearFailures drops the counter, e.g. after a successful login.
+func (s *attemptStore) ClearFailures(key string) error {
+	if err := s.redis.Del(context.Background(), attemptKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to clear login failures: %w", err)
+	}
+	return nil
+}
+
+// attemptKey hashes the key so emails and IPs are not stored in Redis key names.
+func attemptKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "login:failures:" + hex.EncodeToString(sum[:])
+}
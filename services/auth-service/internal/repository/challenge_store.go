@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// challengeStore implements domain.MFAChallengeStore using Redis.
+type challengeStore struct {
+	redis *redis.Client
+}
+
+// NewMFAChallengeStore creates an MFAChallengeStore backed by the given Redis client.
+func NewMFAChallengeStore(client *redis.Client) domain.MFAChallengeStore {
+	return &challengeStore{redis: client}
+}
+
+// SaveChallenge stores the pending login under token until ttl elapses.
+func (s *challengeStore) SaveChallenge(token string, ch *model.MFAChallenge, ttl time.Duration) error {
+	bb, err := json.Marshal(ch)
+	if err != nil {
+		return fmt.Errorf("failed to encode mfa challenge: %w", err)
+	}
+	if err := s.redis.Set(context.Background(), challengeKey(token), bb, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save mfa challenge: %w", err)
+	}
+	return nil
+}
+
+// GetChallenge reads the pending login without consuming it.
+func (s *challengeStore) GetChallenge(token string) (*model.MFAChallenge, error) {
+	return s.read(s.redis.Get(context.Background(), challengeKey(token)))
+}
+
+// ConsumeChallenge atomically reads and deletes the pending login.
+func (s *challengeStore) ConsumeChallenge(token string) (*model.MFAChallenge, error) {
+	return s.read(s.redis.GetDel(context.Background(), challengeKey(token)))
+}
+
+func (s *challengeStore) read(cmd *redis.StringCmd) (*model.MFAChallenge, error) {
+	bb, err := cmd.Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get mfa challenge: %w", err)
+	}
+	var ch model.MFAChallenge
+	if err := json.Unmarshal(bb, &ch); err != nil {
+		return nil, fmt.Errorf("failed to decode mfa challenge: %w", err)
+	}
+	return &ch, nil
+}
+
+// challengeKey hashes the token so values sent by browsers are not used as Redis keys.
+func challengeKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "mfa:challenge:" + hex.EncodeToString(sum[:])
+}
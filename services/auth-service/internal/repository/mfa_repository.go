@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+)
+
+// mfaRepository implements domain.MFARepository using GORM.
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+// NewMFARepository creates a new MFARepository with the given GORM DB instance.
+func NewMFARepository(db *gorm.DB) domain.MFARepository {
+	return &mfaRepository{db: db}
+}
+
+// GetTOTP retrieves the user's authenticator.
+func (r *mfaRepository) GetTOTP(userID uint) (*domain.TOTPCredential, error) {
+	var cred domain.TOTPCredential
+	if err := r.db.Where("user_id = ?", userID).First(&cred).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrMFANotEnrolled
+		}
+		return nil, fmt.Errorf("failed to get authenticator: %w", err)
+	}
+	return &cred, nil
+}
+
+// SaveTOTP inserts or replaces the user's authenticator.
+func (r *mfaRepository) SaveTOTP(cred *domain.TOTPCredential) error {
+	if err := r.db.Save(cred).Error; err != nil {
+		return fmt.Errorf("failed to save authenticator: %w", err)
+	}
+	return nil
+}
+
+// EnableTOTP marks the user's authenticator as confirmed.
+func (r *mfaRepository) EnableTOTP(userID uint) error {
+	result := r.db.Model(&domain.TOTPCredential{}).Where("user_id = ?", userID).Update("enabled", true)
+	if result.Error != nil {
+		return fmt.Errorf("failed to enable authenticator: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrMFANotEnrolled
+	}
+	return nil
+}
+
+// DeleteTOTP removes the user's authenticator and recovery codes.
+func (r *mfaRepository) DeleteTOTP(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.TOTPCredential{}).Error; err != nil {
+			return fmt.Errorf("failed to delete authenticator: %w", err)
+		}
+		return nil
+	})
+}
+
+// AdvanceStep moves the last accepted time step forward in a single conditional
+// update, so two requests with the same code cannot both succeed.
+func (r *mfaRepository) AdvanceStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&domain.TOTPCredential{}).
+		Where("user_id = ? AND last_step < ?", userID, step).
+		Update("last_step", step)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to record authenticator code: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReplaceRecoveryCodes drops the user's recovery codes and stores the given hashes.
+func (r *mfaRepository) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		codes := make([]domain.RecoveryCode, 0, len(hashes))
+		for _, h := range hashes {
+			codes = append(codes, domain.RecoveryCode{UserID: userID, CodeHash: h})
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		if err := tx.Create(&codes).Error; err != nil {
+			return fmt.Errorf("failed to create recovery codes: %w", err)
+		}
+		return nil
+	})
+}
+
+// UseRecoveryCode marks the matching unused code as used.
+func (r *mfaRepository) UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error) {
+	result := r.db.Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountRecoveryCodes counts the user's unused recovery codes.
+func (r *mfaRepository) CountRecoveryCodes(userID uint) (int64, error) {
+	var n int64
+	if err := r.db.Model(&domain.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&n).Error; err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupMockMFARepo(t *testing.T) (*mfaRepository, sqlmock.Sqlmock, func()) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm db: %v", err)
+	}
+	cleanup := func() { _ = sqlDB.Close() }
+	return &mfaRepository{db: gdb}, mock, cleanup
+}
+
+func TestMFARepository_AdvanceStep(t *testing.T) {
+	repo, mock, cleanup := setupMockMFARepo(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "totp_credentials" SET "last_step"=$1,"updated_at"=$2 WHERE user_id = $3 AND last_step < $4`)).
+		WithArgs(int64(100), sqlmock.AnyArg(), 7, int64(100)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if ok, err := repo.AdvanceStep(7, 100); err != nil || !ok {
+		t.Fatalf("expected step to advance, got %v err=%v", ok, err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "totp_credentials" SET "last_step"=$1`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	if ok, err := repo.AdvanceStep(7, 100); err != nil || ok {
+		t.Fatalf("expected a used step to be refused, got %v err=%v", ok, err)
+	}
+	assertMockExpectations(t, mock)
+}
+
+func TestMFARepository_UseRecoveryCode(t *testing.T) {
+	repo, mock, cleanup := setupMockMFARepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "recovery_codes" SET "used_at"=$1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`)).
+		WithArgs(now, 7, "h1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if ok, err := repo.UseRecoveryCode(7, "h1", now); err != nil || !ok {
+		t.Fatalf("expected code to be used, got %v err=%v", ok, err)
+	}
+	assertMockExpectations(t, mock)
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// rotationStore implements domain.RefreshRotationStore using Redis.
+type rotationStore struct {
+	redis *redis.Client
+}
+
+// NewRefreshRotationStore creates a RefreshRotationStore backed by the given Redis client.
+func NewRefreshRotationStore(client *redis.Client) domain.RefreshRotationStore {
+	return &rotationStore{redis: client}
+}
+
+// SaveRotation stores the token pair issued for oldRefreshToken until ttl elapses.
+func (s *rotationStore) SaveRotation(oldRefreshToken string, rotation *model.RefreshRotation, ttl time.Duration) error {
+	bb, err := json.Marshal(rotation)
+	if err != nil {
+		return fmt.Errorf("failed to encode rotation: %w", err)
+	}
+	if err := s.redis.Set(context.Background(), rotationKey(oldRefreshToken), bb, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save rotation: %w", err)
+	}
+	return nil
+}
+
+// GetRotation returns the token pair issued for oldRefreshToken, or nil if none is recorded.
+func (s *rotationStore) GetRotation(oldRefreshToken string) (*model.RefreshRotation, error) {
+	bb, err := s.redis.Get(context.Background(), rotationKey(oldRefreshToken)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get rotation: %w", err)
+	}
+	var rotation model.RefreshRotation
+	if err := json.Unmarshal(bb, &rotation); err != nil {
+		return nil, fmt.Errorf("failed to decode rotation: %w", err)
+	}
+	return &rotation, nil
+}
+
+// rotationKey hashes the refresh token so raw tokens are not used as Redis keys.
+func rotationKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "jwt:rotated:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// stateStore implements domain.OAuthStateStore using Redis.
+type stateStore struct {
+	redis *redis.Client
+}
+
+// NewOAuthStateStore creates an OAuthStateStore backed by the given Redis client.
+func NewOAuthStateStore(client *redis.Client) domain.OAuthStateStore {
+	return &stateStore{redis: client}
+}
+
+// SaveState stores the pending login under state until ttl elapses.
+func (s *stateStore) SaveState(state string, st *model.OAuthState, ttl time.Duration) error {
+	bb, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("failed to encode oauth state: %w", err)
+	}
+	if err := s.redis.Set(context.Background(), stateKey(state), bb, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save oauth state: %w", err)
+	}
+	return nil
+}
+
+// ConsumeState atomically reads and deletes the pending login, so a state can only be
+// used once. It returns nil when the state is unknown or expired.
+func (s *stateStore) ConsumeState(state string) (*model.OAuthState, error) {
+	bb, err := s.redis.GetDel(context.Background(), stateKey(state)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get oauth state: %w", err)
+	}
+	var st model.OAuthState
+	if err := json.Unmarshal(bb, &st); err != nil {
+		return nil, fmt.Errorf("failed to decode oauth state: %w", err)
+	}
+	return &st, nil
+}
+
+// stateKey hashes the state so values sent by browsers are not used as Redis keys.
+func stateKey(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return "oauth:state:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+)
+
+// tokenRepository implements domain.TokenRepository using GORM.
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+// NewTokenRepository creates a new TokenRepository with the given GORM DB instance.
+func NewTokenRepository(db *gorm.DB) domain.TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+// Create inserts a new personal access token.
+func (r *tokenRepository) Create(token *domain.PersonalAccessToken) error {
+	if err := r.db.Create(token).Error; err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+	return nil
+}
+
+// GetByHash retrieves a personal access token by its hash.
+func (r *tokenRepository) GetByHash(hash string) (*domain.PersonalAccessToken, error) {
+	var token domain.PersonalAccessToken
+	if err := r.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	return &token, nil
+}
+
+// ListByUser returns all tokens owned by the user, newest first.
+func (r *tokenRepository) ListByUser(userID uint) ([]*domain.PersonalAccessToken, error) {
+	var tokens []*domain.PersonalAccessToken
+	if err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// Revoke marks the user's token as revoked.
+func (r *tokenRepository) Revoke(id, userID uint, at time.Time) error {
+	result := r.db.Model(&domain.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrTokenNotFound
+	}
+	return nil
+}
+
+// RecordUsage bumps the usage counter and last-used timestamp of a token.
+func (r *tokenRepository) RecordUsage(id uint, at time.Time) error {
+	result := r.db.Model(&domain.PersonalAccessToken{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"usage_count":  gorm.Expr("usage_count + 1"),
+		"last_used_at": at,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to record token usage: %w", result.Error)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+)
+
+func setupMockTokenRepo(t *testing.T) (*tokenRepository, sqlmock.Sqlmock, func()) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm db: %v", err)
+	}
+	cleanup := func() { _ = sqlDB.Close() }
+	return &tokenRepository{db: gdb}, mock, cleanup
+}
+
+func TestTokenRepository_GetByHash(t *testing.T) {
+	repo, mock, cleanup := setupMockTokenRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personal_access_tokens" WHERE token_hash = $1 ORDER BY "personal_access_tokens"."id" LIMIT $2`)).
+		WithArgs("h1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "token_hash", "scopes"}).AddRow(1, 7, "ci", "h1", "posts:write"))
+	tok, err := repo.GetByHash("h1")
+	if err != nil || tok.UserID != 7 || tok.Scopes != "posts:write" {
+		t.Fatalf("expected token, got %+v err=%v", tok, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personal_access_tokens" WHERE token_hash = $1`)).
+		WithArgs("none", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	if _, err := repo.GetByHash("none"); !errors.Is(err, domain.ErrTokenNotFound) {
+		t.Fatalf("expected ErrTokenNotFound, got %v", err)
+	}
+	assertMockExpectations(t, mock)
+}
+
+func TestTokenRepository_Revoke(t *testing.T) {
+	repo, mock, cleanup := setupMockTokenRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "personal_access_tokens" SET "revoked_at"=$1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`)).
+		WithArgs(now, 3, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.Revoke(3, 7, now); err != nil {
+		t.Fatalf("expected revoke success, got %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "personal_access_tokens" SET "revoked_at"=$1`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	if err := repo.Revoke(4, 7, now); !errors.Is(err, domain.ErrTokenNotFound) {
+		t.Fatalf("expected ErrTokenNotFound, got %v", err)
+	}
+	assertMockExpectations(t, mock)
+}
+
+func TestTokenRepository_RecordUsage(t *testing.T) {
+	repo, mock, cleanup := setupMockTokenRepo(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "personal_access_tokens" SET "last_used_at"=$1,"usage_count"=usage_count + 1 WHERE id = $2`)).
+		WithArgs(now, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.RecordUsage(3, now); err != nil {
+		t.Fatalf("expected record usage success, got %v", err)
+	}
+	assertMockExpectations(t, mock)
+}
@@ -30,7 +30,7 @@ func (r *userRepository) GetByUsername(username string) (*domain.User, error) {
 	var user domain.User
 	if err := r.db.Where("username = ?", username).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("user not found")
+			return nil, domain.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -42,7 +42,7 @@ func (r *userRepository) GetByEmail(email string) (*domain.User, error) {
 	var user domain.User
 	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("user not found")
+			return nil, domain.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -57,7 +57,7 @@ func (r *userRepository) GetByProviderID(provider, providerID string) (*domain.U
 		fmt.Printf("DEBUG: Query error: %v\n", err)
 		if err == gorm.ErrRecordNotFound {
 			fmt.Println("DEBUG: User not found")
-			return nil, fmt.Errorf("user not found")
+			return nil, domain.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -70,7 +70,7 @@ func (r *userRepository) GetByID(id uint) (*domain.User, error) {
 	var user domain.User
 	if err := r.db.First(&user, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("user not found")
+			return nil, domain.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -92,7 +92,25 @@ func (r *userRepository) Delete(id uint) error {
 		return fmt.Errorf("failed to delete user: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("user not found")
+		return domain.ErrUserNotFound
 	}
 	return nil
 }
+
+// List returns all users ordered by ID.
+func (r *userRepository) List() ([]*domain.User, error) {
+	var users []*domain.User
+	if err := r.db.Order("id").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
+
+// CountByRole counts the users holding role.
+func (r *userRepository) CountByRole(role string) (int64, error) {
+	var count int64
+	if err := r.db.Model(&domain.User{}).Where("role = ?", role).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
@@ -39,7 +39,7 @@ func TestCreate_Success(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users"`)).
-		WithArgs("u1", "u1@example.com", "google", "pid1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs("u1", "u1@example.com", "google", "pid1", "reader", "", "", "", sqlmock.AnyArg(), "", sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
 
@@ -56,7 +56,7 @@ func TestCreate_DBError(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users"`)).
-		WithArgs("u1", "u1@example.com", "", "", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs("u1", "u1@example.com", "", "", "reader", "", "", "", sqlmock.AnyArg(), "", sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnError(errors.New("insert failed"))
 	mock.ExpectRollback()
 
@@ -85,7 +85,7 @@ func TestGetByUsername_SuccessAndNotFound(t *testing.T) {
 		WillReturnError(gorm.ErrRecordNotFound)
 
 	_, err = repo.GetByUsername("none")
-	if err == nil || !strings.Contains(err.Error(), "user not found") {
+	if !errors.Is(err, domain.ErrUserNotFound) {
 		t.Fatalf("expected not found, got %v", err)
 	}
 	assertMockExpectations(t, mock)
@@ -109,7 +109,7 @@ func TestGetByEmail_SuccessAndNotFound(t *testing.T) {
 		WillReturnError(gorm.ErrRecordNotFound)
 
 	_, err = repo.GetByEmail("none@example.com")
-	if err == nil || !strings.Contains(err.Error(), "user not found") {
+	if !errors.Is(err, domain.ErrUserNotFound) {
 		t.Fatalf("expected not found, got %v", err)
 	}
 	assertMockExpectations(t, mock)
@@ -133,7 +133,7 @@ func TestGetByProviderID_SuccessAndNotFound(t *testing.T) {
 		WillReturnError(gorm.ErrRecordNotFound)
 
 	_, err = repo.GetByProviderID("google", "none")
-	if err == nil || !strings.Contains(err.Error(), "user not found") {
+	if !errors.Is(err, domain.ErrUserNotFound) {
 		t.Fatalf("expected not found, got %v", err)
 	}
 	assertMockExpectations(t, mock)
@@ -157,7 +157,7 @@ func TestGetByID_SuccessAndNotFound(t *testing.T) {
 		WillReturnError(gorm.ErrRecordNotFound)
 
 	_, err = repo.GetByID(9999)
-	if err == nil || !strings.Contains(err.Error(), "user not found") {
+	if !errors.Is(err, domain.ErrUserNotFound) {
 		t.Fatalf("expected not found, got %v", err)
 	}
 	assertMockExpectations(t, mock)
@@ -202,12 +202,12 @@ func TestUpdate_SuccessAndDBError(t *testing.T) {
 	repo, mock, cleanup := setupMockRepo(t)
 	defer cleanup()
 
-	user := &domain.User{ID: 7, Username: "eve", Email: "eve@example.com"}
+	user := &domain.User{ID: 7, Username: "eve", Email: "eve@example.com", Role: "editor"}
 	user.Username = "eve2"
 
 	mock.ExpectBegin()
 	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET`)).
-		WithArgs("eve2", "eve@example.com", "", "", sqlmock.AnyArg(), sqlmock.AnyArg(), uint(7)).
+		WithArgs("eve2", "eve@example.com", "", "", "editor", "", "", "", sqlmock.AnyArg(), "", sqlmock.AnyArg(), sqlmock.AnyArg(), uint(7)).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
@@ -217,7 +217,7 @@ func TestUpdate_SuccessAndDBError(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET`)).
-		WithArgs("eve2", "eve@example.com", "", "", sqlmock.AnyArg(), sqlmock.AnyArg(), uint(7)).
+		WithArgs("eve2", "eve@example.com", "", "", "editor", "", "", "", sqlmock.AnyArg(), "", sqlmock.AnyArg(), sqlmock.AnyArg(), uint(7)).
 		WillReturnError(errors.New("update fail"))
 	mock.ExpectRollback()
 
@@ -247,7 +247,7 @@ func TestDelete_SuccessNotFoundAndDBError(t *testing.T) {
 		WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectCommit()
 
-	if err := repo.Delete(9999); err == nil || !strings.Contains(err.Error(), "user not found") {
+	if err := repo.Delete(9999); !errors.Is(err, domain.ErrUserNotFound) {
 		t.Fatalf("expected not found on delete, got %v", err)
 	}
 
@@ -262,3 +262,33 @@ func TestDelete_SuccessNotFoundAndDBError(t *testing.T) {
 	}
 	assertMockExpectations(t, mock)
 }
+
+func TestListAndCountByRole(t *testing.T) {
+	repo, mock, cleanup := setupMockRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" ORDER BY id`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "role"}).
+			AddRow(1, "owner", "owner@example.com", "owner").
+			AddRow(2, "ed", "ed@example.com", "editor"))
+	users, err := repo.List()
+	if err != nil || len(users) != 2 || users[1].Role != "editor" {
+		t.Fatalf("unexpected list result %v err=%v", users, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "users" WHERE role = $1`)).
+		WithArgs("owner").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	count, err := repo.CountByRole("owner")
+	if err != nil || count != 1 {
+		t.Fatalf("expected one owner, got %d err=%v", count, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "users" WHERE role = $1`)).
+		WithArgs("owner").
+		WillReturnError(errors.New("count fail"))
+	if _, err := repo.CountByRole("owner"); err == nil || !strings.Contains(err.Error(), "failed to count users") {
+		t.Fatalf("expected count db error, got %v", err)
+	}
+	assertMockExpectations(t, mock)
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"strings"
+
+	"seungpyo.lee/PersonalWebSite/pkg/role"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/config"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+)
+
+// accessPolicy decides who may sign up and with which role. Accounts that already
+// exist (including ones an owner pre-registered) are governed by their stored role.
+type accessPolicy struct {
+	owners        map[string]bool
+	allowed       map[string]bool
+	hostedDomains map[string]bool
+	defaultRole   string
+}
+
+func newAccessPolicy(cfg config.AuthConfig) accessPolicy {
+	set := func(values []string) map[string]bool {
+		m := make(map[string]bool, len(values))
+		for _, v := range values {
+			m[strings.ToLower(v)] = true
+		}
+		return m
+	}
+	defaultRole := cfg.DefaultRole
+	if !role.Valid(defaultRole) {
+		defaultRole = role.Reader
+	}
+	return accessPolicy{
+		owners:        set(cfg.OwnerEmails),
+		allowed:       set(cfg.AllowedEmails),
+		hostedDomains: set(cfg.AllowedHostedDomains),
+		defaultRole:   defaultRole,
+	}
+}
+
+// isOwner reports whether the identity's verified email is a configured owner.
+func (p accessPolicy) isOwner(info *domain.OAuthUserInfo) bool {
+	return info.EmailVerified && p.owners[strings.ToLower(info.Email)]
+}
+
+// roleFor returns the role a new identity signs up with, or false when it may not sign up.
+// Emails only count once the provider has verified them.
+func (p accessPolicy) roleFor(info *domain.OAuthUserInfo) (string, bool) {
+	if p.isOwner(info) {
+		return role.Owner, true
+	}
+	if info.EmailVerified && p.allowed[strings.ToLower(info.Email)] {
+		return p.defaultRole, true
+	}
+	// hd is a Google Workspace claim; another provider may send any value under that name
+	if info.Provider == "google" && info.HostedDomain != "" && p.hostedDomains[strings.ToLower(info.HostedDomain)] {
+		return p.defaultRole, true
+	}
+	return "", false
+}
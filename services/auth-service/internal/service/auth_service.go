@@ -2,17 +2,20 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"log"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 	"seungpyo.lee/PersonalWebSite/pkg/jwt"
+	"seungpyo.lee/PersonalWebSite/pkg/returnto"
+	"seungpyo.lee/PersonalWebSite/pkg/role"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/config"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/oauth"
 )
 
 // authService implements domain.AuthService using a UserRepository.
@@ -20,107 +23,214 @@ type authService struct {
 	repo         domain.UserRepository
 	config       config.AuthConfig
 	TokenManager jwt.TokenManager // JWTService can be injected here if needed
+	rotations    domain.RefreshRotationStore
+	states       domain.OAuthStateStore
+	providers    *oauth.Registry
+	mfa          domain.MFAService
+	policy       accessPolicy
 }
 
-type googleUser struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-	Name  string `json:"name"`
+// oauthStateTTL bounds how long a user may take at the provider's consent screen.
+// It matches the lifetime of the oauth_state cookie.
+const oauthStateTTL = 5 * time.Minute
+
+// NewAuthService creates a new AuthService with the given UserRepository.
+// rotations may be nil, in which case a rotated refresh token is rejected immediately.
+// mfa may be nil, in which case logins never ask for a second factor.
+func NewAuthService(repo domain.UserRepository, tokenManager jwt.TokenManager, rotations domain.RefreshRotationStore, states domain.OAuthStateStore, providers *oauth.Registry, mfa domain.MFAService) domain.AuthService {
+	cfg := *config.LoadAuthConfig()
+	return &authService{repo: repo, config: cfg, TokenManager: tokenManager, rotations: rotations, states: states, providers: providers, mfa: mfa, policy: newAccessPolicy(cfg)}
 }
 
-var exchangeCode = func(oauthConfig *oauth2.Config, code string) (*oauth2.Token, error) {
-	return oauthConfig.Exchange(context.Background(), code)
+// Providers lists the configured login providers.
+func (s *authService) Providers() []string {
+	return s.providers.Names()
 }
 
-var fetchGoogleUser = func(oauthConfig *oauth2.Config, token *oauth2.Token) (*googleUser, error) {
-	client := oauthConfig.Client(context.Background(), token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user info: %w", err)
+// LoginURL returns the authorization URL of the named provider. The PKCE verifier, the
+// ID token nonce and the return path stay server-side under state until the callback
+// consumes them, so the provider round trip cannot alter where the user lands.
+func (s *authService) LoginURL(provider, state, returnTo string) (string, error) {
+	p, ok := s.providers.Get(provider)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", domain.ErrUnknownProvider, provider)
 	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read user info: %w", err)
+	pending := &model.OAuthState{
+		Provider:     provider,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		Nonce:        oauth2.GenerateVerifier(), // 32 random bytes, like the verifier
+		ReturnTo:     returnto.Sanitize(returnTo),
 	}
-	var user googleUser
-	if err := json.Unmarshal(body, &user); err != nil {
-		return nil, fmt.Errorf("failed to decode user info: %w", err)
+	if err := s.states.SaveState(state, pending, oauthStateTTL); err != nil {
+		return "", err
 	}
-	return &user, nil
+	return p.AuthCodeURL(state,
+		oauth2.AccessTypeOffline,
+		oauth2.S256ChallengeOption(pending.CodeVerifier),
+		oauth2.SetAuthURLParam("nonce", pending.Nonce),
+	), nil
 }
 
-// NewAuthService creates a new AuthService with the given UserRepository.
-func NewAuthService(repo domain.UserRepository, tokenManager jwt.TokenManager) domain.AuthService {
-	return &authService{repo: repo, config: *config.LoadAuthConfig(), TokenManager: tokenManager}
-}
-
-// OAuthLogin handles OAuth login for providers like Google.
-func (s *authService) OAuthLogin(provider, code string) (*model.LoginResponse, *domain.GoogleUserInfo, error) {
-	var oauthConfig *oauth2.Config
-	switch provider {
-	case "google":
-		oauthConfig = &oauth2.Config{
-			ClientID:     s.config.GoogleClientID,
-			ClientSecret: s.config.GoogleClientSecret,
-			RedirectURL:  s.config.MYDOMAIN + "/api/v1/auth/oauth/google/callback",
-			Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
-			Endpoint:     google.Endpoint,
-		}
-	default:
-		return nil, nil, fmt.Errorf("unsupported provider: %s", provider)
+// OAuthLogin exchanges the authorization code with the named provider and logs the
+// user in, creating the account on first login. state must have been issued by
+// LoginURL for the same provider and is usable once.
+func (s *authService) OAuthLogin(provider, state, code string) (*model.LoginResponse, *domain.OAuthUserInfo, error) {
+	p, ok := s.providers.Get(provider)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", domain.ErrUnknownProvider, provider)
 	}
-
-	token, err := exchangeCode(oauthConfig, code)
+	pending, err := s.states.ConsumeState(state)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pending == nil || pending.Provider != provider {
+		return nil, nil, domain.ErrInvalidState
+	}
+	ctx := context.Background()
+	token, err := p.Exchange(ctx, code, oauth2.VerifierOption(pending.CodeVerifier))
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to exchange code: %w", err)
 	}
 
-	googleUser, err := fetchGoogleUser(oauthConfig, token)
+	info, err := p.UserInfo(ctx, token, pending.Nonce)
 	if err != nil {
 		return nil, nil, err
 	}
+	if info.Email == "" || !info.EmailVerified {
+		return nil, nil, domain.ErrEmailNotVerified
+	}
 
-	// Check if user exists
-	if googleUser.Email != "lspyo11@gmail.com" {
-		return nil, nil, fmt.Errorf("unauthorized email")
+	user, err := s.findUser(info)
+	if err != nil {
+		return nil, nil, err
 	}
-	fmt.Printf("DEBUG: Google user ID: %s, Name: %s, Email: %s\n", googleUser.ID, googleUser.Name, googleUser.Email)
-	user, err := s.repo.GetByProviderID("google", googleUser.ID)
+	created := false
+	if user == nil {
+		r, ok := s.policy.roleFor(info)
+		if !ok {
+			return nil, nil, fmt.Errorf("unauthorized email")
+		}
+		if user, err = s.createUser(info, r); err != nil {
+			return nil, nil, err
+		}
+		created = true
+	} else if s.policy.isOwner(info) && user.Role != role.Owner {
+		// configured owners cannot be locked out through the admin API
+		user.Role = role.Owner
+		if err := s.repo.Update(user); err != nil {
+			return nil, nil, fmt.Errorf("failed to update user: %w", err)
+		}
+	}
+
+	respModel, err := issueLogin(s.TokenManager, s.config, s.mfa, user, created, "")
 	if err != nil {
-		fmt.Printf("DEBUG: GetByProviderID error: %v\n", err)
-		if err.Error() == "user not found" {
-			// User does not exist, create new user
-			fmt.Println("DEBUG: Creating new user")
-			newUser := &domain.User{
-				Username:   googleUser.Name,
-				Email:      googleUser.Email,
-				Provider:   "google",
-				ProviderID: googleUser.ID,
-			}
-			if err := s.repo.Create(newUser); err != nil {
-				return nil, nil, fmt.Errorf("failed to create user: %w", err)
-			}
-			user = newUser
-		} else {
-			return nil, nil, fmt.Errorf("failed to get user: %w", err)
+		return nil, nil, err
+	}
+	respModel.ReturnTo = pending.ReturnTo
+	return respModel, info, nil
+}
+
+// issueLogin finishes a login whose first factor succeeded. Users with two-factor
+// authentication get a challenge instead of tokens. mfa may be nil.
+func issueLogin(tokens jwt.TokenManager, cfg config.AuthConfig, mfa domain.MFAService, user *domain.User, newUser bool, jkt string) (*model.LoginResponse, error) {
+	if mfa != nil {
+		challenge, err := mfa.Challenge(user, newUser, jkt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start two-factor authentication: %w", err)
+		}
+		if challenge != "" {
+			return &model.LoginResponse{User: *user, NewUser: newUser, MFARequired: true, MFAToken: challenge}, nil
 		}
-	} else {
-		fmt.Printf("DEBUG: Found existing user: ID=%d, Username=%s\n", user.ID, user.Username)
 	}
+	resp, err := issueTokens(tokens, cfg, user, false, jkt)
+	if err != nil {
+		return nil, err
+	}
+	resp.NewUser = newUser
+	return resp, nil
+}
 
-	//generate tokens
-	accessToken, refreshToken, err := s.TokenManager.GenerateToken(user.ID, user.Username, time.Duration(s.config.AccessTokenTTL)*time.Minute, time.Duration(s.config.RefreshTokenTTL)*time.Minute)
+// issueTokens generates the access and refresh tokens of a login; withMFA marks a
+// session that passed two-factor authentication and a non-empty jkt binds the tokens
+// to that DPoP key.
+func issueTokens(tokens jwt.TokenManager, cfg config.AuthConfig, user *domain.User, withMFA bool, jkt string) (*model.LoginResponse, error) {
+	session := jwt.Session{UserID: user.ID, Username: user.Username, Role: user.Role, MFA: withMFA, JKT: jkt}
+	accessToken, refreshToken, err := tokens.GenerateSessionToken(session, time.Duration(cfg.AccessTokenTTL)*time.Minute, time.Duration(cfg.RefreshTokenTTL)*time.Minute)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate tokens: %w", err)
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
-	respModel := &model.LoginResponse{
+	resp := &model.LoginResponse{
 		Token:        accessToken,
-		ExpiresAt:    time.Now().Add(time.Duration(s.config.AccessTokenTTL) * time.Minute).Unix(),
+		ExpiresAt:    time.Now().Add(time.Duration(cfg.AccessTokenTTL) * time.Minute).Unix(),
 		RefreshToken: refreshToken,
 		User:         *user,
 	}
-	return respModel, nil, nil
+	if jkt != "" {
+		resp.TokenType = "DPoP"
+	}
+	return resp, nil
+}
+
+// findUser returns the account linked to the provider identity, or nil when there is
+// none yet. Accounts are matched by provider and subject only; the one exception is an
+// account an owner pre-registered that has never signed in, which the first login
+// with its verified email claims. Any other account with that email is refused with
+// ErrAccountExists, so a provider vouching for an address cannot take it over.
+func (s *authService) findUser(info *domain.OAuthUserInfo) (*domain.User, error) {
+	user, err := s.repo.GetByProviderID(info.Provider, info.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, domain.ErrUserNotFound) {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	existing, err := s.repo.GetByEmail(info.Email)
+	if err != nil || existing == nil {
+		return nil, nil
+	}
+	if existing.Provider != "" || existing.PasswordHash != "" {
+		return nil, domain.ErrAccountExists
+	}
+	existing.Provider = info.Provider
+	existing.ProviderID = info.Subject
+	if err := s.repo.Update(existing); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	return existing, nil
+}
+
+// createUser creates the account for a new provider identity.
+func (s *authService) createUser(info *domain.OAuthUserInfo, r string) (*domain.User, error) {
+	username := info.Name
+	if username == "" {
+		username = strings.Split(info.Email, "@")[0]
+	}
+	newUser := &domain.User{
+		Username:    s.uniqueUsername(username),
+		DisplayName: info.Name,
+		Email:       info.Email,
+		Provider:    info.Provider,
+		ProviderID:  info.Subject,
+		Role:        r,
+	}
+	if err := s.repo.Create(newUser); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	log.Printf("created %s user %d for %s login", r, newUser.ID, info.Provider)
+	return newUser, nil
+}
+
+// uniqueUsername returns base, or base with the first free numeric suffix when another
+// account already uses it. Users can pick a different one on the profile page.
+func (s *authService) uniqueUsername(base string) string {
+	candidate := base
+	for i := 2; i <= 100; i++ {
+		if existing, err := s.repo.GetByUsername(candidate); err != nil || existing == nil {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+	return candidate
 }
 
 // GetUserByEmail retrieves a user by their Email.
@@ -142,22 +252,99 @@ func (s *authService) GetUserByID(id uint) (*domain.User, error) {
 }
 
 // RefreshToken generates new access/refresh tokens for the given refresh token.
-func (s *authService) RefreshToken(refreshToken string) (string, string, error) {
-	// Validate the provided refresh token
-	claims, err := s.TokenManager.ValidateRefreshToken(refreshToken)
+// A token that was rotated within the reuse grace window yields the same pair
+// that was issued for it, so concurrent tabs refreshing at once keep working.
+// The pair is only served after the checks of a normal refresh pass for the token
+// it holds, which shares the session, user and DPoP binding of the rotated one.
+func (s *authService) RefreshToken(refreshToken, jkt string) (string, string, error) {
+	if s.rotations != nil {
+		rotation, err := s.rotations.GetRotation(refreshToken)
+		if err != nil {
+			log.Printf("failed to look up refresh rotation: %v", err)
+		} else if rotation != nil {
+			// A successor revoked by logout no longer validates and must not be handed out again
+			if _, _, err := s.checkRefresh(rotation.RefreshToken, jkt); err != nil {
+				return "", "", err
+			}
+			return rotation.AccessToken, rotation.RefreshToken, nil
+		}
+	}
+	claims, user, err := s.checkRefresh(refreshToken, jkt)
 	if err != nil {
-		return "", "", fmt.Errorf("invalid refresh token: %w", err)
+		return "", "", err
 	}
 	// Generate new tokens (rotation): issue a new refresh token and access token
-	newAccess, newRefresh, err := s.TokenManager.GenerateToken(claims.UserID, claims.Username, time.Duration(s.config.AccessTokenTTL)*time.Minute, time.Duration(s.config.RefreshTokenTTL)*time.Minute)
+	// for the same session; the session keeps its mfa claim and DPoP binding
+	session := jwt.Session{ID: claims.SessionID, UserID: user.ID, Username: user.Username, Role: user.Role, MFA: claims.MFA, JKT: claims.BoundKey()}
+	newAccess, newRefresh, err := s.TokenManager.GenerateSessionToken(session, time.Duration(s.config.AccessTokenTTL)*time.Minute, time.Duration(s.config.RefreshTokenTTL)*time.Minute)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to generate tokens: %w", err)
 	}
+	// Remember the new pair before revoking so a request racing with this one can still succeed
+	if s.rotations != nil && s.config.RefreshReuseGraceSecs > 0 {
+		grace := time.Duration(s.config.RefreshReuseGraceSecs) * time.Second
+		rotation := &model.RefreshRotation{AccessToken: newAccess, RefreshToken: newRefresh}
+		if err := s.rotations.SaveRotation(refreshToken, rotation, grace); err != nil {
+			log.Printf("failed to save refresh rotation: %v", err)
+		}
+	}
 	// Revoke old refresh token by adding to blacklist until it would naturally expire
 	// The TokenManager.RevokeToken expects the token string and TTL; let it compute TTL from token claims
 	_ = s.TokenManager.RevokeToken(refreshToken, 0)
-	// For the service layer we return the new access token and new refresh token together
 	// Caller (handler) can decide how to deliver the refresh token (cookie).
-	// We encode both separated by a pipe as a simple return (handler will parse)
 	return newAccess, newRefresh, nil
 }
+
+// checkRefresh validates a refresh token presented with the DPoP key jkt and reloads
+// its user.
+func (s *authService) checkRefresh(refreshToken, jkt string) (*jwt.Claims, *domain.User, error) {
+	claims, err := s.TokenManager.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid refresh token: %w", err)
+	}
+	// A token bound to a DPoP key is only refreshed by the holder of that key
+	if bound := claims.BoundKey(); bound != "" && bound != jkt {
+		return nil, nil, domain.ErrDPoPKeyMismatch
+	}
+	// Reload the user so role changes and deletions take effect on the next refresh
+	user, err := s.repo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid refresh token: %w", err)
+	}
+	return claims, user, nil
+}
+
+// Logout revokes refreshToken until it would expire. When the token was rotated within
+// the reuse grace window, the token issued for it is revoked too, so the grace window
+// cannot be used to resurrect the session.
+func (s *authService) Logout(refreshToken string) error {
+	if s.rotations != nil {
+		rotation, err := s.rotations.GetRotation(refreshToken)
+		if err != nil {
+			log.Printf("failed to look up refresh rotation: %v", err)
+		} else if rotation != nil {
+			if err := s.revoke(rotation.RefreshToken); err != nil {
+				return err
+			}
+		}
+	}
+	return s.revoke(refreshToken)
+}
+
+// revoke blacklists a refresh token and its session, so introspection reports the
+// session's access tokens as inactive too; tokens that no longer validate need no revocation.
+func (s *authService) revoke(refreshToken string) error {
+	claims, err := s.TokenManager.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil
+	}
+	if err := s.TokenManager.RevokeToken(refreshToken, 0); err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	if claims.SessionID != "" && claims.ExpiresAt != nil {
+		if err := s.TokenManager.RevokeSession(claims.SessionID, time.Until(claims.ExpiresAt.Time)); err != nil {
+			return fmt.Errorf("failed to revoke session: %w", err)
+		}
+	}
+	return nil
+}
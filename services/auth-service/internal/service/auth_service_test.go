@@ -1,57 +1,105 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
 	"time"
 
+	jwtlib "github.com/golang-jwt/jwt/v5"
 	"golang.org/x/oauth2"
 	pkgconfig "seungpyo.lee/PersonalWebSite/pkg/config"
 	"seungpyo.lee/PersonalWebSite/pkg/jwt"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/config"
 	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/oauth"
 )
 
 type stubUserRepo struct {
 	getByEmailFn      func(email string) (*domain.User, error)
 	getByIDFn         func(id uint) (*domain.User, error)
 	getByProviderIDFn func(provider, providerID string) (*domain.User, error)
+	getByUsernameFn   func(username string) (*domain.User, error)
 	createFn          func(user *domain.User) error
+	updateFn          func(user *domain.User) error
+	countByRoleFn     func(role string) (int64, error)
+	listFn            func() ([]*domain.User, error)
 }
 
 func (s *stubUserRepo) Create(user *domain.User) error {
 	return s.createFn(user)
 }
 func (s *stubUserRepo) GetByUsername(username string) (*domain.User, error) {
-	return nil, errors.New("not implemented")
+	if s.getByUsernameFn == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	return s.getByUsernameFn(username)
 }
 func (s *stubUserRepo) GetByEmail(email string) (*domain.User, error) {
+	if s.getByEmailFn == nil {
+		return nil, domain.ErrUserNotFound
+	}
 	return s.getByEmailFn(email)
 }
 func (s *stubUserRepo) GetByProviderID(provider, providerID string) (*domain.User, error) {
+	if s.getByProviderIDFn == nil {
+		return nil, domain.ErrUserNotFound
+	}
 	return s.getByProviderIDFn(provider, providerID)
 }
+
+// GetByID returns a stored owner with the given id unless getByIDFn is set.
 func (s *stubUserRepo) GetByID(id uint) (*domain.User, error) {
+	if s.getByIDFn == nil {
+		return &domain.User{ID: id, Username: "john", Role: "owner"}, nil
+	}
 	return s.getByIDFn(id)
 }
-func (s *stubUserRepo) Update(user *domain.User) error { return nil }
-func (s *stubUserRepo) Delete(id uint) error           { return nil }
+func (s *stubUserRepo) Update(user *domain.User) error {
+	if s.updateFn == nil {
+		return nil
+	}
+	return s.updateFn(user)
+}
+func (s *stubUserRepo) Delete(id uint) error { return nil }
+func (s *stubUserRepo) List() ([]*domain.User, error) {
+	return s.listFn()
+}
+func (s *stubUserRepo) CountByRole(role string) (int64, error) {
+	return s.countByRoleFn(role)
+}
 
 type stubTokenManager struct {
 	validateRefreshTokenFn func(token string) (*jwt.Claims, error)
-	generateTokenFn        func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error)
+	generateTokenFn        func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error)
 	revokeTokenFn          func(token string, expiresIn time.Duration) error
+	validateAccessTokenFn  func(token string) (*jwt.Claims, error)
+	revoked                map[string]bool
+	revokedSessions        map[string]bool
+	mfaIssued              int           // sessions issued with the mfa claim
+	sessions               []jwt.Session // sessions passed to GenerateSessionToken
 }
 
-func (s *stubTokenManager) GenerateToken(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
-	return s.generateTokenFn(userID, username, accessTokenExp, refreshTokenExp)
+func (s *stubTokenManager) GenerateToken(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+	return s.generateTokenFn(userID, username, role, accessTokenExp, refreshTokenExp)
+}
+func (s *stubTokenManager) GenerateSessionToken(session jwt.Session, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+	s.sessions = append(s.sessions, session)
+	if session.MFA {
+		s.mfaIssued++
+	}
+	return s.generateTokenFn(session.UserID, session.Username, session.Role, accessTokenExp, refreshTokenExp)
 }
 func (s *stubTokenManager) RefreshToken(refreshToken string, accessTokenExp time.Duration) (string, error) {
 	return "", errors.New("not implemented")
 }
 func (s *stubTokenManager) ValidateAccessToken(tokenString string) (*jwt.Claims, error) {
-	return nil, errors.New("not implemented")
+	if s.validateAccessTokenFn == nil {
+		return nil, errors.New("not implemented")
+	}
+	return s.validateAccessTokenFn(tokenString)
 }
 func (s *stubTokenManager) ValidateRefreshToken(tokenString string) (*jwt.Claims, error) {
 	return s.validateRefreshTokenFn(tokenString)
@@ -60,19 +108,105 @@ func (s *stubTokenManager) RevokeToken(tokenString string, expiresIn time.Durati
 	return s.revokeTokenFn(tokenString, expiresIn)
 }
 func (s *stubTokenManager) IsTokenRevoked(tokenString string) (bool, error) {
-	return false, nil
+	return s.revoked[tokenString], nil
+}
+func (s *stubTokenManager) RevokeSession(sessionID string, expiresIn time.Duration) error {
+	if s.revokedSessions == nil {
+		s.revokedSessions = map[string]bool{}
+	}
+	s.revokedSessions[sessionID] = true
+	return nil
+}
+func (s *stubTokenManager) IsSessionRevoked(sessionID string) (bool, error) {
+	return s.revokedSessions[sessionID], nil
+}
+
+type stubProvider struct {
+	name        string
+	exchangeErr error
+	info        *model.OAuthUserInfo
+	userInfoErr error
+	exchanges   int
+	nonce       string // nonce passed to UserInfo
+}
+
+func (p *stubProvider) Name() string { return p.name }
+func (p *stubProvider) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
+	cfg := &oauth2.Config{ClientID: "cid", Endpoint: oauth2.Endpoint{AuthURL: "https://provider.example/auth"}}
+	return cfg.AuthCodeURL(state, opts...)
+}
+func (p *stubProvider) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	p.exchanges++
+	if p.exchangeErr != nil {
+		return nil, p.exchangeErr
+	}
+	return &oauth2.Token{AccessToken: "x"}, nil
+}
+func (p *stubProvider) UserInfo(ctx context.Context, token *oauth2.Token, nonce string) (*model.OAuthUserInfo, error) {
+	p.nonce = nonce
+	return p.info, p.userInfoErr
+}
+
+type stubStateStore struct {
+	saved map[string]*model.OAuthState
+	ttl   time.Duration
+}
+
+func (s *stubStateStore) SaveState(state string, st *model.OAuthState, ttl time.Duration) error {
+	if s.saved == nil {
+		s.saved = map[string]*model.OAuthState{}
+	}
+	s.saved[state] = st
+	s.ttl = ttl
+	return nil
+}
+func (s *stubStateStore) ConsumeState(state string) (*model.OAuthState, error) {
+	st := s.saved[state]
+	delete(s.saved, state)
+	return st, nil
+}
+
+// oauthLogin starts a login with LoginURL and completes it, as the callback would.
+func oauthLogin(svc *authService, provider string) (*model.LoginResponse, *domain.OAuthUserInfo, error) {
+	_, _ = svc.LoginURL(provider, "st", "")
+	return svc.OAuthLogin(provider, "st", "code")
+}
+
+type stubRotationStore struct {
+	saved map[string]*model.RefreshRotation
+	ttl   time.Duration
+	err   error
+}
+
+func (s *stubRotationStore) SaveRotation(oldRefreshToken string, rotation *model.RefreshRotation, ttl time.Duration) error {
+	if s.saved == nil {
+		s.saved = map[string]*model.RefreshRotation{}
+	}
+	s.saved[oldRefreshToken] = rotation
+	s.ttl = ttl
+	return nil
+}
+func (s *stubRotationStore) GetRotation(oldRefreshToken string) (*model.RefreshRotation, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.saved[oldRefreshToken], nil
 }
 
 func newServiceForTest(repo domain.UserRepository, tm jwt.TokenManager) *authService {
+	cfg := config.AuthConfig{
+		GlobalConfig:       pkgconfig.GlobalConfig{AccessTokenTTL: 15, RefreshTokenTTL: 60, ServerPort: "8081"},
+		GoogleClientID:     "cid",
+		GoogleClientSecret: "csecret",
+		OwnerEmails:        []string{"lspyo11@gmail.com"},
+		MYDOMAIN:           "http://localhost:3000",
+	}
 	return &authService{
-		repo: repo,
-		config: config.AuthConfig{
-			GlobalConfig:       pkgconfig.GlobalConfig{AccessTokenTTL: 15, RefreshTokenTTL: 60, ServerPort: "8081"},
-			GoogleClientID:     "cid",
-			GoogleClientSecret: "csecret",
-			MYDOMAIN:           "http://localhost:3000",
-		},
+		repo:         repo,
+		config:       cfg,
 		TokenManager: tm,
+		states:       &stubStateStore{},
+		policy:       newAccessPolicy(cfg),
 	}
 }
 
@@ -81,12 +215,12 @@ func TestGetUserByEmail(t *testing.T) {
 		getByEmailFn: func(email string) (*domain.User, error) {
 			return &domain.User{Email: email, Username: "u"}, nil
 		},
-		getByIDFn:         func(id uint) (*domain.User, error) { return nil, nil },
+		getByIDFn:         func(id uint) (*domain.User, error) { return &domain.User{ID: id, Username: "u", Role: "editor"}, nil },
 		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) { return nil, nil },
 		createFn:          func(user *domain.User) error { return nil },
 	}, &stubTokenManager{
 		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return nil, nil },
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
 			return "", "", nil
 		},
 		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
@@ -100,12 +234,12 @@ func TestGetUserByEmail(t *testing.T) {
 func TestGetUserByEmail_NotFound(t *testing.T) {
 	svc := newServiceForTest(&stubUserRepo{
 		getByEmailFn:      func(email string) (*domain.User, error) { return nil, errors.New("db err") },
-		getByIDFn:         func(id uint) (*domain.User, error) { return nil, nil },
+		getByIDFn:         func(id uint) (*domain.User, error) { return &domain.User{ID: id, Username: "u", Role: "editor"}, nil },
 		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) { return nil, nil },
 		createFn:          func(user *domain.User) error { return nil },
 	}, &stubTokenManager{
 		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return nil, nil },
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
 			return "", "", nil
 		},
 		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
@@ -124,7 +258,7 @@ func TestGetUserByID(t *testing.T) {
 		createFn:          func(user *domain.User) error { return nil },
 	}, &stubTokenManager{
 		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return nil, nil },
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
 			return "", "", nil
 		},
 		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
@@ -143,7 +277,7 @@ func TestGetUserByID_NotFound(t *testing.T) {
 		createFn:          func(user *domain.User) error { return nil },
 	}, &stubTokenManager{
 		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return nil, nil },
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
 			return "", "", nil
 		},
 		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
@@ -157,17 +291,17 @@ func TestGetUserByID_NotFound(t *testing.T) {
 func TestRefreshToken_ValidateFail(t *testing.T) {
 	svc := newServiceForTest(&stubUserRepo{
 		getByEmailFn:      func(email string) (*domain.User, error) { return nil, nil },
-		getByIDFn:         func(id uint) (*domain.User, error) { return nil, nil },
+		getByIDFn:         func(id uint) (*domain.User, error) { return &domain.User{ID: id, Username: "u", Role: "editor"}, nil },
 		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) { return nil, nil },
 		createFn:          func(user *domain.User) error { return nil },
 	}, &stubTokenManager{
 		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return nil, errors.New("bad refresh") },
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
 			return "", "", nil
 		},
 		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
 	})
-	_, _, err := svc.RefreshToken("bad")
+	_, _, err := svc.RefreshToken("bad", "")
 	if err == nil || !strings.Contains(err.Error(), "invalid refresh token") {
 		t.Fatalf("expected invalid refresh token error, got %v", err)
 	}
@@ -176,19 +310,19 @@ func TestRefreshToken_ValidateFail(t *testing.T) {
 func TestRefreshToken_GenerateFail(t *testing.T) {
 	svc := newServiceForTest(&stubUserRepo{
 		getByEmailFn:      func(email string) (*domain.User, error) { return nil, nil },
-		getByIDFn:         func(id uint) (*domain.User, error) { return nil, nil },
+		getByIDFn:         func(id uint) (*domain.User, error) { return &domain.User{ID: id, Username: "u", Role: "editor"}, nil },
 		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) { return nil, nil },
 		createFn:          func(user *domain.User) error { return nil },
 	}, &stubTokenManager{
 		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) {
 			return &jwt.Claims{UserID: 1, Username: "u"}, nil
 		},
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
 			return "", "", errors.New("gen fail")
 		},
 		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
 	})
-	_, _, err := svc.RefreshToken("ok")
+	_, _, err := svc.RefreshToken("ok", "")
 	if err == nil || !strings.Contains(err.Error(), "failed to generate tokens") {
 		t.Fatalf("expected generate error, got %v", err)
 	}
@@ -198,14 +332,14 @@ func TestRefreshToken_SuccessAndRevokeCalled(t *testing.T) {
 	revokeCalled := false
 	svc := newServiceForTest(&stubUserRepo{
 		getByEmailFn:      func(email string) (*domain.User, error) { return nil, nil },
-		getByIDFn:         func(id uint) (*domain.User, error) { return nil, nil },
+		getByIDFn:         func(id uint) (*domain.User, error) { return &domain.User{ID: id, Username: "u", Role: "editor"}, nil },
 		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) { return nil, nil },
 		createFn:          func(user *domain.User) error { return nil },
 	}, &stubTokenManager{
 		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) {
 			return &jwt.Claims{UserID: 2, Username: "john"}, nil
 		},
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
 			return "new-access", "new-refresh", nil
 		},
 		revokeTokenFn: func(token string, expiresIn time.Duration) error {
@@ -213,7 +347,7 @@ func TestRefreshToken_SuccessAndRevokeCalled(t *testing.T) {
 			return nil
 		},
 	})
-	access, refresh, err := svc.RefreshToken("old-refresh")
+	access, refresh, err := svc.RefreshToken("old-refresh", "")
 	if err != nil || access != "new-access" || refresh != "new-refresh" {
 		t.Fatalf("expected success, got access=%q refresh=%q err=%v", access, refresh, err)
 	}
@@ -222,264 +356,704 @@ func TestRefreshToken_SuccessAndRevokeCalled(t *testing.T) {
 	}
 }
 
+func TestRefreshToken_KeepsMFAClaim(t *testing.T) {
+	tm := &stubTokenManager{
+		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) {
+			return &jwt.Claims{UserID: 2, Username: "john", MFA: token == "mfa-refresh", SessionID: "sid-" + token}, nil
+		},
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+			return "new-access", "new-refresh", nil
+		},
+		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
+	}
+	svc := newServiceForTest(&stubUserRepo{
+		getByIDFn: func(id uint) (*domain.User, error) { return &domain.User{ID: id, Username: "u", Role: "owner"}, nil },
+	}, tm)
+	if _, _, err := svc.RefreshToken("plain-refresh", ""); err != nil || tm.mfaIssued != 0 {
+		t.Fatalf("expected plain tokens, err=%v mfaIssued=%d", err, tm.mfaIssued)
+	}
+	if _, _, err := svc.RefreshToken("mfa-refresh", ""); err != nil || tm.mfaIssued != 1 {
+		t.Fatalf("expected the mfa claim to survive the refresh, err=%v mfaIssued=%d", err, tm.mfaIssued)
+	}
+	if len(tm.sessions) != 2 || tm.sessions[0].ID != "sid-plain-refresh" || tm.sessions[1].ID != "sid-mfa-refresh" {
+		t.Fatalf("expected rotation to keep the session, got %v", tm.sessions)
+	}
+}
+
 func TestOAuthLogin_UnsupportedProvider(t *testing.T) {
 	svc := newServiceForTest(&stubUserRepo{
 		getByEmailFn:      func(email string) (*domain.User, error) { return nil, nil },
-		getByIDFn:         func(id uint) (*domain.User, error) { return nil, nil },
+		getByIDFn:         func(id uint) (*domain.User, error) { return &domain.User{ID: id, Username: "u", Role: "editor"}, nil },
 		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) { return nil, nil },
 		createFn:          func(user *domain.User) error { return nil },
 	}, &stubTokenManager{
 		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return nil, nil },
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
 			return "", "", nil
 		},
 		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
 	})
-	_, _, err := svc.OAuthLogin("github", "code")
+	_, _, err := oauthLogin(svc, "github")
 	if err == nil || !strings.Contains(err.Error(), "unsupported provider") {
 		t.Fatalf("expected unsupported provider error, got %v", err)
 	}
 }
 
 func TestOAuthLogin_ExchangeFail(t *testing.T) {
-	origExchange := exchangeCode
-	origFetch := fetchGoogleUser
-	exchangeCode = func(oauthConfig *oauth2.Config, code string) (*oauth2.Token, error) {
-		return nil, errors.New("exchange fail")
-	}
-	fetchGoogleUser = origFetch
-	t.Cleanup(func() {
-		exchangeCode = origExchange
-		fetchGoogleUser = origFetch
-	})
-
-	svc := newServiceForTest(&stubUserRepo{
-		getByEmailFn:      func(email string) (*domain.User, error) { return nil, nil },
-		getByIDFn:         func(id uint) (*domain.User, error) { return nil, nil },
-		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) { return nil, nil },
-		createFn:          func(user *domain.User) error { return nil },
-	}, &stubTokenManager{
-		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return nil, nil },
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
-			return "", "", nil
-		},
-		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
+	svc := newServiceForTest(&stubUserRepo{}, &stubTokenManager{})
+	svc.providers = oauth.NewRegistry(&stubProvider{
+		name:        "google",
+		exchangeErr: errors.New("exchange fail"),
 	})
-	_, _, err := svc.OAuthLogin("google", "code")
+	_, _, err := oauthLogin(svc, "google")
 	if err == nil || !strings.Contains(err.Error(), "failed to exchange code") {
 		t.Fatalf("expected exchange error, got %v", err)
 	}
 }
 
 func TestOAuthLogin_FetchFail(t *testing.T) {
-	origExchange := exchangeCode
-	origFetch := fetchGoogleUser
-	exchangeCode = func(oauthConfig *oauth2.Config, code string) (*oauth2.Token, error) {
-		return &oauth2.Token{AccessToken: "x"}, nil
+	svc := newServiceForTest(&stubUserRepo{}, &stubTokenManager{})
+	svc.providers = oauth.NewRegistry(&stubProvider{
+		name:        "google",
+		userInfoErr: errors.New("failed to get user info: boom"),
+	})
+	_, _, err := oauthLogin(svc, "google")
+	if err == nil || !strings.Contains(err.Error(), "failed to get user info") {
+		t.Fatalf("expected fetch error, got %v", err)
 	}
-	fetchGoogleUser = func(oauthConfig *oauth2.Config, token *oauth2.Token) (*googleUser, error) {
-		return nil, errors.New("failed to get user info: boom")
+}
+
+func TestOAuthLogin_UnauthorizedEmail(t *testing.T) {
+	svc := newServiceForTest(&stubUserRepo{}, &stubTokenManager{})
+	svc.providers = oauth.NewRegistry(&stubProvider{
+		name: "google",
+		info: &model.OAuthUserInfo{Provider: "google", Subject: "gid", Email: "other@example.com", EmailVerified: true, Name: "Other"},
+	})
+	_, _, err := oauthLogin(svc, "google")
+	if err == nil || !strings.Contains(err.Error(), "unauthorized email") {
+		t.Fatalf("expected unauthorized email error, got %v", err)
 	}
-	t.Cleanup(func() {
-		exchangeCode = origExchange
-		fetchGoogleUser = origFetch
+}
+
+func TestOAuthLogin_ExistingUser(t *testing.T) {
+	createCalled := false
+	var lookedUp string
+	svc := newServiceForTest(&stubUserRepo{
+		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) {
+			lookedUp = provider + ":" + providerID
+			return &domain.User{ID: 10, Username: "existing", Email: "lspyo11@gmail.com"}, nil
+		},
+		createFn: func(user *domain.User) error {
+			createCalled = true
+			return nil
+		},
+	}, &stubTokenManager{
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+			return "access", "refresh", nil
+		},
 	})
+	svc.providers = oauth.NewRegistry(&stubProvider{
+		name: "github",
+		info: &model.OAuthUserInfo{Provider: "github", Subject: "42", Email: "lspyo11@gmail.com", EmailVerified: true, Name: "Lee"},
+	})
+	resp, info, err := oauthLogin(svc, "github")
+	if err != nil || resp == nil || resp.Token == "" {
+		t.Fatalf("expected success, got resp=%v err=%v", resp, err)
+	}
+	if info == nil || info.Subject != "42" {
+		t.Fatalf("expected provider user info, got %+v", info)
+	}
+	if lookedUp != "github:42" {
+		t.Fatalf("expected lookup by provider identity, got %q", lookedUp)
+	}
+	if createCalled {
+		t.Fatalf("did not expect create for existing user")
+	}
+}
 
+func TestOAuthLogin_NewUser(t *testing.T) {
+	var created *domain.User
 	svc := newServiceForTest(&stubUserRepo{
-		getByEmailFn:      func(email string) (*domain.User, error) { return nil, nil },
-		getByIDFn:         func(id uint) (*domain.User, error) { return nil, nil },
-		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) { return nil, nil },
-		createFn:          func(user *domain.User) error { return nil },
+		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) {
+			return nil, domain.ErrUserNotFound
+		},
+		createFn: func(user *domain.User) error {
+			user.ID = 33
+			created = user
+			return nil
+		},
 	}, &stubTokenManager{
-		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return nil, nil },
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
-			return "", "", nil
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+			return "access", "refresh", nil
 		},
-		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
 	})
-	_, _, err := svc.OAuthLogin("google", "code")
-	if err == nil || !strings.Contains(err.Error(), "failed to get user info") {
-		t.Fatalf("expected fetch error, got %v", err)
+	svc.providers = oauth.NewRegistry(&stubProvider{
+		name: "google",
+		info: &model.OAuthUserInfo{Provider: "google", Subject: "gid", Email: "lspyo11@gmail.com", EmailVerified: true, Name: "Newbie"},
+	})
+	resp, _, err := oauthLogin(svc, "google")
+	if err != nil || resp == nil || resp.User.ID != 33 {
+		t.Fatalf("expected created user success, got resp=%v err=%v", resp, err)
+	}
+	if created == nil || created.Provider != "google" || created.ProviderID != "gid" || created.Role != "owner" {
+		t.Fatalf("expected owner created with provider identity, got %+v", created)
 	}
 }
 
-func TestOAuthLogin_DecodeFail(t *testing.T) {
-	origExchange := exchangeCode
-	origFetch := fetchGoogleUser
-	exchangeCode = func(oauthConfig *oauth2.Config, code string) (*oauth2.Token, error) {
-		return &oauth2.Token{AccessToken: "x"}, nil
+func TestOAuthLogin_RefusesEmailOfAccountThatSignsInElsewhere(t *testing.T) {
+	existing := map[string]*domain.User{
+		"other provider": {ID: 7, Username: "owner", Email: "lspyo11@gmail.com", Provider: "google", ProviderID: "gid", Role: "owner"},
+		"password":       {ID: 7, Username: "owner", Email: "lspyo11@gmail.com", PasswordHash: "$2a$10$hash", Role: "owner"},
 	}
-	fetchGoogleUser = func(oauthConfig *oauth2.Config, token *oauth2.Token) (*googleUser, error) {
-		return nil, errors.New("failed to decode user info: bad json")
+	for name, user := range existing {
+		t.Run(name, func(t *testing.T) {
+			svc := newServiceForTest(&stubUserRepo{
+				getByProviderIDFn: func(provider, providerID string) (*domain.User, error) {
+					return nil, domain.ErrUserNotFound
+				},
+				getByEmailFn: func(email string) (*domain.User, error) {
+					return user, nil
+				},
+				createFn: func(user *domain.User) error {
+					t.Fatalf("did not expect a new user for an email in use")
+					return nil
+				},
+				updateFn: func(user *domain.User) error {
+					t.Fatalf("did not expect the account to be linked")
+					return nil
+				},
+			}, &stubTokenManager{
+				generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+					t.Fatalf("did not expect tokens")
+					return "", "", nil
+				},
+			})
+			svc.providers = oauth.NewRegistry(&stubProvider{
+				name: "github",
+				info: &model.OAuthUserInfo{Provider: "github", Subject: "42", Email: "lspyo11@gmail.com", EmailVerified: true},
+			})
+			resp, _, err := oauthLogin(svc, "github")
+			if !errors.Is(err, domain.ErrAccountExists) || resp != nil {
+				t.Fatalf("expected ErrAccountExists, got resp=%v err=%v", resp, err)
+			}
+		})
 	}
-	t.Cleanup(func() {
-		exchangeCode = origExchange
-		fetchGoogleUser = origFetch
-	})
+}
 
+func TestOAuthLogin_GenerateTokenFail(t *testing.T) {
 	svc := newServiceForTest(&stubUserRepo{
-		getByEmailFn:      func(email string) (*domain.User, error) { return nil, nil },
-		getByIDFn:         func(id uint) (*domain.User, error) { return nil, nil },
-		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) { return nil, nil },
-		createFn:          func(user *domain.User) error { return nil },
+		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) {
+			return &domain.User{ID: 10, Username: "existing", Email: "lspyo11@gmail.com"}, nil
+		},
 	}, &stubTokenManager{
-		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return nil, nil },
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
-			return "", "", nil
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+			return "", "", errors.New("gen fail")
 		},
-		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
 	})
-	_, _, err := svc.OAuthLogin("google", "code")
-	if err == nil || !strings.Contains(err.Error(), "failed to decode user info") {
-		t.Fatalf("expected decode error, got %v", err)
+	svc.providers = oauth.NewRegistry(&stubProvider{
+		name: "google",
+		info: &model.OAuthUserInfo{Provider: "google", Subject: "gid", Email: "lspyo11@gmail.com", EmailVerified: true, Name: "Lee"},
+	})
+	_, _, err := oauthLogin(svc, "google")
+	if err == nil || !strings.Contains(err.Error(), "failed to generate tokens") {
+		t.Fatalf("expected token generate error, got %v", err)
 	}
 }
 
-func TestOAuthLogin_UnauthorizedEmail(t *testing.T) {
-	origExchange := exchangeCode
-	origFetch := fetchGoogleUser
-	exchangeCode = func(oauthConfig *oauth2.Config, code string) (*oauth2.Token, error) {
-		return &oauth2.Token{AccessToken: "x"}, nil
+func TestOAuthLogin_StateIsOneTimeAndBoundToProvider(t *testing.T) {
+	svc := newServiceForTest(&stubUserRepo{
+		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) {
+			return &domain.User{ID: 10, Username: "existing"}, nil
+		},
+	}, &stubTokenManager{
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+			return "access", "refresh", nil
+		},
+	})
+	google := &stubProvider{name: "google", info: &model.OAuthUserInfo{Provider: "google", Subject: "gid", Email: "a@example.com", EmailVerified: true}}
+	github := &stubProvider{name: "github"}
+	svc.providers = oauth.NewRegistry(google, github)
+
+	if _, err := svc.LoginURL("google", "st", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := svc.OAuthLogin("github", "st", "code"); !errors.Is(err, domain.ErrInvalidState) {
+		t.Fatalf("expected invalid state for another provider, got %v", err)
+	}
+	if _, _, err := svc.OAuthLogin("google", "st", "code"); !errors.Is(err, domain.ErrInvalidState) {
+		t.Fatalf("expected state to be consumed by the first callback, got %v", err)
+	}
+	if _, err := svc.LoginURL("google", "st2", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	fetchGoogleUser = func(oauthConfig *oauth2.Config, token *oauth2.Token) (*googleUser, error) {
-		return &googleUser{ID: "gid", Email: "other@example.com", Name: "Other"}, nil
+	nonce := svc.states.(*stubStateStore).saved["st2"].Nonce
+	if _, _, err := svc.OAuthLogin("google", "st2", "code"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	t.Cleanup(func() {
-		exchangeCode = origExchange
-		fetchGoogleUser = origFetch
+	if _, _, err := svc.OAuthLogin("google", "st2", "code"); !errors.Is(err, domain.ErrInvalidState) {
+		t.Fatalf("expected replayed state to be rejected, got %v", err)
+	}
+	if google.exchanges != 1 || github.exchanges != 0 {
+		t.Fatalf("expected a single code exchange, got google=%d github=%d", google.exchanges, github.exchanges)
+	}
+	if nonce == "" || google.nonce != nonce {
+		t.Fatalf("expected stored nonce %q to be checked, got %q", nonce, google.nonce)
+	}
+}
+
+func TestOAuthLogin_UnverifiedEmailRejected(t *testing.T) {
+	svc := newServiceForTest(&stubUserRepo{
+		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) {
+			return &domain.User{ID: 10, Username: "existing"}, nil
+		},
+	}, &stubTokenManager{})
+	svc.providers = oauth.NewRegistry(&stubProvider{
+		name: "github",
+		info: &model.OAuthUserInfo{Provider: "github", Subject: "42", Email: "lspyo11@gmail.com"},
 	})
+	if _, _, err := oauthLogin(svc, "github"); !errors.Is(err, domain.ErrEmailNotVerified) {
+		t.Fatalf("expected email not verified error, got %v", err)
+	}
+}
 
+func TestOAuthLogin_NewUserGetsFreeUsername(t *testing.T) {
+	var created *domain.User
 	svc := newServiceForTest(&stubUserRepo{
-		getByEmailFn:      func(email string) (*domain.User, error) { return nil, nil },
-		getByIDFn:         func(id uint) (*domain.User, error) { return nil, nil },
-		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) { return nil, nil },
-		createFn:          func(user *domain.User) error { return nil },
+		getByUsernameFn: func(username string) (*domain.User, error) {
+			if username == "Lee" || username == "Lee-2" {
+				return &domain.User{ID: 1, Username: username}, nil
+			}
+			return nil, domain.ErrUserNotFound
+		},
+		createFn: func(user *domain.User) error {
+			user.ID = 5
+			created = user
+			return nil
+		},
 	}, &stubTokenManager{
-		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return nil, nil },
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
-			return "", "", nil
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+			return "access", "refresh", nil
 		},
-		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
 	})
-	_, _, err := svc.OAuthLogin("google", "code")
-	if err == nil || !strings.Contains(err.Error(), "unauthorized email") {
-		t.Fatalf("expected unauthorized email error, got %v", err)
+	svc.providers = oauth.NewRegistry(&stubProvider{
+		name: "google",
+		info: &model.OAuthUserInfo{Provider: "google", Subject: "gid", Email: "lspyo11@gmail.com", EmailVerified: true, Name: "Lee"},
+	})
+	resp, _, err := oauthLogin(svc, "google")
+	if err != nil || !resp.NewUser {
+		t.Fatalf("expected new user login, got resp=%+v err=%v", resp, err)
+	}
+	if created.Username != "Lee-3" || created.DisplayName != "Lee" {
+		t.Fatalf("expected free username and display name, got %+v", created)
 	}
 }
 
-func TestOAuthLogin_ExistingUser(t *testing.T) {
-	origExchange := exchangeCode
-	origFetch := fetchGoogleUser
-	exchangeCode = func(oauthConfig *oauth2.Config, code string) (*oauth2.Token, error) {
-		return &oauth2.Token{AccessToken: "x"}, nil
+func TestLoginURL(t *testing.T) {
+	svc := newServiceForTest(&stubUserRepo{}, &stubTokenManager{})
+	svc.providers = oauth.NewRegistry(&stubProvider{name: "google"})
+
+	url, err := svc.LoginURL("google", "st", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	fetchGoogleUser = func(oauthConfig *oauth2.Config, token *oauth2.Token) (*googleUser, error) {
-		return &googleUser{ID: "gid", Email: "lspyo11@gmail.com", Name: "Lee"}, nil
+	states := svc.states.(*stubStateStore)
+	pending := states.saved["st"]
+	if pending == nil || pending.Provider != "google" || pending.CodeVerifier == "" || pending.Nonce == "" || states.ttl != 5*time.Minute {
+		t.Fatalf("expected pending login to be stored, got %+v ttl=%v", pending, states.ttl)
 	}
-	t.Cleanup(func() {
-		exchangeCode = origExchange
-		fetchGoogleUser = origFetch
-	})
+	challenge := oauth2.S256ChallengeFromVerifier(pending.CodeVerifier)
+	for _, want := range []string{"state=st", "code_challenge=" + challenge, "code_challenge_method=S256", "nonce=" + pending.Nonce} {
+		if !strings.Contains(url, want) {
+			t.Fatalf("expected %q in login url %q", want, url)
+		}
+	}
+	if _, err := svc.LoginURL("gitlab", "st", ""); !errors.Is(err, domain.ErrUnknownProvider) {
+		t.Fatalf("expected ErrUnknownProvider, got %v", err)
+	}
+	if names := svc.Providers(); len(names) != 1 || names[0] != "google" {
+		t.Fatalf("unexpected providers %v", names)
+	}
+}
 
-	createCalled := false
+func TestOAuthLogin_CarriesReturnToThroughState(t *testing.T) {
 	svc := newServiceForTest(&stubUserRepo{
-		getByEmailFn: func(email string) (*domain.User, error) { return nil, nil },
-		getByIDFn:    func(id uint) (*domain.User, error) { return nil, nil },
 		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) {
-			return &domain.User{ID: 10, Username: "existing", Email: "lspyo11@gmail.com"}, nil
-		},
-		createFn: func(user *domain.User) error {
-			createCalled = true
-			return nil
+			return &domain.User{ID: 10, Username: "existing"}, nil
 		},
 	}, &stubTokenManager{
-		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return nil, nil },
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
 			return "access", "refresh", nil
 		},
+	})
+	svc.providers = oauth.NewRegistry(&stubProvider{name: "google", info: &model.OAuthUserInfo{Provider: "google", Subject: "gid", Email: "a@example.com", EmailVerified: true}})
+
+	cases := map[string]string{
+		"/blog-edit/42":         "/blog-edit/42",
+		"/blog?tag=go":          "/blog?tag=go",
+		"https://evil.example/": "",
+		"//evil.example/":       "",
+		"/logout":               "",
+		"/blog/../logout":       "",
+	}
+	for returnTo, want := range cases {
+		if _, err := svc.LoginURL("google", "st", returnTo); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, _, err := svc.OAuthLogin("google", "st", "code")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.ReturnTo != want {
+			t.Fatalf("return_to %q: expected %q, got %q", returnTo, want, resp.ReturnTo)
+		}
+	}
+}
+
+func TestRefreshToken_SavesRotationForGraceWindow(t *testing.T) {
+	store := &stubRotationStore{}
+	svc := newServiceForTest(&stubUserRepo{}, &stubTokenManager{
+		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) {
+			return &jwt.Claims{UserID: 2, Username: "john"}, nil
+		},
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+			return "new-access", "new-refresh", nil
+		},
 		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
 	})
-	resp, _, err := svc.OAuthLogin("google", "code")
-	if err != nil || resp == nil || resp.Token == "" {
-		t.Fatalf("expected success, got resp=%v err=%v", resp, err)
+	svc.rotations = store
+	svc.config.RefreshReuseGraceSecs = 30
+
+	if _, _, err := svc.RefreshToken("old-refresh", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if createCalled {
-		t.Fatalf("did not expect create for existing user")
+	rot := store.saved["old-refresh"]
+	if rot == nil || rot.AccessToken != "new-access" || rot.RefreshToken != "new-refresh" {
+		t.Fatalf("expected rotation to be saved, got %+v", rot)
+	}
+	if store.ttl != 30*time.Second {
+		t.Fatalf("expected 30s grace ttl, got %v", store.ttl)
 	}
 }
 
-func TestOAuthLogin_NewUser(t *testing.T) {
-	origExchange := exchangeCode
-	origFetch := fetchGoogleUser
-	exchangeCode = func(oauthConfig *oauth2.Config, code string) (*oauth2.Token, error) {
-		return &oauth2.Token{AccessToken: "x"}, nil
+func TestRefreshToken_RecentlyRotatedTokenReturnsSamePair(t *testing.T) {
+	store := &stubRotationStore{saved: map[string]*model.RefreshRotation{
+		"old-refresh": {AccessToken: "issued-access", RefreshToken: "issued-refresh"},
+	}}
+	svc := newServiceForTest(&stubUserRepo{}, &stubTokenManager{
+		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) {
+			if token == "issued-refresh" {
+				return &jwt.Claims{UserID: 2}, nil
+			}
+			return nil, errors.New("refresh token is revoked")
+		},
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+			t.Fatalf("did not expect new tokens to be generated")
+			return "", "", nil
+		},
+		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
+	})
+	svc.rotations = store
+	svc.config.RefreshReuseGraceSecs = 30
+
+	access, refresh, err := svc.RefreshToken("old-refresh", "")
+	if err != nil || access != "issued-access" || refresh != "issued-refresh" {
+		t.Fatalf("expected cached pair, got access=%q refresh=%q err=%v", access, refresh, err)
 	}
-	fetchGoogleUser = func(oauthConfig *oauth2.Config, token *oauth2.Token) (*googleUser, error) {
-		return &googleUser{ID: "gid", Email: "lspyo11@gmail.com", Name: "Newbie"}, nil
+}
+
+func TestRefreshToken_RotationLookupErrorFallsBackToValidation(t *testing.T) {
+	store := &stubRotationStore{err: errors.New("redis down")}
+	svc := newServiceForTest(&stubUserRepo{}, &stubTokenManager{
+		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) {
+			return nil, errors.New("refresh token is revoked")
+		},
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+			return "", "", nil
+		},
+		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
+	})
+	svc.rotations = store
+
+	_, _, err := svc.RefreshToken("old-refresh", "")
+	if err == nil || !strings.Contains(err.Error(), "invalid refresh token") {
+		t.Fatalf("expected invalid refresh token error, got %v", err)
 	}
-	t.Cleanup(func() {
-		exchangeCode = origExchange
-		fetchGoogleUser = origFetch
+}
+
+func TestRefreshToken_RevokedSuccessorIsNotReturned(t *testing.T) {
+	store := &stubRotationStore{saved: map[string]*model.RefreshRotation{
+		"old-refresh": {AccessToken: "issued-access", RefreshToken: "issued-refresh"},
+	}}
+	svc := newServiceForTest(&stubUserRepo{}, &stubTokenManager{
+		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) {
+			return nil, errors.New("refresh token is revoked")
+		},
+		revoked: map[string]bool{"issued-refresh": true},
 	})
+	svc.rotations = store
 
-	createCalled := false
+	if _, _, err := svc.RefreshToken("old-refresh", ""); err == nil || !strings.Contains(err.Error(), "invalid refresh token") {
+		t.Fatalf("expected invalid refresh token error, got %v", err)
+	}
+}
+
+func TestRefreshToken_RecentlyRotatedTokenIsChecked(t *testing.T) {
+	boundClaims := &jwt.Claims{UserID: 2, Confirmation: &jwt.Confirmation{JKT: "key-1"}}
+	cases := []struct {
+		name    string
+		claims  *jwt.Claims
+		jkt     string
+		userErr error
+		wantErr error
+	}{
+		{name: "other DPoP key", claims: boundClaims, jkt: "key-2", wantErr: domain.ErrDPoPKeyMismatch},
+		{name: "missing DPoP proof", claims: boundClaims, wantErr: domain.ErrDPoPKeyMismatch},
+		{name: "deleted user", claims: &jwt.Claims{UserID: 2}, userErr: domain.ErrUserNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &stubRotationStore{saved: map[string]*model.RefreshRotation{
+				"old-refresh": {AccessToken: "issued-access", RefreshToken: "issued-refresh"},
+			}}
+			svc := newServiceForTest(&stubUserRepo{
+				getByIDFn: func(id uint) (*domain.User, error) {
+					if tc.userErr != nil {
+						return nil, tc.userErr
+					}
+					return &domain.User{ID: id, Username: "john", Role: "owner"}, nil
+				},
+			}, &stubTokenManager{
+				validateRefreshTokenFn: func(token string) (*jwt.Claims, error) {
+					if token == "issued-refresh" {
+						return tc.claims, nil
+					}
+					return nil, errors.New("refresh token is revoked")
+				},
+			})
+			svc.rotations = store
+
+			access, refresh, err := svc.RefreshToken("old-refresh", tc.jkt)
+			if err == nil || access != "" || refresh != "" {
+				t.Fatalf("expected the cached pair to be refused, got access=%q refresh=%q err=%v", access, refresh, err)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLogout_RevokesTokenAndRotatedSuccessor(t *testing.T) {
+	store := &stubRotationStore{saved: map[string]*model.RefreshRotation{
+		"old-refresh": {AccessToken: "issued-access", RefreshToken: "issued-refresh"},
+	}}
+	var revoked []string
+	svc := newServiceForTest(&stubUserRepo{}, &stubTokenManager{
+		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) {
+			if token == "old-refresh" {
+				return nil, errors.New("refresh token is revoked")
+			}
+			return &jwt.Claims{UserID: 1, SessionID: "sid-" + token, RegisteredClaims: jwtlib.RegisteredClaims{ExpiresAt: jwtlib.NewNumericDate(time.Now().Add(time.Hour))}}, nil
+		},
+		revokeTokenFn: func(token string, expiresIn time.Duration) error {
+			revoked = append(revoked, token)
+			return nil
+		},
+	})
+	tm := svc.TokenManager.(*stubTokenManager)
+	svc.rotations = store
+
+	if err := svc.Logout("old-refresh"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(revoked) != 1 || revoked[0] != "issued-refresh" {
+		t.Fatalf("expected only the successor to be revoked, got %v", revoked)
+	}
+	revoked = nil
+	if err := svc.Logout("current-refresh"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(revoked) != 1 || revoked[0] != "current-refresh" {
+		t.Fatalf("expected current token to be revoked, got %v", revoked)
+	}
+	if !tm.revokedSessions["sid-issued-refresh"] || !tm.revokedSessions["sid-current-refresh"] {
+		t.Fatalf("expected the sessions to be revoked, got %v", tm.revokedSessions)
+	}
+}
+
+func TestLogout_RevokeFailure(t *testing.T) {
+	svc := newServiceForTest(&stubUserRepo{}, &stubTokenManager{
+		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return &jwt.Claims{UserID: 1}, nil },
+		revokeTokenFn:          func(token string, expiresIn time.Duration) error { return errors.New("redis down") },
+	})
+	if err := svc.Logout("refresh"); err == nil || !strings.Contains(err.Error(), "failed to revoke") {
+		t.Fatalf("expected revoke error, got %v", err)
+	}
+}
+
+func TestOAuthLogin_RolesFromAccessPolicy(t *testing.T) {
+	cases := []struct {
+		name     string
+		info     model.OAuthUserInfo
+		wantRole string
+		wantErr  bool
+	}{
+		{"owner", model.OAuthUserInfo{Email: "LSPYO11@gmail.com", EmailVerified: true}, "owner", false},
+		{"unverified owner email", model.OAuthUserInfo{Email: "lspyo11@gmail.com"}, "", true},
+		{"allowed email", model.OAuthUserInfo{Email: "co@example.com", EmailVerified: true}, "editor", false},
+		{"workspace domain", model.OAuthUserInfo{Email: "a@corp.example", EmailVerified: true, HostedDomain: "corp.example"}, "editor", false},
+		{"other domain", model.OAuthUserInfo{Email: "a@other.example", EmailVerified: true, HostedDomain: "other.example"}, "", true},
+		{"workspace domain from another provider", model.OAuthUserInfo{Provider: "corp", Email: "a@corp.example", EmailVerified: true, HostedDomain: "corp.example"}, "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var created *domain.User
+			var tokenRole string
+			svc := newServiceForTest(&stubUserRepo{
+				createFn: func(user *domain.User) error {
+					created = user
+					return nil
+				},
+			}, &stubTokenManager{
+				generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+					tokenRole = role
+					return "access", "refresh", nil
+				},
+			})
+			svc.config.AllowedEmails = []string{"co@example.com"}
+			svc.config.AllowedHostedDomains = []string{"corp.example"}
+			svc.config.DefaultRole = "editor"
+			svc.policy = newAccessPolicy(svc.config)
+			info := tc.info
+			if info.Provider == "" {
+				info.Provider = "google"
+			}
+			info.Subject = "sub"
+			svc.providers = oauth.NewRegistry(&stubProvider{name: "google", info: &info})
+
+			_, _, err := oauthLogin(svc, "google")
+			if tc.wantErr {
+				if err == nil || created != nil {
+					t.Fatalf("expected rejection, got err=%v created=%+v", err, created)
+				}
+				return
+			}
+			if err != nil || created == nil || created.Role != tc.wantRole || tokenRole != tc.wantRole {
+				t.Fatalf("expected role %q, got err=%v created=%+v token role=%q", tc.wantRole, err, created, tokenRole)
+			}
+		})
+	}
+}
+
+func TestOAuthLogin_ConfiguredOwnerIsPromoted(t *testing.T) {
+	var updated *domain.User
 	svc := newServiceForTest(&stubUserRepo{
-		getByEmailFn: func(email string) (*domain.User, error) { return nil, nil },
-		getByIDFn:    func(id uint) (*domain.User, error) { return nil, nil },
 		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) {
-			return nil, errors.New("user not found")
+			return &domain.User{ID: 3, Username: "me", Email: "lspyo11@gmail.com", Role: "reader"}, nil
 		},
-		createFn: func(user *domain.User) error {
-			createCalled = true
-			user.ID = 33
+		updateFn: func(user *domain.User) error {
+			updated = user
 			return nil
 		},
 	}, &stubTokenManager{
-		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return nil, nil },
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
 			return "access", "refresh", nil
 		},
-		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
 	})
-	resp, _, err := svc.OAuthLogin("google", "code")
-	if err != nil || resp == nil || resp.User.ID != 33 {
-		t.Fatalf("expected created user success, got resp=%v err=%v", resp, err)
-	}
-	if !createCalled {
-		t.Fatalf("expected create for new user")
+	svc.providers = oauth.NewRegistry(&stubProvider{
+		name: "google",
+		info: &model.OAuthUserInfo{Provider: "google", Subject: "gid", Email: "lspyo11@gmail.com", EmailVerified: true},
+	})
+	resp, _, err := oauthLogin(svc, "google")
+	if err != nil || updated == nil || updated.Role != "owner" || resp.User.Role != "owner" {
+		t.Fatalf("expected owner promotion, got resp=%v updated=%+v err=%v", resp, updated, err)
 	}
 }
 
-func TestOAuthLogin_GenerateTokenFail(t *testing.T) {
-	origExchange := exchangeCode
-	origFetch := fetchGoogleUser
-	exchangeCode = func(oauthConfig *oauth2.Config, code string) (*oauth2.Token, error) {
-		return &oauth2.Token{AccessToken: "x"}, nil
+func TestOAuthLogin_LinksPreRegisteredUser(t *testing.T) {
+	var updated *domain.User
+	svc := newServiceForTest(&stubUserRepo{
+		getByEmailFn: func(email string) (*domain.User, error) {
+			return &domain.User{ID: 8, Username: "co", Email: email, Role: "editor"}, nil
+		},
+		updateFn: func(user *domain.User) error {
+			updated = user
+			return nil
+		},
+	}, &stubTokenManager{
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+			return "access", "refresh", nil
+		},
+	})
+	svc.providers = oauth.NewRegistry(&stubProvider{
+		name: "github",
+		info: &model.OAuthUserInfo{Provider: "github", Subject: "77", Email: "co@example.com", EmailVerified: true},
+	})
+	resp, _, err := oauthLogin(svc, "github")
+	if err != nil || resp.User.ID != 8 || resp.User.Role != "editor" {
+		t.Fatalf("expected login as pre-registered editor, got resp=%v err=%v", resp, err)
 	}
-	fetchGoogleUser = func(oauthConfig *oauth2.Config, token *oauth2.Token) (*googleUser, error) {
-		return &googleUser{ID: "gid", Email: "lspyo11@gmail.com", Name: "Lee"}, nil
+	if updated == nil || updated.Provider != "github" || updated.ProviderID != "77" {
+		t.Fatalf("expected provider identity to be linked, got %+v", updated)
 	}
-	t.Cleanup(func() {
-		exchangeCode = origExchange
-		fetchGoogleUser = origFetch
-	})
+}
 
+func TestRefreshToken_UsesCurrentRole(t *testing.T) {
+	var tokenRole string
 	svc := newServiceForTest(&stubUserRepo{
-		getByEmailFn: func(email string) (*domain.User, error) { return nil, nil },
-		getByIDFn:    func(id uint) (*domain.User, error) { return nil, nil },
-		getByProviderIDFn: func(provider, providerID string) (*domain.User, error) {
-			return &domain.User{ID: 10, Username: "existing", Email: "lspyo11@gmail.com"}, nil
+		getByIDFn: func(id uint) (*domain.User, error) {
+			return &domain.User{ID: id, Username: "co", Role: "reader"}, nil
 		},
-		createFn: func(user *domain.User) error { return nil },
 	}, &stubTokenManager{
-		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) { return nil, nil },
-		generateTokenFn: func(userID uint, username string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
-			return "", "", errors.New("gen fail")
+		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) {
+			return &jwt.Claims{UserID: 8, Username: "co", Role: "editor"}, nil
+		},
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+			tokenRole = role
+			return "access", "refresh", nil
 		},
 		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
 	})
-	_, _, err := svc.OAuthLogin("google", "code")
-	if err == nil || !strings.Contains(err.Error(), "failed to generate tokens") {
-		t.Fatalf("expected token generate error, got %v", err)
+	if _, _, err := svc.RefreshToken("old", ""); err != nil || tokenRole != "reader" {
+		t.Fatalf("expected refreshed token with stored role, got role=%q err=%v", tokenRole, err)
+	}
+}
+
+func TestRefreshToken_DeletedUserRejected(t *testing.T) {
+	svc := newServiceForTest(&stubUserRepo{
+		getByIDFn: func(id uint) (*domain.User, error) { return nil, domain.ErrUserNotFound },
+	}, &stubTokenManager{
+		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) {
+			return &jwt.Claims{UserID: 8, Username: "co"}, nil
+		},
+	})
+	if _, _, err := svc.RefreshToken("old", ""); err == nil || !strings.Contains(err.Error(), "invalid refresh token") {
+		t.Fatalf("expected invalid refresh token, got %v", err)
+	}
+}
+
+func TestRefreshToken_KeepsDPoPBinding(t *testing.T) {
+	tm := &stubTokenManager{
+		validateRefreshTokenFn: func(token string) (*jwt.Claims, error) {
+			return &jwt.Claims{UserID: 2, SessionID: "sid", Confirmation: &jwt.Confirmation{JKT: "key-1"}}, nil
+		},
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+			return "new-access", "new-refresh", nil
+		},
+		revokeTokenFn: func(token string, expiresIn time.Duration) error { return nil },
+	}
+	svc := newServiceForTest(&stubUserRepo{
+		getByIDFn: func(id uint) (*domain.User, error) { return &domain.User{ID: id, Username: "u", Role: "owner"}, nil },
+	}, tm)
+	for _, jkt := range []string{"", "key-2"} {
+		if _, _, err := svc.RefreshToken("bound-refresh", jkt); !errors.Is(err, domain.ErrDPoPKeyMismatch) {
+			t.Fatalf("jkt %q: expected key mismatch, got %v", jkt, err)
+		}
+	}
+	if _, _, err := svc.RefreshToken("bound-refresh", "key-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tm.sessions) != 1 || tm.sessions[0].JKT != "key-1" || tm.sessions[0].ID != "sid" {
+		t.Fatalf("expected rotation to keep the binding, got %+v", tm.sessions)
 	}
 }
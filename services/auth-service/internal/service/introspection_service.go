@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"seungpyo.lee/PersonalWebSite/pkg/jwt"
+	"seungpyo.lee/PersonalWebSite/pkg/pat"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// introspectionService implements domain.IntrospectionService.
+type introspectionService struct {
+	tokens jwt.TokenManager
+	users  domain.UserRepository
+	pats   domain.TokenService
+}
+
+// NewIntrospectionService creates a new IntrospectionService.
+func NewIntrospectionService(tokens jwt.TokenManager, users domain.UserRepository, pats domain.TokenService) domain.IntrospectionService {
+	return &introspectionService{tokens: tokens, users: users, pats: pats}
+}
+
+// Introspect resolves a JWT or personal access token. JWTs are inactive once their
+// refresh token or session was revoked, or their user was deleted; the role is the
+// user's current one. Browser sessions are not scoped and report every known scope.
+func (s *introspectionService) Introspect(token string) (*model.IntrospectionResponse, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return &model.IntrospectionResponse{}, nil
+	}
+	if pat.IsPersonalAccessToken(token) {
+		return s.introspectPAT(token)
+	}
+	claims, err := s.tokens.ValidateAccessToken(token)
+	if err != nil {
+		return &model.IntrospectionResponse{}, nil
+	}
+	revoked, err := s.tokens.IsTokenRevoked(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if !revoked {
+		if revoked, err = s.tokens.IsSessionRevoked(claims.SessionID); err != nil {
+			return nil, fmt.Errorf("failed to check session revocation: %w", err)
+		}
+	}
+	if revoked {
+		return &model.IntrospectionResponse{}, nil
+	}
+	user, err := s.users.GetByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return &model.IntrospectionResponse{}, nil
+		}
+		return nil, err
+	}
+	resp := &model.IntrospectionResponse{
+		Active:     true,
+		Scope:      strings.Join(pat.KnownScopes, " "),
+		Username:   user.Username,
+		Subject:    strconv.FormatUint(uint64(user.ID), 10),
+		SessionID:  claims.SessionID,
+		AuthMethod: "jwt",
+		Role:       user.Role,
+		MFA:        claims.MFA,
+	}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.IssuedAt = claims.IssuedAt.Unix()
+	}
+	if jkt := claims.BoundKey(); jkt != "" {
+		resp.Confirmation = &model.Confirmation{JKT: jkt}
+	}
+	return resp, nil
+}
+
+// introspectPAT resolves a personal access token; this counts as a use of the token.
+func (s *introspectionService) introspectPAT(token string) (*model.IntrospectionResponse, error) {
+	identity, err := s.pats.ValidateToken(token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			return &model.IntrospectionResponse{}, nil
+		}
+		return nil, err
+	}
+	resp := &model.IntrospectionResponse{
+		Active:     true,
+		Scope:      identity.Scopes,
+		Username:   identity.Username,
+		Subject:    strconv.FormatUint(uint64(identity.UserID), 10),
+		IssuedAt:   identity.CreatedAt.Unix(),
+		TokenID:    identity.TokenID,
+		AuthMethod: "pat",
+		Role:       identity.Role,
+	}
+	if identity.ExpiresAt != nil {
+		resp.ExpiresAt = identity.ExpiresAt.Unix()
+	}
+	if identity.DPoPJKT != "" {
+		resp.Confirmation = &model.Confirmation{JKT: identity.DPoPJKT}
+	}
+	return resp, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"seungpyo.lee/PersonalWebSite/pkg/jwt"
+	"seungpyo.lee/PersonalWebSite/pkg/pat"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+)
+
+func TestIntrospect_AccessToken(t *testing.T) {
+	exp := time.Now().Add(15 * time.Minute)
+	tm := &stubTokenManager{
+		validateAccessTokenFn: func(token string) (*jwt.Claims, error) {
+			switch token {
+			case "access", "revoked-refresh":
+				return &jwt.Claims{UserID: 4, Username: "old-name", Role: "editor", MFA: true, SessionID: "sid-1",
+					RegisteredClaims: jwtlib.RegisteredClaims{ExpiresAt: jwtlib.NewNumericDate(exp)}}, nil
+			case "deleted-user":
+				return &jwt.Claims{UserID: 9, SessionID: "sid-2"}, nil
+			}
+			return nil, errors.New("invalid token")
+		},
+		revoked: map[string]bool{"revoked-refresh": true},
+	}
+	users := userStore(&domain.User{ID: 4, Username: "me", Role: "owner"})
+	svc := NewIntrospectionService(tm, users, nil)
+
+	resp, err := svc.Introspect(" access ")
+	if err != nil || !resp.Active || resp.Subject != "4" || resp.Username != "me" || resp.Role != "owner" ||
+		resp.SessionID != "sid-1" || !resp.MFA || resp.ExpiresAt != exp.Unix() || resp.AuthMethod != "jwt" || resp.Scope == "" {
+		t.Fatalf("unexpected response %+v err=%v", resp, err)
+	}
+	for _, token := range []string{"", "garbage", "revoked-refresh", "deleted-user"} {
+		if resp, err := svc.Introspect(token); err != nil || resp.Active || resp.Subject != "" {
+			t.Fatalf("%q: expected inactive, got %+v err=%v", token, resp, err)
+		}
+	}
+
+	// logout revokes the session, which ends every access token issued for it
+	_ = tm.RevokeSession("sid-1", time.Hour)
+	if resp, err := svc.Introspect("access"); err != nil || resp.Active {
+		t.Fatalf("expected revoked session to be inactive, got %+v err=%v", resp, err)
+	}
+}
+
+func TestIntrospect_PersonalAccessToken(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	repo := &stubTokenRepo{byHash: map[string]*domain.PersonalAccessToken{
+		pat.Hash("pws_valid"):   {ID: 3, UserID: 7, Scopes: "posts:write"},
+		pat.Hash("pws_revoked"): {ID: 5, UserID: 7, RevokedAt: &past},
+	}}
+	svc := NewIntrospectionService(&stubTokenManager{}, nil, newTokenServiceForTest(repo))
+
+	resp, err := svc.Introspect("pws_valid")
+	if err != nil || !resp.Active || resp.Scope != "posts:write" || resp.TokenID != 3 || resp.Subject != "7" || resp.AuthMethod != "pat" || resp.SessionID != "" {
+		t.Fatalf("unexpected response %+v err=%v", resp, err)
+	}
+	for _, token := range []string{"pws_revoked", "pws_unknown"} {
+		if resp, err := svc.Introspect(token); err != nil || resp.Active {
+			t.Fatalf("%q: expected inactive, got %+v err=%v", token, resp, err)
+		}
+	}
+}
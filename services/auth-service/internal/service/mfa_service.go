@@ -0,0 +1,280 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/pkg/jwt"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/config"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/util"
+)
+
+const (
+	// mfaChallengeTTL bounds how long a login may wait for its second factor.
+	mfaChallengeTTL = 5 * time.Minute
+	// recoveryCodeCount is how many recovery codes enrollment hands out.
+	recoveryCodeCount = 10
+	// totpSkew is how many time steps a code may be off, for clock drift.
+	totpSkew = 1
+)
+
+var readRandom = rand.Read
+
+// mfaService implements domain.MFAService.
+type mfaService struct {
+	repo       domain.MFARepository
+	users      domain.UserRepository
+	challenges domain.MFAChallengeStore
+	attempts   domain.LoginAttemptStore
+	tokens     jwt.TokenManager
+	config     config.AuthConfig
+	now        func() time.Time
+}
+
+// NewMFAService creates a new MFAService. Wrong codes are counted in attempts like
+// failed password logins, per user.
+func NewMFAService(repo domain.MFARepository, users domain.UserRepository, challenges domain.MFAChallengeStore, attempts domain.LoginAttemptStore, tokens jwt.TokenManager, cfg config.AuthConfig) domain.MFAService {
+	return &mfaService{repo: repo, users: users, challenges: challenges, attempts: attempts, tokens: tokens, config: cfg, now: time.Now}
+}
+
+// enabled returns the user's authenticator, or ErrMFANotEnrolled unless it is confirmed.
+func (s *mfaService) enabled(userID uint) (*domain.TOTPCredential, error) {
+	cred, err := s.repo.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !cred.Enabled {
+		return nil, domain.ErrMFANotEnrolled
+	}
+	return cred, nil
+}
+
+// Status reports whether two-factor authentication is on and how many recovery codes are left.
+func (s *mfaService) Status(userID uint) (*model.MFAStatus, error) {
+	if _, err := s.enabled(userID); err != nil {
+		if errors.Is(err, domain.ErrMFANotEnrolled) {
+			return &model.MFAStatus{}, nil
+		}
+		return nil, err
+	}
+	left, err := s.repo.CountRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.MFAStatus{Enabled: true, RecoveryCodesLeft: left}, nil
+}
+
+// BeginEnrollment stores a new unconfirmed secret and returns it with its provisioning URI.
+func (s *mfaService) BeginEnrollment(userID uint) (*model.TOTPEnrollment, error) {
+	if _, err := s.enabled(userID); err == nil {
+		return nil, domain.ErrMFAAlreadyEnabled
+	} else if !errors.Is(err, domain.ErrMFANotEnrolled) {
+		return nil, err
+	}
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+	secret, err := util.NewTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	if err := s.repo.SaveTOTP(&domain.TOTPCredential{UserID: userID, Secret: secret}); err != nil {
+		return nil, err
+	}
+	return &model.TOTPEnrollment{Secret: secret, ProvisioningURI: util.TOTPURI(s.config.MFAIssuer, user.Email, secret)}, nil
+}
+
+// ConfirmEnrollment checks a first code from the new authenticator, stores fresh
+// recovery codes and turns two-factor authentication on.
+func (s *mfaService) ConfirmEnrollment(userID uint, code string) ([]string, error) {
+	cred, err := s.repo.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if cred.Enabled {
+		return nil, domain.ErrMFAAlreadyEnabled
+	}
+	if err := s.checkCode(cred, code, false); err != nil {
+		return nil, err
+	}
+	codes, err := s.replaceRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.EnableTOTP(userID); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable removes the authenticator and the recovery codes.
+func (s *mfaService) Disable(userID uint, code string) error {
+	cred, err := s.enabled(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkCode(cred, code, true); err != nil {
+		return err
+	}
+	return s.repo.DeleteTOTP(userID)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes; the old ones stop working.
+func (s *mfaService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	cred, err := s.enabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCode(cred, code, true); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(userID)
+}
+
+// Challenge stores the login under a random token until its second factor arrives.
+// A failure to look up the authenticator fails the login rather than skipping the step.
+func (s *mfaService) Challenge(user *domain.User, newUser bool, jkt string) (string, error) {
+	if _, err := s.enabled(user.ID); err != nil {
+		if errors.Is(err, domain.ErrMFANotEnrolled) {
+			return "", nil
+		}
+		return "", err
+	}
+	buf := make([]byte, 32)
+	if _, err := readRandom(buf); err != nil {
+		return "", fmt.Errorf("failed to generate mfa challenge: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	if err := s.challenges.SaveChallenge(token, &model.MFAChallenge{UserID: user.ID, NewUser: newUser, JKT: jkt}, mfaChallengeTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Verify completes a challenged login. The challenge survives wrong codes until it
+// expires, but wrong codes count towards the user's lockout.
+func (s *mfaService) Verify(challenge, code, jkt string) (*model.LoginResponse, error) {
+	pending, err := s.challenges.GetChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+	// a challenge started with a DPoP key is finished by the same key
+	if pending == nil || pending.JKT != jkt {
+		return nil, domain.ErrInvalidMFAChallenge
+	}
+	cred, err := s.enabled(pending.UserID)
+	if err != nil {
+		if errors.Is(err, domain.ErrMFANotEnrolled) {
+			return nil, domain.ErrInvalidMFAChallenge
+		}
+		return nil, err
+	}
+	if err := s.checkCode(cred, code, true); err != nil {
+		return nil, err
+	}
+	if pending, err = s.challenges.ConsumeChallenge(challenge); err != nil {
+		return nil, err
+	}
+	if pending == nil {
+		return nil, domain.ErrInvalidMFAChallenge
+	}
+	user, err := s.users.GetByID(pending.UserID)
+	if err != nil {
+		return nil, domain.ErrInvalidMFAChallenge
+	}
+	resp, err := issueTokens(s.tokens, s.config, user, true, pending.JKT)
+	if err != nil {
+		return nil, err
+	}
+	resp.NewUser = pending.NewUser
+	return resp, nil
+}
+
+// checkCode accepts a current authenticator code, or with allowRecovery an unused
+// recovery code. Wrong codes are counted; too many lock the user out for a while.
+func (s *mfaService) checkCode(cred *domain.TOTPCredential, code string, allowRecovery bool) error {
+	key := "mfa:" + strconv.FormatUint(uint64(cred.UserID), 10)
+	n, err := s.attempts.Failures(key)
+	if err != nil {
+		return fmt.Errorf("failed to check mfa attempts: %w", err)
+	}
+	if n >= s.config.LoginMaxFailures {
+		return domain.ErrLoginLocked
+	}
+	ok, err := s.matchCode(cred, code, allowRecovery)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if _, err := s.attempts.RecordFailure(key, time.Duration(s.config.LoginLockoutSecs)*time.Second); err != nil {
+			log.Printf("failed to record mfa failure: %v", err)
+		}
+		return domain.ErrInvalidMFACode
+	}
+	if err := s.attempts.ClearFailures(key); err != nil {
+		log.Printf("failed to clear mfa failures: %v", err)
+	}
+	return nil
+}
+
+func (s *mfaService) matchCode(cred *domain.TOTPCredential, code string, allowRecovery bool) (bool, error) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) == util.TOTPDigits {
+		current := util.TOTPStep(s.now())
+		for step := current - totpSkew; step <= current+totpSkew; step++ {
+			want, err := util.TOTPCode(cred.Secret, step)
+			if err != nil {
+				return false, err
+			}
+			if subtle.ConstantTimeCompare([]byte(code), []byte(want)) == 1 {
+				// a code is spent once, including every earlier step
+				return s.repo.AdvanceStep(cred.UserID, step)
+			}
+		}
+		return false, nil
+	}
+	if !allowRecovery {
+		return false, nil
+	}
+	return s.repo.UseRecoveryCode(cred.UserID, hashRecoveryCode(code), s.now())
+}
+
+// replaceRecoveryCodes generates new recovery codes and stores their hashes.
+func (s *mfaService) replaceRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := readRandom(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		// 8 base32 characters, shown as xxxx-xxxx
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(buf))
+		codes = append(codes, raw[:4]+"-"+raw[4:])
+		hashes = append(hashes, hashRecoveryCode(raw))
+	}
+	if err := s.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode normalizes a recovery code as typed and hashes it.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
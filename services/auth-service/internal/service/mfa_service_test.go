@@ -0,0 +1,208 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/pkg/role"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/util"
+)
+
+// stubMFARepo keeps one authenticator and its recovery codes in memory.
+type stubMFARepo struct {
+	cred  *domain.TOTPCredential
+	codes map[string]bool // hash -> used
+}
+
+func (r *stubMFARepo) GetTOTP(userID uint) (*domain.TOTPCredential, error) {
+	if r.cred == nil || r.cred.UserID != userID {
+		return nil, domain.ErrMFANotEnrolled
+	}
+	c := *r.cred
+	return &c, nil
+}
+func (r *stubMFARepo) SaveTOTP(cred *domain.TOTPCredential) error {
+	c := *cred
+	r.cred = &c
+	return nil
+}
+func (r *stubMFARepo) EnableTOTP(userID uint) error {
+	r.cred.Enabled = true
+	return nil
+}
+func (r *stubMFARepo) DeleteTOTP(userID uint) error {
+	r.cred, r.codes = nil, nil
+	return nil
+}
+func (r *stubMFARepo) AdvanceStep(userID uint, step int64) (bool, error) {
+	if r.cred.LastStep >= step {
+		return false, nil
+	}
+	r.cred.LastStep = step
+	return true, nil
+}
+func (r *stubMFARepo) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	r.codes = map[string]bool{}
+	for _, h := range hashes {
+		r.codes[h] = false
+	}
+	return nil
+}
+func (r *stubMFARepo) UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error) {
+	if used, ok := r.codes[hash]; !ok || used {
+		return false, nil
+	}
+	r.codes[hash] = true
+	return true, nil
+}
+func (r *stubMFARepo) CountRecoveryCodes(userID uint) (int64, error) {
+	var n int64
+	for _, used := range r.codes {
+		if !used {
+			n++
+		}
+	}
+	return n, nil
+}
+
+type stubChallengeStore struct {
+	saved map[string]*model.MFAChallenge
+}
+
+func (s *stubChallengeStore) SaveChallenge(token string, ch *model.MFAChallenge, ttl time.Duration) error {
+	if s.saved == nil {
+		s.saved = map[string]*model.MFAChallenge{}
+	}
+	s.saved[token] = ch
+	return nil
+}
+func (s *stubChallengeStore) GetChallenge(token string) (*model.MFAChallenge, error) {
+	return s.saved[token], nil
+}
+func (s *stubChallengeStore) ConsumeChallenge(token string) (*model.MFAChallenge, error) {
+	ch := s.saved[token]
+	delete(s.saved, token)
+	return ch, nil
+}
+
+// newMFAServiceForTest returns an MFA service for user 4 with its clock at now.
+func newMFAServiceForTest(now time.Time) (*mfaService, *stubMFARepo, *stubAttempts, *stubTokenManager) {
+	repo := &stubMFARepo{}
+	attempts := &stubAttempts{}
+	tokens := loginTokens()
+	users := userStore(&domain.User{ID: 4, Email: "me@example.com", Username: "me", Role: role.Owner})
+	svc := NewMFAService(repo, users, &stubChallengeStore{}, attempts, tokens, passwordConfig()).(*mfaService)
+	svc.now = func() time.Time { return now }
+	return svc, repo, attempts, tokens
+}
+
+func TestMFAEnrollmentAndLogin(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	svc, repo, _, tokens := newMFAServiceForTest(now)
+
+	enrollment, err := svc.BeginEnrollment(4)
+	if err != nil || enrollment.Secret == "" || enrollment.ProvisioningURI == "" {
+		t.Fatalf("unexpected enrollment %+v err=%v", enrollment, err)
+	}
+	if ch, _ := svc.Challenge(&domain.User{ID: 4}, false, ""); ch != "" {
+		t.Fatalf("expected no challenge before the authenticator is confirmed")
+	}
+	if _, err := svc.ConfirmEnrollment(4, "000000"); !errors.Is(err, domain.ErrInvalidMFACode) {
+		t.Fatalf("expected wrong code to be rejected, got %v", err)
+	}
+	code, _ := util.TOTPCode(enrollment.Secret, util.TOTPStep(now))
+	recovery, err := svc.ConfirmEnrollment(4, code)
+	if err != nil || len(recovery) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %v err=%v", recoveryCodeCount, recovery, err)
+	}
+	if _, err := svc.BeginEnrollment(4); !errors.Is(err, domain.ErrMFAAlreadyEnabled) {
+		t.Fatalf("expected re-enrollment to be refused, got %v", err)
+	}
+
+	challenge, err := svc.Challenge(&domain.User{ID: 4}, false, "")
+	if err != nil || challenge == "" {
+		t.Fatalf("expected a challenge, got %q err=%v", challenge, err)
+	}
+	// the code used to confirm the authenticator is spent
+	if _, err := svc.Verify(challenge, code, ""); !errors.Is(err, domain.ErrInvalidMFACode) {
+		t.Fatalf("expected replayed code to be rejected, got %v", err)
+	}
+	next, _ := util.TOTPCode(enrollment.Secret, util.TOTPStep(now)+1)
+	resp, err := svc.Verify(challenge, next, "")
+	if err != nil || resp.Token != "access" || resp.User.ID != 4 || tokens.mfaIssued != 1 {
+		t.Fatalf("expected mfa tokens, got %+v err=%v mfaIssued=%d", resp, err, tokens.mfaIssued)
+	}
+	if _, err := svc.Verify(challenge, next, ""); !errors.Is(err, domain.ErrInvalidMFAChallenge) {
+		t.Fatalf("expected challenge to be usable once, got %v", err)
+	}
+
+	// a recovery code works once, in any case and without the dash
+	challenge, _ = svc.Challenge(&domain.User{ID: 4}, false, "")
+	typed := "  " + recovery[0][:4] + recovery[0][5:] + " "
+	if _, err := svc.Verify(challenge, typed, ""); err != nil {
+		t.Fatalf("expected recovery code to work, got %v", err)
+	}
+	if status, _ := svc.Status(4); !status.Enabled || status.RecoveryCodesLeft != recoveryCodeCount-1 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if err := svc.Disable(4, recovery[0]); !errors.Is(err, domain.ErrInvalidMFACode) {
+		t.Fatalf("expected used recovery code to be rejected, got %v", err)
+	}
+	if err := svc.Disable(4, recovery[1]); err != nil || repo.cred != nil {
+		t.Fatalf("expected authenticator to be removed, err=%v", err)
+	}
+}
+
+func TestMFAVerify_LocksOutAfterFailures(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	svc, repo, attempts, _ := newMFAServiceForTest(now)
+	repo.cred = &domain.TOTPCredential{UserID: 4, Secret: "JBSWY3DPEHPK3PXP", Enabled: true}
+	challenge, _ := svc.Challenge(&domain.User{ID: 4}, false, "")
+
+	for i := 0; i < 5; i++ {
+		if _, err := svc.Verify(challenge, "bad-code", ""); !errors.Is(err, domain.ErrInvalidMFACode) {
+			t.Fatalf("attempt %d: expected invalid code, got %v", i, err)
+		}
+	}
+	code, _ := util.TOTPCode(repo.cred.Secret, util.TOTPStep(now))
+	if _, err := svc.Verify(challenge, code, ""); !errors.Is(err, domain.ErrLoginLocked) {
+		t.Fatalf("expected lockout, got %v", err)
+	}
+	if attempts.failures["mfa:4"] != 5 {
+		t.Fatalf("expected failures counted per user, got %v", attempts.failures)
+	}
+}
+
+func TestMFAVerify_RequiresSameDPoPKey(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	svc, repo, _, tokens := newMFAServiceForTest(now)
+	repo.cred = &domain.TOTPCredential{UserID: 4, Secret: "JBSWY3DPEHPK3PXP", Enabled: true}
+	challenge, _ := svc.Challenge(&domain.User{ID: 4}, false, "key-1")
+	code, _ := util.TOTPCode(repo.cred.Secret, util.TOTPStep(now))
+
+	for _, jkt := range []string{"", "key-2"} {
+		if _, err := svc.Verify(challenge, code, jkt); !errors.Is(err, domain.ErrInvalidMFAChallenge) {
+			t.Fatalf("jkt %q: expected the challenge to be refused, got %v", jkt, err)
+		}
+	}
+	resp, err := svc.Verify(challenge, code, "key-1")
+	if err != nil || resp.TokenType != "DPoP" || tokens.sessions[0].JKT != "key-1" || !tokens.sessions[0].MFA {
+		t.Fatalf("expected DPoP-bound mfa tokens, got %+v err=%v", resp, err)
+	}
+}
+
+func TestPasswordLogin_ChallengesMFAUsers(t *testing.T) {
+	hash, _ := util.HashPassword("correct horse battery")
+	users := userStore(&domain.User{ID: 4, Email: "me@example.com", Username: "me", Role: role.Owner, PasswordHash: hash})
+	mfa, repo, _, tokens := newMFAServiceForTest(time.Now())
+	repo.cred = &domain.TOTPCredential{UserID: 4, Secret: "JBSWY3DPEHPK3PXP", Enabled: true}
+	svc := NewPasswordService(users, tokens, &stubAttempts{}, nil, mfa, passwordConfig())
+
+	resp, err := svc.Login(model.PasswordLoginRequest{Email: "me@example.com", Password: "correct horse battery"}, "10.0.0.1")
+	if err != nil || !resp.MFARequired || resp.MFAToken == "" || resp.Token != "" || resp.RefreshToken != "" {
+		t.Fatalf("expected a challenge without tokens, got %+v err=%v", resp, err)
+	}
+}
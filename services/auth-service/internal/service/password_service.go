@@ -0,0 +1,277 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/pkg/jwt"
+	"seungpyo.lee/PersonalWebSite/pkg/role"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/config"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/util"
+)
+
+// Password length limits; bcrypt ignores everything after 72 bytes.
+const (
+	minPasswordLen = 10
+	maxPasswordLen = 72
+)
+
+// passwordService implements domain.PasswordService.
+type passwordService struct {
+	repo     domain.UserRepository
+	tokens   jwt.TokenManager
+	attempts domain.LoginAttemptStore
+	mail     domain.MailSender
+	mfa      domain.MFAService
+	config   config.AuthConfig
+	now      func() time.Time
+}
+
+// NewPasswordService creates a new PasswordService. mail may be nil, in which case
+// registration and password resets fail with ErrMailNotConfigured. mfa may be nil, in
+// which case logins never ask for a second factor.
+func NewPasswordService(repo domain.UserRepository, tokens jwt.TokenManager, attempts domain.LoginAttemptStore, mail domain.MailSender, mfa domain.MFAService, cfg config.AuthConfig) domain.PasswordService {
+	return &passwordService{repo: repo, tokens: tokens, attempts: attempts, mail: mail, mfa: mfa, config: cfg, now: time.Now}
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// compareDummy spends the time of a password check on a login for an account without
+// a password, so response times do not reveal which emails exist.
+func compareDummy(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = util.HashPassword("not the password of any account")
+	})
+	_ = util.CheckPassword(dummyHash, password)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func checkPasswordPolicy(password string) error {
+	if len(password) < minPasswordLen || len(password) > maxPasswordLen {
+		return domain.ErrWeakPassword
+	}
+	return nil
+}
+
+// Login checks the password and issues tokens, or a two-factor challenge. Failures are
+// counted per account and per client IP; either counter reaching its limit locks
+// logins until the window ends.
+func (s *passwordService) Login(req model.PasswordLoginRequest, clientIP string) (*model.LoginResponse, error) {
+	if !s.config.PasswordLoginEnabled {
+		return nil, domain.ErrPasswordLoginDisabled
+	}
+	email := normalizeEmail(req.Email)
+	accountKey, ipKey := "account:"+email, "ip:"+clientIP
+	for key, limit := range map[string]int{accountKey: s.config.LoginMaxFailures, ipKey: s.config.LoginIPMaxFailures} {
+		n, err := s.attempts.Failures(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check login attempts: %w", err)
+		}
+		if n >= limit {
+			return nil, domain.ErrLoginLocked
+		}
+	}
+
+	user, err := s.repo.GetByEmail(email)
+	if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if err != nil || user == nil || user.PasswordHash == "" {
+		compareDummy(req.Password)
+		s.recordFailure(accountKey, ipKey)
+		return nil, domain.ErrInvalidCredentials
+	}
+	if util.CheckPassword(user.PasswordHash, req.Password) != nil {
+		s.recordFailure(accountKey, ipKey)
+		return nil, domain.ErrInvalidCredentials
+	}
+	if err := s.attempts.ClearFailures(accountKey); err != nil {
+		log.Printf("failed to clear login failures: %v", err)
+	}
+	return issueLogin(s.tokens, s.config, s.mfa, user, false, req.JKT)
+}
+
+func (s *passwordService) recordFailure(keys ...string) {
+	window := time.Duration(s.config.LoginLockoutSecs) * time.Second
+	for _, key := range keys {
+		if _, err := s.attempts.RecordFailure(key, window); err != nil {
+			log.Printf("failed to record login failure: %v", err)
+		}
+	}
+}
+
+// Register creates a reader account and mails it a link to set the password. The
+// password is not taken here so that only the owner of the address can use it.
+func (s *passwordService) Register(req model.RegisterRequest) error {
+	if !s.config.PasswordLoginEnabled {
+		return domain.ErrPasswordLoginDisabled
+	}
+	if !s.config.RegistrationEnabled {
+		return domain.ErrRegistrationDisabled
+	}
+	if s.mail == nil {
+		return domain.ErrMailNotConfigured
+	}
+	username := strings.TrimSpace(req.Username)
+	if !usernamePattern.MatchString(username) {
+		return domain.ErrInvalidUsername
+	}
+	email := normalizeEmail(req.Email)
+	existing, err := s.repo.GetByEmail(email)
+	if err == nil && existing != nil {
+		return nil
+	}
+	if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if taken, err := s.repo.GetByUsername(username); err == nil && taken != nil {
+		return domain.ErrUsernameTaken
+	}
+	user := &domain.User{Email: email, Username: username, DisplayName: username, Role: role.Reader}
+	if err := s.repo.Create(user); err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return s.sendResetLink(user, "Set your password", "Welcome! Open this link to set the password of your new account:")
+}
+
+// RequestPasswordReset mails a reset link to a known email. Requests per email are
+// limited like failed logins so the address cannot be flooded.
+func (s *passwordService) RequestPasswordReset(email string) error {
+	if !s.config.PasswordLoginEnabled {
+		return domain.ErrPasswordLoginDisabled
+	}
+	if s.mail == nil {
+		return domain.ErrMailNotConfigured
+	}
+	email = normalizeEmail(email)
+	user, err := s.repo.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	n, err := s.attempts.RecordFailure("reset:"+email, time.Duration(s.config.LoginLockoutSecs)*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to record reset request: %w", err)
+	}
+	if n > s.config.LoginMaxFailures {
+		log.Printf("password reset for user %d throttled", user.ID)
+		return nil
+	}
+	return s.sendResetLink(user, "Reset your password", "Open this link to choose a new password:")
+}
+
+// ResetPassword sets a new password with a token from sendResetLink.
+func (s *passwordService) ResetPassword(token, password string) (*domain.User, error) {
+	if !s.config.PasswordLoginEnabled {
+		return nil, domain.ErrPasswordLoginDisabled
+	}
+	if err := checkPasswordPolicy(password); err != nil {
+		return nil, err
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, domain.ErrInvalidResetToken
+	}
+	userID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, domain.ErrInvalidResetToken
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || s.now().Unix() > expires {
+		return nil, domain.ErrInvalidResetToken
+	}
+	user, err := s.repo.GetByID(uint(userID))
+	if err != nil {
+		return nil, domain.ErrInvalidResetToken
+	}
+	want := s.sign(parts[0]+"."+parts[1], user.PasswordHash)
+	if !hmac.Equal([]byte(parts[2]), []byte(want)) {
+		return nil, domain.ErrInvalidResetToken
+	}
+	hash, err := util.HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.PasswordHash = hash
+	if err := s.repo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	if err := s.attempts.ClearFailures("account:" + normalizeEmail(user.Email)); err != nil {
+		log.Printf("failed to clear login failures: %v", err)
+	}
+	return user, nil
+}
+
+// BootstrapOwner creates or promotes the owner account and sets its password. It is
+// used by the bootstrap-owner command and works while password login is disabled.
+func (s *passwordService) BootstrapOwner(email, username, password string) (*domain.User, error) {
+	if err := checkPasswordPolicy(password); err != nil {
+		return nil, err
+	}
+	hash, err := util.HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	email = normalizeEmail(email)
+	user, err := s.repo.GetByEmail(email)
+	if err != nil {
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		username = strings.TrimSpace(username)
+		if !usernamePattern.MatchString(username) {
+			return nil, domain.ErrInvalidUsername
+		}
+		user = &domain.User{Email: email, Username: username, DisplayName: username, Role: role.Owner, PasswordHash: hash}
+		if err := s.repo.Create(user); err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+		return user, nil
+	}
+	user.Role = role.Owner
+	user.PasswordHash = hash
+	if err := s.repo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	return user, nil
+}
+
+// sendResetLink mails a link to the web-front reset page. The token is
+// "<user id>.<expiry>.<signature>"; the signature covers the current password hash,
+// so the link stops working once it has been used.
+func (s *passwordService) sendResetLink(user *domain.User, subject, intro string) error {
+	ttl := time.Duration(s.config.PasswordResetTTLMins) * time.Minute
+	payload := fmt.Sprintf("%d.%d", user.ID, s.now().Add(ttl).Unix())
+	token := payload + "." + s.sign(payload, user.PasswordHash)
+	link := s.config.MYDOMAIN + "/reset-password?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("%s\n\n%s\n\nThe link expires in %d minutes. If you did not ask for it, ignore this mail.\n", intro, link, s.config.PasswordResetTTLMins)
+	if err := s.mail.SendMail(user.Email, subject, body); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+func (s *passwordService) sign(payload, passwordHash string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.JWTSecretKey))
+	mac.Write([]byte("password-reset\x00" + payload + "\x00" + passwordHash))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	pkgconfig "seungpyo.lee/PersonalWebSite/pkg/config"
+	"seungpyo.lee/PersonalWebSite/pkg/role"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/config"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/util"
+)
+
+type stubAttempts struct {
+	failures map[string]int
+}
+
+func (s *stubAttempts) Failures(key string) (int, error) { return s.failures[key], nil }
+func (s *stubAttempts) RecordFailure(key string, window time.Duration) (int, error) {
+	if s.failures == nil {
+		s.failures = map[string]int{}
+	}
+	s.failures[key]++
+	return s.failures[key], nil
+}
+func (s *stubAttempts) ClearFailures(key string) error {
+	delete(s.failures, key)
+	return nil
+}
+
+type sentMail struct{ to, subject, body string }
+
+type stubMailer struct {
+	sent []sentMail
+}
+
+func (m *stubMailer) SendMail(to, subject, body string) error {
+	m.sent = append(m.sent, sentMail{to, subject, body})
+	return nil
+}
+
+func passwordConfig() config.AuthConfig {
+	return config.AuthConfig{
+		GlobalConfig:         pkgconfig.GlobalConfig{AccessTokenTTL: 15, RefreshTokenTTL: 60},
+		JWTSecretKey:         "secret",
+		PasswordLoginEnabled: true,
+		LoginMaxFailures:     5,
+		LoginIPMaxFailures:   20,
+		LoginLockoutSecs:     900,
+		PasswordResetTTLMins: 30,
+		MYDOMAIN:             "https://blog.example",
+	}
+}
+
+func loginTokens() *stubTokenManager {
+	return &stubTokenManager{
+		generateTokenFn: func(userID uint, username, role string, accessTokenExp, refreshTokenExp time.Duration) (string, string, error) {
+			return "access", "refresh", nil
+		},
+	}
+}
+
+// userStore is a stubUserRepo holding a single user that Update writes back to.
+func userStore(user *domain.User) *stubUserRepo {
+	return &stubUserRepo{
+		getByEmailFn: func(email string) (*domain.User, error) {
+			if user == nil || email != user.Email {
+				return nil, domain.ErrUserNotFound
+			}
+			u := *user
+			return &u, nil
+		},
+		getByIDFn: func(id uint) (*domain.User, error) {
+			if user == nil || id != user.ID {
+				return nil, domain.ErrUserNotFound
+			}
+			u := *user
+			return &u, nil
+		},
+		updateFn: func(u *domain.User) error {
+			*user = *u
+			return nil
+		},
+	}
+}
+
+func TestPasswordLogin(t *testing.T) {
+	hash, _ := util.HashPassword("correct horse battery")
+	repo := userStore(&domain.User{ID: 4, Email: "me@example.com", Username: "me", Role: role.Editor, PasswordHash: hash})
+	attempts := &stubAttempts{}
+	svc := NewPasswordService(repo, loginTokens(), attempts, nil, nil, passwordConfig())
+
+	_, err := svc.Login(model.PasswordLoginRequest{Email: "me@example.com", Password: "wrong password"}, "10.0.0.1")
+	if !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+	_, err = svc.Login(model.PasswordLoginRequest{Email: "nobody@example.com", Password: "correct horse battery"}, "10.0.0.1")
+	if !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials for unknown email, got %v", err)
+	}
+	if attempts.failures["account:me@example.com"] != 1 || attempts.failures["ip:10.0.0.1"] != 2 {
+		t.Fatalf("unexpected failure counters %v", attempts.failures)
+	}
+
+	resp, err := svc.Login(model.PasswordLoginRequest{Email: " Me@Example.com ", Password: "correct horse battery"}, "10.0.0.1")
+	if err != nil || resp.Token != "access" || resp.User.ID != 4 {
+		t.Fatalf("expected login, got resp=%+v err=%v", resp, err)
+	}
+	if resp.TokenType != "" {
+		t.Fatalf("expected bearer tokens without a DPoP key, got %q", resp.TokenType)
+	}
+	if _, ok := attempts.failures["account:me@example.com"]; ok {
+		t.Fatalf("expected account failures to be cleared")
+	}
+}
+
+func TestPasswordLogin_BindsDPoPKey(t *testing.T) {
+	hash, _ := util.HashPassword("correct horse battery")
+	tokens := loginTokens()
+	svc := NewPasswordService(userStore(&domain.User{ID: 4, Email: "me@example.com", PasswordHash: hash}), tokens, &stubAttempts{}, nil, nil, passwordConfig())
+
+	resp, err := svc.Login(model.PasswordLoginRequest{Email: "me@example.com", Password: "correct horse battery", JKT: "key-1"}, "10.0.0.1")
+	if err != nil || resp.TokenType != "DPoP" || len(tokens.sessions) != 1 || tokens.sessions[0].JKT != "key-1" {
+		t.Fatalf("expected DPoP-bound tokens, got %+v err=%v sessions=%+v", resp, err, tokens.sessions)
+	}
+}
+
+func TestPasswordLogin_Lockout(t *testing.T) {
+	hash, _ := util.HashPassword("correct horse battery")
+	repo := userStore(&domain.User{ID: 4, Email: "me@example.com", PasswordHash: hash})
+	req := model.PasswordLoginRequest{Email: "me@example.com", Password: "correct horse battery"}
+
+	for _, failures := range []map[string]int{{"account:me@example.com": 5}, {"ip:10.0.0.1": 20}} {
+		svc := NewPasswordService(repo, loginTokens(), &stubAttempts{failures: failures}, nil, nil, passwordConfig())
+		if _, err := svc.Login(req, "10.0.0.1"); !errors.Is(err, domain.ErrLoginLocked) {
+			t.Fatalf("%v: expected lockout, got %v", failures, err)
+		}
+	}
+
+	cfg := passwordConfig()
+	cfg.PasswordLoginEnabled = false
+	svc := NewPasswordService(repo, loginTokens(), &stubAttempts{}, nil, nil, cfg)
+	if _, err := svc.Login(req, "10.0.0.1"); !errors.Is(err, domain.ErrPasswordLoginDisabled) {
+		t.Fatalf("expected disabled, got %v", err)
+	}
+}
+
+// resetToken extracts the token from the link in a reset mail.
+func resetToken(t *testing.T, m sentMail) string {
+	t.Helper()
+	i := strings.Index(m.body, "https://blog.example/reset-password?token=")
+	if i < 0 {
+		t.Fatalf("no reset link in %q", m.body)
+	}
+	u, err := url.Parse(strings.Fields(m.body[i:])[0])
+	if err != nil {
+		t.Fatalf("bad reset link: %v", err)
+	}
+	return u.Query().Get("token")
+}
+
+func TestPasswordReset_TokenIsSingleUse(t *testing.T) {
+	user := &domain.User{ID: 4, Email: "me@example.com"}
+	mailer := &stubMailer{}
+	attempts := &stubAttempts{failures: map[string]int{"account:me@example.com": 5}}
+	svc := NewPasswordService(userStore(user), loginTokens(), attempts, mailer, nil, passwordConfig())
+
+	if err := svc.RequestPasswordReset("nobody@example.com"); err != nil || len(mailer.sent) != 0 {
+		t.Fatalf("expected silent success for unknown email, got err=%v mails=%d", err, len(mailer.sent))
+	}
+	if err := svc.RequestPasswordReset("me@example.com"); err != nil || len(mailer.sent) != 1 {
+		t.Fatalf("expected reset mail, got err=%v mails=%d", err, len(mailer.sent))
+	}
+	token := resetToken(t, mailer.sent[0])
+
+	if _, err := svc.ResetPassword(token, "short"); !errors.Is(err, domain.ErrWeakPassword) {
+		t.Fatalf("expected weak password, got %v", err)
+	}
+	if _, err := svc.ResetPassword(token+"x", "a much better password"); !errors.Is(err, domain.ErrInvalidResetToken) {
+		t.Fatalf("expected tampered token to fail, got %v", err)
+	}
+	if _, err := svc.ResetPassword(token, "a much better password"); err != nil {
+		t.Fatalf("expected reset, got %v", err)
+	}
+	if util.CheckPassword(user.PasswordHash, "a much better password") != nil {
+		t.Fatalf("expected new password to be stored")
+	}
+	if _, ok := attempts.failures["account:me@example.com"]; ok {
+		t.Fatalf("expected reset to lift the account lockout")
+	}
+	if _, err := svc.ResetPassword(token, "yet another password"); !errors.Is(err, domain.ErrInvalidResetToken) {
+		t.Fatalf("expected used token to fail, got %v", err)
+	}
+}
+
+func TestPasswordReset_ExpiredTokenAndThrottle(t *testing.T) {
+	user := &domain.User{ID: 4, Email: "me@example.com"}
+	mailer := &stubMailer{}
+	svc := NewPasswordService(userStore(user), loginTokens(), &stubAttempts{}, mailer, nil, passwordConfig()).(*passwordService)
+
+	for i := 0; i < 7; i++ {
+		if err := svc.RequestPasswordReset("me@example.com"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(mailer.sent) != 5 {
+		t.Fatalf("expected reset mails to be throttled to 5, got %d", len(mailer.sent))
+	}
+
+	svc.now = func() time.Time { return time.Now().Add(31 * time.Minute) }
+	if _, err := svc.ResetPassword(resetToken(t, mailer.sent[0]), "a much better password"); !errors.Is(err, domain.ErrInvalidResetToken) {
+		t.Fatalf("expected expired token to fail, got %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	var created *domain.User
+	repo := userStore(&domain.User{ID: 1, Email: "taken@example.com"})
+	repo.createFn = func(u *domain.User) error {
+		u.ID = 9
+		created = u
+		return nil
+	}
+	mailer := &stubMailer{}
+	req := model.RegisterRequest{Email: "New@Example.com", Username: "newbie"}
+
+	svc := NewPasswordService(repo, loginTokens(), &stubAttempts{}, mailer, nil, passwordConfig())
+	if err := svc.Register(req); !errors.Is(err, domain.ErrRegistrationDisabled) {
+		t.Fatalf("expected registration to be disabled by default, got %v", err)
+	}
+
+	cfg := passwordConfig()
+	cfg.RegistrationEnabled = true
+	svc = NewPasswordService(repo, loginTokens(), &stubAttempts{}, mailer, nil, cfg)
+	if err := svc.Register(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created == nil || created.Email != "new@example.com" || created.Role != role.Reader || created.PasswordHash != "" {
+		t.Fatalf("unexpected account %+v", created)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].to != "new@example.com" {
+		t.Fatalf("expected set-password mail, got %+v", mailer.sent)
+	}
+
+	created = nil
+	if err := svc.Register(model.RegisterRequest{Email: "taken@example.com", Username: "other"}); err != nil || created != nil || len(mailer.sent) != 1 {
+		t.Fatalf("expected silent success for existing email, got err=%v created=%v", err, created)
+	}
+}
+
+func TestBootstrapOwner(t *testing.T) {
+	var created *domain.User
+	repo := userStore(&domain.User{ID: 2, Email: "co@example.com", Role: role.Reader})
+	repo.createFn = func(u *domain.User) error {
+		created = u
+		return nil
+	}
+	svc := NewPasswordService(repo, loginTokens(), &stubAttempts{}, nil, nil, passwordConfig())
+
+	user, err := svc.BootstrapOwner("me@example.com", "me", "correct horse battery")
+	if err != nil || created != user || user.Role != role.Owner || util.CheckPassword(user.PasswordHash, "correct horse battery") != nil {
+		t.Fatalf("expected new owner, got %+v err=%v", user, err)
+	}
+	user, err = svc.BootstrapOwner("co@example.com", "", "correct horse battery")
+	if err != nil || user.ID != 2 || user.Role != role.Owner || user.PasswordHash == "" {
+		t.Fatalf("expected existing account to be promoted, got %+v err=%v", user, err)
+	}
+	if _, err := svc.BootstrapOwner("me@example.com", "me", "short"); !errors.Is(err, domain.ErrWeakPassword) {
+		t.Fatalf("expected weak password, got %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// maxAvatarBytes caps the decoded size of an uploaded avatar.
+const maxAvatarBytes = 2 << 20
+
+var (
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	avatarDataURL   = regexp.MustCompile(`^data:image/(png|jpeg|gif|webp);base64,`)
+)
+
+// profileService implements domain.ProfileService.
+type profileService struct {
+	repo    domain.UserRepository
+	avatars domain.AvatarStore
+}
+
+// NewProfileService creates a new ProfileService. avatars may be nil, in which case
+// avatar uploads are rejected and provider pictures are not imported.
+func NewProfileService(repo domain.UserRepository, avatars domain.AvatarStore) domain.ProfileService {
+	return &profileService{repo: repo, avatars: avatars}
+}
+
+// GetProfile returns the public part of a user.
+func (s *profileService) GetProfile(userID uint) (*model.PublicProfile, error) {
+	user, err := s.get(userID)
+	if err != nil {
+		return nil, err
+	}
+	return publicProfile(user), nil
+}
+
+// UpdateProfile applies the fields present in req to the user's profile.
+func (s *profileService) UpdateProfile(userID uint, req model.UpdateProfileRequest) (*domain.User, error) {
+	user, err := s.get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Username != nil {
+		username := strings.TrimSpace(*req.Username)
+		if !usernamePattern.MatchString(username) {
+			return nil, domain.ErrInvalidUsername
+		}
+		if username != user.Username {
+			if existing, err := s.repo.GetByUsername(username); err == nil && existing != nil && existing.ID != user.ID {
+				return nil, domain.ErrUsernameTaken
+			}
+			user.Username = username
+		}
+	}
+	if req.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.Bio != nil {
+		user.Bio = strings.TrimSpace(*req.Bio)
+	}
+	if req.Links != nil {
+		links := make([]model.SocialLink, 0, len(*req.Links))
+		for _, l := range *req.Links {
+			u, err := url.Parse(strings.TrimSpace(l.URL))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, domain.ErrInvalidLink
+			}
+			links = append(links, model.SocialLink{Label: strings.TrimSpace(l.Label), URL: u.String()})
+		}
+		user.Links = links
+	}
+	if req.Avatar != "" {
+		if err := validateAvatar(req.Avatar); err != nil {
+			return nil, err
+		}
+		if s.avatars == nil {
+			return nil, fmt.Errorf("avatar uploads are not configured")
+		}
+		path, err := s.avatars.UploadAvatar(user.ID, req.Avatar)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload avatar: %w", err)
+		}
+		user.AvatarURL = path
+	}
+	if err := s.repo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	return user, nil
+}
+
+// ImportAvatar copies the provider picture for a user who has no avatar yet.
+func (s *profileService) ImportAvatar(userID uint, pictureURL string) error {
+	if s.avatars == nil || pictureURL == "" {
+		return nil
+	}
+	user, err := s.get(userID)
+	if err != nil {
+		return err
+	}
+	if user.AvatarURL != "" {
+		return nil
+	}
+	path, err := s.avatars.ImportAvatar(user.ID, pictureURL)
+	if err != nil {
+		return fmt.Errorf("failed to import avatar: %w", err)
+	}
+	user.AvatarURL = path
+	if err := s.repo.Update(user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+func (s *profileService) get(userID uint) (*domain.User, error) {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// validateAvatar checks that dataURL holds an image of an accepted type and size.
+func validateAvatar(dataURL string) error {
+	loc := avatarDataURL.FindStringIndex(dataURL)
+	if loc == nil {
+		return domain.ErrInvalidAvatar
+	}
+	data := dataURL[loc[1]:]
+	if base64.StdEncoding.DecodedLen(len(data)) > maxAvatarBytes {
+		return fmt.Errorf("%w: larger than %d bytes", domain.ErrInvalidAvatar, maxAvatarBytes)
+	}
+	if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+		return domain.ErrInvalidAvatar
+	}
+	return nil
+}
+
+func publicProfile(u *domain.User) *model.PublicProfile {
+	links := u.Links
+	if links == nil {
+		links = []model.SocialLink{}
+	}
+	return &model.PublicProfile{
+		ID:          u.ID,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		AvatarURL:   u.AvatarURL,
+		Links:       links,
+	}
+}
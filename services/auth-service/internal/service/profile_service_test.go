@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+type stubAvatarStore struct {
+	uploaded string
+	imported string
+	err      error
+}
+
+func (s *stubAvatarStore) UploadAvatar(userID uint, dataURL string) (string, error) {
+	s.uploaded = dataURL
+	return "1/avatar/new.png", s.err
+}
+func (s *stubAvatarStore) ImportAvatar(userID uint, pictureURL string) (string, error) {
+	s.imported = pictureURL
+	return "1/avatar/imported.png", s.err
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestUpdateProfile_AppliesSentFields(t *testing.T) {
+	var saved *domain.User
+	repo := &stubUserRepo{
+		getByIDFn: func(id uint) (*domain.User, error) {
+			return &domain.User{ID: id, Username: "old", DisplayName: "Old", Bio: "keep"}, nil
+		},
+		updateFn: func(user *domain.User) error {
+			saved = user
+			return nil
+		},
+	}
+	avatars := &stubAvatarStore{}
+	svc := NewProfileService(repo, avatars)
+
+	links := []model.SocialLink{{Label: " GitHub ", URL: "https://github.com/me"}}
+	user, err := svc.UpdateProfile(1, model.UpdateProfileRequest{
+		Username:    strPtr("new_name"),
+		DisplayName: strPtr(" New "),
+		Links:       &links,
+		Avatar:      "data:image/png;base64,iVBORw0KGgo=",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if saved != user || user.Username != "new_name" || user.DisplayName != "New" || user.Bio != "keep" {
+		t.Fatalf("unexpected user %+v", user)
+	}
+	if len(user.Links) != 1 || user.Links[0].Label != "GitHub" || user.AvatarURL != "1/avatar/new.png" || avatars.uploaded == "" {
+		t.Fatalf("expected links and avatar to be updated, got %+v", user)
+	}
+}
+
+func TestUpdateProfile_Validation(t *testing.T) {
+	repo := &stubUserRepo{
+		getByIDFn: func(id uint) (*domain.User, error) { return &domain.User{ID: id, Username: "me"}, nil },
+		getByUsernameFn: func(username string) (*domain.User, error) {
+			if username == "taken" {
+				return &domain.User{ID: 2, Username: username}, nil
+			}
+			return nil, domain.ErrUserNotFound
+		},
+		updateFn: func(user *domain.User) error {
+			t.Fatalf("did not expect an update")
+			return nil
+		},
+	}
+	svc := NewProfileService(repo, &stubAvatarStore{})
+	badLinks := []model.SocialLink{{Label: "x", URL: "javascript:alert(1)"}}
+	cases := []struct {
+		name string
+		req  model.UpdateProfileRequest
+		want error
+	}{
+		{"username with spaces", model.UpdateProfileRequest{Username: strPtr("my name")}, domain.ErrInvalidUsername},
+		{"username taken", model.UpdateProfileRequest{Username: strPtr("taken")}, domain.ErrUsernameTaken},
+		{"script link", model.UpdateProfileRequest{Links: &badLinks}, domain.ErrInvalidLink},
+		{"svg avatar", model.UpdateProfileRequest{Avatar: "data:image/svg+xml;base64,PHN2Zz4="}, domain.ErrInvalidAvatar},
+		{"broken avatar", model.UpdateProfileRequest{Avatar: "data:image/png;base64,***"}, domain.ErrInvalidAvatar},
+	}
+	for _, tc := range cases {
+		if _, err := svc.UpdateProfile(1, tc.req); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	svc := NewProfileService(&stubUserRepo{
+		getByIDFn: func(id uint) (*domain.User, error) {
+			if id != 1 {
+				return nil, domain.ErrUserNotFound
+			}
+			return &domain.User{ID: 1, Username: "me", Email: "me@example.com", Bio: "hi"}, nil
+		},
+	}, nil)
+	p, err := svc.GetProfile(1)
+	if err != nil || p.Username != "me" || p.Bio != "hi" || p.Links == nil {
+		t.Fatalf("unexpected profile %+v err=%v", p, err)
+	}
+	if _, err := svc.GetProfile(2); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestImportAvatar_OnlyWhenMissing(t *testing.T) {
+	avatar := ""
+	var updates int
+	repo := &stubUserRepo{
+		getByIDFn: func(id uint) (*domain.User, error) { return &domain.User{ID: id, AvatarURL: avatar}, nil },
+		updateFn: func(user *domain.User) error {
+			updates++
+			avatar = user.AvatarURL
+			return nil
+		},
+	}
+	avatars := &stubAvatarStore{}
+	svc := NewProfileService(repo, avatars)
+
+	if err := svc.ImportAvatar(1, "https://provider.example/me.jpg"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if avatar != "1/avatar/imported.png" || avatars.imported != "https://provider.example/me.jpg" {
+		t.Fatalf("expected picture to be imported, got %q", avatar)
+	}
+	if err := svc.ImportAvatar(1, "https://provider.example/other.jpg"); err != nil || updates != 1 {
+		t.Fatalf("expected existing avatar to be kept, got updates=%d err=%v", updates, err)
+	}
+	if err := NewProfileService(repo, nil).ImportAvatar(1, "https://provider.example/me.jpg"); err != nil {
+		t.Fatalf("expected no-op without an avatar store, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/pkg/pat"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// ErrInvalidToken is returned when a personal access token is unknown, revoked or expired.
+var ErrInvalidToken = errors.New("invalid personal access token")
+
+// tokenService implements domain.TokenService.
+type tokenService struct {
+	tokens domain.TokenRepository
+	users  domain.UserRepository
+	now    func() time.Time
+}
+
+var generateToken = pat.Generate
+
+// NewTokenService creates a new TokenService.
+func NewTokenService(tokens domain.TokenRepository, users domain.UserRepository) domain.TokenService {
+	return &tokenService{tokens: tokens, users: users, now: time.Now}
+}
+
+// CreateToken issues a new personal access token. The plaintext token is only returned here.
+func (s *tokenService) CreateToken(userID uint, req model.CreateTokenRequest) (*model.CreateTokenResponse, error) {
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := &domain.PersonalAccessToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		TokenHash: pat.Hash(plain),
+		Prefix:    plain[:len(pat.Prefix)+6],
+		Scopes:    scopes,
+		DPoPJKT:   req.DPoPJKT,
+	}
+	if req.ExpiresInDays > 0 {
+		exp := s.now().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+		token.ExpiresAt = &exp
+	}
+	if err := s.tokens.Create(token); err != nil {
+		return nil, err
+	}
+	return &model.CreateTokenResponse{Token: plain, Info: *token}, nil
+}
+
+// ListTokens returns the user's tokens without their secrets.
+func (s *tokenService) ListTokens(userID uint) ([]*domain.PersonalAccessToken, error) {
+	return s.tokens.ListByUser(userID)
+}
+
+// RevokeToken revokes one of the user's tokens.
+func (s *tokenService) RevokeToken(userID, tokenID uint) error {
+	return s.tokens.Revoke(tokenID, userID, s.now())
+}
+
+// ValidateToken resolves a plaintext token to its owner and records the usage.
+func (s *tokenService) ValidateToken(token string) (*model.ValidateTokenResponse, error) {
+	if !pat.IsPersonalAccessToken(token) {
+		return nil, ErrInvalidToken
+	}
+	stored, err := s.tokens.GetByHash(pat.Hash(token))
+	if err != nil {
+		if errors.Is(err, domain.ErrTokenNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	now := s.now()
+	if stored.RevokedAt != nil || (stored.ExpiresAt != nil && now.After(*stored.ExpiresAt)) {
+		return nil, ErrInvalidToken
+	}
+	user, err := s.users.GetByID(stored.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := s.tokens.RecordUsage(stored.ID, now); err != nil {
+		// usage statistics are best-effort; do not fail authentication over them
+		log.Printf("failed to record token usage: %v", err)
+	}
+	return &model.ValidateTokenResponse{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		Scopes:    stored.Scopes,
+		TokenID:   stored.ID,
+		ExpiresAt: stored.ExpiresAt,
+		CreatedAt: stored.CreatedAt,
+		DPoPJKT:   stored.DPoPJKT,
+	}, nil
+}
+
+// normalizeScopes validates requested scopes and returns them space-separated without duplicates.
+func normalizeScopes(requested []string) (string, error) {
+	seen := map[string]bool{}
+	var out []string
+	for _, sc := range requested {
+		sc = strings.TrimSpace(sc)
+		if sc == "" || seen[sc] {
+			continue
+		}
+		known := false
+		for _, k := range pat.KnownScopes {
+			if k == sc {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return "", fmt.Errorf("unknown scope: %s", sc)
+		}
+		seen[sc] = true
+		out = append(out, sc)
+	}
+	if len(out) == 0 {
+		return "", fmt.Errorf("at least one scope is required")
+	}
+	return strings.Join(out, " "), nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/pkg/pat"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+type stubTokenRepo struct {
+	created   *domain.PersonalAccessToken
+	byHash    map[string]*domain.PersonalAccessToken
+	revokeErr error
+	usage     []uint
+}
+
+func (s *stubTokenRepo) Create(token *domain.PersonalAccessToken) error {
+	token.ID = 1
+	s.created = token
+	return nil
+}
+func (s *stubTokenRepo) GetByHash(hash string) (*domain.PersonalAccessToken, error) {
+	if t, ok := s.byHash[hash]; ok {
+		return t, nil
+	}
+	return nil, domain.ErrTokenNotFound
+}
+func (s *stubTokenRepo) ListByUser(userID uint) ([]*domain.PersonalAccessToken, error) {
+	return nil, nil
+}
+func (s *stubTokenRepo) Revoke(id, userID uint, at time.Time) error { return s.revokeErr }
+func (s *stubTokenRepo) RecordUsage(id uint, at time.Time) error {
+	s.usage = append(s.usage, id)
+	return nil
+}
+
+func newTokenServiceForTest(tokens domain.TokenRepository) *tokenService {
+	users := &stubUserRepo{
+		getByIDFn: func(id uint) (*domain.User, error) {
+			return &domain.User{ID: id, Username: "owner", Role: "owner"}, nil
+		},
+	}
+	return NewTokenService(tokens, users).(*tokenService)
+}
+
+func TestCreateToken_StoresHashOnly(t *testing.T) {
+	repo := &stubTokenRepo{}
+	svc := newTokenServiceForTest(repo)
+
+	jkt := "0ZcOCORZNYy-DWpqq30jZyJGHTN0d2HglBV3uiguA4I"
+	resp, err := svc.CreateToken(7, model.CreateTokenRequest{Name: " ci ", Scopes: []string{"posts:write", "posts:write"}, ExpiresInDays: 30, DPoPJKT: jkt})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(resp.Token, pat.Prefix) {
+		t.Fatalf("expected token with prefix, got %q", resp.Token)
+	}
+	if repo.created.TokenHash != pat.Hash(resp.Token) || repo.created.TokenHash == resp.Token {
+		t.Fatalf("expected only the hash to be stored")
+	}
+	if repo.created.Name != "ci" || repo.created.Scopes != "posts:write" || repo.created.ExpiresAt == nil || repo.created.DPoPJKT != jkt {
+		t.Fatalf("unexpected stored token: %+v", repo.created)
+	}
+}
+
+func TestCreateToken_UnknownScope(t *testing.T) {
+	svc := newTokenServiceForTest(&stubTokenRepo{})
+	_, err := svc.CreateToken(7, model.CreateTokenRequest{Name: "ci", Scopes: []string{"admin"}})
+	if err == nil || !strings.Contains(err.Error(), "unknown scope") {
+		t.Fatalf("expected unknown scope error, got %v", err)
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	repo := &stubTokenRepo{byHash: map[string]*domain.PersonalAccessToken{
+		pat.Hash("pws_valid"):   {ID: 1, UserID: 7, Scopes: "posts:write"},
+		pat.Hash("pws_revoked"): {ID: 2, UserID: 7, RevokedAt: &past},
+		pat.Hash("pws_expired"): {ID: 3, UserID: 7, ExpiresAt: &past},
+	}}
+	svc := newTokenServiceForTest(repo)
+
+	resp, err := svc.ValidateToken("pws_valid")
+	if err != nil || resp.UserID != 7 || resp.Username != "owner" || resp.Role != "owner" || resp.Scopes != "posts:write" {
+		t.Fatalf("expected valid token, got %+v err=%v", resp, err)
+	}
+	if len(repo.usage) != 1 || repo.usage[0] != 1 {
+		t.Fatalf("expected usage to be recorded, got %v", repo.usage)
+	}
+
+	for _, tok := range []string{"pws_revoked", "pws_expired", "pws_unknown", "not-a-pat"} {
+		if _, err := svc.ValidateToken(tok); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("%s: expected ErrInvalidToken, got %v", tok, err)
+		}
+	}
+	if len(repo.usage) != 1 {
+		t.Fatalf("expected no usage recorded for rejected tokens, got %v", repo.usage)
+	}
+}
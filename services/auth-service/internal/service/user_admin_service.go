@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"seungpyo.lee/PersonalWebSite/pkg/role"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+// userAdminService implements domain.UserAdminService.
+type userAdminService struct {
+	repo domain.UserRepository
+}
+
+// NewUserAdminService creates a new UserAdminService.
+func NewUserAdminService(repo domain.UserRepository) domain.UserAdminService {
+	return &userAdminService{repo: repo}
+}
+
+// RequireOwner checks the stored role, so a demotion takes effect before the
+// caller's access token expires.
+func (s *userAdminService) RequireOwner(userID uint) error {
+	user, err := s.repo.GetByID(userID)
+	if err != nil || user.Role != role.Owner {
+		return domain.ErrNotOwner
+	}
+	return nil
+}
+
+// ListUsers returns every account.
+func (s *userAdminService) ListUsers() ([]*domain.User, error) {
+	return s.repo.List()
+}
+
+// CreateUser pre-registers an account by email. It is linked to the first login
+// whose provider reports the same verified email.
+func (s *userAdminService) CreateUser(req model.CreateUserRequest) (*domain.User, error) {
+	if !role.Valid(req.Role) {
+		return nil, domain.ErrInvalidRole
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if existing, err := s.repo.GetByEmail(email); err == nil && existing != nil {
+		return nil, domain.ErrUserExists
+	}
+	username := strings.TrimSpace(req.Username)
+	if username == "" {
+		username = strings.Split(email, "@")[0]
+	}
+	user := &domain.User{Username: username, Email: email, Role: req.Role}
+	if err := s.repo.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateRole changes a user's role. The last owner cannot be demoted.
+func (s *userAdminService) UpdateRole(userID uint, r string) (*domain.User, *domain.User, error) {
+	if !role.Valid(r) {
+		return nil, nil, domain.ErrInvalidRole
+	}
+	user, err := s.get(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	before := *user
+	if user.Role == r {
+		return &before, user, nil
+	}
+	if user.Role == role.Owner {
+		if err := s.ensureAnotherOwner(); err != nil {
+			return nil, nil, err
+		}
+	}
+	user.Role = r
+	if err := s.repo.Update(user); err != nil {
+		return nil, nil, err
+	}
+	return &before, user, nil
+}
+
+// DeleteUser removes an account. The last owner cannot be deleted.
+func (s *userAdminService) DeleteUser(userID uint) (*domain.User, error) {
+	user, err := s.get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role.Owner {
+		if err := s.ensureAnotherOwner(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.Delete(userID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *userAdminService) get(userID uint) (*domain.User, error) {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// ensureAnotherOwner fails with ErrLastOwner unless more than one owner exists.
+func (s *userAdminService) ensureAnotherOwner() error {
+	owners, err := s.repo.CountByRole(role.Owner)
+	if err != nil {
+		return fmt.Errorf("failed to count owners: %w", err)
+	}
+	if owners <= 1 {
+		return domain.ErrLastOwner
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+func TestRequireOwner(t *testing.T) {
+	svc := NewUserAdminService(&stubUserRepo{
+		getByIDFn: func(id uint) (*domain.User, error) {
+			if id == 1 {
+				return &domain.User{ID: 1, Role: "owner"}, nil
+			}
+			if id == 2 {
+				return &domain.User{ID: 2, Role: "editor"}, nil
+			}
+			return nil, domain.ErrUserNotFound
+		},
+	})
+	if err := svc.RequireOwner(1); err != nil {
+		t.Fatalf("expected owner to pass, got %v", err)
+	}
+	for _, id := range []uint{2, 3} {
+		if err := svc.RequireOwner(id); !errors.Is(err, domain.ErrNotOwner) {
+			t.Fatalf("user %d: expected ErrNotOwner, got %v", id, err)
+		}
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	var created *domain.User
+	svc := NewUserAdminService(&stubUserRepo{
+		getByEmailFn: func(email string) (*domain.User, error) {
+			if email == "taken@example.com" {
+				return &domain.User{ID: 4}, nil
+			}
+			return nil, domain.ErrUserNotFound
+		},
+		createFn: func(user *domain.User) error {
+			created = user
+			return nil
+		},
+	})
+	if _, err := svc.CreateUser(model.CreateUserRequest{Email: "a@example.com", Role: "admin"}); !errors.Is(err, domain.ErrInvalidRole) {
+		t.Fatalf("expected ErrInvalidRole, got %v", err)
+	}
+	if _, err := svc.CreateUser(model.CreateUserRequest{Email: "Taken@example.com", Role: "editor"}); !errors.Is(err, domain.ErrUserExists) {
+		t.Fatalf("expected ErrUserExists, got %v", err)
+	}
+	user, err := svc.CreateUser(model.CreateUserRequest{Email: " Co@Example.com ", Role: "editor"})
+	if err != nil || user != created || created.Email != "co@example.com" || created.Username != "co" || created.Role != "editor" {
+		t.Fatalf("unexpected created user %+v err=%v", created, err)
+	}
+}
+
+func TestUpdateRole_ProtectsLastOwner(t *testing.T) {
+	owners := int64(1)
+	var updated *domain.User
+	svc := NewUserAdminService(&stubUserRepo{
+		getByIDFn: func(id uint) (*domain.User, error) {
+			return &domain.User{ID: id, Role: "owner"}, nil
+		},
+		countByRoleFn: func(role string) (int64, error) { return owners, nil },
+		updateFn: func(user *domain.User) error {
+			updated = user
+			return nil
+		},
+	})
+	if _, _, err := svc.UpdateRole(1, "editor"); !errors.Is(err, domain.ErrLastOwner) || updated != nil {
+		t.Fatalf("expected ErrLastOwner, got %v", err)
+	}
+	owners = 2
+	before, after, err := svc.UpdateRole(1, "editor")
+	if err != nil || before.Role != "owner" || after.Role != "editor" || updated == nil {
+		t.Fatalf("expected demotion, got before=%+v after=%+v err=%v", before, after, err)
+	}
+	if _, _, err := svc.UpdateRole(1, "root"); !errors.Is(err, domain.ErrInvalidRole) {
+		t.Fatalf("expected ErrInvalidRole, got %v", err)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	svc := NewUserAdminService(&stubUserRepo{
+		getByIDFn: func(id uint) (*domain.User, error) {
+			switch id {
+			case 1:
+				return &domain.User{ID: 1, Role: "owner"}, nil
+			case 2:
+				return &domain.User{ID: 2, Role: "reader"}, nil
+			}
+			return nil, domain.ErrUserNotFound
+		},
+		countByRoleFn: func(role string) (int64, error) { return 1, nil },
+	})
+	if _, err := svc.DeleteUser(1); !errors.Is(err, domain.ErrLastOwner) {
+		t.Fatalf("expected ErrLastOwner, got %v", err)
+	}
+	if _, err := svc.DeleteUser(9); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+	if user, err := svc.DeleteUser(2); err != nil || user.ID != 2 {
+		t.Fatalf("expected delete, got %+v err=%v", user, err)
+	}
+}
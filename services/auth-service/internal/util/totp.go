@@ -0,0 +1,63 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238 defaults, which every authenticator app supports).
+const (
+	TOTPPeriod = 30
+	TOTPDigits = 6
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret returns a random 160-bit secret in unpadded base32.
+func NewTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPStep returns the time step t falls into.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / TOTPPeriod
+}
+
+// TOTPCode computes the code of a base32 secret for a time step (HOTP with HMAC-SHA1).
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret: %w", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", TOTPDigits, value%1000000), nil
+}
+
+// TOTPURI returns the otpauth:// provisioning URI that authenticator apps scan as a QR code.
+func TOTPURI(issuer, account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprintf("%d", TOTPDigits))
+	q.Set("period", fmt.Sprintf("%d", TOTPPeriod))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
@@ -0,0 +1,41 @@
+package util
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTOTPCode checks the SHA-1 test vectors of RFC 6238, appendix B, truncated to six digits.
+func TestTOTPCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range cases {
+		got, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil || got != want {
+			t.Fatalf("time %d: expected %s, got %s err=%v", unix, want, got, err)
+		}
+	}
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Fatalf("expected invalid secret to be rejected")
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil || len(secret) != 32 {
+		t.Fatalf("expected a 32 character secret, got %q err=%v", secret, err)
+	}
+	uri := TOTPURI("My Blog", "me@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/My%20Blog:me@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Fatalf("unexpected provisioning uri %q", uri)
+	}
+}
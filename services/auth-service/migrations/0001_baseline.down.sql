@@ -0,0 +1,5 @@
+-- audit_logs is shared with the other services and keeps its history.
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS totp_credentials;
+DROP TABLE IF EXISTS personal_access_tokens;
+DROP TABLE IF EXISTS users;
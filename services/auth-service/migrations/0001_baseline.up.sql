@@ -0,0 +1,84 @@
+-- Baseline: the schema GORM AutoMigrate created before versioned migrations, and the
+-- columns and tables added since. Every statement is IF NOT EXISTS, so databases
+-- created by AutoMigrate keep their data and gain what they lack.
+
+-- users as AutoMigrate created it; an existing table is left as it is.
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    username text,
+    email text,
+    provider text,
+    provider_id text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+-- roles, profiles and password login came after AutoMigrate; existing users become
+-- readers, and the emails in OWNER_EMAILS become owners again on their next login.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'reader';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bio text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS links text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash text;
+
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    name text NOT NULL,
+    token_hash text NOT NULL,
+    prefix text,
+    scopes text,
+    dpop_jkt text,
+    expires_at timestamptz,
+    last_used_at timestamptz,
+    usage_count bigint NOT NULL DEFAULT 0,
+    revoked_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user_id ON personal_access_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_personal_access_tokens_token_hash ON personal_access_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS totp_credentials (
+    user_id bigint PRIMARY KEY,
+    secret text NOT NULL,
+    enabled boolean NOT NULL DEFAULT false,
+    last_step bigint NOT NULL DEFAULT 0,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    code_hash text NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+-- audit_logs is shared with the other services; each baseline creates it if missing.
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    service text NOT NULL,
+    actor_id bigint,
+    actor_name text,
+    action text NOT NULL,
+    target_type text,
+    target_id text,
+    request_id text,
+    ip text,
+    before text,
+    after text,
+    outcome text NOT NULL,
+    error text
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target_type ON audit_logs (target_type);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target_id ON audit_logs (target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_outcome ON audit_logs (outcome);
+-- the audit log is append-only
+CREATE OR REPLACE RULE audit_logs_no_update AS ON UPDATE TO audit_logs DO INSTEAD NOTHING;
+CREATE OR REPLACE RULE audit_logs_no_delete AS ON DELETE TO audit_logs DO INSTEAD NOTHING;
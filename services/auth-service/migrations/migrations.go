@@ -0,0 +1,10 @@
+// Package migrations holds auth-service's versioned SQL migrations, applied with
+// pkg/migrate.
+package migrations
+
+import "embed"
+
+// FS contains the <version>_<name>.up.sql and .down.sql files.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,173 @@
+package migrations
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm/schema"
+	"seungpyo.lee/PersonalWebSite/pkg/migrate"
+	"seungpyo.lee/PersonalWebSite/services/auth-service/internal/model"
+)
+
+func TestMigrationsLoad(t *testing.T) {
+	ms, err := migrate.Load(FS)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for i, m := range ms {
+		if m.Version != int64(i+1) {
+			t.Fatalf("expected contiguous versions, got %d at position %d", m.Version, i)
+		}
+	}
+	if len(ms) == 0 || !strings.Contains(ms[0].Up, "CREATE TABLE IF NOT EXISTS users") {
+		t.Fatalf("expected the baseline to create users")
+	}
+}
+
+func TestUpAppliesPendingMigrationsUnderLock(t *testing.T) {
+	ms, err := migrate.Load(FS)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("to_regclass('schema_migrations')")).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM schema_migrations WHERE service = $1")).WithArgs("auth-service").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"}))
+	for _, m := range ms {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(m.Up[:40])).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations")).
+			WithArgs("auth-service", m.Version, m.Name, m.Checksum).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	count, err := migrate.New(db, "auth-service", ms).Up(context.Background())
+	if err != nil || count != len(ms) {
+		t.Fatalf("expected %d migrations, got %d err=%v", len(ms), count, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestVerifyRejectsModifiedMigration(t *testing.T) {
+	ms, err := migrate.Load(FS)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+	rows := sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"})
+	for _, m := range ms {
+		rows.AddRow(m.Version, m.Name, m.Checksum, time.Now())
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("to_regclass")).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM schema_migrations")).WillReturnRows(rows)
+	if err := migrate.New(db, "auth-service", ms).Verify(context.Background()); err != nil {
+		t.Fatalf("expected current schema, got %v", err)
+	}
+
+	ms[0].Checksum = "changed"
+	mock.ExpectQuery(regexp.QuoteMeta("to_regclass")).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM schema_migrations")).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"}).AddRow(ms[0].Version, ms[0].Name, "original", time.Now()))
+	if err := migrate.New(db, "auth-service", ms).Verify(context.Background()); err == nil || !strings.Contains(err.Error(), "changed after it was applied") {
+		t.Fatalf("expected modified migration to be reported, got %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("to_regclass")).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	if err := migrate.New(db, "auth-service", ms).Verify(context.Background()); err == nil || !strings.Contains(err.Error(), "pending") {
+		t.Fatalf("expected pending migrations on an empty database, got %v", err)
+	}
+}
+
+var (
+	createTableStmt = regexp.MustCompile(`(?s)^CREATE TABLE IF NOT EXISTS (\w+) \((.*)\)$`)
+	addColumnStmt   = regexp.MustCompile(`^ALTER TABLE (\w+) ADD COLUMN IF NOT EXISTS (\w+) `)
+)
+
+// applyColumns plays the table and column statements of the up migrations on tables,
+// a table name to column names map, the way Postgres would: CREATE TABLE IF NOT EXISTS
+// leaves an existing table as it is.
+func applyColumns(t *testing.T, ms []migrate.Migration, tables map[string][]string) {
+	t.Helper()
+	for _, m := range ms {
+		var lines []string
+		for _, line := range strings.Split(m.Up, "\n") {
+			if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+				lines = append(lines, line)
+			}
+		}
+		for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+			stmt = strings.TrimSpace(stmt)
+			if match := createTableStmt.FindStringSubmatch(stmt); match != nil {
+				if _, ok := tables[match[1]]; ok {
+					continue
+				}
+				var columns []string
+				for _, def := range strings.Split(match[2], ",\n") {
+					name := strings.Fields(def)[0]
+					if name != "PRIMARY" && name != "CONSTRAINT" {
+						columns = append(columns, name)
+					}
+				}
+				tables[match[1]] = columns
+			} else if match := addColumnStmt.FindStringSubmatch(stmt); match != nil {
+				if !slices.Contains(tables[match[1]], match[2]) {
+					tables[match[1]] = append(tables[match[1]], match[2])
+				}
+			}
+		}
+	}
+}
+
+// TestBaselineUpgradesAutoMigrateSchema starts from the users table AutoMigrate created
+// before versioned migrations and checks the migrations give it every column the
+// model needs, as they do for a new database.
+func TestBaselineUpgradesAutoMigrateSchema(t *testing.T) {
+	ms, err := migrate.Load(FS)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	userSchema, err := schema.Parse(&model.User{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("parse user model: %v", err)
+	}
+
+	upgraded := map[string][]string{"users": {"id", "username", "email", "provider", "provider_id", "created_at", "updated_at"}}
+	applyColumns(t, ms, upgraded)
+	fresh := map[string][]string{}
+	applyColumns(t, ms, fresh)
+
+	for _, field := range userSchema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		if !slices.Contains(upgraded["users"], field.DBName) {
+			t.Fatalf("an AutoMigrate database gets no users.%s column, got %v", field.DBName, upgraded["users"])
+		}
+		if !slices.Contains(fresh["users"], field.DBName) {
+			t.Fatalf("a new database gets no users.%s column, got %v", field.DBName, fresh["users"])
+		}
+	}
+	if !strings.Contains(ms[0].Up, "ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'reader'") {
+		t.Fatalf("expected existing users to get the reader role")
+	}
+}
@@ -18,6 +18,7 @@ import (
 
 type blogImageHandler interface {
 	UploadBlogImageHandler(c *gin.Context)
+	UploadAvatarHandler(c *gin.Context)
 	DeleteBlogImageHandler(c *gin.Context)
 }
 
@@ -34,6 +35,7 @@ func handleError(err error) {
 func registerRoutes(r *gin.Engine, h blogImageHandler) {
 	r.POST("/blog-image", h.UploadBlogImageHandler)
 	r.DELETE("/blog-image", h.DeleteBlogImageHandler)
+	r.POST("/avatar-image", h.UploadAvatarHandler)
 }
 
 func ensureContainerExists(client blobContainerClient, containerName string) error {
@@ -62,6 +64,9 @@ func main() {
 	imageHandler := handler.NewBlogImageHandler(imageService)
 
 	r := gin.Default()
+	if err := r.SetTrustedProxies(conf.TrustedProxies); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
 	registerRoutes(r, imageHandler)
 
 	if err := r.Run(":" + conf.ServerPort); err != nil {
@@ -18,6 +18,7 @@ import (
 type fakeHandler struct{}
 
 func (f *fakeHandler) UploadBlogImageHandler(c *gin.Context) { c.Status(http.StatusOK) }
+func (f *fakeHandler) UploadAvatarHandler(c *gin.Context)    { c.Status(http.StatusOK) }
 func (f *fakeHandler) DeleteBlogImageHandler(c *gin.Context) { c.Status(http.StatusOK) }
 
 type fakeContainerClient struct {
@@ -64,6 +65,13 @@ func TestRegisterRoutes(t *testing.T) {
 	if deleteW.Code != http.StatusOK {
 		t.Fatalf("expected DELETE /blog-image route, got %d", deleteW.Code)
 	}
+
+	avatarReq := httptest.NewRequest(http.MethodPost, "/avatar-image", nil)
+	avatarW := httptest.NewRecorder()
+	r.ServeHTTP(avatarW, avatarReq)
+	if avatarW.Code != http.StatusOK {
+		t.Fatalf("expected POST /avatar-image route, got %d", avatarW.Code)
+	}
 }
 
 func TestEnsureContainerExists_Success(t *testing.T) {
@@ -12,6 +12,7 @@ import (
 
 type BlogImageService interface {
 	UploadBlogImage(ctx context.Context, filename string, data string, userId int) (*model.ImageResponse, error)
+	UploadAvatar(ctx context.Context, filename string, data string, userId int) (*model.ImageResponse, error)
 	DeleteBlogImage(ctx context.Context, filePath string) error
 }
 
@@ -24,6 +25,15 @@ func NewBlogImageHandler(service *service.ImgService) *imageHandler {
 }
 
 func (h *imageHandler) UploadBlogImageHandler(c *gin.Context) {
+	h.upload(c, h.service.UploadBlogImage)
+}
+
+// UploadAvatarHandler stores a user's profile picture.
+func (h *imageHandler) UploadAvatarHandler(c *gin.Context) {
+	h.upload(c, h.service.UploadAvatar)
+}
+
+func (h *imageHandler) upload(c *gin.Context, upload func(ctx context.Context, filename string, data string, userId int) (*model.ImageResponse, error)) {
 	var req model.UploadImageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
@@ -34,13 +44,12 @@ func (h *imageHandler) UploadBlogImageHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
-	img, err := h.service.UploadBlogImage(c.Request.Context(), req.Filename, req.Data, userId_I)
+	img, err := upload(c.Request.Context(), req.Filename, req.Data, userId_I)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, img)
-
 }
 
 func (h *imageHandler) DeleteBlogImageHandler(c *gin.Context) {
@@ -14,12 +14,16 @@ import (
 
 type stubBlogImageService struct {
 	uploadFn func(ctx context.Context, filename string, data string, userId int) (*model.ImageResponse, error)
+	avatarFn func(ctx context.Context, filename string, data string, userId int) (*model.ImageResponse, error)
 	deleteFn func(ctx context.Context, filePath string) error
 }
 
 func (s *stubBlogImageService) UploadBlogImage(ctx context.Context, filename string, data string, userId int) (*model.ImageResponse, error) {
 	return s.uploadFn(ctx, filename, data, userId)
 }
+func (s *stubBlogImageService) UploadAvatar(ctx context.Context, filename string, data string, userId int) (*model.ImageResponse, error) {
+	return s.avatarFn(ctx, filename, data, userId)
+}
 func (s *stubBlogImageService) DeleteBlogImage(ctx context.Context, filePath string) error {
 	return s.deleteFn(ctx, filePath)
 }
@@ -96,6 +100,28 @@ func TestUploadBlogImageHandler_Success(t *testing.T) {
 	}
 }
 
+func TestUploadAvatarHandler_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var gotUser int
+	h := &imageHandler{service: &stubBlogImageService{
+		avatarFn: func(ctx context.Context, filename string, data string, userId int) (*model.ImageResponse, error) {
+			gotUser = userId
+			return &model.ImageResponse{URL: "1/avatar/x.png", Name: "avatar.png", Size: 12}, nil
+		},
+	}}
+	r := gin.New()
+	r.POST("/avatar-image", h.UploadAvatarHandler)
+
+	req := httptest.NewRequest(http.MethodPost, "/avatar-image", strings.NewReader(`{"filename":"avatar.png","userId":"1","data":"dGVzdA=="}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || gotUser != 1 || !strings.Contains(w.Body.String(), "1/avatar/x.png") {
+		t.Fatalf("expected avatar upload, got %d %s", w.Code, w.Body.String())
+	}
+}
+
 func TestDeleteBlogImageHandler_InvalidJSON(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	h := &imageHandler{service: &stubBlogImageService{}}
@@ -28,6 +28,16 @@ var generateUUID = func() string {
 }
 
 func (s *ImgService) UploadBlogImage(ctx context.Context, filename string, data string, userId int) (*model.ImageResponse, error) {
+	return s.upload(ctx, filename, data, fmt.Sprintf("%d/blog/img/", userId))
+}
+
+// UploadAvatar stores a profile picture under <userId>/avatar/.
+func (s *ImgService) UploadAvatar(ctx context.Context, filename string, data string, userId int) (*model.ImageResponse, error) {
+	return s.upload(ctx, filename, data, fmt.Sprintf("%d/avatar/", userId))
+}
+
+// upload decodes data and stores it under dir with a random name that keeps filename's extension.
+func (s *ImgService) upload(ctx context.Context, filename string, data string, dir string) (*model.ImageResponse, error) {
 	// data: "data:image/png;base64,...."
 	if idx := strings.Index(data, ","); idx != -1 {
 		data = data[idx+1:]
@@ -42,7 +52,7 @@ func (s *ImgService) UploadBlogImage(ctx context.Context, filename string, data
 		ext = filename[dot:]
 	}
 	uuidName := generateUUID() + ext
-	imgPath := fmt.Sprintf("%d/blog/img/%s", userId, uuidName)
+	imgPath := dir + uuidName
 	contentType := "image/png"
 	switch ext {
 	case ".jpeg":
@@ -55,6 +55,29 @@ func TestUploadBlogImage_DataURIAndPath(t *testing.T) {
 	}
 }
 
+func TestUploadAvatar_Path(t *testing.T) {
+	origUUID := generateUUID
+	generateUUID = func() string { return "avatar-uuid" }
+	t.Cleanup(func() { generateUUID = origUUID })
+
+	var gotPath, gotType string
+	svc := NewImgService(&stubRepo{
+		uploadFn: func(ctx context.Context, file []byte, filePath string, contentType string) error {
+			gotPath, gotType = filePath, contentType
+			return nil
+		},
+		deleteFn: func(ctx context.Context, filePath string) error { return nil },
+	})
+
+	resp, err := svc.UploadAvatar(context.Background(), "avatar.webp", base64.StdEncoding.EncodeToString([]byte("hello")), 4)
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if gotPath != "4/avatar/avatar-uuid.webp" || gotType != "image/webp" || resp.URL != gotPath {
+		t.Fatalf("unexpected upload path=%q type=%q resp=%+v", gotPath, gotType, resp)
+	}
+}
+
 func TestUploadBlogImage_RawBase64(t *testing.T) {
 	origUUID := generateUUID
 	generateUUID = func() string { return "raw-uuid" }
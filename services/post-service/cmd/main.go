@@ -1,30 +1,70 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
 	"seungpyo.lee/PersonalWebSite/pkg/logger"
+	"seungpyo.lee/PersonalWebSite/pkg/migrate"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/adapter"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/config"
-	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/handler"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/repository"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/service"
+	"seungpyo.lee/PersonalWebSite/services/post-service/migrations"
 )
 
 type postRoutesHandler interface {
 	GetPosts(c *gin.Context)
+	SearchPosts(c *gin.Context)
 	GetPost(c *gin.Context)
+	GetRelatedPosts(c *gin.Context)
+	GetPostBySlug(c *gin.Context)
+	GetDrafts(c *gin.Context)
+	GetDraft(c *gin.Context)
+	ListRevisions(c *gin.Context)
+	GetRevision(c *gin.Context)
+	DiffRevisions(c *gin.Context)
+	RestoreRevision(c *gin.Context)
 	GetTags(c *gin.Context)
 	CreatePost(c *gin.Context)
 	UpdatePost(c *gin.Context)
 	DeletePost(c *gin.Context)
 }
 
-func registerRoutes(r *gin.Engine, h postRoutesHandler, logger *logger.Logger) {
+type commentRoutesHandler interface {
+	ListComments(c *gin.Context)
+	CreateComment(c *gin.Context)
+	ModerationQueue(c *gin.Context)
+	ModerateComment(c *gin.Context)
+	DeleteComment(c *gin.Context)
+}
+
+type seriesRoutesHandler interface {
+	ListSeries(c *gin.Context)
+	GetSeries(c *gin.Context)
+	CreateSeries(c *gin.Context)
+	UpdateSeries(c *gin.Context)
+	DeleteSeries(c *gin.Context)
+}
+
+type tagRoutesHandler interface {
+	GetTag(c *gin.Context)
+	ListAllTags(c *gin.Context)
+	UpdateTag(c *gin.Context)
+	MergeTag(c *gin.Context)
+	AddAlias(c *gin.Context)
+	DeleteAlias(c *gin.Context)
+}
+
+func registerRoutes(r *gin.Engine, h postRoutesHandler, ch commentRoutesHandler, sh seriesRoutesHandler, th tagRoutesHandler, logger *logger.Logger) {
 	r.GET("/health", func(c *gin.Context) {
 		logger.Info("health check OK")
 		c.JSON(200, gin.H{
@@ -32,11 +72,36 @@ func registerRoutes(r *gin.Engine, h postRoutesHandler, logger *logger.Logger) {
 		})
 	})
 	r.GET("/posts", h.GetPosts)
+	r.GET("/posts/search", h.SearchPosts)
 	r.GET("/posts/:id", h.GetPost)
+	r.GET("/posts/:id/related", h.GetRelatedPosts)
+	r.GET("/posts/by-slug/:slug", h.GetPostBySlug)
+	r.GET("/drafts", h.GetDrafts)
+	r.GET("/drafts/:id", h.GetDraft)
+	r.GET("/posts/:id/revisions", h.ListRevisions)
+	r.GET("/posts/:id/revisions/:rev", h.GetRevision)
+	r.GET("/posts/:id/revisions/:rev/diff", h.DiffRevisions)
+	r.POST("/posts/:id/revisions/:rev/restore", h.RestoreRevision)
 	r.GET("/tags", h.GetTags)
+	r.GET("/tags/:name", th.GetTag)
 	r.POST("/posts", h.CreatePost)
 	r.PUT("/posts/:id", h.UpdatePost)
 	r.DELETE("/posts/:id", h.DeletePost)
+	r.GET("/posts/:id/comments", ch.ListComments)
+	r.POST("/posts/:id/comments", ch.CreateComment)
+	r.GET("/comments", ch.ModerationQueue)
+	r.PUT("/comments/:id/status", ch.ModerateComment)
+	r.DELETE("/comments/:id", ch.DeleteComment)
+	r.GET("/series", sh.ListSeries)
+	r.GET("/series/:id", sh.GetSeries)
+	r.POST("/series", sh.CreateSeries)
+	r.PUT("/series/:id", sh.UpdateSeries)
+	r.DELETE("/series/:id", sh.DeleteSeries)
+	r.GET("/admin/tags", th.ListAllTags)
+	r.PUT("/admin/tags/:id", th.UpdateTag)
+	r.POST("/admin/tags/:id/merge", th.MergeTag)
+	r.POST("/admin/tags/:id/aliases", th.AddAlias)
+	r.DELETE("/admin/tags/:id/aliases/:alias", th.DeleteAlias)
 }
 
 func main() {
@@ -48,24 +113,78 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to connect db: %v", err)
 	}
-	// auto migration
-	if err := db.AutoMigrate(&domain.Post{}, &domain.Tag{}, &domain.User{}); err != nil {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		log.Fatalf("failed to load migrations: %v", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Command(context.Background(), migrator, os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	if err := migrate.OnStartup(context.Background(), migrator, conf.MigrationMode); err != nil {
 		log.Fatalf("failed to migrate db: %v", err)
 	}
+	auditStore := audit.NewStore(db, "post-service")
 
 	postRepo := repository.NewPostRepository(db)
+	// posts from before slugs get one generated from their title
+	if n, err := service.BackfillSlugs(postRepo); err != nil {
+		logger.Error("failed to backfill post slugs: " + err.Error())
+	} else if n > 0 {
+		logger.Info(fmt.Sprintf("generated slugs for %d posts", n))
+	}
 	tagRepo := repository.NewTagRepository(db)
+	seriesRepo := repository.NewSeriesRepository(db)
 
 	imageAdapter := adapter.NewImageAdapter(conf)
 	transAdapter := adapter.NewTranslationAdapter(conf)
 
-	svc := service.NewPostService(postRepo, tagRepo, conf, imageAdapter, transAdapter)
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:       fmt.Sprintf("%s:%s", conf.RedisDBURL, conf.RedisDBPort),
+		Password:   conf.RedisDBPassword,
+		MaxRetries: conf.RedisMaxRetries,
+		PoolSize:   conf.RedisPoolSize,
+	})
+	// related posts are cached in Redis so every replica drops them when a post changes
+	relatedCache := repository.NewRelatedCache(redisClient)
+
+	events := service.NewEventBroker()
+	svc := service.NewPostService(postRepo, tagRepo, seriesRepo, conf, imageAdapter, transAdapter, auditStore, events, relatedCache)
+	// every replica runs the scheduler; a database lock lets one apply each change
+	go service.RunScheduler(context.Background(), svc, conf.ScheduleInterval, logger)
 	h := handler.NewPostHandler(svc)
+	commentSvc := service.NewCommentService(repository.NewCommentRepository(db), postRepo, conf, auditStore, events)
+	ch := handler.NewCommentHandler(commentSvc)
+	sh := handler.NewSeriesHandler(service.NewSeriesService(seriesRepo, postRepo, auditStore))
+	th := handler.NewTagHandler(service.NewTagService(tagRepo, auditStore, relatedCache))
+	eh := handler.NewEventHandler(events)
 
 	r := gin.Default()
-	registerRoutes(r, h, logger)
+	// comment rate limits key on the client IP the gateway forwards
+	if err := r.SetTrustedProxies(conf.TrustedProxies); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(audit.Middleware())
+	registerRoutes(r, h, ch, sh, th, logger)
+	r.GET("/events", eh.Stream)
 
 	if err := r.Run(":" + conf.ServerPort); err != nil {
 		log.Fatalf("failed to run server: %v", err)
 	}
 }
+
+// newMigrator prepares post-service's versioned migrations for db. The users table
+// the posts refer to belongs to auth-service.
+func newMigrator(db *gorm.DB) (*migrate.Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	ms, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sqlDB, "post-service", ms), nil
+}
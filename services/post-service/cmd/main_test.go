@@ -11,17 +11,51 @@ import (
 
 type fakePostHandler struct{}
 
-func (f *fakePostHandler) GetPosts(c *gin.Context)   { c.Status(http.StatusOK) }
-func (f *fakePostHandler) GetPost(c *gin.Context)    { c.Status(http.StatusOK) }
-func (f *fakePostHandler) GetTags(c *gin.Context)    { c.Status(http.StatusOK) }
-func (f *fakePostHandler) CreatePost(c *gin.Context) { c.Status(http.StatusCreated) }
-func (f *fakePostHandler) UpdatePost(c *gin.Context) { c.Status(http.StatusOK) }
-func (f *fakePostHandler) DeletePost(c *gin.Context) { c.Status(http.StatusNoContent) }
+func (f *fakePostHandler) GetPosts(c *gin.Context)        { c.Status(http.StatusOK) }
+func (f *fakePostHandler) SearchPosts(c *gin.Context)     { c.Status(http.StatusOK) }
+func (f *fakePostHandler) GetPost(c *gin.Context)         { c.Status(http.StatusOK) }
+func (f *fakePostHandler) GetRelatedPosts(c *gin.Context) { c.Status(http.StatusOK) }
+func (f *fakePostHandler) GetPostBySlug(c *gin.Context)   { c.Status(http.StatusOK) }
+func (f *fakePostHandler) GetDrafts(c *gin.Context)       { c.Status(http.StatusOK) }
+func (f *fakePostHandler) GetDraft(c *gin.Context)        { c.Status(http.StatusOK) }
+func (f *fakePostHandler) ListRevisions(c *gin.Context)   { c.Status(http.StatusOK) }
+func (f *fakePostHandler) GetRevision(c *gin.Context)     { c.Status(http.StatusOK) }
+func (f *fakePostHandler) DiffRevisions(c *gin.Context)   { c.Status(http.StatusOK) }
+func (f *fakePostHandler) RestoreRevision(c *gin.Context) { c.Status(http.StatusOK) }
+func (f *fakePostHandler) GetTags(c *gin.Context)         { c.Status(http.StatusOK) }
+func (f *fakePostHandler) CreatePost(c *gin.Context)      { c.Status(http.StatusCreated) }
+func (f *fakePostHandler) UpdatePost(c *gin.Context)      { c.Status(http.StatusOK) }
+func (f *fakePostHandler) DeletePost(c *gin.Context)      { c.Status(http.StatusNoContent) }
+
+type fakeCommentHandler struct{}
+
+func (f *fakeCommentHandler) ListComments(c *gin.Context)    { c.Status(http.StatusOK) }
+func (f *fakeCommentHandler) CreateComment(c *gin.Context)   { c.Status(http.StatusAccepted) }
+func (f *fakeCommentHandler) ModerationQueue(c *gin.Context) { c.Status(http.StatusOK) }
+func (f *fakeCommentHandler) ModerateComment(c *gin.Context) { c.Status(http.StatusOK) }
+func (f *fakeCommentHandler) DeleteComment(c *gin.Context)   { c.Status(http.StatusNoContent) }
+
+type fakeSeriesHandler struct{}
+
+func (f *fakeSeriesHandler) ListSeries(c *gin.Context)   { c.Status(http.StatusOK) }
+func (f *fakeSeriesHandler) GetSeries(c *gin.Context)    { c.Status(http.StatusOK) }
+func (f *fakeSeriesHandler) CreateSeries(c *gin.Context) { c.Status(http.StatusCreated) }
+func (f *fakeSeriesHandler) UpdateSeries(c *gin.Context) { c.Status(http.StatusOK) }
+func (f *fakeSeriesHandler) DeleteSeries(c *gin.Context) { c.Status(http.StatusNoContent) }
+
+type fakeTagHandler struct{}
+
+func (f *fakeTagHandler) GetTag(c *gin.Context)      { c.Status(http.StatusOK) }
+func (f *fakeTagHandler) ListAllTags(c *gin.Context) { c.Status(http.StatusOK) }
+func (f *fakeTagHandler) UpdateTag(c *gin.Context)   { c.Status(http.StatusOK) }
+func (f *fakeTagHandler) MergeTag(c *gin.Context)    { c.Status(http.StatusOK) }
+func (f *fakeTagHandler) AddAlias(c *gin.Context)    { c.Status(http.StatusCreated) }
+func (f *fakeTagHandler) DeleteAlias(c *gin.Context) { c.Status(http.StatusOK) }
 
 func TestRegisterRoutes(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
-	registerRoutes(r, &fakePostHandler{}, logger.New("test"))
+	registerRoutes(r, &fakePostHandler{}, &fakeCommentHandler{}, &fakeSeriesHandler{}, &fakeTagHandler{}, logger.New("test"))
 
 	tests := []struct {
 		method string
@@ -30,11 +64,36 @@ func TestRegisterRoutes(t *testing.T) {
 	}{
 		{http.MethodGet, "/health", http.StatusOK},
 		{http.MethodGet, "/posts", http.StatusOK},
+		{http.MethodGet, "/posts/search?q=go", http.StatusOK},
 		{http.MethodGet, "/posts/1", http.StatusOK},
+		{http.MethodGet, "/posts/1/related", http.StatusOK},
+		{http.MethodGet, "/posts/by-slug/hello-world", http.StatusOK},
+		{http.MethodGet, "/drafts", http.StatusOK},
+		{http.MethodGet, "/drafts/1", http.StatusOK},
+		{http.MethodGet, "/posts/1/revisions", http.StatusOK},
+		{http.MethodGet, "/posts/1/revisions/2", http.StatusOK},
+		{http.MethodGet, "/posts/1/revisions/2/diff", http.StatusOK},
+		{http.MethodPost, "/posts/1/revisions/2/restore", http.StatusOK},
 		{http.MethodGet, "/tags", http.StatusOK},
+		{http.MethodGet, "/tags/go", http.StatusOK},
 		{http.MethodPost, "/posts", http.StatusCreated},
 		{http.MethodPut, "/posts/1", http.StatusOK},
 		{http.MethodDelete, "/posts/1", http.StatusNoContent},
+		{http.MethodGet, "/posts/1/comments", http.StatusOK},
+		{http.MethodPost, "/posts/1/comments", http.StatusAccepted},
+		{http.MethodGet, "/comments?status=pending", http.StatusOK},
+		{http.MethodPut, "/comments/1/status", http.StatusOK},
+		{http.MethodDelete, "/comments/1", http.StatusNoContent},
+		{http.MethodGet, "/series", http.StatusOK},
+		{http.MethodGet, "/series/1", http.StatusOK},
+		{http.MethodPost, "/series", http.StatusCreated},
+		{http.MethodPut, "/series/1", http.StatusOK},
+		{http.MethodDelete, "/series/1", http.StatusNoContent},
+		{http.MethodGet, "/admin/tags", http.StatusOK},
+		{http.MethodPut, "/admin/tags/1", http.StatusOK},
+		{http.MethodPost, "/admin/tags/1/merge", http.StatusOK},
+		{http.MethodPost, "/admin/tags/1/aliases", http.StatusCreated},
+		{http.MethodDelete, "/admin/tags/1/aliases/golang", http.StatusOK},
 	}
 
 	for _, tc := range tests {
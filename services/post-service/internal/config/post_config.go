@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"seungpyo.lee/PersonalWebSite/pkg/config"
@@ -11,14 +12,18 @@ import (
 type PostConfig struct {
 	config.GlobalConfig
 	PostgreConnectionString string
+	MigrationMode           string // DB_MIGRATE: "up" applies migrations at startup, "verify" only checks them
 	RedisDBURL              string
 	RedisDBPort             string
 	RedisDBPassword         string
 	RedisMaxRetries         int
 	RedisPoolSize           int
-	ImageServiceURL         string // URL of the Image Service
-	TranslationAPIURL       string // optional translation service URL
-	TranslationAPIKey       string // optional translation service API key (e.g., DeepL)
+	ImageServiceURL         string        // URL of the Image Service
+	TranslationAPIURL       string        // optional translation service URL
+	TranslationAPIKey       string        // optional translation service API key (e.g., DeepL)
+	ScheduleInterval        time.Duration // how often scheduled publishes and unpublishes are applied
+	CommentRateLimit        int           // comments accepted from one address per CommentRateWindow
+	CommentRateWindow       time.Duration
 }
 
 func LoadPostConfig() *PostConfig {
@@ -29,6 +34,7 @@ func LoadPostConfig() *PostConfig {
 	return &PostConfig{
 		GlobalConfig:            *config.LoadGlobalConfig(),
 		PostgreConnectionString: getEnv("POSTGRE_CONNECTION_STRING"),
+		MigrationMode:           getEnvDefault("DB_MIGRATE", "up"),
 		RedisDBURL:              getEnv("REDIS_DB_URL"),
 		RedisDBPort:             getEnv("REDIS_DB_PORT"),
 		RedisDBPassword:         getEnv("REDIS_DB_PASSWORD"),
@@ -37,6 +43,9 @@ func LoadPostConfig() *PostConfig {
 		TranslationAPIKey:       getEnv("TRANSLATION_API_KEY"),
 		RedisMaxRetries:         3,
 		RedisPoolSize:           10,
+		ScheduleInterval:        30 * time.Second,
+		CommentRateLimit:        5,
+		CommentRateWindow:       10 * time.Minute,
 	}
 }
 
@@ -48,3 +57,11 @@ func getEnv(key string) string {
 		panic("critical config missing: " + key)
 	}
 }
+
+// getEnvDefault returns the environment variable named by key, or fallback if unset.
+func getEnvDefault(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
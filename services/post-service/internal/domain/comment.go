@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+// Comment statuses: new comments wait in the moderation queue as pending until the
+// post's author approves them or marks them spam. Only approved comments are shown.
+const (
+	CommentPending  = "pending"
+	CommentApproved = "approved"
+	CommentSpam     = "spam"
+)
+
+// Comment is a reader's reply to a post or, with a ParentID, to another comment.
+// AuthorEmail is only shown to the post's author; public listings leave it out.
+type Comment struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	PostID      uint       `json:"post_id" gorm:"not null"`
+	PostTitle   string     `json:"post_title,omitempty" gorm:"->"` // loaded for the moderation queue
+	ParentID    *uint      `json:"parent_id,omitempty"`
+	UserID      *uint      `json:"user_id,omitempty"` // nil for anonymous readers
+	AuthorName  string     `json:"author_name" gorm:"type:text;not null"`
+	AuthorEmail string     `json:"author_email,omitempty" gorm:"type:text;not null;default:''"`
+	Body        string     `json:"body" gorm:"type:text;not null"`
+	Status      string     `json:"status" gorm:"type:text;not null;default:'pending'"`
+	IP          string     `json:"-" gorm:"type:text;not null;default:''"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Replies     []*Comment `json:"replies,omitempty" gorm:"-"`
+}
+
+var (
+	ErrPostNotFound    = errors.New("post not found")
+	ErrCommentNotFound = errors.New("comment not found")
+	// ErrInvalidComment wraps the reason a comment or a moderation decision was refused.
+	ErrInvalidComment     = errors.New("invalid comment")
+	ErrCommentRateLimited = errors.New("too many comments, try again later")
+)
+
+type CommentRepository interface {
+	Create(comment *Comment) error
+	GetByID(id uint) (*Comment, error)
+	// ListForPost returns a post's comments with status, oldest first.
+	ListForPost(postID uint, status string) ([]*Comment, error)
+	// ListForAuthor returns the comments with status on authorID's posts, newest first,
+	// with their post's title.
+	ListForAuthor(authorID uint, status string, limit int) ([]*Comment, error)
+	SetStatus(id uint, status string) error
+	// Delete removes a comment and, through the foreign key, its replies.
+	Delete(id uint) error
+	// CountRecent counts the comments submitted from ip since the given time.
+	CountRecent(ip string, since time.Time) (int64, error)
+}
+
+type CommentService interface {
+	// AddComment submits a comment on a published post. Comments by the post's author
+	// are approved at once; others wait for moderation and notify the author.
+	AddComment(ctx context.Context, postID uint, req model.CreateCommentRequest, by model.Commenter) (*Comment, error)
+	// ListComments returns the approved comments of a published post as threads.
+	ListComments(postID uint) ([]*Comment, error)
+	// ModerationQueue lists the comments with status on authorID's posts.
+	ModerationQueue(authorID uint, status string, limit int) ([]*Comment, error)
+	ModerateComment(ctx context.Context, id uint, status string, authorID uint) (*Comment, error)
+	DeleteComment(ctx context.Context, id, authorID uint) error
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// Event types pushed to the author's editor and article pages.
+const (
+	EventTranslationCompleted = "translation.completed"
+	EventTranslationFailed    = "translation.failed"
+	EventImagesProcessed      = "images.processed"
+	EventPostPublished        = "post.published"
+	EventPostUnpublished      = "post.unpublished"
+	EventCommentPending       = "comment.pending"
+)
+
+// Event is a notification about background work on a post.
+type Event struct {
+	Type     string    `json:"type"`
+	PostID   uint      `json:"post_id"`
+	AuthorID uint      `json:"-"`
+	Message  string    `json:"message,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// EventBroker fans events out to subscribers. Events are delivered only to
+// subscriptions of the post's author; slow subscribers may miss events.
+type EventBroker interface {
+	Publish(event Event)
+	// Subscribe returns a channel of events for authorID and a function that
+	// ends the subscription and closes the channel.
+	Subscribe(authorID uint) (<-chan Event, func())
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
@@ -15,6 +17,7 @@ type User struct {
 type Post struct {
 	ID          uint       `json:"id" gorm:"primaryKey"`
 	Title       string     `json:"title" gorm:"type:text;not null"`
+	Slug        string     `json:"slug" gorm:"type:text;not null;default:''"`
 	Content     string     `json:"content" gorm:"type:text;not null"`
 	EnTitle     string     `json:"en_title,omitempty" gorm:"type:text"`
 	EnContent   string     `json:"en_content,omitempty" gorm:"type:text"`
@@ -23,43 +26,197 @@ type Post struct {
 	Author      User       `json:"author" gorm:"foreignKey:AuthorID"`
 	Published   bool       `json:"published" gorm:"default:false"`
 	PublishedAt *time.Time `json:"published_at,omitempty"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`   // scheduled publish of an unpublished post
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty"` // scheduled unpublish
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	Tags        []*Tag     `json:"tags,omitempty" gorm:"many2many:post_tags;constraint:OnDelete:CASCADE;"`
+	Series      *SeriesNav `json:"series,omitempty" gorm:"-"` // set when a published post is read
 }
 
 // Tag represents a post tag/label. Tags are normalized into their own table
-// and associated with posts through a many-to-many join table.
+// and associated with posts through a many-to-many join table. Names are unique
+// regardless of case.
 type Tag struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"type:text;not null"`
+	Description string    `json:"description,omitempty" gorm:"type:text;not null;default:''"`
+	PostCount   int64     `json:"post_count,omitempty" gorm:"->;-:migration"` // set by the tag lists
+	Aliases     []string  `json:"aliases,omitempty" gorm:"-"`                 // set by the admin list
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PostSlug is a slug a post had before; links using it redirect to the current one.
+type PostSlug struct {
+	Slug      string `gorm:"primaryKey;type:text"`
+	PostID    uint   `gorm:"index;not null"`
+	CreatedAt time.Time
+}
+
+// Revision kinds: what changed the post's text.
+const (
+	RevisionImport      = "import" // the text a post had when revisions were introduced
+	RevisionCreate      = "create"
+	RevisionEdit        = "edit"
+	RevisionTranslation = "translation"
+	RevisionRestore     = "restore"
+)
+
+// PostRevision is a snapshot of a post's title, content and translation taken after
+// a change to any of them. Listings leave Content and EnContent out.
+type PostRevision struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name" gorm:"type:text;uniqueIndex;not null"`
+	PostID    uint      `json:"post_id" gorm:"not null"`
+	Title     string    `json:"title" gorm:"type:text;not null"`
+	Content   string    `json:"content,omitempty" gorm:"type:text;not null"`
+	EnTitle   string    `json:"en_title,omitempty" gorm:"type:text;not null;default:''"`
+	EnContent string    `json:"en_content,omitempty" gorm:"type:text;not null;default:''"`
+	AuthorID  *uint     `json:"author_id,omitempty"` // nil for background changes such as translation
+	Kind      string    `json:"kind" gorm:"type:text;not null"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// SameText reports whether r and other hold the same title, content and translation.
+func (r *PostRevision) SameText(other *PostRevision) bool {
+	return r.Title == other.Title && r.Content == other.Content && r.EnTitle == other.EnTitle && r.EnContent == other.EnContent
+}
+
+// RevisionDiff is the unified diff between two revisions of a post. From is 0 when
+// To is compared with an empty post.
+type RevisionDiff struct {
+	From uint   `json:"from"`
+	To   uint   `json:"to"`
+	Diff string `json:"diff"`
+}
+
+// PostPage is a page of a post list, newest first. NextCursor and PrevCursor fetch
+// the pages after and before it, and are "" when there is none; TotalCount counts the
+// posts of all pages.
+type PostPage struct {
+	Posts      []*Post `json:"posts"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+	TotalCount int64   `json:"total_count"`
+}
+
+// SearchHit is a post found by a full-text search. The highlights are HTML with the
+// matches in <mark>; the post comes without its content.
+type SearchHit struct {
+	Post           *Post   `json:"post"`
+	Rank           float64 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+// SearchResult is a page of search hits, best first, with the number of all hits.
+type SearchResult struct {
+	Query string       `json:"query"`
+	Total int64        `json:"total"`
+	Hits  []*SearchHit `json:"hits"`
+}
+
+var (
+	ErrInvalidSlug = errors.New("invalid slug: use lowercase letters, digits and hyphens, with at least one letter")
+	ErrSlugTaken   = errors.New("slug is already used by another post")
+	// ErrInvalidSchedule wraps the reason a publish_at or unpublish_at was refused.
+	ErrInvalidSchedule  = errors.New("invalid schedule")
+	ErrRevisionNotFound = errors.New("revision not found")
+	ErrEmptySearch      = errors.New("search query is empty")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+)
+
 type PostRepository interface {
 	Create(post *Post) error
 	GetByID(id uint) (*Post, error)
-	GetAll(filter model.PostFilter) ([]*Post, error)
+	// GetBySlug finds a post by its current or a previous slug.
+	GetBySlug(slug string) (*Post, error)
+	// SlugOwner returns the post that uses slug now or used it before, or 0.
+	SlugOwner(slug string) (uint, error)
+	// ChangeSlug sets the post's slug and keeps oldSlug so links using it still resolve.
+	ChangeSlug(postID uint, oldSlug, newSlug string) error
+	ListWithoutSlug() ([]*Post, error)
+	// ApplySchedule publishes the posts whose publish_at has passed and unpublishes the
+	// ones whose unpublish_at has passed, as of now, and returns them in their new state.
+	// It holds a database lock, so with several replicas only one applies each change.
+	ApplySchedule(now time.Time) (published, unpublished []*Post, err error)
+	// AddRevision appends rev to its post's history unless the latest revision already
+	// has the same text; rev.ID stays 0 then.
+	AddRevision(rev *PostRevision) error
+	// ListRevisions returns a post's revisions, newest first, without their content.
+	ListRevisions(postID uint) ([]*PostRevision, error)
+	GetRevision(postID, revisionID uint) (*PostRevision, error)
+	// PreviousRevision returns the revision before revisionID, or nil for the first one.
+	PreviousRevision(postID, revisionID uint) (*PostRevision, error)
+	// GetAll returns the page of posts matching filter. With the default order, newest
+	// first, pages are keyed by cursor: filter.Cursor continues from a cursor of an
+	// earlier page, and the page has cursors when there are posts before or after it.
+	GetAll(filter model.PostFilter) (*PostPage, error)
+	// Search ranks the published posts matching query and returns a page of them with
+	// their total count. The highlights come straight from ts_headline, with matches
+	// between util.HighlightStart and util.HighlightStop.
+	Search(query model.SearchQuery) ([]*SearchHit, int64, error)
+	// Related ranks the other published posts by how many tags they share with postID
+	// and how similar their text is, and returns the best limit of them.
+	Related(postID uint, limit int) ([]*RelatedPost, error)
 	Update(post *Post) error
+	// UpdateTranslation sets the English title and content of a post, leaving every
+	// other column as it is; nil leaves that field unchanged too.
+	UpdateTranslation(postID uint, enTitle, enContent *string) error
 	Delete(id uint) error
 	GetByAuthorID(authorID uint) ([]*Post, error)
 }
 
+// TagRepository stores tags. Names given to it match existing tags regardless of case
+// and through their aliases.
 type TagRepository interface {
 	AttachTagsToPost(postID uint, tagNames []string) error
 	ReplaceTagsForPost(postID uint, tagNames []string) error
 	GetTagsForPost(postID uint) ([]*Tag, error)
+	// ListTags returns the tags of published posts with their published post counts.
 	ListTags() ([]*Tag, error)
+	// ListAllTags returns every tag with its aliases and the count of all its posts.
+	ListAllTags() ([]*Tag, error)
+	GetTagByID(id uint) (*Tag, error)
+	// FindTag finds the tag named name or with name as an alias, with its published
+	// post count.
+	FindTag(name string) (*Tag, error)
+	UpdateTag(tag *Tag) error
+	// MergeTags moves the posts and aliases of fromID to intoID, keeps the name of fromID
+	// as an alias and deletes it.
+	MergeTags(fromID, intoID uint) error
+	AddAlias(tagID uint, alias string) error
+	DeleteAlias(tagID uint, alias string) error
 	DeleteTag(id uint) error
+	// DeleteUnusedTag deletes the tag if no post uses it and it has no description or
+	// aliases.
 	DeleteUnusedTag(tagID uint) error
 }
 
 type PostService interface {
-	CreatePost(req model.CreatePostRequest, authorID uint) (*Post, error)
+	CreatePost(ctx context.Context, req model.CreatePostRequest, authorID uint) (*Post, error)
+	// GetPost and GetPostBySlug only find published posts.
 	GetPost(id uint) (*Post, error)
-	GetPostsByFilter(filter model.PostFilter) ([]*Post, error)
-	UpdatePost(id uint, req model.UpdatePostRequest, authorID uint) (*Post, error)
-	DeletePost(id, authorID uint) error
+	GetPostBySlug(slug string) (*Post, error)
+	// GetPostForAuthor finds a post of authorID in any state, for editing.
+	GetPostForAuthor(id, authorID uint) (*Post, error)
+	GetPostsByFilter(filter model.PostFilter) (*PostPage, error)
+	SearchPosts(query model.SearchQuery) (*SearchResult, error)
+	// RelatedPosts suggests up to limit published posts to read after the published
+	// post id, best first.
+	RelatedPosts(id uint, limit int) ([]*RelatedPost, error)
+	UpdatePost(ctx context.Context, id uint, req model.UpdatePostRequest, authorID uint) (*Post, error)
+	DeletePost(ctx context.Context, id, authorID uint) error
 	ListTags() ([]*Tag, error)
+	// ListRevisions, GetRevision and DiffRevisions show a post's history to its author.
+	// DiffRevisions compares fromID with toID, or toID with the revision before it when
+	// fromID is 0.
+	ListRevisions(postID, authorID uint) ([]*PostRevision, error)
+	GetRevision(postID, revisionID, authorID uint) (*PostRevision, error)
+	DiffRevisions(postID, fromID, toID, authorID uint) (*RevisionDiff, error)
+	// RestoreRevision puts a revision's title, content and translation back as a new revision.
+	RestoreRevision(ctx context.Context, postID, revisionID, authorID uint) (*Post, error)
+	// RunSchedule applies due scheduled publishes and unpublishes and returns how many
+	// posts changed.
+	RunSchedule(ctx context.Context) (int, error)
 }
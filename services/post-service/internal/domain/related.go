@@ -0,0 +1,22 @@
+package domain
+
+// RelatedPost is a published post suggested as a read after another one. Score ranks
+// it by the tags the two posts share and the similarity of their text; the post comes
+// without its content.
+type RelatedPost struct {
+	Post       *Post   `json:"post"`
+	Score      float64 `json:"score"`
+	SharedTags int     `json:"shared_tags"`
+}
+
+// RelatedCache keeps the related posts computed for each post. Any change to a post
+// or a tag can change the ranking of every post, so entries belong to a generation and
+// Invalidate starts a new one. Posts computed before an invalidation are stored under
+// the generation read before computing them, where they are never read again.
+type RelatedCache interface {
+	Generation() (int64, error)
+	// Get returns the related posts cached for postID, and false when there are none.
+	Get(generation int64, postID uint) ([]*RelatedPost, bool, error)
+	Set(generation int64, postID uint, related []*RelatedPost) error
+	Invalidate() error
+}
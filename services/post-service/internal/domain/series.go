@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+// Series is an author's ordered set of posts, such as the parts of a tutorial. Parts
+// are in reading order; public responses only list the published ones.
+type Series struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	Title       string        `json:"title" gorm:"type:text;not null"`
+	Description string        `json:"description" gorm:"type:text;not null;default:''"`
+	AuthorID    uint          `json:"author_id" gorm:"not null"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	Parts       []*SeriesPart `json:"parts" gorm:"-"`
+}
+
+// SeriesPost places a post in a series; Position orders the parts and may have gaps
+// where posts were deleted.
+type SeriesPost struct {
+	SeriesID uint `gorm:"primaryKey"`
+	PostID   uint `gorm:"primaryKey"`
+	Position int  `gorm:"not null"`
+}
+
+// SeriesPart is a post of a series as listed with it.
+type SeriesPart struct {
+	PostID    uint   `json:"post_id"`
+	Title     string `json:"title"`
+	Slug      string `json:"slug"`
+	Published bool   `json:"published"`
+}
+
+// SeriesNav shows where a post stands in its series: part Part of Total, with the
+// parts before and after it. Only published parts are counted.
+type SeriesNav struct {
+	ID    uint        `json:"id"`
+	Title string      `json:"title"`
+	Part  int         `json:"part"`
+	Total int         `json:"total"`
+	Prev  *SeriesPart `json:"prev,omitempty"`
+	Next  *SeriesPart `json:"next,omitempty"`
+}
+
+// PublishedParts returns the series' published parts in order.
+func (s *Series) PublishedParts() []*SeriesPart {
+	parts := make([]*SeriesPart, 0, len(s.Parts))
+	for _, p := range s.Parts {
+		if p.Published {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// Navigation returns the place of postID among the published parts, or nil when it is
+// not one of them.
+func (s *Series) Navigation(postID uint) *SeriesNav {
+	parts := s.PublishedParts()
+	for i, p := range parts {
+		if p.PostID != postID {
+			continue
+		}
+		nav := &SeriesNav{ID: s.ID, Title: s.Title, Part: i + 1, Total: len(parts)}
+		if i > 0 {
+			nav.Prev = parts[i-1]
+		}
+		if i+1 < len(parts) {
+			nav.Next = parts[i+1]
+		}
+		return nav
+	}
+	return nil
+}
+
+var (
+	ErrSeriesNotFound = errors.New("series not found")
+	// ErrInvalidSeries wraps the reason a series or its parts were refused.
+	ErrInvalidSeries  = errors.New("invalid series")
+	ErrSeriesConflict = errors.New("post already belongs to another series")
+)
+
+type SeriesRepository interface {
+	// Create inserts a series with postIDs as its parts, in order.
+	Create(series *Series, postIDs []uint) error
+	GetByID(id uint) (*Series, error)
+	// List returns every series, newest first.
+	List() ([]*Series, error)
+	// Update saves the series' title and description and, unless postIDs is nil,
+	// replaces its parts.
+	Update(series *Series, postIDs []uint) error
+	Delete(id uint) error
+	// SeriesOf returns the series postID belongs to, or nil.
+	SeriesOf(postID uint) (*Series, error)
+	// Memberships maps those of postIDs that belong to a series to its ID.
+	Memberships(postIDs []uint) (map[uint]uint, error)
+}
+
+type SeriesService interface {
+	// ListSeries and GetSeries show the series with published parts, with only those.
+	ListSeries() ([]*Series, error)
+	GetSeries(id uint) (*Series, error)
+	CreateSeries(ctx context.Context, req model.CreateSeriesRequest, authorID uint) (*Series, error)
+	UpdateSeries(ctx context.Context, id uint, req model.UpdateSeriesRequest, authorID uint) (*Series, error)
+	DeleteSeries(ctx context.Context, id, authorID uint) error
+}
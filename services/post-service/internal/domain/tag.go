@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+// TagAlias is another spelling of a tag, such as "golang" for "Go". Aliases are stored
+// in lowercase and posts tagged with one get the tag itself.
+type TagAlias struct {
+	Alias     string `gorm:"primaryKey;type:text"`
+	TagID     uint   `gorm:"index;not null"`
+	CreatedAt time.Time
+}
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrInvalidTag  = errors.New("invalid tag")
+	// ErrTagConflict means a name or alias is already taken by another tag; merging the
+	// tags is the way to combine them.
+	ErrTagConflict = errors.New("tag name is taken")
+)
+
+// TagService manages tags. GetTag is public; the other methods are for admins.
+type TagService interface {
+	// GetTag finds a tag of published posts by its name or an alias.
+	GetTag(name string) (*Tag, error)
+	ListAllTags() ([]*Tag, error)
+	UpdateTag(ctx context.Context, id uint, req model.UpdateTagRequest) (*Tag, error)
+	// MergeTag merges the tag into intoID and returns the merged tag.
+	MergeTag(ctx context.Context, id, intoID uint) (*Tag, error)
+	AddAlias(ctx context.Context, id uint, alias string) (*Tag, error)
+	DeleteAlias(ctx context.Context, id uint, alias string) (*Tag, error)
+}
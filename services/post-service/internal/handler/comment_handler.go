@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+// CommentHandler handles HTTP requests for comments and their moderation.
+type CommentHandler struct {
+	Service domain.CommentService
+}
+
+// NewCommentHandler creates a new CommentHandler.
+func NewCommentHandler(service domain.CommentService) *CommentHandler {
+	return &CommentHandler{Service: service}
+}
+
+// ListComments handles GET /posts/:id/comments. Lists the approved comments of a
+// published post as threads.
+func (h *CommentHandler) ListComments(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	comments, err := h.Service.ListComments(uint(id))
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	if comments == nil {
+		comments = []*domain.Comment{}
+	}
+	c.JSON(http.StatusOK, comments)
+}
+
+// CreateComment handles POST /posts/:id/comments. Readers may be signed in, in which
+// case the gateway identifies them, or anonymous. Responds 201 for a comment shown at
+// once and 202 for one waiting for moderation.
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req model.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	by := model.Commenter{Username: c.GetHeader("X-Username"), IP: c.ClientIP()}
+	if c.GetHeader("X-User-Id") != "" {
+		userID, ok := userIDFrom(c)
+		if !ok {
+			return
+		}
+		by.UserID = &userID
+	}
+	comment, err := h.Service.AddComment(c.Request.Context(), uint(id), req, by)
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	status := http.StatusCreated
+	if comment.Status != domain.CommentApproved {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, comment)
+}
+
+// ModerationQueue handles GET /comments. Lists the comments on the caller's posts with
+// the status given by the status query parameter, pending by default.
+func (h *CommentHandler) ModerationQueue(c *gin.Context) {
+	userID, ok := userIDFrom(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	comments, err := h.Service.ModerationQueue(userID, c.Query("status"), limit)
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	if comments == nil {
+		comments = []*domain.Comment{}
+	}
+	c.JSON(http.StatusOK, comments)
+}
+
+// ModerateComment handles PUT /comments/:id/status. Approves a comment on the caller's
+// post, marks it spam or returns it to the queue.
+func (h *CommentHandler) ModerateComment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req model.ModerateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := userIDFrom(c)
+	if !ok {
+		return
+	}
+	comment, err := h.Service.ModerateComment(c.Request.Context(), uint(id), req.Status, userID)
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment handles DELETE /comments/:id. Deletes a comment on the caller's post
+// together with its replies.
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	userID, ok := userIDFrom(c)
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteComment(c.Request.Context(), uint(id), userID); err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+type stubCommentService struct {
+	addCommentFn      func(postID uint, req model.CreateCommentRequest, by model.Commenter) (*domain.Comment, error)
+	listCommentsFn    func(postID uint) ([]*domain.Comment, error)
+	moderationQueueFn func(authorID uint, status string, limit int) ([]*domain.Comment, error)
+	moderateFn        func(id uint, status string, authorID uint) (*domain.Comment, error)
+	deleteFn          func(id, authorID uint) error
+}
+
+func (s *stubCommentService) AddComment(_ context.Context, postID uint, req model.CreateCommentRequest, by model.Commenter) (*domain.Comment, error) {
+	return s.addCommentFn(postID, req, by)
+}
+func (s *stubCommentService) ListComments(postID uint) ([]*domain.Comment, error) {
+	return s.listCommentsFn(postID)
+}
+func (s *stubCommentService) ModerationQueue(authorID uint, status string, limit int) ([]*domain.Comment, error) {
+	return s.moderationQueueFn(authorID, status, limit)
+}
+func (s *stubCommentService) ModerateComment(_ context.Context, id uint, status string, authorID uint) (*domain.Comment, error) {
+	return s.moderateFn(id, status, authorID)
+}
+func (s *stubCommentService) DeleteComment(_ context.Context, id, authorID uint) error {
+	return s.deleteFn(id, authorID)
+}
+
+func TestCreateComment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var seen model.Commenter
+	svc := &stubCommentService{
+		addCommentFn: func(postID uint, req model.CreateCommentRequest, by model.Commenter) (*domain.Comment, error) {
+			seen = by
+			switch {
+			case postID == 2:
+				return nil, domain.ErrPostNotFound
+			case req.Body == "again":
+				return nil, domain.ErrCommentRateLimited
+			case req.Email == "bad":
+				return nil, domain.ErrInvalidComment
+			case by.UserID != nil && *by.UserID == 7:
+				return &domain.Comment{ID: 1, PostID: postID, Status: domain.CommentApproved}, nil
+			}
+			return &domain.Comment{ID: 1, PostID: postID, Status: domain.CommentPending}, nil
+		},
+	}
+	h := NewCommentHandler(svc)
+	r := gin.New()
+	r.POST("/posts/:id/comments", h.CreateComment)
+
+	tests := []struct {
+		name, path, user string
+		body             model.CreateCommentRequest
+		want             int
+	}{
+		{"bad id", "/posts/x/comments", "", model.CreateCommentRequest{Body: "hi"}, http.StatusBadRequest},
+		{"no body", "/posts/1/comments", "", model.CreateCommentRequest{}, http.StatusBadRequest},
+		{"bad user", "/posts/1/comments", "x", model.CreateCommentRequest{Body: "hi"}, http.StatusUnauthorized},
+		{"draft", "/posts/2/comments", "", model.CreateCommentRequest{Body: "hi"}, http.StatusNotFound},
+		{"invalid", "/posts/1/comments", "", model.CreateCommentRequest{Body: "hi", Email: "bad"}, http.StatusBadRequest},
+		{"rate limited", "/posts/1/comments", "", model.CreateCommentRequest{Body: "again"}, http.StatusTooManyRequests},
+		{"anonymous", "/posts/1/comments", "", model.CreateCommentRequest{Body: "hi"}, http.StatusAccepted},
+		{"author", "/posts/1/comments", "7", model.CreateCommentRequest{Body: "hi"}, http.StatusCreated},
+	}
+	for _, tc := range tests {
+		req := jsonReq(t, http.MethodPost, tc.path, tc.body)
+		req.Header.Set("X-Username", "kim")
+		req.Header.Set("X-Forwarded-For", "203.0.113.9")
+		if tc.user != "" {
+			req.Header.Set("X-User-Id", tc.user)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s: want %d got %d body=%s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+	if seen.UserID == nil || *seen.UserID != 7 || seen.Username != "kim" || seen.IP != "203.0.113.9" {
+		t.Fatalf("unexpected commenter %+v", seen)
+	}
+}
+
+func TestCreateComment_ForwardedForOnlyFromTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const limit = 2
+	counts := map[string]int{}
+	svc := &stubCommentService{
+		addCommentFn: func(postID uint, req model.CreateCommentRequest, by model.Commenter) (*domain.Comment, error) {
+			if counts[by.IP] >= limit {
+				return nil, domain.ErrCommentRateLimited
+			}
+			counts[by.IP]++
+			return &domain.Comment{ID: 1, PostID: postID, Status: domain.CommentPending}, nil
+		},
+	}
+	h := NewCommentHandler(svc)
+	r := gin.New()
+	if err := r.SetTrustedProxies([]string{"192.0.2.10"}); err != nil {
+		t.Fatal(err)
+	}
+	r.POST("/posts/:id/comments", h.CreateComment)
+
+	post := func(remoteAddr, xff string) int {
+		req := jsonReq(t, http.MethodPost, "/posts/1/comments", model.CreateCommentRequest{Body: "hi"})
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", xff)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	// a client reaching the service directly cannot pick a fresh IP per comment
+	for i := 0; i < limit; i++ {
+		if code := post("198.51.100.7:1234", fmt.Sprintf("203.0.113.%d", i)); code != http.StatusAccepted {
+			t.Fatalf("comment %d: want 202 got %d", i, code)
+		}
+	}
+	if code := post("198.51.100.7:1234", "203.0.113.99"); code != http.StatusTooManyRequests {
+		t.Fatalf("spoofed X-Forwarded-For reset the limit: got %d", code)
+	}
+	// the gateway's X-Forwarded-For names the client
+	if code := post("192.0.2.10:1234", "198.51.100.7"); code != http.StatusTooManyRequests {
+		t.Fatalf("client behind the gateway: want 429 got %d", code)
+	}
+	if code := post("192.0.2.10:1234", "198.51.100.8"); code != http.StatusAccepted {
+		t.Fatalf("other client behind the gateway: want 202 got %d", code)
+	}
+}
+
+func TestListComments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := &stubCommentService{
+		listCommentsFn: func(postID uint) ([]*domain.Comment, error) {
+			if postID == 2 {
+				return nil, domain.ErrPostNotFound
+			}
+			return nil, nil
+		},
+	}
+	h := NewCommentHandler(svc)
+	r := gin.New()
+	r.GET("/posts/:id/comments", h.ListComments)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts/2/comments", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("want 404 got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts/1/comments", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "[]" {
+		t.Fatalf("want an empty list, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestModeration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var seenStatus string
+	var seenLimit int
+	svc := &stubCommentService{
+		moderationQueueFn: func(authorID uint, status string, limit int) ([]*domain.Comment, error) {
+			seenStatus, seenLimit = status, limit
+			return []*domain.Comment{{ID: 3, AuthorEmail: "kim@example.com", IP: "10.0.0.1"}}, nil
+		},
+		moderateFn: func(id uint, status string, authorID uint) (*domain.Comment, error) {
+			if id != 3 || authorID != 7 {
+				return nil, domain.ErrCommentNotFound
+			}
+			if status == "hidden" {
+				return nil, domain.ErrInvalidComment
+			}
+			return &domain.Comment{ID: id, Status: status}, nil
+		},
+		deleteFn: func(id, authorID uint) error {
+			if id != 3 || authorID != 7 {
+				return domain.ErrCommentNotFound
+			}
+			return nil
+		},
+	}
+	h := NewCommentHandler(svc)
+	r := gin.New()
+	r.GET("/comments", h.ModerationQueue)
+	r.PUT("/comments/:id/status", h.ModerateComment)
+	r.DELETE("/comments/:id", h.DeleteComment)
+
+	tests := []struct {
+		method, path, user, status string
+		want                       int
+	}{
+		{http.MethodGet, "/comments", "", "", http.StatusUnauthorized},
+		{http.MethodGet, "/comments?status=spam&limit=5", "7", "", http.StatusOK},
+		{http.MethodPut, "/comments/3/status", "", "approved", http.StatusUnauthorized},
+		{http.MethodPut, "/comments/3/status", "7", "", http.StatusBadRequest},
+		{http.MethodPut, "/comments/3/status", "7", "hidden", http.StatusBadRequest},
+		{http.MethodPut, "/comments/3/status", "8", "approved", http.StatusNotFound},
+		{http.MethodPut, "/comments/3/status", "7", "approved", http.StatusOK},
+		{http.MethodDelete, "/comments/x", "7", "", http.StatusBadRequest},
+		{http.MethodDelete, "/comments/3", "8", "", http.StatusNotFound},
+		{http.MethodDelete, "/comments/3", "7", "", http.StatusNoContent},
+	}
+	for _, tc := range tests {
+		req := jsonReq(t, tc.method, tc.path, model.ModerateCommentRequest{Status: tc.status})
+		if tc.user != "" {
+			req.Header.Set("X-User-Id", tc.user)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s %s as %q: want %d got %d", tc.method, tc.path, tc.user, tc.want, w.Code)
+		}
+		if tc.method == http.MethodGet && w.Code == http.StatusOK {
+			var queue []map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &queue); err != nil || len(queue) != 1 {
+				t.Fatalf("unexpected queue %s", w.Body.String())
+			}
+			if queue[0]["author_email"] != "kim@example.com" || queue[0]["ip"] != nil {
+				t.Fatalf("the queue shows the email but never the address, got %v", queue[0])
+			}
+		}
+	}
+	if seenStatus != "spam" || seenLimit != 5 {
+		t.Fatalf("unexpected queue query %q %d", seenStatus, seenLimit)
+	}
+}
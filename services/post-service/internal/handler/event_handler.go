@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+)
+
+// defaultHeartbeat keeps idle SSE connections open through proxies.
+const defaultHeartbeat = 25 * time.Second
+
+// EventHandler streams live post notifications to the author as Server-Sent Events.
+type EventHandler struct {
+	Broker    domain.EventBroker
+	Heartbeat time.Duration
+}
+
+// NewEventHandler creates a new EventHandler.
+func NewEventHandler(broker domain.EventBroker) *EventHandler {
+	return &EventHandler{Broker: broker, Heartbeat: defaultHeartbeat}
+}
+
+// Stream handles GET /events. It streams events for the caller's posts until the
+// client disconnects. An optional post_id query parameter limits the stream to one post.
+func (h *EventHandler) Stream(c *gin.Context) {
+	userIDStr := c.GetHeader("X-User-Id")
+	if userIDStr == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	parsed, err := strconv.ParseUint(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+	var postID uint
+	if v := c.Query("post_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post_id"})
+			return
+		}
+		postID = uint(id)
+	}
+
+	events, cancel := h.Broker.Subscribe(uint(parsed))
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if postID != 0 && event.PostID != postID {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, data)
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+)
+
+type stubBroker struct {
+	ch        chan domain.Event
+	authorID  uint
+	cancelled chan struct{}
+}
+
+func (b *stubBroker) Publish(event domain.Event) { b.ch <- event }
+func (b *stubBroker) Subscribe(authorID uint) (<-chan domain.Event, func()) {
+	b.authorID = authorID
+	return b.ch, func() { close(b.cancelled) }
+}
+
+func TestEventHandler_RequiresUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/events", NewEventHandler(&stubBroker{}).Stream)
+
+	for _, tc := range []struct {
+		path, userID string
+		want         int
+	}{
+		{"/events", "", http.StatusUnauthorized},
+		{"/events", "x", http.StatusUnauthorized},
+		{"/events?post_id=x", "1", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.userID != "" {
+			req.Header.Set("X-User-Id", tc.userID)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s (user %q): expected %d, got %d", tc.path, tc.userID, tc.want, w.Code)
+		}
+	}
+}
+
+func TestEventHandler_StreamsFilteredEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	broker := &stubBroker{ch: make(chan domain.Event, 4), cancelled: make(chan struct{})}
+	h := NewEventHandler(broker)
+	h.Heartbeat = time.Hour
+	r := gin.New()
+	r.GET("/events", h.Stream)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/events?post_id=2", nil)
+	req.Header.Set("X-User-Id", "7")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if broker.authorID != 7 {
+		t.Fatalf("expected subscription for user 7, got %d", broker.authorID)
+	}
+
+	broker.Publish(domain.Event{Type: domain.EventTranslationFailed, PostID: 1, AuthorID: 7})
+	broker.Publish(domain.Event{Type: domain.EventTranslationCompleted, PostID: 2, AuthorID: 7})
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	deadline := time.After(2 * time.Second)
+	for len(lines) < 2 {
+		lineCh := make(chan string, 1)
+		go func() {
+			line, _ := reader.ReadString('\n')
+			lineCh <- line
+		}()
+		select {
+		case line := <-lineCh:
+			if strings.HasPrefix(line, "event:") || strings.HasPrefix(line, "data:") {
+				lines = append(lines, strings.TrimSpace(line))
+			}
+		case <-deadline:
+			t.Fatalf("timed out waiting for event, got %v", lines)
+		}
+	}
+	if lines[0] != "event: translation.completed" || !strings.Contains(lines[1], `"post_id":2`) {
+		t.Fatalf("unexpected stream: %v", lines)
+	}
+
+	resp.Body.Close()
+	select {
+	case <-broker.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected subscription to end after disconnect")
+	}
+}
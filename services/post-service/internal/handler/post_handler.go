@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
@@ -19,6 +22,25 @@ func NewPostHandler(service domain.PostService) *PostHandler {
 	return &PostHandler{Service: service}
 }
 
+// writeStatus maps PostService and CommentService errors to HTTP status codes.
+func writeStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidSlug), errors.Is(err, domain.ErrInvalidSchedule), errors.Is(err, domain.ErrEmptySearch),
+		errors.Is(err, domain.ErrInvalidCursor), errors.Is(err, domain.ErrInvalidComment), errors.Is(err, domain.ErrInvalidSeries),
+		errors.Is(err, domain.ErrInvalidTag):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrSlugTaken), errors.Is(err, domain.ErrSeriesConflict), errors.Is(err, domain.ErrTagConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrRevisionNotFound), errors.Is(err, domain.ErrPostNotFound), errors.Is(err, domain.ErrCommentNotFound),
+		errors.Is(err, domain.ErrSeriesNotFound), errors.Is(err, domain.ErrTagNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrCommentRateLimited):
+		return http.StatusTooManyRequests
+	default:
+		return fallback
+	}
+}
+
 // CreatePost handles POST /posts. Creates a new blog post.
 func (h *PostHandler) CreatePost(c *gin.Context) {
 	var req model.CreatePostRequest
@@ -38,9 +60,9 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		return
 	}
 	userID := uint(parsed)
-	post, err := h.Service.CreatePost(req, userID)
+	post, err := h.Service.CreatePost(c.Request.Context(), req, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, post)
@@ -61,8 +83,253 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 	c.JSON(http.StatusOK, post)
 }
 
-// GetPosts handles GET /posts. Lists posts with optional filters.
+// GetPostBySlug handles GET /posts/by-slug/:slug. A previous slug of a post finds it
+// too; its slug field then differs from the requested one, and clients redirect.
+func (h *PostHandler) GetPostBySlug(c *gin.Context) {
+	post, err := h.Service.GetPostBySlug(c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, post)
+}
+
+// writePage responds with the posts of a list page. The total count goes in
+// X-Total-Count and the neighbouring pages in a Link header, as this request's query
+// with their cursor, so the body stays a plain array.
+func writePage(c *gin.Context, page *domain.PostPage) {
+	c.Header("X-Total-Count", strconv.FormatInt(page.TotalCount, 10))
+	var links []string
+	for _, link := range []struct{ rel, cursor string }{{"next", page.NextCursor}, {"prev", page.PrevCursor}} {
+		if link.cursor == "" {
+			continue
+		}
+		query := c.Request.URL.Query()
+		query.Set("cursor", link.cursor)
+		query.Del("offset")
+		links = append(links, fmt.Sprintf(`<?%s>; rel="%s"`, query.Encode(), link.rel))
+	}
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+	posts := page.Posts
+	if posts == nil {
+		posts = []*domain.Post{}
+	}
+	c.JSON(http.StatusOK, posts)
+}
+
+// GetPosts handles GET /posts. Lists published posts with optional filters, a page
+// at a time with limit and cursor.
 func (h *PostHandler) GetPosts(c *gin.Context) {
+	filter := listFilter(c)
+	// the list is public, so drafts never appear whatever the query asks for
+	published := true
+	filter.Published = &published
+	page, err := h.Service.GetPostsByFilter(filter)
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	writePage(c, page)
+}
+
+// SearchPosts handles GET /posts/search?q=. Ranks published posts by relevance to q,
+// optionally within a tag, and returns a page of hits with highlights and the total.
+func (h *PostHandler) SearchPosts(c *gin.Context) {
+	query := model.SearchQuery{Text: c.Query("q")}
+	if tag := c.Query("tag"); tag != "" {
+		query.Tag = &tag
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		query.Limit = limit
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil {
+		query.Offset = offset
+	}
+	result, err := h.Service.SearchPosts(query)
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetRelatedPosts handles GET /posts/:id/related. Suggests published posts to read
+// after a published post, best first; limit asks for more or fewer than the default.
+func (h *PostHandler) GetRelatedPosts(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	related, err := h.Service.RelatedPosts(uint(id), limit)
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, related)
+}
+
+// GetDrafts handles GET /drafts. Lists the caller's unpublished posts, scheduled ones
+// included, with the same filters as GET /posts.
+func (h *PostHandler) GetDrafts(c *gin.Context) {
+	userID, ok := userIDFrom(c)
+	if !ok {
+		return
+	}
+	filter := listFilter(c)
+	published := false
+	filter.AuthorID = &userID
+	filter.Published = &published
+	page, err := h.Service.GetPostsByFilter(filter)
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	writePage(c, page)
+}
+
+// GetDraft handles GET /drafts/:id. Retrieves a post of the caller in any state, for
+// the editor.
+func (h *PostHandler) GetDraft(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	userID, ok := userIDFrom(c)
+	if !ok {
+		return
+	}
+	post, err := h.Service.GetPostForAuthor(uint(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, post)
+}
+
+// ListRevisions handles GET /posts/:id/revisions. Lists the history of the caller's
+// post, newest first.
+func (h *PostHandler) ListRevisions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	userID, ok := userIDFrom(c)
+	if !ok {
+		return
+	}
+	revs, err := h.Service.ListRevisions(uint(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, revs)
+}
+
+// GetRevision handles GET /posts/:id/revisions/:rev. Retrieves one revision with its
+// content.
+func (h *PostHandler) GetRevision(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	revID, err := strconv.ParseUint(c.Param("rev"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return
+	}
+	userID, ok := userIDFrom(c)
+	if !ok {
+		return
+	}
+	rev, err := h.Service.GetRevision(uint(id), uint(revID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rev)
+}
+
+// DiffRevisions handles GET /posts/:id/revisions/:rev/diff. Compares the revision with
+// the one given by ?from=, or with the revision before it.
+func (h *PostHandler) DiffRevisions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	revID, err := strconv.ParseUint(c.Param("rev"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return
+	}
+	var fromID uint64
+	if fromStr := c.Query("from"); fromStr != "" {
+		if fromID, err = strconv.ParseUint(fromStr, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from revision"})
+			return
+		}
+	}
+	userID, ok := userIDFrom(c)
+	if !ok {
+		return
+	}
+	diff, err := h.Service.DiffRevisions(uint(id), uint(fromID), uint(revID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// RestoreRevision handles POST /posts/:id/revisions/:rev/restore. Puts the revision's
+// text back and returns the post.
+func (h *PostHandler) RestoreRevision(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	revID, err := strconv.ParseUint(c.Param("rev"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return
+	}
+	userID, ok := userIDFrom(c)
+	if !ok {
+		return
+	}
+	post, err := h.Service.RestoreRevision(c.Request.Context(), uint(id), uint(revID), userID)
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusForbidden), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, post)
+}
+
+// userIDFrom returns the user the api-gateway authenticated, or writes 401.
+func userIDFrom(c *gin.Context) (uint, bool) {
+	userIDStr := c.GetHeader("X-User-Id")
+	if userIDStr == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, false
+	}
+	parsed, err := strconv.ParseUint(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return 0, false
+	}
+	return uint(parsed), true
+}
+
+// listFilter reads the list query parameters shared by GET /posts and GET /drafts.
+func listFilter(c *gin.Context) model.PostFilter {
 	var filter model.PostFilter
 	if authorIDStr := c.Query("author_id"); authorIDStr != "" {
 		authorID, err := strconv.ParseUint(authorIDStr, 10, 64)
@@ -71,11 +338,6 @@ func (h *PostHandler) GetPosts(c *gin.Context) {
 			filter.AuthorID = &authorIDUint
 		}
 	}
-	if publishedStr := c.Query("published"); publishedStr != "" {
-		if published, err := strconv.ParseBool(publishedStr); err == nil {
-			filter.Published = &published
-		}
-	}
 	if limitStr := c.Query("limit"); limitStr != "" {
 		if limit, err := strconv.Atoi(limitStr); err == nil {
 			filter.Limit = limit
@@ -92,15 +354,12 @@ func (h *PostHandler) GetPosts(c *gin.Context) {
 	if tag := c.Query("tag"); tag != "" {
 		filter.Tag = &tag
 	}
-	posts, err := h.Service.GetPostsByFilter(filter)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.JSON(http.StatusOK, posts)
+	filter.Cursor = c.Query("cursor")
+	return filter
 }
 
-// GetTags handles GET /tags and returns all tags.
+// GetTags handles GET /tags and returns the tags of published posts with their
+// published post counts.
 func (h *PostHandler) GetTags(c *gin.Context) {
 	tags, err := h.Service.ListTags()
 	if err != nil {
@@ -133,9 +392,9 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 	userID := uint(parsed)
-	post, err := h.Service.UpdatePost(uint(id), req, userID)
+	post, err := h.Service.UpdatePost(c.Request.Context(), uint(id), req, userID)
 	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		c.JSON(writeStatus(err, http.StatusForbidden), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, post)
@@ -159,7 +418,7 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 	userID := uint(parsed)
-	if err := h.Service.DeletePost(uint(id), userID); err != nil {
+	if err := h.Service.DeletePost(c.Request.Context(), uint(id), userID); err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
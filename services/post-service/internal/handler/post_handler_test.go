@@ -2,8 +2,10 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -16,24 +18,59 @@ import (
 type stubPostService struct {
 	createPostFn       func(req model.CreatePostRequest, authorID uint) (*domain.Post, error)
 	getPostFn          func(id uint) (*domain.Post, error)
-	getPostsByFilterFn func(filter model.PostFilter) ([]*domain.Post, error)
+	getPostBySlugFn    func(slug string) (*domain.Post, error)
+	getPostForAuthorFn func(id, authorID uint) (*domain.Post, error)
+	getPostsByFilterFn func(filter model.PostFilter) (*domain.PostPage, error)
+	searchPostsFn      func(query model.SearchQuery) (*domain.SearchResult, error)
+	relatedPostsFn     func(id uint, limit int) ([]*domain.RelatedPost, error)
 	updatePostFn       func(id uint, req model.UpdatePostRequest, authorID uint) (*domain.Post, error)
 	deletePostFn       func(id, authorID uint) error
 	listTagsFn         func() ([]*domain.Tag, error)
+	listRevisionsFn    func(postID, authorID uint) ([]*domain.PostRevision, error)
+	getRevisionFn      func(postID, revisionID, authorID uint) (*domain.PostRevision, error)
+	diffRevisionsFn    func(postID, fromID, toID, authorID uint) (*domain.RevisionDiff, error)
+	restoreRevisionFn  func(postID, revisionID, authorID uint) (*domain.Post, error)
 }
 
-func (s *stubPostService) CreatePost(req model.CreatePostRequest, authorID uint) (*domain.Post, error) {
+func (s *stubPostService) CreatePost(_ context.Context, req model.CreatePostRequest, authorID uint) (*domain.Post, error) {
 	return s.createPostFn(req, authorID)
 }
 func (s *stubPostService) GetPost(id uint) (*domain.Post, error) { return s.getPostFn(id) }
-func (s *stubPostService) GetPostsByFilter(filter model.PostFilter) ([]*domain.Post, error) {
+func (s *stubPostService) GetPostBySlug(slug string) (*domain.Post, error) {
+	return s.getPostBySlugFn(slug)
+}
+func (s *stubPostService) GetPostForAuthor(id, authorID uint) (*domain.Post, error) {
+	return s.getPostForAuthorFn(id, authorID)
+}
+func (s *stubPostService) GetPostsByFilter(filter model.PostFilter) (*domain.PostPage, error) {
 	return s.getPostsByFilterFn(filter)
 }
-func (s *stubPostService) UpdatePost(id uint, req model.UpdatePostRequest, authorID uint) (*domain.Post, error) {
+func (s *stubPostService) SearchPosts(query model.SearchQuery) (*domain.SearchResult, error) {
+	return s.searchPostsFn(query)
+}
+func (s *stubPostService) RelatedPosts(id uint, limit int) ([]*domain.RelatedPost, error) {
+	return s.relatedPostsFn(id, limit)
+}
+func (s *stubPostService) UpdatePost(_ context.Context, id uint, req model.UpdatePostRequest, authorID uint) (*domain.Post, error) {
 	return s.updatePostFn(id, req, authorID)
 }
-func (s *stubPostService) DeletePost(id, authorID uint) error { return s.deletePostFn(id, authorID) }
-func (s *stubPostService) ListTags() ([]*domain.Tag, error)   { return s.listTagsFn() }
+func (s *stubPostService) DeletePost(_ context.Context, id, authorID uint) error {
+	return s.deletePostFn(id, authorID)
+}
+func (s *stubPostService) ListTags() ([]*domain.Tag, error) { return s.listTagsFn() }
+func (s *stubPostService) ListRevisions(postID, authorID uint) ([]*domain.PostRevision, error) {
+	return s.listRevisionsFn(postID, authorID)
+}
+func (s *stubPostService) GetRevision(postID, revisionID, authorID uint) (*domain.PostRevision, error) {
+	return s.getRevisionFn(postID, revisionID, authorID)
+}
+func (s *stubPostService) DiffRevisions(postID, fromID, toID, authorID uint) (*domain.RevisionDiff, error) {
+	return s.diffRevisionsFn(postID, fromID, toID, authorID)
+}
+func (s *stubPostService) RestoreRevision(_ context.Context, postID, revisionID, authorID uint) (*domain.Post, error) {
+	return s.restoreRevisionFn(postID, revisionID, authorID)
+}
+func (s *stubPostService) RunSchedule(_ context.Context) (int, error) { return 0, nil }
 
 func jsonReq(t *testing.T, method, path string, payload any) *http.Request {
 	t.Helper()
@@ -59,6 +96,9 @@ func TestCreatePost(t *testing.T) {
 		{"missing user", model.CreatePostRequest{Title: "t", Content: "c"}, "", nil, http.StatusUnauthorized},
 		{"invalid user", model.CreatePostRequest{Title: "t", Content: "c"}, "x", nil, http.StatusUnauthorized},
 		{"service error", model.CreatePostRequest{Title: "t", Content: "c"}, "1", errors.New("fail"), http.StatusInternalServerError},
+		{"invalid slug", model.CreatePostRequest{Title: "t", Content: "c", Slug: "123"}, "1", domain.ErrInvalidSlug, http.StatusBadRequest},
+		{"slug taken", model.CreatePostRequest{Title: "t", Content: "c", Slug: "taken"}, "1", domain.ErrSlugTaken, http.StatusConflict},
+		{"invalid schedule", model.CreatePostRequest{Title: "t", Content: "c", PublishAt: "soon"}, "1", domain.ErrInvalidSchedule, http.StatusBadRequest},
 		{"success", model.CreatePostRequest{Title: "t", Content: "c"}, "1", nil, http.StatusCreated},
 	}
 	for _, tc := range tests {
@@ -71,7 +111,7 @@ func TestCreatePost(t *testing.T) {
 					return &domain.Post{ID: 1, Title: req.Title}, nil
 				},
 				getPostFn:          func(id uint) (*domain.Post, error) { return nil, nil },
-				getPostsByFilterFn: func(filter model.PostFilter) ([]*domain.Post, error) { return nil, nil },
+				getPostsByFilterFn: func(filter model.PostFilter) (*domain.PostPage, error) { return nil, nil },
 				updatePostFn:       func(id uint, req model.UpdatePostRequest, authorID uint) (*domain.Post, error) { return nil, nil },
 				deletePostFn:       func(id, authorID uint) error { return nil },
 				listTagsFn:         func() ([]*domain.Tag, error) { return nil, nil },
@@ -109,7 +149,7 @@ func TestGetPost(t *testing.T) {
 			}
 			return nil, errors.New("not found")
 		},
-		getPostsByFilterFn: func(filter model.PostFilter) ([]*domain.Post, error) { return nil, nil },
+		getPostsByFilterFn: func(filter model.PostFilter) (*domain.PostPage, error) { return nil, nil },
 		updatePostFn:       func(id uint, req model.UpdatePostRequest, authorID uint) (*domain.Post, error) { return nil, nil },
 		deletePostFn:       func(id, authorID uint) error { return nil },
 		listTagsFn:         func() ([]*domain.Tag, error) { return nil, nil },
@@ -144,15 +184,198 @@ func TestGetPost(t *testing.T) {
 	}
 }
 
+func TestGetPostBySlug(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := &stubPostService{
+		getPostBySlugFn: func(slug string) (*domain.Post, error) {
+			if slug == "hello-world" || slug == "old-hello" {
+				return &domain.Post{ID: 1, Slug: "hello-world"}, nil
+			}
+			return nil, errors.New("post not found")
+		},
+	}
+	h := NewPostHandler(svc)
+	r := gin.New()
+	r.GET("/posts/by-slug/:slug", h.GetPostBySlug)
+
+	for path, want := range map[string]int{
+		"/posts/by-slug/hello-world": http.StatusOK,
+		"/posts/by-slug/old-hello":   http.StatusOK,
+		"/posts/by-slug/missing":     http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Fatalf("%s: want %d got %d", path, want, w.Code)
+		}
+		if want == http.StatusOK {
+			var post domain.Post
+			_ = json.Unmarshal(w.Body.Bytes(), &post)
+			if post.Slug != "hello-world" {
+				t.Fatalf("%s: expected the current slug, got %q", path, post.Slug)
+			}
+		}
+	}
+}
+
+func TestSearchPosts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var seen model.SearchQuery
+	svc := &stubPostService{
+		searchPostsFn: func(query model.SearchQuery) (*domain.SearchResult, error) {
+			seen = query
+			if query.Text == "" {
+				return nil, domain.ErrEmptySearch
+			}
+			if query.Text == "boom" {
+				return nil, errors.New("db down")
+			}
+			return &domain.SearchResult{Query: query.Text, Total: 12, Hits: []*domain.SearchHit{
+				{Post: &domain.Post{ID: 4}, Rank: 0.5, Snippet: "a <mark>go</mark> post"},
+			}}, nil
+		},
+	}
+	h := NewPostHandler(svc)
+	r := gin.New()
+	r.GET("/posts/search", h.SearchPosts)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts/search?q=go&tag=dev&limit=5&offset=10", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200 got %d", w.Code)
+	}
+	if seen.Text != "go" || seen.Tag == nil || *seen.Tag != "dev" || seen.Limit != 5 || seen.Offset != 10 {
+		t.Fatalf("unexpected search query %+v", seen)
+	}
+	var result domain.SearchResult
+	_ = json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Total != 12 || len(result.Hits) != 1 || result.Hits[0].Post.ID != 4 || result.Hits[0].Snippet != "a <mark>go</mark> post" {
+		t.Fatalf("unexpected search result %+v", result)
+	}
+
+	for path, want := range map[string]int{
+		"/posts/search":        http.StatusBadRequest,
+		"/posts/search?q=boom": http.StatusInternalServerError,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Fatalf("%s: want %d got %d", path, want, w.Code)
+		}
+	}
+}
+
+func TestGetRelatedPosts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var seenLimit int
+	svc := &stubPostService{
+		relatedPostsFn: func(id uint, limit int) ([]*domain.RelatedPost, error) {
+			seenLimit = limit
+			switch id {
+			case 4:
+				return []*domain.RelatedPost{{Post: &domain.Post{ID: 9}, Score: 1.2, SharedTags: 1}}, nil
+			case 5:
+				return nil, errors.New("db down")
+			default:
+				return nil, domain.ErrPostNotFound
+			}
+		},
+	}
+	h := NewPostHandler(svc)
+	r := gin.New()
+	r.GET("/posts/:id/related", h.GetRelatedPosts)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts/4/related?limit=5", nil))
+	if w.Code != http.StatusOK || seenLimit != 5 {
+		t.Fatalf("want 200 with limit 5, got %d limit=%d", w.Code, seenLimit)
+	}
+	var related []*domain.RelatedPost
+	_ = json.Unmarshal(w.Body.Bytes(), &related)
+	if len(related) != 1 || related[0].Post.ID != 9 || related[0].SharedTags != 1 {
+		t.Fatalf("unexpected related posts %s", w.Body.String())
+	}
+
+	for path, want := range map[string]int{
+		"/posts/x/related": http.StatusBadRequest,
+		"/posts/3/related": http.StatusNotFound,
+		"/posts/5/related": http.StatusInternalServerError,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Fatalf("%s: want %d got %d", path, want, w.Code)
+		}
+	}
+	// without a limit the service picks its default
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/posts/4/related", nil))
+	if seenLimit != 0 {
+		t.Fatalf("expected no limit, got %d", seenLimit)
+	}
+}
+
+func TestGetDrafts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var seenFilter model.PostFilter
+	svc := &stubPostService{
+		getPostsByFilterFn: func(filter model.PostFilter) (*domain.PostPage, error) {
+			seenFilter = filter
+			return &domain.PostPage{Posts: []*domain.Post{{ID: 3}}}, nil
+		},
+		getPostForAuthorFn: func(id, authorID uint) (*domain.Post, error) {
+			if id == 3 && authorID == 7 {
+				return &domain.Post{ID: 3, AuthorID: 7}, nil
+			}
+			return nil, errors.New("post not found")
+		},
+	}
+	h := NewPostHandler(svc)
+	r := gin.New()
+	r.GET("/drafts", h.GetDrafts)
+	r.GET("/drafts/:id", h.GetDraft)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/drafts", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("want 401 without a user, got %d", w.Code)
+	}
+
+	// the author filter is the caller, whatever the query says
+	req := httptest.NewRequest(http.MethodGet, "/drafts?author_id=1&limit=5", nil)
+	req.Header.Set("X-User-Id", "7")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200 got %d", w.Code)
+	}
+	if seenFilter.AuthorID == nil || *seenFilter.AuthorID != 7 || seenFilter.Published == nil || *seenFilter.Published || seenFilter.Limit != 5 {
+		t.Fatalf("unexpected drafts filter %+v", seenFilter)
+	}
+
+	for path, want := range map[string]int{
+		"/drafts/x": http.StatusBadRequest,
+		"/drafts/3": http.StatusOK,
+		"/drafts/4": http.StatusNotFound,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-User-Id", "7")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("%s: want %d got %d", path, want, w.Code)
+		}
+	}
+}
+
 func TestGetPostsAndTags(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	var seenFilter model.PostFilter
 	svc := &stubPostService{
 		createPostFn: func(req model.CreatePostRequest, authorID uint) (*domain.Post, error) { return nil, nil },
 		getPostFn:    func(id uint) (*domain.Post, error) { return nil, nil },
-		getPostsByFilterFn: func(filter model.PostFilter) ([]*domain.Post, error) {
+		getPostsByFilterFn: func(filter model.PostFilter) (*domain.PostPage, error) {
 			seenFilter = filter
-			return []*domain.Post{{ID: 1}}, nil
+			return &domain.PostPage{Posts: []*domain.Post{{ID: 1}}, TotalCount: 3, NextCursor: "n1"}, nil
 		},
 		updatePostFn: func(id uint, req model.UpdatePostRequest, authorID uint) (*domain.Post, error) { return nil, nil },
 		deletePostFn: func(id, authorID uint) error { return nil },
@@ -165,17 +388,24 @@ func TestGetPostsAndTags(t *testing.T) {
 	r.GET("/posts", h.GetPosts)
 	r.GET("/tags", h.GetTags)
 
-	req := httptest.NewRequest(http.MethodGet, "/posts?author_id=9&published=true&limit=20&offset=10&search=abc&tag=go", nil)
+	req := httptest.NewRequest(http.MethodGet, "/posts?author_id=9&published=false&limit=20&offset=10&search=abc&tag=go", nil)
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 	if w.Code != http.StatusOK {
 		t.Fatalf("want 200 got %d", w.Code)
 	}
+	if got := w.Header().Get("X-Total-Count"); got != "3" {
+		t.Fatalf("want total count 3, got %q", got)
+	}
+	// the next page keeps the filters and replaces the offset with the cursor
+	if got := w.Header().Get("Link"); got != `<?author_id=9&cursor=n1&limit=20&published=false&search=abc&tag=go>; rel="next"` {
+		t.Fatalf("unexpected Link header %q", got)
+	}
 	if seenFilter.AuthorID == nil || *seenFilter.AuthorID != 9 {
 		t.Fatalf("author filter not parsed")
 	}
 	if seenFilter.Published == nil || !*seenFilter.Published {
-		t.Fatalf("published filter not parsed")
+		t.Fatalf("public list must only ask for published posts")
 	}
 	if seenFilter.Limit != 20 || seenFilter.Offset != 10 {
 		t.Fatalf("limit/offset not parsed")
@@ -184,6 +414,12 @@ func TestGetPostsAndTags(t *testing.T) {
 		t.Fatalf("search/tag not parsed")
 	}
 
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts?cursor=n1", nil))
+	if seenFilter.Cursor != "n1" {
+		t.Fatalf("cursor not parsed")
+	}
+
 	tagReq := httptest.NewRequest(http.MethodGet, "/tags", nil)
 	tagW := httptest.NewRecorder()
 	r.ServeHTTP(tagW, tagReq)
@@ -204,7 +440,10 @@ func TestGetPostsAndTags_ServiceError(t *testing.T) {
 	svc := &stubPostService{
 		createPostFn: func(req model.CreatePostRequest, authorID uint) (*domain.Post, error) { return nil, nil },
 		getPostFn:    func(id uint) (*domain.Post, error) { return nil, nil },
-		getPostsByFilterFn: func(filter model.PostFilter) ([]*domain.Post, error) {
+		getPostsByFilterFn: func(filter model.PostFilter) (*domain.PostPage, error) {
+			if filter.Cursor != "" {
+				return nil, fmt.Errorf("failed to get posts: %w", domain.ErrInvalidCursor)
+			}
 			return nil, errors.New("list fail")
 		},
 		updatePostFn: func(id uint, req model.UpdatePostRequest, authorID uint) (*domain.Post, error) { return nil, nil },
@@ -224,6 +463,12 @@ func TestGetPostsAndTags_ServiceError(t *testing.T) {
 		t.Fatalf("want 500 got %d", w1.Code)
 	}
 
+	w1 = httptest.NewRecorder()
+	r.ServeHTTP(w1, httptest.NewRequest(http.MethodGet, "/posts?cursor=bad", nil))
+	if w1.Code != http.StatusBadRequest {
+		t.Fatalf("want 400 for a bad cursor, got %d", w1.Code)
+	}
+
 	w2 := httptest.NewRecorder()
 	r.ServeHTTP(w2, httptest.NewRequest(http.MethodGet, "/tags", nil))
 	if w2.Code != http.StatusInternalServerError {
@@ -237,7 +482,7 @@ func TestUpdateAndDeletePost(t *testing.T) {
 	svc := &stubPostService{
 		createPostFn: func(req model.CreatePostRequest, authorID uint) (*domain.Post, error) { return nil, nil },
 		getPostFn:    func(id uint) (*domain.Post, error) { return nil, nil },
-		getPostsByFilterFn: func(filter model.PostFilter) ([]*domain.Post, error) {
+		getPostsByFilterFn: func(filter model.PostFilter) (*domain.PostPage, error) {
 			return nil, nil
 		},
 		updatePostFn: func(id uint, req model.UpdatePostRequest, authorID uint) (*domain.Post, error) {
@@ -355,3 +600,79 @@ func TestUpdateAndDeletePost(t *testing.T) {
 		t.Fatalf("want 204 got %d", dw4.Code)
 	}
 }
+
+func TestRevisions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var seenFrom, seenTo uint
+	svc := &stubPostService{
+		listRevisionsFn: func(postID, authorID uint) ([]*domain.PostRevision, error) {
+			if authorID != 7 {
+				return nil, errors.New("post not found")
+			}
+			return []*domain.PostRevision{{ID: 2, PostID: postID}, {ID: 1, PostID: postID}}, nil
+		},
+		getRevisionFn: func(postID, revisionID, authorID uint) (*domain.PostRevision, error) {
+			if revisionID != 2 {
+				return nil, domain.ErrRevisionNotFound
+			}
+			return &domain.PostRevision{ID: 2, PostID: postID, Content: "c"}, nil
+		},
+		diffRevisionsFn: func(postID, fromID, toID, authorID uint) (*domain.RevisionDiff, error) {
+			seenFrom, seenTo = fromID, toID
+			return &domain.RevisionDiff{From: fromID, To: toID, Diff: "--- a\n+++ b\n"}, nil
+		},
+		restoreRevisionFn: func(postID, revisionID, authorID uint) (*domain.Post, error) {
+			if revisionID != 2 {
+				return nil, fmt.Errorf("failed: %w", domain.ErrRevisionNotFound)
+			}
+			return &domain.Post{ID: postID, Title: "restored"}, nil
+		},
+	}
+	h := NewPostHandler(svc)
+	r := gin.New()
+	r.GET("/posts/:id/revisions", h.ListRevisions)
+	r.GET("/posts/:id/revisions/:rev", h.GetRevision)
+	r.GET("/posts/:id/revisions/:rev/diff", h.DiffRevisions)
+	r.POST("/posts/:id/revisions/:rev/restore", h.RestoreRevision)
+
+	tests := []struct {
+		method, path, user string
+		want               int
+	}{
+		{http.MethodGet, "/posts/1/revisions", "", http.StatusUnauthorized},
+		{http.MethodGet, "/posts/x/revisions", "7", http.StatusBadRequest},
+		{http.MethodGet, "/posts/1/revisions", "8", http.StatusNotFound},
+		{http.MethodGet, "/posts/1/revisions", "7", http.StatusOK},
+		{http.MethodGet, "/posts/1/revisions/2", "7", http.StatusOK},
+		{http.MethodGet, "/posts/1/revisions/3", "7", http.StatusNotFound},
+		{http.MethodGet, "/posts/1/revisions/x", "7", http.StatusBadRequest},
+		{http.MethodGet, "/posts/1/revisions/2/diff?from=x", "7", http.StatusBadRequest},
+		{http.MethodPost, "/posts/1/revisions/3/restore", "7", http.StatusNotFound},
+		{http.MethodPost, "/posts/1/revisions/2/restore", "", http.StatusUnauthorized},
+		{http.MethodPost, "/posts/1/revisions/2/restore", "7", http.StatusOK},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.user != "" {
+			req.Header.Set("X-User-Id", tc.user)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s %s as %q: want %d got %d", tc.method, tc.path, tc.user, tc.want, w.Code)
+		}
+	}
+
+	for path, want := range map[string][2]uint{
+		"/posts/1/revisions/2/diff":        {0, 2},
+		"/posts/1/revisions/2/diff?from=1": {1, 2},
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-User-Id", "7")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || seenFrom != want[0] || seenTo != want[1] {
+			t.Fatalf("%s: got %d from=%d to=%d", path, w.Code, seenFrom, seenTo)
+		}
+	}
+}
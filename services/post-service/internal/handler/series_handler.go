@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+// SeriesHandler handles HTTP requests for post series.
+type SeriesHandler struct {
+	Service domain.SeriesService
+}
+
+// NewSeriesHandler creates a new SeriesHandler.
+func NewSeriesHandler(service domain.SeriesService) *SeriesHandler {
+	return &SeriesHandler{Service: service}
+}
+
+// ListSeries handles GET /series. Lists the series that have published parts.
+func (h *SeriesHandler) ListSeries(c *gin.Context) {
+	list, err := h.Service.ListSeries()
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	if list == nil {
+		list = []*domain.Series{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetSeries handles GET /series/:id. Returns a series with its published parts in
+// order.
+func (h *SeriesHandler) GetSeries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	series, err := h.Service.GetSeries(uint(id))
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, series)
+}
+
+// CreateSeries handles POST /series. Creates a series of the caller's posts.
+func (h *SeriesHandler) CreateSeries(c *gin.Context) {
+	var req model.CreateSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := userIDFrom(c)
+	if !ok {
+		return
+	}
+	series, err := h.Service.CreateSeries(c.Request.Context(), req, userID)
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, series)
+}
+
+// UpdateSeries handles PUT /series/:id. Changes the title, description or parts of
+// the caller's series; post_ids, when given, replaces the parts in the new order.
+func (h *SeriesHandler) UpdateSeries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req model.UpdateSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := userIDFrom(c)
+	if !ok {
+		return
+	}
+	series, err := h.Service.UpdateSeries(c.Request.Context(), uint(id), req, userID)
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, series)
+}
+
+// DeleteSeries handles DELETE /series/:id. Deletes the caller's series; its posts are
+// kept.
+func (h *SeriesHandler) DeleteSeries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	userID, ok := userIDFrom(c)
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteSeries(c.Request.Context(), uint(id), userID); err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
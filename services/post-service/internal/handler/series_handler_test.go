@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+type stubSeriesService struct {
+	listFn   func() ([]*domain.Series, error)
+	getFn    func(id uint) (*domain.Series, error)
+	createFn func(req model.CreateSeriesRequest, authorID uint) (*domain.Series, error)
+	updateFn func(id uint, req model.UpdateSeriesRequest, authorID uint) (*domain.Series, error)
+	deleteFn func(id, authorID uint) error
+}
+
+func (s *stubSeriesService) ListSeries() ([]*domain.Series, error)     { return s.listFn() }
+func (s *stubSeriesService) GetSeries(id uint) (*domain.Series, error) { return s.getFn(id) }
+func (s *stubSeriesService) CreateSeries(_ context.Context, req model.CreateSeriesRequest, authorID uint) (*domain.Series, error) {
+	return s.createFn(req, authorID)
+}
+func (s *stubSeriesService) UpdateSeries(_ context.Context, id uint, req model.UpdateSeriesRequest, authorID uint) (*domain.Series, error) {
+	return s.updateFn(id, req, authorID)
+}
+func (s *stubSeriesService) DeleteSeries(_ context.Context, id, authorID uint) error {
+	return s.deleteFn(id, authorID)
+}
+
+func TestSeriesReads(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := &stubSeriesService{
+		listFn: func() ([]*domain.Series, error) { return nil, nil },
+		getFn: func(id uint) (*domain.Series, error) {
+			if id != 1 {
+				return nil, domain.ErrSeriesNotFound
+			}
+			return &domain.Series{ID: 1, Title: "Go"}, nil
+		},
+	}
+	h := NewSeriesHandler(svc)
+	r := gin.New()
+	r.GET("/series", h.ListSeries)
+	r.GET("/series/:id", h.GetSeries)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/series/x", http.StatusBadRequest},
+		{"/series/2", http.StatusNotFound},
+		{"/series/1", http.StatusOK},
+	}
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != tc.want {
+			t.Fatalf("%s: want %d got %d", tc.path, tc.want, w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/series", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "[]" {
+		t.Fatalf("want an empty list, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSeriesWrites(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	owned := func(id, authorID uint) error {
+		if id != 1 || authorID != 7 {
+			return domain.ErrSeriesNotFound
+		}
+		return nil
+	}
+	svc := &stubSeriesService{
+		createFn: func(req model.CreateSeriesRequest, authorID uint) (*domain.Series, error) {
+			switch {
+			case len(req.PostIDs) > 0 && req.PostIDs[0] == 9:
+				return nil, domain.ErrSeriesConflict
+			case len(req.PostIDs) > 0 && req.PostIDs[0] == 8:
+				return nil, domain.ErrInvalidSeries
+			}
+			return &domain.Series{ID: 1, Title: req.Title, AuthorID: authorID}, nil
+		},
+		updateFn: func(id uint, req model.UpdateSeriesRequest, authorID uint) (*domain.Series, error) {
+			if err := owned(id, authorID); err != nil {
+				return nil, err
+			}
+			return &domain.Series{ID: id}, nil
+		},
+		deleteFn: owned,
+	}
+	h := NewSeriesHandler(svc)
+	r := gin.New()
+	r.POST("/series", h.CreateSeries)
+	r.PUT("/series/:id", h.UpdateSeries)
+	r.DELETE("/series/:id", h.DeleteSeries)
+
+	title := "Go"
+	tests := []struct {
+		method, path, user string
+		body               any
+		want               int
+	}{
+		{http.MethodPost, "/series", "7", model.CreateSeriesRequest{}, http.StatusBadRequest},
+		{http.MethodPost, "/series", "", model.CreateSeriesRequest{Title: "Go"}, http.StatusUnauthorized},
+		{http.MethodPost, "/series", "7", model.CreateSeriesRequest{Title: "Go", PostIDs: []uint{8}}, http.StatusBadRequest},
+		{http.MethodPost, "/series", "7", model.CreateSeriesRequest{Title: "Go", PostIDs: []uint{9}}, http.StatusConflict},
+		{http.MethodPost, "/series", "7", model.CreateSeriesRequest{Title: "Go", PostIDs: []uint{1, 2}}, http.StatusCreated},
+		{http.MethodPut, "/series/x", "7", model.UpdateSeriesRequest{Title: &title}, http.StatusBadRequest},
+		{http.MethodPut, "/series/1", "", model.UpdateSeriesRequest{Title: &title}, http.StatusUnauthorized},
+		{http.MethodPut, "/series/1", "8", model.UpdateSeriesRequest{Title: &title}, http.StatusNotFound},
+		{http.MethodPut, "/series/1", "7", model.UpdateSeriesRequest{Title: &title}, http.StatusOK},
+		{http.MethodDelete, "/series/1", "8", nil, http.StatusNotFound},
+		{http.MethodDelete, "/series/1", "7", nil, http.StatusNoContent},
+	}
+	for _, tc := range tests {
+		req := jsonReq(t, tc.method, tc.path, tc.body)
+		if tc.user != "" {
+			req.Header.Set("X-User-Id", tc.user)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s %s as %q: want %d got %d body=%s", tc.method, tc.path, tc.user, tc.want, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+// TagHandler handles HTTP requests for tag pages and tag administration. The gateway
+// only lets owners signed in with a session reach the /admin routes.
+type TagHandler struct {
+	Service domain.TagService
+}
+
+// NewTagHandler creates a new TagHandler.
+func NewTagHandler(service domain.TagService) *TagHandler {
+	return &TagHandler{Service: service}
+}
+
+// rejectPAT answers 403 to callers the gateway authenticated with a personal access
+// token, in case one reaches an admin route anyway.
+func rejectPAT(c *gin.Context) bool {
+	if c.GetHeader("X-Auth-Method") != "pat" {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "personal access tokens cannot manage tags"})
+	return true
+}
+
+// GetTag handles GET /tags/:name. Returns a tag of published posts, found by its name
+// in any case or by an alias, with its description and published post count.
+func (h *TagHandler) GetTag(c *gin.Context) {
+	tag, err := h.Service.GetTag(c.Param("name"))
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tag)
+}
+
+// ListAllTags handles GET /admin/tags. Lists every tag with its aliases and the count
+// of all its posts, drafts included.
+func (h *TagHandler) ListAllTags(c *gin.Context) {
+	if rejectPAT(c) {
+		return
+	}
+	tags, err := h.Service.ListAllTags()
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	if tags == nil {
+		tags = []*domain.Tag{}
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+// UpdateTag handles PUT /admin/tags/:id. Renames a tag or changes its description.
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	if rejectPAT(c) {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req model.UpdateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tag, err := h.Service.UpdateTag(c.Request.Context(), uint(id), req)
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tag)
+}
+
+// MergeTag handles POST /admin/tags/:id/merge. Moves the tag's posts and aliases to
+// into_id, deletes it and returns the tag it was merged into.
+func (h *TagHandler) MergeTag(c *gin.Context) {
+	if rejectPAT(c) {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req model.MergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tag, err := h.Service.MergeTag(c.Request.Context(), uint(id), req.IntoID)
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tag)
+}
+
+// AddAlias handles POST /admin/tags/:id/aliases. Adds another spelling of the tag.
+func (h *TagHandler) AddAlias(c *gin.Context) {
+	if rejectPAT(c) {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req model.TagAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tag, err := h.Service.AddAlias(c.Request.Context(), uint(id), req.Alias)
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, tag)
+}
+
+// DeleteAlias handles DELETE /admin/tags/:id/aliases/:alias.
+func (h *TagHandler) DeleteAlias(c *gin.Context) {
+	if rejectPAT(c) {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	tag, err := h.Service.DeleteAlias(c.Request.Context(), uint(id), c.Param("alias"))
+	if err != nil {
+		c.JSON(writeStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tag)
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+type stubTagService struct {
+	getFn         func(name string) (*domain.Tag, error)
+	listAllFn     func() ([]*domain.Tag, error)
+	updateFn      func(id uint, req model.UpdateTagRequest) (*domain.Tag, error)
+	mergeFn       func(id, intoID uint) (*domain.Tag, error)
+	addAliasFn    func(id uint, alias string) (*domain.Tag, error)
+	deleteAliasFn func(id uint, alias string) (*domain.Tag, error)
+}
+
+func (s *stubTagService) GetTag(name string) (*domain.Tag, error) { return s.getFn(name) }
+func (s *stubTagService) ListAllTags() ([]*domain.Tag, error)     { return s.listAllFn() }
+func (s *stubTagService) UpdateTag(_ context.Context, id uint, req model.UpdateTagRequest) (*domain.Tag, error) {
+	return s.updateFn(id, req)
+}
+func (s *stubTagService) MergeTag(_ context.Context, id, intoID uint) (*domain.Tag, error) {
+	return s.mergeFn(id, intoID)
+}
+func (s *stubTagService) AddAlias(_ context.Context, id uint, alias string) (*domain.Tag, error) {
+	return s.addAliasFn(id, alias)
+}
+func (s *stubTagService) DeleteAlias(_ context.Context, id uint, alias string) (*domain.Tag, error) {
+	return s.deleteAliasFn(id, alias)
+}
+
+func TestTagHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var seenName, seenAlias string
+	svc := &stubTagService{
+		getFn: func(name string) (*domain.Tag, error) {
+			seenName = name
+			if name != "machine learning" {
+				return nil, domain.ErrTagNotFound
+			}
+			return &domain.Tag{ID: 1, Name: "Machine Learning"}, nil
+		},
+		listAllFn: func() ([]*domain.Tag, error) { return nil, nil },
+		updateFn: func(id uint, req model.UpdateTagRequest) (*domain.Tag, error) {
+			if req.Name != nil && *req.Name == "golang" {
+				return nil, domain.ErrTagConflict
+			}
+			return &domain.Tag{ID: id}, nil
+		},
+		mergeFn: func(id, intoID uint) (*domain.Tag, error) {
+			if id == intoID {
+				return nil, domain.ErrInvalidTag
+			}
+			return &domain.Tag{ID: intoID}, nil
+		},
+		addAliasFn: func(id uint, alias string) (*domain.Tag, error) {
+			return &domain.Tag{ID: id, Aliases: []string{alias}}, nil
+		},
+		deleteAliasFn: func(id uint, alias string) (*domain.Tag, error) {
+			seenAlias = alias
+			return &domain.Tag{ID: id}, nil
+		},
+	}
+	h := NewTagHandler(svc)
+	r := gin.New()
+	r.GET("/tags/:name", h.GetTag)
+	r.GET("/admin/tags", h.ListAllTags)
+	r.PUT("/admin/tags/:id", h.UpdateTag)
+	r.POST("/admin/tags/:id/merge", h.MergeTag)
+	r.POST("/admin/tags/:id/aliases", h.AddAlias)
+	r.DELETE("/admin/tags/:id/aliases/:alias", h.DeleteAlias)
+
+	golang := "golang"
+	tests := []struct {
+		method, path string
+		body         any
+		want         int
+	}{
+		{http.MethodGet, "/tags/machine%20learning", nil, http.StatusOK},
+		{http.MethodGet, "/tags/rust", nil, http.StatusNotFound},
+		{http.MethodGet, "/admin/tags", nil, http.StatusOK},
+		{http.MethodPut, "/admin/tags/x", model.UpdateTagRequest{}, http.StatusBadRequest},
+		{http.MethodPut, "/admin/tags/1", model.UpdateTagRequest{Name: &golang}, http.StatusConflict},
+		{http.MethodPut, "/admin/tags/1", model.UpdateTagRequest{}, http.StatusOK},
+		{http.MethodPost, "/admin/tags/1/merge", model.MergeTagRequest{}, http.StatusBadRequest},
+		{http.MethodPost, "/admin/tags/1/merge", model.MergeTagRequest{IntoID: 1}, http.StatusBadRequest},
+		{http.MethodPost, "/admin/tags/2/merge", model.MergeTagRequest{IntoID: 1}, http.StatusOK},
+		{http.MethodPost, "/admin/tags/1/aliases", model.TagAliasRequest{}, http.StatusBadRequest},
+		{http.MethodPost, "/admin/tags/1/aliases", model.TagAliasRequest{Alias: "golang"}, http.StatusCreated},
+		{http.MethodDelete, "/admin/tags/1/aliases/go%20lang", nil, http.StatusOK},
+	}
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, jsonReq(t, tc.method, tc.path, tc.body))
+		if w.Code != tc.want {
+			t.Fatalf("%s %s: want %d got %d body=%s", tc.method, tc.path, tc.want, w.Code, w.Body.String())
+		}
+	}
+	if seenName != "rust" || seenAlias != "go lang" {
+		t.Fatalf("path parameters should be unescaped, got %q %q", seenName, seenAlias)
+	}
+}
+
+func TestTagHandler_RejectsPersonalAccessTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	called := false
+	svc := &stubTagService{
+		updateFn: func(id uint, req model.UpdateTagRequest) (*domain.Tag, error) {
+			called = true
+			return &domain.Tag{ID: id}, nil
+		},
+		mergeFn: func(id, intoID uint) (*domain.Tag, error) {
+			called = true
+			return &domain.Tag{ID: intoID}, nil
+		},
+	}
+	h := NewTagHandler(svc)
+	r := gin.New()
+	r.PUT("/admin/tags/:id", h.UpdateTag)
+	r.POST("/admin/tags/:id/merge", h.MergeTag)
+
+	golang := "golang"
+	tests := []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodPut, "/admin/tags/1", model.UpdateTagRequest{Name: &golang}},
+		{http.MethodPost, "/admin/tags/2/merge", model.MergeTagRequest{IntoID: 1}},
+	}
+	for _, tc := range tests {
+		req := jsonReq(t, tc.method, tc.path, tc.body)
+		req.Header.Set("X-User-Id", "1")
+		req.Header.Set("X-User-Role", "owner")
+		req.Header.Set("X-Auth-Method", "pat")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("%s %s with an owner PAT: want 403 got %d", tc.method, tc.path, w.Code)
+		}
+	}
+	if called {
+		t.Fatal("tag service should not be called for a personal access token")
+	}
+}
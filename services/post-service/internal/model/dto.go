@@ -20,18 +20,24 @@ type UploadImageResponse struct {
 // CreatePostRequest represents the request payload for creating a new post
 type CreatePostRequest struct {
 	Title         string   `json:"title" binding:"required,min=1,max=200"`
+	Slug          string   `json:"slug,omitempty"` // generated from the title when empty
 	Content       string   `json:"content" binding:"required"`
 	ThumbnailData string   `json:"thumbnail_data,omitempty"`
 	Published     bool     `json:"published"`
+	PublishAt     string   `json:"publish_at,omitempty"`   // RFC 3339; publishes a draft then
+	UnpublishAt   string   `json:"unpublish_at,omitempty"` // RFC 3339
 	Tags          []string `json:"tags,omitempty"`
 }
 
 // UpdatePostRequest represents the request payload for updating an existing post
 type UpdatePostRequest struct {
 	Title         *string   `json:"title,omitempty" binding:"omitempty,min=1,max=200"`
+	Slug          *string   `json:"slug,omitempty"` // "" generates it again from the title
 	Content       *string   `json:"content,omitempty"`
 	ThumbnailData *string   `json:"thumbnail_data,omitempty"`
 	Published     *bool     `json:"published,omitempty"`
+	PublishAt     *string   `json:"publish_at,omitempty"`   // RFC 3339; "" cancels the scheduled publish
+	UnpublishAt   *string   `json:"unpublish_at,omitempty"` // RFC 3339; "" cancels the scheduled unpublish
 	Tags          *[]string `json:"tags,omitempty"`
 }
 
@@ -44,4 +50,67 @@ type PostFilter struct {
 	OrderBy   string  `json:"order_by"`
 	Search    *string `json:"search"`
 	Tag       *string `json:"tag"`
+	// Cursor continues a list from the next_cursor or prev_cursor of another page
+	Cursor string `json:"cursor"`
+}
+
+// SearchQuery represents a full-text search over published posts
+type SearchQuery struct {
+	Text   string  `json:"q"`
+	Tag    *string `json:"tag"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
+
+// CreateCommentRequest represents a comment submitted on a post
+type CreateCommentRequest struct {
+	ParentID *uint  `json:"parent_id,omitempty"` // replies to an approved comment of the same post
+	Name     string `json:"name,omitempty"`      // required from anonymous readers
+	Email    string `json:"email,omitempty"`     // required from anonymous readers; only the post's author sees it
+	Body     string `json:"body" binding:"required"`
+	Website  string `json:"website,omitempty"` // honeypot: hidden from people, so only bots fill it in
+}
+
+// Commenter identifies who submits a comment: a signed-in reader or, with a nil
+// UserID, an anonymous one
+type Commenter struct {
+	UserID   *uint
+	Username string
+	IP       string
+}
+
+// ModerateCommentRequest represents a moderation decision on a comment
+type ModerateCommentRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// CreateSeriesRequest represents the request payload for creating a series of posts
+type CreateSeriesRequest struct {
+	Title       string `json:"title" binding:"required,min=1,max=200"`
+	Description string `json:"description,omitempty"`
+	PostIDs     []uint `json:"post_ids,omitempty"` // the parts, in reading order
+}
+
+// UpdateSeriesRequest represents the request payload for updating a series
+type UpdateSeriesRequest struct {
+	Title       *string `json:"title,omitempty" binding:"omitempty,min=1,max=200"`
+	Description *string `json:"description,omitempty"`
+	PostIDs     *[]uint `json:"post_ids,omitempty"` // replaces the parts
+}
+
+// UpdateTagRequest represents the request payload for renaming a tag or changing its
+// description
+type UpdateTagRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+// MergeTagRequest represents the request payload for merging a tag into another
+type MergeTagRequest struct {
+	IntoID uint `json:"into_id" binding:"required"`
+}
+
+// TagAliasRequest represents the request payload for adding an alias to a tag
+type TagAliasRequest struct {
+	Alias string `json:"alias" binding:"required"`
 }
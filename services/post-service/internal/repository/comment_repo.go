@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+)
+
+type commentRepository struct {
+	db *gorm.DB
+}
+
+// NewCommentRepository creates a new CommentRepository with the given GORM DB instance.
+func NewCommentRepository(db *gorm.DB) domain.CommentRepository {
+	return &commentRepository{db: db}
+}
+
+// Create inserts a new comment.
+func (r *commentRepository) Create(comment *domain.Comment) error {
+	if err := r.db.Create(comment).Error; err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns one comment.
+func (r *commentRepository) GetByID(id uint) (*domain.Comment, error) {
+	var comment domain.Comment
+	if err := r.db.Take(&comment, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	return &comment, nil
+}
+
+// ListForPost returns the post's comments with status, oldest first.
+func (r *commentRepository) ListForPost(postID uint, status string) ([]*domain.Comment, error) {
+	var comments []*domain.Comment
+	if err := r.db.Where("post_id = ? AND status = ?", postID, status).Order("id").Find(&comments).Error; err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	return comments, nil
+}
+
+// ListForAuthor returns the comments with status on the author's posts, newest first.
+func (r *commentRepository) ListForAuthor(authorID uint, status string, limit int) ([]*domain.Comment, error) {
+	var comments []*domain.Comment
+	if err := r.db.Select("comments.*, posts.title AS post_title").
+		Joins("JOIN posts ON posts.id = comments.post_id").
+		Where("posts.author_id = ? AND comments.status = ?", authorID, status).
+		Order("comments.id DESC").Limit(limit).Find(&comments).Error; err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	return comments, nil
+}
+
+// SetStatus moves a comment into or out of the moderation queue.
+func (r *commentRepository) SetStatus(id uint, status string) error {
+	result := r.db.Model(&domain.Comment{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update comment: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrCommentNotFound
+	}
+	return nil
+}
+
+// Delete removes a comment; its replies go with it.
+func (r *commentRepository) Delete(id uint) error {
+	result := r.db.Delete(&domain.Comment{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete comment: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrCommentNotFound
+	}
+	return nil
+}
+
+// CountRecent counts the comments submitted from ip after since.
+func (r *commentRepository) CountRecent(ip string, since time.Time) (int64, error) {
+	var n int64
+	if err := r.db.Model(&domain.Comment{}).Where("ip = ? AND created_at > ?", ip, since).Count(&n).Error; err != nil {
+		return 0, fmt.Errorf("failed to count comments: %w", err)
+	}
+	return n, nil
+}
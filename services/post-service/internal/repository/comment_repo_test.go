@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+)
+
+func setupMockCommentRepo(t *testing.T) (*commentRepository, sqlmock.Sqlmock, func()) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	mock.MatchExpectationsInOrder(true)
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm open: %v", err)
+	}
+	cleanup := func() { _ = sqlDB.Close() }
+	return &commentRepository{db: gdb}, mock, cleanup
+}
+
+func TestCommentRepository_CreateAndGet(t *testing.T) {
+	repo, mock, cleanup := setupMockCommentRepo(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "comments" ("post_id","parent_id","user_id","author_name","author_email","body","status","ip","created_at","updated_at") VALUES`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectCommit()
+	c := &domain.Comment{PostID: 1, AuthorName: "kim", AuthorEmail: "kim@example.com", Body: "hi", Status: domain.CommentPending, IP: "10.0.0.1"}
+	if err := repo.Create(c); err != nil || c.ID != 5 {
+		t.Fatalf("create: id=%d err=%v", c.ID, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "comments" WHERE "comments"."id" = $1 LIMIT $2`)).
+		WithArgs(uint(5), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_name", "body", "status"}).AddRow(5, 1, "kim", "hi", "pending"))
+	got, err := repo.GetByID(5)
+	if err != nil || got.PostID != 1 || got.Status != domain.CommentPending {
+		t.Fatalf("get: %+v err=%v", got, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "comments" WHERE "comments"."id" = $1 LIMIT $2`)).
+		WithArgs(uint(6), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if _, err := repo.GetByID(6); !errors.Is(err, domain.ErrCommentNotFound) {
+		t.Fatalf("expected ErrCommentNotFound, got %v", err)
+	}
+	assertPostMock(t, mock)
+}
+
+func TestCommentRepository_Lists(t *testing.T) {
+	repo, mock, cleanup := setupMockCommentRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "comments" WHERE post_id = $1 AND status = $2 ORDER BY id`)).
+		WithArgs(uint(3), domain.CommentApproved).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id"}).AddRow(1, 3).AddRow(2, 3))
+	comments, err := repo.ListForPost(3, domain.CommentApproved)
+	if err != nil || len(comments) != 2 {
+		t.Fatalf("list for post: %d err=%v", len(comments), err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT comments.*, posts.title AS post_title FROM "comments" JOIN posts ON posts.id = comments.post_id WHERE posts.author_id = $1 AND comments.status = $2 ORDER BY comments.id DESC LIMIT $3`)).
+		WithArgs(uint(7), domain.CommentPending, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "post_title"}).AddRow(9, 3, "Hello"))
+	comments, err = repo.ListForAuthor(7, domain.CommentPending, 20)
+	if err != nil || len(comments) != 1 || comments[0].PostTitle != "Hello" {
+		t.Fatalf("list for author: %+v err=%v", comments, err)
+	}
+	assertPostMock(t, mock)
+}
+
+func TestCommentRepository_SetStatusAndDelete(t *testing.T) {
+	repo, mock, cleanup := setupMockCommentRepo(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "comments" SET "status"=$1,"updated_at"=$2 WHERE id = $3`)).
+		WithArgs(domain.CommentApproved, sqlmock.AnyArg(), uint(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.SetStatus(5, domain.CommentApproved); err != nil {
+		t.Fatalf("set status: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "comments" SET "status"=$1,"updated_at"=$2 WHERE id = $3`)).
+		WithArgs(domain.CommentSpam, sqlmock.AnyArg(), uint(6)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	if err := repo.SetStatus(6, domain.CommentSpam); !errors.Is(err, domain.ErrCommentNotFound) {
+		t.Fatalf("expected ErrCommentNotFound, got %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "comments" WHERE "comments"."id" = $1`)).
+		WithArgs(uint(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.Delete(5); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	assertPostMock(t, mock)
+}
+
+func TestCommentRepository_CountRecent(t *testing.T) {
+	repo, mock, cleanup := setupMockCommentRepo(t)
+	defer cleanup()
+
+	since := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "comments" WHERE ip = $1 AND created_at > $2`)).
+		WithArgs("10.0.0.1", since).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+	if n, err := repo.CountRecent("10.0.0.1", since); err != nil || n != 4 {
+		t.Fatalf("count: %d err=%v", n, err)
+	}
+	assertPostMock(t, mock)
+}
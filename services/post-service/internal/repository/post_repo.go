@@ -2,13 +2,63 @@ package repository
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/util"
 )
 
+// scheduleLockKey is the transaction-level advisory lock held while applying the
+// publishing schedule, so only one replica applies it at a time.
+const scheduleLockKey int64 = 0x7075626c697368 // "publish"
+
+// Full-text search over the columns migration 0005 adds. A post matches when its
+// search_vector matches the query in either text search configuration, or when the
+// query is a substring of its text, which the trigram index serves.
+const (
+	searchTSQuery   = "(websearch_to_tsquery('simple', @q) || websearch_to_tsquery('english', @q))"
+	searchDocument  = "post_search_document(posts.title, posts.content, posts.en_title, posts.en_content)"
+	searchCondition = "(posts.search_vector @@ " + searchTSQuery + " OR " + searchDocument + " ILIKE @like)"
+	// substring matches rank below lexeme matches, by how close the query is to a word
+	searchRank = "ts_rank(posts.search_vector, " + searchTSQuery + ") + 0.1 * word_similarity(@q, " + searchDocument + ")"
+	// the content snippet comes from the translation when only the translation matched
+	searchSnippet = "CASE WHEN to_tsvector('simple', post_search_text(posts.content)) @@ " + searchTSQuery +
+		" OR post_search_text(posts.content) ILIKE @like OR post_search_text(posts.en_content) = ''" +
+		" THEN ts_headline('simple', post_search_text(posts.content), " + searchTSQuery + ", @snippet)" +
+		" ELSE ts_headline('english', post_search_text(posts.en_content), " + searchTSQuery + ", @snippet) END"
+	searchTitle = "ts_headline('simple', posts.title, " + searchTSQuery + ", @title)"
+)
+
+// Related posts of post s. Each shared tag counts 1, and the trigram similarity of the
+// titles and of the whole texts adds up to 2 more, so tags lead the ranking and the
+// text orders posts with as many shared tags, or none.
+const (
+	relatedSharedTags = "(SELECT count(*) FROM post_tags a JOIN post_tags b ON b.tag_id = a.tag_id WHERE a.post_id = p.id AND b.post_id = s.id)"
+	relatedSimilarity = "similarity(p.title, s.title) + similarity(post_search_document(p.title, p.content, p.en_title, p.en_content)," +
+		" post_search_document(s.title, s.content, s.en_title, s.en_content))"
+	relatedScore = relatedSharedTags + " + " + relatedSimilarity
+)
+
+var (
+	headlineMarkers = `StartSel="` + util.HighlightStart + `", StopSel="` + util.HighlightStop + `"`
+	titleOptions    = headlineMarkers + ", HighlightAll=true"
+	snippetOptions  = headlineMarkers + `, MaxFragments=2, MaxWords=30, MinWords=12, FragmentDelimiter=" … "`
+)
+
+// likePattern matches s anywhere in a text, with LIKE wildcards in s taken literally.
+func likePattern(s string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s) + "%"
+}
+
+// searchArgs are the named arguments of the search expressions.
+func searchArgs(q string) map[string]interface{} {
+	return map[string]interface{}{"q": q, "like": likePattern(q), "title": titleOptions, "snippet": snippetOptions}
+}
+
 type postRepository struct {
 	db *gorm.DB
 }
@@ -46,39 +96,270 @@ func (r *postRepository) GetByID(id uint) (*domain.Post, error) {
 	return &post, nil
 }
 
-// GetAll returns all posts matching the given filter.
-func (r *postRepository) GetAll(filter model.PostFilter) ([]*domain.Post, error) {
+// GetBySlug retrieves a post by its current slug, or by a slug it had before.
+func (r *postRepository) GetBySlug(slug string) (*domain.Post, error) {
+	var post domain.Post
+	err := r.db.Preload("Tags").Preload("Author").Where("slug = ?", slug).First(&post).Error
+	if err == nil {
+		return &post, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	var old domain.PostSlug
+	if err := r.db.Where("slug = ?", slug).First(&old).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("post not found")
+		}
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	return r.GetByID(old.PostID)
+}
+
+// SlugOwner returns the ID of the post using slug now or before, or 0 if it is free.
+func (r *postRepository) SlugOwner(slug string) (uint, error) {
+	var ids []uint
+	if err := r.db.Model(&domain.Post{}).Where("slug = ?", slug).Limit(1).Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to look up slug: %w", err)
+	}
+	if len(ids) == 0 {
+		if err := r.db.Model(&domain.PostSlug{}).Where("slug = ?", slug).Limit(1).Pluck("post_id", &ids).Error; err != nil {
+			return 0, fmt.Errorf("failed to look up slug: %w", err)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return ids[0], nil
+}
+
+// ChangeSlug sets the post's slug to newSlug and keeps oldSlug in the slug history,
+// in one transaction. A slug the post returns to is removed from its history.
+func (r *postRepository) ChangeSlug(postID uint, oldSlug, newSlug string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Post{}).Where("id = ?", postID).Update("slug", newSlug)
+		if result.Error != nil {
+			return fmt.Errorf("failed to change slug: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("post not found")
+		}
+		if err := tx.Where("slug = ? AND post_id = ?", newSlug, postID).Delete(&domain.PostSlug{}).Error; err != nil {
+			return fmt.Errorf("failed to change slug: %w", err)
+		}
+		if oldSlug == "" || oldSlug == newSlug {
+			return nil
+		}
+		if err := tx.Create(&domain.PostSlug{Slug: oldSlug, PostID: postID, CreatedAt: time.Now()}).Error; err != nil {
+			return fmt.Errorf("failed to keep old slug: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListWithoutSlug returns the posts that have no slug yet, oldest first.
+func (r *postRepository) ListWithoutSlug() ([]*domain.Post, error) {
 	var posts []*domain.Post
-	query := r.db.Model(&domain.Post{}).Preload("Tags").Preload("Author")
+	if err := r.db.Where("slug = ''").Order("id").Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to list posts: %w", err)
+	}
+	return posts, nil
+}
+
+// GetAll returns the page of posts matching the given filter and how many match in all.
+func (r *postRepository) GetAll(filter model.PostFilter) (*domain.PostPage, error) {
+	var cursor *util.PostCursor
+	if filter.Cursor != "" {
+		c, err := util.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", domain.ErrInvalidCursor, err)
+		}
+		cursor = &c
+	}
+	query := r.db.Model(&domain.Post{})
 	if filter.AuthorID != nil {
-		query = query.Where("author_id = ?", *filter.AuthorID)
+		query = query.Where("posts.author_id = ?", *filter.AuthorID)
 	}
 	if filter.Published != nil {
-		query = query.Where("published = ?", *filter.Published)
+		query = query.Where("posts.published = ?", *filter.Published)
 	}
-	if filter.Search != nil {
-		like := "%" + *filter.Search + "%"
-		query = query.Where("title ILIKE ? OR content ILIKE ?", like, like)
+	if filter.Search != nil && strings.TrimSpace(*filter.Search) != "" {
+		query = query.Where(searchCondition, searchArgs(strings.TrimSpace(*filter.Search)))
 	}
 	if filter.Tag != nil && *filter.Tag != "" {
-		// join tags via post_tags to filter by tag name
-		query = query.Joins("JOIN post_tags pt ON pt.post_id = posts.id").Joins("JOIN tags t ON t.id = pt.tag_id").Where("t.name = ?", *filter.Tag)
+		// join tags via post_tags to filter by tag name or alias
+		query = query.Joins("JOIN post_tags pt ON pt.post_id = posts.id").Joins("JOIN tags t ON t.id = pt.tag_id").Where(tagMatches("t"), *filter.Tag, *filter.Tag)
 	}
-	orderBy := "created_at DESC"
+	page := &domain.PostPage{}
+	if err := query.Session(&gorm.Session{}).Count(&page.TotalCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count posts: %w", err)
+	}
+	query = query.Preload("Tags").Preload("Author")
 	if filter.OrderBy != "" {
-		orderBy = filter.OrderBy
+		// a custom order has no cursors; it pages by offset only
+		query = query.Order(filter.OrderBy)
+		if filter.Limit > 0 {
+			query = query.Limit(filter.Limit)
+		}
+		if filter.Offset > 0 {
+			query = query.Offset(filter.Offset)
+		}
+		if err := query.Find(&page.Posts).Error; err != nil {
+			return nil, fmt.Errorf("failed to list posts: %w", err)
+		}
+		return page, nil
 	}
-	query = query.Order(orderBy)
-	if filter.Limit > 0 {
-		query = query.Limit(filter.Limit)
+
+	// the posts before a cursor are read oldest first, from the cursor outwards
+	backward := cursor != nil && cursor.Before
+	switch {
+	case backward:
+		query = query.Where("(posts.created_at, posts.id) > (?, ?)", cursor.CreatedAt, cursor.ID).Order("posts.created_at, posts.id")
+	case cursor != nil:
+		query = query.Where("(posts.created_at, posts.id) < (?, ?)", cursor.CreatedAt, cursor.ID).Order("posts.created_at DESC, posts.id DESC")
+	default:
+		query = query.Order("posts.created_at DESC, posts.id DESC")
+		if filter.Offset > 0 {
+			query = query.Offset(filter.Offset)
+		}
 	}
-	if filter.Offset > 0 {
-		query = query.Offset(filter.Offset)
+	if filter.Limit > 0 {
+		// one more post than the page tells whether another page follows
+		query = query.Limit(filter.Limit + 1)
 	}
-	if err := query.Find(&posts).Error; err != nil {
+	if err := query.Find(&page.Posts).Error; err != nil {
 		return nil, fmt.Errorf("failed to list posts: %w", err)
 	}
-	return posts, nil
+	more := filter.Limit > 0 && len(page.Posts) > filter.Limit
+	if more {
+		page.Posts = page.Posts[:filter.Limit]
+	}
+	if backward {
+		slices.Reverse(page.Posts)
+	}
+	if len(page.Posts) == 0 {
+		return page, nil
+	}
+	// a page reached backward has the page it came from after it, and one reached
+	// forward has the page it came from, or the skipped posts, in front of it
+	hasNext, hasPrev := more, cursor != nil || filter.Offset > 0
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		last := page.Posts[len(page.Posts)-1]
+		page.NextCursor = util.PostCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	if hasPrev {
+		first := page.Posts[0]
+		page.PrevCursor = util.PostCursor{CreatedAt: first.CreatedAt, ID: first.ID, Before: true}.Encode()
+	}
+	return page, nil
+}
+
+// searchRow is a search hit as the ranking query returns it.
+type searchRow struct {
+	ID             uint
+	Rank           float64
+	TitleHighlight string
+	Snippet        string
+}
+
+// Search ranks the published posts matching query.Text, optionally with a tag, and
+// returns the requested page with highlights and the total number of hits.
+func (r *postRepository) Search(query model.SearchQuery) ([]*domain.SearchHit, int64, error) {
+	args := searchArgs(query.Text)
+	base := r.db.Table("posts").Where("posts.published").Where(searchCondition, args)
+	if query.Tag != nil && *query.Tag != "" {
+		base = base.Joins("JOIN post_tags pt ON pt.post_id = posts.id").Joins("JOIN tags t ON t.id = pt.tag_id").Where(tagMatches("t"), *query.Tag, *query.Tag)
+	}
+	var total int64
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count search hits: %w", err)
+	}
+	if total == 0 {
+		return []*domain.SearchHit{}, 0, nil
+	}
+	var rows []searchRow
+	page := base.Session(&gorm.Session{}).
+		Select("posts.id, "+searchRank+" AS rank, "+searchTitle+" AS title_highlight, "+searchSnippet+" AS snippet", args).
+		Order("rank DESC, posts.id DESC")
+	if query.Limit > 0 {
+		page = page.Limit(query.Limit)
+	}
+	if query.Offset > 0 {
+		page = page.Offset(query.Offset)
+	}
+	if err := page.Scan(&rows).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to search posts: %w", err)
+	}
+	hits := make([]*domain.SearchHit, 0, len(rows))
+	if len(rows) == 0 {
+		return hits, total, nil
+	}
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	var posts []*domain.Post
+	if err := r.db.Preload("Tags").Preload("Author").Where("id IN ?", ids).Find(&posts).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to load search hits: %w", err)
+	}
+	byID := make(map[uint]*domain.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+	for _, row := range rows {
+		if post, ok := byID[row.ID]; ok {
+			hits = append(hits, &domain.SearchHit{Post: post, Rank: row.Rank, TitleHighlight: row.TitleHighlight, Snippet: row.Snippet})
+		}
+	}
+	return hits, total, nil
+}
+
+// relatedRow is a related post as the ranking query returns it.
+type relatedRow struct {
+	ID         uint
+	Score      float64
+	SharedTags int
+}
+
+// Related ranks the published posts other than postID by the tags they share with it
+// and the similarity of their text, and returns the best limit of them.
+func (r *postRepository) Related(postID uint, limit int) ([]*domain.RelatedPost, error) {
+	var rows []relatedRow
+	err := r.db.Table("posts p").
+		Joins("JOIN posts s ON s.id = ?", postID).
+		Select("p.id, " + relatedScore + " AS score, " + relatedSharedTags + " AS shared_tags").
+		Where("p.published AND p.id <> s.id").
+		Order("score DESC, p.published_at DESC, p.id DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank related posts: %w", err)
+	}
+	related := make([]*domain.RelatedPost, 0, len(rows))
+	if len(rows) == 0 {
+		return related, nil
+	}
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	var posts []*domain.Post
+	if err := r.db.Preload("Tags").Preload("Author").Where("id IN ?", ids).Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to load related posts: %w", err)
+	}
+	byID := make(map[uint]*domain.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+	for _, row := range rows {
+		if post, ok := byID[row.ID]; ok {
+			related = append(related, &domain.RelatedPost{Post: post, Score: row.Score, SharedTags: row.SharedTags})
+		}
+	}
+	return related, nil
 }
 
 // Update updates an existing post in the database.
@@ -98,6 +379,8 @@ func (r *postRepository) Update(post *domain.Post) error {
 		"en_content":   post.EnContent,
 		"published":    post.Published,
 		"published_at": post.PublishedAt,
+		"publish_at":   post.PublishAt,
+		"unpublish_at": post.UnpublishAt,
 		"updated_at":   post.UpdatedAt,
 	})
 	if result.Error != nil {
@@ -109,6 +392,131 @@ func (r *postRepository) Update(post *domain.Post) error {
 	return nil
 }
 
+// UpdateTranslation writes only the given English fields, so a translation finishing
+// after the post was edited again cannot overwrite the edit.
+func (r *postRepository) UpdateTranslation(postID uint, enTitle, enContent *string) error {
+	fields := map[string]interface{}{}
+	if enTitle != nil {
+		fields["en_title"] = *enTitle
+	}
+	if enContent != nil {
+		fields["en_content"] = *enContent
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	result := r.db.Model(&domain.Post{}).Where("id = ?", postID).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update translation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("post not found")
+	}
+	return nil
+}
+
+// ApplySchedule publishes posts whose publish_at has passed, then unpublishes published
+// posts whose unpublish_at has passed, in one transaction under an advisory lock. A
+// replica that finds the lock taken changes nothing; the holder applies the schedule.
+func (r *postRepository) ApplySchedule(now time.Time) (published, unpublished []*domain.Post, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", scheduleLockKey).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("failed to lock schedule: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+		if err := tx.Where("publish_at <= ?", now).Order("publish_at").Find(&published).Error; err != nil {
+			return fmt.Errorf("failed to list scheduled posts: %w", err)
+		}
+		for _, post := range published {
+			publishedAt := *post.PublishAt
+			if err := tx.Model(post).Updates(map[string]interface{}{
+				"published":    true,
+				"published_at": publishedAt,
+				"publish_at":   nil,
+				"updated_at":   now,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to publish post %d: %w", post.ID, err)
+			}
+			post.Published, post.PublishedAt, post.PublishAt, post.UpdatedAt = true, &publishedAt, nil, now
+		}
+		if err := tx.Where("published AND unpublish_at <= ?", now).Order("unpublish_at").Find(&unpublished).Error; err != nil {
+			return fmt.Errorf("failed to list scheduled posts: %w", err)
+		}
+		for _, post := range unpublished {
+			if err := tx.Model(post).Updates(map[string]interface{}{
+				"published":    false,
+				"published_at": nil,
+				"unpublish_at": nil,
+				"updated_at":   now,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to unpublish post %d: %w", post.ID, err)
+			}
+			post.Published, post.PublishedAt, post.UnpublishAt, post.UpdatedAt = false, nil, nil, now
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return published, unpublished, nil
+}
+
+// AddRevision inserts rev unless the post's latest revision has the same text, so
+// saves that only touch tags, the thumbnail or the schedule add nothing.
+func (r *postRepository) AddRevision(rev *domain.PostRevision) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var latest domain.PostRevision
+		err := tx.Where("post_id = ?", rev.PostID).Order("id DESC").Take(&latest).Error
+		if err == nil && latest.SameText(rev) {
+			return nil
+		}
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return fmt.Errorf("failed to get latest revision: %w", err)
+		}
+		if err := tx.Create(rev).Error; err != nil {
+			return fmt.Errorf("failed to add revision: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListRevisions returns the post's revisions, newest first, without their content.
+func (r *postRepository) ListRevisions(postID uint) ([]*domain.PostRevision, error) {
+	var revs []*domain.PostRevision
+	if err := r.db.Select("id", "post_id", "title", "en_title", "author_id", "kind", "created_at").
+		Where("post_id = ?", postID).Order("id DESC").Find(&revs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	return revs, nil
+}
+
+// GetRevision returns one revision of the post with its content.
+func (r *postRepository) GetRevision(postID, revisionID uint) (*domain.PostRevision, error) {
+	var rev domain.PostRevision
+	if err := r.db.Where("post_id = ? AND id = ?", postID, revisionID).Take(&rev).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("failed to get revision: %w", err)
+	}
+	return &rev, nil
+}
+
+// PreviousRevision returns the revision before revisionID, or nil when there is none.
+func (r *postRepository) PreviousRevision(postID, revisionID uint) (*domain.PostRevision, error) {
+	var rev domain.PostRevision
+	if err := r.db.Where("post_id = ? AND id < ?", postID, revisionID).Order("id DESC").Take(&rev).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get revision: %w", err)
+	}
+	return &rev, nil
+}
+
 // Delete removes a post by its ID from the database.
 func (r *postRepository) Delete(id uint) error {
 	result := r.db.Delete(&domain.Post{}, id)
@@ -123,9 +531,9 @@ func (r *postRepository) Delete(id uint) error {
 
 // GetByAuthorID returns all posts by a specific author.
 func (r *postRepository) GetByAuthorID(authorID uint) ([]*domain.Post, error) {
-	filter := model.PostFilter{
-		AuthorID: &authorID,
-		OrderBy:  "created_at DESC",
+	var posts []*domain.Post
+	if err := r.db.Preload("Tags").Preload("Author").Where("author_id = ?", authorID).Order("created_at DESC").Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to list posts: %w", err)
 	}
-	return r.GetAll(filter)
+	return posts, nil
 }
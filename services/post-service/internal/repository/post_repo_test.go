@@ -1,10 +1,13 @@
 package repository
 
 import (
+	"database/sql/driver"
 	"errors"
 	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"gorm.io/driver/postgres"
@@ -120,49 +123,76 @@ func TestPostRepository_GetByID(t *testing.T) {
 func TestPostRepository_GetAll(t *testing.T) {
 	repo, mock, cleanup := setupMockPostRepo(t)
 	defer cleanup()
-
-	emptyRows := sqlmock.NewRows([]string{"id", "title", "content", "author_id", "published"})
+	mock.MatchExpectationsInOrder(true)
+	columns := []string{"id", "title", "content", "author_id", "published"}
+	count := func(n int) *sqlmock.Rows { return sqlmock.NewRows([]string{"count"}).AddRow(n) }
 
 	authorID := uint(7)
-	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE author_id = \$1 ORDER BY created_at DESC`).
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "posts" WHERE posts\.author_id = \$1`).
+		WithArgs(authorID).
+		WillReturnRows(count(0))
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE posts\.author_id = \$1 ORDER BY posts\.created_at DESC, posts\.id DESC`).
 		WithArgs(authorID).
-		WillReturnRows(emptyRows)
-	if _, err := repo.GetAll(model.PostFilter{AuthorID: &authorID}); err != nil {
-		t.Fatalf("author filter: %v", err)
+		WillReturnRows(sqlmock.NewRows(columns))
+	if page, err := repo.GetAll(model.PostFilter{AuthorID: &authorID}); err != nil || page.TotalCount != 0 || page.NextCursor != "" || page.PrevCursor != "" {
+		t.Fatalf("author filter: %+v err=%v", page, err)
 	}
 
 	published := true
-	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE published = \$1 ORDER BY created_at DESC`).
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "posts" WHERE posts\.published = \$1`).
 		WithArgs(published).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "author_id", "published"}))
+		WillReturnRows(count(0))
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE posts\.published = \$1 ORDER BY posts\.created_at DESC, posts\.id DESC`).
+		WithArgs(published).
+		WillReturnRows(sqlmock.NewRows(columns))
 	if _, err := repo.GetAll(model.PostFilter{Published: &published}); err != nil {
 		t.Fatalf("published filter: %v", err)
 	}
 
 	search := "go"
-	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE title ILIKE \$1 OR content ILIKE \$2 ORDER BY created_at DESC`).
-		WithArgs("%go%", "%go%").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "author_id", "published"}))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "posts" WHERE \(posts\.search_vector @@ .* ILIKE \$3\)`).
+		WithArgs("go", "go", "%go%").
+		WillReturnRows(count(0))
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE \(posts\.search_vector @@ \(websearch_to_tsquery\('simple', \$1\) \|\| websearch_to_tsquery\('english', \$2\)\) OR post_search_document\(.*\) ILIKE \$3\) ORDER BY posts\.created_at DESC`).
+		WithArgs("go", "go", "%go%").
+		WillReturnRows(sqlmock.NewRows(columns))
 	if _, err := repo.GetAll(model.PostFilter{Search: &search}); err != nil {
 		t.Fatalf("search filter: %v", err)
 	}
 
+	// a custom order pages by offset, without cursors
 	tag := "go"
-	mock.ExpectQuery(`SELECT .* FROM "posts" JOIN post_tags pt ON pt\.post_id = posts\.id JOIN tags t ON t\.id = pt\.tag_id WHERE t\.name = \$1 ORDER BY posts\.created_at DESC`).
-		WithArgs(tag).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "author_id", "published"}))
-	if _, err := repo.GetAll(model.PostFilter{Tag: &tag, OrderBy: "posts.created_at DESC"}); err != nil {
-		t.Fatalf("tag filter: %v", err)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "posts" JOIN post_tags pt ON pt\.post_id = posts\.id JOIN tags t ON t\.id = pt\.tag_id WHERE \(lower\(t\.name\) = lower\(\$1\) OR t\.id IN \(SELECT tag_id FROM tag_aliases WHERE alias = lower\(\$2\)\)\)`).
+		WithArgs(tag, tag).
+		WillReturnRows(count(1))
+	mock.ExpectQuery(`SELECT .* FROM "posts" JOIN post_tags pt ON pt\.post_id = posts\.id JOIN tags t ON t\.id = pt\.tag_id WHERE \(lower\(t\.name\) = lower\(\$1\) OR t\.id IN \(SELECT tag_id FROM tag_aliases WHERE alias = lower\(\$2\)\)\) ORDER BY posts\.updated_at DESC LIMIT \$3`).
+		WithArgs(tag, tag, 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(4, "t", "c", 7, true))
+	mock.ExpectQuery(`SELECT \* FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(`SELECT \* FROM "post_tags"`).WillReturnRows(sqlmock.NewRows([]string{"post_id", "tag_id"}))
+	if page, err := repo.GetAll(model.PostFilter{Tag: &tag, OrderBy: "posts.updated_at DESC", Limit: 1}); err != nil || len(page.Posts) != 1 || page.NextCursor != "" {
+		t.Fatalf("tag filter: %+v err=%v", page, err)
 	}
 
-	mock.ExpectQuery(`SELECT \* FROM "posts" ORDER BY created_at DESC LIMIT \$1 OFFSET \$2`).
-		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "author_id", "published"}))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "posts"`).WillReturnRows(count(0))
+	mock.ExpectQuery(`SELECT \* FROM "posts" ORDER BY posts\.created_at DESC, posts\.id DESC LIMIT \$1 OFFSET \$2`).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows(columns))
 	if _, err := repo.GetAll(model.PostFilter{Limit: 1, Offset: 1}); err != nil {
 		t.Fatalf("limit/offset: %v", err)
 	}
 
-	mock.ExpectQuery(`SELECT \* FROM "posts" ORDER BY created_at DESC`).
+	if _, err := repo.GetAll(model.PostFilter{Cursor: "not a cursor"}); !errors.Is(err, domain.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "posts"`).WillReturnError(errors.New("count fail"))
+	if _, err := repo.GetAll(model.PostFilter{}); err == nil || !strings.Contains(err.Error(), "failed to count posts") {
+		t.Fatalf("expected count error, got %v", err)
+	}
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "posts"`).WillReturnRows(count(0))
+	mock.ExpectQuery(`SELECT \* FROM "posts" ORDER BY posts\.created_at DESC, posts\.id DESC`).
 		WillReturnError(errors.New("list fail"))
 	if _, err := repo.GetAll(model.PostFilter{}); err == nil || !strings.Contains(err.Error(), "failed to list posts") {
 		t.Fatalf("expected get all db error, got %v", err)
@@ -171,6 +201,73 @@ func TestPostRepository_GetAll(t *testing.T) {
 	assertPostMock(t, mock)
 }
 
+func TestPostRepository_GetAll_Cursors(t *testing.T) {
+	repo, mock, cleanup := setupMockPostRepo(t)
+	defer cleanup()
+	mock.MatchExpectationsInOrder(true)
+	columns := []string{"id", "title", "author_id", "created_at"}
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC) }
+	rows := func(ids ...int) *sqlmock.Rows {
+		r := sqlmock.NewRows(columns)
+		for _, id := range ids {
+			r.AddRow(id, "t", 7, day(id))
+		}
+		return r
+	}
+	expectPage := func(where string, args []driver.Value, ids ...int) {
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "posts"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+		mock.ExpectQuery(`SELECT \* FROM "posts" ` + where).WithArgs(args...).WillReturnRows(rows(ids...))
+		mock.ExpectQuery(`SELECT \* FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`SELECT \* FROM "post_tags"`).WillReturnRows(sqlmock.NewRows([]string{"post_id", "tag_id"}))
+	}
+	ids := func(page *domain.PostPage) []uint {
+		var out []uint
+		if page == nil {
+			return nil
+		}
+		for _, p := range page.Posts {
+			out = append(out, p.ID)
+		}
+		return out
+	}
+
+	// the first page of posts 5..1, two at a time, has only a next page
+	expectPage(`ORDER BY posts\.created_at DESC, posts\.id DESC LIMIT \$1`, []driver.Value{3}, 5, 4, 3)
+	first, err := repo.GetAll(model.PostFilter{Limit: 2})
+	if err != nil || !slices.Equal(ids(first), []uint{5, 4}) || first.TotalCount != 5 || first.NextCursor == "" || first.PrevCursor != "" {
+		t.Fatalf("unexpected first page %v %+v err=%v", ids(first), first, err)
+	}
+
+	expectPage(`WHERE \(posts\.created_at, posts\.id\) < \(\$1, \$2\) ORDER BY posts\.created_at DESC, posts\.id DESC LIMIT \$3`,
+		[]driver.Value{day(4), uint(4), 3}, 3, 2, 1)
+	second, err := repo.GetAll(model.PostFilter{Limit: 2, Cursor: first.NextCursor})
+	if err != nil || !slices.Equal(ids(second), []uint{3, 2}) || second.NextCursor == "" || second.PrevCursor == "" {
+		t.Fatalf("unexpected second page %v %+v err=%v", ids(second), second, err)
+	}
+
+	expectPage(`WHERE \(posts\.created_at, posts\.id\) < \(\$1, \$2\)`, []driver.Value{day(2), uint(2), 3}, 1)
+	last, err := repo.GetAll(model.PostFilter{Limit: 2, Cursor: second.NextCursor})
+	if err != nil || !slices.Equal(ids(last), []uint{1}) || last.NextCursor != "" || last.PrevCursor == "" {
+		t.Fatalf("unexpected last page %v %+v err=%v", ids(last), last, err)
+	}
+
+	// going back from the last page reads oldest first and returns newest first
+	expectPage(`WHERE \(posts\.created_at, posts\.id\) > \(\$1, \$2\) ORDER BY posts\.created_at, posts\.id LIMIT \$3`,
+		[]driver.Value{day(1), uint(1), 3}, 2, 3, 4)
+	back, err := repo.GetAll(model.PostFilter{Limit: 2, Cursor: last.PrevCursor})
+	if err != nil || !slices.Equal(ids(back), []uint{3, 2}) || back.NextCursor == "" || back.PrevCursor == "" {
+		t.Fatalf("unexpected page going back %v %+v err=%v", ids(back), back, err)
+	}
+
+	expectPage(`WHERE \(posts\.created_at, posts\.id\) > \(\$1, \$2\)`, []driver.Value{day(3), uint(3), 3}, 4, 5)
+	top, err := repo.GetAll(model.PostFilter{Limit: 2, Cursor: back.PrevCursor})
+	if err != nil || !slices.Equal(ids(top), []uint{5, 4}) || top.NextCursor == "" || top.PrevCursor != "" {
+		t.Fatalf("unexpected page back at the top %v %+v err=%v", ids(top), top, err)
+	}
+
+	assertPostMock(t, mock)
+}
+
 func TestPostRepository_Update(t *testing.T) {
 	repo, mock, cleanup := setupMockPostRepo(t)
 	defer cleanup()
@@ -218,6 +315,35 @@ func TestPostRepository_Update(t *testing.T) {
 	assertPostMock(t, mock)
 }
 
+func TestPostRepository_UpdateTranslation(t *testing.T) {
+	repo, mock, cleanup := setupMockPostRepo(t)
+	defer cleanup()
+
+	title, content := "title", "content"
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "posts" SET "en_content"=\$1,"en_title"=\$2,"updated_at"=\$3 WHERE id = \$4`).
+		WithArgs(content, title, sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.UpdateTranslation(1, &title, &content); err != nil {
+		t.Fatalf("update translation: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "posts" SET "en_title"=\$1,"updated_at"=\$2 WHERE id = \$3`).
+		WithArgs(title, sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	if err := repo.UpdateTranslation(2, &title, nil); err == nil {
+		t.Fatalf("expected post not found")
+	}
+
+	if err := repo.UpdateTranslation(1, nil, nil); err != nil {
+		t.Fatalf("expected no-op, got %v", err)
+	}
+	assertPostMock(t, mock)
+}
+
 func TestPostRepository_DeleteAndGetByAuthorID(t *testing.T) {
 	repo, mock, cleanup := setupMockPostRepo(t)
 	defer cleanup()
@@ -259,3 +385,279 @@ func TestPostRepository_DeleteAndGetByAuthorID(t *testing.T) {
 
 	assertPostMock(t, mock)
 }
+
+func TestPostRepository_GetBySlug(t *testing.T) {
+	repo, mock, cleanup := setupMockPostRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "posts" WHERE slug = $1 ORDER BY "posts"."id" LIMIT $2`)).
+		WithArgs("old-slug", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "post_slugs" WHERE slug = $1 ORDER BY "post_slugs"."slug" LIMIT $2`)).
+		WithArgs("old-slug", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"slug", "post_id"}).AddRow("old-slug", 4))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "posts" WHERE "posts"."id" = $1 ORDER BY "posts"."id" LIMIT $2`)).
+		WithArgs(uint(4), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "slug", "author_id"}).AddRow(4, "t", "new-slug", 10))
+	mock.ExpectQuery(`SELECT \* FROM "post_tags" WHERE "post_tags"\."post_id" = \$1`).
+		WithArgs(uint(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "tag_id"}))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1`).
+		WithArgs(uint(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(10, "u1"))
+	got, err := repo.GetBySlug("old-slug")
+	if err != nil || got.ID != 4 || got.Slug != "new-slug" {
+		t.Fatalf("expected post found by its old slug, got %+v err=%v", got, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "posts" WHERE slug = $1`)).
+		WithArgs("missing", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "post_slugs" WHERE slug = $1`)).
+		WithArgs("missing", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	if _, err := repo.GetBySlug("missing"); err == nil || !strings.Contains(err.Error(), "post not found") {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	assertPostMock(t, mock)
+}
+
+func TestPostRepository_SlugOwnerAndChangeSlug(t *testing.T) {
+	repo, mock, cleanup := setupMockPostRepo(t)
+	defer cleanup()
+	mock.MatchExpectationsInOrder(true)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "posts" WHERE slug = $1 LIMIT $2`)).
+		WithArgs("old-slug", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "post_id" FROM "post_slugs" WHERE slug = $1 LIMIT $2`)).
+		WithArgs("old-slug", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id"}).AddRow(4))
+	if owner, err := repo.SlugOwner("old-slug"); err != nil || owner != 4 {
+		t.Fatalf("expected old slug to belong to post 4, got %d err=%v", owner, err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "posts" SET "slug"=$1,"updated_at"=$2 WHERE id = $3`)).
+		WithArgs("new-slug", sqlmock.AnyArg(), uint(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "post_slugs" WHERE slug = $1 AND post_id = $2`)).
+		WithArgs("new-slug", uint(4)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "post_slugs"`)).
+		WithArgs("old-slug", uint(4), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.ChangeSlug(4, "old-slug", "new-slug"); err != nil {
+		t.Fatalf("change slug: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "posts" SET "slug"=$1,"updated_at"=$2 WHERE id = $3`)).
+		WithArgs("x", sqlmock.AnyArg(), uint(999)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	if err := repo.ChangeSlug(999, "", "x"); err == nil || !strings.Contains(err.Error(), "post not found") {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	assertPostMock(t, mock)
+}
+
+func TestPostRepository_ApplySchedule(t *testing.T) {
+	repo, mock, cleanup := setupMockPostRepo(t)
+	defer cleanup()
+	mock.MatchExpectationsInOrder(true)
+	now := time.Now()
+	publishAt := now.Add(-time.Minute)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock($1)`)).
+		WithArgs(scheduleLockKey).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "posts" WHERE publish_at <= $1 ORDER BY publish_at`)).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "publish_at"}).AddRow(1, 7, publishAt))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "posts" SET`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "posts" WHERE published AND unpublish_at <= $1 ORDER BY unpublish_at`)).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "published", "unpublish_at"}).AddRow(2, 7, true, now))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "posts" SET`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	published, unpublished, err := repo.ApplySchedule(now)
+	if err != nil {
+		t.Fatalf("apply schedule: %v", err)
+	}
+	if len(published) != 1 || !published[0].Published || published[0].PublishAt != nil || !published[0].PublishedAt.Equal(publishAt) {
+		t.Fatalf("unexpected published posts %+v", published)
+	}
+	if len(unpublished) != 1 || unpublished[0].Published || unpublished[0].UnpublishAt != nil {
+		t.Fatalf("unexpected unpublished posts %+v", unpublished)
+	}
+
+	// another replica holds the lock
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock($1)`)).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(false))
+	mock.ExpectCommit()
+	if published, unpublished, err := repo.ApplySchedule(now); err != nil || len(published)+len(unpublished) != 0 {
+		t.Fatalf("expected no changes without the lock, got %v %v err=%v", published, unpublished, err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock($1)`)).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "posts" WHERE publish_at <= $1`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "publish_at"}).AddRow(1, publishAt))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "posts" SET`)).
+		WillReturnError(errors.New("update fail"))
+	mock.ExpectRollback()
+	if _, _, err := repo.ApplySchedule(now); err == nil || !strings.Contains(err.Error(), "failed to publish post 1") {
+		t.Fatalf("expected publish error, got %v", err)
+	}
+
+	assertPostMock(t, mock)
+}
+
+func TestPostRepository_Revisions(t *testing.T) {
+	repo, mock, cleanup := setupMockPostRepo(t)
+	defer cleanup()
+	mock.MatchExpectationsInOrder(true)
+	columns := []string{"id", "post_id", "title", "content", "en_title", "en_content", "author_id", "kind", "created_at"}
+
+	// same text as the latest revision: nothing is added
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "post_revisions" WHERE post_id = $1 ORDER BY id DESC LIMIT $2`)).
+		WithArgs(uint(4), 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(2, 4, "t", "c", "", "", 7, "edit", time.Now()))
+	mock.ExpectCommit()
+	rev := &domain.PostRevision{PostID: 4, Title: "t", Content: "c", Kind: domain.RevisionEdit}
+	if err := repo.AddRevision(rev); err != nil || rev.ID != 0 {
+		t.Fatalf("expected unchanged text to be skipped, got id %d err=%v", rev.ID, err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "post_revisions" WHERE post_id = $1 ORDER BY id DESC LIMIT $2`)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(2, 4, "t", "c", "", "", 7, "edit", time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "post_revisions"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectCommit()
+	rev = &domain.PostRevision{PostID: 4, Title: "t", Content: "changed", Kind: domain.RevisionEdit}
+	if err := repo.AddRevision(rev); err != nil || rev.ID != 3 {
+		t.Fatalf("expected a new revision, got id %d err=%v", rev.ID, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","post_id","title","en_title","author_id","kind","created_at" FROM "post_revisions" WHERE post_id = $1 ORDER BY id DESC`)).
+		WithArgs(uint(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "title"}).AddRow(3, 4, "t").AddRow(2, 4, "t"))
+	if revs, err := repo.ListRevisions(4); err != nil || len(revs) != 2 || revs[0].ID != 3 {
+		t.Fatalf("unexpected revisions %v err=%v", revs, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "post_revisions" WHERE post_id = $1 AND id = $2 LIMIT $3`)).
+		WithArgs(uint(4), uint(9), 1).
+		WillReturnRows(sqlmock.NewRows(columns))
+	if _, err := repo.GetRevision(4, 9); !errors.Is(err, domain.ErrRevisionNotFound) {
+		t.Fatalf("expected ErrRevisionNotFound, got %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "post_revisions" WHERE post_id = $1 AND id < $2 ORDER BY id DESC LIMIT $3`)).
+		WithArgs(uint(4), uint(2), 1).
+		WillReturnRows(sqlmock.NewRows(columns))
+	if prev, err := repo.PreviousRevision(4, 2); err != nil || prev != nil {
+		t.Fatalf("expected no previous revision, got %v err=%v", prev, err)
+	}
+
+	assertPostMock(t, mock)
+}
+
+func TestPostRepository_Search(t *testing.T) {
+	repo, mock, cleanup := setupMockPostRepo(t)
+	defer cleanup()
+	mock.MatchExpectationsInOrder(true)
+	tag := "dev"
+	condition := `WHERE posts\.published AND \(\(posts\.search_vector @@ \(websearch_to_tsquery\('simple', \$1\) \|\| websearch_to_tsquery\('english', \$2\)\) OR post_search_document\(posts\.title, posts\.content, posts\.en_title, posts\.en_content\) ILIKE \$3\)\)`
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "posts" JOIN post_tags pt ON pt\.post_id = posts\.id JOIN tags t ON t\.id = pt\.tag_id `+condition+` AND \(\(lower\(t\.name\) = lower\(\$4\) OR t\.id IN \(SELECT tag_id FROM tag_aliases WHERE alias = lower\(\$5\)\)\)\)`).
+		WithArgs("50%", "50%", `%50\%%`, tag, tag).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(`SELECT posts\.id, ts_rank\(.*\) AS rank, ts_headline\('simple', posts\.title, .*\) AS title_highlight, CASE .* END AS snippet FROM "posts" JOIN .* ORDER BY rank DESC, posts\.id DESC LIMIT \$\d+ OFFSET \$\d+`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "rank", "title_highlight", "snippet"}).
+			AddRow(5, 0.9, "best", "best snippet").
+			AddRow(2, 0.4, "next", "next snippet"))
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id IN \(\$1,\$2\)`).
+		WithArgs(uint(5), uint(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "author_id", "published"}).AddRow(2, "next", 10, true).AddRow(5, "best", 10, true))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1`).
+		WithArgs(uint(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(10, "u1"))
+	mock.ExpectQuery(`SELECT \* FROM "post_tags" WHERE "post_tags"\."post_id" IN \(\$1,\$2\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "tag_id"}))
+
+	hits, total, err := repo.Search(model.SearchQuery{Text: "50%", Tag: &tag, Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if total != 3 || len(hits) != 2 || hits[0].Post.ID != 5 || hits[0].Snippet != "best snippet" || hits[1].Post.ID != 2 || hits[1].Rank != 0.4 {
+		t.Fatalf("expected hits in rank order, got total=%d %+v", total, hits)
+	}
+
+	// no hits: the page is not queried
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "posts" ` + condition).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	if hits, total, err := repo.Search(model.SearchQuery{Text: "nothing"}); err != nil || total != 0 || len(hits) != 0 {
+		t.Fatalf("expected no hits, got total=%d %v err=%v", total, hits, err)
+	}
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "posts"`).WillReturnError(errors.New("db down"))
+	if _, _, err := repo.Search(model.SearchQuery{Text: "go"}); err == nil || !strings.Contains(err.Error(), "failed to count search hits") {
+		t.Fatalf("expected count error, got %v", err)
+	}
+
+	assertPostMock(t, mock)
+}
+
+func TestPostRepository_Related(t *testing.T) {
+	repo, mock, cleanup := setupMockPostRepo(t)
+	defer cleanup()
+	mock.MatchExpectationsInOrder(true)
+
+	mock.ExpectQuery(`SELECT p\.id, \(SELECT count\(\*\) FROM post_tags a JOIN post_tags b ON b\.tag_id = a\.tag_id WHERE a\.post_id = p\.id AND b\.post_id = s\.id\) \+ similarity\(p\.title, s\.title\) \+ similarity\(.*\) AS score, \(SELECT count\(\*\) .*\) AS shared_tags FROM posts p JOIN posts s ON s\.id = \$1 WHERE p\.published AND p\.id <> s\.id ORDER BY score DESC, p\.published_at DESC, p\.id DESC LIMIT \$2`).
+		WithArgs(uint(4), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "score", "shared_tags"}).
+			AddRow(7, 2.3, 2).
+			AddRow(3, 0.2, 0))
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id IN \(\$1,\$2\)`).
+		WithArgs(uint(7), uint(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "author_id", "published"}).AddRow(3, "far", 10, true).AddRow(7, "near", 10, true))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1`).
+		WithArgs(uint(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(10, "u1"))
+	mock.ExpectQuery(`SELECT \* FROM "post_tags" WHERE "post_tags"\."post_id" IN \(\$1,\$2\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "tag_id"}))
+
+	related, err := repo.Related(4, 2)
+	if err != nil {
+		t.Fatalf("related: %v", err)
+	}
+	if len(related) != 2 || related[0].Post.ID != 7 || related[0].SharedTags != 2 || related[1].Post.ID != 3 || related[1].Score != 0.2 {
+		t.Fatalf("expected related posts in score order, got %+v", related)
+	}
+
+	// nothing else published: no posts are loaded
+	mock.ExpectQuery(`SELECT p\.id, .* FROM posts p`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "score", "shared_tags"}))
+	if related, err := repo.Related(4, 2); err != nil || len(related) != 0 {
+		t.Fatalf("expected no related posts, got %v err=%v", related, err)
+	}
+
+	mock.ExpectQuery(`SELECT p\.id, .* FROM posts p`).WillReturnError(errors.New("db down"))
+	if _, err := repo.Related(4, 2); err == nil || !strings.Contains(err.Error(), "failed to rank related posts") {
+		t.Fatalf("expected ranking error, got %v", err)
+	}
+
+	assertPostMock(t, mock)
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+)
+
+const (
+	relatedGenerationKey = "post:related:generation"
+	// relatedTTL bounds how long a change the service does not see, such as an
+	// author renaming their account, stays out of the cached posts. It also clears
+	// the entries of past generations.
+	relatedTTL = time.Hour
+)
+
+// relatedCache implements domain.RelatedCache using Redis, so every replica sees the
+// same entries and an invalidation by any of them.
+type relatedCache struct {
+	redis *redis.Client
+}
+
+// NewRelatedCache creates a RelatedCache backed by the given Redis client.
+func NewRelatedCache(client *redis.Client) domain.RelatedCache {
+	return &relatedCache{redis: client}
+}
+
+// Generation returns the current generation, 0 until the first invalidation.
+func (c *relatedCache) Generation() (int64, error) {
+	gen, err := c.redis.Get(context.Background(), relatedGenerationKey).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get related posts generation: %w", err)
+	}
+	return gen, nil
+}
+
+// Get returns the related posts of postID cached in generation.
+func (c *relatedCache) Get(generation int64, postID uint) ([]*domain.RelatedPost, bool, error) {
+	bb, err := c.redis.Get(context.Background(), relatedKey(generation, postID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get related posts: %w", err)
+	}
+	var related []*domain.RelatedPost
+	if err := json.Unmarshal(bb, &related); err != nil {
+		return nil, false, fmt.Errorf("failed to decode related posts: %w", err)
+	}
+	return related, true, nil
+}
+
+// Set caches the related posts of postID in generation until relatedTTL elapses.
+func (c *relatedCache) Set(generation int64, postID uint, related []*domain.RelatedPost) error {
+	bb, err := json.Marshal(related)
+	if err != nil {
+		return fmt.Errorf("failed to encode related posts: %w", err)
+	}
+	if err := c.redis.Set(context.Background(), relatedKey(generation, postID), bb, relatedTTL).Err(); err != nil {
+		return fmt.Errorf("failed to save related posts: %w", err)
+	}
+	return nil
+}
+
+// Invalidate starts a new generation, which no entry belongs to yet.
+func (c *relatedCache) Invalidate() error {
+	if err := c.redis.Incr(context.Background(), relatedGenerationKey).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate related posts: %w", err)
+	}
+	return nil
+}
+
+func relatedKey(generation int64, postID uint) string {
+	return fmt.Sprintf("post:related:%d:%d", generation, postID)
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+)
+
+type seriesRepository struct {
+	db *gorm.DB
+}
+
+// NewSeriesRepository creates a new SeriesRepository with the given GORM DB instance.
+func NewSeriesRepository(db *gorm.DB) domain.SeriesRepository {
+	return &seriesRepository{db: db}
+}
+
+// Create inserts the series and its parts in one transaction.
+func (r *seriesRepository) Create(series *domain.Series, postIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(series).Error; err != nil {
+			return fmt.Errorf("failed to create series: %w", err)
+		}
+		return insertSeriesPosts(tx, series.ID, postIDs)
+	})
+}
+
+func insertSeriesPosts(tx *gorm.DB, seriesID uint, postIDs []uint) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+	rows := make([]domain.SeriesPost, len(postIDs))
+	for i, id := range postIDs {
+		rows[i] = domain.SeriesPost{SeriesID: seriesID, PostID: id, Position: i + 1}
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return fmt.Errorf("failed to add posts to series: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns a series with all its parts.
+func (r *seriesRepository) GetByID(id uint) (*domain.Series, error) {
+	var series domain.Series
+	if err := r.db.Take(&series, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrSeriesNotFound
+		}
+		return nil, fmt.Errorf("failed to get series: %w", err)
+	}
+	if err := r.loadParts([]*domain.Series{&series}); err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// List returns every series with its parts, newest first.
+func (r *seriesRepository) List() ([]*domain.Series, error) {
+	var series []*domain.Series
+	if err := r.db.Order("id DESC").Find(&series).Error; err != nil {
+		return nil, fmt.Errorf("failed to list series: %w", err)
+	}
+	if err := r.loadParts(series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// seriesPartRow is a part as read with the series it belongs to.
+type seriesPartRow struct {
+	SeriesID  uint
+	PostID    uint
+	Title     string
+	Slug      string
+	Published bool
+}
+
+// loadParts fills in the parts of series, in order, with one query.
+func (r *seriesRepository) loadParts(series []*domain.Series) error {
+	if len(series) == 0 {
+		return nil
+	}
+	byID := make(map[uint]*domain.Series, len(series))
+	ids := make([]uint, 0, len(series))
+	for _, s := range series {
+		s.Parts = []*domain.SeriesPart{}
+		byID[s.ID] = s
+		ids = append(ids, s.ID)
+	}
+	var rows []seriesPartRow
+	if err := r.db.Table("series_posts").
+		Select("series_posts.series_id, posts.id AS post_id, posts.title, posts.slug, posts.published").
+		Joins("JOIN posts ON posts.id = series_posts.post_id").
+		Where("series_posts.series_id IN ?", ids).
+		Order("series_posts.series_id, series_posts.position").
+		Scan(&rows).Error; err != nil {
+		return fmt.Errorf("failed to list series posts: %w", err)
+	}
+	for _, row := range rows {
+		s := byID[row.SeriesID]
+		s.Parts = append(s.Parts, &domain.SeriesPart{PostID: row.PostID, Title: row.Title, Slug: row.Slug, Published: row.Published})
+	}
+	return nil
+}
+
+// Update saves the series and replaces its parts when postIDs is not nil.
+func (r *seriesRepository) Update(series *domain.Series, postIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(series).Select("title", "description").Updates(series).Error; err != nil {
+			return fmt.Errorf("failed to update series: %w", err)
+		}
+		if postIDs == nil {
+			return nil
+		}
+		if err := tx.Where("series_id = ?", series.ID).Delete(&domain.SeriesPost{}).Error; err != nil {
+			return fmt.Errorf("failed to update series posts: %w", err)
+		}
+		return insertSeriesPosts(tx, series.ID, postIDs)
+	})
+}
+
+// Delete removes a series; its posts stay.
+func (r *seriesRepository) Delete(id uint) error {
+	result := r.db.Delete(&domain.Series{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete series: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrSeriesNotFound
+	}
+	return nil
+}
+
+// SeriesOf returns the series the post belongs to, or nil.
+func (r *seriesRepository) SeriesOf(postID uint) (*domain.Series, error) {
+	var member domain.SeriesPost
+	if err := r.db.Where("post_id = ?", postID).Take(&member).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get series: %w", err)
+	}
+	return r.GetByID(member.SeriesID)
+}
+
+// Memberships maps the posts among postIDs that belong to a series to the series.
+func (r *seriesRepository) Memberships(postIDs []uint) (map[uint]uint, error) {
+	out := make(map[uint]uint)
+	if len(postIDs) == 0 {
+		return out, nil
+	}
+	var members []domain.SeriesPost
+	if err := r.db.Where("post_id IN ?", postIDs).Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to get series posts: %w", err)
+	}
+	for _, m := range members {
+		out[m.PostID] = m.SeriesID
+	}
+	return out, nil
+}
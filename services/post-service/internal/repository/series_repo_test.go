@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+)
+
+func setupMockSeriesRepo(t *testing.T) (*seriesRepository, sqlmock.Sqlmock, func()) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	mock.MatchExpectationsInOrder(true)
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm open: %v", err)
+	}
+	cleanup := func() { _ = sqlDB.Close() }
+	return &seriesRepository{db: gdb}, mock, cleanup
+}
+
+const seriesPartsQuery = `SELECT series_posts.series_id, posts.id AS post_id, posts.title, posts.slug, posts.published FROM "series_posts" JOIN posts ON posts.id = series_posts.post_id WHERE series_posts.series_id IN ($1`
+
+var seriesPartColumns = []string{"series_id", "post_id", "title", "slug", "published"}
+
+func TestSeriesRepository_Create(t *testing.T) {
+	repo, mock, cleanup := setupMockSeriesRepo(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "series" ("title","description","author_id","created_at","updated_at") VALUES`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "series_posts" ("series_id","post_id","position") VALUES ($1,$2,$3),($4,$5,$6)`)).
+		WithArgs(uint(4), uint(9), 1, uint(4), uint(3), 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	s := &domain.Series{Title: "Go", AuthorID: 7}
+	if err := repo.Create(s, []uint{9, 3}); err != nil || s.ID != 4 {
+		t.Fatalf("create: id=%d err=%v", s.ID, err)
+	}
+	assertPostMock(t, mock)
+}
+
+func TestSeriesRepository_GetAndList(t *testing.T) {
+	repo, mock, cleanup := setupMockSeriesRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "series" WHERE "series"."id" = $1 LIMIT $2`)).
+		WithArgs(uint(4), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "author_id"}).AddRow(4, "Go", 7))
+	mock.ExpectQuery(regexp.QuoteMeta(seriesPartsQuery + `) ORDER BY series_posts.series_id, series_posts.position`)).
+		WithArgs(uint(4)).
+		WillReturnRows(sqlmock.NewRows(seriesPartColumns).AddRow(4, 9, "Part one", "part-one", true).AddRow(4, 3, "Part two", "part-two", false))
+	s, err := repo.GetByID(4)
+	if err != nil || len(s.Parts) != 2 || s.Parts[0].PostID != 9 || s.Parts[1].Published {
+		t.Fatalf("get: %+v err=%v", s, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "series" WHERE "series"."id" = $1 LIMIT $2`)).
+		WithArgs(uint(5), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if _, err := repo.GetByID(5); !errors.Is(err, domain.ErrSeriesNotFound) {
+		t.Fatalf("expected ErrSeriesNotFound, got %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "series" ORDER BY id DESC`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(6, "Rust").AddRow(4, "Go"))
+	mock.ExpectQuery(regexp.QuoteMeta(seriesPartsQuery+`,$2) ORDER BY series_posts.series_id, series_posts.position`)).
+		WithArgs(uint(6), uint(4)).
+		WillReturnRows(sqlmock.NewRows(seriesPartColumns).AddRow(4, 9, "Part one", "part-one", true))
+	list, err := repo.List()
+	if err != nil || len(list) != 2 || len(list[0].Parts) != 0 || len(list[1].Parts) != 1 {
+		t.Fatalf("list: %+v err=%v", list, err)
+	}
+	assertPostMock(t, mock)
+}
+
+func TestSeriesRepository_Update(t *testing.T) {
+	repo, mock, cleanup := setupMockSeriesRepo(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "series" SET "title"=$1,"description"=$2,"updated_at"=$3 WHERE "id" = $4`)).
+		WithArgs("Go 2", "", sqlmock.AnyArg(), uint(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "series_posts" WHERE series_id = $1`)).
+		WithArgs(uint(4)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "series_posts" ("series_id","post_id","position") VALUES ($1,$2,$3)`)).
+		WithArgs(uint(4), uint(3), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.Update(&domain.Series{ID: 4, Title: "Go 2"}, []uint{3}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	// nil keeps the parts; an empty list removes them all
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "series" SET`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.Update(&domain.Series{ID: 4, Title: "Go 2"}, nil); err != nil {
+		t.Fatalf("update without parts: %v", err)
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "series" SET`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "series_posts" WHERE series_id = $1`)).
+		WithArgs(uint(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.Update(&domain.Series{ID: 4, Title: "Go 2"}, []uint{}); err != nil {
+		t.Fatalf("update clearing parts: %v", err)
+	}
+	assertPostMock(t, mock)
+}
+
+func TestSeriesRepository_Memberships(t *testing.T) {
+	repo, mock, cleanup := setupMockSeriesRepo(t)
+	defer cleanup()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "series_posts" WHERE post_id = $1 LIMIT $2`)).
+		WithArgs(uint(8), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"series_id", "post_id", "position"}))
+	if s, err := repo.SeriesOf(8); err != nil || s != nil {
+		t.Fatalf("expected no series, got %+v err=%v", s, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "series_posts" WHERE post_id IN ($1,$2)`)).
+		WithArgs(uint(9), uint(8)).
+		WillReturnRows(sqlmock.NewRows([]string{"series_id", "post_id", "position"}).AddRow(4, 9, 1))
+	members, err := repo.Memberships([]uint{9, 8})
+	if err != nil || len(members) != 1 || members[9] != 4 {
+		t.Fatalf("memberships: %v err=%v", members, err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "series" WHERE "series"."id" = $1`)).
+		WithArgs(uint(4)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	if err := repo.Delete(4); !errors.Is(err, domain.ErrSeriesNotFound) {
+		t.Fatalf("expected ErrSeriesNotFound, got %v", err)
+	}
+	assertPostMock(t, mock)
+}
@@ -2,11 +2,23 @@ package repository
 
 import (
 	"fmt"
+	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/util"
 )
 
+// tagMatches is the condition on table for the tags a name refers to: the one named
+// like it regardless of case, or the one with it as an alias. It takes the name twice.
+func tagMatches(table string) string {
+	return fmt.Sprintf("(lower(%[1]s.name) = lower(?) OR %[1]s.id IN (SELECT tag_id FROM tag_aliases WHERE alias = lower(?)))", table)
+}
+
+// publishedPostCount counts the published posts of each tag in a query on tags.
+const publishedPostCount = "(SELECT COUNT(*) FROM post_tags JOIN posts ON posts.id = post_tags.post_id WHERE post_tags.tag_id = tags.id AND posts.published) AS post_count"
+
 type tagRepository struct {
 	db *gorm.DB
 }
@@ -28,17 +40,10 @@ func (r *tagRepository) AttachTagsToPost(postID uint, tagNames []string) error {
 		tx.Rollback()
 		return fmt.Errorf("post not found: %w", err)
 	}
-	var tags []*domain.Tag
-	for _, name := range tagNames {
-		if name == "" {
-			continue
-		}
-		var t domain.Tag
-		if err := tx.Where("name = ?", name).FirstOrCreate(&t, domain.Tag{Name: name}).Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to upsert tag %s: %w", name, err)
-		}
-		tags = append(tags, &t)
+	tags, err := resolveTags(tx, tagNames)
+	if err != nil {
+		tx.Rollback()
+		return err
 	}
 	if len(tags) > 0 {
 		if err := tx.Model(&post).Association("Tags").Append(tags); err != nil {
@@ -57,23 +62,38 @@ func (r *tagRepository) ReplaceTagsForPost(postID uint, tagNames []string) error
 		tx.Rollback()
 		return fmt.Errorf("post not found: %w", err)
 	}
+	tags, err := resolveTags(tx, tagNames)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Model(&post).Association("Tags").Replace(tags); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to replace tags for post: %w", err)
+	}
+	return tx.Commit().Error
+}
+
+// resolveTags finds or creates the tags named by names. A name matches an existing tag
+// regardless of case or through an alias, and names of the same tag give it once.
+func resolveTags(tx *gorm.DB, names []string) ([]*domain.Tag, error) {
 	var tags []*domain.Tag
-	for _, name := range tagNames {
+	seen := make(map[uint]bool, len(names))
+	for _, name := range names {
+		name = util.NormalizeTag(name)
 		if name == "" {
 			continue
 		}
 		var t domain.Tag
-		if err := tx.Where("name = ?", name).FirstOrCreate(&t, domain.Tag{Name: name}).Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to upsert tag %s: %w", name, err)
+		if err := tx.Where(tagMatches("tags"), name, name).Attrs(domain.Tag{Name: name}).FirstOrCreate(&t).Error; err != nil {
+			return nil, fmt.Errorf("failed to upsert tag %s: %w", name, err)
+		}
+		if !seen[t.ID] {
+			seen[t.ID] = true
+			tags = append(tags, &t)
 		}
-		tags = append(tags, &t)
-	}
-	if err := tx.Model(&post).Association("Tags").Replace(tags); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to replace tags for post: %w", err)
 	}
-	return tx.Commit().Error
+	return tags, nil
 }
 
 // GetTagsForPost returns tags attached to a post.
@@ -85,15 +105,142 @@ func (r *tagRepository) GetTagsForPost(postID uint) ([]*domain.Tag, error) {
 	return post.Tags, nil
 }
 
-// ListTags returns all tags ordered by name.
+// ListTags returns the tags of published posts ordered by name, with the number of
+// published posts of each, so the tags of drafts stay private.
 func (r *tagRepository) ListTags() ([]*domain.Tag, error) {
 	var tags []*domain.Tag
-	if err := r.db.Order("name ASC").Find(&tags).Error; err != nil {
+	err := r.db.Model(&domain.Tag{}).Select("tags.*, COUNT(*) AS post_count").
+		Joins("JOIN post_tags ON post_tags.tag_id = tags.id").Joins("JOIN posts ON posts.id = post_tags.post_id").
+		Where("posts.published").Group("tags.id").Order("lower(tags.name) ASC").Find(&tags).Error
+	if err != nil {
 		return nil, fmt.Errorf("failed to list tags: %w", err)
 	}
 	return tags, nil
 }
 
+// ListAllTags returns every tag ordered by name, with its aliases and the number of all
+// its posts, published or not.
+func (r *tagRepository) ListAllTags() ([]*domain.Tag, error) {
+	var tags []*domain.Tag
+	err := r.db.Model(&domain.Tag{}).Select("tags.*, COUNT(post_tags.post_id) AS post_count").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Group("tags.id").Order("lower(tags.name) ASC").Find(&tags).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	if err := r.loadAliases(tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// loadAliases sets the aliases of tags, in alphabetical order.
+func (r *tagRepository) loadAliases(tags []*domain.Tag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	byID := make(map[uint]*domain.Tag, len(tags))
+	ids := make([]uint, 0, len(tags))
+	for _, t := range tags {
+		byID[t.ID] = t
+		ids = append(ids, t.ID)
+	}
+	var aliases []*domain.TagAlias
+	if err := r.db.Where("tag_id IN ?", ids).Order("alias ASC").Find(&aliases).Error; err != nil {
+		return fmt.Errorf("failed to load tag aliases: %w", err)
+	}
+	for _, a := range aliases {
+		byID[a.TagID].Aliases = append(byID[a.TagID].Aliases, a.Alias)
+	}
+	return nil
+}
+
+// GetTagByID returns a tag with its aliases.
+func (r *tagRepository) GetTagByID(id uint) (*domain.Tag, error) {
+	var tag domain.Tag
+	if err := r.db.First(&tag, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrTagNotFound
+		}
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+	if err := r.loadAliases([]*domain.Tag{&tag}); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// FindTag finds the tag name refers to, with the number of its published posts.
+func (r *tagRepository) FindTag(name string) (*domain.Tag, error) {
+	var tag domain.Tag
+	err := r.db.Model(&domain.Tag{}).Select("tags.*, "+publishedPostCount).
+		Where(tagMatches("tags"), name, name).First(&tag).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrTagNotFound
+		}
+		return nil, fmt.Errorf("failed to find tag: %w", err)
+	}
+	return &tag, nil
+}
+
+// UpdateTag saves a tag's name and description.
+func (r *tagRepository) UpdateTag(tag *domain.Tag) error {
+	if err := r.db.Model(tag).Select("name", "description").Updates(tag).Error; err != nil {
+		return fmt.Errorf("failed to update tag: %w", err)
+	}
+	return nil
+}
+
+// MergeTags re-points the posts and aliases of fromID to intoID in one transaction,
+// then deletes fromID and keeps its name as an alias of intoID, so links to it still
+// find the posts.
+func (r *tagRepository) MergeTags(fromID, intoID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var from domain.Tag
+		if err := tx.First(&from, fromID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return domain.ErrTagNotFound
+			}
+			return fmt.Errorf("failed to get tag: %w", err)
+		}
+		if err := tx.Exec("INSERT INTO post_tags (post_id, tag_id) SELECT post_id, ? FROM post_tags WHERE tag_id = ? ON CONFLICT DO NOTHING", intoID, fromID).Error; err != nil {
+			return fmt.Errorf("failed to move tagged posts: %w", err)
+		}
+		if err := tx.Model(&domain.TagAlias{}).Where("tag_id = ?", fromID).Update("tag_id", intoID).Error; err != nil {
+			return fmt.Errorf("failed to move tag aliases: %w", err)
+		}
+		if err := tx.Delete(&domain.Tag{}, fromID).Error; err != nil {
+			return fmt.Errorf("failed to delete merged tag: %w", err)
+		}
+		alias := &domain.TagAlias{Alias: strings.ToLower(from.Name), TagID: intoID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(alias).Error; err != nil {
+			return fmt.Errorf("failed to keep merged tag name: %w", err)
+		}
+		return nil
+	})
+}
+
+// AddAlias adds alias, in lowercase, to a tag.
+func (r *tagRepository) AddAlias(tagID uint, alias string) error {
+	if err := r.db.Create(&domain.TagAlias{Alias: strings.ToLower(alias), TagID: tagID}).Error; err != nil {
+		return fmt.Errorf("failed to add tag alias: %w", err)
+	}
+	return nil
+}
+
+// DeleteAlias removes alias from a tag.
+func (r *tagRepository) DeleteAlias(tagID uint, alias string) error {
+	result := r.db.Where("tag_id = ? AND alias = ?", tagID, strings.ToLower(alias)).Delete(&domain.TagAlias{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete tag alias: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrTagNotFound
+	}
+	return nil
+}
+
 // DeleteTag deletes a tag by its ID.
 func (r *tagRepository) DeleteTag(id uint) error {
 	if err := r.db.Delete(&domain.Tag{}, id).Error; err != nil {
@@ -102,14 +249,16 @@ func (r *tagRepository) DeleteTag(id uint) error {
 	return nil
 }
 
-// DeleteUnusedTag deletes the tag if it is not associated with any posts.
+// DeleteUnusedTag deletes the tag if it is not associated with any posts. Tags an admin
+// has described or given aliases are kept for the posts to come.
 func (r *tagRepository) DeleteUnusedTag(tagID uint) error {
 	var count int64
 	if err := r.db.Model(&domain.Post{}).Joins("JOIN post_tags ON posts.id = post_tags.post_id").Where("post_tags.tag_id = ?", tagID).Count(&count).Error; err != nil {
 		return fmt.Errorf("failed to count tag usage: %w", err)
 	}
 	if count == 0 {
-		if err := r.db.Delete(&domain.Tag{}, tagID).Error; err != nil {
+		curated := "description <> '' OR EXISTS (SELECT 1 FROM tag_aliases WHERE tag_aliases.tag_id = tags.id)"
+		if err := r.db.Not(curated).Delete(&domain.Tag{}, tagID).Error; err != nil {
 			return fmt.Errorf("failed to delete unused tag: %w", err)
 		}
 	}
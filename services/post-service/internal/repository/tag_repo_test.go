@@ -9,6 +9,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
 )
 
 func setupMockTagRepo(t *testing.T) (*tagRepository, sqlmock.Sqlmock, func()) {
@@ -55,7 +56,8 @@ func TestTagRepository_AttachTagsToPost(t *testing.T) {
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "posts" WHERE "posts"."id" = $1 ORDER BY "posts"."id" LIMIT $2`)).
 		WithArgs(uint(1), 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-	mock.ExpectQuery(`SELECT \* FROM "tags" WHERE name = \$1 .*ORDER BY "tags"\."id" LIMIT \$3`).
+	mock.ExpectQuery(`SELECT \* FROM "tags" WHERE \(lower\(tags\.name\) = lower\(\$1\) OR tags\.id IN \(SELECT tag_id FROM tag_aliases WHERE alias = lower\(\$2\)\)\) ORDER BY "tags"\."id" LIMIT \$3`).
+		WithArgs("go", "go", 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "updated_at"}))
 	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "tags"`)).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
@@ -131,14 +133,14 @@ func TestTagRepository_ReadAndDeleteMethods(t *testing.T) {
 		t.Fatalf("expected get tags db error, got %v", err)
 	}
 
-	mock.ExpectQuery(`SELECT \* FROM "tags" ORDER BY name ASC`).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "go").AddRow(2, "web"))
+	mock.ExpectQuery(`SELECT tags\.\*, COUNT\(\*\) AS post_count FROM "tags" JOIN post_tags ON post_tags\.tag_id = tags\.id JOIN posts ON posts\.id = post_tags\.post_id WHERE posts\.published GROUP BY "tags"\."id" ORDER BY lower\(tags\.name\) ASC`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "post_count"}).AddRow(1, "go", 3).AddRow(2, "web", 1))
 	all, err := repo.ListTags()
-	if err != nil || len(all) != 2 {
+	if err != nil || len(all) != 2 || all[0].PostCount != 3 {
 		t.Fatalf("list tags failed: tags=%v err=%v", all, err)
 	}
 
-	mock.ExpectQuery(`SELECT \* FROM "tags" ORDER BY name ASC`).
+	mock.ExpectQuery(`SELECT tags\.\*, COUNT\(\*\) AS post_count FROM "tags"`).
 		WillReturnError(errors.New("list fail"))
 	if _, err := repo.ListTags(); err == nil || !strings.Contains(err.Error(), "failed to list tags") {
 		t.Fatalf("expected list db error, got %v", err)
@@ -173,7 +175,7 @@ func TestTagRepository_ReadAndDeleteMethods(t *testing.T) {
 		WithArgs(uint(4)).
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "tags" WHERE "tags"."id" = $1`)).
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "tags" WHERE NOT (description <> '' OR EXISTS (SELECT 1 FROM tag_aliases WHERE tag_aliases.tag_id = tags.id)) AND "tags"."id" = $1`)).
 		WithArgs(uint(4)).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
@@ -190,3 +192,127 @@ func TestTagRepository_ReadAndDeleteMethods(t *testing.T) {
 
 	assertTagMock(t, mock)
 }
+
+func TestTagRepository_AttachResolvesSpellings(t *testing.T) {
+	repo, mock, cleanup := setupMockTagRepo(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "posts" WHERE "posts"."id" = $1 ORDER BY "posts"."id" LIMIT $2`)).
+		WithArgs(uint(1), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	// "golang" is an alias of Go and "GO " is Go in other case: both are tag 3
+	for _, name := range []string{"golang", "GO"} {
+		mock.ExpectQuery(`SELECT \* FROM "tags" WHERE \(lower\(tags\.name\) = lower\(\$1\)`).
+			WithArgs(name, name, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "Go"))
+	}
+	mock.ExpectQuery(`INSERT INTO "tags" .*ON CONFLICT DO NOTHING RETURNING "id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectExec(`INSERT INTO "post_tags" \("post_id","tag_id"\) VALUES \(\$1,\$2\) ON CONFLICT DO NOTHING`).
+		WithArgs(uint(1), uint(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "posts" SET "updated_at"=\$1 WHERE "id" = \$2`).
+		WithArgs(sqlmock.AnyArg(), uint(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := repo.AttachTagsToPost(1, []string{"golang", " GO ", "  "}); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	assertTagMock(t, mock)
+}
+
+func TestTagRepository_AdminMethods(t *testing.T) {
+	repo, mock, cleanup := setupMockTagRepo(t)
+	defer cleanup()
+	mock.MatchExpectationsInOrder(true)
+
+	mock.ExpectQuery(`SELECT tags\.\*, COUNT\(post_tags\.post_id\) AS post_count FROM "tags" LEFT JOIN post_tags ON post_tags\.tag_id = tags\.id GROUP BY "tags"\."id" ORDER BY lower\(tags\.name\) ASC`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "post_count"}).AddRow(1, "Go", 2).AddRow(2, "web", 0))
+	mock.ExpectQuery(`SELECT \* FROM "tag_aliases" WHERE tag_id IN \(\$1,\$2\) ORDER BY alias ASC`).
+		WithArgs(uint(1), uint(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"alias", "tag_id"}).AddRow("go-lang", 1).AddRow("golang", 1))
+	all, err := repo.ListAllTags()
+	if err != nil || len(all) != 2 || all[0].PostCount != 2 || len(all[0].Aliases) != 2 || all[1].Aliases != nil {
+		t.Fatalf("list all tags: %+v err=%v", all, err)
+	}
+
+	mock.ExpectQuery(`SELECT \* FROM "tags" WHERE "tags"\."id" = \$1`).
+		WithArgs(uint(9), 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	if _, err := repo.GetTagByID(9); !errors.Is(err, domain.ErrTagNotFound) {
+		t.Fatalf("expected ErrTagNotFound, got %v", err)
+	}
+
+	mock.ExpectQuery(`SELECT tags\.\*, \(SELECT COUNT\(\*\) FROM post_tags JOIN posts ON posts\.id = post_tags\.post_id WHERE post_tags\.tag_id = tags\.id AND posts\.published\) AS post_count FROM "tags" WHERE \(lower\(tags\.name\) = lower\(\$1\) OR tags\.id IN \(SELECT tag_id FROM tag_aliases WHERE alias = lower\(\$2\)\)\)`).
+		WithArgs("Golang", "Golang", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "post_count"}).AddRow(1, "Go", "The Go language", 2))
+	if tag, err := repo.FindTag("Golang"); err != nil || tag.Name != "Go" || tag.PostCount != 2 {
+		t.Fatalf("find tag: %+v err=%v", tag, err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "tags" SET "name"=\$1,"description"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+		WithArgs("Go", "The Go language", sqlmock.AnyArg(), uint(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.UpdateTag(&domain.Tag{ID: 1, Name: "Go", Description: "The Go language"}); err != nil {
+		t.Fatalf("update tag: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "tags" WHERE "tags"\."id" = \$1`).
+		WithArgs(uint(2), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "Golang"))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO post_tags (post_id, tag_id) SELECT post_id, $1 FROM post_tags WHERE tag_id = $2 ON CONFLICT DO NOTHING`)).
+		WithArgs(uint(1), uint(2)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`UPDATE "tag_aliases" SET "tag_id"=\$1 WHERE tag_id = \$2`).
+		WithArgs(uint(1), uint(2)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "tags" WHERE "tags"."id" = $1`)).
+		WithArgs(uint(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO "tag_aliases" \("alias","tag_id","created_at"\) VALUES \(\$1,\$2,\$3\) ON CONFLICT DO NOTHING`).
+		WithArgs("golang", uint(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.MergeTags(2, 1); err != nil {
+		t.Fatalf("merge tags: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "tags" WHERE "tags"\."id" = \$1`).
+		WithArgs(uint(8), 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectRollback()
+	if err := repo.MergeTags(8, 1); !errors.Is(err, domain.ErrTagNotFound) {
+		t.Fatalf("expected ErrTagNotFound, got %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "tag_aliases" \("alias","tag_id","created_at"\) VALUES \(\$1,\$2,\$3\)`).
+		WithArgs("go-lang", uint(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.AddAlias(1, "Go-Lang"); err != nil {
+		t.Fatalf("add alias: %v", err)
+	}
+
+	for _, affected := range []int64{1, 0} {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "tag_aliases" WHERE tag_id = $1 AND alias = $2`)).
+			WithArgs(uint(1), "go-lang").
+			WillReturnResult(sqlmock.NewResult(0, affected))
+		mock.ExpectCommit()
+	}
+	if err := repo.DeleteAlias(1, "go-lang"); err != nil {
+		t.Fatalf("delete alias: %v", err)
+	}
+	if err := repo.DeleteAlias(1, "go-lang"); !errors.Is(err, domain.ErrTagNotFound) {
+		t.Fatalf("expected ErrTagNotFound, got %v", err)
+	}
+
+	assertTagMock(t, mock)
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/config"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+// Limits on what a comment may hold.
+const (
+	maxCommentBody  = 4000
+	maxCommentName  = 80
+	maxCommentEmail = 254
+)
+
+// The moderation queue lists defaultQueueLimit comments unless asked for up to maxQueueLimit.
+const (
+	defaultQueueLimit = 50
+	maxQueueLimit     = 200
+)
+
+type commentService struct {
+	comments domain.CommentRepository
+	posts    domain.PostRepository
+	config   *config.PostConfig
+	auditor  audit.Recorder
+	events   domain.EventBroker
+	now      func() time.Time
+}
+
+// NewCommentService creates a new CommentService. auditor and events may be nil, in
+// which case moderation is not audited and authors are not notified of new comments.
+func NewCommentService(comments domain.CommentRepository, posts domain.PostRepository, config *config.PostConfig, auditor audit.Recorder, events domain.EventBroker) domain.CommentService {
+	return &commentService{comments: comments, posts: posts, config: config, auditor: auditor, events: events, now: time.Now}
+}
+
+// AddComment validates and stores a comment on a published post. A filled-in honeypot
+// gets the answer a person would get, but the comment is dropped.
+func (s *commentService) AddComment(ctx context.Context, postID uint, req model.CreateCommentRequest, by model.Commenter) (*domain.Comment, error) {
+	comment, err := newComment(postID, req, by)
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.posts.GetByID(postID)
+	if err != nil || !post.Published {
+		return nil, domain.ErrPostNotFound
+	}
+	if req.ParentID != nil {
+		parent, err := s.comments.GetByID(*req.ParentID)
+		if err != nil || parent.PostID != postID || parent.Status != domain.CommentApproved {
+			return nil, fmt.Errorf("%w: the comment replied to was not found", domain.ErrInvalidComment)
+		}
+	}
+	byAuthor := by.UserID != nil && *by.UserID == post.AuthorID
+	if strings.TrimSpace(req.Website) != "" {
+		comment.CreatedAt = s.now()
+		return comment, nil
+	}
+	if !byAuthor && by.IP != "" && s.config.CommentRateLimit > 0 {
+		n, err := s.comments.CountRecent(by.IP, s.now().Add(-s.config.CommentRateWindow))
+		if err != nil {
+			return nil, err
+		}
+		if n >= int64(s.config.CommentRateLimit) {
+			return nil, domain.ErrCommentRateLimited
+		}
+	}
+	if byAuthor {
+		comment.Status = domain.CommentApproved
+	}
+	if err := s.comments.Create(comment); err != nil {
+		return nil, err
+	}
+	if comment.Status == domain.CommentPending {
+		s.notify(domain.EventCommentPending, postID, post.AuthorID, "new comment from "+comment.AuthorName+" awaits moderation")
+	}
+	return comment, nil
+}
+
+// newComment builds a pending comment from a submission, or reports why it is refused.
+// Signed-in readers comment under their username; anonymous ones give a name and email.
+func newComment(postID uint, req model.CreateCommentRequest, by model.Commenter) (*domain.Comment, error) {
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return nil, fmt.Errorf("%w: the comment is empty", domain.ErrInvalidComment)
+	}
+	if utf8.RuneCountInString(body) > maxCommentBody {
+		return nil, fmt.Errorf("%w: comments are limited to %d characters", domain.ErrInvalidComment, maxCommentBody)
+	}
+	name := strings.TrimSpace(by.Username)
+	if by.UserID == nil || name == "" {
+		name = strings.TrimSpace(req.Name)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%w: a name is required", domain.ErrInvalidComment)
+	}
+	if utf8.RuneCountInString(name) > maxCommentName {
+		return nil, fmt.Errorf("%w: names are limited to %d characters", domain.ErrInvalidComment, maxCommentName)
+	}
+	email := strings.TrimSpace(req.Email)
+	if email == "" && by.UserID == nil {
+		return nil, fmt.Errorf("%w: an email address is required", domain.ErrInvalidComment)
+	}
+	if email != "" {
+		addr, err := mail.ParseAddress(email)
+		if err != nil || addr.Address != email || len(email) > maxCommentEmail {
+			return nil, fmt.Errorf("%w: the email address is not valid", domain.ErrInvalidComment)
+		}
+	}
+	return &domain.Comment{
+		PostID:      postID,
+		ParentID:    req.ParentID,
+		UserID:      by.UserID,
+		AuthorName:  name,
+		AuthorEmail: email,
+		Body:        body,
+		Status:      domain.CommentPending,
+		IP:          by.IP,
+	}, nil
+}
+
+// ListComments returns the approved comments of a published post, oldest first, with
+// replies nested under the comment they answer. Replies to a comment that is not
+// shown are not shown either.
+func (s *commentService) ListComments(postID uint) ([]*domain.Comment, error) {
+	post, err := s.posts.GetByID(postID)
+	if err != nil || !post.Published {
+		return nil, domain.ErrPostNotFound
+	}
+	comments, err := s.comments.ListForPost(postID, domain.CommentApproved)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*domain.Comment, len(comments))
+	var threads []*domain.Comment
+	for _, c := range comments {
+		c.AuthorEmail = ""
+		byID[c.ID] = c
+		if c.ParentID == nil {
+			threads = append(threads, c)
+		} else if parent, ok := byID[*c.ParentID]; ok {
+			parent.Replies = append(parent.Replies, c)
+		}
+	}
+	return threads, nil
+}
+
+// ModerationQueue lists the comments with status, pending by default, on the author's
+// posts, newest first.
+func (s *commentService) ModerationQueue(authorID uint, status string, limit int) ([]*domain.Comment, error) {
+	if status == "" {
+		status = domain.CommentPending
+	}
+	if !validCommentStatus(status) {
+		return nil, fmt.Errorf("%w: unknown status %q", domain.ErrInvalidComment, status)
+	}
+	if limit <= 0 {
+		limit = defaultQueueLimit
+	} else if limit > maxQueueLimit {
+		limit = maxQueueLimit
+	}
+	return s.comments.ListForAuthor(authorID, status, limit)
+}
+
+// ModerateComment approves a comment on the author's post, marks it spam or puts it
+// back in the queue.
+func (s *commentService) ModerateComment(ctx context.Context, id uint, status string, authorID uint) (*domain.Comment, error) {
+	if !validCommentStatus(status) {
+		return nil, fmt.Errorf("%w: unknown status %q", domain.ErrInvalidComment, status)
+	}
+	comment, err := s.authorComment(id, authorID)
+	if err != nil {
+		return nil, err
+	}
+	before := commentSummary(comment)
+	err = s.comments.SetStatus(id, status)
+	if err == nil {
+		comment.Status = status
+	}
+	s.record(ctx, "comment.moderate", id, before, commentSummary(comment), err)
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// DeleteComment removes a comment on the author's post together with its replies.
+func (s *commentService) DeleteComment(ctx context.Context, id, authorID uint) error {
+	comment, err := s.authorComment(id, authorID)
+	if err != nil {
+		return err
+	}
+	err = s.comments.Delete(id)
+	s.record(ctx, "comment.delete", id, commentSummary(comment), nil, err)
+	return err
+}
+
+// authorComment returns a comment on one of authorID's posts; comments on other
+// authors' posts are reported as not found.
+func (s *commentService) authorComment(id, authorID uint) (*domain.Comment, error) {
+	comment, err := s.comments.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.posts.GetByID(comment.PostID)
+	if err != nil || post.AuthorID != authorID {
+		return nil, domain.ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+func validCommentStatus(status string) bool {
+	switch status {
+	case domain.CommentPending, domain.CommentApproved, domain.CommentSpam:
+		return true
+	}
+	return false
+}
+
+// notify publishes a live notification to the post's author.
+func (s *commentService) notify(eventType string, postID, authorID uint, message string) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(domain.Event{Type: eventType, PostID: postID, AuthorID: authorID, Message: message})
+}
+
+// commentSummary is the audit before/after snapshot of a comment.
+func commentSummary(comment *domain.Comment) interface{} {
+	if comment == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"post_id":     comment.PostID,
+		"author_name": comment.AuthorName,
+		"status":      comment.Status,
+	}
+}
+
+// record appends an audit entry for a moderation action.
+func (s *commentService) record(ctx context.Context, action string, id uint, before, after interface{}, err error) {
+	audit.Record(ctx, s.auditor, &audit.Entry{
+		Action:     action,
+		TargetType: "comment",
+		TargetID:   strconv.FormatUint(uint64(id), 10),
+		Before:     audit.Summary(before),
+		After:      audit.Summary(after),
+	}, err)
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/config"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+// stubCommentRepo keeps comments in memory; recent is what CountRecent reports.
+type stubCommentRepo struct {
+	comments []*domain.Comment
+	recent   int64
+	since    time.Time
+}
+
+func (s *stubCommentRepo) Create(c *domain.Comment) error {
+	c.ID = uint(len(s.comments) + 1)
+	s.comments = append(s.comments, c)
+	return nil
+}
+
+func (s *stubCommentRepo) GetByID(id uint) (*domain.Comment, error) {
+	for _, c := range s.comments {
+		if c.ID == id {
+			copied := *c
+			return &copied, nil
+		}
+	}
+	return nil, domain.ErrCommentNotFound
+}
+
+func (s *stubCommentRepo) ListForPost(postID uint, status string) ([]*domain.Comment, error) {
+	var out []*domain.Comment
+	for _, c := range s.comments {
+		if c.PostID == postID && c.Status == status {
+			copied := *c
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+func (s *stubCommentRepo) ListForAuthor(authorID uint, status string, limit int) ([]*domain.Comment, error) {
+	return nil, nil
+}
+
+func (s *stubCommentRepo) SetStatus(id uint, status string) error {
+	for _, c := range s.comments {
+		if c.ID == id {
+			c.Status = status
+			return nil
+		}
+	}
+	return domain.ErrCommentNotFound
+}
+
+func (s *stubCommentRepo) Delete(id uint) error {
+	for i, c := range s.comments {
+		if c.ID == id {
+			s.comments = append(s.comments[:i], s.comments[i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrCommentNotFound
+}
+
+func (s *stubCommentRepo) CountRecent(ip string, since time.Time) (int64, error) {
+	s.since = since
+	return s.recent, nil
+}
+
+func newCommentSvcForTest(repo *stubCommentRepo, events domain.EventBroker) *commentService {
+	posts := &stubPostRepo{getByID: func(id uint) (*domain.Post, error) {
+		switch id {
+		case 1:
+			return &domain.Post{ID: 1, AuthorID: 7, Published: true}, nil
+		case 2:
+			return &domain.Post{ID: 2, AuthorID: 7}, nil
+		}
+		return nil, errors.New("post not found")
+	}}
+	cfg := &config.PostConfig{CommentRateLimit: 3, CommentRateWindow: 10 * time.Minute}
+	return NewCommentService(repo, posts, cfg, nil, events).(*commentService)
+}
+
+func TestAddComment_Validation(t *testing.T) {
+	svc := newCommentSvcForTest(&stubCommentRepo{}, nil)
+	anonymous := model.Commenter{IP: "10.0.0.1"}
+	cases := []struct {
+		name string
+		req  model.CreateCommentRequest
+		by   model.Commenter
+	}{
+		{"empty body", model.CreateCommentRequest{Name: "kim", Email: "kim@example.com", Body: "  "}, anonymous},
+		{"no name", model.CreateCommentRequest{Email: "kim@example.com", Body: "hi"}, anonymous},
+		{"no email", model.CreateCommentRequest{Name: "kim", Body: "hi"}, anonymous},
+		{"bad email", model.CreateCommentRequest{Name: "kim", Email: "Kim <kim@example.com>", Body: "hi"}, anonymous},
+		{"missing parent", model.CreateCommentRequest{Name: "kim", Email: "kim@example.com", Body: "hi", ParentID: new(uint)}, anonymous},
+	}
+	for _, tc := range cases {
+		if _, err := svc.AddComment(context.Background(), 1, tc.req, tc.by); !errors.Is(err, domain.ErrInvalidComment) {
+			t.Errorf("%s: expected ErrInvalidComment, got %v", tc.name, err)
+		}
+	}
+	valid := model.CreateCommentRequest{Name: "kim", Email: "kim@example.com", Body: "hi"}
+	for _, postID := range []uint{2, 3} {
+		if _, err := svc.AddComment(context.Background(), postID, valid, anonymous); !errors.Is(err, domain.ErrPostNotFound) {
+			t.Errorf("post %d: expected ErrPostNotFound, got %v", postID, err)
+		}
+	}
+}
+
+func TestAddComment_ModerationAndNotification(t *testing.T) {
+	repo := &stubCommentRepo{}
+	events := NewEventBroker()
+	ch, cancel := events.Subscribe(7)
+	defer cancel()
+	svc := newCommentSvcForTest(repo, events)
+
+	reader := uint(9)
+	c, err := svc.AddComment(context.Background(), 1, model.CreateCommentRequest{Name: "ignored", Body: " first! "},
+		model.Commenter{UserID: &reader, Username: "reader9", IP: "10.0.0.1"})
+	if err != nil || c.Status != domain.CommentPending || c.AuthorName != "reader9" || c.Body != "first!" {
+		t.Fatalf("unexpected comment %+v err=%v", c, err)
+	}
+	select {
+	case ev := <-ch:
+		if ev.Type != domain.EventCommentPending || ev.PostID != 1 {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the author to be notified")
+	}
+
+	author := uint(7)
+	reply, err := svc.AddComment(context.Background(), 1, model.CreateCommentRequest{Body: "thanks", ParentID: &c.ID},
+		model.Commenter{UserID: &author, Username: "owner", IP: "10.0.0.2"})
+	if !errors.Is(err, domain.ErrInvalidComment) {
+		t.Fatalf("replies to pending comments must be refused, got %+v err=%v", reply, err)
+	}
+	repo.comments[0].Status = domain.CommentApproved
+	reply, err = svc.AddComment(context.Background(), 1, model.CreateCommentRequest{Body: "thanks", ParentID: &c.ID},
+		model.Commenter{UserID: &author, Username: "owner", IP: "10.0.0.2"})
+	if err != nil || reply.Status != domain.CommentApproved {
+		t.Fatalf("expected the author's reply to be approved, got %+v err=%v", reply, err)
+	}
+	select {
+	case ev := <-ch:
+		t.Fatalf("the author's own comment must not notify, got %+v", ev)
+	default:
+	}
+}
+
+func TestAddComment_HoneypotAndRateLimit(t *testing.T) {
+	repo := &stubCommentRepo{}
+	svc := newCommentSvcForTest(repo, nil)
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	req := model.CreateCommentRequest{Name: "bot", Email: "bot@example.com", Body: "buy now", Website: "http://spam.example"}
+
+	c, err := svc.AddComment(context.Background(), 1, req, model.Commenter{IP: "10.0.0.1"})
+	if err != nil || c.Status != domain.CommentPending || len(repo.comments) != 0 {
+		t.Fatalf("expected the honeypot to look accepted and store nothing, got %+v err=%v stored=%d", c, err, len(repo.comments))
+	}
+
+	req.Website = ""
+	repo.recent = 3
+	if _, err := svc.AddComment(context.Background(), 1, req, model.Commenter{IP: "10.0.0.1"}); !errors.Is(err, domain.ErrCommentRateLimited) {
+		t.Fatalf("expected ErrCommentRateLimited, got %v", err)
+	}
+	if !repo.since.Equal(now.Add(-10 * time.Minute)) {
+		t.Fatalf("expected the window to start 10 minutes ago, got %v", repo.since)
+	}
+	repo.recent = 2
+	if _, err := svc.AddComment(context.Background(), 1, req, model.Commenter{IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("expected the comment under the limit, got %v", err)
+	}
+}
+
+func TestListComments_Threads(t *testing.T) {
+	parent := func(id uint) *uint { return &id }
+	repo := &stubCommentRepo{comments: []*domain.Comment{
+		{ID: 1, PostID: 1, Status: domain.CommentApproved, AuthorEmail: "a@example.com"},
+		{ID: 2, PostID: 1, Status: domain.CommentSpam},
+		{ID: 3, PostID: 1, ParentID: parent(1), Status: domain.CommentApproved},
+		{ID: 4, PostID: 1, ParentID: parent(2), Status: domain.CommentApproved},
+		{ID: 5, PostID: 1, ParentID: parent(3), Status: domain.CommentApproved},
+		{ID: 6, PostID: 1, Status: domain.CommentApproved},
+	}}
+	svc := newCommentSvcForTest(repo, nil)
+	threads, err := svc.ListComments(1)
+	if err != nil || len(threads) != 2 || threads[0].ID != 1 || threads[1].ID != 6 {
+		t.Fatalf("unexpected threads %+v err=%v", threads, err)
+	}
+	if threads[0].AuthorEmail != "" {
+		t.Fatal("public comments must not carry the email address")
+	}
+	if len(threads[0].Replies) != 1 || len(threads[0].Replies[0].Replies) != 1 || threads[0].Replies[0].Replies[0].ID != 5 {
+		t.Fatalf("unexpected replies %+v", threads[0].Replies)
+	}
+	if _, err := svc.ListComments(2); !errors.Is(err, domain.ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound for a draft, got %v", err)
+	}
+}
+
+func TestModerateComment(t *testing.T) {
+	repo := &stubCommentRepo{comments: []*domain.Comment{{ID: 1, PostID: 1, Status: domain.CommentPending}}}
+	svc := newCommentSvcForTest(repo, nil)
+	rec := &stubRecorder{}
+	svc.auditor = rec
+
+	if _, err := svc.ModerateComment(context.Background(), 1, "hidden", 7); !errors.Is(err, domain.ErrInvalidComment) {
+		t.Fatalf("expected ErrInvalidComment, got %v", err)
+	}
+	if _, err := svc.ModerateComment(context.Background(), 1, domain.CommentApproved, 8); !errors.Is(err, domain.ErrCommentNotFound) {
+		t.Fatalf("other authors must not moderate, got %v", err)
+	}
+	c, err := svc.ModerateComment(context.Background(), 1, domain.CommentApproved, 7)
+	if err != nil || c.Status != domain.CommentApproved || repo.comments[0].Status != domain.CommentApproved {
+		t.Fatalf("unexpected comment %+v err=%v", c, err)
+	}
+	if err := svc.DeleteComment(context.Background(), 1, 7); err != nil || len(repo.comments) != 0 {
+		t.Fatalf("delete: err=%v left=%d", err, len(repo.comments))
+	}
+	if got := rec.actions(); len(got) != 2 || got[0] != "comment.moderate:success" || got[1] != "comment.delete:success" {
+		t.Fatalf("unexpected audit %v", got)
+	}
+	if _, err := svc.ModerationQueue(7, "hidden", 0); !errors.Is(err, domain.ErrInvalidComment) {
+		t.Fatalf("expected ErrInvalidComment, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/pkg/logger"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+)
+
+// subscriberBuffer is how many undelivered events a subscriber may queue before
+// further events are dropped for it.
+const subscriberBuffer = 16
+
+// eventBroker is an in-process domain.EventBroker.
+type eventBroker struct {
+	mu     sync.Mutex
+	subs   map[uint]map[chan domain.Event]struct{}
+	logger *logger.Logger
+}
+
+// NewEventBroker creates an in-memory EventBroker.
+func NewEventBroker() domain.EventBroker {
+	return &eventBroker{subs: make(map[uint]map[chan domain.Event]struct{}), logger: logger.New("info")}
+}
+
+// Publish delivers event to every subscription of its author without blocking.
+func (b *eventBroker) Publish(event domain.Event) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[event.AuthorID] {
+		select {
+		case ch <- event:
+		default:
+			b.logger.Error(fmt.Sprintf("Dropped %s event for post %d: subscriber is not keeping up", event.Type, event.PostID))
+		}
+	}
+}
+
+// Subscribe registers a subscription for authorID.
+func (b *eventBroker) Subscribe(authorID uint) (<-chan domain.Event, func()) {
+	ch := make(chan domain.Event, subscriberBuffer)
+	b.mu.Lock()
+	if b.subs[authorID] == nil {
+		b.subs[authorID] = make(map[chan domain.Event]struct{})
+	}
+	b.subs[authorID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs[authorID], ch)
+			if len(b.subs[authorID]) == 0 {
+				delete(b.subs, authorID)
+			}
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
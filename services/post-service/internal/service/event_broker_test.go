@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+)
+
+func TestEventBroker_DeliversOnlyToAuthor(t *testing.T) {
+	b := NewEventBroker()
+	mine, cancelMine := b.Subscribe(7)
+	defer cancelMine()
+	other, cancelOther := b.Subscribe(8)
+	defer cancelOther()
+
+	b.Publish(domain.Event{Type: domain.EventTranslationCompleted, PostID: 1, AuthorID: 7})
+
+	select {
+	case e := <-mine:
+		if e.Type != domain.EventTranslationCompleted || e.PostID != 1 || e.At.IsZero() {
+			t.Fatalf("unexpected event: %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected event for author")
+	}
+	select {
+	case e := <-other:
+		t.Fatalf("unexpected event for other user: %+v", e)
+	default:
+	}
+}
+
+func TestEventBroker_SlowSubscriberDoesNotBlock(t *testing.T) {
+	b := NewEventBroker()
+	ch, cancel := b.Subscribe(1)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBuffer*2; i++ {
+			b.Publish(domain.Event{Type: domain.EventImagesProcessed, AuthorID: 1})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("publish blocked on a full subscriber")
+	}
+	if len(ch) != subscriberBuffer {
+		t.Fatalf("expected %d buffered events, got %d", subscriberBuffer, len(ch))
+	}
+}
+
+func TestEventBroker_CancelClosesChannel(t *testing.T) {
+	b := NewEventBroker()
+	ch, cancel := b.Subscribe(1)
+	cancel()
+	cancel() // idempotent
+	if _, ok := <-ch; ok {
+		t.Fatalf("expected closed channel")
+	}
+	// publishing after cancel must not panic
+	b.Publish(domain.Event{Type: domain.EventPostPublished, AuthorID: 1})
+}
@@ -1,27 +1,251 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
 	"seungpyo.lee/PersonalWebSite/pkg/logger"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/adapter"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/config"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/util"
+)
+
+// maxSlugAttempts bounds the numbered variants tried when a generated slug is taken.
+const maxSlugAttempts = 100
+
+// Search pages hold defaultSearchLimit hits unless the caller asks for up to maxSearchLimit.
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+)
+
+// Related posts come defaultRelatedLimit at a time unless the caller asks for up to
+// maxRelatedLimit, which is how many are ranked and cached for each post.
+const (
+	defaultRelatedLimit = 3
+	maxRelatedLimit     = 10
 )
 
 type postService struct {
 	postRepo     domain.PostRepository
 	tagRepo      domain.TagRepository
+	seriesRepo   domain.SeriesRepository
 	config       *config.PostConfig
 	imageAdapter adapter.ImageAdapter
 	transAdapter adapter.TranslationAdapter
+	auditor      audit.Recorder
+	events       domain.EventBroker
+	related      domain.RelatedCache
 	logger       *logger.Logger
 }
 
 // NewPostService creates a new PostService with the given repository.
-func NewPostService(postRepo domain.PostRepository, tagRepo domain.TagRepository, config *config.PostConfig, imageAdapter adapter.ImageAdapter, transAdapter adapter.TranslationAdapter) domain.PostService {
-	return &postService{postRepo: postRepo, tagRepo: tagRepo, config: config, imageAdapter: imageAdapter, transAdapter: transAdapter, logger: logger.New("info")}
+// seriesRepo, auditor, events and related may be nil, in which case posts come without
+// their series, mutations are not audited, no live notifications are published and
+// related posts are ranked on every request.
+func NewPostService(postRepo domain.PostRepository, tagRepo domain.TagRepository, seriesRepo domain.SeriesRepository, config *config.PostConfig, imageAdapter adapter.ImageAdapter, transAdapter adapter.TranslationAdapter, auditor audit.Recorder, events domain.EventBroker, related domain.RelatedCache) domain.PostService {
+	return &postService{postRepo: postRepo, tagRepo: tagRepo, seriesRepo: seriesRepo, config: config, imageAdapter: imageAdapter, transAdapter: transAdapter, auditor: auditor, events: events, related: related, logger: logger.New("info")}
+}
+
+// notify publishes a live notification to the post's author.
+func (s *postService) notify(eventType string, postID, authorID uint, message string) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(domain.Event{Type: eventType, PostID: postID, AuthorID: authorID, Message: message})
+}
+
+// postsChanged drops the cached related posts, as a change to any post can reorder
+// the related posts of every other one.
+func (s *postService) postsChanged() {
+	invalidateRelated(s.related, s.logger)
+}
+
+// invalidateRelated drops the entries of cache, if there is one. A failure is logged;
+// the entries expire on their own.
+func invalidateRelated(cache domain.RelatedCache, log *logger.Logger) {
+	if cache == nil {
+		return
+	}
+	if err := cache.Invalidate(); err != nil {
+		log.Error(fmt.Sprintf("failed to invalidate related posts: %v", err))
+	}
+}
+
+// postSummary is the audit before/after snapshot of a post.
+func postSummary(post *domain.Post) interface{} {
+	if post == nil {
+		return nil
+	}
+	tags := make([]string, 0, len(post.Tags))
+	for _, t := range post.Tags {
+		tags = append(tags, t.Name)
+	}
+	return map[string]interface{}{
+		"title":     post.Title,
+		"slug":      post.Slug,
+		"published": post.Published,
+		"thumbnail": post.Thumbnail,
+		"tags":      tags,
+	}
+}
+
+// record appends an audit entry for a post or image action.
+func (s *postService) record(ctx context.Context, action, targetType, targetID string, before, after interface{}, err error) {
+	audit.Record(ctx, s.auditor, &audit.Entry{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     audit.Summary(before),
+		After:      audit.Summary(after),
+	}, err)
+}
+
+func (s *postService) recordPost(ctx context.Context, action string, id uint, before, after *domain.Post, err error) {
+	targetID := ""
+	if id != 0 {
+		targetID = strconv.FormatUint(uint64(id), 10)
+	}
+	s.record(ctx, action, "post", targetID, postSummary(before), postSummary(after), err)
+}
+
+// uniqueSlug generates a slug from title that no other post uses or used, numbering it
+// -2, -3, ... when needed. postID is the post the slug is for, or 0 for a new post.
+func uniqueSlug(repo domain.PostRepository, title string, postID uint) (string, error) {
+	base := util.Slugify(title)
+	if base == "" {
+		base = "post"
+	} else if !util.ValidSlug(base) {
+		base = "post-" + base // only digits would read as a post ID
+	}
+	for n := 1; n <= maxSlugAttempts; n++ {
+		candidate := base
+		if n > 1 {
+			suffix := "-" + strconv.Itoa(n)
+			if len(candidate)+len(suffix) > util.MaxSlugLength {
+				candidate = strings.TrimRight(candidate[:util.MaxSlugLength-len(suffix)], "-")
+			}
+			candidate += suffix
+		}
+		owner, err := repo.SlugOwner(candidate)
+		if err != nil {
+			return "", err
+		}
+		if owner == 0 || owner == postID {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("failed to find a free slug for %q", title)
+}
+
+// checkSlug validates a slug chosen by the author of postID (0 for a new post).
+func (s *postService) checkSlug(slug string, postID uint) error {
+	if !util.ValidSlug(slug) {
+		return domain.ErrInvalidSlug
+	}
+	owner, err := s.postRepo.SlugOwner(slug)
+	if err != nil {
+		return err
+	}
+	if owner != 0 && owner != postID {
+		return domain.ErrSlugTaken
+	}
+	return nil
+}
+
+// BackfillSlugs gives every post without a slug one generated from its title, and
+// returns how many it updated. It is run at startup for posts created before slugs.
+func BackfillSlugs(repo domain.PostRepository) (int, error) {
+	posts, err := repo.ListWithoutSlug()
+	if err != nil {
+		return 0, err
+	}
+	for i, post := range posts {
+		slug, err := uniqueSlug(repo, post.Title, post.ID)
+		if err != nil {
+			return i, err
+		}
+		if err := repo.ChangeSlug(post.ID, "", slug); err != nil {
+			return i, err
+		}
+	}
+	return len(posts), nil
+}
+
+// parseSchedule parses a publish_at or unpublish_at given as RFC 3339; "" means none.
+// A newly set time must be in the future.
+func parseSchedule(field, value string, now time.Time) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be an RFC 3339 time", domain.ErrInvalidSchedule, field)
+	}
+	if !t.After(now) {
+		return nil, fmt.Errorf("%w: %s must be in the future", domain.ErrInvalidSchedule, field)
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
+// checkSchedule refuses schedules that could never apply to the post: a publish_at on a
+// published post, or an unpublish_at on a draft that is not going to be published
+// before it.
+func checkSchedule(post *domain.Post) error {
+	switch {
+	case post.Published && post.PublishAt != nil:
+		return fmt.Errorf("%w: the post is already published", domain.ErrInvalidSchedule)
+	case !post.Published && post.UnpublishAt != nil && post.PublishAt == nil:
+		return fmt.Errorf("%w: unpublish_at needs a published post or a publish_at", domain.ErrInvalidSchedule)
+	case post.PublishAt != nil && post.UnpublishAt != nil && !post.UnpublishAt.After(*post.PublishAt):
+		return fmt.Errorf("%w: unpublish_at must be after publish_at", domain.ErrInvalidSchedule)
+	}
+	return nil
+}
+
+// visibilityChanged runs the hooks of a publish or unpublish, whether by hand or on
+// schedule: the audit entry, the author's notification and, for a post published
+// without an English version, its translation unless one is already running.
+func (s *postService) visibilityChanged(ctx context.Context, before, after *domain.Post, translating bool) {
+	if !after.Published {
+		s.recordPost(ctx, "post.unpublish", after.ID, before, after, nil)
+		s.notify(domain.EventPostUnpublished, after.ID, after.AuthorID, "post unpublished")
+		return
+	}
+	s.recordPost(ctx, "post.publish", after.ID, before, after, nil)
+	s.notify(domain.EventPostPublished, after.ID, after.AuthorID, "post published")
+	if !translating && s.shouldTranslate() && (after.EnTitle == "" || after.EnContent == "") {
+		s.translateAndPersistAsync(after.ID, after.Title, after.Content, after.EnTitle == "", after.EnContent == "")
+	}
+}
+
+// saveRevision adds the post's current text to its history. A failure is logged and
+// does not undo the change that was saved.
+func (s *postService) saveRevision(post *domain.Post, authorID *uint, kind string) {
+	rev := &domain.PostRevision{
+		PostID:    post.ID,
+		Title:     post.Title,
+		Content:   post.Content,
+		EnTitle:   post.EnTitle,
+		EnContent: post.EnContent,
+		AuthorID:  authorID,
+		Kind:      kind,
+	}
+	if err := s.postRepo.AddRevision(rev); err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to save revision of post %d: %v", post.ID, err))
+	}
+}
+
+// sameText reports whether a and b have the same text, as kept in their revisions.
+func sameText(a, b *domain.Post) bool {
+	return a.Title == b.Title && a.Content == b.Content && a.EnTitle == b.EnTitle && a.EnContent == b.EnContent
 }
 
 func (s *postService) shouldTranslate() bool {
@@ -36,41 +260,94 @@ func (s *postService) translateAndPersistAsync(postID uint, title string, conten
 			return
 		}
 
-		updated := false
+		var enTitle, enContent *string
+		var failures []string
 
 		if translateTitle {
 			if t, err := s.transAdapter.TranslateSingle(title); err == nil {
-				post.EnTitle = t
-				updated = true
+				enTitle = &t
 				s.logger.Info(fmt.Sprintf("Translated title asynchronously for post %d", postID))
 			} else {
 				s.logger.Error(fmt.Sprintf("Failed to translate title asynchronously: %v", err))
+				failures = append(failures, "title")
 			}
 		}
 
 		if translateContent {
 			if t, err := s.transAdapter.TranslateMarkdown(content); err == nil {
-				post.EnContent = t
-				updated = true
+				enContent = &t
 				s.logger.Info(fmt.Sprintf("Translated content asynchronously for post %d", postID))
 			} else {
 				s.logger.Error(fmt.Sprintf("Failed to translate content asynchronously: %v", err))
+				failures = append(failures, "content")
 			}
 		}
 
-		if updated {
-			if err := s.postRepo.Update(post); err != nil {
+		if enTitle != nil || enContent != nil {
+			// the post may have been edited while translating; write the translation alone
+			if err := s.postRepo.UpdateTranslation(postID, enTitle, enContent); err != nil {
 				s.logger.Error(fmt.Sprintf("Failed to persist async translations: %v", err))
+				s.notify(domain.EventTranslationFailed, postID, post.AuthorID, "failed to save translation")
+				return
 			}
+			if current, err := s.postRepo.GetByID(postID); err == nil {
+				s.saveRevision(current, nil, domain.RevisionTranslation)
+			} else {
+				s.logger.Error(fmt.Sprintf("Failed to reload post %d after translation: %v", postID, err))
+			}
+			s.postsChanged()
+		}
+		if len(failures) > 0 {
+			s.notify(domain.EventTranslationFailed, postID, post.AuthorID, "failed to translate "+strings.Join(failures, " and "))
+			return
 		}
+		s.notify(domain.EventTranslationCompleted, postID, post.AuthorID, "translation completed")
 	}()
 }
 
 // CreatePost creates a new blog post with the given request and author ID.
-func (s *postService) CreatePost(req model.CreatePostRequest, authorID uint) (*domain.Post, error) {
+func (s *postService) CreatePost(ctx context.Context, req model.CreatePostRequest, authorID uint) (*domain.Post, error) {
+	post, err := s.createPost(ctx, req, authorID)
+	if err != nil {
+		s.recordPost(ctx, "post.create", 0, nil, nil, err)
+		return nil, err
+	}
+	s.recordPost(ctx, "post.create", post.ID, nil, post, nil)
+	s.postsChanged()
+	if post.Published {
+		s.visibilityChanged(ctx, nil, post, true)
+	}
+	return post, nil
+}
+
+func (s *postService) createPost(ctx context.Context, req model.CreatePostRequest, authorID uint) (*domain.Post, error) {
+	now := time.Now()
+	publishAt, err := parseSchedule("publish_at", req.PublishAt, now)
+	if err != nil {
+		return nil, err
+	}
+	unpublishAt, err := parseSchedule("unpublish_at", req.UnpublishAt, now)
+	if err != nil {
+		return nil, err
+	}
+	schedule := &domain.Post{Published: req.Published, PublishAt: publishAt, UnpublishAt: unpublishAt}
+	if err := checkSchedule(schedule); err != nil {
+		return nil, err
+	}
+
+	slug := req.Slug
+	if slug == "" {
+		generated, err := uniqueSlug(s.postRepo, req.Title, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate slug: %w", err)
+		}
+		slug = generated
+	} else if err := s.checkSlug(slug, 0); err != nil {
+		return nil, err
+	}
+
 	// Process Markdown for image uploads BEFORE sanitization
 	var processedContent string
-	var err error
 	processedContent, err = s.imageAdapter.ProcessMarkdownForImages(req.Content, authorID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process images in content: %w", err)
@@ -83,6 +360,7 @@ func (s *postService) CreatePost(req model.CreatePostRequest, authorID uint) (*d
 	var thumbnailURL string
 	if req.ThumbnailData != "" {
 		url, err := s.imageAdapter.UploadImage(req.ThumbnailData, authorID)
+		s.record(ctx, "image.upload", "image", url, nil, nil, err)
 		if err != nil {
 			return nil, fmt.Errorf("failed to upload thumbnail: %w", err)
 		}
@@ -92,15 +370,23 @@ func (s *postService) CreatePost(req model.CreatePostRequest, authorID uint) (*d
 	}
 
 	post := &domain.Post{
-		Title:     req.Title,
-		Content:   safeContent,
-		Thumbnail: thumbnailURL,
-		AuthorID:  authorID,
-		Published: req.Published,
+		Title:       req.Title,
+		Slug:        slug,
+		Content:     safeContent,
+		Thumbnail:   thumbnailURL,
+		AuthorID:    authorID,
+		Published:   req.Published,
+		PublishAt:   publishAt,
+		UnpublishAt: unpublishAt,
 	}
 	if err := s.postRepo.Create(post); err != nil {
 		return nil, fmt.Errorf("failed to create post: %w", err)
 	}
+	s.saveRevision(post, &authorID, domain.RevisionCreate)
+
+	if processedContent != req.Content {
+		s.notify(domain.EventImagesProcessed, post.ID, authorID, "embedded images uploaded")
+	}
 
 	// Attach tags if provided (best-effort). Normalization is handled by repository.
 	if len(req.Tags) > 0 {
@@ -121,40 +407,231 @@ func (s *postService) CreatePost(req model.CreatePostRequest, authorID uint) (*d
 	return loadedPost, nil
 }
 
-// GetPost retrieves a post by its ID.
+// GetPost retrieves a published post by its ID.
 func (s *postService) GetPost(id uint) (*domain.Post, error) {
 	post, err := s.postRepo.GetByID(id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get post: %w", err)
 	}
+	if !post.Published {
+		return nil, fmt.Errorf("failed to get post: post not found")
+	}
 	// Load tags for the post if repository supports it
 	if tags, err := s.tagRepo.GetTagsForPost(id); err == nil {
 		post.Tags = tags
 	}
+	s.loadSeries(post)
 	return post, nil
 }
 
-// GetPostsByFilter returns a list of posts matching the given filter.
-func (s *postService) GetPostsByFilter(filter model.PostFilter) ([]*domain.Post, error) {
-	posts, err := s.postRepo.GetAll(filter)
+// GetPostBySlug retrieves a published post by its current or a previous slug. Callers
+// compare the returned post's Slug to redirect old links to the current one.
+func (s *postService) GetPostBySlug(slug string) (*domain.Post, error) {
+	post, err := s.postRepo.GetBySlug(slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if !post.Published {
+		return nil, fmt.Errorf("failed to get post: post not found")
+	}
+	if tags, err := s.tagRepo.GetTagsForPost(post.ID); err == nil {
+		post.Tags = tags
+	}
+	s.loadSeries(post)
+	return post, nil
+}
+
+// loadSeries sets where a published post stands in its series, if it belongs to one.
+// The post is still served without it when the lookup fails.
+func (s *postService) loadSeries(post *domain.Post) {
+	if s.seriesRepo == nil {
+		return
+	}
+	series, err := s.seriesRepo.SeriesOf(post.ID)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("failed to load the series of post %d: %v", post.ID, err))
+		return
+	}
+	if series != nil {
+		post.Series = series.Navigation(post.ID)
+	}
+}
+
+// GetPostForAuthor retrieves a post of authorID, published or not. Other authors'
+// posts are reported as not found.
+func (s *postService) GetPostForAuthor(id, authorID uint) (*domain.Post, error) {
+	post, err := s.postRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if post.AuthorID != authorID {
+		return nil, fmt.Errorf("failed to get post: post not found")
+	}
+	if tags, err := s.tagRepo.GetTagsForPost(id); err == nil {
+		post.Tags = tags
+	}
+	return post, nil
+}
+
+// GetPostsByFilter returns a page of the posts matching the given filter.
+func (s *postService) GetPostsByFilter(filter model.PostFilter) (*domain.PostPage, error) {
+	page, err := s.postRepo.GetAll(filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get posts: %w", err)
 	}
-	return posts, nil
+	return page, nil
+}
+
+// SearchPosts returns a page of the published posts matching query.Text, best first,
+// with the matches highlighted in their title and in a snippet of their content.
+func (s *postService) SearchPosts(query model.SearchQuery) (*domain.SearchResult, error) {
+	query.Text = strings.TrimSpace(query.Text)
+	if query.Text == "" {
+		return nil, domain.ErrEmptySearch
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultSearchLimit
+	}
+	query.Limit = min(query.Limit, maxSearchLimit)
+	query.Offset = max(query.Offset, 0)
+	hits, total, err := s.postRepo.Search(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search posts: %w", err)
+	}
+	for _, hit := range hits {
+		hit.TitleHighlight = util.HighlightHTML(hit.TitleHighlight, query.Text)
+		hit.Snippet = util.HighlightHTML(hit.Snippet, query.Text)
+		// the snippet stands in for the content, which a result list does not need
+		hit.Post.Content = ""
+		hit.Post.EnContent = ""
+	}
+	return &domain.SearchResult{Query: query.Text, Total: total, Hits: hits}, nil
+}
+
+// RelatedPosts returns up to limit published posts to read after the published post
+// id, best first. The ranking is cached until a post or a tag changes; when the cache
+// fails, the posts are ranked again.
+func (s *postService) RelatedPosts(id uint, limit int) ([]*domain.RelatedPost, error) {
+	post, err := s.postRepo.GetByID(id)
+	if err != nil || !post.Published {
+		return nil, domain.ErrPostNotFound
+	}
+	if limit <= 0 {
+		limit = defaultRelatedLimit
+	}
+	limit = min(limit, maxRelatedLimit)
+	related, err := s.rankedRelated(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get related posts: %w", err)
+	}
+	return related[:min(limit, len(related))], nil
+}
+
+// rankedRelated returns the best maxRelatedLimit related posts of post id, from the
+// cache when it has them.
+func (s *postService) rankedRelated(id uint) ([]*domain.RelatedPost, error) {
+	cache := s.related
+	var generation int64
+	if cache != nil {
+		var err error
+		if generation, err = cache.Generation(); err != nil {
+			s.logger.Error(fmt.Sprintf("failed to read the related posts cache: %v", err))
+			cache = nil
+		} else if related, ok, err := cache.Get(generation, id); err != nil {
+			s.logger.Error(fmt.Sprintf("failed to read the related posts cache: %v", err))
+		} else if ok {
+			return related, nil
+		}
+	}
+	related, err := s.postRepo.Related(id, maxRelatedLimit)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range related {
+		// a suggestion shows the title and thumbnail, not the text
+		r.Post.Content = ""
+		r.Post.EnContent = ""
+	}
+	if cache != nil {
+		if err := cache.Set(generation, id, related); err != nil {
+			s.logger.Error(fmt.Sprintf("failed to cache related posts of post %d: %v", id, err))
+		}
+	}
+	return related, nil
 }
 
 // UpdatePost updates an existing post if the author matches.
-func (s *postService) UpdatePost(id uint, req model.UpdatePostRequest, authorID uint) (*domain.Post, error) {
+func (s *postService) UpdatePost(ctx context.Context, id uint, req model.UpdatePostRequest, authorID uint) (*domain.Post, error) {
 	post, err := s.postRepo.GetByID(id)
 	if err != nil {
+		s.recordPost(ctx, "post.update", id, nil, nil, err)
 		return nil, fmt.Errorf("failed to get post: %w", err)
 	}
+	before := *post
+	updated, err := s.updatePost(ctx, id, post, req, authorID)
+	if err != nil {
+		s.recordPost(ctx, "post.update", id, &before, nil, err)
+		return nil, err
+	}
+	s.recordPost(ctx, "post.update", id, &before, updated, nil)
+	s.postsChanged()
+	if before.Published != updated.Published {
+		s.visibilityChanged(ctx, &before, updated, s.shouldTranslate() && (req.Title != nil || req.Content != nil))
+	}
+	return updated, nil
+}
+
+func (s *postService) updatePost(ctx context.Context, id uint, post *domain.Post, req model.UpdatePostRequest, authorID uint) (*domain.Post, error) {
 	if post.AuthorID != authorID {
 		return nil, fmt.Errorf("unauthorized: only the author can update this post")
 	}
+	previous := *post
 	if req.Title != nil {
 		post.Title = *req.Title
 	}
+	// The slug only changes when the author asks; an empty one is generated again
+	// from the title. Posts from before slugs get one on their first update.
+	slug := post.Slug
+	switch {
+	case req.Slug != nil && *req.Slug != "":
+		if err := s.checkSlug(*req.Slug, id); err != nil {
+			return nil, err
+		}
+		slug = *req.Slug
+	case req.Slug != nil || post.Slug == "":
+		generated, err := uniqueSlug(s.postRepo, post.Title, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate slug: %w", err)
+		}
+		slug = generated
+	}
+	// Publishing or unpublishing by hand replaces the schedule for it.
+	if req.Published != nil && *req.Published != post.Published {
+		post.Published = *req.Published
+		if post.Published {
+			post.PublishAt = nil
+		} else {
+			post.UnpublishAt = nil
+		}
+	}
+	now := time.Now()
+	if req.PublishAt != nil {
+		publishAt, err := parseSchedule("publish_at", *req.PublishAt, now)
+		if err != nil {
+			return nil, err
+		}
+		post.PublishAt = publishAt
+	}
+	if req.UnpublishAt != nil {
+		unpublishAt, err := parseSchedule("unpublish_at", *req.UnpublishAt, now)
+		if err != nil {
+			return nil, err
+		}
+		post.UnpublishAt = unpublishAt
+	}
+	if err := checkSchedule(post); err != nil {
+		return nil, err
+	}
 	var processedContent string
 	if req.Content != nil {
 		// Process Markdown for image uploads and keep raw Markdown in storage.
@@ -164,20 +641,31 @@ func (s *postService) UpdatePost(id uint, req model.UpdatePostRequest, authorID
 			return nil, fmt.Errorf("failed to process images in content: %w", err)
 		}
 		post.Content = processedContent
+		if processedContent != *req.Content {
+			s.notify(domain.EventImagesProcessed, id, authorID, "embedded images uploaded")
+		}
 	}
 	if req.ThumbnailData != nil && *req.ThumbnailData != "" {
 		url, err := s.imageAdapter.UploadImage(*req.ThumbnailData, authorID)
+		s.record(ctx, "image.upload", "image", url, nil, nil, err)
 		if err != nil {
 			return nil, fmt.Errorf("failed to upload thumbnail: %w", err)
 		}
 		post.Thumbnail = url
 	}
-	if req.Published != nil {
-		post.Published = *req.Published
-	}
 	if err := s.postRepo.Update(post); err != nil {
 		return nil, fmt.Errorf("failed to update post: %w", err)
 	}
+	// tags, schedule and thumbnail are not versioned; only text changes make a revision
+	if !sameText(&previous, post) {
+		s.saveRevision(post, &authorID, domain.RevisionEdit)
+	}
+	if slug != post.Slug {
+		if err := s.postRepo.ChangeSlug(id, post.Slug, slug); err != nil {
+			return nil, fmt.Errorf("failed to change slug: %w", err)
+		}
+		post.Slug = slug
+	}
 
 	// Run translation in background so update path is not blocked by external API.
 	if s.shouldTranslate() && (req.Title != nil || req.Content != nil) {
@@ -197,48 +685,166 @@ func (s *postService) UpdatePost(id uint, req model.UpdatePostRequest, authorID
 }
 
 // DeletePost deletes a post if the author matches.
-func (s *postService) DeletePost(id, authorID uint) error {
+// Cleanup of tags and images is best-effort; failures are logged and audited.
+func (s *postService) DeletePost(ctx context.Context, id, authorID uint) error {
 	post, err := s.postRepo.GetByID(id)
 	if err != nil {
+		s.recordPost(ctx, "post.delete", id, nil, nil, err)
 		return fmt.Errorf("failed to get post: %w", err)
 	}
 	if post.AuthorID != authorID {
-		return fmt.Errorf("unauthorized: only the author can delete this post")
+		err := fmt.Errorf("unauthorized: only the author can delete this post")
+		s.recordPost(ctx, "post.delete", id, post, nil, err)
+		return err
 	}
 	// Store tags before deletion
 	tags := post.Tags
 	if err := s.postRepo.Delete(id); err != nil {
+		s.recordPost(ctx, "post.delete", id, post, nil, err)
 		return fmt.Errorf("failed to delete post: %w", err)
 	}
+	s.recordPost(ctx, "post.delete", id, post, nil, nil)
+	s.postsChanged()
 	// Delete unused tags
 	for _, tag := range tags {
 		if err := s.tagRepo.DeleteUnusedTag(tag.ID); err != nil {
-			// Log error but proceed
-			fmt.Printf("Failed to delete unused tag %s: %v\n", tag.Name, err)
+			s.logger.Error(fmt.Sprintf("Failed to delete unused tag %s: %v", tag.Name, err))
 		}
 	}
-	// Delete thumbnail image via img-service if exists
+	// Delete thumbnail and content images via img-service
+	imageURLs := s.imageAdapter.ExtractImageURLsFromContent(post.Content)
 	if post.Thumbnail != "" {
-		if err := s.imageAdapter.DeleteImage(post.Thumbnail); err != nil {
-			// Log error but proceed with post deletion
-			fmt.Printf("Failed to delete thumbnail via img-service: %v\n", err)
-		}
-	} else {
-		fmt.Println("No thumbnail to delete")
+		imageURLs = append([]string{post.Thumbnail}, imageURLs...)
 	}
-	// Delete images in content via img-service if any
-	imageURLs := s.imageAdapter.ExtractImageURLsFromContent(post.Content)
 	for _, url := range imageURLs {
-		if err := s.imageAdapter.DeleteImage(url); err != nil {
-			// Log error but proceed
-			fmt.Printf("Failed to delete content image via img-service: %v\n", err)
+		err := s.imageAdapter.DeleteImage(url)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to delete image %s of post %d via img-service: %v", url, id, err))
 		}
+		s.record(ctx, "image.delete", "image", url, map[string]uint{"post_id": id}, nil, err)
 	}
 
 	return nil
 }
 
-// ListTags returns all available tags.
+// authorPost returns post id if authorID wrote it. Other authors' posts are reported
+// as not found, as their history is not public.
+func (s *postService) authorPost(id, authorID uint) (*domain.Post, error) {
+	post, err := s.postRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if post.AuthorID != authorID {
+		return nil, fmt.Errorf("failed to get post: post not found")
+	}
+	return post, nil
+}
+
+// ListRevisions returns the history of the author's post, newest first.
+func (s *postService) ListRevisions(postID, authorID uint) ([]*domain.PostRevision, error) {
+	if _, err := s.authorPost(postID, authorID); err != nil {
+		return nil, err
+	}
+	return s.postRepo.ListRevisions(postID)
+}
+
+// GetRevision returns one revision of the author's post.
+func (s *postService) GetRevision(postID, revisionID, authorID uint) (*domain.PostRevision, error) {
+	if _, err := s.authorPost(postID, authorID); err != nil {
+		return nil, err
+	}
+	return s.postRepo.GetRevision(postID, revisionID)
+}
+
+// DiffRevisions returns the unified diff of the title, content and translation between
+// two revisions of the author's post.
+func (s *postService) DiffRevisions(postID, fromID, toID, authorID uint) (*domain.RevisionDiff, error) {
+	if _, err := s.authorPost(postID, authorID); err != nil {
+		return nil, err
+	}
+	to, err := s.postRepo.GetRevision(postID, toID)
+	if err != nil {
+		return nil, err
+	}
+	var from *domain.PostRevision
+	if fromID != 0 {
+		from, err = s.postRepo.GetRevision(postID, fromID)
+	} else {
+		from, err = s.postRepo.PreviousRevision(postID, toID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if from == nil {
+		from = &domain.PostRevision{}
+	}
+	fromName, toName := fmt.Sprintf("revision %d", from.ID), fmt.Sprintf("revision %d", to.ID)
+	if from.ID == 0 {
+		fromName = "empty"
+	}
+	var diff strings.Builder
+	for _, field := range []struct{ name, from, to string }{
+		{"title", from.Title, to.Title},
+		{"content", from.Content, to.Content},
+		{"en_title", from.EnTitle, to.EnTitle},
+		{"en_content", from.EnContent, to.EnContent},
+	} {
+		diff.WriteString(util.UnifiedDiff(fromName+"/"+field.name, toName+"/"+field.name, field.from, field.to))
+	}
+	return &domain.RevisionDiff{From: from.ID, To: to.ID, Diff: diff.String()}, nil
+}
+
+// RestoreRevision puts back the title, content and translation of a revision of the
+// author's post. The restored text becomes the newest revision, so a restore can be
+// undone like any other change.
+func (s *postService) RestoreRevision(ctx context.Context, postID, revisionID, authorID uint) (*domain.Post, error) {
+	post, err := s.authorPost(postID, authorID)
+	if err != nil {
+		s.recordPost(ctx, "post.restore", postID, nil, nil, err)
+		return nil, err
+	}
+	before := *post
+	rev, err := s.postRepo.GetRevision(postID, revisionID)
+	if err != nil {
+		s.recordPost(ctx, "post.restore", postID, &before, nil, err)
+		return nil, err
+	}
+	post.Title, post.Content, post.EnTitle, post.EnContent = rev.Title, rev.Content, rev.EnTitle, rev.EnContent
+	if err := s.postRepo.Update(post); err != nil {
+		err = fmt.Errorf("failed to restore revision: %w", err)
+		s.recordPost(ctx, "post.restore", postID, &before, nil, err)
+		return nil, err
+	}
+	s.saveRevision(post, &authorID, domain.RevisionRestore)
+	s.recordPost(ctx, "post.restore", postID, &before, post, nil)
+	s.postsChanged()
+	return post, nil
+}
+
+// RunSchedule applies the publishing schedule and runs the publish and unpublish hooks
+// for each post it changed. The changes are committed before any hook runs.
+func (s *postService) RunSchedule(ctx context.Context) (int, error) {
+	published, unpublished, err := s.postRepo.ApplySchedule(time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to apply schedule: %w", err)
+	}
+	if len(published)+len(unpublished) > 0 {
+		s.postsChanged()
+	}
+	for _, post := range published {
+		before := *post
+		before.Published = false
+		s.visibilityChanged(ctx, &before, post, false)
+	}
+	for _, post := range unpublished {
+		before := *post
+		before.Published = true
+		s.visibilityChanged(ctx, &before, post, false)
+	}
+	return len(published) + len(unpublished), nil
+}
+
+// ListTags returns tags used by published posts.
 func (s *postService) ListTags() ([]*domain.Tag, error) {
 	return s.tagRepo.ListTags()
 }
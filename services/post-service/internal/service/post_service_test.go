@@ -1,10 +1,13 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/adapter"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/config"
 	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
@@ -14,19 +17,86 @@ import (
 type stubPostRepo struct {
 	createFn func(post *domain.Post) error
 	getByID  func(id uint) (*domain.Post, error)
-	getAll   func(filter model.PostFilter) ([]*domain.Post, error)
+	getAll   func(filter model.PostFilter) (*domain.PostPage, error)
 	updateFn func(post *domain.Post) error
 	deleteFn func(id uint) error
+	// updateTranslationFn is called by UpdateTranslation; nil accepts every translation
+	updateTranslationFn func(postID uint, enTitle, enContent *string) error
+	// slugs maps slugs in use to their post; nil means every slug is free
+	slugs        map[string]uint
+	changeSlugFn func(postID uint, oldSlug, newSlug string) error
+	scheduleFn   func(now time.Time) ([]*domain.Post, []*domain.Post, error)
+	searchFn     func(query model.SearchQuery) ([]*domain.SearchHit, int64, error)
+	relatedFn    func(postID uint, limit int) ([]*domain.RelatedPost, error)
+	// revisions records added revisions; nil keeps none
+	revisions *[]*domain.PostRevision
 }
 
 func (s *stubPostRepo) Create(post *domain.Post) error        { return s.createFn(post) }
 func (s *stubPostRepo) GetByID(id uint) (*domain.Post, error) { return s.getByID(id) }
-func (s *stubPostRepo) GetAll(filter model.PostFilter) ([]*domain.Post, error) {
+func (s *stubPostRepo) GetAll(filter model.PostFilter) (*domain.PostPage, error) {
 	return s.getAll(filter)
 }
-func (s *stubPostRepo) Update(post *domain.Post) error                      { return s.updateFn(post) }
+func (s *stubPostRepo) Search(query model.SearchQuery) ([]*domain.SearchHit, int64, error) {
+	return s.searchFn(query)
+}
+func (s *stubPostRepo) Update(post *domain.Post) error { return s.updateFn(post) }
+func (s *stubPostRepo) UpdateTranslation(postID uint, enTitle, enContent *string) error {
+	if s.updateTranslationFn == nil {
+		return nil
+	}
+	return s.updateTranslationFn(postID, enTitle, enContent)
+}
 func (s *stubPostRepo) Delete(id uint) error                                { return s.deleteFn(id) }
 func (s *stubPostRepo) GetByAuthorID(authorID uint) ([]*domain.Post, error) { return nil, nil }
+func (s *stubPostRepo) GetBySlug(slug string) (*domain.Post, error) {
+	if id, ok := s.slugs[slug]; ok {
+		return s.getByID(id)
+	}
+	return nil, errors.New("post not found")
+}
+func (s *stubPostRepo) SlugOwner(slug string) (uint, error) { return s.slugs[slug], nil }
+func (s *stubPostRepo) ChangeSlug(postID uint, oldSlug, newSlug string) error {
+	if s.changeSlugFn == nil {
+		return nil
+	}
+	return s.changeSlugFn(postID, oldSlug, newSlug)
+}
+func (s *stubPostRepo) ListWithoutSlug() ([]*domain.Post, error) { return nil, nil }
+func (s *stubPostRepo) AddRevision(rev *domain.PostRevision) error {
+	if s.revisions != nil {
+		rev.ID = uint(len(*s.revisions) + 1)
+		*s.revisions = append(*s.revisions, rev)
+	}
+	return nil
+}
+func (s *stubPostRepo) ListRevisions(postID uint) ([]*domain.PostRevision, error) {
+	var out []*domain.PostRevision
+	if s.revisions != nil {
+		for i := len(*s.revisions) - 1; i >= 0; i-- {
+			out = append(out, (*s.revisions)[i])
+		}
+	}
+	return out, nil
+}
+func (s *stubPostRepo) GetRevision(postID, revisionID uint) (*domain.PostRevision, error) {
+	if s.revisions == nil || revisionID == 0 || int(revisionID) > len(*s.revisions) {
+		return nil, domain.ErrRevisionNotFound
+	}
+	return (*s.revisions)[revisionID-1], nil
+}
+func (s *stubPostRepo) PreviousRevision(postID, revisionID uint) (*domain.PostRevision, error) {
+	if revisionID <= 1 {
+		return nil, nil
+	}
+	return s.GetRevision(postID, revisionID-1)
+}
+func (s *stubPostRepo) ApplySchedule(now time.Time) ([]*domain.Post, []*domain.Post, error) {
+	return s.scheduleFn(now)
+}
+func (s *stubPostRepo) Related(postID uint, limit int) ([]*domain.RelatedPost, error) {
+	return s.relatedFn(postID, limit)
+}
 
 type stubTagRepo struct {
 	attachFn     func(postID uint, tagNames []string) error
@@ -45,6 +115,13 @@ func (s *stubTagRepo) ReplaceTagsForPost(postID uint, tagNames []string) error {
 func (s *stubTagRepo) GetTagsForPost(postID uint) ([]*domain.Tag, error) { return s.getTagsFn(postID) }
 func (s *stubTagRepo) ListTags() ([]*domain.Tag, error)                  { return s.listTagsFn() }
 func (s *stubTagRepo) DeleteTag(id uint) error                           { return nil }
+func (s *stubTagRepo) ListAllTags() ([]*domain.Tag, error)               { return nil, nil }
+func (s *stubTagRepo) GetTagByID(id uint) (*domain.Tag, error)           { return nil, domain.ErrTagNotFound }
+func (s *stubTagRepo) FindTag(name string) (*domain.Tag, error)          { return nil, domain.ErrTagNotFound }
+func (s *stubTagRepo) UpdateTag(tag *domain.Tag) error                   { return nil }
+func (s *stubTagRepo) MergeTags(fromID, intoID uint) error               { return nil }
+func (s *stubTagRepo) AddAlias(tagID uint, alias string) error           { return nil }
+func (s *stubTagRepo) DeleteAlias(tagID uint, alias string) error        { return nil }
 func (s *stubTagRepo) DeleteUnusedTag(tagID uint) error                  { return s.deleteUnused(tagID) }
 
 type stubImageAdapter struct {
@@ -77,8 +154,22 @@ func (s *stubTranslationAdapter) TranslateMarkdown(content string) (string, erro
 	return s.markdownFn(content)
 }
 
+type stubRecorder struct {
+	entries []*audit.Entry
+}
+
+func (r *stubRecorder) Record(_ context.Context, e *audit.Entry) { r.entries = append(r.entries, e) }
+
+func (r *stubRecorder) actions() []string {
+	out := make([]string, 0, len(r.entries))
+	for _, e := range r.entries {
+		out = append(out, e.Action+":"+e.Outcome)
+	}
+	return out
+}
+
 func newSvcForTest(postRepo domain.PostRepository, tagRepo domain.TagRepository, cfg *config.PostConfig, img adapter.ImageAdapter, tr adapter.TranslationAdapter) *postService {
-	return NewPostService(postRepo, tagRepo, cfg, img, tr).(*postService)
+	return NewPostService(postRepo, tagRepo, nil, cfg, img, tr, nil, nil, nil).(*postService)
 }
 
 func TestCreatePost_Flow(t *testing.T) {
@@ -88,7 +179,7 @@ func TestCreatePost_Flow(t *testing.T) {
 		&stubPostRepo{
 			createFn: func(p *domain.Post) error { p.ID = 1; return nil },
 			getByID:  func(id uint) (*domain.Post, error) { return post, nil },
-			getAll:   func(filter model.PostFilter) ([]*domain.Post, error) { return nil, nil },
+			getAll:   func(filter model.PostFilter) (*domain.PostPage, error) { return nil, nil },
 			updateFn: func(post *domain.Post) error { return nil },
 			deleteFn: func(id uint) error { return nil },
 		},
@@ -115,7 +206,7 @@ func TestCreatePost_Flow(t *testing.T) {
 		},
 	)
 
-	got, err := svc.CreatePost(model.CreatePostRequest{
+	got, err := svc.CreatePost(context.Background(), model.CreatePostRequest{
 		Title: "title", Content: "content", ThumbnailData: "data", Tags: []string{"go"}, Published: true,
 	}, 7)
 	if err != nil {
@@ -135,7 +226,7 @@ func TestCreatePost_ErrorCases(t *testing.T) {
 	basePostRepo := &stubPostRepo{
 		createFn: func(p *domain.Post) error { p.ID = 1; return nil },
 		getByID:  func(id uint) (*domain.Post, error) { return &domain.Post{ID: id}, nil },
-		getAll:   func(filter model.PostFilter) ([]*domain.Post, error) { return nil, nil },
+		getAll:   func(filter model.PostFilter) (*domain.PostPage, error) { return nil, nil },
 		updateFn: func(post *domain.Post) error { return nil },
 		deleteFn: func(id uint) error { return nil },
 	}
@@ -162,7 +253,7 @@ func TestCreatePost_ErrorCases(t *testing.T) {
 		processFn: func(content string, userID uint) (string, error) { return "", errors.New("process fail") },
 		uploadFn:  baseImg.uploadFn, deleteFn: baseImg.deleteFn, extractFn: baseImg.extractFn,
 	}, baseTr)
-	if _, err := svc1.CreatePost(model.CreatePostRequest{Title: "t", Content: "c"}, 1); err == nil {
+	if _, err := svc1.CreatePost(context.Background(), model.CreatePostRequest{Title: "t", Content: "c"}, 1); err == nil {
 		t.Fatalf("expected process error")
 	}
 
@@ -172,7 +263,7 @@ func TestCreatePost_ErrorCases(t *testing.T) {
 		uploadFn:  func(data string, userID uint) (string, error) { return "", errors.New("upload fail") },
 		deleteFn:  baseImg.deleteFn, extractFn: baseImg.extractFn,
 	}, baseTr)
-	if _, err := svc2.CreatePost(model.CreatePostRequest{Title: "t", Content: "c", ThumbnailData: "d"}, 1); err == nil {
+	if _, err := svc2.CreatePost(context.Background(), model.CreatePostRequest{Title: "t", Content: "c", ThumbnailData: "d"}, 1); err == nil {
 		t.Fatalf("expected thumbnail error")
 	}
 
@@ -181,7 +272,7 @@ func TestCreatePost_ErrorCases(t *testing.T) {
 		createFn: func(p *domain.Post) error { return errors.New("create fail") },
 		getByID:  basePostRepo.getByID, getAll: basePostRepo.getAll, updateFn: basePostRepo.updateFn, deleteFn: basePostRepo.deleteFn,
 	}, baseTags, &config.PostConfig{}, baseImg, baseTr)
-	if _, err := svc3.CreatePost(model.CreatePostRequest{Title: "t", Content: "c"}, 1); err == nil {
+	if _, err := svc3.CreatePost(context.Background(), model.CreatePostRequest{Title: "t", Content: "c"}, 1); err == nil {
 		t.Fatalf("expected create error")
 	}
 
@@ -190,7 +281,7 @@ func TestCreatePost_ErrorCases(t *testing.T) {
 		attachFn:  func(postID uint, tagNames []string) error { return errors.New("tag fail") },
 		replaceFn: baseTags.replaceFn, getTagsFn: baseTags.getTagsFn, listTagsFn: baseTags.listTagsFn, deleteUnused: baseTags.deleteUnused,
 	}, &config.PostConfig{}, baseImg, baseTr)
-	if _, err := svc4.CreatePost(model.CreatePostRequest{Title: "t", Content: "c", Tags: []string{"go"}}, 1); err == nil {
+	if _, err := svc4.CreatePost(context.Background(), model.CreatePostRequest{Title: "t", Content: "c", Tags: []string{"go"}}, 1); err == nil {
 		t.Fatalf("expected attach tags error")
 	}
 
@@ -200,7 +291,7 @@ func TestCreatePost_ErrorCases(t *testing.T) {
 		getByID:  func(id uint) (*domain.Post, error) { return nil, errors.New("load fail") },
 		getAll:   basePostRepo.getAll, updateFn: basePostRepo.updateFn, deleteFn: basePostRepo.deleteFn,
 	}, baseTags, &config.PostConfig{}, baseImg, baseTr)
-	if _, err := svc5.CreatePost(model.CreatePostRequest{Title: "t", Content: "c"}, 1); err == nil {
+	if _, err := svc5.CreatePost(context.Background(), model.CreatePostRequest{Title: "t", Content: "c"}, 1); err == nil {
 		t.Fatalf("expected load fail")
 	}
 }
@@ -211,7 +302,7 @@ func TestCreatePost_TranslationDisabled_NoAsync(t *testing.T) {
 		&stubPostRepo{
 			createFn: func(p *domain.Post) error { p.ID = 1; return nil },
 			getByID:  func(id uint) (*domain.Post, error) { return &domain.Post{ID: id}, nil },
-			getAll:   func(filter model.PostFilter) ([]*domain.Post, error) { return nil, nil },
+			getAll:   func(filter model.PostFilter) (*domain.PostPage, error) { return nil, nil },
 			updateFn: func(post *domain.Post) error { return nil },
 			deleteFn: func(id uint) error { return nil },
 		},
@@ -237,7 +328,7 @@ func TestCreatePost_TranslationDisabled_NoAsync(t *testing.T) {
 			},
 		},
 	)
-	if _, err := svc.CreatePost(model.CreatePostRequest{Title: "title", Content: "content"}, 1); err != nil {
+	if _, err := svc.CreatePost(context.Background(), model.CreatePostRequest{Title: "title", Content: "content"}, 1); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	time.Sleep(120 * time.Millisecond)
@@ -250,8 +341,10 @@ func TestGetPostAndGetPostsByFilter(t *testing.T) {
 	svc := newSvcForTest(
 		&stubPostRepo{
 			createFn: func(post *domain.Post) error { return nil },
-			getByID:  func(id uint) (*domain.Post, error) { return &domain.Post{ID: id}, nil },
-			getAll:   func(filter model.PostFilter) ([]*domain.Post, error) { return []*domain.Post{{ID: 1}}, nil },
+			getByID:  func(id uint) (*domain.Post, error) { return &domain.Post{ID: id, Published: id == 1}, nil },
+			getAll: func(filter model.PostFilter) (*domain.PostPage, error) {
+				return &domain.PostPage{Posts: []*domain.Post{{ID: 1}}}, nil
+			},
 			updateFn: func(post *domain.Post) error { return nil },
 			deleteFn: func(id uint) error { return nil },
 		},
@@ -271,15 +364,18 @@ func TestGetPostAndGetPostsByFilter(t *testing.T) {
 	if err != nil || len(post.Tags) != 1 {
 		t.Fatalf("expected post with tags, got %v err=%v", post, err)
 	}
-	posts, err := svc.GetPostsByFilter(model.PostFilter{})
-	if err != nil || len(posts) != 1 {
-		t.Fatalf("expected posts, got %v err=%v", posts, err)
+	if _, err := svc.GetPost(2); err == nil {
+		t.Fatalf("expected a draft not to be found")
+	}
+	page, err := svc.GetPostsByFilter(model.PostFilter{})
+	if err != nil || len(page.Posts) != 1 {
+		t.Fatalf("expected posts, got %v err=%v", page, err)
 	}
 }
 
 func TestGetPost_TagLoadFailureNonFatal(t *testing.T) {
 	svc := newSvcForTest(
-		&stubPostRepo{createFn: func(post *domain.Post) error { return nil }, getByID: func(id uint) (*domain.Post, error) { return &domain.Post{ID: id}, nil }, getAll: func(filter model.PostFilter) ([]*domain.Post, error) { return nil, nil }, updateFn: func(post *domain.Post) error { return nil }, deleteFn: func(id uint) error { return nil }},
+		&stubPostRepo{createFn: func(post *domain.Post) error { return nil }, getByID: func(id uint) (*domain.Post, error) { return &domain.Post{ID: id, Published: true}, nil }, getAll: func(filter model.PostFilter) (*domain.PostPage, error) { return nil, nil }, updateFn: func(post *domain.Post) error { return nil }, deleteFn: func(id uint) error { return nil }},
 		&stubTagRepo{attachFn: func(postID uint, tagNames []string) error { return nil }, replaceFn: func(postID uint, tagNames []string) error { return nil }, getTagsFn: func(postID uint) ([]*domain.Tag, error) { return nil, errors.New("tag fail") }, listTagsFn: func() ([]*domain.Tag, error) { return nil, nil }, deleteUnused: func(tagID uint) error { return nil }},
 		&config.PostConfig{},
 		&stubImageAdapter{processFn: func(content string, userID uint) (string, error) { return content, nil }, uploadFn: func(data string, userID uint) (string, error) { return "", nil }, deleteFn: func(path string) error { return nil }, extractFn: func(content string) []string { return nil }},
@@ -296,7 +392,7 @@ func TestGetPostAndGetPostsByFilter_RepoErrors(t *testing.T) {
 		&stubPostRepo{
 			createFn: func(post *domain.Post) error { return nil },
 			getByID:  func(id uint) (*domain.Post, error) { return nil, errors.New("get fail") },
-			getAll:   func(filter model.PostFilter) ([]*domain.Post, error) { return nil, errors.New("list fail") },
+			getAll:   func(filter model.PostFilter) (*domain.PostPage, error) { return nil, errors.New("list fail") },
 			updateFn: func(post *domain.Post) error { return nil },
 			deleteFn: func(id uint) error { return nil },
 		},
@@ -340,7 +436,7 @@ func TestUpdatePost(t *testing.T) {
 				cp.ID = id
 				return &cp, nil
 			},
-			getAll: func(filter model.PostFilter) ([]*domain.Post, error) { return nil, nil },
+			getAll: func(filter model.PostFilter) (*domain.PostPage, error) { return nil, nil },
 			updateFn: func(post *domain.Post) error {
 				if post.ID == 2 {
 					return errors.New("update fail")
@@ -387,27 +483,27 @@ func TestUpdatePost(t *testing.T) {
 		},
 	)
 
-	if _, err := svc.UpdatePost(99, model.UpdatePostRequest{}, 1); err == nil {
+	if _, err := svc.UpdatePost(context.Background(), 99, model.UpdatePostRequest{}, 1); err == nil {
 		t.Fatalf("expected get post fail")
 	}
-	if _, err := svc.UpdatePost(1, model.UpdatePostRequest{}, 2); err == nil {
+	if _, err := svc.UpdatePost(context.Background(), 1, model.UpdatePostRequest{}, 2); err == nil {
 		t.Fatalf("expected unauthorized")
 	}
 	bad := "bad"
-	if _, err := svc.UpdatePost(1, model.UpdatePostRequest{Content: &bad}, 1); err == nil {
+	if _, err := svc.UpdatePost(context.Background(), 1, model.UpdatePostRequest{Content: &bad}, 1); err == nil {
 		t.Fatalf("expected process fail")
 	}
 	badThumb := "bad-thumb"
-	if _, err := svc.UpdatePost(1, model.UpdatePostRequest{ThumbnailData: &badThumb}, 1); err == nil {
+	if _, err := svc.UpdatePost(context.Background(), 1, model.UpdatePostRequest{ThumbnailData: &badThumb}, 1); err == nil {
 		t.Fatalf("expected thumbnail fail")
 	}
-	if _, err := svc.UpdatePost(2, model.UpdatePostRequest{Title: &title}, 1); err == nil {
+	if _, err := svc.UpdatePost(context.Background(), 2, model.UpdatePostRequest{Title: &title}, 1); err == nil {
 		t.Fatalf("expected update fail")
 	}
-	if _, err := svc.UpdatePost(3, model.UpdatePostRequest{Title: &title, Tags: &tags}, 1); err == nil {
+	if _, err := svc.UpdatePost(context.Background(), 3, model.UpdatePostRequest{Title: &title, Tags: &tags}, 1); err == nil {
 		t.Fatalf("expected replace tags fail")
 	}
-	got, err := svc.UpdatePost(1, model.UpdatePostRequest{
+	got, err := svc.UpdatePost(context.Background(), 1, model.UpdatePostRequest{
 		Title: &title, Content: &content, ThumbnailData: &thumb, Published: &published, Tags: &tags,
 	}, 1)
 	if err != nil || got.Title != "new" || got.Content != "processed" || got.Thumbnail != "/thumb.png" || !got.Published {
@@ -429,7 +525,7 @@ func TestUpdatePost_TranslationDisabled_NoAsync(t *testing.T) {
 			getByID: func(id uint) (*domain.Post, error) {
 				return &domain.Post{ID: id, AuthorID: 1, Title: "old", Content: "old"}, nil
 			},
-			getAll:   func(filter model.PostFilter) ([]*domain.Post, error) { return nil, nil },
+			getAll:   func(filter model.PostFilter) (*domain.PostPage, error) { return nil, nil },
 			updateFn: func(post *domain.Post) error { return nil },
 			deleteFn: func(id uint) error { return nil },
 		},
@@ -452,7 +548,7 @@ func TestUpdatePost_TranslationDisabled_NoAsync(t *testing.T) {
 			markdownFn: func(content string) (string, error) { translated = true; return "<p>en</p>", nil },
 		},
 	)
-	if _, err := svc.UpdatePost(1, model.UpdatePostRequest{Title: &title}, 1); err != nil {
+	if _, err := svc.UpdatePost(context.Background(), 1, model.UpdatePostRequest{Title: &title}, 1); err != nil {
 		t.Fatalf("unexpected update error: %v", err)
 	}
 	time.Sleep(120 * time.Millisecond)
@@ -470,7 +566,7 @@ func TestUpdatePost_TitleOnly(t *testing.T) {
 			getByID: func(id uint) (*domain.Post, error) {
 				return &domain.Post{ID: id, AuthorID: 1, Title: "old", Content: "keep-content"}, nil
 			},
-			getAll:   func(filter model.PostFilter) ([]*domain.Post, error) { return nil, nil },
+			getAll:   func(filter model.PostFilter) (*domain.PostPage, error) { return nil, nil },
 			updateFn: func(post *domain.Post) error { cp := *post; updated = &cp; return nil },
 			deleteFn: func(id uint) error { return nil },
 		},
@@ -491,7 +587,7 @@ func TestUpdatePost_TitleOnly(t *testing.T) {
 		&stubTranslationAdapter{singleFn: func(text string) (string, error) { return "", nil }, markdownFn: func(content string) (string, error) { return "", nil }},
 	)
 
-	got, err := svc.UpdatePost(1, model.UpdatePostRequest{Title: &title}, 1)
+	got, err := svc.UpdatePost(context.Background(), 1, model.UpdatePostRequest{Title: &title}, 1)
 	if err != nil {
 		t.Fatalf("unexpected update error: %v", err)
 	}
@@ -518,7 +614,7 @@ func TestDeletePost(t *testing.T) {
 				}
 				return post, nil
 			},
-			getAll:   func(filter model.PostFilter) ([]*domain.Post, error) { return nil, nil },
+			getAll:   func(filter model.PostFilter) (*domain.PostPage, error) { return nil, nil },
 			updateFn: func(post *domain.Post) error { return nil },
 			deleteFn: func(id uint) error {
 				if id == 2 {
@@ -546,17 +642,17 @@ func TestDeletePost(t *testing.T) {
 		},
 		&stubTranslationAdapter{singleFn: func(text string) (string, error) { return "", nil }, markdownFn: func(content string) (string, error) { return "", nil }},
 	)
-	if err := svc.DeletePost(99, 1); err == nil {
+	if err := svc.DeletePost(context.Background(), 99, 1); err == nil {
 		t.Fatalf("expected get fail")
 	}
-	if err := svc.DeletePost(1, 2); err == nil {
+	if err := svc.DeletePost(context.Background(), 1, 2); err == nil {
 		t.Fatalf("expected unauthorized")
 	}
-	if err := svc.DeletePost(2, 1); err == nil {
+	if err := svc.DeletePost(context.Background(), 2, 1); err == nil {
 		t.Fatalf("expected delete fail")
 	}
 	// cleanup errors should not fail the operation
-	if err := svc.DeletePost(1, 1); err != nil {
+	if err := svc.DeletePost(context.Background(), 1, 1); err != nil {
 		t.Fatalf("expected success despite cleanup errors: %v", err)
 	}
 	if deleteImageCalls == 0 {
@@ -564,9 +660,187 @@ func TestDeletePost(t *testing.T) {
 	}
 }
 
+func TestDeletePost_RecordsAuditTrail(t *testing.T) {
+	post := &domain.Post{ID: 1, AuthorID: 1, Title: "t", Thumbnail: "/thumb.png", Content: "![a](/a.png)"}
+	svc := newSvcForTest(
+		&stubPostRepo{
+			getByID:  func(id uint) (*domain.Post, error) { return post, nil },
+			deleteFn: func(id uint) error { return nil },
+		},
+		&stubTagRepo{},
+		&config.PostConfig{},
+		&stubImageAdapter{
+			deleteFn: func(path string) error {
+				if path == "/a.png" {
+					return errors.New("img delete fail")
+				}
+				return nil
+			},
+			extractFn: func(content string) []string { return []string{"/a.png"} },
+		},
+		&stubTranslationAdapter{},
+	)
+	rec := &stubRecorder{}
+	svc.auditor = rec
+	ctx := audit.WithMeta(context.Background(), audit.Meta{ActorID: 1, RequestID: "req-1", IP: "10.0.0.1"})
+
+	if err := svc.DeletePost(ctx, 1, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"post.delete:success", "image.delete:success", "image.delete:failure"}
+	got := rec.actions()
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	first := rec.entries[0]
+	if first.TargetID != "1" || first.RequestID != "req-1" || first.IP != "10.0.0.1" || first.ActorID != 1 {
+		t.Fatalf("unexpected entry metadata: %+v", first)
+	}
+	if first.Before == "" || first.After != "" {
+		t.Fatalf("expected before snapshot only, got before=%q after=%q", first.Before, first.After)
+	}
+	if rec.entries[2].Error == "" {
+		t.Fatalf("expected error to be recorded on failed image delete")
+	}
+}
+
+func TestUpdatePost_RecordsPublishTransition(t *testing.T) {
+	post := &domain.Post{ID: 1, AuthorID: 1, Title: "t"}
+	svc := newSvcForTest(
+		&stubPostRepo{
+			getByID:  func(id uint) (*domain.Post, error) { return post, nil },
+			updateFn: func(post *domain.Post) error { return nil },
+		},
+		&stubTagRepo{},
+		&config.PostConfig{},
+		&stubImageAdapter{},
+		&stubTranslationAdapter{},
+	)
+	rec := &stubRecorder{}
+	svc.auditor = rec
+
+	published := true
+	if _, err := svc.UpdatePost(context.Background(), 1, model.UpdatePostRequest{Published: &published}, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := rec.actions()
+	if len(got) != 2 || got[0] != "post.update:success" || got[1] != "post.publish:success" {
+		t.Fatalf("unexpected audit actions: %v", got)
+	}
+
+	if _, err := svc.UpdatePost(context.Background(), 1, model.UpdatePostRequest{}, 2); err == nil {
+		t.Fatalf("expected unauthorized")
+	}
+	if last := rec.entries[len(rec.entries)-1]; last.Action != "post.update" || last.Outcome != audit.OutcomeFailure {
+		t.Fatalf("expected failed update to be audited, got %+v", last)
+	}
+}
+
+func TestTranslateAndPersistAsync_PublishesEvents(t *testing.T) {
+	post := &domain.Post{ID: 3, AuthorID: 7}
+	newSvc := func(markdownErr error) *postService {
+		svc := newSvcForTest(
+			&stubPostRepo{
+				getByID:  func(id uint) (*domain.Post, error) { return post, nil },
+				updateFn: func(post *domain.Post) error { return nil },
+			},
+			&stubTagRepo{},
+			&config.PostConfig{TranslationAPIURL: "http://translate"},
+			&stubImageAdapter{},
+			&stubTranslationAdapter{
+				singleFn:   func(text string) (string, error) { return "en-title", nil },
+				markdownFn: func(content string) (string, error) { return "en", markdownErr },
+			},
+		)
+		svc.events = NewEventBroker()
+		return svc
+	}
+
+	cases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"completed", nil, domain.EventTranslationCompleted},
+		{"failed", errors.New("quota exceeded"), domain.EventTranslationFailed},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := newSvc(tc.err)
+			events, cancel := svc.events.Subscribe(7)
+			defer cancel()
+
+			svc.translateAndPersistAsync(3, "title", "content", true, true)
+			select {
+			case e := <-events:
+				if e.Type != tc.want || e.PostID != 3 {
+					t.Fatalf("expected %s for post 3, got %+v", tc.want, e)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("expected %s event", tc.want)
+			}
+		})
+	}
+}
+
+func TestTranslateAndPersistAsync_WritesOnlyTheTranslation(t *testing.T) {
+	// the author saves a new title while the translation is running
+	loads := 0
+	var revisions []*domain.PostRevision
+	var gotTitle, gotContent *string
+	svc := newSvcForTest(
+		&stubPostRepo{
+			getByID: func(id uint) (*domain.Post, error) {
+				loads++
+				if loads == 1 {
+					return &domain.Post{ID: 3, AuthorID: 7, Title: "old", Content: "content"}, nil
+				}
+				return &domain.Post{ID: 3, AuthorID: 7, Title: "edited", Content: "content", EnContent: "en"}, nil
+			},
+			updateFn: func(post *domain.Post) error {
+				t.Errorf("did not expect the whole post to be written, got %+v", post)
+				return nil
+			},
+			updateTranslationFn: func(postID uint, enTitle, enContent *string) error {
+				gotTitle, gotContent = enTitle, enContent
+				return nil
+			},
+			revisions: &revisions,
+		},
+		&stubTagRepo{},
+		&config.PostConfig{TranslationAPIURL: "http://translate"},
+		&stubImageAdapter{},
+		&stubTranslationAdapter{
+			singleFn:   func(text string) (string, error) { return "en-title", nil },
+			markdownFn: func(content string) (string, error) { return "en", nil },
+		},
+	)
+	svc.events = NewEventBroker()
+	events, cancel := svc.events.Subscribe(7)
+	defer cancel()
+
+	svc.translateAndPersistAsync(3, "old", "content", false, true)
+	select {
+	case <-events:
+	case <-time.After(time.Second):
+		t.Fatalf("expected a translation event")
+	}
+	if gotTitle != nil || gotContent == nil || *gotContent != "en" {
+		t.Fatalf("expected only the English content to be written, got title=%v content=%v", gotTitle, gotContent)
+	}
+	if len(revisions) != 1 || revisions[0].Title != "edited" || revisions[0].EnContent != "en" || revisions[0].Kind != domain.RevisionTranslation {
+		t.Fatalf("expected a translation revision of the current post, got %+v", revisions)
+	}
+}
+
 func TestListTags(t *testing.T) {
 	svc := newSvcForTest(
-		&stubPostRepo{createFn: func(post *domain.Post) error { return nil }, getByID: func(id uint) (*domain.Post, error) { return nil, nil }, getAll: func(filter model.PostFilter) ([]*domain.Post, error) { return nil, nil }, updateFn: func(post *domain.Post) error { return nil }, deleteFn: func(id uint) error { return nil }},
+		&stubPostRepo{createFn: func(post *domain.Post) error { return nil }, getByID: func(id uint) (*domain.Post, error) { return nil, nil }, getAll: func(filter model.PostFilter) (*domain.PostPage, error) { return nil, nil }, updateFn: func(post *domain.Post) error { return nil }, deleteFn: func(id uint) error { return nil }},
 		&stubTagRepo{
 			attachFn:     func(postID uint, tagNames []string) error { return nil },
 			replaceFn:    func(postID uint, tagNames []string) error { return nil },
@@ -583,3 +857,500 @@ func TestListTags(t *testing.T) {
 		t.Fatalf("expected tags, got %v err=%v", tags, err)
 	}
 }
+
+func TestCreatePost_Slugs(t *testing.T) {
+	var created *domain.Post
+	repo := &stubPostRepo{
+		createFn: func(p *domain.Post) error { p.ID = 9; created = p; return nil },
+		getByID:  func(id uint) (*domain.Post, error) { return created, nil },
+		slugs:    map[string]uint{"hangugeo-beullogeu": 1, "hangugeo-beullogeu-2": 2, "taken": 3},
+	}
+	svc := newSvcForTest(repo, &stubTagRepo{}, &config.PostConfig{},
+		&stubImageAdapter{processFn: func(content string, userID uint) (string, error) { return content, nil }},
+		&stubTranslationAdapter{},
+	)
+
+	got, err := svc.CreatePost(context.Background(), model.CreatePostRequest{Title: "한국어 블로그", Content: "c"}, 1)
+	if err != nil || got.Slug != "hangugeo-beullogeu-3" {
+		t.Fatalf("expected numbered generated slug, got %+v err=%v", got, err)
+	}
+	got, err = svc.CreatePost(context.Background(), model.CreatePostRequest{Title: "2024", Content: "c"}, 1)
+	if err != nil || got.Slug != "post-2024" {
+		t.Fatalf("expected numeric title to be prefixed, got %+v err=%v", got, err)
+	}
+	got, err = svc.CreatePost(context.Background(), model.CreatePostRequest{Title: "t", Slug: "my-choice", Content: "c"}, 1)
+	if err != nil || got.Slug != "my-choice" {
+		t.Fatalf("expected author slug, got %+v err=%v", got, err)
+	}
+	if _, err := svc.CreatePost(context.Background(), model.CreatePostRequest{Title: "t", Slug: "Not Valid", Content: "c"}, 1); !errors.Is(err, domain.ErrInvalidSlug) {
+		t.Fatalf("expected ErrInvalidSlug, got %v", err)
+	}
+	if _, err := svc.CreatePost(context.Background(), model.CreatePostRequest{Title: "t", Slug: "taken", Content: "c"}, 1); !errors.Is(err, domain.ErrSlugTaken) {
+		t.Fatalf("expected ErrSlugTaken, got %v", err)
+	}
+}
+
+func TestUpdatePost_Slugs(t *testing.T) {
+	type change struct{ old, new string }
+	var changes []change
+	repo := &stubPostRepo{
+		getByID: func(id uint) (*domain.Post, error) {
+			return &domain.Post{ID: id, AuthorID: 1, Title: "Hello World", Slug: "hello"}, nil
+		},
+		updateFn: func(post *domain.Post) error { return nil },
+		slugs:    map[string]uint{"hello": 1, "other-post": 2},
+		changeSlugFn: func(postID uint, oldSlug, newSlug string) error {
+			changes = append(changes, change{oldSlug, newSlug})
+			return nil
+		},
+	}
+	svc := newSvcForTest(repo, &stubTagRepo{}, &config.PostConfig{}, &stubImageAdapter{}, &stubTranslationAdapter{})
+	update := func(slug *string) (*domain.Post, error) {
+		title := "Renamed"
+		return svc.UpdatePost(context.Background(), 1, model.UpdatePostRequest{Title: &title, Slug: slug}, 1)
+	}
+
+	if got, err := update(nil); err != nil || got.Slug != "hello" || len(changes) != 0 {
+		t.Fatalf("expected slug to survive a title change, got %+v err=%v changes=%v", got, err, changes)
+	}
+	same := "hello"
+	if _, err := update(&same); err != nil || len(changes) != 0 {
+		t.Fatalf("expected resubmitting the slug to change nothing, err=%v changes=%v", err, changes)
+	}
+	chosen := "hello-again"
+	if got, err := update(&chosen); err != nil || got.Slug != "hello-again" || changes[0] != (change{"hello", "hello-again"}) {
+		t.Fatalf("expected author slug to replace the old one, got %+v err=%v changes=%v", got, err, changes)
+	}
+	empty := ""
+	if got, err := update(&empty); err != nil || got.Slug != "renamed" {
+		t.Fatalf("expected slug regenerated from the title, got %+v err=%v", got, err)
+	}
+	taken := "other-post"
+	if _, err := update(&taken); !errors.Is(err, domain.ErrSlugTaken) {
+		t.Fatalf("expected ErrSlugTaken, got %v", err)
+	}
+}
+
+func TestBackfillSlugs(t *testing.T) {
+	repo := &backfillRepo{stubPostRepo: stubPostRepo{slugs: map[string]uint{"first-post": 1}}}
+	repo.changeSlugFn = func(postID uint, oldSlug, newSlug string) error {
+		repo.slugs[newSlug] = postID
+		return nil
+	}
+	n, err := BackfillSlugs(repo)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 posts backfilled, got %d err=%v", n, err)
+	}
+	if repo.slugs["first-post-2"] != 2 || repo.slugs["first-post-3"] != 3 {
+		t.Fatalf("expected numbered slugs, got %v", repo.slugs)
+	}
+}
+
+// backfillRepo lists two posts without a slug that share a title with a third.
+type backfillRepo struct{ stubPostRepo }
+
+func (r *backfillRepo) ListWithoutSlug() ([]*domain.Post, error) {
+	return []*domain.Post{{ID: 2, Title: "First post"}, {ID: 3, Title: "First post"}}, nil
+}
+
+func TestCreatePost_Schedule(t *testing.T) {
+	var created *domain.Post
+	svc := newSvcForTest(
+		&stubPostRepo{
+			createFn: func(post *domain.Post) error { created = post; return nil },
+			getByID:  func(id uint) (*domain.Post, error) { return created, nil },
+		},
+		&stubTagRepo{},
+		&config.PostConfig{},
+		&stubImageAdapter{processFn: func(content string, userID uint) (string, error) { return content, nil }},
+		&stubTranslationAdapter{},
+	)
+	now := time.Now()
+	at := func(d time.Duration) string { return now.Add(d).Format(time.RFC3339) }
+
+	post, err := svc.CreatePost(context.Background(), model.CreatePostRequest{Title: "t", Content: "c", PublishAt: at(time.Hour), UnpublishAt: at(2 * time.Hour)}, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if post.Published || post.PublishAt == nil || post.UnpublishAt == nil {
+		t.Fatalf("expected a scheduled draft, got %+v", post)
+	}
+
+	for name, req := range map[string]model.CreatePostRequest{
+		"not a time":               {PublishAt: "tomorrow"},
+		"publish in the past":      {PublishAt: at(-time.Hour)},
+		"published and scheduled":  {Published: true, PublishAt: at(time.Hour)},
+		"unpublish of a draft":     {UnpublishAt: at(time.Hour)},
+		"unpublish before publish": {PublishAt: at(2 * time.Hour), UnpublishAt: at(time.Hour)},
+	} {
+		req.Title, req.Content = "t", "c"
+		if _, err := svc.CreatePost(context.Background(), req, 1); !errors.Is(err, domain.ErrInvalidSchedule) {
+			t.Errorf("%s: expected ErrInvalidSchedule, got %v", name, err)
+		}
+	}
+}
+
+func TestUpdatePost_Schedule(t *testing.T) {
+	publishAt := time.Now().Add(time.Hour)
+	post := &domain.Post{ID: 1, AuthorID: 1, Slug: "t", PublishAt: &publishAt}
+	svc := newSvcForTest(
+		&stubPostRepo{
+			getByID:  func(id uint) (*domain.Post, error) { return post, nil },
+			updateFn: func(post *domain.Post) error { return nil },
+		},
+		&stubTagRepo{},
+		&config.PostConfig{},
+		&stubImageAdapter{},
+		&stubTranslationAdapter{},
+	)
+
+	// publishing by hand drops the scheduled publish
+	published := true
+	updated, err := svc.UpdatePost(context.Background(), 1, model.UpdatePostRequest{Published: &published}, 1)
+	if err != nil || !updated.Published || updated.PublishAt != nil {
+		t.Fatalf("expected published post without publish_at, got %+v err=%v", updated, err)
+	}
+
+	unpublishAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	updated, err = svc.UpdatePost(context.Background(), 1, model.UpdatePostRequest{UnpublishAt: &unpublishAt}, 1)
+	if err != nil || updated.UnpublishAt == nil {
+		t.Fatalf("expected scheduled unpublish, got %+v err=%v", updated, err)
+	}
+
+	// unpublishing by hand drops the scheduled unpublish
+	published = false
+	updated, err = svc.UpdatePost(context.Background(), 1, model.UpdatePostRequest{Published: &published}, 1)
+	if err != nil || updated.Published || updated.UnpublishAt != nil {
+		t.Fatalf("expected draft without unpublish_at, got %+v err=%v", updated, err)
+	}
+
+	if _, err := svc.UpdatePost(context.Background(), 1, model.UpdatePostRequest{UnpublishAt: &unpublishAt}, 1); !errors.Is(err, domain.ErrInvalidSchedule) {
+		t.Fatalf("expected ErrInvalidSchedule for unpublish_at on a draft, got %v", err)
+	}
+}
+
+func TestGetPostForAuthor(t *testing.T) {
+	svc := newSvcForTest(
+		&stubPostRepo{getByID: func(id uint) (*domain.Post, error) { return &domain.Post{ID: id, AuthorID: 7}, nil }},
+		&stubTagRepo{getTagsFn: func(postID uint) ([]*domain.Tag, error) { return nil, nil }},
+		&config.PostConfig{},
+		&stubImageAdapter{},
+		&stubTranslationAdapter{},
+	)
+	if post, err := svc.GetPostForAuthor(1, 7); err != nil || post.Published {
+		t.Fatalf("expected the author's draft, got %+v err=%v", post, err)
+	}
+	if _, err := svc.GetPostForAuthor(1, 8); err == nil {
+		t.Fatalf("expected another author's draft not to be found")
+	}
+}
+
+func TestSearchPosts(t *testing.T) {
+	var seen model.SearchQuery
+	svc := newSvcForTest(
+		&stubPostRepo{searchFn: func(query model.SearchQuery) ([]*domain.SearchHit, int64, error) {
+			seen = query
+			return []*domain.SearchHit{
+				{
+					Post:           &domain.Post{ID: 2, Title: "Go <generics>", Content: "long content", EnContent: "long translation"},
+					TitleHighlight: "\ue000Go\ue001 <generics>",
+					Snippet:        "using \ue000go\ue001 & friends",
+				},
+				// a substring match has no headline markers, so the query is marked directly
+				{Post: &domain.Post{ID: 3}, TitleHighlight: "한국어 블로그", Snippet: "블로그를 시작하며"},
+			}, 42, nil
+		}},
+		&stubTagRepo{},
+		&config.PostConfig{},
+		&stubImageAdapter{},
+		&stubTranslationAdapter{},
+	)
+
+	if _, err := svc.SearchPosts(model.SearchQuery{Text: "   "}); !errors.Is(err, domain.ErrEmptySearch) {
+		t.Fatalf("expected ErrEmptySearch, got %v", err)
+	}
+
+	result, err := svc.SearchPosts(model.SearchQuery{Text: " go ", Limit: 500, Offset: -3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if seen.Text != "go" || seen.Limit != maxSearchLimit || seen.Offset != 0 {
+		t.Fatalf("unexpected repository query %+v", seen)
+	}
+	if result.Total != 42 || result.Query != "go" || len(result.Hits) != 2 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	hit := result.Hits[0]
+	if hit.TitleHighlight != "<mark>Go</mark> &lt;generics&gt;" || hit.Snippet != "using <mark>go</mark> &amp; friends" {
+		t.Fatalf("unexpected highlights %q %q", hit.TitleHighlight, hit.Snippet)
+	}
+	if hit.Post.Content != "" || hit.Post.EnContent != "" {
+		t.Fatalf("expected hits without content, got %+v", hit.Post)
+	}
+
+	result, err = svc.SearchPosts(model.SearchQuery{Text: "블로그"})
+	if err != nil || seen.Limit != defaultSearchLimit {
+		t.Fatalf("expected the default limit, got %+v err=%v", seen, err)
+	}
+	if got := result.Hits[1].Snippet; got != "<mark>블로그</mark>를 시작하며" {
+		t.Fatalf("unexpected substring highlight %q", got)
+	}
+}
+
+// stubRelatedCache keeps related posts in memory, by generation and post.
+type stubRelatedCache struct {
+	generation int64
+	entries    map[[2]int64][]*domain.RelatedPost
+	err        error // returned by every method when set
+}
+
+func (c *stubRelatedCache) Generation() (int64, error) { return c.generation, c.err }
+func (c *stubRelatedCache) Get(generation int64, postID uint) ([]*domain.RelatedPost, bool, error) {
+	related, ok := c.entries[[2]int64{generation, int64(postID)}]
+	return related, ok, c.err
+}
+func (c *stubRelatedCache) Set(generation int64, postID uint, related []*domain.RelatedPost) error {
+	if c.entries == nil {
+		c.entries = make(map[[2]int64][]*domain.RelatedPost)
+	}
+	c.entries[[2]int64{generation, int64(postID)}] = related
+	return c.err
+}
+func (c *stubRelatedCache) Invalidate() error {
+	c.generation++
+	return c.err
+}
+
+func TestRelatedPosts(t *testing.T) {
+	ranked := 0
+	repo := &stubPostRepo{
+		getByID: func(id uint) (*domain.Post, error) {
+			if id == 2 {
+				return &domain.Post{ID: 2}, nil // a draft
+			}
+			if id != 1 {
+				return nil, errors.New("post not found")
+			}
+			return &domain.Post{ID: 1, Published: true}, nil
+		},
+		relatedFn: func(postID uint, limit int) ([]*domain.RelatedPost, error) {
+			ranked++
+			if limit != maxRelatedLimit {
+				t.Fatalf("expected the posts to be ranked up to %d, got %d", maxRelatedLimit, limit)
+			}
+			related := make([]*domain.RelatedPost, 0, 5)
+			for id := uint(10); id < 15; id++ {
+				related = append(related, &domain.RelatedPost{Post: &domain.Post{ID: id, Content: "text", EnContent: "text"}})
+			}
+			return related, nil
+		},
+	}
+	svc := newSvcForTest(repo, &stubTagRepo{}, &config.PostConfig{}, &stubImageAdapter{}, &stubTranslationAdapter{})
+	cache := &stubRelatedCache{}
+	svc.related = cache
+
+	related, err := svc.RelatedPosts(1, 0)
+	if err != nil || len(related) != defaultRelatedLimit || related[0].Post.ID != 10 {
+		t.Fatalf("expected the %d best posts, got %v err=%v", defaultRelatedLimit, related, err)
+	}
+	if related[0].Post.Content != "" || related[0].Post.EnContent != "" {
+		t.Fatalf("expected related posts without their content, got %+v", related[0].Post)
+	}
+	if related, err := svc.RelatedPosts(1, 4); err != nil || len(related) != 4 || ranked != 1 {
+		t.Fatalf("expected 4 posts from the cache, got %d err=%v ranked=%d", len(related), err, ranked)
+	}
+	if related, _ := svc.RelatedPosts(1, 100); len(related) != 5 {
+		t.Fatalf("expected every ranked post, got %d", len(related))
+	}
+
+	// a change to any post starts a new generation, so the posts are ranked again
+	svc.postsChanged()
+	if _, err := svc.RelatedPosts(1, 0); err != nil || ranked != 2 {
+		t.Fatalf("expected the posts to be ranked again, got ranked=%d err=%v", ranked, err)
+	}
+
+	// a failing cache is skipped
+	cache.err = errors.New("redis down")
+	if related, err := svc.RelatedPosts(1, 0); err != nil || len(related) != defaultRelatedLimit || ranked != 3 {
+		t.Fatalf("expected ranking without the cache, got %v err=%v ranked=%d", related, err, ranked)
+	}
+
+	for _, id := range []uint{2, 3} {
+		if _, err := svc.RelatedPosts(id, 0); !errors.Is(err, domain.ErrPostNotFound) {
+			t.Fatalf("post %d: expected not found, got %v", id, err)
+		}
+	}
+	repo.relatedFn = func(postID uint, limit int) ([]*domain.RelatedPost, error) { return nil, errors.New("db down") }
+	if _, err := svc.RelatedPosts(1, 0); err == nil {
+		t.Fatalf("expected ranking error")
+	}
+}
+
+func TestDeletePost_InvalidatesRelated(t *testing.T) {
+	svc := newSvcForTest(
+		&stubPostRepo{
+			getByID:  func(id uint) (*domain.Post, error) { return &domain.Post{ID: id, AuthorID: 1}, nil },
+			deleteFn: func(id uint) error { return nil },
+		},
+		&stubTagRepo{},
+		&config.PostConfig{},
+		&stubImageAdapter{extractFn: func(content string) []string { return nil }},
+		&stubTranslationAdapter{},
+	)
+	cache := &stubRelatedCache{}
+	svc.related = cache
+
+	if err := svc.DeletePost(context.Background(), 1, 2); err == nil || cache.generation != 0 {
+		t.Fatalf("expected a refused delete to keep the cache, got err=%v generation=%d", err, cache.generation)
+	}
+	if err := svc.DeletePost(context.Background(), 1, 1); err != nil || cache.generation != 1 {
+		t.Fatalf("expected the delete to invalidate the cache, got err=%v generation=%d", err, cache.generation)
+	}
+}
+
+func TestRunSchedule_RunsHooks(t *testing.T) {
+	translated := make(chan uint, 2)
+	events := NewEventBroker()
+	ch, cancel := events.Subscribe(7)
+	defer cancel()
+	svc := NewPostService(
+		&stubPostRepo{
+			scheduleFn: func(now time.Time) ([]*domain.Post, []*domain.Post, error) {
+				return []*domain.Post{{ID: 1, AuthorID: 7, Title: "t", Content: "c", Published: true}},
+					[]*domain.Post{{ID: 2, AuthorID: 7}}, nil
+			},
+			getByID: func(id uint) (*domain.Post, error) {
+				translated <- id
+				return nil, errors.New("stop")
+			},
+		},
+		&stubTagRepo{},
+		nil,
+		&config.PostConfig{TranslationAPIURL: "http://translate"},
+		&stubImageAdapter{},
+		&stubTranslationAdapter{},
+		nil,
+		events,
+		nil,
+	).(*postService)
+	rec := &stubRecorder{}
+	svc.auditor = rec
+
+	n, err := svc.RunSchedule(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 changes, got %d err=%v", n, err)
+	}
+	got := rec.actions()
+	if len(got) != 2 || got[0] != "post.publish:success" || got[1] != "post.unpublish:success" {
+		t.Fatalf("unexpected audit actions: %v", got)
+	}
+	for _, want := range []string{domain.EventPostPublished, domain.EventPostUnpublished} {
+		select {
+		case e := <-ch:
+			if e.Type != want {
+				t.Fatalf("expected %s, got %s", want, e.Type)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s event", want)
+		}
+	}
+	select {
+	case id := <-translated:
+		if id != 1 {
+			t.Fatalf("expected the published post to be translated, got %d", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected translation of the published post")
+	}
+}
+
+func TestRunSchedule_RepoError(t *testing.T) {
+	svc := newSvcForTest(
+		&stubPostRepo{scheduleFn: func(now time.Time) ([]*domain.Post, []*domain.Post, error) {
+			return nil, nil, errors.New("db down")
+		}},
+		&stubTagRepo{},
+		&config.PostConfig{},
+		&stubImageAdapter{},
+		&stubTranslationAdapter{},
+	)
+	if _, err := svc.RunSchedule(context.Background()); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestRevisions_RecordDiffAndRestore(t *testing.T) {
+	var revisions []*domain.PostRevision
+	var stored *domain.Post
+	repo := &stubPostRepo{
+		createFn: func(post *domain.Post) error { post.ID = 1; stored = post; return nil },
+		getByID: func(id uint) (*domain.Post, error) {
+			copied := *stored
+			return &copied, nil
+		},
+		updateFn:  func(post *domain.Post) error { stored = post; return nil },
+		revisions: &revisions,
+	}
+	var replacedTags []string
+	tagRepo := &stubTagRepo{replaceFn: func(postID uint, tagNames []string) error { replacedTags = tagNames; return nil }}
+	svc := newSvcForTest(repo, tagRepo,
+		&config.PostConfig{},
+		&stubImageAdapter{processFn: func(content string, userID uint) (string, error) { return content, nil }},
+		&stubTranslationAdapter{},
+	)
+	rec := &stubRecorder{}
+	svc.auditor = rec
+	ctx := context.Background()
+
+	if _, err := svc.CreatePost(ctx, model.CreatePostRequest{Title: "t", Content: "one\ntwo\n"}, 7); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	content := "one\n2\n"
+	if _, err := svc.UpdatePost(ctx, 1, model.UpdatePostRequest{Content: &content}, 7); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Kind != domain.RevisionCreate || revisions[1].Kind != domain.RevisionEdit || *revisions[1].AuthorID != 7 {
+		t.Fatalf("unexpected revisions %+v", revisions)
+	}
+	tags := []string{"go"}
+	if _, err := svc.UpdatePost(ctx, 1, model.UpdatePostRequest{Tags: &tags}, 7); err != nil || len(replacedTags) != 1 {
+		t.Fatalf("update tags: %v", err)
+	}
+	if _, err := svc.UpdatePost(ctx, 1, model.UpdatePostRequest{Content: &content}, 7); err != nil {
+		t.Fatalf("update with the same content: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("expected no revision for an update that leaves the text alone, got %+v", revisions)
+	}
+
+	diff, err := svc.DiffRevisions(1, 0, 2, 7)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if diff.From != 1 || diff.To != 2 || !strings.Contains(diff.Diff, "--- revision 1/content\n+++ revision 2/content\n") || !strings.Contains(diff.Diff, "-two\n+2\n") || strings.Contains(diff.Diff, "/title") {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+	if diff, err := svc.DiffRevisions(1, 0, 1, 7); err != nil || diff.From != 0 || !strings.Contains(diff.Diff, "--- empty/title") {
+		t.Fatalf("expected the first revision compared with an empty post, got %+v err=%v", diff, err)
+	}
+	if _, err := svc.DiffRevisions(1, 0, 2, 8); err == nil {
+		t.Fatalf("expected another author's history not to be found")
+	}
+
+	post, err := svc.RestoreRevision(ctx, 1, 1, 7)
+	if err != nil || post.Content != "one\ntwo\n" {
+		t.Fatalf("restore: %+v err=%v", post, err)
+	}
+	if len(revisions) != 3 || revisions[2].Kind != domain.RevisionRestore {
+		t.Fatalf("expected the restore to add a revision, got %+v", revisions)
+	}
+	if _, err := svc.RestoreRevision(ctx, 1, 9, 7); !errors.Is(err, domain.ErrRevisionNotFound) {
+		t.Fatalf("expected ErrRevisionNotFound, got %v", err)
+	}
+	if last := rec.entries[len(rec.entries)-1]; last.Action != "post.restore" || last.Outcome != audit.OutcomeFailure {
+		t.Fatalf("expected failed restore to be audited, got %+v", last)
+	}
+	if revs, err := svc.ListRevisions(1, 7); err != nil || len(revs) != 3 || revs[0].ID != 3 {
+		t.Fatalf("expected newest revision first, got %v err=%v", revs, err)
+	}
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"seungpyo.lee/PersonalWebSite/pkg/logger"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+)
+
+// RunScheduler applies the publishing schedule every interval until ctx is done.
+func RunScheduler(ctx context.Context, svc domain.PostService, interval time.Duration, logger *logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := svc.RunSchedule(ctx); err != nil {
+			logger.Error("failed to run publishing schedule: " + err.Error())
+		} else if n > 0 {
+			logger.Info(fmt.Sprintf("published or unpublished %d scheduled posts", n))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
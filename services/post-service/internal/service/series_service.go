@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+// Limits on what a series may hold.
+const (
+	maxSeriesDescription = 2000
+	maxSeriesParts       = 100
+)
+
+type seriesService struct {
+	series  domain.SeriesRepository
+	posts   domain.PostRepository
+	auditor audit.Recorder
+}
+
+// NewSeriesService creates a new SeriesService. auditor may be nil, in which case
+// changes to series are not audited.
+func NewSeriesService(series domain.SeriesRepository, posts domain.PostRepository, auditor audit.Recorder) domain.SeriesService {
+	return &seriesService{series: series, posts: posts, auditor: auditor}
+}
+
+// ListSeries returns the series that have published parts, newest first.
+func (s *seriesService) ListSeries() ([]*domain.Series, error) {
+	all, err := s.series.List()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*domain.Series, 0, len(all))
+	for _, series := range all {
+		if series.Parts = series.PublishedParts(); len(series.Parts) > 0 {
+			list = append(list, series)
+		}
+	}
+	return list, nil
+}
+
+// GetSeries returns a series with its published parts. A series without any is not
+// found.
+func (s *seriesService) GetSeries(id uint) (*domain.Series, error) {
+	series, err := s.series.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if series.Parts = series.PublishedParts(); len(series.Parts) == 0 {
+		return nil, domain.ErrSeriesNotFound
+	}
+	return series, nil
+}
+
+// CreateSeries creates a series of the author's posts, in the given order.
+func (s *seriesService) CreateSeries(ctx context.Context, req model.CreateSeriesRequest, authorID uint) (*domain.Series, error) {
+	series := &domain.Series{AuthorID: authorID}
+	err := applySeriesText(series, req.Title, req.Description)
+	if err == nil {
+		err = s.checkParts(0, req.PostIDs, authorID)
+	}
+	if err == nil {
+		err = s.series.Create(series, req.PostIDs)
+	}
+	if err != nil {
+		s.record(ctx, "series.create", series.ID, nil, nil, err)
+		return nil, err
+	}
+	created, err := s.series.GetByID(series.ID)
+	s.record(ctx, "series.create", series.ID, nil, seriesSummary(created), err)
+	return created, err
+}
+
+// UpdateSeries changes the title, description or parts of the author's series. The
+// given post IDs replace the parts, in order.
+func (s *seriesService) UpdateSeries(ctx context.Context, id uint, req model.UpdateSeriesRequest, authorID uint) (*domain.Series, error) {
+	series, err := s.authorSeries(id, authorID)
+	if err != nil {
+		return nil, err
+	}
+	before := seriesSummary(series)
+	title, description := series.Title, series.Description
+	if req.Title != nil {
+		title = *req.Title
+	}
+	if req.Description != nil {
+		description = *req.Description
+	}
+	var postIDs []uint
+	err = applySeriesText(series, title, description)
+	if err == nil && req.PostIDs != nil {
+		postIDs = append([]uint{}, (*req.PostIDs)...)
+		err = s.checkParts(id, postIDs, authorID)
+	}
+	if err == nil {
+		err = s.series.Update(series, postIDs)
+	}
+	if err != nil {
+		s.record(ctx, "series.update", id, before, nil, err)
+		return nil, err
+	}
+	updated, err := s.series.GetByID(id)
+	s.record(ctx, "series.update", id, before, seriesSummary(updated), err)
+	return updated, err
+}
+
+// DeleteSeries deletes the author's series. Its posts are kept.
+func (s *seriesService) DeleteSeries(ctx context.Context, id, authorID uint) error {
+	series, err := s.authorSeries(id, authorID)
+	if err != nil {
+		return err
+	}
+	err = s.series.Delete(id)
+	s.record(ctx, "series.delete", id, seriesSummary(series), nil, err)
+	return err
+}
+
+// authorSeries returns a series of authorID; other authors' series are reported as
+// not found.
+func (s *seriesService) authorSeries(id, authorID uint) (*domain.Series, error) {
+	series, err := s.series.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if series.AuthorID != authorID {
+		return nil, domain.ErrSeriesNotFound
+	}
+	return series, nil
+}
+
+// applySeriesText sets a series' title and description after checking them.
+func applySeriesText(series *domain.Series, title, description string) error {
+	title = strings.TrimSpace(title)
+	description = strings.TrimSpace(description)
+	if title == "" {
+		return fmt.Errorf("%w: a title is required", domain.ErrInvalidSeries)
+	}
+	if utf8.RuneCountInString(description) > maxSeriesDescription {
+		return fmt.Errorf("%w: descriptions are limited to %d characters", domain.ErrInvalidSeries, maxSeriesDescription)
+	}
+	series.Title, series.Description = title, description
+	return nil
+}
+
+// checkParts makes sure postIDs are distinct posts of the author that belong to no
+// series other than seriesID.
+func (s *seriesService) checkParts(seriesID uint, postIDs []uint, authorID uint) error {
+	if len(postIDs) > maxSeriesParts {
+		return fmt.Errorf("%w: a series has at most %d parts", domain.ErrInvalidSeries, maxSeriesParts)
+	}
+	seen := make(map[uint]bool, len(postIDs))
+	for _, id := range postIDs {
+		if seen[id] {
+			return fmt.Errorf("%w: post %d is listed twice", domain.ErrInvalidSeries, id)
+		}
+		seen[id] = true
+		post, err := s.posts.GetByID(id)
+		if err != nil || post.AuthorID != authorID {
+			return fmt.Errorf("%w: post %d not found", domain.ErrInvalidSeries, id)
+		}
+	}
+	members, err := s.series.Memberships(postIDs)
+	if err != nil {
+		return err
+	}
+	for _, id := range postIDs {
+		if other, ok := members[id]; ok && other != seriesID {
+			return fmt.Errorf("%w: post %d", domain.ErrSeriesConflict, id)
+		}
+	}
+	return nil
+}
+
+// seriesSummary is the audit before/after snapshot of a series.
+func seriesSummary(series *domain.Series) interface{} {
+	if series == nil {
+		return nil
+	}
+	posts := make([]uint, 0, len(series.Parts))
+	for _, p := range series.Parts {
+		posts = append(posts, p.PostID)
+	}
+	return map[string]interface{}{
+		"title": series.Title,
+		"posts": posts,
+	}
+}
+
+// record appends an audit entry for a change to a series.
+func (s *seriesService) record(ctx context.Context, action string, id uint, before, after interface{}, err error) {
+	targetID := ""
+	if id != 0 {
+		targetID = strconv.FormatUint(uint64(id), 10)
+	}
+	audit.Record(ctx, s.auditor, &audit.Entry{
+		Action:     action,
+		TargetType: "series",
+		TargetID:   targetID,
+		Before:     audit.Summary(before),
+		After:      audit.Summary(after),
+	}, err)
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+// stubSeriesRepo keeps series in memory, with their parts drawn from posts.
+type stubSeriesRepo struct {
+	series  map[uint]*domain.Series
+	members map[uint][]uint // series ID to post IDs in order
+	posts   map[uint]*domain.Post
+}
+
+func newStubSeriesRepo(posts ...*domain.Post) *stubSeriesRepo {
+	r := &stubSeriesRepo{series: map[uint]*domain.Series{}, members: map[uint][]uint{}, posts: map[uint]*domain.Post{}}
+	for _, p := range posts {
+		r.posts[p.ID] = p
+	}
+	return r
+}
+
+func (r *stubSeriesRepo) withParts(s *domain.Series) *domain.Series {
+	copied := *s
+	copied.Parts = []*domain.SeriesPart{}
+	for _, id := range r.members[s.ID] {
+		p := r.posts[id]
+		copied.Parts = append(copied.Parts, &domain.SeriesPart{PostID: id, Title: p.Title, Slug: p.Slug, Published: p.Published})
+	}
+	return &copied
+}
+
+func (r *stubSeriesRepo) Create(s *domain.Series, postIDs []uint) error {
+	s.ID = uint(len(r.series) + 1)
+	r.series[s.ID] = s
+	r.members[s.ID] = postIDs
+	return nil
+}
+
+func (r *stubSeriesRepo) GetByID(id uint) (*domain.Series, error) {
+	s, ok := r.series[id]
+	if !ok {
+		return nil, domain.ErrSeriesNotFound
+	}
+	return r.withParts(s), nil
+}
+
+func (r *stubSeriesRepo) List() ([]*domain.Series, error) {
+	var out []*domain.Series
+	for id := uint(len(r.series)); id > 0; id-- {
+		out = append(out, r.withParts(r.series[id]))
+	}
+	return out, nil
+}
+
+func (r *stubSeriesRepo) Update(s *domain.Series, postIDs []uint) error {
+	r.series[s.ID] = s
+	if postIDs != nil {
+		r.members[s.ID] = postIDs
+	}
+	return nil
+}
+
+func (r *stubSeriesRepo) Delete(id uint) error {
+	delete(r.series, id)
+	delete(r.members, id)
+	return nil
+}
+
+func (r *stubSeriesRepo) SeriesOf(postID uint) (*domain.Series, error) {
+	for id, ids := range r.members {
+		for _, p := range ids {
+			if p == postID {
+				return r.GetByID(id)
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (r *stubSeriesRepo) Memberships(postIDs []uint) (map[uint]uint, error) {
+	out := map[uint]uint{}
+	for _, postID := range postIDs {
+		if s, _ := r.SeriesOf(postID); s != nil {
+			out[postID] = s.ID
+		}
+	}
+	return out, nil
+}
+
+func seriesFixture() (*stubSeriesRepo, *stubPostRepo) {
+	posts := []*domain.Post{
+		{ID: 1, AuthorID: 7, Title: "Part one", Slug: "one", Published: true},
+		{ID: 2, AuthorID: 7, Title: "Draft part", Slug: "draft"},
+		{ID: 3, AuthorID: 7, Title: "Part two", Slug: "two", Published: true},
+		{ID: 4, AuthorID: 8, Title: "Someone else's", Published: true},
+		{ID: 5, AuthorID: 7, Title: "Part three", Slug: "three", Published: true},
+	}
+	repo := newStubSeriesRepo(posts...)
+	postRepo := &stubPostRepo{getByID: func(id uint) (*domain.Post, error) {
+		if p, ok := repo.posts[id]; ok {
+			copied := *p
+			return &copied, nil
+		}
+		return nil, errors.New("post not found")
+	}}
+	return repo, postRepo
+}
+
+func TestCreateSeries(t *testing.T) {
+	repo, posts := seriesFixture()
+	svc := NewSeriesService(repo, posts, nil)
+	ctx := context.Background()
+
+	for name, req := range map[string]model.CreateSeriesRequest{
+		"blank title":    {Title: "  "},
+		"duplicate post": {Title: "Go", PostIDs: []uint{1, 1}},
+		"missing post":   {Title: "Go", PostIDs: []uint{99}},
+		"other author":   {Title: "Go", PostIDs: []uint{4}},
+	} {
+		if _, err := svc.CreateSeries(ctx, req, 7); !errors.Is(err, domain.ErrInvalidSeries) {
+			t.Errorf("%s: expected ErrInvalidSeries, got %v", name, err)
+		}
+	}
+
+	s, err := svc.CreateSeries(ctx, model.CreateSeriesRequest{Title: " Go ", Description: "Learn Go", PostIDs: []uint{3, 1, 2}}, 7)
+	if err != nil || s.Title != "Go" || len(s.Parts) != 3 || s.Parts[0].PostID != 3 {
+		t.Fatalf("unexpected series %+v err=%v", s, err)
+	}
+	if _, err := svc.CreateSeries(ctx, model.CreateSeriesRequest{Title: "Other", PostIDs: []uint{5, 1}}, 7); !errors.Is(err, domain.ErrSeriesConflict) {
+		t.Fatalf("expected ErrSeriesConflict, got %v", err)
+	}
+}
+
+func TestUpdateAndDeleteSeries(t *testing.T) {
+	repo, posts := seriesFixture()
+	svc := NewSeriesService(repo, posts, nil)
+	ctx := context.Background()
+	s, _ := svc.CreateSeries(ctx, model.CreateSeriesRequest{Title: "Go", PostIDs: []uint{1, 3}}, 7)
+
+	if _, err := svc.UpdateSeries(ctx, s.ID, model.UpdateSeriesRequest{}, 8); !errors.Is(err, domain.ErrSeriesNotFound) {
+		t.Fatalf("other authors must not update, got %v", err)
+	}
+	title := "Go basics"
+	updated, err := svc.UpdateSeries(ctx, s.ID, model.UpdateSeriesRequest{Title: &title}, 7)
+	if err != nil || updated.Title != title || len(updated.Parts) != 2 {
+		t.Fatalf("unexpected update %+v err=%v", updated, err)
+	}
+	order := []uint{5, 3, 1}
+	updated, err = svc.UpdateSeries(ctx, s.ID, model.UpdateSeriesRequest{PostIDs: &order}, 7)
+	if err != nil || len(updated.Parts) != 3 || updated.Parts[0].PostID != 5 || updated.Parts[2].PostID != 1 {
+		t.Fatalf("expected reordered parts, got %+v err=%v", updated, err)
+	}
+
+	if err := svc.DeleteSeries(ctx, s.ID, 8); !errors.Is(err, domain.ErrSeriesNotFound) {
+		t.Fatalf("other authors must not delete, got %v", err)
+	}
+	if err := svc.DeleteSeries(ctx, s.ID, 7); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := svc.GetSeries(s.ID); !errors.Is(err, domain.ErrSeriesNotFound) {
+		t.Fatalf("expected the series to be gone, got %v", err)
+	}
+}
+
+func TestGetSeries_PublishedPartsOnly(t *testing.T) {
+	repo, posts := seriesFixture()
+	svc := NewSeriesService(repo, posts, nil)
+	ctx := context.Background()
+	s, _ := svc.CreateSeries(ctx, model.CreateSeriesRequest{Title: "Go", PostIDs: []uint{1, 2, 3}}, 7)
+	drafts, _ := svc.CreateSeries(ctx, model.CreateSeriesRequest{Title: "Soon"}, 7)
+
+	got, err := svc.GetSeries(s.ID)
+	if err != nil || len(got.Parts) != 2 || got.Parts[1].PostID != 3 {
+		t.Fatalf("expected the published parts, got %+v err=%v", got, err)
+	}
+	if _, err := svc.GetSeries(drafts.ID); !errors.Is(err, domain.ErrSeriesNotFound) {
+		t.Fatalf("a series without published parts is not found, got %v", err)
+	}
+	list, err := svc.ListSeries()
+	if err != nil || len(list) != 1 || list[0].ID != s.ID {
+		t.Fatalf("unexpected list %+v err=%v", list, err)
+	}
+}
+
+func TestGetPost_SeriesNavigation(t *testing.T) {
+	repo, posts := seriesFixture()
+	_, _ = NewSeriesService(repo, posts, nil).CreateSeries(context.Background(), model.CreateSeriesRequest{Title: "Go", PostIDs: []uint{1, 2, 3, 5}}, 7)
+	tags := &stubTagRepo{getTagsFn: func(postID uint) ([]*domain.Tag, error) { return nil, nil }}
+	svc := NewPostService(posts, tags, repo, nil, &stubImageAdapter{}, &stubTranslationAdapter{}, nil, nil, nil)
+
+	cases := []struct {
+		id         uint
+		part       int
+		prev, next uint
+	}{
+		{1, 1, 0, 3},
+		{3, 2, 1, 5},
+		{5, 3, 3, 0},
+	}
+	for _, tc := range cases {
+		post, err := svc.GetPost(tc.id)
+		if err != nil || post.Series == nil {
+			t.Fatalf("post %d: expected series navigation, got %+v err=%v", tc.id, post, err)
+		}
+		nav := post.Series
+		if nav.Part != tc.part || nav.Total != 3 || nav.Title != "Go" {
+			t.Fatalf("post %d: unexpected navigation %+v", tc.id, nav)
+		}
+		if (nav.Prev == nil) != (tc.prev == 0) || (nav.Prev != nil && nav.Prev.PostID != tc.prev) {
+			t.Fatalf("post %d: unexpected previous part %+v", tc.id, nav.Prev)
+		}
+		if (nav.Next == nil) != (tc.next == 0) || (nav.Next != nil && nav.Next.PostID != tc.next) {
+			t.Fatalf("post %d: unexpected next part %+v", tc.id, nav.Next)
+		}
+	}
+	if post, err := NewPostService(posts, tags, newStubSeriesRepo(), nil, &stubImageAdapter{}, &stubTranslationAdapter{}, nil, nil, nil).GetPost(1); err != nil || post.Series != nil {
+		t.Fatalf("expected no series, got %+v err=%v", post, err)
+	}
+}
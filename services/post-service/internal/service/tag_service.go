@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"seungpyo.lee/PersonalWebSite/pkg/audit"
+	"seungpyo.lee/PersonalWebSite/pkg/logger"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/util"
+)
+
+// maxTagDescription bounds tag descriptions, which are shown on tag pages.
+const maxTagDescription = 500
+
+type tagService struct {
+	tags    domain.TagRepository
+	auditor audit.Recorder
+	related domain.RelatedCache
+	logger  *logger.Logger
+}
+
+// NewTagService creates a new TagService. auditor and related may be nil, in which
+// case changes to tags are not audited and there is no related posts cache to drop
+// when tags are renamed or merged.
+func NewTagService(tags domain.TagRepository, auditor audit.Recorder, related domain.RelatedCache) domain.TagService {
+	return &tagService{tags: tags, auditor: auditor, related: related, logger: logger.New("info")}
+}
+
+// GetTag finds a tag by its name, in any case, or by an alias. Tags without published
+// posts are not found, so the tags of drafts stay private.
+func (s *tagService) GetTag(name string) (*domain.Tag, error) {
+	name = util.NormalizeTag(name)
+	if name == "" {
+		return nil, domain.ErrTagNotFound
+	}
+	tag, err := s.tags.FindTag(name)
+	if err != nil {
+		return nil, err
+	}
+	if tag.PostCount == 0 {
+		return nil, domain.ErrTagNotFound
+	}
+	return tag, nil
+}
+
+// ListAllTags returns every tag with its aliases and the count of all its posts.
+func (s *tagService) ListAllTags() ([]*domain.Tag, error) {
+	return s.tags.ListAllTags()
+}
+
+// UpdateTag renames a tag or changes its description. A tag cannot take the name or
+// alias of another tag; merging them is the way to combine the two.
+func (s *tagService) UpdateTag(ctx context.Context, id uint, req model.UpdateTagRequest) (*domain.Tag, error) {
+	tag, err := s.tags.GetTagByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := tagSummary(tag)
+	if req.Name != nil {
+		name := util.NormalizeTag(*req.Name)
+		if err = s.checkName(name, id); err == nil {
+			tag.Name = name
+		}
+	}
+	if err == nil && req.Description != nil {
+		description := strings.TrimSpace(*req.Description)
+		if utf8.RuneCountInString(description) > maxTagDescription {
+			err = fmt.Errorf("%w: descriptions are limited to %d characters", domain.ErrInvalidTag, maxTagDescription)
+		}
+		tag.Description = description
+	}
+	if err == nil {
+		err = s.tags.UpdateTag(tag)
+	}
+	if err != nil {
+		s.record(ctx, "tag.update", id, before, nil, err)
+		return nil, err
+	}
+	if req.Name != nil {
+		// related posts are served with their tags' names
+		invalidateRelated(s.related, s.logger)
+	}
+	updated, err := s.tags.GetTagByID(id)
+	s.record(ctx, "tag.update", id, before, tagSummary(updated), err)
+	return updated, err
+}
+
+// MergeTag moves the posts and aliases of a tag to intoID and deletes it. Its name
+// becomes an alias of intoID.
+func (s *tagService) MergeTag(ctx context.Context, id, intoID uint) (*domain.Tag, error) {
+	if id == intoID {
+		return nil, fmt.Errorf("%w: a tag cannot be merged into itself", domain.ErrInvalidTag)
+	}
+	from, err := s.tags.GetTagByID(id)
+	if err != nil {
+		return nil, err
+	}
+	into, err := s.tags.GetTagByID(intoID)
+	if err != nil {
+		return nil, err
+	}
+	before := map[string]interface{}{"from": tagSummary(from), "into": tagSummary(into)}
+	if err := s.tags.MergeTags(id, intoID); err != nil {
+		s.record(ctx, "tag.merge", id, before, nil, err)
+		return nil, err
+	}
+	invalidateRelated(s.related, s.logger)
+	merged, err := s.tags.GetTagByID(intoID)
+	s.record(ctx, "tag.merge", id, before, tagSummary(merged), err)
+	return merged, err
+}
+
+// AddAlias adds another spelling of a tag. Posts tagged with it get the tag itself.
+func (s *tagService) AddAlias(ctx context.Context, id uint, alias string) (*domain.Tag, error) {
+	tag, err := s.tags.GetTagByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := tagSummary(tag)
+	alias = util.NormalizeTag(alias)
+	err = s.checkName(alias, 0)
+	if err == nil {
+		err = s.tags.AddAlias(id, alias)
+	}
+	if err != nil {
+		s.record(ctx, "tag.alias.add", id, before, nil, err)
+		return nil, err
+	}
+	updated, err := s.tags.GetTagByID(id)
+	s.record(ctx, "tag.alias.add", id, before, tagSummary(updated), err)
+	return updated, err
+}
+
+// DeleteAlias removes an alias from a tag.
+func (s *tagService) DeleteAlias(ctx context.Context, id uint, alias string) (*domain.Tag, error) {
+	tag, err := s.tags.GetTagByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := tagSummary(tag)
+	if err := s.tags.DeleteAlias(id, util.NormalizeTag(alias)); err != nil {
+		s.record(ctx, "tag.alias.delete", id, before, nil, err)
+		return nil, err
+	}
+	updated, err := s.tags.GetTagByID(id)
+	s.record(ctx, "tag.alias.delete", id, before, tagSummary(updated), err)
+	return updated, err
+}
+
+// checkName makes sure name can name a tag or alias and is not taken by a tag other
+// than ownID, by name or alias. An ownID of 0 allows no tag at all.
+func (s *tagService) checkName(name string, ownID uint) error {
+	if !util.ValidTag(name) {
+		return fmt.Errorf("%w: names have 1 to %d characters and no commas", domain.ErrInvalidTag, util.MaxTagLength)
+	}
+	other, err := s.tags.FindTag(name)
+	if errors.Is(err, domain.ErrTagNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if other.ID != ownID {
+		return fmt.Errorf("%w: %q is tag %q", domain.ErrTagConflict, name, other.Name)
+	}
+	return nil
+}
+
+// tagSummary is the audit before/after snapshot of a tag.
+func tagSummary(tag *domain.Tag) interface{} {
+	if tag == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"name":        tag.Name,
+		"description": tag.Description,
+		"aliases":     tag.Aliases,
+	}
+}
+
+// record appends an audit entry for a change to a tag.
+func (s *tagService) record(ctx context.Context, action string, id uint, before, after interface{}, err error) {
+	audit.Record(ctx, s.auditor, &audit.Entry{
+		Action:     action,
+		TargetType: "tag",
+		TargetID:   strconv.FormatUint(uint64(id), 10),
+		Before:     audit.Summary(before),
+		After:      audit.Summary(after),
+	}, err)
+}
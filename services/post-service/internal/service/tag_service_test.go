@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/domain"
+	"seungpyo.lee/PersonalWebSite/services/post-service/internal/model"
+)
+
+// memTagRepo keeps tags, their aliases and published post counts in memory.
+type memTagRepo struct {
+	stubTagRepo
+	tags    map[uint]*domain.Tag
+	aliases map[string]uint
+	merged  [2]uint
+}
+
+func newMemTagRepo(tags ...*domain.Tag) *memTagRepo {
+	r := &memTagRepo{tags: map[uint]*domain.Tag{}, aliases: map[string]uint{}}
+	for _, t := range tags {
+		r.tags[t.ID] = t
+	}
+	return r
+}
+
+func (r *memTagRepo) GetTagByID(id uint) (*domain.Tag, error) {
+	t, ok := r.tags[id]
+	if !ok {
+		return nil, domain.ErrTagNotFound
+	}
+	copied := *t
+	copied.Aliases = nil
+	for alias, tagID := range r.aliases {
+		if tagID == id {
+			copied.Aliases = append(copied.Aliases, alias)
+		}
+	}
+	return &copied, nil
+}
+
+func (r *memTagRepo) FindTag(name string) (*domain.Tag, error) {
+	if id, ok := r.aliases[strings.ToLower(name)]; ok {
+		return r.GetTagByID(id)
+	}
+	for id, t := range r.tags {
+		if strings.EqualFold(t.Name, name) {
+			return r.GetTagByID(id)
+		}
+	}
+	return nil, domain.ErrTagNotFound
+}
+
+func (r *memTagRepo) UpdateTag(tag *domain.Tag) error {
+	r.tags[tag.ID].Name, r.tags[tag.ID].Description = tag.Name, tag.Description
+	return nil
+}
+
+func (r *memTagRepo) MergeTags(fromID, intoID uint) error {
+	r.merged = [2]uint{fromID, intoID}
+	r.aliases[strings.ToLower(r.tags[fromID].Name)] = intoID
+	delete(r.tags, fromID)
+	return nil
+}
+
+func (r *memTagRepo) AddAlias(tagID uint, alias string) error {
+	r.aliases[strings.ToLower(alias)] = tagID
+	return nil
+}
+
+func (r *memTagRepo) DeleteAlias(tagID uint, alias string) error {
+	if r.aliases[strings.ToLower(alias)] != tagID {
+		return domain.ErrTagNotFound
+	}
+	delete(r.aliases, strings.ToLower(alias))
+	return nil
+}
+
+func tagFixture() *memTagRepo {
+	return newMemTagRepo(
+		&domain.Tag{ID: 1, Name: "Go", PostCount: 3},
+		&domain.Tag{ID: 2, Name: "golang", PostCount: 1},
+		&domain.Tag{ID: 3, Name: "drafts only"},
+	)
+}
+
+func TestGetTag(t *testing.T) {
+	repo := tagFixture()
+	repo.aliases["go-lang"] = 1
+	svc := NewTagService(repo, nil, nil)
+
+	for _, name := range []string{"Go", " go ", "GO-LANG"} {
+		if tag, err := svc.GetTag(name); err != nil || tag.ID != 1 {
+			t.Fatalf("GetTag(%q) = %+v, %v", name, tag, err)
+		}
+	}
+	for _, name := range []string{"", "rust", "drafts only"} {
+		if _, err := svc.GetTag(name); !errors.Is(err, domain.ErrTagNotFound) {
+			t.Fatalf("GetTag(%q): expected ErrTagNotFound, got %v", name, err)
+		}
+	}
+}
+
+func TestUpdateTag(t *testing.T) {
+	repo := tagFixture()
+	rec := &stubRecorder{}
+	svc := NewTagService(repo, rec, nil)
+	ctx := context.Background()
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name string
+		req  model.UpdateTagRequest
+		want error
+	}{
+		{"taken by another tag", model.UpdateTagRequest{Name: str("GoLang")}, domain.ErrTagConflict},
+		{"blank", model.UpdateTagRequest{Name: str("  ")}, domain.ErrInvalidTag},
+		{"comma", model.UpdateTagRequest{Name: str("go, web")}, domain.ErrInvalidTag},
+		{"long description", model.UpdateTagRequest{Description: str(strings.Repeat("a", maxTagDescription+1))}, domain.ErrInvalidTag},
+	}
+	for _, tc := range tests {
+		if _, err := svc.UpdateTag(ctx, 1, tc.req); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+	if _, err := svc.UpdateTag(ctx, 9, model.UpdateTagRequest{}); !errors.Is(err, domain.ErrTagNotFound) {
+		t.Fatalf("expected ErrTagNotFound, got %v", err)
+	}
+
+	tag, err := svc.UpdateTag(ctx, 1, model.UpdateTagRequest{Name: str(" GO "), Description: str(" The Go language ")})
+	if err != nil || tag.Name != "GO" || tag.Description != "The Go language" {
+		t.Fatalf("a tag may change its own case: %+v err=%v", tag, err)
+	}
+	if got := rec.actions(); len(got) != 5 || got[4] != "tag.update:success" {
+		t.Fatalf("unexpected audit %v", got)
+	}
+}
+
+func TestMergeTag(t *testing.T) {
+	repo := tagFixture()
+	cache := &stubRelatedCache{}
+	svc := NewTagService(repo, nil, cache)
+	ctx := context.Background()
+
+	if _, err := svc.MergeTag(ctx, 1, 1); !errors.Is(err, domain.ErrInvalidTag) {
+		t.Fatalf("expected ErrInvalidTag, got %v", err)
+	}
+	if _, err := svc.MergeTag(ctx, 2, 9); !errors.Is(err, domain.ErrTagNotFound) {
+		t.Fatalf("expected ErrTagNotFound, got %v", err)
+	}
+	tag, err := svc.MergeTag(ctx, 2, 1)
+	if err != nil || tag.ID != 1 || repo.merged != [2]uint{2, 1} {
+		t.Fatalf("unexpected merge %+v err=%v", tag, err)
+	}
+	if len(tag.Aliases) != 1 || tag.Aliases[0] != "golang" {
+		t.Fatalf("the merged name should become an alias, got %v", tag.Aliases)
+	}
+	if cache.generation != 1 {
+		t.Fatalf("expected only the merge to invalidate related posts, got generation %d", cache.generation)
+	}
+}
+
+func TestTagAliases(t *testing.T) {
+	repo := tagFixture()
+	svc := NewTagService(repo, nil, nil)
+	ctx := context.Background()
+
+	for alias, want := range map[string]error{"Go": domain.ErrTagConflict, "GOLANG": domain.ErrTagConflict, "a,b": domain.ErrInvalidTag} {
+		if _, err := svc.AddAlias(ctx, 1, alias); !errors.Is(err, want) {
+			t.Fatalf("AddAlias(%q): expected %v, got %v", alias, want, err)
+		}
+	}
+	tag, err := svc.AddAlias(ctx, 1, " Go  Lang ")
+	if err != nil || len(tag.Aliases) != 1 || tag.Aliases[0] != "go lang" {
+		t.Fatalf("unexpected alias %+v err=%v", tag, err)
+	}
+	if _, err := svc.AddAlias(ctx, 3, "go lang"); !errors.Is(err, domain.ErrTagConflict) {
+		t.Fatalf("an alias belongs to one tag, got %v", err)
+	}
+	if _, err := svc.DeleteAlias(ctx, 3, "go lang"); !errors.Is(err, domain.ErrTagNotFound) {
+		t.Fatalf("expected ErrTagNotFound, got %v", err)
+	}
+	if tag, err := svc.DeleteAlias(ctx, 1, "Go Lang"); err != nil || len(tag.Aliases) != 0 {
+		t.Fatalf("unexpected delete %+v err=%v", tag, err)
+	}
+}
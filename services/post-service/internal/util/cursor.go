@@ -0,0 +1,52 @@
+package util
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PostCursor is a position in a post list ordered newest first: the creation time and
+// ID of the post at the edge of a page. Before selects the posts in front of it
+// rather than those after it.
+type PostCursor struct {
+	CreatedAt time.Time
+	ID        uint
+	Before    bool
+}
+
+var errMalformedCursor = errors.New("malformed cursor")
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c PostCursor) Encode() string {
+	dir := "a"
+	if c.Before {
+		dir = "b"
+	}
+	raw := fmt.Sprintf("%s.%d.%d", dir, c.CreatedAt.UnixMicro(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a token made by PostCursor.Encode.
+func DecodeCursor(token string) (PostCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return PostCursor{}, errMalformedCursor
+	}
+	parts := strings.Split(string(raw), ".")
+	if len(parts) != 3 || (parts[0] != "a" && parts[0] != "b") {
+		return PostCursor{}, errMalformedCursor
+	}
+	micros, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return PostCursor{}, errMalformedCursor
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil || id == 0 {
+		return PostCursor{}, errMalformedCursor
+	}
+	return PostCursor{CreatedAt: time.UnixMicro(micros).UTC(), ID: uint(id), Before: parts[0] == "b"}, nil
+}
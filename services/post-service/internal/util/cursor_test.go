@@ -0,0 +1,26 @@
+package util
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestPostCursor_RoundTrip(t *testing.T) {
+	created := time.Date(2026, 10, 19, 8, 30, 0, 123456000, time.UTC)
+	for _, c := range []PostCursor{{CreatedAt: created, ID: 42}, {CreatedAt: created, ID: 7, Before: true}} {
+		got, err := DecodeCursor(c.Encode())
+		if err != nil || !got.CreatedAt.Equal(c.CreatedAt) || got.ID != c.ID || got.Before != c.Before {
+			t.Fatalf("round trip of %+v gave %+v err=%v", c, got, err)
+		}
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	enc := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	for _, token := range []string{"", "!!!", enc("a.1"), enc("x.1.2"), enc("a.t.2"), enc("b.1.0"), enc("a.1.-2")} {
+		if _, err := DecodeCursor(token); err == nil {
+			t.Errorf("expected %q to be rejected", token)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// maxDiffCells bounds the table used to compare the differing middle of two texts.
+// Larger inputs are shown as the whole middle removed and added again.
+const maxDiffCells = 1 << 22
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// UnifiedDiff returns the line diff from one text to another in unified format, with
+// fromName and toName as the file headers, or "" when the texts are equal.
+func UnifiedDiff(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+	ops := diffLines(splitLines(from), splitLines(to))
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+
+	// aLine and bLine are the 1-based line numbers each op starts at
+	aLine := make([]int, len(ops)+1)
+	bLine := make([]int, len(ops)+1)
+	aLine[0], bLine[0] = 1, 1
+	for i, op := range ops {
+		aLine[i+1], bLine[i+1] = aLine[i], bLine[i]
+		if op.kind != '+' {
+			aLine[i+1]++
+		}
+		if op.kind != '-' {
+			bLine[i+1]++
+		}
+	}
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// a hunk runs until diffContext lines after the last change that is no more
+		// than 2*diffContext unchanged lines from the next one
+		start := max(0, i-diffContext)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		end = min(len(ops), end+diffContext)
+		aCount, bCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(aLine[start], aCount), hunkRange(bLine[start], bCount))
+		for _, op := range ops[start:end] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.line)
+			b.WriteByte('\n')
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange formats a hunk's line range the way diff -u does.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines compares a and b line by line: common lines at both ends are kept, and the
+// differing middle is aligned on its longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(ma)+1)*(len(mb)+1) > maxDiffCells {
+		for _, line := range ma {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range mb {
+			ops = append(ops, diffOp{'+', line})
+		}
+	} else {
+		ops = append(ops, lcsDiff(ma, mb)...)
+	}
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// lcsDiff aligns a and b on their longest common subsequence, removals before additions.
+func lcsDiff(a, b []string) []diffOp {
+	width := len(b) + 1
+	// lcs[i*width+j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([]int32, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else {
+				lcs[i*width+j] = max(lcs[(i+1)*width+j], lcs[i*width+j+1])
+			}
+		}
+	}
+	ops := make([]diffOp, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	to := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	want := `--- from
++++ to
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n
+`
+	if got := UnifiedDiff("from", "to", from, to); got != want {
+		t.Fatalf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiff_MergesCloseChanges(t *testing.T) {
+	got := UnifiedDiff("from", "to", "1\n2\n3\n4\n5\n6\n7\n8", "1\nx\n3\n4\n5\n6\n7\ny")
+	if strings.Count(got, "@@ ") != 1 || !strings.Contains(got, "@@ -1,8 +1,8 @@") {
+		t.Fatalf("expected one hunk, got:\n%s", got)
+	}
+}
+
+func TestUnifiedDiff_EmptySides(t *testing.T) {
+	if got := UnifiedDiff("from", "to", "same", "same"); got != "" {
+		t.Fatalf("expected no diff for equal texts, got %q", got)
+	}
+	if got := UnifiedDiff("from", "to", "", "a\nb\n"); !strings.Contains(got, "@@ -0,0 +1,2 @@\n+a\n+b\n") {
+		t.Fatalf("unexpected diff from empty text:\n%s", got)
+	}
+	if got := UnifiedDiff("from", "to", "a\n", ""); !strings.Contains(got, "@@ -1 +0,0 @@\n-a\n") {
+		t.Fatalf("unexpected diff to empty text:\n%s", got)
+	}
+}
+
+func TestUnifiedDiff_LargeInputFallsBack(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < 3000; i++ {
+		a.WriteString("a line\n")
+		b.WriteString("b line\n")
+	}
+	got := UnifiedDiff("from", "to", a.String(), b.String())
+	if !strings.HasPrefix(got, "--- from\n+++ to\n@@ -1,3000 +1,3000 @@\n") {
+		t.Fatalf("unexpected fallback diff header: %q", got[:60])
+	}
+}
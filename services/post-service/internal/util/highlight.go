@@ -0,0 +1,48 @@
+package util
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Markers put around matches in search headlines. They are private-use characters, so
+// they survive until HighlightHTML turns them into <mark> after escaping.
+const (
+	HighlightStart = "\ue000"
+	HighlightStop  = "\ue001"
+)
+
+// HighlightHTML escapes a search headline for HTML and marks its matches with <mark>.
+// A headline without marked matches, as when only a substring of a word matched, gets
+// the query's words marked wherever they appear.
+func HighlightHTML(headline, query string) string {
+	if !strings.Contains(headline, HighlightStart) {
+		headline = markTerms(headline, query)
+	}
+	s := html.EscapeString(headline)
+	s = strings.ReplaceAll(s, HighlightStart, "<mark>")
+	return strings.ReplaceAll(s, HighlightStop, "</mark>")
+}
+
+// markTerms marks the words of a websearch-style query in s, ignoring case. Excluded
+// words (-word) and the OR operator are not marked.
+func markTerms(s, query string) string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		if strings.HasPrefix(word, "-") || word == "OR" || word == "or" {
+			continue
+		}
+		if word = strings.Trim(word, `"'`); word != "" {
+			terms = append(terms, regexp.QuoteMeta(word))
+		}
+	}
+	if len(terms) == 0 {
+		return s
+	}
+	re, err := regexp.Compile("(?i)(" + strings.Join(terms, "|") + ")")
+	if err != nil {
+		return s
+	}
+	return re.ReplaceAllString(s, HighlightStart+"${1}"+HighlightStop)
+}
@@ -0,0 +1,20 @@
+package util
+
+import "testing"
+
+func TestHighlightHTML(t *testing.T) {
+	cases := []struct {
+		headline, query, want string
+	}{
+		{"a " + HighlightStart + "go" + HighlightStop + " <b>post</b>", "go", "a <mark>go</mark> &lt;b&gt;post&lt;/b&gt;"},
+		{"한국어 블로그를 시작하기", "블로그", "한국어 <mark>블로그</mark>를 시작하기"},
+		{"Go and GO and gopher", `"go" -gopher`, "<mark>Go</mark> and <mark>GO</mark> and <mark>go</mark>pher"},
+		{"1+1 = 2", "1+1 or", "<mark>1+1</mark> = 2"},
+		{"nothing", "-x", "nothing"},
+	}
+	for _, tc := range cases {
+		if got := HighlightHTML(tc.headline, tc.query); got != tc.want {
+			t.Errorf("HighlightHTML(%q, %q) = %q, want %q", tc.headline, tc.query, got, tc.want)
+		}
+	}
+}
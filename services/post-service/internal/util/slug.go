@@ -0,0 +1,119 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxSlugLength bounds generated and author-chosen slugs.
+const MaxSlugLength = 80
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Hangul syllables decompose into initial, medial and final jamo; these are their
+// Revised Romanization of Korean spellings. A final consonant followed by a silent
+// initial ㅇ is carried over to the next syllable and spelled as an initial.
+var (
+	hangulInitials     = []string{"g", "kk", "n", "d", "tt", "r", "m", "b", "pp", "s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h"}
+	hangulMedials      = []string{"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o", "wa", "wae", "oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i"}
+	hangulFinals       = []string{"", "k", "k", "k", "n", "n", "n", "t", "l", "k", "m", "l", "l", "l", "p", "l", "m", "p", "p", "t", "t", "ng", "t", "t", "k", "t", "p", "t"}
+	hangulLinkedFinals = []string{"", "g", "kk", "ks", "n", "nj", "n", "d", "r", "lg", "lm", "lb", "ls", "lt", "lp", "r", "m", "b", "bs", "s", "ss", "ng", "j", "ch", "k", "t", "p", ""}
+)
+
+const (
+	hangulFirst       = 0xAC00
+	hangulLast        = 0xD7A3
+	hangulSilentIndex = 11 // ㅇ as an initial
+	hangulRieulFinal  = 8  // ㄹ as a final
+)
+
+// latinFallbacks spells letters that do not decompose into a base letter and marks.
+var latinFallbacks = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l", 'þ': "th", 'ı': "i",
+}
+
+// ValidSlug reports whether s can be used as a post slug: lowercase ASCII letters and
+// digits in hyphen-separated words, not only digits, so it never reads as a post ID.
+func ValidSlug(s string) bool {
+	return len(s) <= MaxSlugLength && slugPattern.MatchString(s) && strings.Trim(s, "0123456789-") != ""
+}
+
+// Slugify turns a post title into a slug. Hangul is romanized, accented Latin letters
+// lose their accents, and anything else that is not a letter or digit separates words.
+// It returns "" when the title has nothing to spell.
+func Slugify(title string) string {
+	var b strings.Builder
+	sep := false
+	final := 0 // final jamo of the previous syllable, spelled once the next rune is known
+	flush := func() {
+		b.WriteString(hangulFinals[final])
+		final = 0
+	}
+	word := func(s string) {
+		flush()
+		if sep && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		sep = false
+		b.WriteString(s)
+	}
+	for _, r := range norm.NFC.String(strings.ToLower(title)) {
+		switch {
+		case r >= hangulFirst && r <= hangulLast:
+			idx := int(r - hangulFirst)
+			initial := hangulInitials[idx/(21*28)]
+			if !sep && idx/(21*28) == hangulSilentIndex {
+				b.WriteString(hangulLinkedFinals[final])
+				final = 0
+			} else if !sep && initial == "r" && final == hangulRieulFinal {
+				initial = "l" // ㄹㄹ is spelled ll
+			}
+			word(initial + hangulMedials[idx%(21*28)/28])
+			final = idx % 28
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			word(string(r))
+		case latinFallbacks[r] != "":
+			word(latinFallbacks[r])
+		case unicode.Is(unicode.Latin, r):
+			if base := stripMarks(r); base != "" {
+				word(base)
+			} else {
+				flush()
+				sep = true
+			}
+		case unicode.Is(unicode.Mn, r):
+			// combining marks left over from decomposed input
+		default:
+			flush()
+			sep = true
+		}
+	}
+	flush()
+	return truncateSlug(b.String())
+}
+
+// stripMarks returns the ASCII letters r decomposes into, without its accents.
+func stripMarks(r rune) string {
+	var b strings.Builder
+	for _, d := range norm.NFD.String(string(r)) {
+		if d < unicode.MaxASCII && unicode.IsLetter(d) {
+			b.WriteRune(d)
+		}
+	}
+	return b.String()
+}
+
+// truncateSlug cuts s to MaxSlugLength, at a word boundary when there is one.
+func truncateSlug(s string) string {
+	if len(s) <= MaxSlugLength {
+		return s
+	}
+	s = s[:MaxSlugLength]
+	if i := strings.LastIndexByte(s, '-'); i > 0 {
+		s = s[:i]
+	}
+	return strings.Trim(s, "-")
+}
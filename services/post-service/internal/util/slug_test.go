@@ -0,0 +1,47 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Hello, World!":            "hello-world",
+		"  Go 1.22 --- release  ":  "go-1-22-release",
+		"안녕하세요":                    "annyeonghaseyo",
+		"한국어 블로그 시작하기":             "hangugeo-beullogeu-sijakhagi",
+		"Go 언어로 만든 블로그":            "go-eoneoro-mandeun-beullogeu",
+		"서울은":                      "seoureun",
+		"빨리":                       "ppalli",
+		"Crème brûlée à Straße":    "creme-brulee-a-strasse",
+		"Ærø og Łódź":              "aero-og-lodz",
+		"東京":                       "",
+		"C++ & Rust: 메모리 safety 😀": "c-rust-memori-safety",
+	}
+	for title, want := range cases {
+		if got := Slugify(title); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestSlugify_Truncates(t *testing.T) {
+	got := Slugify(strings.Repeat("word ", 40))
+	if len(got) > MaxSlugLength || strings.HasSuffix(got, "-") || !ValidSlug(got) {
+		t.Fatalf("unexpected truncated slug %q", got)
+	}
+}
+
+func TestValidSlug(t *testing.T) {
+	for _, s := range []string{"hello", "hello-world", "go-1-22", "a1"} {
+		if !ValidSlug(s) {
+			t.Errorf("expected %q to be valid", s)
+		}
+	}
+	for _, s := range []string{"", "123", "1-2", "Hello", "hello--world", "-hello", "hello-", "héllo", "by slug", strings.Repeat("a", MaxSlugLength+1)} {
+		if ValidSlug(s) {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package util
+
+import "strings"
+
+// MaxTagLength bounds tag names and aliases.
+const MaxTagLength = 50
+
+// NormalizeTag trims a tag name and collapses its inner whitespace to single spaces.
+// Tags are compared regardless of case, so "Go" and "go" are the same tag.
+func NormalizeTag(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// ValidTag reports whether a normalized name can name a tag: not empty, not too long,
+// and without the commas that separate tags in the editor.
+func ValidTag(name string) bool {
+	return name != "" && len([]rune(name)) <= MaxTagLength && !strings.Contains(name, ",")
+}
@@ -0,0 +1,33 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	cases := map[string]string{
+		"  Go ":             "Go",
+		"machine\tlearning": "machine learning",
+		"web  dev\n":        "web dev",
+		"   ":               "",
+	}
+	for name, want := range cases {
+		if got := NormalizeTag(name); got != want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestValidTag(t *testing.T) {
+	for _, name := range []string{"Go", "machine learning", "한국어", strings.Repeat("가", MaxTagLength)} {
+		if !ValidTag(name) {
+			t.Errorf("ValidTag(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"", "go, web", strings.Repeat("a", MaxTagLength+1)} {
+		if ValidTag(name) {
+			t.Errorf("ValidTag(%q) = true", name)
+		}
+	}
+}
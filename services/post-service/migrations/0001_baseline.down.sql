@@ -0,0 +1,4 @@
+-- audit_logs is shared with the other services and keeps its history.
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS posts;
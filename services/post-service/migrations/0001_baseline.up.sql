@@ -0,0 +1,61 @@
+-- Baseline: the schema GORM AutoMigrate created before versioned migrations.
+-- Every statement is IF NOT EXISTS, so databases created by AutoMigrate adopt it unchanged.
+-- users belongs to auth-service; posts only read it for the author.
+
+CREATE TABLE IF NOT EXISTS posts (
+    id bigserial PRIMARY KEY,
+    title text NOT NULL,
+    content text NOT NULL,
+    en_title text,
+    en_content text,
+    thumbnail text,
+    author_id bigint,
+    published boolean DEFAULT false,
+    published_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_posts_author_id ON posts (author_id);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_name ON tags (name);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+    post_id bigint NOT NULL,
+    tag_id bigint NOT NULL,
+    PRIMARY KEY (post_id, tag_id),
+    CONSTRAINT fk_post_tags_post FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    CONSTRAINT fk_post_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
+
+-- audit_logs is shared with the other services; each baseline creates it if missing.
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    service text NOT NULL,
+    actor_id bigint,
+    actor_name text,
+    action text NOT NULL,
+    target_type text,
+    target_id text,
+    request_id text,
+    ip text,
+    before text,
+    after text,
+    outcome text NOT NULL,
+    error text
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target_type ON audit_logs (target_type);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target_id ON audit_logs (target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_outcome ON audit_logs (outcome);
+-- the audit log is append-only
+CREATE OR REPLACE RULE audit_logs_no_update AS ON UPDATE TO audit_logs DO INSTEAD NOTHING;
+CREATE OR REPLACE RULE audit_logs_no_delete AS ON DELETE TO audit_logs DO INSTEAD NOTHING;
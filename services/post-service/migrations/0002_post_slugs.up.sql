@@ -0,0 +1,13 @@
+-- Human-readable permalinks. Existing posts get their slug from the title when
+-- post-service starts, as romanizing titles is done in Go.
+ALTER TABLE posts ADD COLUMN slug text NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX idx_posts_slug ON posts (slug) WHERE slug <> '';
+
+-- Slugs a post had before, kept so old links redirect to the current one.
+CREATE TABLE post_slugs (
+    slug text PRIMARY KEY,
+    post_id bigint NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT fk_post_slugs_post FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_post_slugs_post_id ON post_slugs (post_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_posts_published_created_at;
+DROP INDEX IF EXISTS idx_posts_unpublish_at;
+DROP INDEX IF EXISTS idx_posts_publish_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS unpublish_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS publish_at;
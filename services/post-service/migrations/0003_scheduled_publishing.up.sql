@@ -0,0 +1,7 @@
+-- Scheduled publishing: the scheduler publishes posts once publish_at has passed and
+-- unpublishes them once unpublish_at has passed, then clears the column.
+ALTER TABLE posts ADD COLUMN publish_at timestamptz;
+ALTER TABLE posts ADD COLUMN unpublish_at timestamptz;
+CREATE INDEX idx_posts_publish_at ON posts (publish_at) WHERE publish_at IS NOT NULL;
+CREATE INDEX idx_posts_unpublish_at ON posts (unpublish_at) WHERE unpublish_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_posts_published_created_at ON posts (published, created_at DESC);
@@ -0,0 +1,21 @@
+-- Revision history: a snapshot of a post's title, content and translation after every
+-- change to them, so earlier text can be compared and restored.
+CREATE TABLE post_revisions (
+    id bigserial PRIMARY KEY,
+    post_id bigint NOT NULL,
+    title text NOT NULL,
+    content text NOT NULL,
+    en_title text NOT NULL DEFAULT '',
+    en_content text NOT NULL DEFAULT '',
+    author_id bigint,
+    kind text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT fk_post_revisions_post FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_post_revisions_post_id ON post_revisions (post_id, id);
+
+-- The text existing posts have now is the first revision of their history.
+INSERT INTO post_revisions (post_id, title, content, en_title, en_content, author_id, kind, created_at)
+SELECT id, title, content, COALESCE(en_title, ''), COALESCE(en_content, ''), author_id, 'import', COALESCE(updated_at, now())
+FROM posts
+ORDER BY id;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_posts_search_trgm;
+DROP INDEX IF EXISTS idx_posts_search_vector;
+ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS post_search_document(text, text, text, text);
+DROP FUNCTION IF EXISTS post_search_text(text);
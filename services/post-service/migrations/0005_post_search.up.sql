@@ -0,0 +1,34 @@
+-- Full-text search. pg_trgm matches substrings, which Korean needs: particles are
+-- written onto words, so 블로그를 has no lexeme 블로그 in the 'simple' configuration.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- post_search_text reduces Markdown or HTML to the words a reader would search for:
+-- images and links keep their text, and URLs, tags and Markdown punctuation go.
+CREATE FUNCTION post_search_text(doc text) RETURNS text
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT regexp_replace(
+        regexp_replace(
+            regexp_replace(
+                regexp_replace(coalesce(doc, ''), '<[^>]*>', ' ', 'g'),
+                '!?\[([^\]]*)\]\([^)]*\)', '\1', 'g'),
+            'https?://[^\s)]+', ' ', 'g'),
+        '[#*_`>~|=\\-]+', ' ', 'g')
+$$;
+
+-- post_search_document is the text the trigram index covers.
+CREATE FUNCTION post_search_document(title text, content text, en_title text, en_content text) RETURNS text
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT coalesce(title, '') || ' ' || post_search_text(content) || ' ' ||
+        coalesce(en_title, '') || ' ' || post_search_text(en_content)
+$$;
+
+-- Titles weigh most, then the Korean content, then the English translation. Korean
+-- uses 'simple', which does not stem; English is stemmed.
+ALTER TABLE posts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple'::regconfig, coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english'::regconfig, coalesce(en_title, '')), 'A') ||
+    setweight(to_tsvector('simple'::regconfig, post_search_text(content)), 'B') ||
+    setweight(to_tsvector('english'::regconfig, post_search_text(en_content)), 'C')
+) STORED;
+CREATE INDEX idx_posts_search_vector ON posts USING gin (search_vector);
+CREATE INDEX idx_posts_search_trgm ON posts USING gin (post_search_document(title, content, en_title, en_content) gin_trgm_ops);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_posts_published_created_at_id;
+CREATE INDEX IF NOT EXISTS idx_posts_published_created_at ON posts (published, created_at DESC);
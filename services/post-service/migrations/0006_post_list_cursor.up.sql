@@ -0,0 +1,4 @@
+-- Post lists page by cursor on (created_at, id), newest first; the id breaks ties
+-- between posts created in the same microsecond.
+DROP INDEX IF EXISTS idx_posts_published_created_at;
+CREATE INDEX idx_posts_published_created_at_id ON posts (published, created_at DESC, id DESC);
@@ -0,0 +1,22 @@
+-- Comments: readers' replies to a post, threaded through parent_id. A comment waits in
+-- the moderation queue as pending until the post's author approves it or marks it spam.
+CREATE TABLE comments (
+    id bigserial PRIMARY KEY,
+    post_id bigint NOT NULL,
+    parent_id bigint,
+    user_id bigint,
+    author_name text NOT NULL,
+    author_email text NOT NULL DEFAULT '',
+    body text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    ip text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT fk_comments_post FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    CONSTRAINT fk_comments_parent FOREIGN KEY (parent_id) REFERENCES comments (id) ON DELETE CASCADE,
+    CONSTRAINT chk_comments_status CHECK (status IN ('pending', 'approved', 'spam'))
+);
+CREATE INDEX idx_comments_post_id ON comments (post_id, status, id);
+CREATE INDEX idx_comments_parent_id ON comments (parent_id);
+-- Submissions are rate limited per client address.
+CREATE INDEX idx_comments_ip_created_at ON comments (ip, created_at);
@@ -0,0 +1,22 @@
+-- Series: an author's ordered set of posts, such as the parts of a tutorial. A post
+-- belongs to at most one series.
+CREATE TABLE series (
+    id bigserial PRIMARY KEY,
+    title text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    author_id bigint NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_series_author_id ON series (author_id);
+
+CREATE TABLE series_posts (
+    series_id bigint NOT NULL,
+    post_id bigint NOT NULL,
+    position integer NOT NULL,
+    PRIMARY KEY (series_id, post_id),
+    CONSTRAINT uq_series_posts_post UNIQUE (post_id),
+    CONSTRAINT fk_series_posts_series FOREIGN KEY (series_id) REFERENCES series (id) ON DELETE CASCADE,
+    CONSTRAINT fk_series_posts_post FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_series_posts_position ON series_posts (series_id, position);
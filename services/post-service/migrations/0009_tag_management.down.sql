@@ -0,0 +1,5 @@
+-- Tags merged by the up migration or by an admin are not split again.
+DROP TABLE IF EXISTS tag_aliases;
+ALTER TABLE tags DROP COLUMN IF EXISTS description;
+DROP INDEX IF EXISTS idx_tags_name_lower;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_name ON tags (name);
@@ -0,0 +1,22 @@
+-- Tag names are unique regardless of case. Tags that differ only in case become the
+-- oldest of them, which takes over their posts.
+CREATE TEMPORARY TABLE tag_merges ON COMMIT DROP AS
+SELECT id, keep_id FROM (SELECT id, MIN(id) OVER (PARTITION BY lower(name)) AS keep_id FROM tags) t
+WHERE id <> keep_id;
+INSERT INTO post_tags (post_id, tag_id)
+SELECT post_tags.post_id, tag_merges.keep_id FROM post_tags JOIN tag_merges ON tag_merges.id = post_tags.tag_id
+ON CONFLICT DO NOTHING;
+DELETE FROM tags WHERE id IN (SELECT id FROM tag_merges);
+
+DROP INDEX IF EXISTS idx_tags_name;
+CREATE UNIQUE INDEX idx_tags_name_lower ON tags (lower(name));
+ALTER TABLE tags ADD COLUMN description text NOT NULL DEFAULT '';
+
+-- Aliases are other spellings of a tag, stored in lowercase, such as "golang" for "Go".
+CREATE TABLE tag_aliases (
+    alias text PRIMARY KEY,
+    tag_id bigint NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT fk_tag_aliases_tag FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_tag_aliases_tag_id ON tag_aliases (tag_id);
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+
+	"seungpyo.lee/PersonalWebSite/pkg/migrate"
+)
+
+func TestMigrationsLoad(t *testing.T) {
+	ms, err := migrate.Load(FS)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for i, m := range ms {
+		if m.Version != int64(i+1) {
+			t.Fatalf("expected contiguous versions, got %d at position %d", m.Version, i)
+		}
+		// users belongs to auth-service
+		if strings.Contains(m.Up, "TABLE IF NOT EXISTS users") || strings.Contains(m.Up, "TABLE users") {
+			t.Fatalf("migration %d_%s must not create the users table", m.Version, m.Name)
+		}
+	}
+	if len(ms) == 0 || !strings.Contains(ms[0].Up, "CREATE TABLE IF NOT EXISTS posts") {
+		t.Fatalf("expected the baseline to create posts")
+	}
+}
@@ -5,10 +5,12 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"seungpyo.lee/PersonalWebSite/pkg/logger"
+	"seungpyo.lee/PersonalWebSite/pkg/middleware"
 	"seungpyo.lee/PersonalWebSite/services/web-front/internal/config"
 	auth "seungpyo.lee/PersonalWebSite/services/web-front/internal/handler/auth"
 	blog "seungpyo.lee/PersonalWebSite/services/web-front/internal/handler/blog"
 	page "seungpyo.lee/PersonalWebSite/services/web-front/internal/handler/page"
+	profile "seungpyo.lee/PersonalWebSite/services/web-front/internal/handler/profile"
 )
 
 func mod(a, b int) int {
@@ -19,6 +21,8 @@ func main() {
 	logger := logger.New("main")
 
 	r := gin.Default()
+	// ETag wraps compression so tags and 304s match the encoded representation
+	r.Use(middleware.ETag(), middleware.Compress())
 	r.SetFuncMap(template.FuncMap{
 		"mod": mod,
 		// renderSanitizedHTML: explicit helper used only for server-sanitized HTML
@@ -26,14 +30,20 @@ func main() {
 	})
 
 	cfg := config.LoadWebConfig()
+	// the browser's IP is forwarded to the gateway, so only nginx may set X-Forwarded-For
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Fatal("invalid TRUSTED_PROXIES: " + err.Error())
+	}
 	authH := auth.NewAuthHandler(cfg)
 	blogH := blog.NewBlogHandler(cfg)
 	postH := blog.NewPostHandler(cfg)
 	pageH := page.NewPageHandler(cfg)
+	profileH := profile.NewProfileHandler(cfg)
 	// Adjust paths for development (ddebug) vs production
 	r.LoadHTMLGlob("/app/services/web-front/templates/html/*.html")
 	r.Static("/static", "/app/services/web-front/static")
 	r.Static("/assets", "/app/services/web-front/templates/assets")
+	r.Static("/js", "/app/services/web-front/templates/js")
 
 	// Health check endpoint
 	r.GET("/health", func(c *gin.Context) {
@@ -49,16 +59,39 @@ func main() {
 	r.GET("/system", pageH.System)
 
 	r.GET("/login", authH.Login)
+	r.POST("/login", authH.PasswordLogin)
+	r.GET("/login/2fa", authH.TwoFactorPage)
+	r.POST("/login/2fa", authH.VerifyTwoFactor)
+	r.GET("/forgot-password", authH.ForgotPasswordPage)
+	r.POST("/forgot-password", authH.ForgotPassword)
+	r.GET("/reset-password", authH.ResetPasswordPage)
+	r.POST("/reset-password", authH.ResetPassword)
 	r.GET("/logout", authH.Logout)
-	r.GET("/oauth/google", authH.OAuthGoogleLogin)
-	r.GET("/oauth/google/callback", authH.OAuthGoogleRedirect)
+	r.GET("/oauth/:provider", authH.OAuthLogin)
+	r.GET("/oauth/:provider/callback", authH.OAuthRedirect)
+	r.GET("/profile", profileH.Page)
+	r.POST("/profile", profileH.Update)
+	r.GET("/set-username", profileH.SetUsernamePage)
+	r.POST("/set-username", profileH.SetUsername)
+	r.GET("/profile/2fa", profileH.TwoFactorPage)
+	r.POST("/profile/2fa/enroll", profileH.EnrollTwoFactor)
+	r.POST("/profile/2fa/confirm", profileH.ConfirmTwoFactor)
+	r.POST("/profile/2fa/disable", profileH.DisableTwoFactor)
+	r.POST("/profile/2fa/recovery-codes", profileH.RegenerateRecoveryCodes)
 	r.GET("/blog", blogH.List)
 	r.GET("/blog-post", blogH.EditOrNew)
+	r.GET("/blog-drafts", blogH.Drafts)
 	r.GET("/blog-edit/:articleNumber", blogH.EditOrNew)
+	r.GET("/blog-history/:articleNumber", blogH.History)
+	r.POST("/blog-history/:articleNumber/restore/:rev", blogH.Restore)
 	r.POST("/blog-post", postH.Save)
-	r.GET("/blog/:articleNumber", blogH.Article)
+	r.GET("/blog/:slug", blogH.Article)
 	r.GET("/blog-remove/:articleNumber", blogH.RemovePage)
 	r.POST("/blog-remove/:articleNumber", blogH.Remove)
+	r.POST("/blog-comment/:articleNumber", blogH.Comment)
+	r.GET("/blog-comments", blogH.Comments)
+	r.POST("/blog-comments/:id", blogH.Moderate)
+	r.GET("/series/:id", blogH.Series)
 
 	r.GET("/contact", pageH.Contact)
 	r.GET("/opensource", pageH.OpenSource)
@@ -1,9 +1,14 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
+	"regexp"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/returnto"
 	"seungpyo.lee/PersonalWebSite/services/web-front/internal/config"
 )
 
@@ -16,10 +21,26 @@ type RegisterRequest struct {
 type AuthHandler interface {
 	Login(c *gin.Context)
 	Logout(c *gin.Context)
-	OAuthGoogleLogin(c *gin.Context)
-	OAuthGoogleRedirect(c *gin.Context)
+	OAuthLogin(c *gin.Context)
+	OAuthRedirect(c *gin.Context)
+	PasswordLogin(c *gin.Context)
+	ForgotPasswordPage(c *gin.Context)
+	ForgotPassword(c *gin.Context)
+	ResetPasswordPage(c *gin.Context)
+	ResetPassword(c *gin.Context)
+	TwoFactorPage(c *gin.Context)
+	VerifyTwoFactor(c *gin.Context)
 }
 
+// loginProvider is a login button on the login page.
+type loginProvider struct {
+	Name  string
+	Label string
+	Icon  string
+}
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
 type authHandler struct {
 	cfg *config.PostConfig
 }
@@ -29,23 +50,256 @@ func NewAuthHandler(cfg *config.PostConfig) AuthHandler {
 }
 
 func (h *authHandler) Login(c *gin.Context) {
-	c.HTML(http.StatusOK, "login.html", gin.H{})
+	h.renderLogin(c, http.StatusOK, "")
+}
+
+func (h *authHandler) renderLogin(c *gin.Context, status int, errMsg string) {
+	providers, password := h.loginProviders()
+	c.HTML(status, "login.html", gin.H{
+		"providers":     providers,
+		"passwordLogin": password,
+		"reset":         c.Query("reset") != "",
+		"error":         errMsg,
+		"returnTo":      requestedReturnTo(c),
+	})
+}
+
+// requestedReturnTo is the validated page to continue at after logging in, taken from
+// the return_to query parameter or form field; "" when absent or not allowed.
+func requestedReturnTo(c *gin.Context) string {
+	return returnto.Sanitize(c.Request.FormValue(returnto.Param))
 }
 
+// afterLogin is where a finished login continues: the requested page or the home page.
+func afterLogin(c *gin.Context) string {
+	if target := requestedReturnTo(c); target != "" {
+		return target
+	}
+	return "/"
+}
+
+// loginProviders asks auth-service which providers are configured and whether password
+// login is on, falling back to Google only when the list cannot be fetched.
+func (h *authHandler) loginProviders() ([]loginProvider, bool) {
+	names := []string{"google"}
+	password := false
+	if resp, err := http.Get(h.cfg.ApiGatewayURL + "/v1/auth/oauth/providers"); err == nil {
+		defer resp.Body.Close()
+		var body struct {
+			Providers []string `json:"providers"`
+			Password  bool     `json:"password"`
+		}
+		if resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&body) == nil {
+			if len(body.Providers) > 0 {
+				names = body.Providers
+			}
+			password = body.Password
+		}
+	}
+	providers := make([]loginProvider, 0, len(names))
+	for _, name := range names {
+		if !providerNamePattern.MatchString(name) {
+			continue
+		}
+		p := loginProvider{Name: name, Label: strings.ToUpper(name[:1]) + name[1:], Icon: "bi-box-arrow-in-right"}
+		switch name {
+		case "google":
+			p.Icon = "bi-google"
+		case "github":
+			p.Label, p.Icon = "GitHub", "bi-github"
+		}
+		providers = append(providers, p)
+	}
+	return providers, password
+}
+
+// Logout revokes the refresh token through the gateway and clears every auth cookie.
+// Cookies are cleared even when the gateway cannot be reached.
 func (h *authHandler) Logout(c *gin.Context) {
-	c.SetCookie("access_token", "", -1, "/", "", false, true)
-	c.SetCookie("user", "", -1, "/", "", false, false)
-	c.SetCookie("refresh_token", "", -1, "/", "", false, true)
+	if refreshToken, err := c.Cookie("refresh_token"); err == nil && refreshToken != "" {
+		req, err := http.NewRequest(http.MethodPost, h.cfg.ApiGatewayURL+"/v1/auth/logout", nil)
+		if err == nil {
+			req.AddCookie(&http.Cookie{Name: "refresh_token", Value: refreshToken})
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+	for _, name := range []string{"access_token", "refresh_token", "userId"} {
+		c.SetCookie(name, "", -1, "/", "", true, true)
+	}
 	c.Redirect(http.StatusFound, "/")
 }
 
-func (h *authHandler) OAuthGoogleLogin(c *gin.Context) {
-	// For browser redirect, use external URL through nginx
-	oauthURL := h.cfg.MYDOMAIN + "/api/v1/auth/oauth/google/login"
-	c.Redirect(http.StatusFound, oauthURL)
+func (h *authHandler) OAuthLogin(c *gin.Context) {
+	provider := c.Param("provider")
+	if !providerNamePattern.MatchString(provider) {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+	// For browser redirect, use external URL through nginx; auth-service keeps the
+	// return path with the login state
+	oauthURL := h.cfg.MYDOMAIN + "/api/v1/auth/oauth/" + provider + "/login"
+	c.Redirect(http.StatusFound, returnto.AppendTo(oauthURL, requestedReturnTo(c)))
 }
-func (h *authHandler) OAuthGoogleRedirect(c *gin.Context) {
+func (h *authHandler) OAuthRedirect(c *gin.Context) {
+	provider := c.Param("provider")
+	if !providerNamePattern.MatchString(provider) {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
 	// For browser redirect, use external URL (localhost in dev)
-	oauthURL := h.cfg.MYDOMAIN + "/api/v1/auth/oauth/google/callback"
+	oauthURL := h.cfg.MYDOMAIN + "/api/v1/auth/oauth/" + provider + "/callback"
+	if c.Request.URL.RawQuery != "" {
+		oauthURL += "?" + c.Request.URL.RawQuery
+	}
 	c.Redirect(http.StatusFound, oauthURL)
 }
+
+// postPassword sends a password-login API call through the gateway on behalf of the
+// browser, forwarding its IP so auth-service can lock out per client.
+func (h *authHandler) postPassword(c *gin.Context, path string, payload interface{}) (*http.Response, error) {
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, h.cfg.ApiGatewayURL+"/v1/auth/password/"+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", c.ClientIP())
+	return http.DefaultClient.Do(req)
+}
+
+// apiError returns the error message of a failed API response.
+func apiError(resp *http.Response, fallback string) string {
+	var e struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+		return e.Error
+	}
+	return fallback
+}
+
+// PasswordLogin handles the email+password form on the login page and passes the
+// auth cookies set by auth-service on to the browser. Accounts with two-factor
+// authentication continue at /login/2fa with the challenge cookie.
+func (h *authHandler) PasswordLogin(c *gin.Context) {
+	resp, err := h.postPassword(c, "login", gin.H{"email": c.PostForm("email"), "password": c.PostForm("password")})
+	if err != nil {
+		h.renderLogin(c, http.StatusBadGateway, "Login is unavailable, try again later")
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		h.renderLogin(c, resp.StatusCode, apiError(resp, "Login failed"))
+		return
+	}
+	var body struct {
+		MFARequired bool `json:"mfa_required"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	for _, ck := range resp.Cookies() {
+		http.SetCookie(c.Writer, ck)
+	}
+	if body.MFARequired {
+		c.Redirect(http.StatusFound, returnto.AppendTo("/login/2fa", requestedReturnTo(c)))
+		return
+	}
+	c.Redirect(http.StatusFound, afterLogin(c))
+}
+
+// TwoFactorPage asks for the second factor of a login that auth-service challenged,
+// after a password or provider login.
+func (h *authHandler) TwoFactorPage(c *gin.Context) {
+	if challenge, err := c.Cookie("mfa_challenge"); err != nil || challenge == "" {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+	c.HTML(http.StatusOK, "login-2fa.html", gin.H{"returnTo": requestedReturnTo(c)})
+}
+
+// VerifyTwoFactor sends the authenticator or recovery code with the challenge cookie
+// and passes the auth cookies on to the browser.
+func (h *authHandler) VerifyTwoFactor(c *gin.Context) {
+	challenge, err := c.Cookie("mfa_challenge")
+	if err != nil || challenge == "" {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+	returnTo := requestedReturnTo(c)
+	body, _ := json.Marshal(gin.H{"challenge": challenge, "code": c.PostForm("code")})
+	req, err := http.NewRequest(http.MethodPost, h.cfg.ApiGatewayURL+"/v1/auth/mfa/verify", bytes.NewReader(body))
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "login-2fa.html", gin.H{"error": "Login failed", "returnTo": returnTo})
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", c.ClientIP())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.HTML(http.StatusBadGateway, "login-2fa.html", gin.H{"error": "Login is unavailable, try again later", "returnTo": returnTo})
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		c.HTML(resp.StatusCode, "login-2fa.html", gin.H{"error": apiError(resp, "Login failed"), "returnTo": returnTo})
+		return
+	}
+	var login struct {
+		NewUser bool `json:"new_user"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&login)
+	for _, ck := range resp.Cookies() {
+		http.SetCookie(c.Writer, ck)
+	}
+	if login.NewUser {
+		c.Redirect(http.StatusFound, returnto.AppendTo("/set-username", returnTo))
+		return
+	}
+	c.Redirect(http.StatusFound, afterLogin(c))
+}
+
+func (h *authHandler) ForgotPasswordPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "forgot-password.html", gin.H{})
+}
+
+// ForgotPassword requests a reset link. The page reads the same whether or not the
+// email has an account.
+func (h *authHandler) ForgotPassword(c *gin.Context) {
+	resp, err := h.postPassword(c, "forgot", gin.H{"email": c.PostForm("email")})
+	if err != nil {
+		c.HTML(http.StatusBadGateway, "forgot-password.html", gin.H{"error": "Password reset is unavailable, try again later"})
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		c.HTML(resp.StatusCode, "forgot-password.html", gin.H{"error": apiError(resp, "Password reset failed")})
+		return
+	}
+	c.HTML(http.StatusOK, "forgot-password.html", gin.H{"sent": true})
+}
+
+// ResetPasswordPage is the target of the link in reset and set-password mails.
+func (h *authHandler) ResetPasswordPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "reset-password.html", gin.H{"token": c.Query("token")})
+}
+
+func (h *authHandler) ResetPassword(c *gin.Context) {
+	token := c.PostForm("token")
+	password := c.PostForm("password")
+	if password != c.PostForm("password_confirm") {
+		c.HTML(http.StatusBadRequest, "reset-password.html", gin.H{"token": token, "error": "Passwords do not match"})
+		return
+	}
+	resp, err := h.postPassword(c, "reset", gin.H{"token": token, "password": password})
+	if err != nil {
+		c.HTML(http.StatusBadGateway, "reset-password.html", gin.H{"token": token, "error": "Password reset is unavailable, try again later"})
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		c.HTML(resp.StatusCode, "reset-password.html", gin.H{"token": token, "error": apiError(resp, "Password reset failed")})
+		return
+	}
+	c.Redirect(http.StatusFound, "/login?reset=1")
+}
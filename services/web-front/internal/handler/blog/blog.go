@@ -12,6 +12,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/microcosm-cc/bluemonday"
+	"seungpyo.lee/PersonalWebSite/pkg/returnto"
 	"seungpyo.lee/PersonalWebSite/services/web-front/internal/config"
 )
 
@@ -23,6 +24,7 @@ type User struct {
 type Post struct {
 	ID          uint       `json:"id" gorm:"primaryKey"`
 	Title       string     `json:"title" gorm:"type:text;not null"`
+	Slug        string     `json:"slug"`
 	Content     string     `json:"content" gorm:"type:text;not null"`
 	EnTitle     string     `json:"en_title,omitempty" gorm:"type:text"`
 	EnContent   string     `json:"en_content,omitempty" gorm:"type:text"`
@@ -31,13 +33,49 @@ type Post struct {
 	Author      User       `json:"author" gorm:"foreignKey:AuthorID"`
 	Published   bool       `json:"published" gorm:"default:false"`
 	PublishedAt *time.Time `json:"published_at,omitempty"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	Tags        []*Tag     `json:"tags,omitempty" gorm:"many2many:post_tags;constraint:OnDelete:CASCADE;"`
+	Series      *SeriesNav `json:"series,omitempty"`
 }
+
+// Permalink is the article's canonical path: its slug, or its ID until it has one.
+func (p Post) Permalink() string {
+	if p.Slug != "" {
+		return "/blog/" + url.PathEscape(p.Slug)
+	}
+	return "/blog/" + strconv.FormatUint(uint64(p.ID), 10)
+}
+
+// scheduleValue formats a scheduled time for the editor, which shows it in the
+// browser's time zone; "" when nothing is scheduled.
+func scheduleValue(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
 type Tag struct {
-	ID   uint   `json:"id"`
-	Name string `json:"name"`
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	PostCount   int    `json:"post_count"`
+}
+
+// AuthorProfile is the public profile shown next to an article.
+type AuthorProfile struct {
+	ID          uint   `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	AvatarURL   string `json:"avatar_url"`
+	Links       []struct {
+		Label string `json:"label"`
+		URL   string `json:"url"`
+	} `json:"links"`
 }
 
 type BlogHandler interface {
@@ -47,6 +85,13 @@ type BlogHandler interface {
 	RemovePage(c *gin.Context)
 	Remove(c *gin.Context)
 	EditOrNew(c *gin.Context)
+	Drafts(c *gin.Context)
+	History(c *gin.Context)
+	Restore(c *gin.Context)
+	Comment(c *gin.Context)
+	Comments(c *gin.Context)
+	Moderate(c *gin.Context)
+	Series(c *gin.Context)
 }
 
 type blogHandler struct {
@@ -57,6 +102,19 @@ func NewBlogHandler(cfg *config.PostConfig) BlogHandler {
 	return &blogHandler{cfg: cfg}
 }
 
+// getAsAuthor fetches an api-gateway URL with the caller's access token, for the
+// author-only post routes.
+func getAsAuthor(c *gin.Context, apiURL string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accessToken, err := c.Cookie("access_token"); err == nil {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return http.DefaultClient.Do(req)
+}
+
 // ensureImageURLs prepends base to relative image URLs in Markdown image syntax.
 // It leaves absolute URLs (http..., data:...) untouched and preserves optional title text.
 func ensureImageURLs(md, base string) string {
@@ -139,31 +197,50 @@ func ensureImageSrcURLs(htmlStr, base string) string {
 func (h *blogHandler) List(c *gin.Context) {
 	apiGatewayURL := h.cfg.ApiGatewayURL
 
-	// Forward optional search query to API gateway
 	searchQ := c.Query("search")
 	tagQ := c.Query("tag")
-	apiURL := apiGatewayURL + "/v1/posts"
-	// Build query params for search and tag
-	q := url.Values{}
-	if searchQ != "" {
-		q.Set("search", searchQ)
-	}
+	// Tag pages go by the tag's own spelling, whatever case or alias led to them
+	var tagInfo *Tag
 	if tagQ != "" {
-		q.Set("tag", tagQ)
-	}
-	if enc := q.Encode(); enc != "" {
-		apiURL = apiURL + "?" + enc
+		if tagInfo = h.tagInfo(tagQ); tagInfo != nil && tagInfo.Name != tagQ {
+			params := c.Request.URL.Query()
+			params.Set("tag", tagInfo.Name)
+			c.Redirect(http.StatusFound, "/blog?"+params.Encode())
+			return
+		}
 	}
-	resp, err := http.Get(apiURL)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to fetch posts"))
-		return
+	pageSize := 8
+	page := 1
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+		if page < 1 {
+			page = 1
+		}
 	}
-	defer resp.Body.Close()
 	var posts []Post
-	if err := json.NewDecoder(resp.Body).Decode(&posts); err != nil {
-		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Invalid post data"))
-		return
+	var found *searchResults
+	var listed *postList
+	totalPosts := 0
+	if searchQ != "" {
+		// searches are ranked and paged by post-service
+		var err error
+		found, err = h.search(searchQ, tagQ, page, pageSize)
+		if err != nil {
+			c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to search posts"))
+			return
+		}
+		posts = found.Posts
+		totalPosts = found.Total
+	} else {
+		// the list and tag pages move through posts by cursor, newest first
+		var err error
+		listed, err = h.listPosts(tagQ, c.Query("cursor"), pageSize)
+		if err != nil {
+			c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to fetch posts"))
+			return
+		}
+		posts = listed.Posts
+		totalPosts = listed.Total
 	}
 	// Convert thumbnails to full URLs if relative
 	for i := range posts {
@@ -178,29 +255,8 @@ func (h *blogHandler) List(c *gin.Context) {
 		defer tr.Body.Close()
 		_ = json.NewDecoder(tr.Body).Decode(&availableTags)
 	}
-	// Pagination logic
-	pageSize := 8
-	page := 1
-	if p := c.Query("page"); p != "" {
-		fmt.Sscanf(p, "%d", &page)
-		if page < 1 {
-			page = 1
-		}
-	}
-	totalPosts := len(posts)
+	// Pagination logic; search results are numbered pages
 	totalPages := (totalPosts + pageSize - 1) / pageSize
-	if page > totalPages && totalPages > 0 {
-		page = totalPages
-	}
-	start := (page - 1) * pageSize
-	end := start + pageSize
-	if start > totalPosts {
-		start = totalPosts
-	}
-	if end > totalPosts {
-		end = totalPosts
-	}
-	pagedPosts := posts[start:end]
 	// pageNumbers slice
 	pageNumbers := []int{}
 	for i := 1; i <= totalPages; i++ {
@@ -226,8 +282,9 @@ func (h *blogHandler) List(c *gin.Context) {
 		isLoggedIn = true
 	}
 	c.HTML(http.StatusOK, "blog-list.html", gin.H{
-		"posts":         pagedPosts,
+		"posts":         posts,
 		"tag":           tagQ,
+		"tagInfo":       tagInfo,
 		"userId":        userId,
 		"isLoggedIn":    isLoggedIn,
 		"search":        searchQ,
@@ -237,6 +294,9 @@ func (h *blogHandler) List(c *gin.Context) {
 		"pageNumbers":   pageNumbers,
 		"prevPage":      prevPage,
 		"nextPage":      nextPage,
+		"found":         found,
+		"listed":        listed,
+		"totalPosts":    totalPosts,
 	})
 }
 
@@ -264,6 +324,11 @@ func (h *blogHandler) EditOrNew(c *gin.Context) {
 	if _, err := c.Cookie("access_token"); err == nil {
 		isLoggedIn = true
 	}
+	if !isLoggedIn {
+		// come back to the editor after logging in
+		c.Redirect(http.StatusFound, returnto.AppendTo("/login", c.Request.URL.RequestURI()))
+		return
+	}
 	articleNumber := c.Param("articleNumber")
 	if articleNumber == "" {
 		c.HTML(http.StatusOK, "blog-post.html", gin.H{
@@ -275,7 +340,8 @@ func (h *blogHandler) EditOrNew(c *gin.Context) {
 
 	apiGatewayURL := h.cfg.ApiGatewayURL
 
-	resp, err := http.Get(apiGatewayURL + "/v1/posts/" + articleNumber)
+	// drafts are only served to their author
+	resp, err := getAsAuthor(c, apiGatewayURL+"/v1/drafts/"+articleNumber)
 	if err != nil || resp.StatusCode != http.StatusOK {
 		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to fetch post for editing"))
 		return
@@ -298,6 +364,7 @@ func (h *blogHandler) EditOrNew(c *gin.Context) {
 		"post": gin.H{
 			"ID":          post.ID,
 			"Title":       post.Title,
+			"Slug":        post.Slug,
 			"EnTitle":     post.EnTitle,
 			"Content":     post.Content,   // Raw Markdown
 			"EnContent":   post.EnContent, // Raw Markdown
@@ -306,6 +373,8 @@ func (h *blogHandler) EditOrNew(c *gin.Context) {
 			"Author":      post.Author,
 			"Published":   post.Published,
 			"PublishedAt": post.PublishedAt,
+			"PublishAt":   scheduleValue(post.PublishAt),
+			"UnpublishAt": scheduleValue(post.UnpublishAt),
 			"CreatedAt":   post.CreatedAt,
 			"UpdatedAt":   post.UpdatedAt,
 			"Tags":        post.Tags,
@@ -313,12 +382,71 @@ func (h *blogHandler) EditOrNew(c *gin.Context) {
 	})
 }
 
+// Drafts lists the author's unpublished and scheduled posts.
+func (h *blogHandler) Drafts(c *gin.Context) {
+	if _, err := c.Cookie("access_token"); err != nil {
+		c.Redirect(http.StatusFound, returnto.AppendTo("/login", c.Request.URL.RequestURI()))
+		return
+	}
+	resp, err := getAsAuthor(c, h.cfg.ApiGatewayURL+"/v1/drafts")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to fetch drafts"))
+		return
+	}
+	defer resp.Body.Close()
+	var posts []Post
+	if err := json.NewDecoder(resp.Body).Decode(&posts); err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Invalid post data"))
+		return
+	}
+	userIdStr, err := c.Cookie("userId")
+	userId := uint(0)
+	if err == nil && userIdStr != "" {
+		if parsed, parseErr := strconv.ParseUint(userIdStr, 10, 64); parseErr == nil {
+			userId = uint(parsed)
+		}
+	}
+	c.HTML(http.StatusOK, "blog-drafts.html", gin.H{
+		"posts":      posts,
+		"userId":     userId,
+		"isLoggedIn": true,
+	})
+}
+
+// authorProfile fetches the public profile of an article's author. It returns nil on
+// any error so the article still renders without the author card.
+func (h *blogHandler) authorProfile(authorID uint) *AuthorProfile {
+	if authorID == 0 {
+		return nil
+	}
+	resp, err := http.Get(fmt.Sprintf("%s/v1/auth/users/%d/profile", h.cfg.ApiGatewayURL, authorID))
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	var p AuthorProfile
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return nil
+	}
+	if p.AvatarURL != "" && !strings.HasPrefix(p.AvatarURL, "http") {
+		p.AvatarURL = h.cfg.ImageBaseURL + p.AvatarURL
+	}
+	return &p
+}
+
 func (h *blogHandler) Article(c *gin.Context) {
 	apiGatewayURL := h.cfg.ApiGatewayURL
 
-	articleNumber := c.Param("articleNumber")
-
-	resp, err := http.Get(apiGatewayURL + "/v1/posts/" + articleNumber)
+	// Articles are addressed by slug; numeric IDs are the links from before slugs
+	ref := c.Param("slug")
+	apiURL := apiGatewayURL + "/v1/posts/by-slug/" + url.PathEscape(ref)
+	if _, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		apiURL = apiGatewayURL + "/v1/posts/" + ref
+	}
+	resp, err := http.Get(apiURL)
 	if err != nil || resp.StatusCode != http.StatusOK {
 		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to fetch posts"))
 		return
@@ -329,6 +457,15 @@ func (h *blogHandler) Article(c *gin.Context) {
 		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Invalid post data"))
 		return
 	}
+	// Old slugs and numeric URLs move permanently to the canonical one
+	if post.Slug != "" && post.Slug != ref {
+		target := post.Permalink()
+		if c.Request.URL.RawQuery != "" {
+			target += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusMovedPermanently, target)
+		return
+	}
 	// Convert thumbnail to full URL if relative
 	if post.Thumbnail != "" && !strings.HasPrefix(post.Thumbnail, "http") {
 		post.Thumbnail = h.cfg.ImageBaseURL + post.Thumbnail
@@ -361,10 +498,12 @@ func (h *blogHandler) Article(c *gin.Context) {
 	if _, err := c.Cookie("access_token"); err == nil {
 		isLoggedIn = true
 	}
+	comments := h.postComments(post.ID)
 	c.HTML(http.StatusOK, "blog-article.html", gin.H{
 		"post": gin.H{
 			"ID":            post.ID,
 			"Title":         post.Title,
+			"Slug":          post.Slug,
 			"EnTitle":       post.EnTitle,
 			"Content":       contentStr,   // raw Markdown
 			"EnContent":     enContentStr, // sanitized HTML (rendered via Toast UI Viewer)
@@ -376,9 +515,16 @@ func (h *blogHandler) Article(c *gin.Context) {
 			"CreatedAt":     post.CreatedAt,
 			"UpdatedAt":     post.UpdatedAt,
 			"Tags":          post.Tags,
+			"Series":        post.Series,
 		},
-		"userId":     userId,
-		"isLoggedIn": isLoggedIn,
+		"author":       h.authorProfile(post.AuthorID),
+		"related":      h.relatedPosts(post.ID),
+		"comments":     comments,
+		"commentCount": countComments(comments),
+		"commentState": c.Query("comment"),
+		"commentError": c.Query("comment_error"),
+		"userId":       userId,
+		"isLoggedIn":   isLoggedIn,
 	})
 }
 
@@ -397,7 +543,7 @@ func (h *blogHandler) RemovePage(c *gin.Context) {
 	articleNumber := c.Param("articleNumber")
 	apiGatewayURL := h.cfg.ApiGatewayURL
 
-	resp, err := http.Get(apiGatewayURL + "/v1/posts/" + articleNumber)
+	resp, err := getAsAuthor(c, apiGatewayURL+"/v1/drafts/"+articleNumber)
 	if err != nil || resp.StatusCode != http.StatusOK {
 		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to fetch posts"))
 		return
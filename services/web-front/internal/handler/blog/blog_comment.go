@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/microcosm-cc/bluemonday"
+	"seungpyo.lee/PersonalWebSite/pkg/returnto"
+)
+
+// Comment is a reader's comment as served by post-service, with its replies. BodyHTML
+// is the body rendered for display.
+type Comment struct {
+	ID          uint          `json:"id"`
+	PostID      uint          `json:"post_id"`
+	PostTitle   string        `json:"post_title"`
+	ParentID    *uint         `json:"parent_id,omitempty"`
+	AuthorName  string        `json:"author_name"`
+	AuthorEmail string        `json:"author_email"`
+	Body        string        `json:"body"`
+	Status      string        `json:"status"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Replies     []Comment     `json:"replies"`
+	BodyHTML    template.HTML `json:"-"`
+}
+
+// commentPolicy keeps what renderCommentBody produces: paragraphs, line breaks,
+// emphasis, inline code and http(s) links, which open in a new tab without passing
+// on ranking or the referring window.
+var commentPolicy = func() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "strong", "em", "code")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}()
+
+var (
+	commentParagraphs = regexp.MustCompile(`\n[ \t]*\n+`)
+	commentCode       = regexp.MustCompile("`([^`\n]+)`")
+	commentLink       = regexp.MustCompile(`\[([^\]\n]+)\]\((https?://[^\s()]+)\)`)
+	commentBold       = regexp.MustCompile(`\*\*([^*\s](?:[^*\n]*[^*\s])?)\*\*`)
+	commentItalic     = regexp.MustCompile(`\*([^*\s](?:[^*\n]*[^*\s])?)\*`)
+)
+
+// renderCommentBody renders the Markdown subset comments may use: blank lines between
+// paragraphs, line breaks, **bold**, *italic*, `code` and [links](https://...).
+// Everything else shows as typed.
+func renderCommentBody(body string) template.HTML {
+	body = strings.TrimSpace(strings.ReplaceAll(body, "\r\n", "\n"))
+	var out strings.Builder
+	for _, para := range commentParagraphs.Split(body, -1) {
+		out.WriteString("<p>")
+		for i, line := range strings.Split(para, "\n") {
+			if i > 0 {
+				out.WriteString("<br>\n")
+			}
+			out.WriteString(renderCommentLine(line))
+		}
+		out.WriteString("</p>\n")
+	}
+	return template.HTML(commentPolicy.Sanitize(out.String()))
+}
+
+// renderCommentLine escapes a line and applies the inline markup; code spans are left
+// as typed inside.
+func renderCommentLine(line string) string {
+	var out strings.Builder
+	last := 0
+	for _, m := range commentCode.FindAllStringSubmatchIndex(line, -1) {
+		out.WriteString(renderCommentInline(line[last:m[0]]))
+		out.WriteString("<code>" + html.EscapeString(line[m[2]:m[3]]) + "</code>")
+		last = m[1]
+	}
+	out.WriteString(renderCommentInline(line[last:]))
+	return out.String()
+}
+
+func renderCommentInline(text string) string {
+	text = html.EscapeString(text)
+	text = commentLink.ReplaceAllString(text, `<a href="$2">$1</a>`)
+	text = commentBold.ReplaceAllString(text, "<strong>$1</strong>")
+	return commentItalic.ReplaceAllString(text, "<em>$1</em>")
+}
+
+// renderComments renders the bodies of comments and their replies.
+func renderComments(comments []Comment) {
+	for i := range comments {
+		comments[i].BodyHTML = renderCommentBody(comments[i].Body)
+		renderComments(comments[i].Replies)
+	}
+}
+
+// countComments counts comments and their replies.
+func countComments(comments []Comment) int {
+	n := len(comments)
+	for _, c := range comments {
+		n += countComments(c.Replies)
+	}
+	return n
+}
+
+// postComments fetches the approved comments of a post as threads. It returns nil on
+// any error so the article still renders without them.
+func (h *blogHandler) postComments(postID uint) []Comment {
+	resp, err := http.Get(h.cfg.ApiGatewayURL + "/v1/posts/" + strconv.FormatUint(uint64(postID), 10) + "/comments")
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	var comments []Comment
+	if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
+		return nil
+	}
+	renderComments(comments)
+	return comments
+}
+
+// Comment submits the comment form of an article and returns to its comments. Signed-in
+// readers comment under their username; the gateway treats the others as anonymous.
+func (h *blogHandler) Comment(c *gin.Context) {
+	articleNumber := c.Param("articleNumber")
+	if _, err := strconv.ParseUint(articleNumber, 10, 64); err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Invalid article number"))
+		return
+	}
+	article := "/blog/" + articleNumber
+	payload := map[string]interface{}{
+		"name":    c.PostForm("name"),
+		"email":   c.PostForm("email"),
+		"body":    c.PostForm("body"),
+		"website": c.PostForm("website"),
+	}
+	if parent, err := strconv.ParseUint(c.PostForm("parent_id"), 10, 64); err == nil {
+		payload["parent_id"] = parent
+	}
+	data, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, h.cfg.ApiGatewayURL+"/v1/posts/"+articleNumber+"/comments", bytes.NewReader(data))
+	if err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to create request"))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// the comment rate limit is per visitor, not per web-front
+	req.Header.Set("X-Forwarded-For", c.ClientIP())
+	accessToken, err := c.Cookie("access_token")
+	if err == nil && accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.Redirect(http.StatusFound, article+"?comment=failed#comments")
+		return
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		c.Redirect(http.StatusFound, article+"?comment=posted#comments")
+	case http.StatusAccepted:
+		c.Redirect(http.StatusFound, article+"?comment=pending#comments")
+	case http.StatusUnauthorized:
+		c.Redirect(http.StatusFound, returnto.AppendTo("/login", article))
+	case http.StatusTooManyRequests:
+		c.Redirect(http.StatusFound, article+"?comment=limited#comments")
+	default:
+		var errMsg struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errMsg)
+		if resp.StatusCode == http.StatusBadRequest && errMsg.Error != "" {
+			c.Redirect(http.StatusFound, article+"?comment_error="+url.QueryEscape(errMsg.Error)+"#comments")
+			return
+		}
+		c.Redirect(http.StatusFound, article+"?comment=failed#comments")
+	}
+}
+
+// commentStatuses are the moderation queue's tabs.
+var commentStatuses = []string{"pending", "approved", "spam"}
+
+// Comments shows the moderation queue of the author's posts, one status at a time.
+func (h *blogHandler) Comments(c *gin.Context) {
+	if _, err := c.Cookie("access_token"); err != nil {
+		c.Redirect(http.StatusFound, returnto.AppendTo("/login", c.Request.URL.RequestURI()))
+		return
+	}
+	status := c.DefaultQuery("status", "pending")
+	resp, err := getAsAuthor(c, h.cfg.ApiGatewayURL+"/v1/comments?status="+url.QueryEscape(status))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to fetch comments"))
+		return
+	}
+	defer resp.Body.Close()
+	var comments []Comment
+	if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Invalid comment data"))
+		return
+	}
+	renderComments(comments)
+	userIdStr, err := c.Cookie("userId")
+	userId := uint(0)
+	if err == nil && userIdStr != "" {
+		if parsed, parseErr := strconv.ParseUint(userIdStr, 10, 64); parseErr == nil {
+			userId = uint(parsed)
+		}
+	}
+	c.HTML(http.StatusOK, "blog-comments.html", gin.H{
+		"comments":   comments,
+		"status":     status,
+		"statuses":   commentStatuses,
+		"userId":     userId,
+		"isLoggedIn": true,
+	})
+}
+
+// Moderate applies the moderation form's action to a comment: approved, spam or
+// pending set its status, delete removes it with its replies.
+func (h *blogHandler) Moderate(c *gin.Context) {
+	accessToken, err := c.Cookie("access_token")
+	if err != nil || accessToken == "" {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Need to Login"))
+		return
+	}
+	id := c.Param("id")
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Invalid comment"))
+		return
+	}
+	action := c.PostForm("action")
+	var req *http.Request
+	if action == "delete" {
+		req, err = http.NewRequest(http.MethodDelete, h.cfg.ApiGatewayURL+"/v1/comments/"+id, nil)
+	} else {
+		data, _ := json.Marshal(map[string]string{"status": action})
+		req, err = http.NewRequest(http.MethodPut, h.cfg.ApiGatewayURL+"/v1/comments/"+id+"/status", bytes.NewReader(data))
+		if req != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+	if err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to create request"))
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to moderate comment"))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		var errMsg struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errMsg)
+		msg := strings.TrimSpace(errMsg.Error)
+		if msg == "" {
+			msg = "Failed to moderate comment"
+		}
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape(msg))
+		return
+	}
+	c.Redirect(http.StatusFound, "/blog-comments?status="+url.QueryEscape(c.DefaultPostForm("status", "pending")))
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/services/web-front/internal/config"
+)
+
+func TestComment_ForwardsVisitorIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var forwarded string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/posts/5/comments" {
+			http.NotFound(w, r)
+			return
+		}
+		forwarded = r.Header.Get("X-Forwarded-For")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer gateway.Close()
+
+	h := NewBlogHandler(&config.PostConfig{ApiGatewayURL: gateway.URL})
+	r := gin.New()
+	// web-front trusts nginx alone, as in the compose files
+	if err := r.SetTrustedProxies([]string{"192.0.2.10"}); err != nil {
+		t.Fatal(err)
+	}
+	r.POST("/blog-comment/:articleNumber", h.Comment)
+
+	cases := []struct {
+		name, remoteAddr, xff, want string
+	}{
+		{"behind nginx", "192.0.2.10:1234", "198.51.100.7", "198.51.100.7"},
+		{"spoofed by a client", "198.51.100.8:1234", "203.0.113.9", "198.51.100.8"},
+	}
+	for _, tc := range cases {
+		forwarded = ""
+		form := url.Values{"name": {"kim"}, "email": {"kim@example.com"}, "body": {"hi"}}
+		req := httptest.NewRequest(http.MethodPost, "/blog-comment/5", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = tc.remoteAddr
+		req.Header.Set("X-Forwarded-For", tc.xff)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusFound || !strings.Contains(w.Header().Get("Location"), "comment=pending") {
+			t.Fatalf("%s: expected redirect to the pending notice, got %d %q", tc.name, w.Code, w.Header().Get("Location"))
+		}
+		if forwarded != tc.want {
+			t.Fatalf("%s: expected X-Forwarded-For %q, got %q", tc.name, tc.want, forwarded)
+		}
+	}
+}
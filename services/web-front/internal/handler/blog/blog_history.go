@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/returnto"
+)
+
+// Revision is an entry of a post's history, as listed by post-service.
+type Revision struct {
+	ID        uint      `json:"id"`
+	PostID    uint      `json:"post_id"`
+	Title     string    `json:"title"`
+	AuthorID  *uint     `json:"author_id,omitempty"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// diffLine is a line of a unified diff with the CSS class it is shown with.
+type diffLine struct {
+	Class string
+	Text  string
+}
+
+// diffLines splits a unified diff for display.
+func diffLines(diff string) []diffLine {
+	var lines []diffLine
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		class := ""
+		switch {
+		case strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+			class = "diff-file"
+		case strings.HasPrefix(line, "@@"):
+			class = "diff-hunk"
+		case strings.HasPrefix(line, "+"):
+			class = "diff-add"
+		case strings.HasPrefix(line, "-"):
+			class = "diff-del"
+		}
+		lines = append(lines, diffLine{Class: class, Text: line})
+	}
+	return lines
+}
+
+// History shows the revisions of the author's post and the changes one of them made,
+// compared with the revision before it or with ?from=.
+func (h *blogHandler) History(c *gin.Context) {
+	if _, err := c.Cookie("access_token"); err != nil {
+		c.Redirect(http.StatusFound, returnto.AppendTo("/login", c.Request.URL.RequestURI()))
+		return
+	}
+	articleNumber := c.Param("articleNumber")
+	if _, err := strconv.ParseUint(articleNumber, 10, 64); err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Invalid article number"))
+		return
+	}
+	apiURL := h.cfg.ApiGatewayURL + "/v1/posts/" + articleNumber + "/revisions"
+	resp, err := getAsAuthor(c, apiURL)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to fetch revisions"))
+		return
+	}
+	defer resp.Body.Close()
+	var revisions []Revision
+	if err := json.NewDecoder(resp.Body).Decode(&revisions); err != nil || len(revisions) == 0 {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Invalid revision data"))
+		return
+	}
+
+	selected := revisions[0].ID
+	if rev, err := strconv.ParseUint(c.Query("rev"), 10, 64); err == nil {
+		selected = uint(rev)
+	}
+	diffURL := fmt.Sprintf("%s/%d/diff", apiURL, selected)
+	if from, err := strconv.ParseUint(c.Query("from"), 10, 64); err == nil {
+		diffURL += "?from=" + strconv.FormatUint(from, 10)
+	}
+	var diff struct {
+		From uint   `json:"from"`
+		To   uint   `json:"to"`
+		Diff string `json:"diff"`
+	}
+	dr, err := getAsAuthor(c, diffURL)
+	if err != nil || dr.StatusCode != http.StatusOK {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to fetch revision changes"))
+		return
+	}
+	defer dr.Body.Close()
+	if err := json.NewDecoder(dr.Body).Decode(&diff); err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Invalid revision data"))
+		return
+	}
+
+	userIdStr, err := c.Cookie("userId")
+	userId := uint(0)
+	if err == nil && userIdStr != "" {
+		if parsed, parseErr := strconv.ParseUint(userIdStr, 10, 64); parseErr == nil {
+			userId = uint(parsed)
+		}
+	}
+	c.HTML(http.StatusOK, "blog-history.html", gin.H{
+		"userId":        userId,
+		"isLoggedIn":    true,
+		"articleNumber": articleNumber,
+		"title":         revisions[0].Title,
+		"revisions":     revisions,
+		"selected":      selected,
+		"from":          diff.From,
+		"diff":          diffLines(diff.Diff),
+		"unchanged":     diff.Diff == "",
+	})
+}
+
+// Restore puts back the text of a revision and returns to the editor.
+func (h *blogHandler) Restore(c *gin.Context) {
+	accessToken, err := c.Cookie("access_token")
+	if err != nil || accessToken == "" {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Need to Login"))
+		return
+	}
+	articleNumber := c.Param("articleNumber")
+	rev := c.Param("rev")
+	if _, err := strconv.ParseUint(articleNumber, 10, 64); err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Invalid article number"))
+		return
+	}
+	if _, err := strconv.ParseUint(rev, 10, 64); err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Invalid revision"))
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, h.cfg.ApiGatewayURL+"/v1/posts/"+articleNumber+"/revisions/"+rev+"/restore", nil)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to create request"))
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to restore revision"))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var errMsg struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errMsg)
+		msg := strings.TrimSpace(errMsg.Error)
+		if msg == "" {
+			msg = "Failed to restore revision"
+		}
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape(msg))
+		return
+	}
+	c.Redirect(http.StatusFound, "/blog-edit/"+articleNumber)
+}
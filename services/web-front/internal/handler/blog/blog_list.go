@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// postList is one page of GET /v1/posts, newest first, with the cursors of the pages
+// around it ("" when there is none) and the number of posts on all pages.
+type postList struct {
+	Posts      []Post
+	Total      int
+	NextCursor string
+	PrevCursor string
+}
+
+// listPosts fetches the page of published posts that cursor points to, or the first
+// one, optionally within a tag.
+func (h *blogHandler) listPosts(tag, cursor string, limit int) (*postList, error) {
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(limit))
+	if tag != "" {
+		params.Set("tag", tag)
+	}
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+	resp, err := http.Get(h.cfg.ApiGatewayURL + "/v1/posts?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("post list returned %d", resp.StatusCode)
+	}
+	list := &postList{}
+	if err := json.NewDecoder(resp.Body).Decode(&list.Posts); err != nil {
+		return nil, err
+	}
+	list.Total, _ = strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	list.NextCursor, list.PrevCursor = linkCursors(resp.Header.Get("Link"))
+	return list, nil
+}
+
+// tagInfo fetches the tag that name refers to, by its name in any case or an alias,
+// with its description. It returns nil for unknown tags and when post-service cannot
+// be reached, so the page is still shown.
+func (h *blogHandler) tagInfo(name string) *Tag {
+	resp, err := http.Get(h.cfg.ApiGatewayURL + "/v1/tags/" + url.PathEscape(name))
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	var tag Tag
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&tag) != nil {
+		return nil
+	}
+	return &tag
+}
+
+// linkCursors reads the cursors of the next and previous pages from a Link header
+// such as `<?cursor=abc&limit=8>; rel="next", <?cursor=def&limit=8>; rel="prev"`.
+func linkCursors(header string) (next, prev string) {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+		if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		u, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">"))
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(params) {
+		case `rel="next"`:
+			next = u.Query().Get("cursor")
+		case `rel="prev"`:
+			prev = u.Query().Get("cursor")
+		}
+	}
+	return next, prev
+}
@@ -8,7 +8,6 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -35,7 +34,9 @@ func (h *postHandler) Save(c *gin.Context) {
 		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Title is required"))
 		return
 	}
-	published := true
+	// "Publish" with a publish time schedules the post; until then it is a draft
+	publishAt := strings.TrimSpace(c.PostForm("publish_at"))
+	published := c.PostForm("action") == "publish" && publishAt == ""
 
 	content := c.PostForm("article-content")
 	if content == "" {
@@ -89,10 +90,13 @@ func (h *postHandler) Save(c *gin.Context) {
 	}
 	payload := map[string]interface{}{
 		"title":          title,
+		"slug":           strings.TrimSpace(c.PostForm("slug")), // empty: generated from the title
 		"tags":           tagsPayload,
 		"content":        content,
 		"thumbnail_data": thumbnailData,
 		"published":      published,
+		"publish_at":     publishAt,                                     // RFC 3339, set by the editor script
+		"unpublish_at":   strings.TrimSpace(c.PostForm("unpublish_at")), // RFC 3339
 	}
 
 	reqBody, _ := json.Marshal(payload)
@@ -143,5 +147,9 @@ func (h *postHandler) Save(c *gin.Context) {
 		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to parse response"))
 		return
 	}
-	c.Redirect(http.StatusFound, "/blog/"+strconv.FormatUint(uint64(body.ID), 10))
+	if !body.Published {
+		c.Redirect(http.StatusFound, "/blog-drafts")
+		return
+	}
+	c.Redirect(http.StatusFound, body.Permalink())
 }
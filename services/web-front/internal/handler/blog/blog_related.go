@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// relatedLimit is how many related posts an article suggests.
+const relatedLimit = 3
+
+// RelatedPost is a post suggested after an article, as served by post-service without
+// its content.
+type RelatedPost struct {
+	Post       Post    `json:"post"`
+	Score      float64 `json:"score"`
+	SharedTags int     `json:"shared_tags"`
+}
+
+// relatedPosts fetches the posts to suggest after an article, best first, with their
+// thumbnails as full URLs. It returns nil on any error so the article still renders
+// without them.
+func (h *blogHandler) relatedPosts(postID uint) []Post {
+	resp, err := http.Get(h.cfg.ApiGatewayURL + "/v1/posts/" + strconv.FormatUint(uint64(postID), 10) + "/related?limit=" + strconv.Itoa(relatedLimit))
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	var related []RelatedPost
+	if err := json.NewDecoder(resp.Body).Decode(&related); err != nil {
+		return nil
+	}
+	posts := make([]Post, 0, len(related))
+	for _, r := range related {
+		if r.Post.Thumbnail != "" && !strings.HasPrefix(r.Post.Thumbnail, "http") {
+			r.Post.Thumbnail = h.cfg.ImageBaseURL + r.Post.Thumbnail
+		}
+		posts = append(posts, r.Post)
+	}
+	return posts
+}
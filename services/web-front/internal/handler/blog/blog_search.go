@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// searchHit is a post found by GET /v1/posts/search. Its highlights are HTML with the
+// matches in <mark>.
+type searchHit struct {
+	Post           Post    `json:"post"`
+	Rank           float64 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+// searchResults is one page of search hits and the number of all of them. Titles and
+// Snippets hold the sanitized highlights by post ID.
+type searchResults struct {
+	Posts    []Post
+	Total    int
+	Titles   map[uint]template.HTML
+	Snippets map[uint]template.HTML
+}
+
+// highlightPolicy keeps the <mark> elements of search highlights and nothing else.
+var highlightPolicy = bluemonday.NewPolicy().AllowElements("mark")
+
+// search fetches the given page of the posts matching q, optionally within a tag.
+func (h *blogHandler) search(q, tag string, page, pageSize int) (*searchResults, error) {
+	params := url.Values{}
+	params.Set("q", q)
+	if tag != "" {
+		params.Set("tag", tag)
+	}
+	params.Set("limit", strconv.Itoa(pageSize))
+	params.Set("offset", strconv.Itoa((page-1)*pageSize))
+	resp, err := http.Get(h.cfg.ApiGatewayURL + "/v1/posts/search?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("search returned %d", resp.StatusCode)
+	}
+	var body struct {
+		Total int         `json:"total"`
+		Hits  []searchHit `json:"hits"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	results := &searchResults{
+		Total:    body.Total,
+		Titles:   make(map[uint]template.HTML, len(body.Hits)),
+		Snippets: make(map[uint]template.HTML, len(body.Hits)),
+	}
+	for _, hit := range body.Hits {
+		results.Posts = append(results.Posts, hit.Post)
+		results.Titles[hit.Post.ID] = template.HTML(highlightPolicy.Sanitize(hit.TitleHighlight))
+		results.Snippets[hit.Post.ID] = template.HTML(highlightPolicy.Sanitize(hit.Snippet))
+	}
+	return results, nil
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SeriesPart is a post of a series, linked by its permalink.
+type SeriesPart struct {
+	PostID uint   `json:"post_id"`
+	Title  string `json:"title"`
+	Slug   string `json:"slug"`
+}
+
+// Permalink is the part's article path, as Post.Permalink.
+func (p SeriesPart) Permalink() string {
+	return Post{ID: p.PostID, Slug: p.Slug}.Permalink()
+}
+
+// SeriesNav is where an article stands in its series: part Part of Total.
+type SeriesNav struct {
+	ID    uint        `json:"id"`
+	Title string      `json:"title"`
+	Part  int         `json:"part"`
+	Total int         `json:"total"`
+	Prev  *SeriesPart `json:"prev"`
+	Next  *SeriesPart `json:"next"`
+}
+
+// Series is a series with its published parts in reading order.
+type Series struct {
+	ID          uint          `json:"id"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	AuthorID    uint          `json:"author_id"`
+	Parts       []*SeriesPart `json:"parts"`
+}
+
+// Series shows a series' landing page: its description and the list of its parts.
+func (h *blogHandler) Series(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Invalid series"))
+		return
+	}
+	resp, err := http.Get(h.cfg.ApiGatewayURL + "/v1/series/" + strconv.FormatUint(id, 10))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to fetch series"))
+		return
+	}
+	defer resp.Body.Close()
+	var series Series
+	if err := json.NewDecoder(resp.Body).Decode(&series); err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Invalid series data"))
+		return
+	}
+	_, err = c.Cookie("access_token")
+	c.HTML(http.StatusOK, "blog-series.html", gin.H{
+		"series":     series,
+		"author":     h.authorProfile(series.AuthorID),
+		"isLoggedIn": err == nil,
+	})
+}
@@ -43,16 +43,12 @@ func (h *pageHandler) Index(c *gin.Context) {
 
 	apiGatewayURL := h.cfg.ApiGatewayURL
 	posts := []blogHandler.Post{}
-	resp, err := http.Get(apiGatewayURL + "/v1/posts")
+	resp, err := http.Get(apiGatewayURL + "/v1/posts?limit=3")
 	if err == nil && resp.StatusCode == http.StatusOK {
 		defer resp.Body.Close()
-		var allPosts []blogHandler.Post
-		if err := json.NewDecoder(resp.Body).Decode(&allPosts); err == nil && len(allPosts) > 0 {
-			if len(allPosts) > 3 {
-				posts = allPosts[:3]
-			} else {
-				posts = allPosts
-			}
+		var latest []blogHandler.Post
+		if err := json.NewDecoder(resp.Body).Decode(&latest); err == nil {
+			posts = latest
 		}
 	}
 	for i := range posts {
@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"seungpyo.lee/PersonalWebSite/pkg/returnto"
+	"seungpyo.lee/PersonalWebSite/services/web-front/internal/config"
+)
+
+// maxAvatarBytes matches the avatar size limit enforced by auth-service.
+const maxAvatarBytes = 2 << 20
+
+type Link struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+type Profile struct {
+	ID          uint   `json:"id"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	AvatarURL   string `json:"avatar_url"`
+	Links       []Link `json:"links"`
+}
+
+type ProfileHandler interface {
+	Page(c *gin.Context)
+	Update(c *gin.Context)
+	SetUsernamePage(c *gin.Context)
+	SetUsername(c *gin.Context)
+	TwoFactorPage(c *gin.Context)
+	EnrollTwoFactor(c *gin.Context)
+	ConfirmTwoFactor(c *gin.Context)
+	DisableTwoFactor(c *gin.Context)
+	RegenerateRecoveryCodes(c *gin.Context)
+}
+
+type profileHandler struct {
+	cfg *config.PostConfig
+}
+
+func NewProfileHandler(cfg *config.PostConfig) ProfileHandler {
+	return &profileHandler{cfg: cfg}
+}
+
+// session returns the signed-in user's id and access token, redirecting to the
+// login page when either cookie is missing. Pages that were requested come back
+// after the login.
+func (h *profileHandler) session(c *gin.Context) (string, string, bool) {
+	userID, err := c.Cookie("userId")
+	accessToken, errToken := c.Cookie("access_token")
+	if err != nil || userID == "" || errToken != nil || accessToken == "" {
+		login := "/login"
+		if c.Request.Method == http.MethodGet {
+			login = returnto.AppendTo(login, c.Request.URL.RequestURI())
+		}
+		c.Redirect(http.StatusFound, login)
+		return "", "", false
+	}
+	return userID, accessToken, true
+}
+
+// fetch loads the signed-in user's own profile through the gateway.
+func (h *profileHandler) fetch(userID, accessToken string) (*Profile, error) {
+	req, err := http.NewRequest(http.MethodGet, h.cfg.ApiGatewayURL+"/v1/auth/users/"+url.PathEscape(userID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	var p Profile
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return nil, err
+	}
+	if p.AvatarURL != "" && !strings.HasPrefix(p.AvatarURL, "http") {
+		p.AvatarURL = h.cfg.ImageBaseURL + p.AvatarURL
+	}
+	return &p, nil
+}
+
+// update sends a partial profile update and returns auth-service's error message on failure.
+func (h *profileHandler) update(userID, accessToken string, payload map[string]interface{}) error {
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPut, h.cfg.ApiGatewayURL+"/v1/auth/users/"+url.PathEscape(userID), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to update profile")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			return fmt.Errorf("%s", e.Error)
+		}
+		return fmt.Errorf("failed to update profile")
+	}
+	return nil
+}
+
+func (h *profileHandler) Page(c *gin.Context) {
+	userID, accessToken, ok := h.session(c)
+	if !ok {
+		return
+	}
+	p, err := h.fetch(userID, accessToken)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape("Failed to load profile"))
+		return
+	}
+	c.HTML(http.StatusOK, "profile.html", gin.H{"user": p, "isLoggedIn": true})
+}
+
+// Update saves the profile form: username, display name, bio, up to ten links and
+// an optional new avatar file.
+func (h *profileHandler) Update(c *gin.Context) {
+	userID, accessToken, ok := h.session(c)
+	if !ok {
+		return
+	}
+	username := strings.TrimSpace(c.PostForm("username"))
+	displayName := strings.TrimSpace(c.PostForm("display_name"))
+	bio := strings.TrimSpace(c.PostForm("bio"))
+	links := []Link{}
+	urls := c.PostFormArray("link_url")
+	for i, label := range c.PostFormArray("link_label") {
+		if i >= len(urls) {
+			break
+		}
+		label, u := strings.TrimSpace(label), strings.TrimSpace(urls[i])
+		if label != "" && u != "" {
+			links = append(links, Link{Label: label, URL: u})
+		}
+	}
+	payload := map[string]interface{}{
+		"username":     username,
+		"display_name": displayName,
+		"bio":          bio,
+		"links":        links,
+	}
+
+	renderError := func(msg string) {
+		p, err := h.fetch(userID, accessToken)
+		if err != nil {
+			c.Redirect(http.StatusFound, "/error?msg="+url.QueryEscape(msg))
+			return
+		}
+		c.HTML(http.StatusBadRequest, "profile.html", gin.H{"user": p, "isLoggedIn": true, "error": msg})
+	}
+
+	if file, err := c.FormFile("avatar"); err == nil && file != nil {
+		if file.Size > maxAvatarBytes {
+			renderError("Avatar must be 2MB or smaller")
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			renderError("Failed to read avatar file")
+			return
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			renderError("Failed to read avatar file")
+			return
+		}
+		mimeType := file.Header.Get("Content-Type")
+		if mimeType == "" {
+			mimeType = http.DetectContentType(data)
+		}
+		payload["avatar"] = fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data))
+	}
+
+	if err := h.update(userID, accessToken, payload); err != nil {
+		renderError(err.Error())
+		return
+	}
+	c.Redirect(http.StatusFound, "/profile")
+}
+
+// SetUsernamePage is shown once after sign-up, prefilled with the generated username.
+func (h *profileHandler) SetUsernamePage(c *gin.Context) {
+	userID, accessToken, ok := h.session(c)
+	if !ok {
+		return
+	}
+	defaultUsername := ""
+	if p, err := h.fetch(userID, accessToken); err == nil {
+		defaultUsername = p.Username
+	}
+	c.HTML(http.StatusOK, "set-username.html", gin.H{"defaultUsername": defaultUsername, "isLoggedIn": true, "returnTo": returnto.Sanitize(c.Query(returnto.Param))})
+}
+
+func (h *profileHandler) SetUsername(c *gin.Context) {
+	userID, accessToken, ok := h.session(c)
+	if !ok {
+		return
+	}
+	username := strings.TrimSpace(c.PostForm("username"))
+	returnTo := returnto.Sanitize(c.PostForm(returnto.Param))
+	if err := h.update(userID, accessToken, map[string]interface{}{"username": username}); err != nil {
+		c.HTML(http.StatusBadRequest, "set-username.html", gin.H{"defaultUsername": username, "isLoggedIn": true, "error": err.Error(), "returnTo": returnTo})
+		return
+	}
+	if returnTo == "" {
+		returnTo = "/"
+	}
+	c.Redirect(http.StatusFound, returnTo)
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorStatus mirrors auth-service's GET /mfa response.
+type TwoFactorStatus struct {
+	Enabled           bool  `json:"enabled"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+// TwoFactorEnrollment is a new authenticator secret and its otpauth:// URI.
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// callMFA sends a two-factor API call through the gateway and decodes the response
+// into out. It returns auth-service's error message on failure.
+func (h *profileHandler) callMFA(method, path, accessToken string, payload, out interface{}) error {
+	var body *bytes.Reader
+	if payload != nil {
+		bb, _ := json.Marshal(payload)
+		body = bytes.NewReader(bb)
+	} else {
+		body = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, h.cfg.ApiGatewayURL+"/v1/auth/mfa"+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("two-factor authentication is unavailable, try again later")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			return fmt.Errorf("%s", e.Error)
+		}
+		return fmt.Errorf("request failed with status %d", resp.StatusCode)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// renderTwoFactor shows the two-factor page with the current status and any extra data.
+func (h *profileHandler) renderTwoFactor(c *gin.Context, accessToken string, status int, data gin.H) {
+	var st TwoFactorStatus
+	if err := h.callMFA(http.MethodGet, "", accessToken, nil, &st); err != nil && data["error"] == nil {
+		data["error"] = err.Error()
+	}
+	data["status"] = st
+	data["isLoggedIn"] = true
+	c.HTML(status, "two-factor.html", data)
+}
+
+// TwoFactorPage shows whether two-factor authentication is on and how to change it.
+func (h *profileHandler) TwoFactorPage(c *gin.Context) {
+	_, accessToken, ok := h.session(c)
+	if !ok {
+		return
+	}
+	h.renderTwoFactor(c, accessToken, http.StatusOK, gin.H{})
+}
+
+// EnrollTwoFactor creates a new authenticator secret and shows it as a QR code.
+func (h *profileHandler) EnrollTwoFactor(c *gin.Context) {
+	_, accessToken, ok := h.session(c)
+	if !ok {
+		return
+	}
+	var enrollment TwoFactorEnrollment
+	if err := h.callMFA(http.MethodPost, "/totp", accessToken, nil, &enrollment); err != nil {
+		h.renderTwoFactor(c, accessToken, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.renderTwoFactor(c, accessToken, http.StatusOK, gin.H{"enrollment": enrollment})
+}
+
+// ConfirmTwoFactor turns two-factor authentication on with a first code and shows
+// the recovery codes once.
+func (h *profileHandler) ConfirmTwoFactor(c *gin.Context) {
+	_, accessToken, ok := h.session(c)
+	if !ok {
+		return
+	}
+	var out struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	code := strings.TrimSpace(c.PostForm("code"))
+	if err := h.callMFA(http.MethodPost, "/totp/confirm", accessToken, gin.H{"code": code}, &out); err != nil {
+		// keep showing the pending secret so the user can try another code
+		enrollment := TwoFactorEnrollment{Secret: c.PostForm("secret"), ProvisioningURI: c.PostForm("provisioning_uri")}
+		h.renderTwoFactor(c, accessToken, http.StatusBadRequest, gin.H{"error": err.Error(), "enrollment": enrollment})
+		return
+	}
+	h.renderTwoFactor(c, accessToken, http.StatusOK, gin.H{"recoveryCodes": out.RecoveryCodes})
+}
+
+// DisableTwoFactor turns two-factor authentication off with a current or recovery code.
+func (h *profileHandler) DisableTwoFactor(c *gin.Context) {
+	_, accessToken, ok := h.session(c)
+	if !ok {
+		return
+	}
+	code := strings.TrimSpace(c.PostForm("code"))
+	if err := h.callMFA(http.MethodDelete, "/totp", accessToken, gin.H{"code": code}, nil); err != nil {
+		h.renderTwoFactor(c, accessToken, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Redirect(http.StatusFound, "/profile/2fa")
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes and shows the new ones once.
+func (h *profileHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	_, accessToken, ok := h.session(c)
+	if !ok {
+		return
+	}
+	var out struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	code := strings.TrimSpace(c.PostForm("code"))
+	if err := h.callMFA(http.MethodPost, "/recovery-codes", accessToken, gin.H{"code": code}, &out); err != nil {
+		h.renderTwoFactor(c, accessToken, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.renderTwoFactor(c, accessToken, http.StatusOK, gin.H{"recoveryCodes": out.RecoveryCodes})
+}
@@ -4,4 +4,40 @@
 * Licensed under MIT (https://github.com/StartBootstrap/startbootstrap-modern-business/blob/master/LICENSE)
 */
 // This file is intentionally blank
-// Use this file to add JavaScript to your project
\ No newline at end of file
+// Use this file to add JavaScript to your project
+
+// Live notifications about background work on the author's posts (Server-Sent Events).
+// onEvent receives the parsed event ({type, post_id, message, at}).
+function subscribePostEvents(postId, onEvent) {
+    if (!window.EventSource) return null;
+    const url = '/api/v1/events' + (postId ? '?post_id=' + encodeURIComponent(postId) : '');
+    // the gateway authenticates the stream with the access_token cookie
+    const source = new EventSource(url, { withCredentials: true });
+    // every type post-service publishes (domain.Event* in post-service)
+    ['translation.completed', 'translation.failed', 'images.processed', 'post.published', 'post.unpublished', 'comment.pending'].forEach(function (type) {
+        source.addEventListener(type, function (e) {
+            try {
+                onEvent(JSON.parse(e.data));
+            } catch (err) {
+                console.warn('Invalid post event:', err);
+            }
+        });
+    });
+    return source;
+}
+
+// Renders a dismissible Bootstrap alert inside the given container.
+function showPostNotice(container, level, text) {
+    if (!container) return;
+    const el = document.createElement('div');
+    el.className = 'alert alert-' + level + ' alert-dismissible fade show small';
+    el.setAttribute('role', 'alert');
+    el.textContent = text;
+    const close = document.createElement('button');
+    close.type = 'button';
+    close.className = 'btn-close';
+    close.setAttribute('data-bs-dismiss', 'alert');
+    close.setAttribute('aria-label', 'Close');
+    el.appendChild(close);
+    container.appendChild(el);
+}